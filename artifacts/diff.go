@@ -0,0 +1,394 @@
+// Package artifacts compares the artifacts two runs of the compiler write,
+// the tokens, the syntax tree, the three-address code and the assembly, by
+// their structure rather than by their lines, so that the effect of a change
+// of the compiler on a corpus can be reviewed at a glance. The sinks the
+// artifacts are written to, a folder, memory, a zip archive or the response
+// to an HTTP request, are here as well.
+package artifacts
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Kind is the kind of an artifact, telling how its structure is read
+type Kind int
+
+const (
+	KindText     Kind = iota // compared line by line
+	KindTokens               // a token per line, as the lexer writes them
+	KindAST                  // the syntax tree of --emit=ast
+	KindTAC                  // the three-address code of --emit=tac
+	KindQuads                // the quadruples of --emit=quads
+	KindAssembly             // the assembly of --emit=mips and --emit=asm
+)
+
+func (k Kind) String() string {
+	return [...]string{"text", "tokens", "ast", "tac", "quads", "assembly"}[k]
+}
+
+// KindOf returns the kind of an artifact by the suffix of its name, a result
+// of the lexer target being tokens if its first line is one
+func KindOf(name string, data []byte) Kind {
+	switch {
+	case strings.HasSuffix(name, ".ast.txt"):
+		return KindAST
+	case strings.HasSuffix(name, ".tac"):
+		return KindTAC
+	case strings.HasSuffix(name, ".quads.txt"):
+		return KindQuads
+	case strings.HasSuffix(name, ".s"), strings.HasSuffix(name, ".asm"):
+		return KindAssembly
+	case strings.HasSuffix(name, ".tokens"):
+		return KindTokens
+	case strings.HasSuffix(name, ".result"):
+		first, _, _ := strings.Cut(strings.TrimLeft(string(data), "\n"), "\n")
+		if strings.HasPrefix(first, "(") && strings.HasSuffix(first, ")") && strings.Contains(first, ", ") {
+			return KindTokens
+		}
+	}
+	return KindText
+}
+
+// Diff is the difference between two versions of an artifact: the hunks of
+// the elements removed, inserted or changed, each in its context, and the
+// count of its elements by class, the type of the tokens or the operation of
+// the instructions, in both. An artifact found in a single run has no hunks,
+// nor one whose versions differ only in positions or in the numbering of the
+// temporaries.
+type Diff struct {
+	Path    string
+	Kind    Kind
+	Missing string // "a" or "b" if the artifact is not in that run
+	Hunks   []Hunk
+	Sizes   [2]int            // the elements of both versions, nodes of a tree
+	Classes map[string][2]int // the elements of each class in both versions
+}
+
+// Hunk is a run of changes in the same context: the function or label of
+// the code, the node of the tree or the place of the tokens and the lines.
+// Its lines start with "- " for an element removed, "+ " for one inserted and
+// "~ " for a node of the tree changed in place.
+type Hunk struct {
+	Context string
+	Lines   []string
+}
+
+// maxHunkLines is the lines of a hunk written before the count of the others
+const maxHunkLines = 20
+
+// Compare compares two versions of the artifact at the path, returning nil
+// if they are the same bytes
+func Compare(path string, a, b []byte) *Diff {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+	d := &Diff{Path: path, Kind: KindOf(path, a)}
+	if d.Kind == KindAST {
+		x, y := parseTree(string(a)), parseTree(string(b))
+		d.Sizes = [2]int{size(x), size(y)}
+		d.Hunks = diffTrees(nil, x, y, nil)
+		return d
+	}
+	x, y := elements(d.Kind, string(a)), elements(d.Kind, string(b))
+	d.Classes = map[string][2]int{}
+	for i, elems := range [][]element{x, y} {
+		for _, e := range elems {
+			if e.class != "" {
+				count := d.Classes[e.class]
+				count[i]++
+				d.Classes[e.class] = count
+				d.Sizes[i]++
+			}
+		}
+	}
+	d.Hunks = diffElements(d.Kind, x, y)
+	return d
+}
+
+// Report is the comparison of the artifacts of two runs
+type Report struct {
+	Compared int // the artifacts found in either run
+	Diffs    []*Diff
+}
+
+// Differs checks if an artifact of the runs differs in its structure or is
+// missing from one of them
+func (r *Report) Differs() bool {
+	return slices.ContainsFunc(r.Diffs, func(d *Diff) bool { return d.Missing != "" || len(d.Hunks) > 0 })
+}
+
+// CompareDirs compares the artifacts of two runs in the folders, the files
+// at the same paths in both, in the order of their paths
+func CompareDirs(a, b string) (*Report, error) {
+	paths := map[string][2]bool{}
+	for i, dir := range []string{a, b} {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			found := paths[rel]
+			found[i] = true
+			paths[rel] = found
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	r := &Report{Compared: len(paths)}
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		if found := paths[path]; !found[0] || !found[1] {
+			missing := "a"
+			if found[0] {
+				missing = "b"
+			}
+			r.Diffs = append(r.Diffs, &Diff{Path: path, Kind: KindOf(path, nil), Missing: missing})
+			continue
+		}
+		x, err := os.ReadFile(filepath.Join(a, path))
+		if err != nil {
+			return nil, err
+		}
+		y, err := os.ReadFile(filepath.Join(b, path))
+		if err != nil {
+			return nil, err
+		}
+		if d := Compare(path, x, y); d != nil {
+			r.Diffs = append(r.Diffs, d)
+		}
+	}
+	return r, nil
+}
+
+// WriteText writes the report for a reader: each artifact differing with
+// its sizes, the classes whose counts changed and its hunks, then the count
+// of those differing
+func (r *Report) WriteText(w io.Writer) error {
+	differing := 0
+	for _, d := range r.Diffs {
+		if d.Missing != "" || len(d.Hunks) > 0 {
+			differing++
+		}
+		if err := d.WriteText(w); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d of %d artifacts differ\n", differing, r.Compared)
+	return err
+}
+
+// WriteText writes the difference, the elements counted as nodes, tokens,
+// instructions or lines by the kind of the artifact
+func (d *Diff) WriteText(w io.Writer) error {
+	if d.Missing != "" {
+		_, err := fmt.Fprintf(w, "== %s: only in %s\n", d.Path, map[string]string{"a": "b", "b": "a"}[d.Missing])
+		return err
+	}
+	if len(d.Hunks) == 0 {
+		_, err := fmt.Fprintf(w, "== %s, %s: the same structure, only positions or numbering differ\n", d.Path, d.Kind)
+		return err
+	}
+	unit := map[Kind]string{KindAST: "nodes", KindTokens: "tokens", KindText: "lines"}[d.Kind]
+	if unit == "" {
+		unit = "instructions"
+	}
+	summary := fmt.Sprintf("%d → %d %s", d.Sizes[0], d.Sizes[1], unit)
+	var classes []string
+	for _, class := range slices.Sorted(maps.Keys(d.Classes)) {
+		if count := d.Classes[class]; d.Kind != KindText && count[0] != count[1] {
+			classes = append(classes, fmt.Sprintf("%s: %+d", class, count[1]-count[0]))
+		}
+	}
+	if len(classes) > 0 {
+		summary += ", " + strings.Join(classes, ", ")
+	}
+	if _, err := fmt.Fprintf(w, "== %s, %s: %s\n", d.Path, d.Kind, summary); err != nil {
+		return err
+	}
+	for _, h := range d.Hunks {
+		if _, err := fmt.Fprintf(w, "  %s\n", h.Context); err != nil {
+			return err
+		}
+		for i, line := range h.Lines {
+			if i == maxHunkLines {
+				if _, err := fmt.Fprintf(w, "    ... %d more\n", len(h.Lines)-i); err != nil {
+					return err
+				}
+				break
+			}
+			if _, err := fmt.Fprintf(w, "    %s\n", line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// element is an element of an artifact read as a sequence: its key, which
+// the versions are compared by, its text as written, the context it is in
+// and its class, empty for the labels, directives and lines not counted
+type element struct {
+	key, text, context, class string
+}
+
+var (
+	temporary = regexp.MustCompile(`\$\(0x[0-9a-fA-F]+\)`)
+	label     = regexp.MustCompile(`^([A-Za-z_.$][A-Za-z0-9_.$]*):`)
+	local     = regexp.MustCompile(`^L[0-9_]`)
+	quad      = regexp.MustCompile(`^\d+: `)
+)
+
+// elements reads an artifact as a sequence. The temporaries of the
+// quadruples are all the same in the keys, so that a change numbering them
+// anew shows only where the code changed, and the comments of the assembly
+// are left out, the quadruples it is lowered from among them.
+func elements(kind Kind, data string) []element {
+	var elems []element
+	// the function or section the code is in, and the last label in it
+	var function, last string
+	for i, line := range strings.Split(data, "\n") {
+		text := strings.TrimSpace(line)
+		e := element{key: text, text: text}
+		switch kind {
+		case KindTokens:
+			if text == "" {
+				continue
+			}
+			e.context = fmt.Sprintf("token %d", len(elems)+1)
+			if t, _, ok := strings.Cut(strings.TrimPrefix(text, "("), ", "); ok && strings.HasPrefix(text, "(") {
+				e.class = t
+			}
+		case KindText:
+			e.key, e.text = line, line
+			e.context = fmt.Sprintf("line %d", i+1)
+			e.class = "line"
+		default:
+			if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";") || strings.HasPrefix(text, "//") {
+				continue
+			}
+			if kind == KindQuads && strings.HasSuffix(text, ":") && !quad.MatchString(text) {
+				// a section, "Optimized code, 3 quadruples:"
+				function, last = strings.TrimSuffix(strings.SplitN(text, ",", 2)[0], ":"), ""
+				continue
+			}
+			if m := label.FindStringSubmatch(text); m != nil && kind != KindQuads {
+				e.key, e.text = m[0], m[0]
+				if local.MatchString(m[1]) {
+					last = m[1]
+				} else {
+					function, last = m[1], ""
+				}
+				break
+			}
+			// the index of a quadruple is its place, which an insertion shifts
+			e.key = strings.Join(strings.Fields(temporary.ReplaceAllString(quad.ReplaceAllString(text, ""), "$$")), " ")
+			e.class = class(kind, e.key)
+		}
+		if kind != KindTokens && kind != KindText {
+			e.context = function
+			if last != "" {
+				e.context += ", after " + last
+			}
+		}
+		elems = append(elems, e)
+	}
+	if kind == KindText && len(elems) > 0 && elems[len(elems)-1].text == "" {
+		elems = elems[:len(elems)-1]
+	}
+	return elems
+}
+
+// class returns the operation of an instruction: the operator of the TAC,
+// "=" for a copy, the first field of a quadruple and the mnemonic of the
+// assembly, directives not counted
+func class(kind Kind, key string) string {
+	switch kind {
+	case KindQuads:
+		if op, _, ok := strings.Cut(strings.TrimPrefix(key, "("), ","); ok {
+			return op
+		}
+		return ""
+	case KindTAC:
+		if _, rhs, ok := strings.Cut(key, " = "); ok {
+			if fields := strings.Fields(rhs); len(fields) == 3 {
+				return fields[1]
+			} else if len(fields) == 2 {
+				return fields[0]
+			}
+			return "="
+		}
+	}
+	mnemonic := strings.Fields(key)[0]
+	if strings.HasPrefix(mnemonic, ".") {
+		return ""
+	}
+	return mnemonic
+}
+
+// diffElements returns the hunks of the edits between two sequences, each in
+// the context of its first element
+func diffElements(kind Kind, a, b []element) []Hunk {
+	keys := func(elems []element) []string {
+		s := make([]string, len(elems))
+		for i, e := range elems {
+			s[i] = e.key
+		}
+		return s
+	}
+	var hunks []Hunk
+	var removed, inserted []string
+	context := ""
+	flush := func() {
+		if len(removed)+len(inserted) > 0 {
+			hunks = append(hunks, Hunk{Context: context, Lines: append(removed, inserted...)})
+		}
+		removed, inserted, context = nil, nil, ""
+	}
+	for _, e := range edits(keys(a), keys(b)) {
+		switch e.op {
+		case keep:
+			flush()
+			continue
+		case remove:
+			removed = append(removed, "- "+a[e.A].text)
+		case insert:
+			inserted = append(inserted, "+ "+b[e.B].text)
+		}
+		if context == "" {
+			switch {
+			case e.op == remove:
+				context = a[e.A].context
+			case e.B < len(b) && kind != KindTokens && kind != KindText:
+				context = b[e.B].context
+			case e.A < len(a):
+				context = a[e.A].context
+			case kind == KindTokens:
+				context = fmt.Sprintf("token %d", len(a)+1)
+			case kind == KindText:
+				context = fmt.Sprintf("line %d", len(a)+1)
+			default:
+				context = b[e.B].context
+			}
+		}
+	}
+	flush()
+	for i := range hunks {
+		if hunks[i].Context == "" {
+			hunks[i].Context = "at the start"
+		}
+	}
+	return hunks
+}
@@ -0,0 +1,156 @@
+package artifacts_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/artifacts"
+)
+
+func TestCompare_AST(t *testing.T) {
+	a := `Program (0:2)
+  Block (0:2)
+    Decl int (1:7)
+      Declarator a (1:9)
+    AssignStmt (2:6)
+      Ident a (2:4)
+      BasicLit int 2 (2:8)
+`
+	// a line inserted moves the nodes after it, which is no change
+	b := `Program (0:2)
+  Block (0:2)
+    Decl int (1:7)
+      Declarator a (1:9)
+      Declarator b (1:12)
+    AssignStmt (3:6)
+      Ident a (3:4)
+      BasicLit int 3 (3:8)
+`
+	d := Compare("1.in.ast.txt", []byte(a), []byte(b))
+	if d.Kind != KindAST || d.Sizes != [2]int{7, 8} {
+		t.Fatalf("Expected an AST of 7 and 8 nodes, got %v %v", d.Kind, d.Sizes)
+	}
+	expected := []Hunk{
+		{Context: "Program (0:2) > Block (0:2) > Decl int (1:7)", Lines: []string{"+ Declarator b (1:12)"}},
+		{Context: "Program (0:2) > Block (0:2) > AssignStmt (3:6)", Lines: []string{"~ BasicLit int 2 (2:8) → BasicLit int 3 (3:8)"}},
+	}
+	if !slices.EqualFunc(d.Hunks, expected, equalHunks) {
+		t.Errorf("Expected the hunks %v, got %v", expected, d.Hunks)
+	}
+
+	moved := strings.ReplaceAll(a, "(2:", "(3:")
+	if d := Compare("1.in.ast.txt", []byte(a), []byte(moved)); d == nil || len(d.Hunks) != 0 {
+		t.Errorf("Expected the positions alone not to differ, got %v", d)
+	}
+}
+
+func TestCompare_Code(t *testing.T) {
+	a := "Generated code, 3 quadruples:\n" +
+		"0: (eq, c, a, $(0x10000001))\n" +
+		"1: (eq, $(0x10000001), 2, $(0x10000002))\n" +
+		"2: (ne, $(0x10000002), c, $(0x10000003))\n"
+	b := "Generated code, 4 quadruples:\n" +
+		"0: (mul, c, 2, $(0x10000001))\n" +
+		"1: (eq, c, a, $(0x10000002))\n" +
+		"2: (eq, $(0x10000002), 2, $(0x10000003))\n" +
+		"3: (ne, $(0x10000003), c, $(0x10000004))\n"
+	// the quadruples after the one inserted are the same but for their
+	// indices and temporaries
+	d := Compare("1.in.quads.txt", []byte(a), []byte(b))
+	expected := []Hunk{{Context: "Generated code", Lines: []string{"+ 0: (mul, c, 2, $(0x10000001))"}}}
+	if !slices.EqualFunc(d.Hunks, expected, equalHunks) {
+		t.Errorf("Expected the hunks %v, got %v", expected, d.Hunks)
+	}
+	if d.Classes["mul"] != [2]int{0, 1} || d.Classes["eq"] != [2]int{2, 2} {
+		t.Errorf("Expected a mul more, got %v", d.Classes)
+	}
+
+	a = "main:\n\t# n = 1\n\tli $t0, 1\n\tsw $t0, data+0\nL_max_0:\n\tli $v0, 10\n\tsyscall\nF_f:\n\tjr $ra\n"
+	b = "main:\n\t# n = 2\n\tli $t0, 2\n\tsw $t0, data+0\nL_max_0:\n\tli $v0, 10\n\tsyscall\nF_f:\n\tnop\n\tjr $ra\n"
+	d = Compare("1.in.s", []byte(a), []byte(b))
+	expected = []Hunk{
+		{Context: "main", Lines: []string{"- li $t0, 1", "+ li $t0, 2"}},
+		{Context: "F_f", Lines: []string{"+ nop"}},
+	}
+	if d.Kind != KindAssembly || !slices.EqualFunc(d.Hunks, expected, equalHunks) {
+		t.Errorf("Expected the hunks %v, got %v %v", expected, d.Kind, d.Hunks)
+	}
+	if d.Sizes != [2]int{5, 6} || d.Classes["nop"] != [2]int{0, 1} {
+		t.Errorf("Expected a nop more, got %v %v", d.Sizes, d.Classes)
+	}
+
+	a = "main:\npush fp\nr0 = a + 1\nL1:\nb = r0\nret\n"
+	b = "main:\npush fp\nr0 = a + 1\nL1:\nb = r0\nc = r0\nret\n"
+	d = Compare("1.in.tac", []byte(a), []byte(b))
+	expected = []Hunk{{Context: "main, after L1", Lines: []string{"+ c = r0"}}}
+	if d.Kind != KindTAC || !slices.EqualFunc(d.Hunks, expected, equalHunks) || d.Classes["="] != [2]int{1, 2} {
+		t.Errorf("Expected the hunks %v, got %v %v %v", expected, d.Kind, d.Hunks, d.Classes)
+	}
+}
+
+func TestCompare_Tokens(t *testing.T) {
+	a := "(保留字, int)\n(标识符, a)\n(分隔符, ;)\n"
+	b := "(保留字, int)\n(标识符, b)\n(分隔符, ,)\n(标识符, c)\n(分隔符, ;)\n"
+	d := Compare("1.in.result", []byte(a), []byte(b))
+	expected := []Hunk{
+		{Context: "token 2", Lines: []string{"- (标识符, a)", "+ (标识符, b)", "+ (分隔符, ,)", "+ (标识符, c)"}},
+	}
+	if d.Kind != KindTokens || !slices.EqualFunc(d.Hunks, expected, equalHunks) {
+		t.Errorf("Expected the hunks %v, got %v %v", expected, d.Kind, d.Hunks)
+	}
+	if d.Classes["标识符"] != [2]int{1, 2} || d.Sizes != [2]int{3, 5} {
+		t.Errorf("Expected an identifier more, got %v %v", d.Sizes, d.Classes)
+	}
+	if kind := KindOf("1.in.result", []byte("State: {[0]}\n")); kind != KindText {
+		t.Errorf("Expected the result of the parser read as text, got %v", kind)
+	}
+}
+
+func TestCompareDirs(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	write := func(dir, path, text string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(a, "same.tac", "main:\nret\n")
+	write(b, "same.tac", "main:\nret\n")
+	write(a, "renumbered.quads.txt", "0: (minus, a, , $(0x10000001))\n")
+	write(b, "renumbered.quads.txt", "0: (minus, a, , $(0x10000004))\n")
+	write(a, "notes.txt", "x\ny\nz\n")
+	write(b, "notes.txt", "x\nY\nz\nw\n")
+	write(b, "only.s", "main:\n")
+
+	r, err := CompareDirs(a, b)
+	if err != nil {
+		t.Fatalf("CompareDirs: %v", err)
+	}
+	if !r.Differs() {
+		t.Errorf("Expected the runs to differ")
+	}
+	var out strings.Builder
+	if err := r.WriteText(&out); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	expected := `== notes.txt, text: 3 → 4 lines
+  line 2
+    - y
+    + Y
+  line 4
+    + w
+== only.s: only in b
+== renumbered.quads.txt, quads: the same structure, only positions or numbering differ
+2 of 4 artifacts differ
+`
+	if out.String() != expected {
+		t.Errorf("Expected the report\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func equalHunks(a, b Hunk) bool {
+	return a.Context == b.Context && slices.Equal(a.Lines, b.Lines)
+}
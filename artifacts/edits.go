@@ -0,0 +1,126 @@
+package artifacts
+
+// op is what an edit does to the elements of the first version
+type op int
+
+const (
+	keep op = iota
+	remove
+	insert
+)
+
+// edit is an element kept, removed from the first version at A or inserted
+// from the second at B
+type edit struct {
+	op   op
+	A, B int
+}
+
+// maxEdits bounds the edits looked for between two versions, beyond which the
+// elements in between are taken as all removed and inserted: two artifacts
+// that different share nothing worth showing, and the search would take time
+// and memory quadratic in their sizes.
+const maxEdits = 2000
+
+// edits returns the shortest edit script turning a into b, by the algorithm
+// of Myers, on what is left of them once their common prefix and suffix are
+// kept as they are.
+func edits(a, b []string) []edit {
+	var prefix, suffix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var script []edit
+	for i := 0; i < prefix; i++ {
+		script = append(script, edit{keep, i, i})
+	}
+	for _, e := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		script = append(script, edit{e.op, e.A + prefix, e.B + prefix})
+	}
+	for i := suffix; i > 0; i-- {
+		script = append(script, edit{keep, len(a) - i, len(b) - i})
+	}
+	return script
+}
+
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	all := func() []edit {
+		script := make([]edit, 0, n+m)
+		for i := range a {
+			script = append(script, edit{remove, i, 0})
+		}
+		for j := range b {
+			script = append(script, edit{insert, n, j})
+		}
+		return script
+	}
+	if n == 0 || m == 0 {
+		return all()
+	}
+	// v[offset+k] is the furthest x reached on the diagonal k = x - y, and
+	// trace[d] the diagonals -d to d of v before the round d
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+	for d := 0; d <= n+m && d <= maxEdits; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+	return all()
+}
+
+// backtrack follows the rounds of myers back from the end of both versions
+func backtrack(trace [][]int, x, y int) []edit {
+	var script []edit
+	for d := len(trace) - 1; d >= 0; d-- {
+		if d == 0 {
+			for x > 0 {
+				x, y = x-1, y-1
+				script = append(script, edit{keep, x, y})
+			}
+			break
+		}
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+		k := x - y
+		prev := k - 1
+		if k == -d || k != d && at(k-1) < at(k+1) {
+			prev = k + 1
+		}
+		px := at(prev)
+		py := px - prev
+		for x > px && y > py {
+			x, y = x-1, y-1
+			script = append(script, edit{keep, x, y})
+		}
+		if x == px {
+			script = append(script, edit{insert, x, py})
+		} else {
+			script = append(script, edit{remove, px, y})
+		}
+		x, y = px, py
+	}
+	for i, j := 0, len(script)-1; i < j; i, j = i+1, j-1 {
+		script[i], script[j] = script[j], script[i]
+	}
+	return script
+}
@@ -0,0 +1,157 @@
+package artifacts
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ArtifactSink receives the artifacts of a run by their paths in the result
+// folder, split by /, such as 1.in.tac or railroad/E.svg, so that the
+// emitters write them alike to a folder, to memory or into an archive
+type ArtifactSink interface {
+	// Create returns the writer of the artifact, which is complete once the
+	// writer is closed; an artifact created again replaces the one before
+	Create(path string) (io.WriteCloser, error)
+}
+
+// DirSink writes the artifacts into the folder, creating the folders of
+// their paths
+type DirSink string
+
+func (d DirSink) Create(name string) (io.WriteCloser, error) {
+	file := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return nil, err
+	}
+	return os.Create(file)
+}
+
+// MemorySink keeps the artifacts in memory, for the tests and the servers.
+// Several artifacts may be written at once
+type MemorySink struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewMemorySink creates an empty sink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{files: map[string][]byte{}}
+}
+
+// memoryFile is an artifact of a MemorySink being written
+type memoryFile struct {
+	bytes.Buffer
+	sink *MemorySink
+	path string
+}
+
+func (f *memoryFile) Close() error {
+	f.sink.mu.Lock()
+	defer f.sink.mu.Unlock()
+	f.sink.files[f.path] = f.Bytes()
+	return nil
+}
+
+func (m *MemorySink) Create(name string) (io.WriteCloser, error) {
+	return &memoryFile{sink: m, path: path.Clean(name)}, nil
+}
+
+// Paths returns the paths of the artifacts closed, in order
+func (m *MemorySink) Paths() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Sorted(maps.Keys(m.files))
+}
+
+// Get returns the artifact at the path, false if it was not closed
+func (m *MemorySink) Get(name string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[path.Clean(name)]
+	return data, ok
+}
+
+// WriteZip writes the artifacts as a zip archive, in the order of their
+// paths and all dated 1980-01-01, the earliest date of the format, so that
+// the archives of the same artifacts are the same
+func (m *MemorySink) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	for _, name := range m.Paths() {
+		data, _ := m.Get(name)
+		f, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err = f.Write(data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// ZipSink packs the artifacts into a zip archive, written to the writer as
+// MemorySink.WriteZip does once the sink is closed, as a single file to
+// hand in
+type ZipSink struct {
+	*MemorySink
+	w io.Writer
+}
+
+// NewZipSink creates a sink writing its archive to the writer
+func NewZipSink(w io.Writer) *ZipSink {
+	return &ZipSink{MemorySink: NewMemorySink(), w: w}
+}
+
+// Close writes the archive of the artifacts
+func (z *ZipSink) Close() error {
+	return z.WriteZip(z.w)
+}
+
+// HTTPSink answers an HTTP request with the artifacts once closed: the
+// artifact alone with the content type of its extension if there is a
+// single one, or else their zip archive, as an attachment named Name
+type HTTPSink struct {
+	*MemorySink
+	Name string // of the archive, artifacts.zip if empty
+	w    http.ResponseWriter
+}
+
+// NewHTTPSink creates a sink answering with the response
+func NewHTTPSink(w http.ResponseWriter) *HTTPSink {
+	return &HTTPSink{MemorySink: NewMemorySink(), w: w}
+}
+
+// Close writes the response
+func (h *HTTPSink) Close() error {
+	if paths := h.Paths(); len(paths) == 1 {
+		data, _ := h.Get(paths[0])
+		typ := mime.TypeByExtension(path.Ext(paths[0]))
+		if typ == "" {
+			typ = http.DetectContentType(data)
+		}
+		h.w.Header().Set("Content-Type", typ)
+		_, err := h.w.Write(data)
+		return err
+	}
+	name := h.Name
+	if name == "" {
+		name = "artifacts.zip"
+	}
+	h.w.Header().Set("Content-Type", "application/zip")
+	h.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	return h.WriteZip(h.w)
+}
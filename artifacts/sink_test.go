@@ -0,0 +1,108 @@
+package artifacts_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	. "app/artifacts"
+)
+
+// writeAll writes the artifacts into the sink, a path and its text each
+func writeAll(t *testing.T, sink ArtifactSink, files ...string) {
+	t.Helper()
+	for i := 0; i < len(files); i += 2 {
+		f, err := sink.Create(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = io.WriteString(f, files[i+1]); err != nil {
+			t.Fatal(err)
+		}
+		if err = f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestArtifactSink(t *testing.T) {
+	files := []string{"1.in.tac", "main:\nret\n", "railroad/E.svg", "<svg/>", "1.in.result", "ok\n"}
+
+	dir := t.TempDir()
+	writeAll(t, DirSink(dir), files...)
+	if text, err := os.ReadFile(filepath.Join(dir, "railroad", "E.svg")); err != nil || string(text) != "<svg/>" {
+		t.Errorf("Expected the folders of the paths created, got %q %v", text, err)
+	}
+
+	m := NewMemorySink()
+	writeAll(t, m, files...)
+	if paths := m.Paths(); !slices.Equal(paths, []string{"1.in.result", "1.in.tac", "railroad/E.svg"}) {
+		t.Errorf("Expected the paths in order, got %v", paths)
+	}
+	if text, ok := m.Get("./1.in.tac"); !ok || string(text) != "main:\nret\n" {
+		t.Errorf("Expected the code kept, got %q", text)
+	}
+	if _, err := m.Create("missing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Get("missing"); ok {
+		t.Error("Expected an artifact not closed to be missing")
+	}
+
+	// the artifacts written in another order give the same archive
+	var a, b bytes.Buffer
+	z := NewZipSink(&a)
+	writeAll(t, z, files...)
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	z = NewZipSink(&b)
+	writeAll(t, z, files[4], files[5], files[2], files[3], files[0], files[1])
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("Expected the archives to be the same")
+	}
+	r, err := zip.NewReader(bytes.NewReader(a.Bytes()), int64(a.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != 3 || r.File[2].Name != "railroad/E.svg" {
+		t.Fatalf("Expected an entry per artifact, got %v", r.File)
+	}
+	f, err := r.File[2].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := io.ReadAll(f); string(text) != "<svg/>" {
+		t.Errorf("Expected the image in the archive, got %q", text)
+	}
+
+	recorder := httptest.NewRecorder()
+	h := NewHTTPSink(recorder)
+	h.Name = "1.in.zip"
+	writeAll(t, h, files...)
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Header().Get("Content-Type") != "application/zip" || recorder.Header().Get("Content-Disposition") != "attachment; filename=1.in.zip" ||
+		!bytes.Equal(recorder.Body.Bytes(), a.Bytes()) {
+		t.Errorf("Expected the archive as an attachment, got %v", recorder.Header())
+	}
+	recorder = httptest.NewRecorder()
+	h = NewHTTPSink(recorder)
+	// an artifact written again replaces the one before
+	writeAll(t, h, "E.svg", "<svg/>", "E.svg", "<svg></svg>")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Header().Get("Content-Type") != "image/svg+xml" || recorder.Body.String() != "<svg></svg>" {
+		t.Errorf("Expected the image alone, got %v %q", recorder.Header(), recorder.Body.String())
+	}
+}
@@ -0,0 +1,129 @@
+package artifacts
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// node is a node of a syntax tree as --emit=ast writes it, a line indented
+// by two spaces per level: its label and its position, which the trees are
+// not compared by, since a line inserted moves every node after it
+type node struct {
+	label, pos string
+	children   []*node
+}
+
+var position = regexp.MustCompile(`^(.*) \((\d+:\d+)\)$`)
+
+func (n *node) String() string {
+	if n.pos == "" {
+		return n.label
+	}
+	return n.label + " (" + n.pos + ")"
+}
+
+// parseTree reads the roots of a tree
+func parseTree(text string) []*node {
+	var roots []*node
+	// stack[i] is the last node of the level i
+	var stack []*node
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+		n := &node{label: strings.TrimSpace(trimmed)}
+		if m := position.FindStringSubmatch(n.label); m != nil {
+			n.label, n.pos = m[1], m[2]
+		}
+		level := min((len(line)-len(trimmed))/2, len(stack))
+		stack = append(stack[:level], n)
+		if level == 0 {
+			roots = append(roots, n)
+		} else {
+			parent := stack[level-1]
+			parent.children = append(parent.children, n)
+		}
+	}
+	return roots
+}
+
+// size returns the nodes of the trees
+func size(nodes []*node) int {
+	count := len(nodes)
+	for _, n := range nodes {
+		count += size(n.children)
+	}
+	return count
+}
+
+// diffTrees appends the hunks of the changes between the children a and b of
+// the node at the path to hunks. The children are matched by their labels; a
+// child removed where another of the same kind, the first word of its label,
+// is inserted is changed in place, and compared down, and the others are
+// removed or inserted with their subtrees.
+func diffTrees(path []string, a, b []*node, hunks []Hunk) []Hunk {
+	labels := func(nodes []*node) []string {
+		s := make([]string, len(nodes))
+		for i, n := range nodes {
+			s[i] = n.label
+		}
+		return s
+	}
+	context := "at the root"
+	if len(path) > 0 {
+		context = strings.Join(path, " > ")
+	}
+	add := func(line string) {
+		if len(hunks) == 0 || hunks[len(hunks)-1].Context != context {
+			hunks = append(hunks, Hunk{Context: context})
+		}
+		h := &hunks[len(hunks)-1]
+		h.Lines = append(h.Lines, line)
+	}
+	subtree := func(n *node) string {
+		if count := size(n.children); count > 0 {
+			return fmt.Sprintf("%s, and %d nodes under it", n, count)
+		}
+		return n.String()
+	}
+	var removed, inserted []*node
+	flush := func() {
+		paired := 0
+		for ; paired < len(removed) && paired < len(inserted); paired++ {
+			x, y := removed[paired], inserted[paired]
+			if kind(x) != kind(y) {
+				break
+			}
+			add(fmt.Sprintf("~ %s → %s", x, y))
+			hunks = diffTrees(append(path[:len(path):len(path)], y.String()), x.children, y.children, hunks)
+		}
+		for _, n := range removed[paired:] {
+			add("- " + subtree(n))
+		}
+		for _, n := range inserted[paired:] {
+			add("+ " + subtree(n))
+		}
+		removed, inserted = nil, nil
+	}
+	for _, e := range edits(labels(a), labels(b)) {
+		switch e.op {
+		case keep:
+			flush()
+			hunks = diffTrees(append(path[:len(path):len(path)], b[e.B].String()), a[e.A].children, b[e.B].children, hunks)
+		case remove:
+			removed = append(removed, a[e.A])
+		case insert:
+			inserted = append(inserted, b[e.B])
+		}
+	}
+	flush()
+	return hunks
+}
+
+// kind returns the kind of a node, the first word of its label
+func kind(n *node) string {
+	k, _, _ := strings.Cut(n.label, " ")
+	return k
+}
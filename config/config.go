@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"strings"
+	"time"
 )
 
 var Config = struct {
@@ -10,23 +11,140 @@ var Config = struct {
 
 	Lexer struct {
 		UsingNoBufferedReader bool
+		Channels              []string // the channels written besides the default one
+		Spec                  string   // file of the token rules lexed with instead of the hand-written lexer, see lexer.ReadRules
 	}
 
-	Path   string
-	Files  []string
-	Silent bool
+	Parser struct {
+		MaxDepth   int
+		MaxSteps   int
+		MaxErrors  int
+		Timeout    time.Duration
+		Strict     bool
+		RegAlloc   string
+		IntWidth   int    // bits of int, see parser.IntWidth
+		CostModel  string // file of the cost model of --emit=cost, see parser.ReadCostModel
+		TargetFile string // file of the target of --emit=asm, see codegen.ReadTarget
+
+		AmbiguityLength int // terminals of the sentences --emit=ambiguity probes at most
+
+		Grammar    string // file of the grammar parsed with instead of the built-in one, see parser.ReadBNF
+		TableCache string // file the table is saved to and loaded from while the grammar is unchanged
+
+		SwitchDefault bool
+		NoFallthrough bool
+
+		Preprocess  bool // expand the #include and #define directives, see preprocess.Expand
+		PruneScopes bool // drop the nested scopes once exited, writing them to <file>.scopes.txt
+		Lifetimes   bool // mark where the locals begin and end in the code, see parser.Walker.MarkLifetimes
+		Fix         bool // insert the tokens the fix-its of the syntax errors suggest, writing <file>.fixed
+		Trace       bool // write the steps of the parse to <file>.trace.txt
+		Derivation  bool // and the rightmost derivation of the input after them
+
+		Package        string // package of the parser generated by --emit=parser
+		DriverTemplate string // files of the templates it is generated with, the default ones if empty
+		TokenTemplate  string
+	}
+
+	REPL struct {
+		Workspace string // file the session is restored from and saved to, none if empty
+	}
+
+	Path        string
+	Files       []string
+	Silent      bool
+	Emit        []string
+	Archive     string   // zip file the artifacts are packed into instead of the result folder, none if empty
+	Summary     string   // format of the summary written to stdout, none if empty
+	Budget      string   // bounds of what a program may use, see parser.ParseBudget, none if empty
+	Diagnostics string   // format of the diagnostics of each file written to stderr, none if empty
+	Format      string   // format of the tokens, the items, the table and the quadruples, the default one if empty
+	Args        []string // arguments after the flags, eg. the tables to compare
+	Flags       []string // the flags set on the command line but -f, eg. -emit=tac
 }{}
 
+// Emits are the artifacts -emit can write.
+var Emits = []string{
+	"items", "dot", "table", "table-csv", "table-html", "stats", "conflicts", "grammar", "textmate", "sets", "symbols", "ll1", "railroad", "lalr", "ambiguity", "profile", "parser",
+	"trace", "doc", "semantic", "ir", "ast", "tac", "quads", "mips", "debug", "map", "layout", "cost", "loops", "symtab", "asm",
+}
+
 func ReadFlag() {
-	t := flag.String("t", "lexer", "Target to run: lexer or parser")
+	t := flag.String("t", "lexer", "Target to run: lexer, parser, compare-tables, rename, link, antlr, verify-determinism, selftest or repl")
 	lnb := flag.Bool("lexer--no-buffered", false, "Use no buffered reader for lexer")
+	lc := flag.String("lexer--channels", "", "Channels of tokens to write besides the default one, split by comma: whitespace, comments, preprocessor")
+	ls := flag.String("lexer--spec", "", "JSON file of the rules of the tokens, a name, a pattern and a priority each, lexed with by a DFA instead of the hand-written lexer, or default for the built-in ones")
 	b := flag.Bool("b", false, "Enable benchmark mode")
 	s := flag.Bool("s", false, "Stop writing results to file")
 	f := flag.String("f", "", "File to run tests on in the folder, split by |, eg. 1.in|2.in|3.in")
+	md := flag.Int("parser--max-depth", 10000, "Maximum depth of the parser stack, 0 for no limit")
+	ms := flag.Int("parser--max-steps", 10000000, "Maximum number of parser actions per file, 0 for no limit")
+	me := flag.Int("max-errors", 0, "Stop compiling a file after this many errors, 0 for no limit")
+	to := flag.Duration("parser--timeout", 0, "Time limit for parsing one file, eg. 10s, 0 for no limit")
+	al := flag.Int("parser--ambiguity-length", 5, "Maximum number of terminals of the sentences -emit=ambiguity parses looking for two trees")
+	st := flag.Bool("parser--strict", false, "Fail when the grammar has conflicts other than the expected ones")
+	gf := flag.String("parser--grammar", "", "File of the grammar to parse with instead of the built-in one, written as -emit=grammar writes it")
+	tc := flag.String("parser--table-cache", "", "File to save the parsing table to, and load it from while the grammar is unchanged")
+	sd := flag.Bool("parser--switch-default", false, "Warn about a switch without a default case")
+	nf := flag.Bool("parser--no-fallthrough", false, "Forbid a case of a switch to fall through into the next one")
+	pp := flag.Bool("parser--preprocess", false, "Expand the #include and #define directives before parsing, the diagnostics naming the lines of the files read")
+	ps := flag.Bool("parser--prune-scopes", false, "Drop the nested scopes of the symbol table once exited, writing them to <file>.scopes.txt as they are")
+	iw := flag.Int("parser--int-width", 32, "Width of int in bits, 16, 32 or 64: the range of the integer literals, the wrapping of the constants folded, the bytes of the variables of int and the instructions of the backends")
+	lt := flag.Bool("parser--lifetimes", false, "Emit begin x and end x where each local is declared and its block exited, sharing the slots of the frame between the locals never alive together")
+	fx := flag.Bool("parser--fix", false, "Insert the tokens the fix-its of the syntax errors suggest, writing the program repaired to <file>.fixed")
+	tr := flag.Bool("parser--trace", false, "Write every step of the parse, the state stack, the input left and the action, to <file>.trace.txt")
+	dv := flag.Bool("parser--derivation", false, "Write the rightmost derivation of the input after the steps of -parser--trace, which it implies")
+	pkg := flag.String("parser--package", "lrparser", "Package of the parser generated by -emit=parser")
+	dt := flag.String("parser--driver-template", "", "Go template of the driver of the generated parser, the default one if empty")
+	tt := flag.String("parser--token-template", "", "Go template of the tokens of the generated parser, the default one if empty")
+	e := flag.String("emit", "", "Extra artifacts to write into the result folder, split by comma: "+strings.Join(Emits, ", "))
+	ar := flag.String("archive", "", "Zip file to pack the results and the artifacts of the lexer or the parser target into, to hand in as one file, instead of writing them into the result folder")
+	dg := flag.String("diagnostics", "", "Write the diagnostics of each file to stderr sorted by position: text, colored with the line of the source, or json, a line per file")
+	bg := flag.String("budget", "", "Bounds of what compiling and running a program may use, for programs from anyone, split by comma: tokens, states, instructions, steps and memory, eg. tokens=100000,memory=4M")
+	sm := flag.String("summary", "", "Write a summary of the run to stdout, moving the log to stderr: json")
+	fm := flag.String("format", "", "Format of the tokens, the items of -emit=items, the table of -emit=table and the quadruples of -emit=quads: lab, as the course requires them")
+	ra := flag.String("regalloc", "linear", "Register allocator for the emitted code: linear or color")
+	cm := flag.String("parser--cost-model", "", "JSON file of the cycles per class of instruction and per memory access of -emit=cost, the default model if empty")
+	tf := flag.String("parser--target-file", "", "JSON file describing the target of -emit=asm: its registers, syscalls and instructions")
+	rw := flag.String("repl--workspace", "", "File of the workspace the REPL restores its session from, if it exists, and saves it to on leaving")
 	flag.Parse()
 
 	Config.Target = *t
+	Config.Args = flag.Args()
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "f" {
+			Config.Flags = append(Config.Flags, "-"+f.Name+"="+f.Value.String())
+		}
+	})
 	Config.Lexer.UsingNoBufferedReader = *lnb
+	if *lc != "" {
+		Config.Lexer.Channels = strings.Split(*lc, ",")
+	}
+	Config.Lexer.Spec = *ls
+	Config.Parser.MaxDepth = *md
+	Config.Parser.MaxSteps = *ms
+	Config.Parser.MaxErrors = *me
+	Config.Parser.Timeout = *to
+	Config.Parser.Strict = *st
+	Config.Parser.AmbiguityLength = *al
+	Config.Parser.RegAlloc = *ra
+	Config.Parser.CostModel = *cm
+	Config.Parser.TargetFile = *tf
+	Config.Parser.Grammar = *gf
+	Config.Parser.TableCache = *tc
+	Config.Parser.SwitchDefault = *sd
+	Config.Parser.NoFallthrough = *nf
+	Config.Parser.Preprocess = *pp
+	Config.Parser.PruneScopes = *ps
+	Config.Parser.Lifetimes = *lt
+	Config.Parser.IntWidth = *iw
+	Config.Parser.Fix = *fx
+	Config.Parser.Trace = *tr || *dv
+	Config.Parser.Derivation = *dv
+	Config.Parser.Package = *pkg
+	Config.Parser.DriverTemplate = *dt
+	Config.Parser.TokenTemplate = *tt
+	Config.REPL.Workspace = *rw
 	if *b {
 		Config.Path = "tests/benchmark/"
 		println("Benchmark mode enabled")
@@ -34,7 +152,15 @@ func ReadFlag() {
 		Config.Path = "tests/"
 	}
 	Config.Silent = *s
+	Config.Archive = *ar
+	Config.Summary = *sm
+	Config.Budget = *bg
+	Config.Diagnostics = *dg
+	Config.Format = *fm
 	if *f != "" {
 		Config.Files = strings.Split(*f, "|")
 	}
+	if *e != "" {
+		Config.Emit = strings.Split(*e, ",")
+	}
 }
@@ -0,0 +1,211 @@
+// Package diagnostics collects the errors and warnings of the lexer, the
+// parser and the semantic rules in one place, with where they are in the
+// source, and writes them sorted by position for the terminal or as JSON.
+package diagnostics
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"app/utils/log"
+)
+
+// The severities of the diagnostics.
+const (
+	Error   = "Error"
+	Warning = "Warning"
+)
+
+// The categories of the diagnostics, the phase reporting them.
+const (
+	Lexical  = "lexical"
+	Syntax   = "syntax"
+	Semantic = "semantic"
+	Internal = "internal"
+)
+
+// Diagnostic is an error or a warning, at the line and pos the message ends
+// with, as the messages of the compiler do. Line is -1 for a message with no
+// position. File is the file the position is in when it is not the one the
+// diagnostics are of, one the source includes.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Line     int64  `json:"line"`
+	Pos      int64  `json:"pos"`
+	Message  string `json:"message"`
+	Snippet  string `json:"snippet,omitempty"` // the line of the source, if known
+	Category string `json:"category,omitempty"`
+	Fatal    bool   `json:"fatal,omitempty"`
+}
+
+var position = regexp.MustCompile(`at line (\d+), pos (\d+)`)
+
+// New returns the diagnostic of the message, at the last position it names.
+func New(severity, category, message string) Diagnostic {
+	d := Diagnostic{Severity: severity, Line: -1, Message: message, Category: category}
+	if matches := position.FindAllStringSubmatch(message, -1); matches != nil {
+		last := matches[len(matches)-1]
+		d.Line, _ = strconv.ParseInt(last[1], 10, 64)
+		d.Pos, _ = strconv.ParseInt(last[2], 10, 64)
+	}
+	return d
+}
+
+// Collector gathers the diagnostics of a source, in the order they are
+// reported.
+type Collector struct {
+	diagnostics []Diagnostic
+	lines       []string
+}
+
+// NewCollector returns a collector of the diagnostics of the source, the
+// snippets of which it takes from the text, none if empty.
+func NewCollector(text string) *Collector {
+	c := &Collector{}
+	c.SetSource(text)
+	return c
+}
+
+// SetSource sets the text the snippets are taken from, filling those of the
+// diagnostics collected already.
+func (c *Collector) SetSource(text string) {
+	c.lines = nil
+	if text != "" {
+		c.lines = strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	}
+	for i := range c.diagnostics {
+		c.snippet(&c.diagnostics[i])
+	}
+}
+
+// Snippet returns the line of the source, counted from 0 as the messages
+// count them, empty if unknown.
+func (c *Collector) Snippet(line int64) string {
+	if line < 0 || line >= int64(len(c.lines)) {
+		return ""
+	}
+	return c.lines[line]
+}
+
+func (c *Collector) snippet(d *Diagnostic) {
+	if d.Snippet == "" {
+		d.Snippet = c.Snippet(d.Line)
+	}
+}
+
+// Add appends the diagnostic.
+func (c *Collector) Add(d Diagnostic) {
+	c.snippet(&d)
+	c.diagnostics = append(c.diagnostics, d)
+}
+
+// Report appends the diagnostic of the message.
+func (c *Collector) Report(severity, category, message string) {
+	c.Add(New(severity, category, message))
+}
+
+// Len returns the number of diagnostics collected.
+func (c *Collector) Len() int {
+	return len(c.diagnostics)
+}
+
+// Remap calls f with each diagnostic from the index on, to move them, as the
+// preprocessor does from the text it expanded to the files it read.
+func (c *Collector) Remap(from int, f func(*Diagnostic)) {
+	for i := max(from, 0); i < len(c.diagnostics); i++ {
+		f(&c.diagnostics[i])
+	}
+}
+
+// All returns the diagnostics in the order they were reported.
+func (c *Collector) All() []Diagnostic {
+	return slices.Clone(c.diagnostics)
+}
+
+// Sorted returns the diagnostics by position, those with none last, the ones
+// of the source before those of the files it includes, and in the order they
+// were reported at the same position.
+func (c *Collector) Sorted() []Diagnostic {
+	sorted := slices.Clone(c.diagnostics)
+	slices.SortStableFunc(sorted, func(a, b Diagnostic) int {
+		if (a.Line < 0) != (b.Line < 0) {
+			return cmp.Compare(b.Line, a.Line)
+		}
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Pos, b.Pos))
+	})
+	return sorted
+}
+
+// Counts returns the number of errors and of warnings.
+func (c *Collector) Counts() (errors, warnings int) {
+	for _, d := range c.diagnostics {
+		if d.Severity == Error {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return errors, warnings
+}
+
+// Write writes the diagnostics sorted, each as its file, the one given unless
+// it has one, its severity and message, then its snippet with a caret under
+// the pos, the severity in red or yellow if colored, and the counts at the
+// end.
+func (c *Collector) Write(w io.Writer, file string, color bool) error {
+	var b strings.Builder
+	for _, d := range c.Sorted() {
+		severity := d.Severity
+		if color {
+			severity = log.Sprintf(log.Argument{FrontColor: severityColor(d.Severity), Highlight: true, Format: "%s", Args: []any{d.Severity}})
+		}
+		fmt.Fprintf(&b, "%s: %s: %s\n", cmp.Or(d.File, file), severity, d.Message)
+		if d.Snippet != "" {
+			// the pos counts runes from 1, the tabs keep their width
+			indent := []rune(d.Snippet)[:min(max(d.Pos-1, 0), int64(len([]rune(d.Snippet))))]
+			for i, r := range indent {
+				if r != '\t' {
+					indent[i] = ' '
+				}
+			}
+			fmt.Fprintf(&b, "    %s\n    %s^\n", d.Snippet, string(indent))
+		}
+	}
+	errors, warnings := c.Counts()
+	fmt.Fprintf(&b, "%s: %d errors, %d warnings\n", file, errors, warnings)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func severityColor(severity string) log.Color {
+	if severity == Error {
+		return log.Red
+	}
+	return log.Yellow
+}
+
+// FileReport is what WriteJSON writes of the diagnostics of a file.
+type FileReport struct {
+	File        string       `json:"file"`
+	Errors      int          `json:"errors"`
+	Warnings    int          `json:"warnings"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// WriteJSON writes the diagnostics sorted as a FileReport on a line of JSON,
+// so that the reports of several files are a line each.
+func (c *Collector) WriteJSON(w io.Writer, file string) error {
+	r := FileReport{File: file, Diagnostics: c.Sorted()}
+	if r.Diagnostics == nil {
+		r.Diagnostics = []Diagnostic{}
+	}
+	r.Errors, r.Warnings = c.Counts()
+	return json.NewEncoder(w).Encode(r)
+}
@@ -0,0 +1,89 @@
+package diagnostics_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "app/diagnostics"
+)
+
+func TestNew(t *testing.T) {
+	d := New(Warning, Semantic, "a may be used before initialization, declared at line 2, pos 9")
+	if d.Line != 2 || d.Pos != 9 {
+		t.Errorf("Expected line 2, pos 9, got %+v", d)
+	}
+	// the fix-its follow the position
+	if d := New(Error, Syntax, "no action found, at line 4, pos 1; insert ;"); d.Line != 4 || d.Pos != 1 {
+		t.Errorf("Expected line 4, pos 1, got %+v", d)
+	}
+	if d := New(Error, Internal, "timeout"); d.Line != -1 {
+		t.Errorf("Expected no position, got %+v", d)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector("")
+	c.Report(Error, Internal, "disk full")
+	c.Report(Warning, Semantic, "x is unused, at line 1, pos 9")
+	c.Report(Error, Semantic, "undefined y, at line 0, pos 5")
+	c.Report(Error, Lexical, "unknown character: @, at line 1, pos 2")
+	c.SetSource("y = 1;\n\tint x;\n")
+
+	var messages []string
+	for _, d := range c.Sorted() {
+		messages = append(messages, d.Message)
+	}
+	expected := []string{"undefined y, at line 0, pos 5", "unknown character: @, at line 1, pos 2", "x is unused, at line 1, pos 9", "disk full"}
+	if strings.Join(messages, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected the diagnostics by position, got %q", messages)
+	}
+	if errors, warnings := c.Counts(); errors != 3 || warnings != 1 {
+		t.Errorf("Expected 3 errors and a warning, got %d and %d", errors, warnings)
+	}
+
+	var out strings.Builder
+	if err := c.Write(&out, "a.in", false); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	for _, expected := range []string{
+		"a.in: Error: undefined y, at line 0, pos 5\n    y = 1;\n        ^\n",
+		"a.in: Error: unknown character: @, at line 1, pos 2\n    \tint x;\n    \t^\n",
+		"a.in: Error: disk full\na.in: 3 errors, 1 warnings\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in the output, got\n%s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := c.WriteJSON(&out, "a.in"); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var report FileReport
+	if err := json.Unmarshal([]byte(out.String()), &report); err != nil || strings.Count(out.String(), "\n") != 1 {
+		t.Fatalf("Expected a line of JSON, got %v\n%s", err, out.String())
+	}
+	if report.File != "a.in" || report.Errors != 3 || len(report.Diagnostics) != 4 || report.Diagnostics[1].Snippet != "\tint x;" {
+		t.Errorf("Expected the report of a.in, got %+v", report)
+	}
+}
+
+func TestCollector_Remap(t *testing.T) {
+	c := NewCollector("")
+	c.Report(Error, Semantic, "undefined y, at line 3, pos 5")
+	c.Report(Error, Semantic, "undefined z, at line 0, pos 2")
+	c.Remap(1, func(d *Diagnostic) { d.File, d.Snippet = "lib.in", "z;" })
+	if all := c.All(); c.Len() != 2 || all[0].File != "" || all[1].File != "lib.in" {
+		t.Fatalf("Expected the second diagnostic moved to lib.in, got %+v", all)
+	}
+	var out strings.Builder
+	if err := c.Write(&out, "a.in", false); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// the diagnostics of the source before those of the files it includes
+	expected := "a.in: Error: undefined y, at line 3, pos 5\nlib.in: Error: undefined z, at line 0, pos 2\n    z;\n     ^\n"
+	if !strings.HasPrefix(out.String(), expected) {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
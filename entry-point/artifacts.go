@@ -0,0 +1,47 @@
+package entrypoint
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+
+	"app/artifacts"
+	. "app/config"
+)
+
+// results is the sink of the artifacts of the parser target, see openResults
+var results artifacts.ArtifactSink
+
+// openResults returns the sink of the artifacts of the target, its result
+// folder, or the zip archive of -archive, and the function closing it once
+// they are all written
+func openResults(target string) (artifacts.ArtifactSink, func() error, error) {
+	if Config.Archive == "" {
+		return artifacts.DirSink(Config.Path + target + "/result"), func() error { return nil }, nil
+	}
+	f, err := os.Create(Config.Archive)
+	if err != nil {
+		return nil, nil, err
+	}
+	archive := artifacts.NewZipSink(f)
+	return archive, func() error { return errors.Join(archive.Close(), f.Close()) }, nil
+}
+
+// writeArtifact writes the artifact at the path into the sink with write,
+// buffered
+func writeArtifact(sink artifacts.ArtifactSink, path string, write func(w io.Writer) error) error {
+	f, err := sink.Create(path)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(f)
+	if err = write(writer); err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,609 @@
+package entrypoint
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"app/artifacts"
+	. "app/config"
+	"app/diagnostics"
+	"app/lexer"
+	"app/parser"
+	"app/parser/codegen"
+	"app/reducer"
+)
+
+// Phases are the phases of the compiler in the order they run, the
+// subcommands of the same names running them up to theirs
+var Phases = []string{"lex", "parse", "ir", "codegen"}
+
+// subcommand is a subcommand of the command line, running a phase of the
+// compiler on its own rather than the whole test run of -t
+type subcommand struct {
+	name  string
+	args  string
+	usage string
+}
+
+var subcommands = []subcommand{
+	{"lex", "<file>", "write the tokens of the file, one per line"},
+	{"parse", "<file>", "write the syntax tree of the program, or the steps of the parse with -trace"},
+	{"table", "", "write the LR(1) table of the grammar, as --format: lab, csv, html or json"},
+	{"ir", "<file>", "write the three-address code, optimized and laid out in the stack frame"},
+	{"codegen", "<file>", "write the MIPS assembly of the program"},
+	{"batch", "<files>", "compile the files and those of the folders with the table built once, a line each"},
+	{"merge", "<files>", "merge the results batch -format=json wrote of the shards of a corpus, in the order of the files"},
+	{"reduce", "<file>", "reduce the program to a small one failing alike: as -panic, -diagnostic, -output or -command says"},
+	{"diff-artifacts", "<a> <b>", "compare the artifacts of two runs, two result folders or two files, by their structure"},
+}
+
+// exitDiffers is the exit code of lab diff-artifacts when the runs differ,
+// past those of the errors of a program
+const exitDiffers = 6
+
+// IsCommand checks if the argument is the name of a subcommand
+func IsCommand(name string) bool {
+	return slices.ContainsFunc(subcommands, func(c subcommand) bool { return c.name == name })
+}
+
+// CommandUsage writes the subcommands and their common flags
+func CommandUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: lab <command> [flags] [file]")
+	for _, c := range subcommands {
+		_, _ = fmt.Fprintf(w, "  %-14s %-7s %s\n", c.name, c.args, c.usage)
+	}
+	_, _ = fmt.Fprintln(w, "Flags:")
+	_, _ = fmt.Fprintln(w, "  -o <file>             write to the file rather than stdout")
+	_, _ = fmt.Fprintln(w, "  -v                    write the log of the phases and their times to stderr")
+	_, _ = fmt.Fprintln(w, "  -stop-after <phase>   stop after an earlier phase: lex, parse, ir or codegen")
+	_, _ = fmt.Fprintln(w, "  -trace                write the steps of the parse instead, parse only")
+	_, _ = fmt.Fprintln(w, "  -derivation           and the rightmost derivation of the input after them")
+	_, _ = fmt.Fprintln(w, "  -budget <bounds>      fail a program beyond the bounds, eg. tokens=100000,memory=4M")
+	_, _ = fmt.Fprintln(w, "  -int-width <bits>     compile for an int of 16, 32 or 64 bits")
+	_, _ = fmt.Fprintln(w, "  -j <n>                compile n files of batch at a time, as many as the processors if 0")
+	_, _ = fmt.Fprintln(w, "  -shard <i/n>          compile the i-th of n shards of the files of batch, eg. 2/4")
+	_, _ = fmt.Fprintln(w, "  -panic                reduce to a program the compiler panics on")
+	_, _ = fmt.Fprintln(w, "  -diagnostic <text>    reduce to a program with a diagnostic with the text")
+	_, _ = fmt.Fprintln(w, "  -output <text>        reduce to a program printing the text on the vm, the input read from -stdin")
+	_, _ = fmt.Fprintln(w, "  -command <command>    reduce to a program the command exits with 0 on, given the file last")
+	_, _ = fmt.Fprintln(w, "  -max-tests <n>        stop reducing after n programs tested, none if 0")
+}
+
+// Command runs the subcommand with its arguments, writing what its phase
+// produces to stdout, or to the file of -o, and the diagnostics and the log
+// to stderr, and returns the exit code: that of the errors of the program,
+// see parser.ExitCode, or 2 for wrong arguments
+func Command(name string, args []string, stdout, stderr io.Writer) int {
+	i := slices.IndexFunc(subcommands, func(c subcommand) bool { return c.name == name })
+	if i < 0 {
+		_, _ = fmt.Fprintf(stderr, "unknown command %s\n", name)
+		CommandUsage(stderr)
+		return 2
+	}
+	flags := flag.NewFlagSet("lab "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("o", "", "File to write to, stdout if empty")
+	verbose := flags.Bool("v", false, "Write the log of the phases and their times to stderr")
+	var stopAfter, format string
+	var trace, derivation bool
+	var jobs int
+	var shard string
+	var panics bool
+	var diagnostic, printed, stdin, command string
+	var maxTests int
+	if name == "parse" {
+		flags.BoolVar(&trace, "trace", false, "Write the steps of the parse, the state stack, the input left and the action, instead of the tree")
+		flags.BoolVar(&derivation, "derivation", false, "Write the rightmost derivation of the input after the steps of -trace, which it implies")
+	}
+	if name != "diff-artifacts" && name != "merge" {
+		flags.StringVar(&Config.Budget, "budget", "", "Bounds of what compiling the program may use, split by comma: tokens, states, instructions and memory, eg. tokens=100000,memory=4M")
+		flags.IntVar(&Config.Parser.IntWidth, "int-width", 32, "Width of int in bits the program is compiled for: 16, 32 or 64")
+		flags.IntVar(&Config.Parser.MaxDepth, "max-depth", parser.DefaultLimits.MaxDepth, "Maximum depth of the parser stack, 0 for no limit")
+		flags.IntVar(&Config.Parser.MaxSteps, "max-steps", parser.DefaultLimits.MaxSteps, "Maximum number of parser actions per file, 0 for no limit")
+	}
+	if name == "batch" {
+		flags.IntVar(&jobs, "j", 0, "Number of files to compile at a time, as many as the processors if 0")
+		flags.StringVar(&shard, "shard", "", "Shard of the files to compile, the i-th of n written i/n, all of them if empty")
+	}
+	if name == "reduce" {
+		flags.BoolVar(&panics, "panic", false, "Reduce to a program compiling or generating the code of which panics")
+		flags.StringVar(&diagnostic, "diagnostic", "", "Reduce to a program with a diagnostic the message of which contains the text")
+		flags.StringVar(&printed, "output", "", "Reduce to a program printing the text run on the vm")
+		flags.StringVar(&stdin, "stdin", "", "File the program of -output reads its input from, none if empty")
+		flags.StringVar(&command, "command", "", "Reduce to a program the command exits with 0 on, the file of the program its last argument")
+		flags.IntVar(&maxTests, "max-tests", 0, "Programs to test at most, no bound if 0")
+	}
+	if name == "table" {
+		flags.StringVar(&format, "format", "lab", "Format of the table: lab, csv, html or json")
+	} else if name == "batch" {
+		flags.StringVar(&format, "format", "text", "Format of the results: text, or json, a line per file for merge")
+	} else if name != "diff-artifacts" && name != "merge" && name != "reduce" {
+		flags.StringVar(&stopAfter, "stop-after", "", "Phase to stop after: lex, parse, ir or codegen, the one of the command if empty")
+	}
+	files, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	usage := func(format string, args ...any) int {
+		_, _ = fmt.Fprintf(stderr, "lab %s: %s\n", name, fmt.Sprintf(format, args...))
+		return 2
+	}
+	if _, err := parser.ParseBudget(Config.Budget); err != nil {
+		return usage("%v", err)
+	}
+	if !parser.IntWidth(Config.Parser.IntWidth).Valid() {
+		return usage("invalid int width %d, expected 16, 32 or 64", Config.Parser.IntWidth)
+	}
+
+	var run func(w io.Writer) (int, error)
+	if name == "table" {
+		if len(files) != 0 {
+			return usage("expects no file")
+		}
+		if !slices.Contains([]string{"lab", "csv", "html", "json"}, format) {
+			return usage("unknown format %s, expected lab, csv, html or json", format)
+		}
+		run = func(w io.Writer) (int, error) { return parser.ExitOK, writeTable(w, format) }
+	} else if name == "diff-artifacts" {
+		if len(files) != 2 {
+			return usage("expects two folders or two files, got %d", len(files))
+		}
+		run = func(w io.Writer) (int, error) { return diffArtifacts(files[0], files[1], w) }
+	} else if name == "batch" {
+		if len(files) == 0 {
+			return usage("expects files or folders")
+		}
+		if format != "text" && format != "json" {
+			return usage("unknown format %s, expected text or json", format)
+		}
+		s, err := parser.ParseShard(shard)
+		if err != nil {
+			return usage("%v", err)
+		}
+		run = func(w io.Writer) (int, error) { return compileBatch(files, jobs, s, format, w, stderr, *verbose) }
+	} else if name == "merge" {
+		if len(files) == 0 {
+			return usage("expects the results of the shards")
+		}
+		run = func(w io.Writer) (int, error) { return mergeBatch(files, w) }
+	} else if name == "reduce" {
+		if len(files) != 1 {
+			return usage("expects a file, got %d", len(files))
+		}
+		given := 0
+		for _, set := range []bool{panics, diagnostic != "", printed != "", command != ""} {
+			if set {
+				given++
+			}
+		}
+		if given != 1 {
+			return usage("expects one of -panic, -diagnostic, -output and -command, got %d", given)
+		}
+		if stdin != "" && printed == "" {
+			return usage("cannot read -stdin but for -output")
+		}
+		run = func(w io.Writer) (int, error) {
+			var fails reducer.Predicate
+			opts, err := reduceOptions()
+			if err != nil {
+				return parser.ExitInternal, err
+			}
+			switch {
+			case panics:
+				fails = reducer.Panics(opts)
+			case diagnostic != "":
+				fails = reducer.Diagnostic(diagnostic, opts)
+			case printed != "":
+				input := ""
+				if stdin != "" {
+					if input, err = readSource(stdin); err != nil {
+						return parser.ExitInternal, err
+					}
+				}
+				fails = reducer.Output(printed, input, opts)
+			default:
+				fields := strings.Fields(command)
+				fails = reducer.Command(fields[0], fields[1:]...)
+			}
+			return reduceFile(files[0], fails, maxTests, w, stderr, *verbose)
+		}
+	} else {
+		if len(files) != 1 {
+			return usage("expects a file, got %d", len(files))
+		}
+		phase := slices.Index(Phases, name)
+		if stopAfter != "" {
+			stop := slices.Index(Phases, stopAfter)
+			if stop < 0 {
+				return usage("unknown phase %s, expected one of %s", stopAfter, strings.Join(Phases, ", "))
+			}
+			if stop > phase {
+				return usage("cannot stop after %s, which comes after %s", stopAfter, name)
+			}
+			phase = stop
+		}
+		t := traceNone
+		if derivation {
+			t = traceDerivation
+		} else if trace {
+			t = traceSteps
+		}
+		if t != traceNone && Phases[phase] != "parse" {
+			return usage("cannot trace the parse and stop after %s", Phases[phase])
+		}
+		run = func(w io.Writer) (int, error) {
+			return runPhases(files[0], Phases[phase], w, stderr, *verbose, t)
+		}
+	}
+
+	w := stdout
+	var f *os.File
+	if *output != "" {
+		if f, err = os.Create(*output); err != nil {
+			_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+			return parser.ExitInternal
+		}
+		w = f
+	}
+	buffered := bufio.NewWriter(w)
+	code, err := run(buffered)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if f != nil {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return parser.ExitInternal
+	}
+	return code
+}
+
+// parseInterspersed parses the flags wherever they are among the arguments,
+// as in "lab codegen a.in -o a.s", returning the other arguments
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return rest, nil
+		}
+		rest = append(rest, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// writeTable writes the table of the grammar of -parser--grammar in the
+// format
+func writeTable(w io.Writer, format string) error {
+	lr, err := newParser()
+	if err != nil {
+		return err
+	}
+	if err = lr.EnsureTableContext(context.Background()); err != nil {
+		return err
+	}
+	switch format {
+	case "csv":
+		return lr.Table.WriteCSV(w)
+	case "html":
+		return lr.Table.WriteHTML(w, "LR(1) parsing table")
+	case "json":
+		return lr.Table.WriteJSON(w)
+	}
+	return lr.Table.WriteLab(w)
+}
+
+// diffArtifacts compares the artifacts of two runs, the result folders of
+// both or two versions of a file, and writes the differences of their
+// structure
+func diffArtifacts(a, b string, w io.Writer) (int, error) {
+	info, err := os.Stat(a)
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	var report *artifacts.Report
+	if info.IsDir() {
+		if report, err = artifacts.CompareDirs(a, b); err != nil {
+			return parser.ExitInternal, err
+		}
+	} else {
+		x, err := os.ReadFile(a)
+		if err != nil {
+			return parser.ExitInternal, err
+		}
+		y, err := os.ReadFile(b)
+		if err != nil {
+			return parser.ExitInternal, err
+		}
+		report = &artifacts.Report{Compared: 1}
+		if d := artifacts.Compare(filepath.Base(a), x, y); d != nil {
+			report.Diffs = append(report.Diffs, d)
+		}
+	}
+	if err = report.WriteText(w); err != nil || !report.Differs() {
+		return parser.ExitOK, err
+	}
+	return exitDiffers, nil
+}
+
+// traceMode is what lab parse writes of the steps of the parse
+type traceMode int
+
+const (
+	traceNone traceMode = iota
+	traceSteps
+	traceDerivation // the steps and the derivation after them
+)
+
+// runPhases runs the phases of the compiler on the file up to the last one,
+// writing its output to w, or the trace of the parse if asked
+func runPhases(filename, last string, w, stderr io.Writer, verbose bool, trace traceMode) (int, error) {
+	logf := func(format string, args ...any) {
+		if verbose {
+			_, _ = fmt.Fprintf(stderr, format, args...)
+		}
+	}
+	source, err := readSource(filename)
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	rules, err := lexerRules()
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	if last == "lex" {
+		st := time.Now()
+		budget, _ := parser.ParseBudget(Config.Budget)
+		code, err := writeTokens(filename, source, rules, budget, w, stderr)
+		logf("lex: %d ms\n", time.Since(st).Milliseconds())
+		return code, err
+	}
+
+	st := time.Now()
+	lr, err := newParser()
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	if err = lr.EnsureTableContext(context.Background()); err != nil {
+		return parser.ExitInternal, err
+	}
+	tables := lr.Tables()
+	logf("table: %d ms\n", time.Since(st).Milliseconds())
+	st = time.Now()
+	opts := parser.Options{Source: strings.NewReader(source), Lexer: rules, Tables: tables, RegAlloc: Config.Parser.RegAlloc, Trace: trace != traceNone,
+		IntWidth: parser.IntWidth(Config.Parser.IntWidth)}
+	if verbose {
+		opts.Log = func(message string) { _, _ = fmt.Fprint(stderr, message) }
+	}
+	result, err := parser.Compile(opts)
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	logf("parse and ir: %d ms\n", time.Since(st).Milliseconds())
+	if errs, warnings := result.Collector.Counts(); errs+warnings > 0 {
+		if err = result.Collector.Write(stderr, filename, false); err != nil {
+			return parser.ExitInternal, err
+		}
+	}
+	code := result.Summary().ExitCode()
+	if trace != traceNone {
+		// the steps up to a syntax error are what shows a bug of the table
+		if err = result.Trace.WriteText(w); err != nil || trace != traceDerivation || !result.Trace.Accepted() {
+			return code, err
+		}
+		if _, err = fmt.Fprintln(w); err != nil {
+			return code, err
+		}
+		return code, result.Trace.WriteDerivation(w)
+	}
+	if _, fatal := result.Fatal(); fatal {
+		return code, nil
+	}
+
+	switch last {
+	case "parse":
+		if result.Program == nil {
+			return code, nil
+		}
+		return code, result.Program.Dump(w)
+	case "ir":
+		for _, line := range result.TAC {
+			if _, err = fmt.Fprintln(w, line); err != nil {
+				return code, err
+			}
+		}
+		return code, nil
+	}
+	// no assembly is written for a program with errors, the names it does not
+	// declare have nowhere to be stored
+	if errs, _ := result.Collector.Counts(); errs > 0 {
+		return code, nil
+	}
+	st = time.Now()
+	err = codegen.MIPS(w, result.Walker, result.Walker.Quads())
+	logf("codegen: %d ms\n", time.Since(st).Milliseconds())
+	return code, err
+}
+
+// compileBatch compiles the files of the paths in the shard concurrently with
+// the table built once, writing a line per file to w, as text or as JSON, and
+// their diagnostics and the stacks of the panics of the compiler to stderr in
+// the order of the files, and returns the exit code of all their errors
+func compileBatch(paths []string, workers int, shard parser.Shard, format string, w, stderr io.Writer, verbose bool) (int, error) {
+	logf := func(format string, args ...any) {
+		if verbose {
+			_, _ = fmt.Fprintf(stderr, format, args...)
+		}
+	}
+	files, err := parser.BatchFiles(paths...)
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	files = shard.Files(files)
+	rules, err := lexerRules()
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	st := time.Now()
+	lr, err := newParser()
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	if err = lr.EnsureTableContext(context.Background()); err != nil {
+		return parser.ExitInternal, err
+	}
+	logf("table: %d ms\n", time.Since(st).Milliseconds())
+	st = time.Now()
+	opts := parser.Options{Lexer: rules, Tables: lr.Tables(), RegAlloc: Config.Parser.RegAlloc, Lifetimes: Config.Parser.Lifetimes,
+		IntWidth: parser.IntWidth(Config.Parser.IntWidth)}
+	results := parser.CompileFiles(files, opts, workers)
+	logf("%d files: %d ms\n", len(files), time.Since(st).Milliseconds())
+	for _, r := range results {
+		if p := (*parser.PanicError)(nil); errors.As(r.Err, &p) {
+			_, _ = fmt.Fprintf(stderr, "%s: %v\n%s", r.File, p, p.Stack)
+		}
+		if r.Result == nil {
+			continue
+		}
+		if errs, warnings := r.Result.Collector.Counts(); errs+warnings > 0 {
+			if err = r.Result.Collector.Write(stderr, r.File, false); err != nil {
+				return parser.ExitInternal, err
+			}
+		}
+	}
+	if format == "json" {
+		err = parser.WriteBatchJSON(w, results)
+	} else {
+		err = parser.WriteBatch(w, results)
+	}
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	counts, _ := parser.BatchCounts(results)
+	return counts.ExitCode(), nil
+}
+
+// mergeBatch merges the results of the shards of a corpus, the files batch
+// -format=json wrote, and writes them as batch does, returning the exit code
+// of all their errors
+func mergeBatch(files []string, w io.Writer) (int, error) {
+	var shards []io.Reader
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return parser.ExitInternal, err
+		}
+		defer f.Close()
+		shards = append(shards, f)
+	}
+	records, err := parser.MergeBatch(shards...)
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	if err = parser.WriteBatchRecords(w, records); err != nil {
+		return parser.ExitInternal, err
+	}
+	counts, _ := parser.BatchRecordCounts(records)
+	return counts.ExitCode(), nil
+}
+
+// reduceOptions returns the options the predicates of lab reduce compile
+// with, the table built once
+func reduceOptions() (parser.Options, error) {
+	rules, err := lexerRules()
+	if err != nil {
+		return parser.Options{}, err
+	}
+	lr, err := newParser()
+	if err != nil {
+		return parser.Options{}, err
+	}
+	if err = lr.EnsureTableContext(context.Background()); err != nil {
+		return parser.Options{}, err
+	}
+	budget, _ := parser.ParseBudget(Config.Budget) // checked with the flags
+	return parser.Options{Lexer: rules, Tables: lr.Tables(), RegAlloc: Config.Parser.RegAlloc, Budget: budget,
+		IntWidth: parser.IntWidth(Config.Parser.IntWidth)}, nil
+}
+
+// reduceFile reduces the program of the file to a small one the predicate
+// holds on and writes it, and the tokens left of those of the file to stderr
+func reduceFile(filename string, fails reducer.Predicate, maxTests int, w, stderr io.Writer, verbose bool) (int, error) {
+	source, err := readSource(filename)
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	opts := reducer.Options{MaxTests: maxTests}
+	if verbose {
+		opts.Log = func(tokens int) { _, _ = fmt.Fprintf(stderr, "%d tokens left\n", tokens) }
+	}
+	st := time.Now()
+	result, err := reducer.Reduce(source, fails, opts)
+	if err != nil {
+		return parser.ExitInternal, err
+	}
+	if _, err = io.WriteString(w, result.Source); err != nil {
+		return parser.ExitInternal, err
+	}
+	note := ""
+	if result.Exhausted {
+		note = ", stopped by -max-tests"
+	}
+	_, _ = fmt.Fprintf(stderr, "%s: %d of %d tokens left after %d tests in %d ms%s\n", filename, result.Tokens, result.Original, result.Tests,
+		time.Since(st).Milliseconds(), note)
+	return parser.ExitOK, nil
+}
+
+// writeTokens writes the tokens of the source as the lexer target does, and
+// its lexical errors to stderr, stopping at the bounds of the budget if any
+func writeTokens(filename, source string, rules *lexer.DFA, budget *parser.Budget, w, stderr io.Writer) (int, error) {
+	l := lexer.NewLexer(strings.NewReader(source))
+	if rules != nil {
+		l = lexer.NewDFALexer(strings.NewReader(source), rules)
+	}
+	if budget == nil {
+		budget = &parser.Budget{}
+	}
+	collector := diagnostics.NewCollector(source)
+	if err := parser.CheckBudget("bytes of source", int64(len(source)), budget.MaxMemory); err != nil {
+		collector.Report(diagnostics.Error, diagnostics.Internal, err.Error())
+		return parser.ExitInternal, collector.Write(stderr, filename, false)
+	}
+	for tokens := 1; ; tokens++ {
+		token, err := l.NextToken()
+		if budgetErr := parser.CheckBudget("tokens", int64(tokens), int64(budget.MaxTokens)); budgetErr != nil {
+			collector.Report(diagnostics.Error, diagnostics.Internal, fmt.Sprintf("%v, at line %d, pos %d", budgetErr, token.Line, token.Pos))
+			return parser.ExitInternal, collector.Write(stderr, filename, false)
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			collector.Report(diagnostics.Error, diagnostics.Lexical, err.Error())
+		} else if token.Type != lexer.EOF && token.Type != 0 {
+			if _, err := fmt.Fprintf(w, "(%s, %s)\n", token.Type.ToString(), token.Val); err != nil {
+				return parser.ExitInternal, err
+			}
+		}
+		if token.Type == lexer.EOF || errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	errs, _ := collector.Counts()
+	if errs == 0 {
+		return parser.ExitOK, nil
+	}
+	return parser.ExitLexical, collector.Write(stderr, filename, false)
+}
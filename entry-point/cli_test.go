@@ -0,0 +1,46 @@
+package entrypoint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"app/parser"
+)
+
+func TestCommand_Limits(t *testing.T) {
+	// lab reads no flags of the targets, its parses have the limits of
+	// parser.DefaultLimits unless told otherwise
+	depth := parser.DefaultLimits.MaxDepth + 100
+	file := filepath.Join(t.TempDir(), "deep.c")
+	src := "{ int a; a = " + strings.Repeat("(", depth) + "1" + strings.Repeat(")", depth) + "; }\n"
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr strings.Builder
+	if code := Command("parse", []string{file}, &stdout, &stderr); code == parser.ExitOK {
+		t.Errorf("Expected lab parse to fail, got %d", code)
+	}
+	if expected := "stack depth exceeds 10000"; !strings.Contains(stderr.String(), expected) {
+		t.Errorf("Expected %q in the errors, got %q", expected, stderr.String())
+	}
+}
+
+func TestCommand_Undeclared(t *testing.T) {
+	// a name declared nowhere is a semantic error, and no assembly is
+	// written for it
+	file := filepath.Join(t.TempDir(), "undeclared.c")
+	if err := os.WriteFile(file, []byte("{\n    a = 1;\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"ir", "codegen"} {
+		var stdout, stderr strings.Builder
+		if code := Command(name, []string{file}, &stdout, &stderr); code != parser.ExitSemantic {
+			t.Errorf("Expected lab %s to exit with %d, got %d: %s", name, parser.ExitSemantic, code, stderr.String())
+		}
+		if expected := "item a not found in any scope, at line 1, pos 5"; !strings.Contains(stderr.String(), expected) {
+			t.Errorf("Expected %q in the errors of lab %s, got %q", expected, name, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package entrypoint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	. "app/config"
+	. "app/utils"
+	"app/utils/log"
+)
+
+// determinismFlags are the flags the runs of VerifyDeterminism set
+// themselves, dropped from those of the command line, and pathFlags the ones
+// naming files, made absolute since the runs are in another folder
+var (
+	determinismFlags = []string{"t", "emit", "summary", "s", "parser--table-cache", "archive"}
+	pathFlags        = []string{"parser--grammar", "parser--cost-model", "parser--driver-template", "parser--token-template"}
+)
+
+// VerifyDeterminism runs the parser target twice on the files, in processes
+// of their own so that the maps are seeded differently, the second on a
+// single thread, and compares everything the runs write: the artifacts of
+// -emit, all of them if not set, the results and the JSON summary. The
+// tables are built by both runs. Any difference fails the command, since an
+// output depending on the order maps or goroutines happen to run in is
+// graded differently from one run to the next.
+func VerifyDeterminism() {
+	report := func(format string, err error) {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: format, Args: []any{err.Error()}}),
+		)
+		fail()
+	}
+	files, err := parserFiles()
+	if err != nil {
+		report("!!! System Error: %s", err)
+		return
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		report("!!! System Error: %s", err)
+		return
+	}
+	work, err := os.MkdirTemp("", "verify-determinism-")
+	if err != nil {
+		report("!!! System Error: %s", err)
+		return
+	}
+	defer os.RemoveAll(work)
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		text, err := os.ReadFile(file.Path)
+		if err == nil {
+			err = os.MkdirAll(filepath.Join(work, Config.Path, "parser"), os.ModePerm)
+		}
+		if err == nil {
+			err = os.WriteFile(filepath.Join(work, Config.Path, "parser", file.Info.Name()), text, 0o644)
+		}
+		if err != nil {
+			report("!!! System Error: %s", err)
+			return
+		}
+		names = append(names, file.Info.Name())
+	}
+	args, err := determinismArgs(names)
+	if err != nil {
+		report("!!! System Error: %s", err)
+		return
+	}
+
+	fmt.Print(log.Sprintf(
+		Divider(),
+		log.Argument{Highlight: true, Format: "*** Verifying the determinism of ", Args: []any{}},
+		log.Argument{FrontColor: log.Magenta, Highlight: true, Format: "%d ", Args: []any{len(files)}},
+		log.Argument{Highlight: true, Format: "Files ***\n", Args: []any{}},
+		Divider(),
+	))
+	runs := [2]string{filepath.Join(work, "run1"), filepath.Join(work, "run2")}
+	for i, env := range [][]string{nil, {"GOMAXPROCS=1"}} {
+		if err := runParser(executable, args, work, env, runs[i]); err != nil {
+			report("!!! Run Error: %s", err)
+			return
+		}
+	}
+
+	diffs, err := DiffDirs(runs[0], runs[1])
+	if err != nil {
+		report("!!! System Error: %s", err)
+		return
+	}
+	for _, diff := range diffs {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Differs: %s", Args: []any{diff.String()}}),
+		)
+	}
+	if len(diffs) > 0 {
+		fail()
+		return
+	}
+	fmt.Print(log.Sprintf(
+		log.Argument{FrontColor: log.Green, Highlight: true, Format: "!!! The outputs of both runs are identical !!!\n", Args: []any{}},
+	))
+}
+
+// determinismArgs returns the arguments of the runs of VerifyDeterminism on
+// the files: the flags of the command line but those it sets itself
+func determinismArgs(files []string) ([]string, error) {
+	emits := Config.Emit
+	if len(emits) == 0 {
+		emits = Emits
+	}
+	args := []string{"-t=parser", "-summary=json", "-emit=" + strings.Join(emits, ","), "-f=" + strings.Join(files, "|")}
+	for _, flag := range Config.Flags {
+		name, value, _ := strings.Cut(strings.TrimPrefix(flag, "-"), "=")
+		if slices.Contains(determinismFlags, name) {
+			continue
+		}
+		if slices.Contains(pathFlags, name) && value != "" {
+			abs, err := filepath.Abs(value)
+			if err != nil {
+				return nil, err
+			}
+			flag = "-" + name + "=" + abs
+		}
+		args = append(args, flag)
+	}
+	return args, nil
+}
+
+// runParser runs the parser target in the folder, then moves the result
+// folder to the output with the summary the run writes to stdout. A run
+// exiting with the code of the errors of the files is not an error.
+func runParser(executable string, args []string, dir string, env []string, output string) error {
+	var stdout bytes.Buffer
+	cmd := exec.Command(executable, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = io.Discard
+	var exit *exec.ExitError
+	if err := cmd.Run(); err != nil && !errors.As(err, &exit) {
+		return err
+	}
+	if stdout.Len() == 0 {
+		return fmt.Errorf("%s %s wrote no summary", executable, strings.Join(args, " "))
+	}
+	if err := os.Rename(filepath.Join(dir, Config.Path, "parser", "result"), output); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(output, "summary.json"), stdout.Bytes(), 0o644)
+}
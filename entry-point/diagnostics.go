@@ -0,0 +1,56 @@
+package entrypoint
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	. "app/config"
+	"app/diagnostics"
+	"app/utils/charset"
+)
+
+// readSource reads the file as the compiler does, in UTF-8 with \n line
+// endings whatever it was written in, see charset
+func readSource(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	text, _ := charset.Decode(data)
+	return text, nil
+}
+
+// writeEncoding writes the encoding and the line endings the source was
+// converted from, nothing if it is UTF-8 with \n line endings already
+func writeEncoding(w io.Writer, encoding charset.Encoding, crlf bool) error {
+	if encoding.Converted() {
+		if _, err := fmt.Fprintf(w, "Note: read as %s, converted to UTF-8\n", encoding); err != nil {
+			return err
+		}
+	}
+	if crlf {
+		if _, err := fmt.Fprintln(w, "Note: the lines end with \\r\\n or \\r, read as \\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diagnosticsMu keeps the diagnostics of the files compiled at once apart
+var diagnosticsMu sync.Mutex
+
+// writeDiagnostics writes the diagnostics of the file to stderr as
+// -diagnostics asks, sorted by position, nothing if it is not set
+func writeDiagnostics(c *diagnostics.Collector, file string) error {
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+	switch Config.Diagnostics {
+	case "text":
+		return c.Write(os.Stderr, file, true)
+	case "json":
+		return c.WriteJSON(os.Stderr, file)
+	}
+	return nil
+}
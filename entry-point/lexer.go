@@ -10,9 +10,13 @@ import (
 	"sync"
 	"time"
 
+	"app/artifacts"
 	. "app/config"
+	"app/diagnostics"
 	"app/lexer"
+	"app/parser"
 	. "app/utils"
+	"app/utils/charset"
 	"app/utils/log"
 	"app/utils/mmap"
 )
@@ -52,10 +56,10 @@ func LexerTest() {
 		Divider(),
 	))
 
+	var sink artifacts.ArtifactSink
+	closeSink := func() error { return nil }
 	if !Config.Silent {
-		// Create result directory if it doesn't exist
-		err = os.MkdirAll(Config.Path+"lexer/result", os.ModePerm)
-		if err != nil {
+		if sink, closeSink, err = openResults("lexer"); err != nil {
 			panic(err)
 		}
 	}
@@ -71,22 +75,23 @@ func LexerTest() {
 		go func(file FileInfo) {
 			st := time.Now()
 			defer wg.Done()
-			var f *os.File
-			var err error
+			var f io.Writer = io.Discard
 			if !Config.Silent {
-				f, err = os.Create(Config.Path + "lexer/result/" + file.Info.Name() + ".result")
+				result, err := sink.Create(file.Info.Name() + ".result")
 				if err != nil {
 					panic(err)
 				}
-				defer func(f *os.File) {
+				f = result
+				defer func(f io.Closer) {
 					err := f.Close()
 					if err != nil {
 						panic(err)
 					}
-				}(f)
+				}(result)
 			}
 			writer := bufio.NewWriter(f)
-			err = StartSingleLexerTest(file.Path, writer)
+			errs, err := StartSingleLexerTest(file.Path, writer)
+			record(file.Path, parser.DiagnosticsSummary{Completed: err == nil, Errors: errs, Categories: parser.ErrorCounts{Lexical: errs}}, err)
 			if err != nil {
 				fmt.Println(
 					log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
@@ -109,6 +114,11 @@ func LexerTest() {
 		}(file)
 	}
 	wg.Wait()
+	if err = closeSink(); err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+		)
+	}
 
 	fmt.Print(log.Sprintf(
 		Divider(),
@@ -117,8 +127,33 @@ func LexerTest() {
 	))
 }
 
-// StartSingleLexerTest runs the lexer test on a single file
-func StartSingleLexerTest(filename string, writer io.Writer) error {
+// lexerRules returns the automaton of the rules of -lexer--spec, compiled
+// once for all the files, nil without the flag.
+var lexerRules = sync.OnceValues(func() (*lexer.DFA, error) {
+	if Config.Lexer.Spec == "" {
+		return nil, nil
+	}
+	rules := lexer.DefaultRules()
+	if Config.Lexer.Spec != "default" {
+		f, err := os.Open(Config.Lexer.Spec)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if rules, err = lexer.ReadRules(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", Config.Lexer.Spec, err)
+		}
+	}
+	d, err := lexer.CompileRules(rules)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", Config.Lexer.Spec, err)
+	}
+	return d, nil
+})
+
+// StartSingleLexerTest runs the lexer test on a single file and returns the
+// number of lexical errors
+func StartSingleLexerTest(filename string, writer io.Writer) (int, error) {
 	file, err := mmap.NewMMapReader(filename)
 	if err != nil {
 		panic(err)
@@ -129,31 +164,85 @@ func StartSingleLexerTest(filename string, writer io.Writer) error {
 			panic(err)
 		}
 	}(file)
-	l := lexer.NewLexer(file)
+	rules, err := lexerRules()
+	if err != nil {
+		return 0, err
+	}
+	source := charset.NewReader(file)
+	l := lexer.NewLexer(source)
+	if rules != nil {
+		l = lexer.NewDFALexer(source, rules)
+	}
+	for _, name := range Config.Lexer.Channels {
+		channel, err := lexer.ParseChannel(name)
+		if err != nil {
+			return 0, err
+		}
+		l.WithChannels(channel)
+	}
 
+	lab := Config.Format == "lab"
+	if !Config.Silent && lab {
+		if _, err := fmt.Fprintln(writer, lexer.LabHeader()); err != nil {
+			return 0, err
+		}
+	}
+	errs, n := 0, 0
+	collector := diagnostics.NewCollector("")
 	for {
 		token, err := l.NextToken()
+		if err != nil && !errors.Is(err, io.EOF) {
+			errs++
+			collector.Report(diagnostics.Error, diagnostics.Lexical, err.Error())
+		}
 		if !Config.Silent && err != nil && !errors.Is(err, io.EOF) {
 			_, err2 := fmt.Fprintf(writer, "Error: %s\n", err.Error())
 			if err2 != nil {
-				return err2
+				return errs, err2
 			}
 		}
 		if token.Type == lexer.EOF || errors.Is(err, io.EOF) {
 			break
 		}
-		if !Config.Silent && token.Type != 0 {
+		if !Config.Silent && lab && token.Type != 0 {
+			n++
+			_, err = fmt.Fprintln(writer, lexer.FormatLab(n, token))
+			if err != nil {
+				return errs, err
+			}
+		} else if !Config.Silent && token.Channel != lexer.DefaultChannel {
+			// the whitespace is quoted to stay on the line
+			_, err = fmt.Fprintf(writer, "(%s, %q)\n", token.Type.ToString(), token.Val)
+			if err != nil {
+				return errs, err
+			}
+		} else if !Config.Silent && token.Type != 0 {
 			_, err = fmt.Fprintf(writer, "(%s, %s)\n", token.Type.ToString(), token.Val)
 			if err != nil {
-				return err
+				return errs, err
 			}
 		}
 	}
+	if !Config.Silent && !lab {
+		if err = writeEncoding(writer, source.Encoding(), source.CRLF()); err != nil {
+			return errs, err
+		}
+	}
 	if !Config.Silent {
 		_, err = fmt.Fprintln(writer)
 		if err != nil {
-			return err
+			return errs, err
+		}
+	}
+	if Config.Diagnostics != "" {
+		source, err := readSource(filename)
+		if err != nil {
+			return errs, err
+		}
+		collector.SetSource(source)
+		if err = writeDiagnostics(collector, filename); err != nil {
+			return errs, err
 		}
 	}
-	return nil
+	return errs, nil
 }
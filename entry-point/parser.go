@@ -2,16 +2,28 @@ package entrypoint
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"path/filepath"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
+	"app/artifacts"
 	. "app/config"
 	"app/lexer"
 	"app/parser"
+	"app/parser/ast"
+	"app/parser/codegen"
+	"app/parser/ir"
+	"app/preprocess"
 	. "app/utils"
 	"app/utils/log"
 	"app/utils/mmap"
@@ -19,20 +31,17 @@ import (
 
 var p *parser.Parser
 
+// profile sums the profiles of the files parsed, if --emit=profile
+var (
+	profile   *parser.Profile
+	profileMu sync.Mutex
+)
+
 func ParserTest() {
-	files, err := GetDirFiles(Config.Path + "parser")
+	files, err := parserFiles()
 	if err != nil {
 		panic(err)
 	}
-	if len(Config.Files) > 0 {
-		fs := []FileInfo{}
-		for _, file := range files {
-			if slices.Contains(Config.Files, file.Info.Name()) {
-				fs = append(fs, file)
-			}
-		}
-		files = fs
-	}
 	fmt.Print(log.Sprintf(
 		Divider(),
 		log.Argument{Highlight: true, Format: "*** Parser Test ***\n", Args: []any{}},
@@ -53,12 +62,6 @@ func ParserTest() {
 		Divider(),
 	))
 
-	// Create result directory if it doesn't exist
-	err = os.MkdirAll(Config.Path+"parser/result", os.ModePerm)
-	if err != nil {
-		panic(err)
-	}
-
 	fmt.Print(log.Sprintf(
 		log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Starting tests... !!!\n", Args: []any{}},
 		Divider(),
@@ -67,8 +70,33 @@ func ParserTest() {
 
 	st := time.Now()
 
-	p = parser.NewParser()
-	p.EnsureTable()
+	if p, err = newParser(); err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Grammar Error: %s", Args: []any{err.Error()}}),
+		)
+		fail()
+		return
+	}
+	if Config.Parser.TableCache != "" {
+		cached, err := p.EnsureCachedTable(Config.Parser.TableCache)
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		} else if cached {
+			fmt.Print(log.Sprintf(
+				log.Argument{FrontColor: log.Green, Highlight: true, Format: "!!! Table loaded from %s !!!\n", Args: []any{Config.Parser.TableCache}},
+			))
+		}
+	}
+	// the states beyond the budget leave no table
+	if err = p.EnsureTableContext(context.Background()); err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Grammar Error: %s", Args: []any{err.Error()}}),
+		)
+		fail()
+		return
+	}
 
 	fmt.Print(log.Sprintf(
 		log.Argument{FrontColor: log.Green, Highlight: true, Format: "!!! Parser prepared, consume", Args: []any{}},
@@ -76,29 +104,211 @@ func ParserTest() {
 		log.Argument{FrontColor: log.Green, Highlight: true, Format: "!!!\n", Args: []any{}},
 	))
 
+	if Config.Parser.Strict {
+		err = p.CheckConflicts()
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Grammar Error: %s", Args: []any{err.Error()}}),
+			)
+			fail()
+			return
+		}
+	}
+
+	var closeResults func() error
+	if results, closeResults, err = openResults("parser"); err != nil {
+		panic(err)
+	}
+
+	if slices.Contains(Config.Emit, "items") {
+		err = EmitItems("items.txt")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "dot") {
+		err = EmitDOT("automaton.dot")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "railroad") {
+		err = EmitRailroad("railroad")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "table") && Config.Format == "lab" {
+		err = EmitLabTable("table.txt")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	} else if slices.Contains(Config.Emit, "table") {
+		err = EmitTable("table.json")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "table-csv") {
+		err = EmitTableCSV("table.csv")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "table-html") {
+		err = EmitTableHTML("table.html")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "stats") {
+		err = EmitStats("stats.txt")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "parser") {
+		err = EmitParser("_" + Config.Parser.Package)
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "conflicts") {
+		err = EmitConflicts("conflicts.txt")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "grammar") {
+		err = EmitGrammar("grammar.bnf")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "textmate") {
+		err = EmitTextMate("lab.tmLanguage.json")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "sets") {
+		err = EmitSets("sets.txt")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "symbols") {
+		err = EmitSymbols("symbols.txt")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "ll1") {
+		err = EmitLL1("ll1.txt")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "ambiguity") {
+		err = EmitAmbiguities("ambiguity.txt")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	mu := sync.Mutex{}
+	commands := []parser.CompileCommand{}
+	if slices.Contains(Config.Emit, "lalr") {
+		err = EmitLALR("lalr.txt")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	if slices.Contains(Config.Emit, "profile") {
+		profile = parser.NewProfile()
+	}
+
 	wg := sync.WaitGroup{}
 	wg.Add(len(files))
 	for _, file := range files {
 		go func(file FileInfo) {
 			st := time.Now()
 			defer wg.Done()
-			result, err := os.Create(Config.Path + "parser/result/" + file.Info.Name() + ".result")
+			result, err := results.Create(file.Info.Name() + ".result")
 			if err != nil {
 				panic(err)
 			}
-			defer func(result *os.File) {
+			defer func(result io.Closer) {
 				err := result.Close()
 				if err != nil {
 					panic(err)
 				}
 			}(result)
 			writer := bufio.NewWriter(result)
-			err = StartSingleParserTest(file.Path, writer)
+			command, err := StartSingleParserTest(file.Path, writer)
 			if err != nil {
 				fmt.Println(
 					log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
 				)
 			}
+			diagnostics := parser.DiagnosticsSummary{}
+			if command != nil {
+				command.Output = resultPath(file.Info.Name() + ".result")
+				mu.Lock()
+				commands = append(commands, *command)
+				mu.Unlock()
+				diagnostics = command.Diagnostics
+			}
+			record(file.Path, diagnostics, err)
 
 			err = writer.Flush()
 			if err != nil {
@@ -117,6 +327,28 @@ func ParserTest() {
 	}
 	wg.Wait()
 
+	if profile != nil {
+		err = EmitProfile("profile.txt")
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+		}
+	}
+
+	err = EmitCompileDB("compile_commands.json", commands)
+	if err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+		)
+	}
+
+	if err = closeResults(); err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+		)
+	}
+
 	fmt.Print(log.Sprintf(
 		Divider(),
 		log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! All tests finished !!!\n", Args: []any{}},
@@ -124,10 +356,700 @@ func ParserTest() {
 	))
 }
 
-func StartSingleParserTest(filename string, writer io.Writer) error {
+// EmitItems writes the LR(1) item sets of the parser to the file, in the
+// format of the lab if -format=lab
+func EmitItems(filename string) error {
+	write := p.WriteItems
+	if Config.Format == "lab" {
+		write = p.WriteLabItems
+	}
+	return writeArtifact(results, filename, write)
+}
+
+// EmitDOT writes the LR(1) automaton of the parser to the file, for Graphviz
+func EmitDOT(filename string) error {
+	return writeArtifact(results, filename, p.ExportDOT)
+}
+
+// EmitRailroad writes the railroad diagrams of the grammar of the parser into
+// the folder, one SVG image per nonterminal and index.html showing them all
+func EmitRailroad(dir string) error {
+	for _, head := range p.Grammar.Heads() {
+		name := strings.Map(func(r rune) rune {
+			if r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return '_'
+		}, string(head))
+		err := writeArtifact(results, path.Join(dir, name+".svg"), func(w io.Writer) error {
+			_, err := io.WriteString(w, p.Grammar.RailroadSVG(head))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return writeArtifact(results, path.Join(dir, "index.html"), p.Grammar.WriteRailroad)
+}
+
+// EmitStats writes the report of the states of the automaton and the heat
+// map of its conflicts to the file
+func EmitStats(filename string) error {
+	return writeArtifact(results, filename, p.AutomatonStats().Write)
+}
+
+// EmitConflicts writes the conflicts of the table, the actions competing and
+// the items asking for them, to the file
+func EmitConflicts(filename string) error {
+	return writeArtifact(results, filename, p.ReportConflicts)
+}
+
+// EmitGrammar writes the grammar of the parser in the format
+// -parser--grammar reads to the file
+func EmitGrammar(filename string) error {
+	return writeArtifact(results, filename, p.Grammar.WriteBNF)
+}
+
+// EmitTextMate writes the TextMate grammar of the tokens, those of
+// -lexer--spec if set, to the file
+func EmitTextMate(filename string) error {
+	rules, err := lexerRules()
+	if err != nil {
+		return err
+	}
+	var g *lexer.TextMate
+	if rules == nil {
+		g, err = lexer.TextMateGrammar(nil, "lab")
+	} else {
+		g, err = lexer.TextMateGrammar(rules.Rules, "lab")
+	}
+	if err != nil {
+		return err
+	}
+	g.FileTypes = []string{"lab", "in"}
+	return writeArtifact(results, filename, g.Write)
+}
+
+// EmitSets writes the FIRST and FOLLOW sets of the nonterminals of the grammar to the file
+func EmitSets(filename string) error {
+	return writeArtifact(results, filename, p.Grammar.WriteSets)
+}
+
+// EmitSymbols writes the symbols of the grammar, their categories, names,
+// texts and precedences, to the file
+func EmitSymbols(filename string) error {
+	return writeArtifact(results, filename, p.Grammar.Symbols().Write)
+}
+
+// EmitLL1 writes the LL(1) table of the grammar and its conflicts to the file
+func EmitLL1(filename string) error {
+	return writeArtifact(results, filename, p.Grammar.BuildLL1().Write)
+}
+
+// ambiguitySentences is the number of the shortest sentences of each
+// nonterminal -emit=ambiguity parses.
+const ambiguitySentences = 200
+
+// EmitAmbiguities writes the sentences of the grammar with two parse trees
+// found among the short ones to the file
+func EmitAmbiguities(filename string) error {
+	return writeArtifact(results, filename, func(w io.Writer) error {
+		return p.Grammar.ReportAmbiguities(w, Config.Parser.AmbiguityLength, ambiguitySentences)
+	})
+}
+
+// EmitLALR writes the states of the automaton that would merge under
+// LALR(1) and the conflicts the merges introduce to the file
+func EmitLALR(filename string) error {
+	return writeArtifact(results, filename, p.LALRReport().Write)
+}
+
+// EmitParser writes the standalone parser of the grammar, parser.go and
+// token.go, into the directory, whose name starts with _ so that the go tool
+// leaves it out of this module
+func EmitParser(dir string) error {
+	opts := parser.GenerateOptions{Package: Config.Parser.Package}
+	for _, template := range []struct {
+		file string
+		text *string
+	}{{Config.Parser.DriverTemplate, &opts.Driver}, {Config.Parser.TokenTemplate, &opts.Token}} {
+		if template.file == "" {
+			continue
+		}
+		text, err := os.ReadFile(template.file)
+		if err != nil {
+			return err
+		}
+		*template.text = string(text)
+	}
+	generated, err := p.Generate(opts)
+	if err != nil {
+		return err
+	}
+	for _, file := range []struct {
+		name string
+		text []byte
+	}{{"parser.go", generated.Driver}, {"token.go", generated.Token}} {
+		err := writeArtifact(results, path.Join(dir, file.name), func(w io.Writer) error {
+			_, err := w.Write(file.text)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EmitProfile writes the report of the reductions and the states of the
+// parses of all the files to the file
+func EmitProfile(filename string) error {
+	return writeArtifact(results, filename, func(w io.Writer) error {
+		return profile.Write(w, p.Grammar, 20)
+	})
+}
+
+// EmitTable writes the parsing table to the file, to be compared with the
+// table of another version of the grammar by the compare-tables target
+func EmitTable(filename string) error {
+	return writeArtifact(results, filename, p.Table.WriteJSON)
+}
+
+// EmitLabTable writes the ACTION and GOTO tables of the parser to the file
+// as text, in the format of the lab
+func EmitLabTable(filename string) error {
+	return writeArtifact(results, filename, p.Table.WriteLab)
+}
+
+// EmitTableCSV writes the ACTION and GOTO tables of the parser to the file as CSV
+func EmitTableCSV(filename string) error {
+	return writeArtifact(results, filename, p.Table.WriteCSV)
+}
+
+// EmitTableHTML writes the ACTION and GOTO tables of the parser to the file as
+// an HTML page
+func EmitTableHTML(filename string) error {
+	return writeArtifact(results, filename, func(w io.Writer) error {
+		return p.Table.WriteHTML(w, "LR(1) parsing table")
+	})
+}
+
+// CompareTables reports the differences between the two tables written by
+// --emit=table whose files are given as arguments
+func CompareTables() {
+	if len(Config.Args) != 2 {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Usage: -t compare-tables <before.json> <after.json>", Args: []any{}}),
+		)
+		return
+	}
+	var tables [2]*parser.LRTable
+	for i, filename := range Config.Args {
+		f, err := os.Open(filename)
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+			return
+		}
+		tables[i], err = parser.ReadTable(bufio.NewReader(f))
+		_ = f.Close()
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s: %s", Args: []any{filename, err.Error()}}),
+			)
+			return
+		}
+	}
+	if err := parser.CompareTables(tables[0], tables[1]).Write(os.Stdout); err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+		)
+	}
+}
+
+// Rename renames the symbol at a position of a file, given as the arguments,
+// and prints the rewritten source.
+func Rename() {
+	if len(Config.Args) != 4 {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Usage: -t rename <file> <line> <pos> <name>", Args: []any{}}),
+		)
+		return
+	}
+	line, err1 := strconv.ParseInt(Config.Args[1], 10, 64)
+	pos, err2 := strconv.ParseInt(Config.Args[2], 10, 64)
+	if err := errors.Join(err1, err2); err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Invalid position: %s", Args: []any{err.Error()}}),
+		)
+		return
+	}
+	source, err := readSource(Config.Args[0])
+	if err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+		)
+		return
+	}
+	renamed, err := parser.Analyze(source).Rename(line, pos, Config.Args[3])
+	if err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Cannot rename: %s", Args: []any{err.Error()}}),
+		)
+		return
+	}
+	fmt.Print(renamed)
+}
+
+// Link compiles the files given as arguments as one program, sharing their
+// globals, and prints the linked three-address code.
+func Link() {
+	if len(Config.Args) == 0 {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Usage: -t link <file>...", Args: []any{}}),
+		)
+		return
+	}
+	sources := make([]parser.Source, 0, len(Config.Args))
+	for _, filename := range Config.Args {
+		text, err := readSource(filename)
+		if err != nil {
+			fmt.Println(
+				log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+			)
+			return
+		}
+		sources = append(sources, parser.Source{Name: filename, Text: text})
+	}
+	var err error
+	if p, err = newParser(); err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Grammar Error: %s", Args: []any{err.Error()}}),
+		)
+		return
+	}
+	program, err := p.Tables().CompileProgram(sources, func(name, message string) {
+		if strings.HasPrefix(message, "Error") || strings.HasPrefix(message, "Warning") {
+			fmt.Print(log.Sprintf(log.Argument{FrontColor: log.Yellow, Highlight: true, Format: "%s: %s", Args: []any{name, message}}))
+		}
+	})
+	if err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Compile Error: %s", Args: []any{err.Error()}}),
+		)
+		return
+	}
+	linked, err := program.Link()
+	if err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Link Error: %s", Args: []any{err.Error()}}),
+		)
+		return
+	}
+	code, _, _, err := parser.CompileTAC(linked, Config.Parser.RegAlloc)
+	if err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+		)
+		return
+	}
+	for _, line := range code {
+		fmt.Println(line)
+	}
+}
+
+// ImportANTLR reads the ANTLR grammar whose file is given as argument,
+// prints its productions and the report of the states of its automaton
+func ImportANTLR() {
+	if len(Config.Args) != 1 {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Usage: -t antlr <grammar.g4>", Args: []any{}}),
+		)
+		return
+	}
+	f, err := os.Open(Config.Args[0])
+	if err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+		)
+		return
+	}
+	grammar, err := parser.ReadANTLR(bufio.NewReader(f), nil)
+	_ = f.Close()
+	if err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Grammar Error: %s", Args: []any{err.Error()}}),
+		)
+		return
+	}
+	for _, production := range grammar.Productions {
+		body := make([]string, len(production.Body))
+		for i, symbol := range production.Body {
+			body[i] = string(symbol)
+		}
+		fmt.Printf("%s → %s\n", production.Head, strings.Join(body, " "))
+	}
+	fmt.Println()
+	if report := grammar.Validate(); !report.OK() {
+		fmt.Println(report)
+	}
+	p = &parser.Parser{Grammar: grammar}
+	p.EnsureTable()
+	if err := p.AutomatonStats().Write(os.Stdout); err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+		)
+	}
+}
+
+// parserFiles returns the files of the parser folder, those of -f if set
+func parserFiles() ([]FileInfo, error) {
+	files, err := GetDirFiles(Config.Path + "parser")
+	if err != nil || len(Config.Files) == 0 {
+		return files, err
+	}
+	return slices.DeleteFunc(files, func(file FileInfo) bool {
+		return !slices.Contains(Config.Files, file.Info.Name())
+	}), nil
+}
+
+// newParser returns a parser of the grammar of -parser--grammar, the
+// built-in one if not set, with the limits, the budget and the checks of the
+// flags
+func newParser() (*parser.Parser, error) {
+	p := parser.NewParser()
+	if Config.Parser.Grammar != "" {
+		f, err := os.Open(Config.Parser.Grammar)
+		if err != nil {
+			return nil, err
+		}
+		grammar, err := parser.ReadBNF(bufio.NewReader(f))
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", Config.Parser.Grammar, err)
+		}
+		// a faulty grammar still has a table, the faults are pointed out
+		// before they show as conflicts there
+		if err := grammar.Validate().Err(); err != nil {
+			fmt.Fprintln(os.Stderr,
+				log.Sprintf(log.Argument{FrontColor: log.Yellow, Highlight: true, Format: "!!! Grammar Warning: %s: %s", Args: []any{Config.Parser.Grammar, err.Error()}}),
+			)
+		}
+		// the productions left as they are keep their semantic rules
+		p.Grammar = grammar.AttachRules(p.Grammar)
+	}
+	p.Limits = parser.Limits{MaxDepth: Config.Parser.MaxDepth, MaxSteps: Config.Parser.MaxSteps, MaxErrors: Config.Parser.MaxErrors}
+	p.Checks = parser.Checks{SwitchDefault: Config.Parser.SwitchDefault, NoFallthrough: Config.Parser.NoFallthrough}
+	budget, err := parser.ParseBudget(Config.Budget)
+	if err != nil {
+		return nil, err
+	}
+	if budget != nil {
+		p.Budget = *budget
+	}
+	return p, nil
+}
+
+// resultFile returns the path of the artifact of the file with the suffix in
+// the result folder, as the sink of the artifacts takes it
+func resultFile(filename, suffix string) string {
+	return filepath.Base(filename) + suffix
+}
+
+// resultPath returns the path of the artifact in the result folder from the
+// working directory, or in the archive of -archive
+func resultPath(name string) string {
+	return Config.Path + "parser/result/" + name
+}
+
+// EmitCompileDB merges the commands into the compilation database of the
+// result folder, replacing the entries of the files compiled again and
+// dropping those of the files that no longer exist. The database of an
+// archive has the commands alone
+func EmitCompileDB(filename string, commands []parser.CompileCommand) error {
+	var db parser.CompileDB
+	if _, ok := results.(artifacts.DirSink); ok {
+		if f, err := os.Open(resultPath(filename)); err == nil {
+			db, err = parser.ReadCompileDB(bufio.NewReader(f))
+			_ = f.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", resultPath(filename), err)
+			}
+		}
+	}
+	db = slices.DeleteFunc(db.Merge(commands...), func(c parser.CompileCommand) bool {
+		_, err := os.Stat(filepath.Join(c.Directory, c.File))
+		return err != nil
+	})
+	return writeArtifact(results, filename, db.WriteJSON)
+}
+
+// EmitTrace writes the HTML replay of the parse of the file into the result folder
+func EmitTrace(trace *parser.Trace, filename string) error {
+	return writeArtifact(results, resultFile(filename, ".trace.html"), func(w io.Writer) error {
+		return trace.WriteHTML(w, filepath.Base(filename))
+	})
+}
+
+// EmitTraceText writes the steps of the parse of the file into the result
+// folder, and the rightmost derivation of the file after them if asked and
+// the parse accepted it
+func EmitTraceText(trace *parser.Trace, filename string, derivation bool) error {
+	return writeArtifact(results, resultFile(filename, ".trace.txt"), func(w io.Writer) error {
+		err := trace.WriteText(w)
+		if err == nil && derivation && trace.Accepted() {
+			if _, err = fmt.Fprintln(w); err == nil {
+				err = trace.WriteDerivation(w)
+			}
+		}
+		return err
+	})
+}
+
+// EmitDocs writes the Markdown summary of the documented declarations of the file into the result folder
+func EmitDocs(docs []parser.Doc, filename string) error {
+	return writeArtifact(results, resultFile(filename, ".md"), func(w io.Writer) error {
+		return parser.WriteDocs(w, filepath.Base(filename), docs)
+	})
+}
+
+// EmitTAC writes the three-address code of the file into the result folder,
+// with the temporaries in the registers chosen by the configured allocator
+// and the locals addressed in the stack frame of the program
+func EmitTAC(result *parser.Result, filename string) error {
+	return writeArtifact(results, resultFile(filename, ".tac"), func(w io.Writer) error {
+		for _, line := range result.TAC {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// EmitIR writes the tokens, the tree and the code of the file into the result
+// folder in CBOR, for the tools reading the output of the compiler
+func EmitIR(result *parser.Result, filename string) error {
+	return writeArtifact(results, resultFile(filename, ".ir.cbor"), func(w io.Writer) error {
+		return parser.WriteIR(w, result.IR(filepath.Base(filename)))
+	})
+}
+
+// EmitAST writes the tree of the program the file parses to into the result
+// folder, as ast.Program.Dump prints it
+func EmitAST(program *ast.Program, filename string) error {
+	return writeArtifact(results, resultFile(filename, ".ast.txt"), program.Dump)
+}
+
+// ScopeArchive writes the scopes pruned from the symbol table into the result
+// folder as they are exited, see parser.WriteScope
+type ScopeArchive struct {
+	file   io.WriteCloser
+	writer *bufio.Writer
+}
+
+// NewScopeArchive creates the archive of the scopes of the file
+func NewScopeArchive(filename string) (*ScopeArchive, error) {
+	f, err := results.Create(resultFile(filename, ".scopes.txt"))
+	if err != nil {
+		return nil, err
+	}
+	return &ScopeArchive{file: f, writer: bufio.NewWriter(f)}, nil
+}
+
+// Write archives the scope, which is the archive function of the parse
+func (a *ScopeArchive) Write(scope *parser.Scope) error {
+	return parser.WriteScope(a.writer, scope)
+}
+
+// Close flushes the scopes archived and closes the file
+func (a *ScopeArchive) Close() error {
+	if err := a.writer.Flush(); err != nil {
+		_ = a.file.Close()
+		return err
+	}
+	return a.file.Close()
+}
+
+// EmitQuads writes the code generated for the file into the result folder
+// as quadruples, as ir.Dump prints them or ir.DumpLab if -format=lab,
+// followed by the code ir.Optimize makes of it, folding the integers and
+// analyzing their ranges in the width of int of the walker
+func EmitQuads(walker *parser.Walker, filename string) error {
+	quads := walker.Quads()
+	optimized := ir.Optimize(quads, ir.FoldConstantsIn(walker.IntWidth.Bits()), ir.PruneBranchesIn(walker.IntWidth.Bits()), ir.RemoveUnreachable)
+	dump := ir.Dump
+	if Config.Format == "lab" {
+		dump = func(w io.Writer, quads []ir.Quad) error { return ir.DumpLab(w, quads, ir.LabStart) }
+	}
+	return writeArtifact(results, resultFile(filename, ".quads.txt"), func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Generated code, %d quadruples:\n", len(quads))
+		if err == nil {
+			err = dump(w, quads)
+		}
+		if err == nil {
+			_, err = fmt.Fprintf(w, "\nOptimized code, %d quadruples:\n", len(optimized))
+		}
+		if err == nil {
+			err = dump(w, optimized)
+		}
+		return err
+	})
+}
+
+// EmitMIPS writes the code of the walker as MIPS32 assembly into the result
+// folder, to run in MARS or SPIM.
+func EmitMIPS(walker *parser.Walker, filename string) error {
+	return writeArtifact(results, resultFile(filename, ".s"), func(w io.Writer) error {
+		return codegen.MIPS(w, walker, walker.Quads())
+	})
+}
+
+// EmitAsm writes the code of the walker as assembly of the target of
+// -parser--target-file into the result folder
+func EmitAsm(walker *parser.Walker, filename string) error {
+	if Config.Parser.TargetFile == "" {
+		return fmt.Errorf("-emit=asm needs the description of a target, see -parser--target-file")
+	}
+	file, err := os.Open(Config.Parser.TargetFile)
+	if err != nil {
+		return err
+	}
+	target, err := codegen.ReadTarget(file)
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+	return writeArtifact(results, resultFile(filename, ".asm"), func(w io.Writer) error {
+		return target.Generate(w, walker, walker.Quads())
+	})
+}
+
+// EmitFixed writes the program with the fix-its of its syntax errors applied
+// into the result folder, and the fixes to the writer.
+func EmitFixed(filename string, writer io.Writer) error {
+	source, err := readSource(filename)
+	if err != nil {
+		return err
+	}
+	fixed, fixes, err := parser.Repair(source, p.Tables())
+	if len(fixes) > 0 {
+		// after the error, which ends without a newline
+		_, _ = fmt.Fprintln(writer)
+	}
+	for _, fix := range fixes {
+		_, _ = fmt.Fprintf(writer, "Fix: %v\n", fix)
+	}
+	if err != nil {
+		return err
+	}
+	return writeArtifact(results, resultFile(filename, ".fixed"), func(w io.Writer) error {
+		_, err := io.WriteString(w, fixed)
+		return err
+	})
+}
+
+// EmitDebug writes the debug information of the three-address code written
+// by EmitTAC into the result folder, for the VM debugger
+func EmitDebug(result *parser.Result, filename string) error {
+	return writeArtifact(results, resultFile(filename, ".debug.json"), func(w io.Writer) error {
+		return parser.NewDebugInfo(filepath.Base(filename), result.Walker, result.Frame, result.TAC, result.Lines).WriteJSON(w)
+	})
+}
+
+// EmitSemanticTokens writes the semantic tokens of the file into the result
+// folder, for an editor to highlight it with
+func EmitSemanticTokens(filename string) error {
+	text, err := readSource(filename)
+	if err != nil {
+		return err
+	}
+	return writeArtifact(results, resultFile(filename, ".semantic.json"), func(w io.Writer) error {
+		return parser.WriteSemanticTokens(w, p.Tables().Analyze(text).SemanticTokens())
+	})
+}
+
+// EmitSourceMap writes the source map of the code written by EmitTAC into
+// the result folder, from its instructions to the TAC and the source
+func EmitSourceMap(result *parser.Result, filename string) error {
+	return writeArtifact(results, resultFile(filename, ".map.json"), func(w io.Writer) error {
+		return parser.NewSourceMap(filepath.Base(filename), result).WriteJSON(w)
+	})
+}
+
+// EmitLayout writes the report of where the variables of the file live,
+// scope by scope, into the result folder
+func EmitLayout(result *parser.Result, filename string) error {
+	return writeArtifact(results, resultFile(filename, ".layout.txt"), func(w io.Writer) error {
+		return parser.NewMemoryLayout(result.Walker, result.Frame).Write(w)
+	})
+}
+
+// EmitCost writes the cost of the code of the file as generated and as
+// optimized into the result folder, under the model of -parser--cost-model
+func EmitCost(result *parser.Result, filename string) error {
+	model := parser.DefaultCostModel()
+	if Config.Parser.CostModel != "" {
+		file, err := os.Open(Config.Parser.CostModel)
+		if err != nil {
+			return err
+		}
+		model, err = parser.ReadCostModel(file)
+		_ = file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return writeArtifact(results, resultFile(filename, ".cost.txt"), func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, "Generated code:")
+		if err == nil {
+			err = model.Run(result.Walker.ThreeAddress, nil).Write(w)
+		}
+		if err == nil {
+			_, err = fmt.Fprintln(w, "\nOptimized code:")
+		}
+		if err == nil {
+			err = model.Run(result.TAC, nil).Write(w)
+		}
+		return err
+	})
+}
+
+// EmitLoops writes the report of the loops of the three-address code of the
+// file and their induction variables into the result folder
+func EmitLoops(walker *parser.Walker, filename string) error {
+	return writeArtifact(results, resultFile(filename, ".loops.txt"), func(w io.Writer) error {
+		return parser.WriteLoops(w, parser.PropagateConstants(walker.ThreeAddress))
+	})
+}
+
+// EmitSymbolTable writes every scope of the symbol table of the file in the
+// format, json or html, into the result folder
+func EmitSymbolTable(walker *parser.Walker, filename, format string) error {
+	return writeArtifact(results, resultFile(filename, ".symtab."+format), func(w io.Writer) error {
+		return walker.SymbolTable.Export(w, format)
+	})
+}
+
+// StartSingleParserTest compiles the file, writing the messages of the parse
+// to the writer and the artifacts asked for into the result folder, and
+// returns the entry of the file in the compilation database, nil if the
+// options cannot be honored
+func StartSingleParserTest(filename string, writer io.Writer) (*parser.CompileCommand, error) {
+	directory, _ := os.Getwd()
+	command := &parser.CompileCommand{
+		Directory: directory,
+		File:      filename,
+		Arguments: append(append([]string{os.Args[0]}, Config.Flags...), "-f="+filepath.Base(filename)),
+		Artifacts: []string{},
+	}
 	file, err := mmap.NewMMapReader(filename)
 	if err != nil {
-		panic(err)
+		// fatal for the file alone, the others are compiled still
+		command.Diagnostics = parser.DiagnosticsSummary{Fatal: true, Errors: 1, FirstError: err.Error(), Categories: parser.ErrorCounts{Internal: 1}}
+		_, err = fmt.Fprintf(writer, "Error: %v\n", err)
+		return command, err
 	}
 	defer func(file *mmap.Reader) {
 		err := file.Close()
@@ -135,14 +1057,183 @@ func StartSingleParserTest(filename string, writer io.Writer) error {
 			panic(err)
 		}
 	}(file)
-	l := lexer.NewLexer(file)
-
-	p.Parse(l, func(s string) {
-		_, _ = fmt.Fprint(writer, s)
-	})
+	rules, err := lexerRules()
+	if err != nil {
+		return command, err
+	}
+	opts := parser.Options{
+		Source:    file,
+		Lexer:     rules,
+		Tables:    p.Tables(),
+		Timeout:   Config.Parser.Timeout,
+		Trace:     slices.Contains(Config.Emit, "trace") || Config.Parser.Trace,
+		Profile:   profile != nil,
+		RegAlloc:  Config.Parser.RegAlloc,
+		Lifetimes: Config.Parser.Lifetimes,
+		IntWidth:  parser.IntWidth(Config.Parser.IntWidth),
+		Log: func(s string) {
+			_, _ = fmt.Fprint(writer, s)
+		},
+	}
+	if Config.Parser.Preprocess {
+		text, lines, err := preprocess.File(filename)
+		if err != nil {
+			command.Diagnostics = parser.DiagnosticsSummary{Fatal: true, Errors: 1, FirstError: err.Error(), Categories: parser.ErrorCounts{Internal: 1}}
+			_, err = fmt.Fprintf(writer, "Error: %v\n", err)
+			return command, err
+		}
+		opts.Source, opts.LineMap = strings.NewReader(text), lines
+	}
+	var archive *ScopeArchive
+	if Config.Parser.PruneScopes {
+		if archive, err = NewScopeArchive(filename); err != nil {
+			return command, err
+		}
+		opts.PruneScopes, opts.Archive = true, archive.Write
+	}
+	result, err := parser.Compile(opts)
+	if archive != nil {
+		if closeErr := archive.Close(); err == nil && closeErr != nil {
+			return command, closeErr
+		}
+		command.Artifacts = append(command.Artifacts, resultPath(resultFile(filename, ".scopes.txt")))
+	}
+	if err != nil {
+		return nil, err
+	}
+	command.Diagnostics = result.Summary()
+	if err = writeDiagnostics(result.Collector, filename); err != nil {
+		return command, err
+	}
+	if _, fatal := result.Fatal(); fatal && (result.Encoding.Converted() || result.CRLF) {
+		// after the error, which ends without a newline
+		_, _ = fmt.Fprintln(writer)
+	}
+	if err = writeEncoding(writer, result.Encoding, result.CRLF); err != nil {
+		return command, err
+	}
+	if result.Profile != nil {
+		profileMu.Lock()
+		profile.Merge(result.Profile)
+		profileMu.Unlock()
+	}
+	emit := func(suffix string, write func() error) error {
+		if err := write(); err != nil {
+			return err
+		}
+		command.Artifacts = append(command.Artifacts, resultPath(resultFile(filename, suffix)))
+		return nil
+	}
+	if slices.Contains(Config.Emit, "trace") {
+		err = emit(".trace.html", func() error { return EmitTrace(result.Trace, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if Config.Parser.Trace {
+		err = emit(".trace.txt", func() error { return EmitTraceText(result.Trace, filename, Config.Parser.Derivation) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if slices.Contains(Config.Emit, "doc") {
+		err = emit(".md", func() error { return EmitDocs(result.Walker.Docs, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if slices.Contains(Config.Emit, "semantic") {
+		err = emit(".semantic.json", func() error { return EmitSemanticTokens(filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if slices.Contains(Config.Emit, "ir") {
+		err = emit(".ir.cbor", func() error { return EmitIR(result, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	// no code is compiled after a fatal error, and no assembly after any
+	d, fatal := result.Fatal()
+	errs, _ := result.Collector.Counts()
+	if d.Fix != nil && Config.Parser.Fix {
+		err = emit(".fixed", func() error { return EmitFixed(filename, writer) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if result.Program != nil && slices.Contains(Config.Emit, "ast") {
+		err = emit(".ast.txt", func() error { return EmitAST(result.Program, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if !fatal && slices.Contains(Config.Emit, "tac") {
+		err = emit(".tac", func() error { return EmitTAC(result, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if !fatal && slices.Contains(Config.Emit, "quads") {
+		err = emit(".quads.txt", func() error { return EmitQuads(result.Walker, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if !fatal && errs == 0 && slices.Contains(Config.Emit, "mips") {
+		err = emit(".s", func() error { return EmitMIPS(result.Walker, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if !fatal && errs == 0 && slices.Contains(Config.Emit, "asm") {
+		err = emit(".asm", func() error { return EmitAsm(result.Walker, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if !fatal && slices.Contains(Config.Emit, "debug") {
+		err = emit(".debug.json", func() error { return EmitDebug(result, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if !fatal && slices.Contains(Config.Emit, "map") {
+		err = emit(".map.json", func() error { return EmitSourceMap(result, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if !fatal && slices.Contains(Config.Emit, "layout") {
+		err = emit(".layout.txt", func() error { return EmitLayout(result, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if !fatal && slices.Contains(Config.Emit, "cost") {
+		err = emit(".cost.txt", func() error { return EmitCost(result, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if !fatal && slices.Contains(Config.Emit, "loops") {
+		err = emit(".loops.txt", func() error { return EmitLoops(result.Walker, filename) })
+		if err != nil {
+			return command, err
+		}
+	}
+	if !fatal && slices.Contains(Config.Emit, "symtab") {
+		for _, format := range []string{parser.ExportJSON, parser.ExportHTML} {
+			err = emit(".symtab."+format, func() error { return EmitSymbolTable(result.Walker, filename, format) })
+			if err != nil {
+				return command, err
+			}
+		}
+	}
 	_, err = fmt.Fprintln(writer)
 	if err != nil {
-		return err
+		return command, err
 	}
-	return nil
+	return command, nil
 }
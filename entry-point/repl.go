@@ -0,0 +1,29 @@
+package entrypoint
+
+import (
+	"fmt"
+	"os"
+
+	. "app/config"
+	"app/parser"
+	"app/repl"
+	"app/utils/log"
+)
+
+// REPL reads the statements of a program from stdin one at a time, running
+// the program after each, with the built-in grammar and under the budget of
+// -budget, and restores and saves the session to the workspace of
+// -repl--workspace if given
+func REPL() {
+	fmt.Print(log.Sprintf(
+		log.Argument{Highlight: true, Format: "*** REPL, :help for the commands ***\n", Args: []any{}},
+	))
+	budget, _ := parser.ParseBudget(Config.Budget) // checked with the flags
+	s := &repl.Session{Budget: budget}
+	if err := s.Run(os.Stdin, os.Stdout, Config.REPL.Workspace); err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+		)
+		fail()
+	}
+}
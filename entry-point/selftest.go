@@ -0,0 +1,55 @@
+package entrypoint
+
+import (
+	"fmt"
+
+	"app/selftest"
+	. "app/utils"
+	"app/utils/log"
+)
+
+// SelfTest compiles and runs the example programs built into the binary,
+// with the built-in grammar, and reports those giving something else than
+// expected, a check of the whole toolchain that needs no file
+func SelfTest() {
+	results, err := selftest.Run(nil)
+	if err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+		)
+		fail()
+		return
+	}
+	fmt.Print(log.Sprintf(
+		Divider(),
+		log.Argument{Highlight: true, Format: "*** Self Test of ", Args: []any{}},
+		log.Argument{FrontColor: log.Magenta, Highlight: true, Format: "%d ", Args: []any{len(results)}},
+		log.Argument{Highlight: true, Format: "Programs ***\n", Args: []any{}},
+		Divider(),
+	))
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Println(log.Sprintf(
+				log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! FAIL %s: ", Args: []any{result.Name}},
+				log.Argument{FrontColor: log.Red, Format: "%s", Args: []any{result.Err.Error()}},
+			))
+			continue
+		}
+		fmt.Println(log.Sprintf(
+			log.Argument{FrontColor: log.Green, Highlight: true, Format: "ok %s", Args: []any{result.Name}},
+			log.Argument{Format: " %d ms", Args: []any{result.Duration.Milliseconds()}},
+		))
+	}
+	if failed > 0 {
+		fail()
+		fmt.Print(log.Sprintf(
+			log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! %d of %d programs failed !!!\n", Args: []any{failed, len(results)}},
+		))
+		return
+	}
+	fmt.Print(log.Sprintf(
+		log.Argument{FrontColor: log.Green, Highlight: true, Format: "!!! All the programs passed !!!\n", Args: []any{}},
+	))
+}
@@ -0,0 +1,85 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"app/parser"
+)
+
+// RunSummary sums up a run of the lexer or parser target for the scripts
+// grading it, written to stdout by -summary=json.
+type RunSummary struct {
+	Target   string             `json:"target"`
+	ExitCode int                `json:"exitCode"`
+	Files    int                `json:"files"`
+	Failed   int                `json:"failed"` // the files with errors
+	Errors   parser.ErrorCounts `json:"errors"`
+	Warnings int                `json:"warnings"`
+	Results  []FileSummary      `json:"results"` // sorted by file
+}
+
+// FileSummary sums up the compilation of a file.
+type FileSummary struct {
+	File        string                    `json:"file"`
+	ExitCode    int                       `json:"exitCode"`
+	Diagnostics parser.DiagnosticsSummary `json:"diagnostics"`
+}
+
+var (
+	summary   = RunSummary{Results: []FileSummary{}}
+	summaryMu sync.Mutex
+)
+
+// record adds the file to the summary, the system error, if any, as an
+// internal error of it.
+func record(file string, diagnostics parser.DiagnosticsSummary, err error) {
+	if err != nil && diagnostics.Categories.Internal == 0 {
+		if diagnostics.Errors == 0 {
+			diagnostics.FirstError = err.Error()
+		}
+		diagnostics.Errors++
+		diagnostics.Categories.Internal++
+	}
+	summaryMu.Lock()
+	defer summaryMu.Unlock()
+	code := diagnostics.ExitCode()
+	summary.Results = append(summary.Results, FileSummary{File: file, ExitCode: code, Diagnostics: diagnostics})
+	summary.Files++
+	if code != parser.ExitOK {
+		summary.Failed++
+	}
+	summary.Errors.Merge(diagnostics.Categories)
+	summary.Warnings += diagnostics.Warnings
+	summary.ExitCode = summary.Errors.ExitCode()
+}
+
+// fail records an internal error of the run outside any file, such as a
+// grammar with conflicts in strict mode.
+func fail() {
+	summaryMu.Lock()
+	defer summaryMu.Unlock()
+	summary.Errors.Internal++
+	summary.ExitCode = summary.Errors.ExitCode()
+}
+
+// ExitCode returns the exit code of the run, see parser.ExitOK and the others.
+func ExitCode() int {
+	summaryMu.Lock()
+	defer summaryMu.Unlock()
+	return summary.ExitCode
+}
+
+// WriteSummary writes the summary of the run as JSON.
+func WriteSummary(w io.Writer, target string) error {
+	summaryMu.Lock()
+	defer summaryMu.Unlock()
+	summary.Target = target
+	slices.SortFunc(summary.Results, func(a, b FileSummary) int { return strings.Compare(a.File, b.File) })
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summary)
+}
@@ -0,0 +1,81 @@
+package lexer
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Channel is the channel a token is on. The tokens of the grammar are on the
+// default one, the text the lexer skips for the parser is on the others:
+// whitespace, comments and preprocessor directives, lines starting with #.
+type Channel uint8
+
+const (
+	DefaultChannel Channel = iota
+	WhitespaceChannel
+	CommentChannel
+	PreprocessorChannel
+)
+
+var _ChannelNames = []string{"default", "whitespace", "comments", "preprocessor"}
+
+func (c Channel) String() string {
+	if int(c) < len(_ChannelNames) {
+		return _ChannelNames[c]
+	}
+	return fmt.Sprintf("channel(%d)", c)
+}
+
+// ParseChannel returns the channel of the name String gives it.
+func ParseChannel(name string) (Channel, error) {
+	i := slices.Index(_ChannelNames, name)
+	if i == -1 {
+		return 0, fmt.Errorf("unknown channel %s", name)
+	}
+	return Channel(i), nil
+}
+
+// WithChannels makes the lexer hand out the tokens of the channels besides
+// the ones of the default channel, all in the order of the input, and
+// returns it. A lexer hands out the default channel alone otherwise, as the
+// parser wants, and Span stays the one of the last token of that channel.
+func (l *Lexer) WithChannels(channels ...Channel) *Lexer {
+	for _, channel := range channels {
+		if channel != DefaultChannel {
+			l._channels |= 1 << channel
+		}
+	}
+	return l
+}
+
+// OnChannels returns the tokens on any of the channels, in order.
+func OnChannels(tokens []Token, channels ...Channel) []Token {
+	on := []Token{}
+	for _, token := range tokens {
+		if slices.Contains(channels, token.Channel) {
+			on = append(on, token)
+		}
+	}
+	return on
+}
+
+// pendingToken is a token read but not handed out yet.
+type pendingToken struct {
+	token Token
+	err   error
+}
+
+// hide puts the text skipped on its channel, if the lexer hands it out. The
+// whitespace skipped in a row makes a single token.
+func (l *Lexer) hide(channel Channel, typ ItemType, text string) {
+	if text == "" || l._channels&(1<<channel) == 0 {
+		return
+	}
+	if n := len(l._pending); n > 0 && typ == WHITESPACE && l._pending[n-1].token.Type == WHITESPACE {
+		last := &l._pending[n-1].token
+		last.Val += text
+		last.Line, last.Pos = l._line, l._pos
+		return
+	}
+	l._pending = append(l._pending, pendingToken{token: Token{Type: typ, Val: text, Line: l._line, Pos: l._pos, Channel: channel}})
+}
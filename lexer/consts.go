@@ -2,6 +2,8 @@ package lexer
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 
 	. "app/utils/collections"
 )
@@ -22,6 +24,11 @@ const (
 	PACKAGE
 	IDENTIFIER
 
+	// the text skipped for the parser, on the channels other than the default one
+	WHITESPACE
+	COMMENT
+	PREPROCESSOR
+
 	EXTRA = 0xff
 )
 
@@ -52,6 +59,12 @@ func (i ItemType) ToString() string {
 		return "包"
 	case IDENTIFIER:
 		return "标识符"
+	case WHITESPACE:
+		return "空白"
+	case COMMENT:
+		return "注释"
+	case PREPROCESSOR:
+		return "预处理指令"
 	case EXTRA:
 		return "拓展类型"
 	default:
@@ -87,6 +100,9 @@ const (
 	TypeBool
 	TypeString
 	TypeByte
+	TypeFunc
+	TypePointer
+	TypeVoid
 	ConstantInt
 	ConstantFloat
 	ConstantChar
@@ -155,6 +171,7 @@ const (
 	ReservedWordVar
 	ReservedWordRune
 	ReservedWordWhile
+	ReservedWordStatic
 	Identifier
 )
 
@@ -192,6 +209,12 @@ func (t TokenSpecificType) ToString() string {
 		return "string"
 	case TypeByte:
 		return "byte"
+	case TypeFunc:
+		return "func"
+	case TypePointer:
+		return "pointer"
+	case TypeVoid:
+		return "void"
 	case ConstantInt:
 		return "constant_int"
 	case ConstantFloat:
@@ -326,6 +349,8 @@ func (t TokenSpecificType) ToString() string {
 		return "var"
 	case ReservedWordRune:
 		return "rune"
+	case ReservedWordStatic:
+		return "static"
 	case Identifier:
 		return "identifier"
 	default:
@@ -337,10 +362,20 @@ type Token struct {
 	Type      ItemType
 	Val       string
 	Line, Pos int64
+	Trivia    Trivia
+	Channel   Channel
+	File      string // the file included the token comes from once preprocessed, empty for the source
 
 	_type TokenSpecificType
 }
 
+// Trivia are the comments around a token, with their delimiters: the ones
+// before it, and the ones following it on the same line. Several comments
+// are joined by newlines.
+type Trivia struct {
+	Leading, Trailing string
+}
+
 // SpecificType returns the specific type of the token
 // It is used to determine the specific type of the token, such as int, float, string, etc.
 func (t *Token) SpecificType() TokenSpecificType {
@@ -353,6 +388,13 @@ func (t *Token) String() string {
 	return fmt.Sprintf("(%v, %s)", t.Type, t.Val)
 }
 
+// SizeOf returns the size of a value of the type, as AllocSize does for the
+// token naming it.
+func SizeOf(t TokenSpecificType) int {
+	token := Token{Type: TYPE, _type: t}
+	return token.AllocSize()
+}
+
 // AllocSize returns the size of the token should be allocated in memory
 // It is used to determine the size of the token in memory, such as int, float, string, etc.
 func (t *Token) AllocSize() int {
@@ -392,6 +434,8 @@ func (t *Token) AllocSize() int {
 		return -1 // string is a reference type, so it doesn't have a fixed size
 	case TypeByte:
 		return 1
+	case TypeFunc:
+		return 4 // address of the function
 	}
 	return -1
 }
@@ -430,6 +474,12 @@ func (t *Token) parseType() {
 		t._type = TypeString
 	case "byte":
 		t._type = TypeByte
+	case "func":
+		t._type = TypeFunc
+	case "pointer":
+		t._type = TypePointer
+	case "void":
+		t._type = TypeVoid
 	default:
 		t._type = Unknown
 	}
@@ -588,6 +638,8 @@ func (t *Token) parseReservedWord() {
 		t._type = ReservedWordRune
 	case "while":
 		t._type = ReservedWordWhile
+	case "static":
+		t._type = ReservedWordStatic
 	default:
 		t._type = Unknown
 	}
@@ -636,6 +688,34 @@ var _Delimiters = func() Set[string] {
 
 var _ReservedWords = func() Set[string] {
 	s := NewSet[string]()
-	s.AddAll("break", "case", "chan", "const", "continue", "default", "defer", "do", "else", "false", "for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch", "true", "type", "var", "rune", "while")
+	s.AddAll("break", "case", "chan", "const", "continue", "default", "defer", "do", "else", "false", "for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch", "true", "type", "var", "rune", "while", "static", "module")
 	return s
 }()
+
+// IsReservedWord checks if the word is reserved by the language.
+func IsReservedWord(s string) bool {
+	return _ReservedWords.Contains(s)
+}
+
+// IsOperator checks if the text is an operator of the language.
+func IsOperator(s string) bool {
+	return _Operators.Contains(s)
+}
+
+// IsDelimiter checks if the text is a delimiter of the language.
+func IsDelimiter(s string) bool {
+	return _Delimiters.Contains(s)
+}
+
+// BasicTypes returns the names of the basic types, sorted.
+func BasicTypes() []string {
+	return slices.Sorted(maps.Keys(_BasicType))
+}
+
+// ParseTypeName returns the specific type of a basic type name such as "int",
+// or Unknown if the name is not a basic type.
+func ParseTypeName(name string) TokenSpecificType {
+	t := Token{Type: TYPE, Val: name}
+	t.parseType()
+	return t._type
+}
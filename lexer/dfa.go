@@ -0,0 +1,351 @@
+package lexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	. "app/utils/collections"
+)
+
+// Rule is a class of tokens declared by the pattern matching them, a regular
+// expression of | * + ? ( ), classes such as [a-z] or [^"], . for any rune
+// but a newline, and the escapes \n \t \r \f \v \0 \xHH \d \w \s, their
+// negations \D \W \S, and \pL for the letters of Unicode. Of the rules
+// matching the longest text, the one of the highest priority gives the
+// token, the first of them if several.
+type Rule struct {
+	Name     string `json:"name"` // the type of the tokens, see RuleType
+	Pattern  string `json:"pattern"`
+	Priority int    `json:"priority"`
+}
+
+var _RuleTypes = map[string]ItemType{
+	"type": TYPE, "integer": INTEGER, "float": FLOAT, "string": STRING, "char": CHAR,
+	"operator": OPERATOR, "delimiter": DELIMITER, "reserved": RESERVED, "import": IMPORT,
+	"package": PACKAGE, "identifier": IDENTIFIER,
+	"whitespace": WHITESPACE, "comment": COMMENT, "preprocessor": PREPROCESSOR,
+}
+
+// RuleType returns the type of the tokens of a rule of the name: the one of
+// the same name in lower case, such as identifier, or EXTRA, which the parser
+// reads by its text as it reads the operators. The text of a string or a char
+// is unquoted, and the whitespace, the comments and the preprocessor
+// directives go on their channels.
+func RuleType(name string) ItemType {
+	if t, ok := _RuleTypes[name]; ok {
+		return t
+	}
+	return EXTRA
+}
+
+// ReadRules reads the rules of a JSON array of objects with a name, a pattern
+// and a priority.
+func ReadRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("invalid rules: %v", err)
+	}
+	return rules, nil
+}
+
+// DefaultRules returns the rules of the tokens the hand-written lexer reads,
+// but the preprocessor directives, which no pattern tells at the start of a
+// line, the nested comments and the malformed numbers, which it reports
+// where these patterns fall back to shorter tokens.
+func DefaultRules() []Rule {
+	alternatives := func(words []string) string {
+		slices.Sort(words)
+		for i, word := range words {
+			words[i] = QuoteMeta(word)
+		}
+		return strings.Join(words, "|")
+	}
+	return []Rule{
+		{Name: "whitespace", Pattern: `[ \t\r\n]+`},
+		{Name: "comment", Pattern: `//[^\n]*|/\*([^*]|\*+[^*/])*\*+/`},
+		{Name: "type", Pattern: alternatives(_BasicType.Elements()), Priority: 2},
+		{Name: "reserved", Pattern: alternatives(_ReservedWords.Elements()), Priority: 1},
+		{Name: "identifier", Pattern: `[\pL_][\pL0-9_]*`},
+		{Name: "float", Pattern: `[0-9]+\.[0-9]+([eE][+\-]?[0-9]+)?|[0-9]+[eE][+\-]?[0-9]+`},
+		{Name: "integer", Pattern: `[0-9]+|0[xX][0-9a-fA-F]+`},
+		{Name: "string", Pattern: `"([^"\\\n]|\\.)*"|` + "`[^`]*`"},
+		{Name: "char", Pattern: `'([^'\\\n]|\\.)+'`},
+		{Name: "operator", Pattern: alternatives(_Operators.Elements())},
+		{Name: "delimiter", Pattern: alternatives(_Delimiters.Elements())},
+	}
+}
+
+// DFA is the automaton the rules compile to, by the subset construction of
+// the automaton of their patterns, as a table of the state after each state
+// on each class of runes: the runes no pattern tells apart make a class.
+type DFA struct {
+	Rules []Rule
+
+	types  []ItemType // of the rules
+	bounds []rune     // class c is the runes from bounds[c] up to bounds[c+1]
+	next   [][]int32  // the state after a state on a class, -1 if none
+	accept []int      // the rule a state accepts, -1 if none
+}
+
+// CompileRules compiles the rules into a DFA, failing on a pattern that does
+// not parse or that matches the empty text, which would never end a token.
+func CompileRules(rules []Rule) (*DFA, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules")
+	}
+	n := &nfa{}
+	start := n.add()
+	d := &DFA{Rules: slices.Clone(rules)}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i)
+		}
+		f, err := compilePattern(n, rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		if slices.Contains(n.closure([]int{f.start}), f.end) {
+			return nil, fmt.Errorf("rule %s: pattern %q matches the empty text", rule.Name, rule.Pattern)
+		}
+		n.states[f.end].accept = i
+		n.states[start].empty = append(n.states[start].empty, f.start)
+		d.types = append(d.types, RuleType(rule.Name))
+	}
+
+	bounds := []rune{0}
+	for _, state := range n.states {
+		for _, r := range state.ranges {
+			bounds = append(bounds, r.lo)
+			if r.hi < unicode.MaxRune {
+				bounds = append(bounds, r.hi+1)
+			}
+		}
+	}
+	slices.Sort(bounds)
+	d.bounds = slices.Compact(bounds)
+
+	sets := [][]int{n.closure([]int{start})}
+	index := map[string]int{key(sets[0]): 0}
+	for i := 0; i < len(sets); i++ {
+		row := make([]int32, len(d.bounds))
+		for c, r := range d.bounds {
+			var moved []int
+			for _, s := range sets[i] {
+				if contains(n.states[s].ranges, r) {
+					moved = append(moved, n.states[s].next)
+				}
+			}
+			row[c] = -1
+			if len(moved) == 0 {
+				continue
+			}
+			set := n.closure(moved)
+			k := key(set)
+			j, ok := index[k]
+			if !ok {
+				j = len(sets)
+				index[k] = j
+				sets = append(sets, set)
+			}
+			row[c] = int32(j)
+		}
+		d.next = append(d.next, row)
+		d.accept = append(d.accept, d.best(n, sets[i]))
+	}
+	return d, nil
+}
+
+// closure returns the states reached from the states on nothing, sorted.
+func (n *nfa) closure(states []int) []int {
+	seen := map[int]bool{}
+	stack := NewStack[int]()
+	stack.PushAll(states...)
+	for !stack.IsEmpty() {
+		s, _ := stack.Pop()
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		stack.PushAll(n.states[s].empty...)
+	}
+	closure := make([]int, 0, len(seen))
+	for s := range seen {
+		closure = append(closure, s)
+	}
+	slices.Sort(closure)
+	return closure
+}
+
+func key(states []int) string {
+	var b strings.Builder
+	for _, s := range states {
+		b.WriteString(strconv.Itoa(s))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+func contains(ranges []runeRange, r rune) bool {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].hi >= r })
+	return i < len(ranges) && ranges[i].lo <= r
+}
+
+// best returns the rule of the highest priority the states accept, the first
+// of them if several, -1 if none.
+func (d *DFA) best(n *nfa, states []int) int {
+	best := -1
+	for _, s := range states {
+		rule := n.states[s].accept
+		if rule < 0 {
+			continue
+		}
+		if best < 0 || d.Rules[rule].Priority > d.Rules[best].Priority ||
+			d.Rules[rule].Priority == d.Rules[best].Priority && rule < best {
+			best = rule
+		}
+	}
+	return best
+}
+
+// States returns the number of states of the automaton.
+func (d *DFA) States() int {
+	return len(d.next)
+}
+
+// Classes returns the number of classes of runes its table has a column for.
+func (d *DFA) Classes() int {
+	return len(d.bounds)
+}
+
+// step returns the state after the state on the rune, -1 if none.
+func (d *DFA) step(state int, r rune) int {
+	c := sort.Search(len(d.bounds), func(i int) bool { return d.bounds[i] > r }) - 1
+	return int(d.next[state][c])
+}
+
+// Match returns the rule of the longest prefix of the text the automaton
+// matches and its length in bytes, -1 and 0 if none.
+func (d *DFA) Match(text string) (rule, length int) {
+	rule = -1
+	state := 0
+	for i, r := range text {
+		if state = d.step(state, r); state < 0 {
+			break
+		}
+		if accept := d.accept[state]; accept >= 0 {
+			rule, length = accept, i+utf8.RuneLen(r)
+		}
+	}
+	return rule, length
+}
+
+// NewDFALexer creates a Lexer of the tokens the rules of the automaton match,
+// instead of the ones of the hand-written lexer. The comments after a token
+// on its line are not its trailing trivia, they lead the next one.
+func NewDFALexer(r io.Reader, d *DFA) *Lexer {
+	l := NewLexer(r)
+	l._dfa = d
+	return l
+}
+
+// scanned is a rune the automaton read but did not consume yet.
+type scanned struct {
+	r    rune
+	size int
+}
+
+// peekRune returns the rune i runes after the ones consumed, reading it.
+func (l *Lexer) peekRune(i int) (rune, error) {
+	for len(l._scanned) <= i {
+		r, size, err := l._reader.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		l._scanned = append(l._scanned, scanned{r, size})
+	}
+	return l._scanned[i].r, nil
+}
+
+// consume consumes the first n runes read and returns their text.
+func (l *Lexer) consume(n int) string {
+	var b strings.Builder
+	for _, s := range l._scanned[:n] {
+		b.WriteRune(s.r)
+		l.advance(s.r, s.size)
+	}
+	l._scanned = l._scanned[n:]
+	return b.String()
+}
+
+// match reads the token of the longest text the automaton matches next,
+// putting the whitespace, the comments and the directives on their channels.
+func (l *Lexer) match() (Token, error) {
+	for {
+		l._start, l._startLine, l._startPos = l._offset, l._line, l._pos
+		if _, err := l.peekRune(0); err != nil {
+			if err == io.EOF {
+				return Token{Type: EOF}, nil
+			}
+			return Token{}, err
+		}
+		rule, length := -1, 0
+		for i, state := 0, 0; ; i++ {
+			r, err := l.peekRune(i)
+			if err != nil && err != io.EOF {
+				return Token{}, err
+			}
+			if err != nil {
+				break
+			}
+			if state = l._dfa.step(state, r); state < 0 {
+				break
+			}
+			if accept := l._dfa.accept[state]; accept >= 0 {
+				rule, length = accept, i+1
+			}
+		}
+		if rule < 0 {
+			text := l.consume(1)
+			return Token{}, fmt.Errorf("unknown character: %s, at line %d, pos %d", text, l._line, l._pos)
+		}
+		text := l.consume(length)
+		typ := l._dfa.types[rule]
+		switch typ {
+		case WHITESPACE:
+			l.hide(WhitespaceChannel, typ, text)
+			continue
+		case COMMENT:
+			l._leading = append(l._leading, text)
+			l.hide(CommentChannel, typ, text)
+			continue
+		case PREPROCESSOR:
+			l.hide(PreprocessorChannel, typ, text)
+			continue
+		case STRING, CHAR:
+			text = unquote(text)
+		}
+		return Token{Type: typ, Val: text, Line: l._line, Pos: l._pos}, nil
+	}
+}
+
+// unquote returns the text between the quotes of a string or a char literal,
+// its escapes replaced if they are those of Go.
+func unquote(text string) string {
+	if s, err := strconv.Unquote(text); err == nil {
+		return s
+	}
+	runes := []rune(text)
+	if len(runes) < 2 {
+		return text
+	}
+	return string(runes[1 : len(runes)-1])
+}
@@ -0,0 +1,146 @@
+package lexer_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"app/lexer"
+)
+
+func TestCompileRules(t *testing.T) {
+	d, err := lexer.CompileRules([]lexer.Rule{
+		{Name: "identifier", Pattern: `[a-z]+`},
+		{Name: "reserved", Pattern: `if|while`, Priority: 1},
+		{Name: "integer", Pattern: `\d+`},
+		{Name: "number", Pattern: `\d+`},
+		{Name: "string", Pattern: `"[^"\n]*"`},
+		{Name: "arrow", Pattern: `-+>|<-+`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		text   string
+		rule   string
+		length int
+	}{
+		{"if (", "reserved", 2},
+		{"iffy", "identifier", 4}, // the longest text before the priority
+		{"while1", "reserved", 5},
+		{"42x", "integer", 2}, // the first of the same priority
+		{`"a b" c`, "string", 5},
+		{`"a`, "", 0},
+		{"--->x", "arrow", 4},
+		{"<--", "arrow", 3},
+		{"->", "arrow", 2},
+		{"-", "", 0},
+		{"é", "", 0},
+	} {
+		rule, length := d.Match(tc.text)
+		name := ""
+		if rule >= 0 {
+			name = d.Rules[rule].Name
+		}
+		if name != tc.rule || length != tc.length {
+			t.Errorf("%q: expected %q of %d bytes, got %q of %d", tc.text, tc.rule, tc.length, name, length)
+		}
+	}
+	if d.Classes() > 32 {
+		t.Errorf("Expected the runes the patterns do not tell apart to share their classes, got %d", d.Classes())
+	}
+
+	for pattern, expected := range map[string]string{
+		`a(b`:    "( not closed",
+		`a)`:     "unexpected )",
+		`*a`:     "nothing to repeat",
+		`[a-`:    "[ not closed",
+		`[z-a]`:  "out of order",
+		`\q`:     "unknown escape",
+		`a?`:     "matches the empty text",
+		`(|a)b*`: "matches the empty text",
+	} {
+		if _, err := lexer.CompileRules([]lexer.Rule{{Name: "x", Pattern: pattern}}); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected %q, got %v", pattern, expected, err)
+		}
+	}
+}
+
+func TestReadRules(t *testing.T) {
+	rules, err := lexer.ReadRules(strings.NewReader(`[{"name": "identifier", "pattern": "[a-z]+", "priority": 1}]`))
+	if err != nil || len(rules) != 1 || rules[0] != (lexer.Rule{Name: "identifier", Pattern: "[a-z]+", Priority: 1}) {
+		t.Errorf("Expected the rule of identifiers, got %v, %v", rules, err)
+	}
+	if _, err := lexer.ReadRules(strings.NewReader(`[{"name": "x", "regex": "a"}]`)); err == nil {
+		t.Error("Expected an unknown field to fail")
+	}
+}
+
+// lex returns the tokens of the lexer, and the errors, as (type, value).
+func lex(l *lexer.Lexer) (tokens []string, errs []string) {
+	for {
+		token, err := l.Next()
+		if err != nil && !errors.Is(err, io.EOF) {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if token.Type == lexer.EOF {
+			return tokens, errs
+		}
+		tokens = append(tokens, "("+token.Type.ToString()+", "+token.Val+")")
+		if errors.Is(err, io.EOF) {
+			return tokens, errs
+		}
+	}
+}
+
+func TestNewDFALexer(t *testing.T) {
+	d, err := lexer.CompileRules(lexer.DefaultRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const src = `// a program
+int main() {
+	float f = 2.5e-3; /* a comment
+	over two lines */ string s = "a \"quoted\" word";
+	byte c = '\x41';
+	while (i <= 10 && !done) { i++; x = 0x1F >> 2; }
+	return iffy;
+}`
+	tokens, errs := lex(lexer.NewDFALexer(strings.NewReader(src), d))
+	expected, expectedErrs := lex(lexer.NewLexer(strings.NewReader(src)))
+	if len(errs) != 0 || len(expectedErrs) != 0 {
+		t.Fatalf("Expected no errors, got %v and %v", errs, expectedErrs)
+	}
+	if strings.Join(tokens, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected the tokens of the hand-written lexer\n%v\ngot\n%v", expected, tokens)
+	}
+
+	l := lexer.NewDFALexer(strings.NewReader("a @ b /* c */ d"), d).WithChannels(lexer.CommentChannel)
+	tokens, errs = lex(l)
+	if len(errs) != 1 || errs[0] != "unknown character: @, at line 0, pos 4" {
+		t.Errorf("Expected @ to be unknown, got %v", errs)
+	}
+	if strings.Join(tokens, " ") != "(标识符, a) (标识符, b) (注释, /* c */) (标识符, d)" {
+		t.Errorf("Expected the comment on its channel, got %v", tokens)
+	}
+
+	l = lexer.NewDFALexer(strings.NewReader("x\n  y z"), d)
+	for _, expected := range []struct {
+		val        string
+		line, pos  int64
+		start, end int64
+	}{{"x", 0, 2, 0, 1}, {"y", 1, 3, 4, 5}, {"z", 1, 5, 6, 7}} {
+		if expected.val == "y" {
+			if token, _ := l.Peek(2); token.Val != "z" {
+				t.Errorf("Expected to peek z, got %v", token)
+			}
+		}
+		token, err := l.Next()
+		start, end := l.Span()
+		if err != nil || token.Val != expected.val || token.Line != expected.line || token.Pos != expected.pos || start != expected.start || end != expected.end {
+			t.Errorf("Expected %s at line %d, pos %d, [%d, %d), got %v, [%d, %d), %v", expected.val, expected.line, expected.pos, expected.start, expected.end, token, start, end, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package lexer
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Span is where a token is in the text, the bytes [Start, End), with the line
+// and pos the lexer was at when the token started.
+type Span struct {
+	Start, End int
+	Line, Pos  int64
+}
+
+// Edit replaces the bytes [Start, End) of the text with Text.
+type Edit struct {
+	Start, End int
+	Text       string
+}
+
+// DocumentError is an error met while lexing, at the given byte offset.
+type DocumentError struct {
+	Offset int
+	Err    error
+}
+
+func (e DocumentError) Error() string {
+	return e.Err.Error()
+}
+
+// Document is a tokenized text that is lexed again incrementally on edits.
+// Only the tokens from the edited region up to the first token that starts
+// at the same place and reads the same as before are lexed again, the ones
+// after it are reused with their spans, lines and positions shifted. The
+// spans are in order, so tokens and lines are found by binary search.
+type Document struct {
+	Text   string
+	Tokens []Token
+	Spans  []Span
+	Errors []DocumentError
+
+	lines []int // offset of the start of each line
+}
+
+// NewDocument lexes the text into a Document.
+func NewDocument(text string) *Document {
+	d := &Document{}
+	d.lex(text, Span{Line: 0, Pos: 1}, "", nil)
+	return d
+}
+
+// Lexer returns a Lexer replaying the tokens of the document.
+func (d *Document) Lexer() *Lexer {
+	return NewReplayLexer(d.Tokens)
+}
+
+// Apply applies the edit to the text and updates the tokens.
+func (d *Document) Apply(e Edit) error {
+	if e.Start < 0 || e.Start > e.End || e.End > len(d.Text) {
+		return fmt.Errorf("invalid edit [%d, %d) of a text of %d bytes", e.Start, e.End, len(d.Text))
+	}
+	text := d.Text[:e.Start] + e.Text + d.Text[e.End:]
+
+	// keep the tokens ending before the edit, but the last one, as where it
+	// ends may depend on what follows, and lex again from its start
+	keep := 0
+	for keep < len(d.Spans) && d.Spans[keep].End < e.Start {
+		keep++
+	}
+	keep = max(keep-1, 0)
+	from, leading := Span{Line: 0, Pos: 1}, ""
+	if keep > 0 {
+		// the comments before it are not read again
+		from, leading = d.Spans[keep], d.Tokens[keep].Trivia.Leading
+	}
+
+	old := *d
+	d.Tokens = d.Tokens[:keep:keep]
+	d.Spans = d.Spans[:keep:keep]
+	errs := 0
+	for errs < len(d.Errors) && d.Errors[errs].Offset < from.Start {
+		errs++
+	}
+	d.Errors = d.Errors[:errs:errs]
+	d.lex(text, from, leading, &resync{old: &old, edit: e, next: keep})
+	return nil
+}
+
+// resync finds where the tokens lexed after an edit meet the old ones again.
+type resync struct {
+	old  *Document
+	edit Edit
+	next int // first old token that may be reused
+}
+
+// match returns the index of the old token the new token at the span reads
+// the same as, or -1 if there is none.
+func (r *resync) match(token Token, span Span) int {
+	delta := len(r.edit.Text) - (r.edit.End - r.edit.Start)
+	if span.Start < r.edit.Start+len(r.edit.Text) {
+		return -1
+	}
+	for r.next < len(r.old.Spans) && r.old.Spans[r.next].Start+delta < span.Start {
+		r.next++
+	}
+	if r.next == len(r.old.Spans) || r.old.Spans[r.next].Start+delta != span.Start || r.old.Spans[r.next].Start < r.edit.End {
+		return -1
+	}
+	if t := r.old.Tokens[r.next]; t.Type != token.Type || t.Val != token.Val || t.Trivia != token.Trivia {
+		return -1
+	}
+	// errors cannot be moved, their messages hold lines and positions
+	for _, err := range r.old.Errors {
+		if err.Offset >= r.old.Spans[r.next].Start {
+			return -1
+		}
+	}
+	return r.next
+}
+
+// lex lexes the text from the start of the span on and appends to the
+// document, the first token gets the leading comments given. With resync, it
+// stops at the first token matching an old one and appends the old tokens
+// from there on, shifted.
+func (d *Document) lex(text string, from Span, leading string, r *resync) {
+	d.Text = text
+	d.lines = []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+	l := NewLexerAt(strings.NewReader(text[from.Start:]), from.Start, from.Line, from.Pos)
+	if leading != "" {
+		l._leading = append(l._leading, leading)
+	}
+	for {
+		token, err := l.NextToken()
+		start, end := l.Span()
+		span := Span{Start: int(start), End: int(end), Line: l._startLine, Pos: l._startPos}
+		if err != nil && !errors.Is(err, io.EOF) {
+			d.Errors = append(d.Errors, DocumentError{Offset: span.Start, Err: err})
+		}
+		if token.Type == EOF {
+			return
+		}
+		if r != nil && err == nil {
+			if j := r.match(token, span); j != -1 {
+				d.splice(r.old, j, span)
+				return
+			}
+		}
+		if token.Type != 0 {
+			d.Tokens = append(d.Tokens, token)
+			d.Spans = append(d.Spans, span)
+		}
+		if errors.Is(err, io.EOF) {
+			return
+		}
+	}
+}
+
+// splice appends the old tokens from j on, moved to start at the span.
+func (d *Document) splice(old *Document, j int, span Span) {
+	from := old.Spans[j]
+	delta := span.Start - from.Start
+	lines, pos := span.Line-from.Line, span.Pos-from.Pos
+	for k := j; k < len(old.Tokens); k++ {
+		token, s := old.Tokens[k], old.Spans[k]
+		// only the positions on the line of the first token move
+		if token.Line == from.Line {
+			token.Pos += pos
+		}
+		if s.Line == from.Line {
+			s.Pos += pos
+		}
+		token.Line += lines
+		s.Line += lines
+		s.Start += delta
+		s.End += delta
+		d.Tokens = append(d.Tokens, token)
+		d.Spans = append(d.Spans, s)
+	}
+}
+
+// TokenAt returns the index of the token covering the byte at the offset,
+// -1 and false if the byte is between tokens.
+func (d *Document) TokenAt(offset int) (int, bool) {
+	if i, j := d.TokensIn(offset, offset+1); i < j {
+		return i, true
+	}
+	return -1, false
+}
+
+// TokensIn returns the tokens [i, j) overlapping the bytes [start, end) of
+// the text, i == j if there are none.
+func (d *Document) TokensIn(start, end int) (int, int) {
+	// the first span ending after start, then the first one starting at end or later
+	i, _ := slices.BinarySearchFunc(d.Spans, start+1, func(s Span, t int) int { return cmp.Compare(s.End, t) })
+	if end <= start {
+		return i, i
+	}
+	j, _ := slices.BinarySearchFunc(d.Spans[i:], end, func(s Span, t int) int { return cmp.Compare(s.Start, t) })
+	return i, i + j
+}
+
+// Offset returns the offset of the position in the text, lines counted from
+// 0 and positions from 1 like the lexer does.
+func (d *Document) Offset(line, pos int64) (int, error) {
+	if line < 0 || line >= int64(len(d.lines)) {
+		return -1, fmt.Errorf("line %d is past the end of the source", line)
+	}
+	return d.lines[line] + int(pos) - 1, nil
+}
+
+// Position returns the line and pos of the offset, counted the way Offset
+// takes them.
+func (d *Document) Position(offset int) (line, pos int64) {
+	i, found := slices.BinarySearch(d.lines, offset)
+	if !found {
+		i--
+	}
+	return int64(i), int64(offset-d.lines[i]) + 1
+}
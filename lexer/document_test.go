@@ -0,0 +1,149 @@
+package lexer_test
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"app/lexer"
+)
+
+const documentSource = `{
+	int a; float b[10];
+	// a comment
+	a = a + 1; /* another
+	one */ b[0] = 1.5;
+	while (a <= 100) { a = a * 2; }
+	string s; s = "x\ty";
+}
+`
+
+func sameDocument(t *testing.T, got, expected *lexer.Document) {
+	t.Helper()
+	if got.Text != expected.Text {
+		t.Fatalf("Expected text %q, got %q", expected.Text, got.Text)
+	}
+	if !slices.Equal(got.Tokens, expected.Tokens) {
+		t.Fatalf("Text %q: expected tokens %v, got %v", got.Text, expected.Tokens, got.Tokens)
+	}
+	if !slices.Equal(got.Spans, expected.Spans) {
+		t.Fatalf("Text %q: expected spans %v, got %v", got.Text, expected.Spans, got.Spans)
+	}
+	if len(got.Errors) != len(expected.Errors) {
+		t.Fatalf("Text %q: expected errors %v, got %v", got.Text, expected.Errors, got.Errors)
+	}
+}
+
+func TestDocument_Apply(t *testing.T) {
+	tests := []struct {
+		name string
+		edit lexer.Edit
+	}{
+		{name: "Rename", edit: lexer.Edit{Start: 8, End: 9, Text: "count"}},
+		{name: "Merge", edit: lexer.Edit{Start: 9, End: 11, Text: ""}},
+		{name: "NewLine", edit: lexer.Edit{Start: 9, End: 9, Text: ";\n\tint c"}},
+		{name: "OpenComment", edit: lexer.Edit{Start: 3, End: 3, Text: "/*"}},
+		{name: "CloseComment", edit: lexer.Edit{Start: 55, End: 55, Text: "*/"}},
+		{name: "OpenString", edit: lexer.Edit{Start: 26, End: 26, Text: `"`}},
+		{name: "Append", edit: lexer.Edit{Start: len(documentSource), End: len(documentSource), Text: "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := lexer.NewDocument(documentSource)
+			if err := d.Apply(tt.edit); err != nil {
+				t.Fatal(err)
+			}
+			sameDocument(t, d, lexer.NewDocument(documentSource[:tt.edit.Start]+tt.edit.Text+documentSource[tt.edit.End:]))
+		})
+	}
+
+	if err := lexer.NewDocument("a").Apply(lexer.Edit{Start: 0, End: 2}); err == nil {
+		t.Errorf("Expected an error for an edit out of the text")
+	}
+}
+
+func TestDocument_ApplyRandom(t *testing.T) {
+	pieces := []string{"a", "1", " ", "\n", ";", "+", "=", "/", "*", "\"", "{", "int ", "0x1f", ".5"}
+	rng := rand.New(rand.NewSource(1))
+	d := lexer.NewDocument(documentSource)
+	for range 500 {
+		start := rng.Intn(len(d.Text) + 1)
+		end := min(start+rng.Intn(4), len(d.Text))
+		text := ""
+		for range rng.Intn(3) {
+			text += pieces[rng.Intn(len(pieces))]
+		}
+		if err := d.Apply(lexer.Edit{Start: start, End: end, Text: text}); err != nil {
+			t.Fatal(err)
+		}
+		sameDocument(t, d, lexer.NewDocument(d.Text))
+	}
+}
+
+func TestNewReplayLexer(t *testing.T) {
+	d := lexer.NewDocument("int a;")
+	l := d.Lexer()
+	for _, expected := range d.Tokens {
+		if token, err := l.NextToken(); err != nil || token != expected {
+			t.Errorf("Expected %v, got %v, %v", expected, token, err)
+		}
+	}
+	if token, _ := l.NextToken(); token.Type != lexer.EOF {
+		t.Errorf("Expected EOF, got %v", token)
+	}
+}
+
+func TestDocument_TokenAt(t *testing.T) {
+	d := lexer.NewDocument(documentSource)
+	for offset := range len(d.Text) {
+		expected := slices.IndexFunc(d.Spans, func(s lexer.Span) bool { return s.Start <= offset && offset < s.End })
+		if i, ok := d.TokenAt(offset); i != expected || ok != (expected >= 0) {
+			t.Fatalf("Expected token %d at offset %d, got %d", expected, offset, i)
+		}
+		line, pos := d.Position(offset)
+		if back, err := d.Offset(line, pos); err != nil || back != offset {
+			t.Fatalf("Expected line %d, pos %d to be offset %d, got %d, %v", line, pos, offset, back, err)
+		}
+		if i, ok := d.TokenAt(offset); ok && line != d.Spans[i].Line {
+			t.Fatalf("Expected token %d at line %d, got line %d", i, d.Spans[i].Line, line)
+		}
+	}
+	if _, err := d.Offset(100, 1); err == nil {
+		t.Errorf("Expected a line past the end to be an error")
+	}
+
+	// "a = a + 1;" on line 3
+	start, _ := d.Offset(3, 2)
+	i, j := d.TokensIn(start, start+7)
+	if got := d.Tokens[i:j]; len(got) != 4 || got[0].Val != "a" || got[1].Val != "=" || got[3].Val != "+" {
+		t.Errorf("Expected the tokens overlapping the range, got %v", got)
+	}
+	if i, j := d.TokensIn(start, start); i != j {
+		t.Errorf("Expected no token in an empty range, got [%d, %d)", i, j)
+	}
+
+	if err := d.Apply(lexer.Edit{Start: start, End: start + 1, Text: "count\n\t"}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if i, ok := d.TokenAt(start + 2); !ok || d.Tokens[i].Val != "count" {
+		t.Errorf("Expected the edited token, got %d", i)
+	}
+	if line, pos := d.Position(start + 7); line != 4 || pos != 2 {
+		t.Errorf("Expected the lines to follow the edit, got line %d, pos %d", line, pos)
+	}
+}
+
+func TestDocument_Spans(t *testing.T) {
+	// the operators are read a rune ahead and given back, the spans stay on them
+	for _, src := range []string{"x+", "a=b\n", "a==b;", "a <= -b", "x+\n"} {
+		d := lexer.NewDocument(src)
+		for i, span := range d.Spans {
+			if text := d.Text[span.Start:span.End]; text != d.Tokens[i].Val {
+				t.Errorf("Text %q: expected span %d to be %q, got %q", src, i, d.Tokens[i].Val, text)
+			}
+			if line, pos := d.Position(span.Start); line != span.Line || pos != span.Pos {
+				t.Errorf("Text %q: expected span %d at line %d, pos %d, got line %d, pos %d", src, i, line, pos, span.Line, span.Pos)
+			}
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package lexer
+
+import (
+	"fmt"
+
+	"app/utils"
+)
+
+// The columns of the tokens in the format of the lab: the number of the
+// token from 1, the word, and its category.
+const (
+	LabNumberColumns = 8
+	LabWordColumns   = 24
+)
+
+// LabHeader returns the header of the tokens in the format of the lab.
+func LabHeader() string {
+	return utils.PadRight("序号", LabNumberColumns) + utils.PadRight("单词", LabWordColumns) + "种别"
+}
+
+// FormatLab returns the token numbered n in the format of the lab, its word
+// before its category as the tuples of the lab, aligned under LabHeader. The
+// words of the channels other than the default one are quoted to stay on the
+// line.
+func FormatLab(n int, t Token) string {
+	word := t.Val
+	if t.Channel != DefaultChannel {
+		word = fmt.Sprintf("%q", t.Val)
+	}
+	// a word too long for its column is still followed by a space
+	return utils.PadRight(fmt.Sprint(n), LabNumberColumns) + utils.PadRight(word+" ", LabWordColumns) + t.Type.ToString()
+}
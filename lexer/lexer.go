@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"unicode"
@@ -16,9 +17,53 @@ import (
 var _PrintNoBufferReaderOnce = atomic.Bool{}
 
 type Lexer struct {
-	_reader      io.RuneScanner
-	_line, _pos  int64
-	_lineLengths []int64
+	_reader     io.RuneScanner
+	_line, _pos int64
+	// the length of the line before the current one, which retract goes back to
+	_lastLineLength int64
+
+	// byte offsets of the reader, and of the last token with the line and pos at its start
+	_offset               int64
+	_lastSize             int
+	_start, _end          int64
+	_startLine, _startPos int64
+
+	// comments read since the last token
+	_leading []string
+
+	// bits of the channels handed out besides the default one, see WithChannels,
+	// and the tokens read but not handed out yet
+	_channels uint8
+	_pending  []pendingToken
+
+	// the first line a directive may start on, the one after the last token
+	_directiveLine int64
+
+	// tokens handed out instead of reading, see NewReplayLexer
+	_replay    []Token
+	_replaying bool
+
+	// an error of the comments after the last token, handed out with the next one
+	_err error
+
+	// the tokens Peek read ahead of the one handed out, a ring from _head
+	_ahead      [Lookahead]lookahead
+	_head, _len int
+
+	// the automaton of the rules the tokens are read by, see NewDFALexer, and
+	// the runes it read ahead of the token
+	_dfa     *DFA
+	_scanned []scanned
+}
+
+// Lookahead is the number of tokens Peek can read ahead.
+const Lookahead = 8
+
+// lookahead is a token read ahead, with its span.
+type lookahead struct {
+	token      Token
+	err        error
+	start, end int64
 }
 
 // NewLexer creates a new Lexer instance with the given io.Reader.
@@ -33,24 +78,126 @@ func NewLexer(r io.Reader) *Lexer {
 	}
 	if config.Config.Lexer.UsingNoBufferedReader {
 		reader = bufio.NewReaderSize(r, 16)
+	} else {
+		reader = bufio.NewReader(r)
 	}
-	reader = bufio.NewReader(r)
 	return &Lexer{
-		_reader:      reader,
-		_line:        0,
-		_pos:         1,
-		_lineLengths: []int64{},
+		_reader: reader,
+		_line:   0,
+		_pos:    1,
 	}
 }
 
-// NextToken reads the next token from the input stream and returns it.
+// NewLexerAt creates a Lexer for input that starts at the byte offset of a
+// text, where a lexer reading the whole text was at the given line and pos.
+func NewLexerAt(r io.Reader, offset int, line, pos int64) *Lexer {
+	l := NewLexer(r)
+	l._offset = int64(offset)
+	l._line, l._pos = line, pos
+	return l
+}
+
+// NewReplayLexer creates a Lexer that hands out the tokens lexed before, e.g.
+// the ones of a Document, followed by EOF.
+func NewReplayLexer(tokens []Token) *Lexer {
+	return &Lexer{_replay: tokens, _replaying: true}
+}
+
+// Span returns the byte offsets [start, end) of the last token read.
+func (l *Lexer) Span() (start, end int64) {
+	return l._start, l._end
+}
+
+// Next returns the next token, the first of those Peek read ahead if any,
+// reading the input as far as the token goes and no further, so that the
+// parser pulls the tokens of a long input as it needs them. The input is read
+// through a buffer of 4 KiB, 16 bytes with -lexer--no-buffered, and the
+// lexer keeps none of the text it read but the tokens it hands out.
+func (l *Lexer) Next() (Token, error) {
+	if l._len == 0 {
+		return l.read()
+	}
+	a := l._ahead[l._head]
+	l._ahead[l._head] = lookahead{}
+	l._head = (l._head + 1) % Lookahead
+	l._len--
+	l._start, l._end = a.start, a.end
+	return a.token, a.err
+}
+
+// NextToken reads the next token, see Next.
 func (l *Lexer) NextToken() (Token, error) {
+	return l.Next()
+}
+
+// Peek returns the n-th token after the one handed out last, from 1 to
+// Lookahead, without handing it out: Next returns it, with its error, once
+// the n-1 tokens before it are. Past the end of the input, it is EOF.
+func (l *Lexer) Peek(n int) (Token, error) {
+	if n < 1 || n > Lookahead {
+		return Token{}, fmt.Errorf("cannot peek %d tokens ahead, 1 to %d", n, Lookahead)
+	}
+	// the span stays the one of the token handed out last
+	start, end := l._start, l._end
+	for l._len < n {
+		token, err := l.read()
+		l._ahead[(l._head+l._len)%Lookahead] = lookahead{token: token, err: err, start: l._start, end: l._end}
+		l._len++
+	}
+	l._start, l._end = start, end
+	a := l._ahead[(l._head+n-1)%Lookahead]
+	return a.token, a.err
+}
+
+// read reads the next token from the input stream and returns it.
+func (l *Lexer) read() (Token, error) {
+	if len(l._pending) > 0 {
+		next := l._pending[0]
+		l._pending = l._pending[1:]
+		return next.token, next.err
+	}
+	if l._err != nil {
+		err := l._err
+		l._err = nil
+		return Token{Type: EOF}, err
+	}
+	if l._replaying {
+		if len(l._replay) == 0 {
+			return Token{Type: EOF}, nil
+		}
+		token := l._replay[0]
+		l._replay = l._replay[1:]
+		return token, nil
+	}
 	if l._reader == nil {
 		return Token{}, fmt.Errorf("lexer is not initialized")
 	}
-	token, err := l.nextToken()
+	var token Token
+	var err error
+	if l._dfa != nil {
+		token, err = l.match()
+	} else {
+		token, err = l.nextToken()
+	}
 	token.parse()
-	return token, err
+	token.Trivia.Leading = strings.Join(l._leading, "\n")
+	l._leading = l._leading[:0]
+	l._end = l._offset
+	l._directiveLine = l._line + 1
+	if l._channels == 0 {
+		if err == nil && token.Type != EOF && l._dfa == nil {
+			token.Trivia.Trailing = l.readTrailing()
+		}
+		return token, err
+	}
+	// the token goes between the tokens of the other channels read before and after it
+	i := len(l._pending)
+	l._pending = append(l._pending, pendingToken{})
+	if err == nil && token.Type != EOF && l._dfa == nil {
+		token.Trivia.Trailing = l.readTrailing()
+	}
+	l._pending[i] = pendingToken{token: token, err: err}
+	return l.read()
 }
 
 // nextToken is a helper function that reads the next token from the input stream.
@@ -60,6 +207,7 @@ func (l *Lexer) nextToken() (Token, error) {
 	if errors.Is(err, io.EOF) {
 		return Token{Type: EOF}, nil
 	}
+	l._start, l._startLine, l._startPos = l._offset, l._line, l._pos
 
 	r, err := l.nextRune()
 	if errors.Is(err, io.EOF) {
@@ -72,29 +220,45 @@ func (l *Lexer) nextToken() (Token, error) {
 			l.retract()
 		} else {
 			if nextRune == '/' {
-				err := l.skipAnnotation()
+				comment, err := l.skipAnnotation()
+				l._leading = append(l._leading, comment)
+				l.hide(CommentChannel, COMMENT, comment)
 				if err != nil {
 					if errors.Is(err, io.EOF) {
 						return Token{Type: EOF}, nil
 					}
 					return Token{}, err
 				}
-				return l.NextToken()
+				return l.nextToken()
 			} else if nextRune == '*' {
-				err := l.skipAnnotation2()
+				comment, err := l.skipAnnotation2()
+				l._leading = append(l._leading, comment)
+				l.hide(CommentChannel, COMMENT, comment)
 				if err != nil {
 					if errors.Is(err, io.EOF) {
-						return Token{Type: EOF}, nil
+						return Token{Type: EOF}, commentNotClosed(l._startLine, l._startPos+1)
 					}
 					return Token{}, err
 				}
-				return l.NextToken()
+				return l.nextToken()
 			} else {
 				l.retract()
 			}
 		}
 	}
 
+	if r == '#' && l._startLine >= l._directiveLine {
+		directive, err := l.readDirective()
+		l.hide(PreprocessorChannel, PREPROCESSOR, directive)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return Token{Type: EOF}, nil
+			}
+			return Token{}, err
+		}
+		return l.nextToken()
+	}
+
 	if r == '"' {
 		return l.ReadString()
 	}
@@ -131,36 +295,50 @@ func (l *Lexer) nextToken() (Token, error) {
 // nextRune reads the next rune from the input stream and updates the line and position counters.
 // It also handles line breaks and updates the line lengths slice.
 func (l *Lexer) nextRune() (rune, error) {
-	r, _, err := l._reader.ReadRune()
+	r, size, err := l._reader.ReadRune()
 	if err != nil {
 		return 0, err
 	}
+	l.advance(r, size)
+	return r, nil
+}
+
+// advance moves the counters past the rune read.
+func (l *Lexer) advance(r rune, size int) {
+	l._offset += int64(size)
+	l._lastSize = size
 	if r == '\n' {
 		l._line++
-		l._lineLengths = append(l._lineLengths, l._pos)
+		l._lastLineLength = l._pos
 		l._pos = 0
 	} else {
 		l._pos++
 	}
-	return r, nil
 }
 
 // retract moves the position back by one rune in the input stream.
-// It updates the line and position counters accordingly.
+// It updates the line and position counters accordingly. The reader gives
+// back only the rune read last, so retracting again, or after EOF, leaves the
+// counters as they are, for the offsets of the spans to stay on the text.
 func (l *Lexer) retract() {
-	_ = l._reader.UnreadRune()
+	if l._reader.UnreadRune() != nil {
+		return
+	}
+	l._offset -= int64(l._lastSize)
+	l._lastSize = 0
 	if l._pos > 0 {
 		l._pos--
-	} else if l._line > 1 {
+	} else if l._line > 0 {
 		l._line--
-		l._pos = l._lineLengths[l._line-1]
-		l._lineLengths = l._lineLengths[:l._line]
+		l._pos = l._lastLineLength
 	}
 }
 
 // skipWhiteSpace skips over whitespace characters in the input stream.
 // It continues reading until a non-whitespace character is found or EOF is reached.
 func (l *Lexer) skipWhiteSpace() error {
+	space := strings.Builder{}
+	defer func() { l.hide(WhitespaceChannel, WHITESPACE, space.String()) }()
 	for {
 		r, err := l.nextRune()
 		if err != nil {
@@ -170,41 +348,125 @@ func (l *Lexer) skipWhiteSpace() error {
 			l.retract()
 			return nil
 		}
+		space.WriteRune(r)
 	}
 }
 
 // skipAnnotation skips over single-line comments in the input stream.
 // It continues reading until a newline character is found or EOF is reached.
-func (l *Lexer) skipAnnotation() error {
+// It returns the comment without the newline, which is left to read.
+func (l *Lexer) skipAnnotation() (string, error) {
+	comment := strings.Builder{}
+	comment.WriteString("//")
 	for {
 		r, err := l.nextRune()
 		if err != nil {
-			return err
+			return comment.String(), err
 		}
 		if r == '\n' {
-			return nil
+			l.retract()
+			return comment.String(), nil
+		}
+		comment.WriteRune(r)
+	}
+}
+
+// readDirective reads a preprocessor directive, the rest of the line after
+// the # read. It returns the directive without the newline, which is left
+// to read.
+func (l *Lexer) readDirective() (string, error) {
+	directive := strings.Builder{}
+	directive.WriteString("#")
+	for {
+		r, err := l.nextRune()
+		if err != nil {
+			return directive.String(), err
+		}
+		if r == '\n' {
+			l.retract()
+			return directive.String(), nil
 		}
+		directive.WriteRune(r)
 	}
 }
 
 // skipAnnotation2 skips over multi-line comments in the input stream.
 // It continues reading until the closing comment sequence "*/" is found or EOF is reached.
-func (l *Lexer) skipAnnotation2() error {
+// A "/*" inside opens a nested comment, which its own "*/" closes, so that
+// code holding comments can be commented out.
+// It returns the comment.
+func (l *Lexer) skipAnnotation2() (string, error) {
+	comment := strings.Builder{}
+	comment.WriteString("/*")
+	depth := 1
+	var previous rune
 	for {
-		r1, err := l.nextRune()
+		r, err := l.nextRune()
 		if err != nil {
-			return err
+			return comment.String(), err
 		}
-		if r1 == '*' {
-			r2, err := l.nextRune()
-			if err != nil {
-				return err
+		comment.WriteRune(r)
+		switch {
+		case previous == '/' && r == '*':
+			depth++
+			r = 0 // the * opens the comment, it does not close it with a / next
+		case previous == '*' && r == '/':
+			depth--
+			if depth == 0 {
+				return comment.String(), nil
+			}
+			r = 0
+		}
+		previous = r
+	}
+}
+
+// commentNotClosed returns the error of a multi-line comment starting at the
+// line and pos and running to the end of the input.
+func commentNotClosed(line, pos int64) error {
+	return fmt.Errorf("comment not closed, at line %d, pos %d", line, pos)
+}
+
+// readTrailing reads the comments following a token on the same line.
+func (l *Lexer) readTrailing() string {
+	reader, ok := l._reader.(*bufio.Reader)
+	if !ok {
+		return ""
+	}
+	comments := []string{}
+	for {
+		for {
+			b, err := reader.Peek(1)
+			if err != nil || (b[0] != ' ' && b[0] != '\t') {
+				break
 			}
-			if r2 == '/' {
-				return nil
+			_, _ = l.nextRune()
+			l.hide(WhitespaceChannel, WHITESPACE, string(b[0]))
+		}
+		b, err := reader.Peek(2)
+		if err != nil || b[0] != '/' || (b[1] != '/' && b[1] != '*') {
+			break
+		}
+		_, _ = l.nextRune()
+		line, pos := l._line, l._pos
+		r, _ := l.nextRune()
+		var comment string
+		if r == '/' {
+			comment, err = l.skipAnnotation()
+		} else {
+			comment, err = l.skipAnnotation2()
+			if errors.Is(err, io.EOF) {
+				l._err = commentNotClosed(line, pos)
 			}
 		}
+		comments = append(comments, comment)
+		l.hide(CommentChannel, COMMENT, comment)
+		// a line comment ends the line
+		if err != nil || r == '/' {
+			break
+		}
 	}
+	return strings.Join(comments, "\n")
 }
 
 // ReadString reads a double-quoted string from the input stream.
@@ -223,7 +485,7 @@ func (l *Lexer) ReadString() (Token, error) {
 		r, err := l.nextRune()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
-				return Token{Type: EOF}, fmt.Errorf("string not closed, line %d, pos %d", l._line, l._pos)
+				return Token{Type: EOF}, fmt.Errorf("string not closed, at line %d, pos %d", l._line, l._pos)
 			} else {
 				return Token{}, err
 			}
@@ -283,8 +545,14 @@ func (l *Lexer) ReadString() (Token, error) {
 				}
 			} else {
 				switch r {
-				case 'n', 't', 'r', 'b', 'f', 'a', 'v', '"':
+				case 'n', 't', 'r', 'b', 'f', 'a', 'v', '"', '\'':
 					s += utils.AppendEscape(r)
+				case 'x': // escape hex
+					h, err := l.readHexEscape()
+					if err != nil {
+						return Token{}, err
+					}
+					s += string(h)
 				case 'u': // escape unicode
 					escapeAsUnicodeLower = true
 				case 'U': // escape unicode
@@ -302,9 +570,9 @@ func (l *Lexer) ReadString() (Token, error) {
 		}
 		if r == '\n' {
 			if errors.Is(err, io.EOF) {
-				return Token{Type: EOF}, fmt.Errorf("string not closed, line %d, pos %d", l._line-1, l._lineLengths[l._line-1])
+				return Token{Type: EOF}, fmt.Errorf("string not closed, at line %d, pos %d", l._line-1, l._lastLineLength)
 			} else {
-				return Token{}, fmt.Errorf("string not closed, line %d, pos %d", l._line-1, l._lineLengths[l._line-1])
+				return Token{}, fmt.Errorf("string not closed, at line %d, pos %d", l._line-1, l._lastLineLength)
 			}
 		}
 		s += string(r)
@@ -321,6 +589,44 @@ func (l *Lexer) ReadString() (Token, error) {
 	return Token{Type: STRING, Val: s, Line: l._line, Pos: l._pos, _type: ConstantStringDoubleQuote}, nil
 }
 
+// readHexEscape reads the two hex digits of a \x escape, the x read, and
+// returns the byte they stand for.
+func (l *Lexer) readHexEscape() (rune, error) {
+	digits := ""
+	for len(digits) < 2 {
+		r, err := l.nextRune()
+		if err != nil || !utils.IsHex(r) {
+			if err == nil {
+				l.retract()
+			}
+			return 0, fmt.Errorf("illegal hex escape \\x%s, at line %d, pos %d", digits, l._line, l._pos)
+		}
+		digits += string(r)
+	}
+	h, _ := strconv.ParseUint(digits, 16, 8)
+	return rune(h), nil
+}
+
+// readOctalEscape reads the octal digits of a \0 escape of a char, none or
+// two of them, the 0 read, and returns the byte they stand for.
+func (l *Lexer) readOctalEscape() (rune, error) {
+	digits := ""
+	for len(digits) < 2 {
+		r, err := l.nextRune()
+		if err != nil || !utils.IsOctal(r) {
+			if err == nil {
+				l.retract()
+			}
+			if digits == "" {
+				return 0, nil
+			}
+			return 0, fmt.Errorf("illegal octal %s, at line %d, pos %d", digits, l._line, l._pos)
+		}
+		digits += string(r)
+	}
+	return utils.OctalToRune(digits), nil
+}
+
 // ReadString2 reads a backtick-quoted string from the input stream.
 func (l *Lexer) ReadString2() (Token, error) {
 	s := ""
@@ -328,7 +634,7 @@ func (l *Lexer) ReadString2() (Token, error) {
 		r, err := l.nextRune()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
-				return Token{Type: EOF}, fmt.Errorf("string not closed, line %d, pos %d", l._line, l._pos)
+				return Token{Type: EOF}, fmt.Errorf("string not closed, at line %d, pos %d", l._line, l._pos)
 			} else {
 				return Token{}, err
 			}
@@ -354,7 +660,7 @@ func (l *Lexer) ReadChar() (Token, error) {
 		r, err := l.nextRune()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
-				return Token{Type: EOF}, fmt.Errorf("char not closed, line %d, pos %d", l._line, l._pos)
+				return Token{Type: EOF}, fmt.Errorf("char not closed, at line %d, pos %d", l._line, l._pos)
 			} else {
 				return Token{}, err
 			}
@@ -374,6 +680,16 @@ func (l *Lexer) ReadChar() (Token, error) {
 			switch r {
 			case 'n', 't', 'r', 'b', 'f', 'a', 'v':
 				s += utils.AppendEscape(r)
+			case 'x', '0': // a byte in hex or octal, \0 alone the zero byte
+				read := l.readHexEscape
+				if r == '0' {
+					read = l.readOctalEscape
+				}
+				b, err := read()
+				if err != nil {
+					return Token{}, err
+				}
+				s += string(b)
 			case 'u': // escapeAsUnicode
 				if escapeAsUnicodeLower || escapeAsUnicodeUpper {
 					illegalUnicode = true
@@ -386,11 +702,10 @@ func (l *Lexer) ReadChar() (Token, error) {
 				}
 				escapeAsUnicodeUpper = true
 				s += string(r)
-			default:
-				if (escapeAsUnicodeLower || escapeAsUnicodeUpper) && !utils.IsHex(r) {
-					illegalUnicode = true
-				}
+			case '\'', '"':
 				s += string(r)
+			default:
+				return Token{}, fmt.Errorf("illegal escape \\%s, at line %d, pos %d", string(r), l._line, l._pos)
 			}
 			width++
 			escape = false
@@ -448,14 +763,19 @@ func (l *Lexer) ReadWord(r rune) (Token, error) {
 
 // ReadNumber reads a number (integer or float) from the input stream.
 // It handles digits, letters, underscores, and hexadecimal numbers.
+// A decimal number may end with an exponent, e or E and an optionally signed
+// integer, which makes it a float, such as 1e10 or 2.5E-3.
 func (l *Lexer) ReadNumber(r rune) (Token, error) {
 	s := string(r)
 	illegalSuffix := false
+	exponent := -1 // the index of the e of the exponent in s
 	tokenWhenWrong := Token{}
 	var errWhenPassed error
 	for {
 		nr, err := l.nextRune()
-		if err != nil || !(utils.IsDigit(nr) || utils.IsLetter(nr) || nr == '_' || nr == '.') {
+		// the sign of the exponent, right after its e
+		sign := (nr == '+' || nr == '-') && exponent == len(s)-1
+		if err != nil || !(utils.IsDigit(nr) || utils.IsLetter(nr) || nr == '_' || nr == '.' || sign) {
 			if errors.Is(err, io.EOF) {
 				tokenWhenWrong.Type = EOF
 				errWhenPassed = io.EOF
@@ -463,7 +783,9 @@ func (l *Lexer) ReadNumber(r rune) (Token, error) {
 			l.retract()
 			break
 		}
-		if utils.IsLetter(nr) || nr == '_' {
+		if (nr == 'e' || nr == 'E') && exponent < 0 && !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+			exponent = len(s)
+		} else if utils.IsLetter(nr) || nr == '_' {
 			illegalSuffix = true
 		}
 		s += string(nr)
@@ -471,6 +793,21 @@ func (l *Lexer) ReadNumber(r rune) (Token, error) {
 	if illegalSuffix && !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
 		return tokenWhenWrong, fmt.Errorf("illegal number[suffix] %s, at line %d, pos %d", s, l._line, l._pos)
 	}
+	if exponent >= 0 {
+		mantissa, power := s[:exponent], strings.TrimLeft(s[exponent+1:], "+-")
+		if power == "" || strings.ContainsFunc(power, func(r rune) bool { return !utils.IsDigit(r) }) {
+			return tokenWhenWrong, fmt.Errorf("illegal number[exponent] %s, at line %d, pos %d", s, l._line, l._pos)
+		}
+		if strings.Count(mantissa, ".") > 1 {
+			return tokenWhenWrong, fmt.Errorf("illegal number[too many dots] %s, at line %d, pos %d", s, l._line, l._pos)
+		}
+		// the leading zeros of the integer part go, as in 00.5
+		whole, fraction, dotted := strings.Cut(mantissa, ".")
+		if whole = utils.RemoveLeadingZeros(whole); dotted {
+			whole += "." + fraction
+		}
+		return Token{Type: FLOAT, Val: whole + s[exponent:], Line: l._line, Pos: l._pos}, errWhenPassed
+	}
 	dotCount := strings.Count(s, ".")
 	if dotCount == 1 {
 		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
@@ -308,9 +308,6 @@ abc@ 123#
 // 非法浮点数
 123.456.789
 
-// 不支持的科学计数法
-1e10
-
 // 不支持的八进制数
 0777
 
@@ -321,7 +318,41 @@ abc@ 123#
 001
 `,
 		expectedTokens: make([]lexer.Token, 0),
-		errorCount:     11,
+		errorCount:     10,
+	},
+	{
+		name: "Float With Exponent",
+		str: `// 科学计数法
+1e10
+2.5E-3
+00.5e+2
+1.e5
+0x1e5
+x = 1e3+2`,
+		expectedTokens: []lexer.Token{
+			{Type: lexer.FLOAT, Val: "1e10"},
+			{Type: lexer.FLOAT, Val: "2.5E-3"},
+			{Type: lexer.FLOAT, Val: "0.5e+2"},
+			{Type: lexer.FLOAT, Val: "1.e5"},
+			{Type: lexer.INTEGER, Val: "0x1e5"},
+			{Type: lexer.IDENTIFIER, Val: "x"},
+			{Type: lexer.OPERATOR, Val: "="},
+			{Type: lexer.FLOAT, Val: "1e3"},
+			{Type: lexer.OPERATOR, Val: "+"},
+			{Type: lexer.INTEGER, Val: "2"},
+		},
+	},
+	{
+		name: "Wrong Exponent",
+		str: `// 非法指数
+1e
+1e+
+1e5.5
+1.2.3e4
+1e2e3
+`,
+		expectedTokens: make([]lexer.Token, 0),
+		errorCount:     5,
 	},
 	{
 		name: "Multiline String Using Double Quotes",
@@ -492,6 +523,49 @@ Line 2"
 			{Type: lexer.STRING, Val: "\u0041abcd\001efghijklmnop\U0001F601qrstuvwxyz\003"},
 		},
 	},
+	{
+		name: "hex and quote escapes",
+		str: `// hex and quote escapes
+"\x41\x62c"
+"it\'s"
+'\x41'
+'\0'
+'\041'
+'\''`,
+		expectedTokens: []lexer.Token{
+			{Type: lexer.STRING, Val: "Abc"},
+			{Type: lexer.STRING, Val: "it's"},
+			{Type: lexer.CHAR, Val: "A"},
+			{Type: lexer.CHAR, Val: "\x00"},
+			{Type: lexer.CHAR, Val: "!"},
+			{Type: lexer.CHAR, Val: "'"},
+		},
+	},
+	{
+		name: "hex escape with error",
+		str: `// hex escape with error
+"\x4"
+'\xG1'
+'\01'`,
+		raisingErrorAnyWay: true,
+	},
+	{
+		name: "Nested Annotation",
+		str: `/* 外层 /* 内层 */ 仍是注释 */ a
+/*/ 不是结束 */ b
+/** 文档注释 **/ c`,
+		expectedTokens: []lexer.Token{
+			{Type: lexer.IDENTIFIER, Val: "a"},
+			{Type: lexer.IDENTIFIER, Val: "b"},
+			{Type: lexer.IDENTIFIER, Val: "c"},
+		},
+	},
+	{
+		name: "Annotation Not Closed",
+		str: `a
+/* 注释未闭合 /* */`,
+		raisingErrorAnyWay: true,
+	},
 	{
 		name: "backtick string",
 		str: "`" + `
@@ -539,3 +613,229 @@ func TestLexer(t *testing.T) {
 		})
 	}
 }
+
+func TestLexer_CommentNotClosed(t *testing.T) {
+	for _, tc := range []struct{ src, expected string }{
+		{"a\n  /* one /* two */", "comment not closed, at line 1, pos 3"},
+		{"a /* one", "comment not closed, at line 0, pos 4"},
+	} {
+		l := lexer.NewLexer(strings.NewReader(tc.src))
+		if token, err := l.NextToken(); err != nil || token.Val != "a" {
+			t.Fatalf("Expected a, got %v, %v", token, err)
+		}
+		token, err := l.NextToken()
+		if err == nil || err.Error() != tc.expected || token.Type != lexer.EOF {
+			t.Errorf("Expected %q at the end of %q, got %v, %v", tc.expected, tc.src, token, err)
+		}
+		if token, err := l.NextToken(); err != nil || token.Type != lexer.EOF {
+			t.Errorf("Expected the end after the error, got %v, %v", token, err)
+		}
+	}
+}
+
+func TestLexer_IllegalEscape(t *testing.T) {
+	// chars reject the escapes strings reject, with the same error
+	for _, src := range []string{`"\q"`, `'\q'`} {
+		_, err := lexer.NewLexer(strings.NewReader(src)).NextToken()
+		if err == nil || err.Error() != "illegal escape \\q, at line 0, pos 4" {
+			t.Errorf("Expected an illegal escape in %s, got %v", src, err)
+		}
+	}
+	tokens, errCount := LexerAct(`'\\' '\"' '\''`)
+	if errCount != 0 || len(tokens) != 3 || tokens[0].Val != "\\" || tokens[1].Val != `"` || tokens[2].Val != "'" {
+		t.Errorf("Expected the escaped backslash and quotes, got %v with %d errors", tokens, errCount)
+	}
+}
+
+func TestLexer_Trivia(t *testing.T) {
+	tokens, _ := LexerAct("// first\n/* second */ int a; // trailing\nb /* one */ /* two */\n/* three */ c")
+	expected := []lexer.Trivia{
+		{Leading: "// first\n/* second */"},
+		{},
+		{Trailing: "// trailing"},
+		{Trailing: "/* one */\n/* two */"},
+		{Leading: "/* three */"},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, token := range tokens {
+		if token.Trivia != expected[i] {
+			t.Errorf("Expected token %d to have %q, got %q", i, expected[i], token.Trivia)
+		}
+	}
+}
+
+func TestLexer_Channels(t *testing.T) {
+	const src = "#include <x>\nint a; // one\n  /* two */ b\n#pragma x\n"
+	read := func(l *lexer.Lexer) (tokens []lexer.Token) {
+		for {
+			token, err := l.NextToken()
+			if err != nil && !errors.Is(err, io.EOF) {
+				t.Fatalf("NextToken: %v", err)
+			}
+			tokens = append(tokens, token)
+			if token.Type == lexer.EOF {
+				return tokens
+			}
+		}
+	}
+
+	all := read(lexer.NewLexer(strings.NewReader(src)).WithChannels(lexer.WhitespaceChannel, lexer.CommentChannel, lexer.PreprocessorChannel))
+	expected := []lexer.Token{
+		{Type: lexer.PREPROCESSOR, Val: "#include <x>", Channel: lexer.PreprocessorChannel},
+		{Type: lexer.WHITESPACE, Val: "\n", Channel: lexer.WhitespaceChannel},
+		{Type: lexer.TYPE, Val: "int"},
+		{Type: lexer.WHITESPACE, Val: " ", Channel: lexer.WhitespaceChannel},
+		{Type: lexer.IDENTIFIER, Val: "a"},
+		{Type: lexer.DELIMITER, Val: ";"},
+		{Type: lexer.WHITESPACE, Val: " ", Channel: lexer.WhitespaceChannel},
+		{Type: lexer.COMMENT, Val: "// one", Channel: lexer.CommentChannel},
+		{Type: lexer.WHITESPACE, Val: "\n  ", Channel: lexer.WhitespaceChannel},
+		{Type: lexer.COMMENT, Val: "/* two */", Channel: lexer.CommentChannel},
+		{Type: lexer.WHITESPACE, Val: " ", Channel: lexer.WhitespaceChannel},
+		{Type: lexer.IDENTIFIER, Val: "b"},
+		{Type: lexer.WHITESPACE, Val: "\n", Channel: lexer.WhitespaceChannel},
+		{Type: lexer.PREPROCESSOR, Val: "#pragma x", Channel: lexer.PreprocessorChannel},
+		{Type: lexer.WHITESPACE, Val: "\n", Channel: lexer.WhitespaceChannel},
+		{Type: lexer.EOF},
+	}
+	if len(all) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d: %v", len(expected), len(all), all)
+	}
+	for i, token := range all {
+		if token.Type != expected[i].Type || token.Val != expected[i].Val || token.Channel != expected[i].Channel {
+			t.Errorf("Expected token %d to be %v on %s, got %v on %s", i, expected[i], expected[i].Channel, token, token.Channel)
+		}
+	}
+	if line := all[9].Line; line != 2 {
+		t.Errorf("Expected /* two */ on line 2, got %d", line)
+	}
+
+	// the default channel is the same with the others or without them
+	plain := read(lexer.NewLexer(strings.NewReader(src)))
+	onDefault := lexer.OnChannels(all, lexer.DefaultChannel)
+	if len(plain) != len(onDefault) {
+		t.Fatalf("Expected the %d tokens of the default channel, got %d", len(plain), len(onDefault))
+	}
+	for i := range plain {
+		if plain[i].Val != onDefault[i].Val || plain[i].Trivia != onDefault[i].Trivia || plain[i].Line != onDefault[i].Line {
+			t.Errorf("Expected token %d to be %v with %q, got %v with %q", i, plain[i], plain[i].Trivia, onDefault[i], onDefault[i].Trivia)
+		}
+	}
+
+	comments := read(lexer.NewLexer(strings.NewReader(src)).WithChannels(lexer.CommentChannel))
+	if n := len(lexer.OnChannels(comments, lexer.CommentChannel)); n != 2 || len(comments) != len(plain)+2 {
+		t.Errorf("Expected the 2 comments alone besides the default channel, got %v", comments)
+	}
+
+	if _, errCount := LexerAct("a #b"); errCount != 1 {
+		t.Errorf("Expected # in the middle of a line to be an error, got %d errors", errCount)
+	}
+	if channel, err := lexer.ParseChannel("comments"); err != nil || channel != lexer.CommentChannel {
+		t.Errorf("Expected the comments channel, got %v, %v", channel, err)
+	}
+	if _, err := lexer.ParseChannel("trivia"); err == nil || err.Error() != "unknown channel trivia" {
+		t.Errorf("Expected an unknown channel, got %v", err)
+	}
+}
+
+func TestFormatLab(t *testing.T) {
+	lines := []string{
+		lexer.LabHeader(),
+		lexer.FormatLab(1, lexer.Token{Type: lexer.RESERVED, Val: "while"}),
+		lexer.FormatLab(12, lexer.Token{Type: lexer.STRING, Val: "一个字符串"}),
+		lexer.FormatLab(13, lexer.Token{Type: lexer.WHITESPACE, Val: "\n", Channel: lexer.WhitespaceChannel}),
+	}
+	expected := []string{
+		"序号    单词                    种别",
+		"1       while                   保留字",
+		"12      一个字符串              字符串",
+		`13      "\n"                    空白`,
+	}
+	for i, line := range lines {
+		if line != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], line)
+		}
+	}
+}
+
+func TestLexer_Peek(t *testing.T) {
+	l := lexer.NewLexer(strings.NewReader("int a = 1; @ b"))
+	if token, err := l.Next(); err != nil || token.Val != "int" {
+		t.Fatalf("Expected int, got %v, %v", token, err)
+	}
+	if start, end := l.Span(); start != 0 || end != 3 {
+		t.Errorf("Expected the span of int, got [%d, %d)", start, end)
+	}
+	for n, expected := range map[int]string{3: "1", 1: "a", 4: ";"} {
+		if token, err := l.Peek(n); err != nil || token.Val != expected {
+			t.Errorf("Expected %s %d tokens ahead, got %v, %v", expected, n, token, err)
+		}
+	}
+	if _, err := l.Peek(5); err == nil || !strings.Contains(err.Error(), "unknown character: @") {
+		t.Errorf("Expected the error of @ 5 tokens ahead, got %v", err)
+	}
+	if token, err := l.Peek(8); err != nil || token.Type != lexer.EOF {
+		t.Errorf("Expected EOF past the end, got %v, %v", token, err)
+	}
+	if _, err := l.Peek(lexer.Lookahead + 1); err == nil {
+		t.Error("Expected an error peeking beyond the lookahead")
+	}
+	if start, end := l.Span(); start != 0 || end != 3 {
+		t.Errorf("Expected peeking to keep the span of int, got [%d, %d)", start, end)
+	}
+	for _, expected := range []string{"a", "=", "1", ";", "", "b"} {
+		token, err := l.Next()
+		if expected == "" {
+			if err == nil {
+				t.Errorf("Expected the error of @, got %v", token)
+			}
+			continue
+		}
+		// the last word ends with the input
+		if err != nil && !errors.Is(err, io.EOF) || token.Val != expected {
+			t.Errorf("Expected %s, got %v, %v", expected, token, err)
+		}
+	}
+	if start, _ := l.Span(); start != 13 {
+		t.Errorf("Expected the span of b, got it from %d", start)
+	}
+	if token, err := l.Next(); err != nil || token.Type != lexer.EOF {
+		t.Errorf("Expected EOF, got %v, %v", token, err)
+	}
+}
+
+// endless is an input repeating a declaration forever, counting the bytes
+// read out of it.
+type endless struct{ read int }
+
+func (e *endless) Read(p []byte) (int, error) {
+	const line = "int a; // a declaration\n"
+	for i := range p {
+		p[i] = line[(e.read+i)%len(line)]
+	}
+	e.read += len(p)
+	return len(p), nil
+}
+
+func TestLexer_Stream(t *testing.T) {
+	input := &endless{}
+	l := lexer.NewLexer(input)
+	for i := range 100000 {
+		token, err := l.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := []string{"int", "a", ";"}[i%3]; token.Val != expected {
+			t.Fatalf("Expected %s at token %d, got %v", expected, i, token)
+		}
+		if _, err := l.Peek(3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the lexer reads as far as the tokens it peeked go, within its buffer
+	if lines := int64(100000/3 + 1); input.read > int(lines)*24+8192 {
+		t.Errorf("Expected to read about %d lines, read %d bytes", lines, input.read)
+	}
+}
@@ -0,0 +1,347 @@
+package lexer
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// runeRange is the runes from lo to hi, both included.
+type runeRange struct {
+	lo, hi rune
+}
+
+// normalize sorts the ranges and merges those overlapping or adjacent.
+func normalize(ranges []runeRange) []runeRange {
+	slices.SortFunc(ranges, func(a, b runeRange) int { return int(a.lo - b.lo) })
+	merged := []runeRange{}
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.lo <= merged[n-1].hi+1 {
+			merged[n-1].hi = max(merged[n-1].hi, r.hi)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// complement returns the runes none of the ranges holds.
+func complement(ranges []runeRange) []runeRange {
+	var out []runeRange
+	next := rune(0)
+	for _, r := range normalize(ranges) {
+		if r.lo > next {
+			out = append(out, runeRange{next, r.lo - 1})
+		}
+		next = r.hi + 1
+	}
+	if next <= unicode.MaxRune {
+		out = append(out, runeRange{next, unicode.MaxRune})
+	}
+	return out
+}
+
+var (
+	digitRanges = []runeRange{{'0', '9'}}
+	wordRanges  = []runeRange{{'0', '9'}, {'A', 'Z'}, {'_', '_'}, {'a', 'z'}}
+	spaceRanges = []runeRange{{'\t', '\r'}, {' ', ' '}}
+	// the letters of unicode.IsLetter, as the hand-written lexer reads them
+	letterRanges = func() []runeRange {
+		var ranges []runeRange
+		add := func(lo, hi, stride rune) {
+			if stride == 1 {
+				ranges = append(ranges, runeRange{lo, hi})
+				return
+			}
+			for r := lo; r <= hi; r += stride {
+				ranges = append(ranges, runeRange{r, r})
+			}
+		}
+		for _, r := range unicode.Letter.R16 {
+			add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+		}
+		for _, r := range unicode.Letter.R32 {
+			add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+		}
+		return normalize(ranges)
+	}()
+)
+
+// nfa is the automaton of Thompson's construction of the patterns, each of
+// its states with an edge on a set of runes, edges on nothing, or both.
+type nfa struct {
+	states []nfaState
+}
+
+type nfaState struct {
+	ranges []runeRange // the runes of the edge to next, none if no edge
+	next   int
+	empty  []int // the states reached on nothing
+	accept int   // the rule the state accepts, -1 if none
+}
+
+func (n *nfa) add() int {
+	n.states = append(n.states, nfaState{next: -1, accept: -1})
+	return len(n.states) - 1
+}
+
+// fragment is the part of the automaton of a subexpression, from its start to
+// its end.
+type fragment struct {
+	start, end int
+}
+
+// regexParser reads a pattern into the automaton by recursive descent:
+//
+//	alternation   = concatenation { "|" concatenation }
+//	concatenation = { repetition }
+//	repetition    = atom { "*" | "+" | "?" }
+//	atom          = "(" alternation ")" | "[" ["^"] class "]" | "." | "\" escape | rune
+type regexParser struct {
+	n       *nfa
+	pattern []rune
+	i       int
+}
+
+// compilePattern adds the automaton of the pattern to n.
+func compilePattern(n *nfa, pattern string) (fragment, error) {
+	p := &regexParser{n: n, pattern: []rune(pattern)}
+	f, err := p.alternation()
+	if err != nil {
+		return fragment{}, err
+	}
+	if p.i < len(p.pattern) {
+		return fragment{}, p.errorf("unexpected %c", p.pattern[p.i])
+	}
+	return f, nil
+}
+
+func (p *regexParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s, at %d of pattern %q", fmt.Sprintf(format, args...), p.i, string(p.pattern))
+}
+
+func (p *regexParser) peek() (rune, bool) {
+	if p.i < len(p.pattern) {
+		return p.pattern[p.i], true
+	}
+	return 0, false
+}
+
+func (p *regexParser) alternation() (fragment, error) {
+	f, err := p.concatenation()
+	if err != nil {
+		return fragment{}, err
+	}
+	for r, ok := p.peek(); ok && r == '|'; r, ok = p.peek() {
+		p.i++
+		g, err := p.concatenation()
+		if err != nil {
+			return fragment{}, err
+		}
+		start, end := p.n.add(), p.n.add()
+		p.n.states[start].empty = []int{f.start, g.start}
+		p.n.states[f.end].empty = append(p.n.states[f.end].empty, end)
+		p.n.states[g.end].empty = append(p.n.states[g.end].empty, end)
+		f = fragment{start, end}
+	}
+	return f, nil
+}
+
+func (p *regexParser) concatenation() (fragment, error) {
+	start := p.n.add()
+	f := fragment{start, start}
+	for r, ok := p.peek(); ok && r != '|' && r != ')'; r, ok = p.peek() {
+		g, err := p.repetition()
+		if err != nil {
+			return fragment{}, err
+		}
+		p.n.states[f.end].empty = append(p.n.states[f.end].empty, g.start)
+		f.end = g.end
+	}
+	return f, nil
+}
+
+func (p *regexParser) repetition() (fragment, error) {
+	f, err := p.atom()
+	if err != nil {
+		return fragment{}, err
+	}
+	for r, ok := p.peek(); ok && strings.ContainsRune("*+?", r); r, ok = p.peek() {
+		p.i++
+		start, end := p.n.add(), p.n.add()
+		p.n.states[start].empty = []int{f.start}
+		p.n.states[f.end].empty = append(p.n.states[f.end].empty, end)
+		if r != '+' {
+			p.n.states[start].empty = append(p.n.states[start].empty, end)
+		}
+		if r != '?' {
+			p.n.states[f.end].empty = append(p.n.states[f.end].empty, f.start)
+		}
+		f = fragment{start, end}
+	}
+	return f, nil
+}
+
+func (p *regexParser) atom() (fragment, error) {
+	r, _ := p.peek()
+	p.i++
+	var ranges []runeRange
+	switch r {
+	case '(':
+		f, err := p.alternation()
+		if err != nil {
+			return fragment{}, err
+		}
+		if r, ok := p.peek(); !ok || r != ')' {
+			return fragment{}, p.errorf("( not closed")
+		}
+		p.i++
+		return f, nil
+	case '*', '+', '?':
+		p.i--
+		return fragment{}, p.errorf("nothing to repeat before %c", r)
+	case '[':
+		var err error
+		if ranges, err = p.class(); err != nil {
+			return fragment{}, err
+		}
+	case '.':
+		ranges = complement([]runeRange{{'\n', '\n'}})
+	case '\\':
+		var err error
+		if ranges, err = p.escape(); err != nil {
+			return fragment{}, err
+		}
+	default:
+		ranges = []runeRange{{r, r}}
+	}
+	start, end := p.n.add(), p.n.add()
+	p.n.states[start].ranges, p.n.states[start].next = normalize(ranges), end
+	return fragment{start, end}, nil
+}
+
+// class reads a class of runes after its [, up to its ].
+func (p *regexParser) class() ([]runeRange, error) {
+	negated := false
+	if r, ok := p.peek(); ok && r == '^' {
+		negated = true
+		p.i++
+	}
+	var ranges []runeRange
+	for {
+		r, ok := p.peek()
+		if !ok {
+			return nil, p.errorf("[ not closed")
+		}
+		p.i++
+		if r == ']' {
+			break
+		}
+		lo := []runeRange{{r, r}}
+		if r == '\\' {
+			var err error
+			if lo, err = p.escape(); err != nil {
+				return nil, err
+			}
+		}
+		// a range, unless the - is the last rune of the class
+		if next, ok := p.peek(); ok && next == '-' && p.i+1 < len(p.pattern) && p.pattern[p.i+1] != ']' && len(lo) == 1 && lo[0].lo == lo[0].hi {
+			p.i++
+			hi := p.pattern[p.i]
+			p.i++
+			if hi == '\\' {
+				escaped, err := p.escape()
+				if err != nil {
+					return nil, err
+				}
+				if len(escaped) != 1 || escaped[0].lo != escaped[0].hi {
+					return nil, p.errorf("a range cannot end with a class")
+				}
+				hi = escaped[0].lo
+			}
+			if hi < lo[0].lo {
+				return nil, p.errorf("range %c-%c out of order", lo[0].lo, hi)
+			}
+			lo[0].hi = hi
+		}
+		ranges = append(ranges, lo...)
+	}
+	if len(ranges) == 0 {
+		return nil, p.errorf("empty class")
+	}
+	if negated {
+		return complement(ranges), nil
+	}
+	return ranges, nil
+}
+
+// escape reads the rune or the class escaped after a \.
+func (p *regexParser) escape() ([]runeRange, error) {
+	r, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("\\ at the end")
+	}
+	p.i++
+	single := func(r rune) []runeRange { return []runeRange{{r, r}} }
+	switch r {
+	case 'n':
+		return single('\n'), nil
+	case 't':
+		return single('\t'), nil
+	case 'r':
+		return single('\r'), nil
+	case 'f':
+		return single('\f'), nil
+	case 'v':
+		return single('\v'), nil
+	case '0':
+		return single(0), nil
+	case 'd':
+		return digitRanges, nil
+	case 'D':
+		return complement(digitRanges), nil
+	case 'w':
+		return wordRanges, nil
+	case 'W':
+		return complement(wordRanges), nil
+	case 's':
+		return spaceRanges, nil
+	case 'S':
+		return complement(spaceRanges), nil
+	case 'p':
+		if r, ok := p.peek(); !ok || r != 'L' {
+			return nil, p.errorf("unknown class \\p, \\pL expected")
+		}
+		p.i++
+		return letterRanges, nil
+	case 'x':
+		if p.i+2 > len(p.pattern) {
+			return nil, p.errorf("\\x needs two hex digits")
+		}
+		h, err := strconv.ParseUint(string(p.pattern[p.i:p.i+2]), 16, 8)
+		if err != nil {
+			return nil, p.errorf("\\x needs two hex digits")
+		}
+		p.i += 2
+		return single(rune(h)), nil
+	}
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		p.i--
+		return nil, p.errorf("unknown escape \\%c", r)
+	}
+	return single(r), nil
+}
+
+// QuoteMeta returns a pattern matching the text as it is.
+func QuoteMeta(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune(`\.+*?()|[]^-`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
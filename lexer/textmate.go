@@ -0,0 +1,171 @@
+package lexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// TextMate is a TextMate grammar, the syntax highlighting definition VS Code,
+// Sublime Text and most editors read, built from the rules of the tokens so
+// that the editors highlight the lab language as the lexer reads it.
+type TextMate struct {
+	Schema     string                     `json:"$schema,omitempty"`
+	Name       string                     `json:"name"`
+	ScopeName  string                     `json:"scopeName"`
+	FileTypes  []string                   `json:"fileTypes,omitempty"`
+	Patterns   []TextMatePattern          `json:"patterns"`
+	Repository map[string]TextMatePattern `json:"repository"`
+}
+
+// TextMatePattern is a pattern of a TextMate grammar: a match, a region from
+// a begin to an end, or the include of a pattern of the repository.
+type TextMatePattern struct {
+	Name     string            `json:"name,omitempty"`
+	Match    string            `json:"match,omitempty"`
+	Begin    string            `json:"begin,omitempty"`
+	End      string            `json:"end,omitempty"`
+	Include  string            `json:"include,omitempty"`
+	Patterns []TextMatePattern `json:"patterns,omitempty"`
+}
+
+// _TextMateScopes are the scopes of the tokens of each type, those the
+// themes of the editors color, suffixed with the name of the rule and of the
+// language.
+var _TextMateScopes = map[ItemType]string{
+	TYPE: "storage.type", INTEGER: "constant.numeric.integer", FLOAT: "constant.numeric.float",
+	STRING: "string.quoted", CHAR: "constant.character", OPERATOR: "keyword.operator",
+	DELIMITER: "punctuation.separator", RESERVED: "keyword.control", IMPORT: "keyword.other",
+	PACKAGE: "keyword.other", IDENTIFIER: "variable.other", COMMENT: "comment",
+	PREPROCESSOR: "meta.preprocessor", EXTRA: "keyword.other",
+}
+
+// _Words matches a pattern of words alone, such as the keywords.
+var _Words = regexp.MustCompile(`^[\pL0-9_]+(\|[\pL0-9_]+)*$`)
+
+// TextMateGrammar returns the TextMate grammar of the language of the name
+// lexed with the rules, or with the hand-written lexer if the rules are nil:
+// then its nested comments and its preprocessor directives, which no rule
+// declares, are regions of their own. The rules of the whitespace are left
+// out, and those of words alone, such as the keywords, only match whole
+// words. The editors try the patterns in order, so the rules of a higher
+// priority come first; a rule matching a text of several lines, such as a
+// comment, is only highlighted on a line.
+func TextMateGrammar(rules []Rule, name string) (*TextMate, error) {
+	g := &TextMate{
+		Schema:     "https://raw.githubusercontent.com/martinring/tmlanguage/master/tmlanguage.json",
+		Name:       name,
+		ScopeName:  "source." + name,
+		Repository: map[string]TextMatePattern{},
+	}
+	scope := func(t ItemType, rule string) string {
+		return _TextMateScopes[t] + "." + rule + "." + name
+	}
+	if rules == nil {
+		rules = slices.DeleteFunc(DefaultRules(), func(r Rule) bool { return r.Name == "comment" })
+		g.Repository["comment-block"] = TextMatePattern{
+			Name:     scope(COMMENT, "block"),
+			Begin:    `/\*`,
+			End:      `\*/`,
+			Patterns: []TextMatePattern{{Include: "#comment-block"}},
+		}
+		g.Repository["comment-line"] = TextMatePattern{Name: scope(COMMENT, "line"), Match: `//.*$`}
+		g.Repository["preprocessor"] = TextMatePattern{Name: scope(PREPROCESSOR, "directive"), Match: `^\s*#.*$`}
+		for _, key := range []string{"comment-block", "comment-line", "preprocessor"} {
+			g.Patterns = append(g.Patterns, TextMatePattern{Include: "#" + key})
+		}
+	}
+	order := make([]int, 0, len(rules))
+	for i, rule := range rules {
+		if _, err := compilePattern(&nfa{}, rule.Pattern); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		if RuleType(rule.Name) != WHITESPACE {
+			order = append(order, i)
+		}
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return rules[b].Priority - rules[a].Priority })
+	for _, i := range order {
+		rule := rules[i]
+		key := rule.Name
+		if _, ok := g.Repository[key]; ok {
+			key = fmt.Sprintf("%s-%d", rule.Name, i)
+		}
+		match := onigurumaPattern(rule.Pattern)
+		if _Words.MatchString(rule.Pattern) {
+			match = `(?<![\p{L}0-9_])(?:` + match + `)(?![\p{L}0-9_])`
+		}
+		g.Repository[key] = TextMatePattern{Name: scope(RuleType(rule.Name), rule.Name), Match: match}
+		g.Patterns = append(g.Patterns, TextMatePattern{Include: "#" + key})
+	}
+	return g, nil
+}
+
+// Write writes the grammar as JSON, the keys of the repository in order.
+func (g *TextMate) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(g)
+}
+
+// _OnigurumaClasses are the runes of the classes escaped, ASCII as the
+// lexer reads them, where Oniguruma would read \d, \w and \s as Unicode.
+var _OnigurumaClasses = map[rune]string{
+	'd': `0-9`, 'w': `0-9A-Z_a-z`, 's': `\t-\r `,
+}
+
+// onigurumaPattern returns the pattern of a rule as a regular expression of
+// Oniguruma, which the TextMate grammars are written in, matching the same
+// texts: the runes Oniguruma reads as operators but the lexer does not are
+// escaped, such as { and $, or [ and & in a class.
+func onigurumaPattern(pattern string) string {
+	var b strings.Builder
+	runes := []rune(pattern)
+	class := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			e := runes[i]
+			switch e {
+			case 'p':
+				i++
+				b.WriteString(`\p{L}`)
+			case '0':
+				b.WriteString(`\x00`)
+			case 'd', 'w', 's':
+				if class {
+					b.WriteString(_OnigurumaClasses[e])
+				} else {
+					b.WriteString("[" + _OnigurumaClasses[e] + "]")
+				}
+			case 'D', 'W', 'S':
+				b.WriteString("[^" + _OnigurumaClasses[e-'A'+'a'] + "]")
+			default:
+				b.WriteRune('\\')
+				b.WriteRune(e)
+			}
+		case !class && r == '[':
+			class = true
+			b.WriteRune(r)
+			if i+1 < len(runes) && runes[i+1] == '^' {
+				i++
+				b.WriteRune('^')
+			}
+		case class && r == ']':
+			class = false
+			b.WriteRune(r)
+		case class && (r == '[' || r == '&'), !class && strings.ContainsRune("{}$^", r):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package lexer_test
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"app/lexer"
+)
+
+func TestTextMateGrammar(t *testing.T) {
+	g, err := lexer.TextMateGrammar([]lexer.Rule{
+		{Name: "whitespace", Pattern: `\s+`},
+		{Name: "identifier", Pattern: `[\pL_]\w*`},
+		{Name: "reserved", Pattern: `if|while`, Priority: 1},
+		{Name: "string", Pattern: `"[^"\n&[]*"`},
+		{Name: "string", Pattern: "`[^`]*`"},
+		{Name: "money", Pattern: `\$\d+{`},
+		{Name: "other", Pattern: `[\D]\0`},
+	}, "lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.ScopeName != "source.lab" {
+		t.Errorf("Expected the scope of the language, got %s", g.ScopeName)
+	}
+	var includes []string
+	for _, p := range g.Patterns {
+		includes = append(includes, p.Include)
+	}
+	if strings.Join(includes, " ") != "#reserved #identifier #string #string-4 #money #other" {
+		t.Errorf("Expected the rules by their priority but the whitespace, got %v", includes)
+	}
+	for key, expected := range map[string]lexer.TextMatePattern{
+		"identifier": {Name: "variable.other.identifier.lab", Match: `[\p{L}_][0-9A-Z_a-z]*`},
+		"reserved":   {Name: "keyword.control.reserved.lab", Match: `(?<![\p{L}0-9_])(?:if|while)(?![\p{L}0-9_])`},
+		"string":     {Name: "string.quoted.string.lab", Match: `"[^"\n\&\[]*"`},
+		"string-4":   {Name: "string.quoted.string.lab", Match: "`[^`]*`"},
+		"money":      {Name: "keyword.other.money.lab", Match: `\$[0-9]+\{`},
+		"other":      {Name: "keyword.other.other.lab", Match: `[[^0-9]]\x00`},
+	} {
+		if p := g.Repository[key]; p.Name != expected.Name || p.Match != expected.Match {
+			t.Errorf("Expected %s to be %+v, got %+v", key, expected, p)
+		}
+	}
+
+	if _, err = lexer.TextMateGrammar([]lexer.Rule{{Name: "bad", Pattern: `(a`}}, "lab"); err == nil || !strings.Contains(err.Error(), "rule bad") {
+		t.Errorf("Expected the rule not parsing to fail, got %v", err)
+	}
+}
+
+func TestTextMateGrammar_Default(t *testing.T) {
+	g, err := lexer.TextMateGrammar(nil, "lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Patterns[0].Include != "#comment-block" || g.Patterns[3].Include != "#type" || g.Patterns[4].Include != "#reserved" {
+		t.Errorf("Expected the comments, then the keywords first, got %v", g.Patterns[:5])
+	}
+	if block := g.Repository["comment-block"]; block.Patterns[0].Include != "#comment-block" {
+		t.Errorf("Expected the block comments nested, got %+v", block)
+	}
+	if _, ok := g.Repository["comment"]; ok {
+		t.Error("Expected the comment rule replaced by the regions")
+	}
+	// the patterns without lookarounds read alike in Go
+	for key, texts := range map[string][2]string{
+		"identifier": {"变量_1", "1x"},
+		"float":      {"1.5e-3", "1."},
+		"string":     {`"a\"b"`, `"a`},
+		"char":       {`'\n'`, `''`},
+	} {
+		re := regexp.MustCompile(`^(?:` + g.Repository[key].Match + `)$`)
+		if !re.MatchString(texts[0]) || re.MatchString(texts[1]) {
+			t.Errorf("Expected %s to match %q but not %q", key, texts[0], texts[1])
+		}
+	}
+
+	var b strings.Builder
+	if err = g.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	var read lexer.TextMate
+	if err = json.Unmarshal([]byte(b.String()), &read); err != nil || read.Repository["reserved"].Match != g.Repository["reserved"].Match {
+		t.Errorf("Expected the grammar read back, got %v", err)
+	}
+}
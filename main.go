@@ -2,26 +2,94 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"runtime"
 
 	. "app/config"
 	entrypoint "app/entry-point"
+	"app/parser"
 	"app/utils/log"
 )
 
 func main() {
 	// EnvChecker()
 
+	// lab <command> runs a phase on its own, -t the tests of a target
+	if len(os.Args) > 1 && entrypoint.IsCommand(os.Args[1]) {
+		os.Exit(entrypoint.Command(os.Args[1], os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "help" {
+		entrypoint.CommandUsage(os.Stdout)
+		return
+	}
+
 	ReadFlag()
 
+	stdout := os.Stdout
+	switch Config.Summary {
+	case "":
+	case "json":
+		// the log goes to stderr, leaving stdout to the summary
+		os.Stdout = os.Stderr
+	default:
+		println("Unknown summary:", Config.Summary)
+		os.Exit(2)
+	}
+
+	switch Config.Diagnostics {
+	case "", "text", "json":
+	default:
+		println("Unknown diagnostics:", Config.Diagnostics)
+		os.Exit(2)
+	}
+
+	if _, err := parser.ParseBudget(Config.Budget); err != nil {
+		println(err.Error())
+		os.Exit(2)
+	}
+	if !parser.IntWidth(Config.Parser.IntWidth).Valid() {
+		println("Unknown int width:", Config.Parser.IntWidth)
+		os.Exit(2)
+	}
+
+	switch Config.Format {
+	case "", "lab":
+	default:
+		println("Unknown format:", Config.Format)
+		os.Exit(2)
+	}
+
 	switch Config.Target {
 	case "lexer":
 		entrypoint.LexerTest()
 	case "parser":
 		entrypoint.ParserTest()
+	case "compare-tables":
+		entrypoint.CompareTables()
+	case "rename":
+		entrypoint.Rename()
+	case "link":
+		entrypoint.Link()
+	case "antlr":
+		entrypoint.ImportANTLR()
+	case "verify-determinism":
+		entrypoint.VerifyDeterminism()
+	case "selftest":
+		entrypoint.SelfTest()
+	case "repl":
+		entrypoint.REPL()
 	default:
 		println("Unknown mode:", Config.Target)
+		os.Exit(2)
+	}
+
+	if Config.Summary == "json" {
+		if err := entrypoint.WriteSummary(stdout, Config.Target); err != nil {
+			println("Cannot write the summary:", err.Error())
+			os.Exit(parser.ExitInternal)
+		}
 	}
+	os.Exit(entrypoint.ExitCode())
 }
 
 func EnvChecker() {
@@ -10,6 +10,7 @@ if "%1"=="help" (
     echo How to run program:
     echo   start ./bin/main.exe
     echo   run ./bin/main.exe -h # for help
+    echo   run ./bin/lab.exe help # for the commands running a phase alone
     exit /b 0
 )
 
@@ -22,6 +23,7 @@ if "%1"=="tidy" (
     call "%~f0" fmt
     echo Building the project...
     go build -o ./bin/main.exe -tags using_mmap_io
+    go build -o ./bin/lab.exe -tags using_mmap_io
 ) else if "%1"=="test-io" (
     echo Running go mod tidy and formatting the code...
     call "%~f0" tidy
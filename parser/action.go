@@ -0,0 +1,492 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"app/lexer"
+	"app/parser/ir"
+)
+
+// actionValue is the attribute $$ and $n stand for without a name, the
+// value of the node.
+const actionValue = "value"
+
+// action is a semantic action written in the expression language of the
+// translation schemes, compiled to the function running it.
+type action struct {
+	run  SemanticAction
+	uses int // the largest n of the $n read, 0 if none
+}
+
+// actionFunctions are the functions of the language, given the context of
+// the reduction and their arguments.
+var actionFunctions = map[string]func(c *ReduceContext, args []any) (any, error){
+	"newtemp": func(c *ReduceContext, args []any) (any, error) {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("newtemp takes no arguments")
+		}
+		return c.Walker.Emitter().NewTemp(), nil
+	},
+	"newlabel": func(c *ReduceContext, args []any) (any, error) {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("newlabel takes no arguments")
+		}
+		return c.Walker.NewLabel(""), nil
+	},
+	"emit": func(c *ReduceContext, args []any) (any, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("emit expects the parts of an instruction")
+		}
+		parts := make([]string, len(args))
+		for i, arg := range args {
+			parts[i] = formatActionValue(arg)
+		}
+		c.Walker.EmitQuad(ir.Parse(strings.Join(parts, " ")))
+		return nil, nil
+	},
+}
+
+// ParseAction compiles a semantic action written in the expression language
+// of the translation schemes, statements split by ; such as
+//
+//	$$ = newtemp(); emit($$, "=", $1, "+", $3)
+//
+// where $$ is the value of the head and $1, $2, ... the values of the symbols
+// of the body, $$.name and $1.name their other attributes. The value of a
+// terminal is its token, as a number if it is one. Values are integers,
+// floats or strings, with + - * / % on numbers, + joining strings too, and
+// the functions newtemp() and newlabel() allocating a temporary and a label,
+// and emit(...) appending the instruction its arguments make, split by
+// spaces, to the code.
+func ParseAction(src string) (SemanticAction, error) {
+	a, err := parseAction(src)
+	if err != nil {
+		return nil, err
+	}
+	return a.run, nil
+}
+
+func parseAction(src string) (*action, error) {
+	tokens, err := scanAction(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &actionParser{tokens: tokens, end: len(src) + 1}
+	var stmts []func(c *ReduceContext) error
+	for p.peek().text != "" {
+		stmt, err := p.stmt()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+		if t := p.peek(); t.text != "" && !p.accept(";") {
+			return nil, actionErrorf(t.pos, "expected ; or the end of the action, got %s", t.text)
+		}
+	}
+	return &action{
+		run: func(c *ReduceContext) error {
+			for _, stmt := range stmts {
+				if err := stmt(c); err != nil {
+					return c.Errorf("%s: %v", formatProduction(*c.Production), err)
+				}
+			}
+			return nil
+		},
+		uses: p.uses,
+	}, nil
+}
+
+// actionSyntaxError is an error in the text of an action, at a position from
+// 1 in it.
+type actionSyntaxError struct {
+	pos int
+	msg string
+}
+
+func actionErrorf(pos int, format string, args ...any) error {
+	return &actionSyntaxError{pos: pos, msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *actionSyntaxError) Error() string {
+	return fmt.Sprintf("%s, at pos %d", e.msg, e.pos)
+}
+
+// actionToken is a token of an action, at a position from 1 in its text.
+type actionToken struct {
+	text string
+	pos  int
+}
+
+// scanAction splits the action into its tokens: $$ and $n, names, numbers,
+// quoted strings and operators.
+func scanAction(src string) ([]actionToken, error) {
+	var tokens []actionToken
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		j := i + 1
+		switch {
+		case unicode.IsSpace(c):
+			i = j
+			continue
+		case c == '$':
+			if j < len(src) && src[j] == '$' {
+				j++
+				break
+			}
+			for j < len(src) && unicode.IsDigit(rune(src[j])) {
+				j++
+			}
+			if j == i+1 {
+				return nil, actionErrorf(i+1, "expected $$ or $ and a number")
+			}
+		case c == '_' || unicode.IsLetter(c):
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+		case unicode.IsDigit(c):
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+		case c == '"':
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(src) {
+				return nil, actionErrorf(i+1, "unterminated string")
+			}
+			j++
+		case !strings.ContainsRune("+-*/%()=,;.", c):
+			return nil, actionErrorf(i+1, "unexpected %c", c)
+		}
+		tokens = append(tokens, actionToken{text: src[i:j], pos: i + 1})
+		i = j
+	}
+	return tokens, nil
+}
+
+// actionParser compiles the tokens of an action by recursive descent.
+type actionParser struct {
+	tokens []actionToken
+	i      int
+	end    int // the position past the action
+	uses   int
+}
+
+func (p *actionParser) peek() actionToken {
+	if p.i < len(p.tokens) {
+		return p.tokens[p.i]
+	}
+	return actionToken{}
+}
+
+func (p *actionParser) accept(text string) bool {
+	if p.peek().text == text {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *actionParser) expect(text string) error {
+	if t := p.peek(); !p.accept(text) {
+		if t.text == "" {
+			return actionErrorf(p.end, "expected %s, got the end of the action", text)
+		}
+		return actionErrorf(t.pos, "expected %s, got %s", text, t.text)
+	}
+	return nil
+}
+
+// stmt compiles $$ = expr, $$.name = expr or a call.
+func (p *actionParser) stmt() (func(c *ReduceContext) error, error) {
+	if p.peek().text == "$$" {
+		p.i++
+		key := actionValue
+		if p.accept(".") {
+			name := p.peek()
+			if !isActionName(name.text) {
+				return nil, actionErrorf(name.pos, "expected the name of an attribute")
+			}
+			p.i++
+			key = name.text
+		}
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return func(c *ReduceContext) error {
+			v, err := value(c)
+			if err == nil {
+				c.Set(key, v)
+			}
+			return err
+		}, nil
+	}
+	t := p.peek()
+	call, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := actionFunctions[t.text]; !ok {
+		return nil, actionErrorf(t.pos, "expected an assignment to $$ or a call")
+	}
+	return func(c *ReduceContext) error {
+		_, err := call(c)
+		return err
+	}, nil
+}
+
+type actionExpr func(c *ReduceContext) (any, error)
+
+// expr compiles a sum of products.
+func (p *actionParser) expr() (actionExpr, error) {
+	return p.binary([]string{"+", "-"}, func() (actionExpr, error) {
+		return p.binary([]string{"*", "/", "%"}, p.unary)
+	})
+}
+
+func (p *actionParser) binary(ops []string, operand func() (actionExpr, error)) (actionExpr, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().text
+		if !slices.Contains(ops, op) {
+			return x, nil
+		}
+		p.i++
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left := x
+		x = func(c *ReduceContext) (any, error) {
+			a, err := left(c)
+			if err != nil {
+				return nil, err
+			}
+			b, err := y(c)
+			if err != nil {
+				return nil, err
+			}
+			return applyActionOperator(op, a, b)
+		}
+	}
+}
+
+func (p *actionParser) unary() (actionExpr, error) {
+	if p.accept("-") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(c *ReduceContext) (any, error) {
+			v, err := x(c)
+			if err != nil {
+				return nil, err
+			}
+			return applyActionOperator("-", int64(0), v)
+		}, nil
+	}
+	return p.primary()
+}
+
+func (p *actionParser) primary() (actionExpr, error) {
+	t := p.peek()
+	p.i++
+	switch {
+	case t.text == "":
+		return nil, actionErrorf(p.end, "expected an operand, got the end of the action")
+	case t.text == "(":
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case t.text[0] == '$':
+		n := 0
+		if t.text != "$$" {
+			n, _ = strconv.Atoi(t.text[1:])
+			if n == 0 {
+				return nil, actionErrorf(t.pos, "symbols are counted from $1")
+			}
+			p.uses = max(p.uses, n)
+		}
+		key := ""
+		if p.accept(".") {
+			name := p.peek()
+			if !isActionName(name.text) {
+				return nil, actionErrorf(name.pos, "expected the name of an attribute")
+			}
+			p.i++
+			key = name.text
+		}
+		return func(c *ReduceContext) (any, error) { return attribute(c, t.text, n, key) }, nil
+	case t.text[0] == '"':
+		s, err := strconv.Unquote(t.text)
+		if err != nil {
+			return nil, actionErrorf(t.pos, "bad string %s", t.text)
+		}
+		return func(*ReduceContext) (any, error) { return s, nil }, nil
+	case unicode.IsDigit(rune(t.text[0])):
+		v := parseActionNumber(t.text)
+		if v == nil {
+			return nil, actionErrorf(t.pos, "bad number %s", t.text)
+		}
+		return func(*ReduceContext) (any, error) { return v, nil }, nil
+	case isActionName(t.text):
+		function, ok := actionFunctions[t.text]
+		if !ok {
+			return nil, actionErrorf(t.pos, "unknown function %s", t.text)
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var args []actionExpr
+		for !p.accept(")") {
+			if len(args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		return func(c *ReduceContext) (any, error) {
+			values := make([]any, len(args))
+			for i, arg := range args {
+				v, err := arg(c)
+				if err != nil {
+					return nil, err
+				}
+				values[i] = v
+			}
+			return function(c, values)
+		}, nil
+	}
+	return nil, actionErrorf(t.pos, "unexpected %s", t.text)
+}
+
+func isActionName(text string) bool {
+	return text != "" && (text[0] == '_' || unicode.IsLetter(rune(text[0])))
+}
+
+// attribute returns the attribute of $$ or $n, its value if the key is empty.
+func attribute(c *ReduceContext, name string, n int, key string) (any, error) {
+	node := c.Result
+	if n > 0 {
+		if n > len(c.Nodes) {
+			return nil, fmt.Errorf("%s is past the %d symbols of the body", name, len(c.Nodes))
+		}
+		node = c.Nodes[n-1]
+	}
+	if key == "" {
+		key = actionValue
+	} else {
+		name += "." + key
+	}
+	if v, ok := node.Attributes[key]; ok {
+		return v, nil
+	}
+	if key == actionValue && n > 0 && len(node.Children) == 0 && node.Token != nil && node.Token.Type != lexer.EXTRA {
+		if v := parseActionNumber(node.Token.Val); v != nil {
+			return v, nil
+		}
+		return node.Token.Val, nil
+	}
+	return nil, fmt.Errorf("%s is not set", name)
+}
+
+// parseActionNumber returns the number as an int64 or a float64, nil if it
+// is not one.
+func parseActionNumber(text string) any {
+	if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(text, 64); err == nil && text != "" && unicode.IsDigit(rune(text[0])) {
+		return v
+	}
+	return nil
+}
+
+// applyActionOperator applies the arithmetic operator to the values, + also
+// joining them if either is a string.
+func applyActionOperator(op string, a, b any) (any, error) {
+	_, as := a.(string)
+	_, bs := b.(string)
+	if as || bs {
+		if op != "+" {
+			return nil, fmt.Errorf("invalid operation %s %s %s on strings", formatActionValue(a), op, formatActionValue(b))
+		}
+		return formatActionValue(a) + formatActionValue(b), nil
+	}
+	x, xok := a.(int64)
+	y, yok := b.(int64)
+	if xok && yok {
+		switch op {
+		case "+":
+			return x + y, nil
+		case "-":
+			return x - y, nil
+		case "*":
+			return x * y, nil
+		}
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "/" {
+			return x / y, nil
+		}
+		return x % y, nil
+	}
+	f, fok := toFloat(a)
+	g, gok := toFloat(b)
+	if !fok || !gok {
+		return nil, fmt.Errorf("invalid operation %s %s %s", formatActionValue(a), op, formatActionValue(b))
+	}
+	switch op {
+	case "+":
+		return f + g, nil
+	case "-":
+		return f - g, nil
+	case "*":
+		return f * g, nil
+	case "/":
+		return f / g, nil
+	}
+	return nil, fmt.Errorf("invalid operation %s %% %s on floats", formatActionValue(a), formatActionValue(b))
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// formatActionValue returns the value as text, as emit writes it.
+func formatActionValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,108 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+)
+
+const schemeBNF = `%token id num
+%left '+' '-'
+%left '*'
+
+stmts -> stmts stmt | stmt
+stmt -> id '=' expr ';'   {{ emit($1, "=", $3) }}
+expr -> expr '+' expr     {{ $$ = newtemp(); emit($$, "=", $1, "+", $3) }}
+      | expr '*' expr     {{ $$ = newtemp(); emit($$, "=", $1, "*", $3) }}
+      | '(' expr ')'      {{ $$ = $2 }}
+      | '-' expr          {{ $$ = $2.neg; $$.neg = $2 }}
+      | id                {{ $$ = $1; $$.neg = "-" + $1 }}
+      | num               {{ $$ = $1 * 2 / 2; $$.neg = -$1 }}
+`
+
+func TestReadBNF_Actions(t *testing.T) {
+	g, err := ReadBNF(strings.NewReader(schemeBNF))
+	if err != nil {
+		t.Fatalf("ReadBNF: %v", err)
+	}
+	if action := g.Productions[2].Action; action != `emit($1, "=", $3)` || g.Productions[2].Rule == nil || g.Productions[0].Rule != nil {
+		t.Errorf("Expected the actions to be the rules of their productions, got %q", action)
+	}
+	p := &Parser{Grammar: g}
+	p.BuildFirstSet()
+	p.BuildTable()
+	var last string
+	w := p.Tables().Parse(lexer.NewLexer(strings.NewReader("a = b + 2 * (c + 1); d = -3 + -a;")), func(s string) { last = s })
+	if last != "Parsing completed successfully." {
+		t.Fatalf("Expected the program to parse, got %q", last)
+	}
+	expected := []string{
+		"$(0x10000000) = c + 1",
+		"$(0x10000001) = 2 * $(0x10000000)",
+		"$(0x10000002) = b + $(0x10000001)",
+		"a = $(0x10000002)",
+		"$(0x10000003) = -3 + -a",
+		"d = $(0x10000003)",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(w.ThreeAddress, "\n"))
+	}
+
+	var b strings.Builder
+	if err := g.WriteBNF(&b); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadBNF(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("ReadBNF: %v\n%s", err, b.String())
+	}
+	for i, production := range read.Productions {
+		if production.Action != g.Productions[i].Action {
+			t.Errorf("Expected the action %q of %v to read back, got %q", g.Productions[i].Action, production, production.Action)
+		}
+	}
+
+	for src, expected := range map[string]string{
+		"a -> 'x' {{ $$ = $2 }}\n":           "$2 is past the 1 symbols of a → x, at line 0, pos 10",
+		"a -> 'x' {{ $$ = }}\n":              "expected an operand, got the end of the action in the action of a → x, at line 0, pos 18",
+		"a -> 'x' {{ $$ = f($1) }}\n":        "unknown function f in the action of a → x, at line 0, pos 18",
+		"a -> 'x' {{ $1 = 2 }}\n":            "expected an assignment to $$ or a call in the action of a → x, at line 0, pos 13",
+		"a -> 'x' {{ $$ = 1 }} 'y'\n":        "unexpected 'y' after the action in rule a, at line 0, pos 23",
+		"a -> ( 'x' {{ $$ = 1 }} ) 'y'\n":    "an action must end an alternative of rule a, not of a group, at line 0, pos 12",
+		"a -> 'x' {{ $$ = 1\n":               "unterminated action, at line 0, pos 10",
+		"a -> 'x' {{ $$ = \"1 }}\n":          "unterminated string in the action of a → x, at line 0, pos 18",
+		"a -> 'x' {{ emit($1) emit($1) }}\n": "expected ; or the end of the action, got emit in the action of a → x, at line 0, pos 22",
+	} {
+		if _, err := ReadBNF(strings.NewReader(src)); err == nil || err.Error() != expected {
+			t.Errorf("%q: expected %q, got %v", src, expected, err)
+		}
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	g, err := ReadBNF(strings.NewReader("%token num\ne -> e '/' e {{ $$ = $1 / $3 }} | e '+' e {{ $$ = $1 + $3.x }} | num\n%left '+' '/'\n"))
+	if err != nil {
+		t.Fatalf("ReadBNF: %v", err)
+	}
+	p := &Parser{Grammar: g}
+	p.BuildFirstSet()
+	p.BuildTable()
+	for src, expected := range map[string]string{
+		"1 / 0\n": "e → e / e: division by zero, at line 0, pos 2",
+		"1 + 2\n": "e → e + e: $3.x is not set, at line 0, pos 2",
+	} {
+		result, err := Compile(Options{Source: strings.NewReader(src), Tables: p.Tables()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.ContainsFunc(result.Diagnostics, func(d Diagnostic) bool { return strings.Contains(d.Message, expected) }) {
+			t.Errorf("%q: expected %q, got %v", src, expected, result.Diagnostics)
+		}
+	}
+	if _, err := ParseAction(`$$ = "a" - 1`); err != nil {
+		t.Errorf("Expected the types to be checked when the action runs, got %v", err)
+	}
+}
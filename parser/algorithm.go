@@ -1,6 +1,10 @@
 package parser
 
 import (
+	"cmp"
+	"context"
+	"maps"
+	"runtime"
 	"slices"
 
 	. "app/utils/collections"
@@ -9,10 +13,30 @@ import (
 // BuildStates constructs the LR(1) states for the parser based on the grammar.
 // It initializes the initial state with the augmented production and computes the closure of the items.
 // Then, it iterates through the states and computes the GOTO for each symbol, creating new states as needed.
+// The states are numbered breadth first from the initial state, with the symbols taken in the order the
+// grammar declares them, so that the numbering is the same on every run.
 // The resulting states are stored in the Parser's States field.
 // The function ensures that the symbols are built before constructing the states.
 func (p *Parser) BuildStates() {
+	_ = p.BuildStatesContext(context.Background())
+}
+
+// BuildStatesContext is BuildStates that gives up once the context is done,
+// leaving the states empty and returning the context's error.
+//
+// The states are built a level of the breadth first search at a time, on
+// p.Workers goroutines: the kernels of the GOTOs of the states of the level
+// are found in parallel and looked up in a stateSet by their hash, the new
+// ones are then numbered in the order of their states and symbols, as one
+// goroutine would, and closed in parallel. Only the kernels are compared,
+// which decide the states, so no closure is computed twice.
+func (p *Parser) BuildStatesContext(ctx context.Context) error {
 	p.EnsureSymbols()
+	p.EnsureFirstSet()
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
 
 	initialItem := LR1Item{
 		Production: p.Grammar.AugmentedProduction,
@@ -20,71 +44,114 @@ func (p *Parser) BuildStates() {
 		Lookahead:  TERMINATE,
 	}
 
-	initialState := &State{
+	// the states and their items come from the slabs of the arenas, one per
+	// worker, and the item sets GOTO computes are only copied there when
+	// they make a new state
+	arenas := make([]*arena, workers)
+	for i := range arenas {
+		arenas[i] = &arena{}
+	}
+	a := arenas[0]
+	initialState := a.state(State{
 		Index:       0,
-		Items:       LR1Items{initialItem},
+		Items:       a.keep(p.closure(a, LR1Items{initialItem})),
 		Transitions: make(map[Symbol]*State),
-	}
-
-	initialState.Items = p.CLOSURE(initialState.Items)
+	})
+	set := newStateSet(p.Grammar)
+	set.find(a, LR1Items{initialItem}, 0).state = initialState
 
 	p.States = States{initialState}
+	symbols := p.Symbols.Elements()
 
-	length := len(p.States)
-	for i := 0; i < length; i++ {
-		state := p.States[i]
-
-		for symbol := range p.Symbols {
-			gotoItems := p.GOTO(state.Items, symbol)
-			if len(gotoItems) == 0 {
-				continue
+	type move struct {
+		symbol Symbol
+		to     *stateEntry
+	}
+	for done := 0; done < len(p.States); {
+		level := p.States[done:]
+		moves := make([][]move, len(level))
+		err := parallel(ctx, workers, len(level), func(worker, i int) {
+			a := arenas[worker]
+			for j, symbol := range symbols {
+				if kernel := p.kernel(a, level[i].Items, symbol); len(kernel) > 0 {
+					moves[i] = append(moves[i], move{symbol, set.find(a, kernel, i*len(symbols)+j)})
+				}
 			}
+		})
+		if err != nil {
+			p.States = States{}
+			return err
+		}
 
-			newState := &State{
-				Index:       len(p.States),
-				Items:       gotoItems,
-				Transitions: make(map[Symbol]*State),
-			}
-			index := slices.IndexFunc(p.States, func(s *State) bool {
-				return s.Equals(newState)
-			})
-			if index == -1 {
-				p.States = append(p.States, newState)
-				state.Transitions[symbol] = newState
-				length++
-			} else {
-				state.Transitions[symbol] = p.States[index]
+		var kernels []LR1Items
+		next := len(p.States)
+		for i, state := range level {
+			for _, m := range moves[i] {
+				if m.to.state == nil {
+					m.to.state = a.state(State{
+						Index:       next + len(kernels),
+						Transitions: make(map[Symbol]*State),
+					})
+					kernels = append(kernels, m.to.kernel)
+					p.States = append(p.States, m.to.state)
+				}
+				state.Transitions[m.symbol] = m.to.state
 			}
 		}
+		if err := CheckBudget("states", int64(len(p.States)), int64(p.Budget.MaxStates)); err != nil {
+			p.States = States{}
+			return err
+		}
+		err = parallel(ctx, workers, len(kernels), func(worker, i int) {
+			a := arenas[worker]
+			p.States[next+i].Items = a.keep(p.closure(a, kernels[i]))
+		})
+		if err != nil {
+			p.States = States{}
+			return err
+		}
+		done += len(level)
 	}
+	return nil
 }
 
 // BuildSymbols constructs the set of symbols used in the grammar by iterating through the productions.
+// The symbols are kept in the order the grammar declares them, the order they first appear in its
+// productions, the augmented one first, which is the order BuildStates takes them in.
 func (p *Parser) BuildSymbols() {
-	p.Symbols = Set[Symbol]{}
+	used := Set[Symbol]{}
 	for _, production := range p.Grammar.Productions {
-		p.Symbols.Add(production.Head)
+		used.Add(production.Head)
 		for _, symbol := range production.Body {
-			p.Symbols.Add(symbol)
+			used.Add(symbol)
 		}
 	}
+	used.Remove(EPSILON)
 
-	p.Symbols.Remove(EPSILON)
+	p.Symbols = NewOrderedSet[Symbol]()
+	for _, production := range append([]Production{p.Grammar.AugmentedProduction}, p.Grammar.Productions...) {
+		for _, symbol := range append([]Symbol{production.Head}, production.Body...) {
+			if used.Contains(symbol) {
+				p.Symbols.Add(symbol)
+			}
+		}
+	}
 }
 
 // BuildFirstSet constructs the FirstSet for the parser based on the grammar's productions.
+// Each set is sorted by the names of its terminals, so that the sets, and the lookaheads
+// CLOSURE adds from them, are iterated in the same order on every run.
 func (p *Parser) BuildFirstSet() {
 	p.EnsureSymbols()
 	p.FirstSet = make(FirstSet)
 
-	for terminal := range p.Grammar.Terminals {
-		p.FirstSet[Symbol(terminal)] = Set[Terminal]{}
-		p.FirstSet[Symbol(terminal)].Add(terminal)
+	for _, terminal := range slices.Sorted(maps.Keys(p.Grammar.Terminals)) {
+		p.FirstSet[Symbol(terminal)] = NewSortedSet(cmp.Compare[Terminal]).Add(terminal)
 	}
 
 	for _, production := range p.Grammar.Productions {
 		if _, exists := p.FirstSet[production.Head]; !exists {
-			p.FirstSet[production.Head] = Set[Terminal]{}
+			p.FirstSet[production.Head] = NewSortedSet(cmp.Compare[Terminal])
 		}
 	}
 
@@ -111,7 +178,7 @@ func (p *Parser) BuildFirstSet() {
 				}
 
 				if symbolFirstSet, isNonTerminal := p.FirstSet[symbol]; isNonTerminal {
-					for terminal := range symbolFirstSet {
+					for terminal := range symbolFirstSet.All() {
 						if !terminal.IsEpsilon() && !firstSet.Contains(terminal) {
 							firstSet.Add(terminal)
 							loop = true
@@ -142,24 +209,23 @@ func (p *Parser) BuildFirstSet() {
 // CLOSURE computes the closure of a set of LR1 items.
 // It adds new items to the closure based on the productions of the grammar and the lookahead symbols.
 func (p *Parser) CLOSURE(items []LR1Item) []LR1Item {
-	p.EnsureFirstSet()
-
-	closure := make([]LR1Item, len(items))
-	copy(closure, items)
-
-	marks := Set[string]{}
-
-	loop := true
-	for loop {
-		loop = false
+	return p.closure(&arena{}, items)
+}
 
-		for _, item := range closure {
-			if marks.Contains(item.AsKey()) {
-				continue
-			}
+// closure is CLOSURE computed in the buffer of the arena.
+func (p *Parser) closure(a *arena, items []LR1Item) []LR1Item {
+	p.EnsureFirstSet()
+	if a.lookaheads == nil {
+		a.lookaheads = NewSortedSet(cmp.Compare[Terminal])
+	}
 
-			marks.Add(item.AsKey())
+	closure := append(a.closure[:0], items...)
 
+	// every pass goes over the items the previous one added, until none is
+	done := 0
+	for done < len(closure) {
+		added := len(closure)
+		for _, item := range closure[done:added] {
 			if item.Dot >= len(item.Production.Body) {
 				continue
 			}
@@ -181,11 +247,10 @@ func (p *Parser) CLOSURE(items []LR1Item) []LR1Item {
 							return i.Equals(newItem)
 						}) {
 							closure = append(closure, newItem)
-							loop = true
 						}
 					} else {
-						lookaheads := p.findLookaheads(item.Production.Body[item.Dot+1:], item.Lookahead)
-						for lookahead := range lookaheads {
+						lookaheads := p.findLookaheads(a.lookaheads, item.Production.Body[item.Dot+1:], item.Lookahead)
+						for lookahead := range lookaheads.All() {
 							newItem := LR1Item{
 								Production: production,
 								Dot:        0,
@@ -196,21 +261,37 @@ func (p *Parser) CLOSURE(items []LR1Item) []LR1Item {
 								return i.Equals(newItem)
 							}) {
 								closure = append(closure, newItem)
-								loop = true
 							}
 						}
 					}
 				}
 			}
 		}
+		done = added
 	}
+	a.closure = closure
 	return closure
 }
 
 // GOTO computes the GOTO set of LR1 items for a given symbol.
 // It iterates through the items and checks if the dot position is at the symbol's index in the production body.
 func (p *Parser) GOTO(items LR1Items, symbol Symbol) LR1Items {
-	gotoItems := LR1Items{}
+	return p.goTo(&arena{}, items, symbol)
+}
+
+// goTo is GOTO computed in the buffers of the arena.
+func (p *Parser) goTo(a *arena, items LR1Items, symbol Symbol) LR1Items {
+	gotoItems := p.kernel(a, items, symbol)
+	if len(gotoItems) == 0 {
+		return LR1Items{}
+	}
+	return p.closure(a, gotoItems)
+}
+
+// kernel returns the items of GOTO before the closure, the items with the
+// dot moved over the symbol, in the buffer of the arena.
+func (p *Parser) kernel(a *arena, items LR1Items, symbol Symbol) LR1Items {
+	gotoItems := a.kernel[:0]
 	for _, item := range items {
 		if item.Dot < len(item.Production.Body) && item.Production.Body[item.Dot] == symbol {
 			newItem := LR1Item{
@@ -221,26 +302,27 @@ func (p *Parser) GOTO(items LR1Items, symbol Symbol) LR1Items {
 			gotoItems = append(gotoItems, newItem)
 		}
 	}
-	return p.CLOSURE(gotoItems)
+	a.kernel = gotoItems
+	return gotoItems
 }
 
 // findLookaheads computes the lookahead symbols for a given set of symbols and a lookahead terminal.
 // It checks if the symbols are empty or if they contain epsilon, and adds the lookahead terminal accordingly.
-func (p *Parser) findLookaheads(symbols []Symbol, lookahead Terminal) Set[Terminal] {
+// The lookaheads are computed in the given set, cleared first, which is returned.
+func (p *Parser) findLookaheads(firstSet *OrderedSet[Terminal], symbols []Symbol, lookahead Terminal) *OrderedSet[Terminal] {
+	firstSet.Clear()
 	if len(symbols) == 0 {
-		s := Set[Terminal]{}
-		s.Add(lookahead)
-		return s
+		firstSet.Add(lookahead)
+		return firstSet
 	}
 
 	flag := true
-	firstSet := Set[Terminal]{}
 	for _, symbol := range symbols {
-		if p.Grammar.IsTerminal(symbol) {
-			firstSet.Add(Terminal(symbol))
+		if terminal, ok := p.Grammar.Terminal(symbol); ok {
+			firstSet.Add(terminal)
 		}
 
-		for terminal := range p.FirstSet[symbol] {
+		for terminal := range p.FirstSet[symbol].All() {
 			if !terminal.IsEpsilon() {
 				firstSet.Add(terminal)
 			}
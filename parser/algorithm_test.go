@@ -1,7 +1,12 @@
 package parser_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"testing"
 
 	. "app/parser"
@@ -66,7 +71,7 @@ func TestParser_BuildFirstSet(t *testing.T) {
 		name        string
 		productions []Production
 		terminals   []Terminal
-		expected    FirstSet
+		expected    map[Symbol]Set[Terminal]
 	}{
 		{
 			name: "Test1",
@@ -81,7 +86,7 @@ func TestParser_BuildFirstSet(t *testing.T) {
 				{Head: "F", Body: []Symbol{"id"}},
 			},
 			terminals: []Terminal{"id", "+", "*", "(", ")", EPSILON},
-			expected: FirstSet{
+			expected: map[Symbol]Set[Terminal]{
 				"E":  Set[Terminal]{}.AddAll("(", "id"),
 				"E'": Set[Terminal]{}.AddAll("+", EPSILON),
 				"T":  Set[Terminal]{}.AddAll("(", "id"),
@@ -104,7 +109,7 @@ func TestParser_BuildFirstSet(t *testing.T) {
 				{Head: "C", Body: []Symbol{"ε"}},
 			},
 			terminals: []Terminal{"a", "b", "c", "d", "e", "f", "g", "h", "ε"},
-			expected: FirstSet{
+			expected: map[Symbol]Set[Terminal]{
 				"S": Set[Terminal]{}.AddAll("a", "ε"),
 				"A": Set[Terminal]{}.AddAll("a", "d", "ε"),
 				"B": Set[Terminal]{}.AddAll("a", "d", "h", "e", "ε"),
@@ -129,7 +134,7 @@ func TestParser_BuildFirstSet(t *testing.T) {
 				{Head: "P", Body: []Symbol{"^"}},
 			},
 			terminals: []Terminal{"+", "(", ")", "a", "b", "^", "*", "ε"},
-			expected: FirstSet{
+			expected: map[Symbol]Set[Terminal]{
 				"E":  Set[Terminal]{}.AddAll("(", "a", "b", "^"),
 				"E'": Set[Terminal]{}.AddAll("+", "ε"),
 				"T":  Set[Terminal]{}.AddAll("(", "a", "b", "^"),
@@ -148,7 +153,7 @@ func TestParser_BuildFirstSet(t *testing.T) {
 				{Head: "B", Body: []Symbol{"a"}},
 			},
 			terminals: []Terminal{"a", "b", "c"},
-			expected: FirstSet{
+			expected: map[Symbol]Set[Terminal]{
 				"B": Set[Terminal]{}.AddAll("a", "b"),
 				"D": Set[Terminal]{}.AddAll("a", "b", "c"),
 			},
@@ -166,7 +171,7 @@ func TestParser_BuildFirstSet(t *testing.T) {
 				{Head: "F", Body: []Symbol{"(", "E", ")"}},
 			},
 			terminals: []Terminal{"+", "*", "i", "(", ")", "ε"},
-			expected: FirstSet{
+			expected: map[Symbol]Set[Terminal]{
 				"E": Set[Terminal]{}.AddAll("i", "("),
 				"A": Set[Terminal]{}.AddAll("+", "ε"),
 				"T": Set[Terminal]{}.AddAll("i", "("),
@@ -186,7 +191,7 @@ func TestParser_BuildFirstSet(t *testing.T) {
 			p.BuildFirstSet()
 			for head, expected := range tt.expected {
 				fmt.Printf("FIRST(%s) : %v", head, p.FirstSet[head])
-				if !p.FirstSet[head].Equals(expected) {
+				if !p.FirstSet[head].ToSet().Equals(expected) || !slices.IsSorted(p.FirstSet[head].Elements()) {
 					fmt.Println(log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: " !!! FAILED", Args: []any{}}))
 					t.Errorf("Expected %v, got %v\n", expected, p.FirstSet[head])
 				} else {
@@ -301,3 +306,175 @@ func TestParser_BuildStates(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_BuildStates_Numbering(t *testing.T) {
+	build := func() *Parser {
+		p := &Parser{
+			Grammar: &Grammar{
+				AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
+				Productions: []Production{
+					{Head: "S", Body: []Symbol{"B", "B"}},
+					{Head: "B", Body: []Symbol{"a", "B"}},
+					{Head: "B", Body: []Symbol{"b"}},
+				},
+				Terminals: Set[Terminal]{}.AddAll("a", "b", EPSILON, TERMINATE),
+			},
+		}
+		p.BuildFirstSet()
+		p.BuildStates()
+		return p
+	}
+	p := build()
+	// the numbering of the textbook, symbols taken in the order S, B, a, b
+	for _, tt := range []struct {
+		from   int
+		symbol Symbol
+		to     int
+	}{
+		{0, "S", 1}, {0, "B", 2}, {0, "a", 3}, {0, "b", 4},
+		{2, "B", 5}, {2, "a", 6}, {2, "b", 7},
+		{3, "B", 8}, {3, "a", 3}, {3, "b", 4},
+		{6, "B", 9}, {6, "a", 6}, {6, "b", 7},
+	} {
+		if next := p.States[tt.from].Transitions[tt.symbol]; next == nil || next.Index != tt.to {
+			t.Errorf("Expected GOTO(I%d, %s) = I%d, got %v", tt.from, tt.symbol, tt.to, next)
+		}
+	}
+
+	first := &strings.Builder{}
+	if err := p.WriteItems(first); err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		again := &strings.Builder{}
+		if err := build().WriteItems(again); err != nil {
+			t.Fatal(err)
+		}
+		if again.String() != first.String() {
+			t.Fatalf("Expected the same item sets on every build, got\n%s\nand\n%s", first, again)
+		}
+	}
+}
+
+func TestParser_BuildStates_Workers(t *testing.T) {
+	build := func(workers int) (*Parser, string) {
+		p := NewParser()
+		p.Workers = workers
+		p.BuildStates()
+		items := &strings.Builder{}
+		if err := p.WriteItems(items); err != nil {
+			t.Fatal(err)
+		}
+		for _, state := range p.States {
+			for _, symbol := range slices.Sorted(maps.Keys(state.Transitions)) {
+				fmt.Fprintf(items, "%d %s %d\n", state.Index, symbol, state.Transitions[symbol].Index)
+			}
+		}
+		return p, items.String()
+	}
+	// the states built on one goroutine are numbered and ordered as on many
+	p, expected := build(1)
+	for _, workers := range []int{2, 8} {
+		if _, got := build(workers); got != expected {
+			t.Errorf("Expected the states of %d workers to be those of one", workers)
+		}
+	}
+	for i, state := range p.States {
+		if state.Index != i || len(state.Items) == 0 {
+			t.Fatalf("Expected the state %d at its index with its items, got %d", i, state.Index)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p = NewParser()
+	p.Workers = 4
+	if err := p.BuildStatesContext(ctx); !errors.Is(err, context.Canceled) || len(p.States) != 0 {
+		t.Errorf("Expected the build to stop with no states, got %v and %d states", err, len(p.States))
+	}
+}
+
+func TestState_FormatItems(t *testing.T) {
+	p := &Parser{
+		Grammar: &Grammar{
+			AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
+			Productions: []Production{
+				{Head: "S", Body: []Symbol{"B", "B"}},
+				{Head: "B", Body: []Symbol{"a", "B"}},
+				{Head: "B", Body: []Symbol{"b"}},
+			},
+			Terminals: Set[Terminal]{}.AddAll("a", "b", EPSILON, TERMINATE),
+		},
+	}
+	p.BuildFirstSet()
+	p.BuildStates()
+	lines := p.States[0].FormatItems()
+	slices.Sort(lines)
+	expected := []string{
+		"[B → · a B, a/b]",
+		"[B → · b, a/b]",
+		"[S → · B B, $]",
+		"[S' → · S, $]",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+
+	buf := &strings.Builder{}
+	if err := p.WriteItems(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "I0:\n") || !strings.Contains(buf.String(), "GOTO(I0, S) = I") {
+		t.Errorf("Unexpected item sets:\n%s", buf.String())
+	}
+}
+
+func BenchmarkBuildStates(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		p := NewParser()
+		p.BuildFirstSet()
+		p.BuildStates()
+	}
+}
+
+func TestParser_ExportDOT(t *testing.T) {
+	p := &Parser{
+		Grammar: &Grammar{
+			AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
+			Productions: []Production{
+				{Head: "S", Body: []Symbol{"B", "B"}},
+				{Head: "B", Body: []Symbol{"a", "B"}},
+				{Head: "B", Body: []Symbol{"b"}},
+			},
+			Terminals: Set[Terminal]{}.AddAll("a", "b", EPSILON, TERMINATE),
+		},
+	}
+	p.BuildFirstSet()
+	p.BuildStates()
+	if kernel := p.States[0].Kernel(p.Grammar); len(kernel) != 1 || kernel[0].Core() != "S' → · S" {
+		t.Errorf("Expected the kernel of I0 to be S' → · S, got %v", kernel)
+	}
+
+	buf := &strings.Builder{}
+	if err := p.ExportDOT(buf); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	accept := p.States[0].Transitions["S"].Index
+	reduce := p.States[0].Transitions["b"].Index
+	for _, expected := range []string{
+		"digraph LR1 {\n",
+		"    I0 [label=\"I0\\n[S' → · S, $]\\l\"];\n",
+		fmt.Sprintf("    I%d [label=\"I%d\\n[S' → S ·, $]\\l\", style=filled, fillcolor=\"palegreen\"];\n", accept, accept),
+		fmt.Sprintf("    I%d [label=\"I%d\\n[B → b ·, a/b]\\l\", style=filled, fillcolor=\"lightyellow\"];\n", reduce, reduce),
+		fmt.Sprintf("    I0 -> I%d [label=\"S\"];\n", accept),
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("Expected %q in\n%s", expected, dot)
+		}
+	}
+	if strings.Count(dot, " -> ") != 13 {
+		t.Errorf("Expected an edge per transition, got\n%s", dot)
+	}
+}
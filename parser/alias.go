@@ -0,0 +1,27 @@
+package parser
+
+import "strings"
+
+// SetAlias declares the name the terminal goes by in the messages and the
+// reports, such as "identifier" for id, and returns the grammar.
+func (g *Grammar) SetAlias(terminal Terminal, alias string) *Grammar {
+	if g.Aliases == nil {
+		g.Aliases = map[Terminal]string{}
+	}
+	g.Aliases[terminal] = alias
+	return g
+}
+
+// Name returns the alias of the symbol, the symbol itself if it has none.
+func (g *Grammar) Name(symbol Symbol) string {
+	return g.Symbol(symbol).Name
+}
+
+// names returns the names of the terminals in the registry joined by commas.
+func names(symbols *SymbolRegistry, terminals []Terminal) string {
+	s := make([]string, len(terminals))
+	for i, terminal := range terminals {
+		s[i] = symbols.Name(Symbol(terminal))
+	}
+	return strings.Join(s, ", ")
+}
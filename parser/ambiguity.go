@@ -0,0 +1,281 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Ambiguity is a sentence a nonterminal derives with two parse trees, the
+// evidence the grammar is ambiguous whatever the table does of its
+// conflicts.
+type Ambiguity struct {
+	Symbol   Symbol
+	Sentence []Terminal
+	Trees    [2]string // bracketed, such as expr(expr(id) + expr(id))
+}
+
+// ProbeAmbiguity looks for an ambiguous sentence of every head of the
+// grammar, in the order of Heads: it enumerates the sentences the head
+// derives of maxLength terminals at most, the shortest maxSentences of them,
+// and parses each with a chart that keeps every tree of every span, as a
+// generalized parser would. The shortest sentence with two trees is the one
+// reported. A derivation coming back to a nonterminal over the same span,
+// such as A -> A or a cycle through empty bodies, gives the span infinitely
+// many trees, of which it reports the one going around the cycle once. No
+// ambiguity found does not prove there is none beyond the bounds.
+func (g *Grammar) ProbeAmbiguity(maxLength, maxSentences int) []Ambiguity {
+	sentences := g.sentences(maxLength, maxSentences)
+	c := newChart(g, sentences, maxLength)
+	var ambiguities []Ambiguity
+	for _, head := range g.Heads() {
+		for _, sentence := range sentences[head] {
+			if trees := c.parse(head, sentence); len(trees) >= 2 {
+				ambiguities = append(ambiguities, Ambiguity{Symbol: head, Sentence: sentence, Trees: [2]string{trees[0], trees[1]}})
+				break
+			}
+		}
+	}
+	return ambiguities
+}
+
+// ReportAmbiguities writes the ambiguities ProbeAmbiguity finds, each with
+// its sentence and two of its trees.
+func (g *Grammar) ReportAmbiguities(w io.Writer, maxLength, maxSentences int) error {
+	ambiguities := g.ProbeAmbiguity(maxLength, maxSentences)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d ambiguous nonterminals in sentences of %d terminals at most\n", len(ambiguities), maxLength)
+	for _, a := range ambiguities {
+		fmt.Fprintf(&b, "\n%s derives %s in two ways:\n  %s\n  %s\n", a.Symbol, g.sentence(a.Sentence), a.Trees[0], a.Trees[1])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// sentence returns the names of the terminals split by spaces, ε if none.
+func (g *Grammar) sentence(terminals []Terminal) string {
+	if len(terminals) == 0 {
+		return EPSILON
+	}
+	s := make([]string, len(terminals))
+	for i, terminal := range terminals {
+		s[i] = g.Name(Symbol(terminal))
+	}
+	return strings.Join(s, " ")
+}
+
+// compareSentences orders the sentences by length, then by their terminals.
+func compareSentences(a, b []Terminal) int {
+	return cmp.Or(cmp.Compare(len(a), len(b)), slices.Compare(a, b))
+}
+
+// sentences returns the shortest sentences of each nonterminal, limit at most
+// of maxLength terminals at most, computed to a fixed point: the sentences of
+// a body are those of its symbols put together, those of a nonterminal the
+// ones of its bodies. The limit applies to the sentences of a body as they
+// are put together too, so that a long body does not multiply them.
+func (g *Grammar) sentences(maxLength, limit int) map[Symbol][][]Terminal {
+	sentences := map[Symbol][][]Terminal{}
+	shortest := func(candidates [][]Terminal) [][]Terminal {
+		slices.SortFunc(candidates, compareSentences)
+		candidates = slices.CompactFunc(candidates, func(a, b []Terminal) bool { return slices.Equal(a, b) })
+		return candidates[:min(len(candidates), limit)]
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, head := range g.Heads() {
+			candidates := slices.Clone(sentences[head])
+			for _, production := range g.Productions {
+				if production.Head != head {
+					continue
+				}
+				body := [][]Terminal{{}}
+				for _, symbol := range production.Body {
+					if symbol.IsEpsilon() {
+						continue
+					}
+					parts := sentences[symbol]
+					if terminal, ok := g.Terminal(symbol); ok {
+						parts = [][]Terminal{{terminal}}
+					}
+					var next [][]Terminal
+					for _, prefix := range body {
+						for _, part := range parts {
+							if len(prefix)+len(part) <= maxLength {
+								next = append(next, append(slices.Clip(prefix), part...))
+							}
+						}
+					}
+					body = shortest(next)
+				}
+				candidates = append(candidates, body...)
+			}
+			if candidates = shortest(candidates); !slices.EqualFunc(candidates, sentences[head], slices.Equal) {
+				sentences[head] = candidates
+				changed = true
+			}
+		}
+	}
+	return sentences
+}
+
+type span struct {
+	symbol Symbol
+	from   int
+	to     int
+}
+
+// chart holds the trees of the spans of a sentence, two at most for each,
+// which is enough to tell an ambiguous span from one that is not.
+type chart struct {
+	g        *Grammar
+	bodies   map[Symbol][][]Symbol
+	shortest map[Symbol]int      // the length of the shortest sentence of each nonterminal
+	empty    map[Symbol][]string // the trees of the empty spans, the same in every sentence
+
+	sentence []Terminal
+	memo     map[span][]string
+	active   map[span]bool
+	cut      map[span]bool // the active spans a derivation came back to
+}
+
+// newChart returns a chart of the grammar, the sentences of which are
+// bounded by maxLength, sentences as they were enumerated.
+func newChart(g *Grammar, sentences map[Symbol][][]Terminal, maxLength int) *chart {
+	c := &chart{
+		g:        g,
+		bodies:   map[Symbol][][]Symbol{},
+		shortest: map[Symbol]int{},
+		empty:    map[Symbol][]string{},
+	}
+	for _, production := range g.Productions {
+		c.bodies[production.Head] = append(c.bodies[production.Head], production.Body)
+		// a nonterminal without sentences is in none of those parsed
+		c.shortest[production.Head] = maxLength + 1
+		if s := sentences[production.Head]; len(s) > 0 {
+			c.shortest[production.Head] = len(s[0])
+		}
+	}
+	return c
+}
+
+// parse returns two trees at most of the symbol deriving the sentence.
+func (c *chart) parse(symbol Symbol, sentence []Terminal) []string {
+	c.sentence = sentence
+	c.memo = map[span][]string{}
+	c.active = map[span]bool{}
+	c.cut = map[span]bool{}
+	return c.trees(symbol, 0, len(sentence))
+}
+
+// length returns the length of the shortest sentence of the symbols.
+func (c *chart) length(symbols []Symbol) int {
+	n := 0
+	for _, symbol := range symbols {
+		if shortest, ok := c.shortest[symbol]; ok {
+			n += shortest
+		} else if !symbol.IsEpsilon() {
+			n++
+		}
+	}
+	return n
+}
+
+// trees returns two trees at most of the symbol deriving the terminals of
+// the sentence from the index from to the index to. Coming back to a span
+// still active gives the marker of the span, which the span replaces by its
+// first tree once done: a cycle through the span is a tree more. The trees
+// of a span are kept unless they hold the marker of a span above it.
+func (c *chart) trees(symbol Symbol, from, to int) []string {
+	bodies, ok := c.bodies[symbol]
+	if !ok {
+		if to == from+1 && c.sentence[from] == Terminal(symbol) {
+			return []string{c.g.Name(symbol)}
+		}
+		return nil
+	}
+	if trees, ok := c.empty[symbol]; ok && from == to {
+		return trees
+	}
+	s := span{symbol, from, to}
+	if trees, ok := c.memo[s]; ok {
+		return trees
+	}
+	if c.active[s] {
+		c.cut[s] = true
+		return []string{c.marker(s)}
+	}
+	c.active[s] = true
+	var trees []string
+	for _, body := range bodies {
+		for _, children := range c.sequences(body, from, to) {
+			trees = append(trees, string(symbol)+"("+children+")")
+		}
+	}
+	if c.cut[s] {
+		marker := c.marker(s)
+		var cycles []string
+		trees = slices.DeleteFunc(trees, func(tree string) bool {
+			if strings.Contains(tree, marker) {
+				cycles = append(cycles, tree)
+				return true
+			}
+			return false
+		})
+		if len(trees) > 0 {
+			for _, cycle := range cycles {
+				trees = append(trees, strings.ReplaceAll(cycle, marker, trees[0]))
+			}
+		}
+	}
+	slices.Sort(trees)
+	trees = slices.Compact(trees)
+	trees = trees[:min(len(trees), 2)]
+	delete(c.active, s)
+	delete(c.cut, s)
+	if len(c.cut) == 0 {
+		c.memo[s] = trees
+		if from == to {
+			c.empty[symbol] = trees
+		}
+	}
+	return trees
+}
+
+// marker returns the marker of the span in the trees of a cycle.
+func (c *chart) marker(s span) string {
+	return fmt.Sprintf("\x00%s %d %d\x00", s.symbol, s.from, s.to)
+}
+
+// sequences returns four sequences of trees at most of the symbols of the
+// body deriving the span, split by spaces, ε for an empty body, four so that
+// those of cycles leave room for the others.
+func (c *chart) sequences(body []Symbol, from, to int) []string {
+	if len(body) == 0 {
+		if from == to {
+			return []string{EPSILON}
+		}
+		return nil
+	}
+	if body[0].IsEpsilon() {
+		return c.sequences(body[1:], from, to)
+	}
+	var sequences []string
+	for middle := from + c.length(body[:1]); middle <= to-c.length(body[1:]); middle++ {
+		for _, tree := range c.trees(body[0], from, middle) {
+			for _, rest := range c.sequences(body[1:], middle, to) {
+				if rest == EPSILON {
+					sequences = append(sequences, tree)
+				} else {
+					sequences = append(sequences, tree+" "+rest)
+				}
+				if len(sequences) == 4 {
+					return sequences
+				}
+			}
+		}
+	}
+	return sequences
+}
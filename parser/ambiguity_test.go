@@ -0,0 +1,81 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestGrammar_ProbeAmbiguity(t *testing.T) {
+	g, err := ReadBNF(strings.NewReader(`%token id
+e -> e '+' e | id
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ambiguities := g.ProbeAmbiguity(5, 50)
+	if len(ambiguities) != 1 {
+		t.Fatalf("Expected e to be ambiguous, got %v", ambiguities)
+	}
+	a := ambiguities[0]
+	if a.Symbol != "e" || !slices.Equal(a.Sentence, []Terminal{"id", "+", "id", "+", "id"}) {
+		t.Errorf("Expected e to derive id + id + id twice, got %v", a)
+	}
+	trees := []string{a.Trees[0], a.Trees[1]}
+	slices.Sort(trees)
+	if !slices.Equal(trees, []string{"e(e(e(id) + e(id)) + e(id))", "e(e(id) + e(e(id) + e(id)))"}) {
+		t.Errorf("Expected the trees of both associativities, got %v", trees)
+	}
+	if ambiguities := g.ProbeAmbiguity(4, 50); len(ambiguities) != 0 {
+		t.Errorf("Expected no ambiguity in sentences of 4 terminals, got %v", ambiguities)
+	}
+
+	// the precedences resolve the conflicts of the table, not the ambiguity
+	g, err = ReadBNF(strings.NewReader(exprBNF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var heads []Symbol
+	for _, a := range g.ProbeAmbiguity(5, 1000) {
+		heads = append(heads, a.Symbol)
+	}
+	if !slices.Equal(heads, []Symbol{"expr", "expr_opt"}) {
+		t.Errorf("Expected expr and expr_opt through it to be ambiguous, got %v", heads)
+	}
+
+	for _, grammar := range grammars {
+		if ambiguities := grammar.ProbeAmbiguity(6, 50); len(ambiguities) != 0 {
+			t.Errorf("Expected no ambiguity, got %v", ambiguities)
+		}
+	}
+}
+
+func TestGrammar_ProbeAmbiguity_Empty(t *testing.T) {
+	// the empty sentence of list has a tree per number of empty items, list
+	// deriving list item over it
+	g, err := ReadBNF(strings.NewReader(`%token id
+list -> list item | %empty
+item -> id | %empty
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ambiguities := g.ProbeAmbiguity(2, 50)
+	if len(ambiguities) != 1 || ambiguities[0].Symbol != "list" || len(ambiguities[0].Sentence) != 0 {
+		t.Fatalf("Expected list to be ambiguous, got %v", ambiguities)
+	}
+	if trees := ambiguities[0].Trees; trees != [2]string{"list(list(ε) item(ε))", "list(ε)"} {
+		t.Errorf("Expected a tree with an empty item and one without, got %v", trees)
+	}
+
+	var b strings.Builder
+	if err := g.ReportAmbiguities(&b, 2, 50); err != nil {
+		t.Fatal(err)
+	}
+	report := b.String()
+	if !strings.HasPrefix(report, "1 ambiguous nonterminals in sentences of 2 terminals at most\n\nlist derives ε in two ways:\n") {
+		t.Errorf("Expected the ambiguity of list, got\n%s", report)
+	}
+}
@@ -0,0 +1,311 @@
+package parser
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"app/lexer"
+	"app/utils"
+)
+
+// Reference is an identifier of the input with the item it names.
+type Reference struct {
+	Token       int // index of the identifier among the tokens of the input
+	Name        string
+	Item        *SymbolTableItem // nil if the name is not declared
+	Scope       *Scope           // scope of the item, or the one the identifier is in if undeclared
+	Site        *Scope           // scope the identifier is in
+	Declaration bool             // the identifier is the one declaring the item
+}
+
+// typedNode is an expression covering the tokens [first, last] of the input,
+// with the type it has in the scope it is in.
+type typedNode struct {
+	first, last int
+	node        *ASTNode
+	dataType    lexer.TokenSpecificType
+	scope       *Scope
+}
+
+// crossReference records the identifiers and expressions of a parse for
+// Analyze, positions are kept as indexes of the tokens.
+type crossReference struct {
+	tokens      map[*lexer.Token]int
+	shifted     []*lexer.Token // the tokens by index
+	scopes      []*Scope       // the scope each token is shifted in, by index
+	references  []Reference
+	expressions []typedNode
+}
+
+// reference records the token about to be shifted, resolving it in the
+// current scope if it is an identifier, or as an expression if it is a
+// literal. Declarations are registered only once their declarator is
+// reduced, the identifiers declaring items are told apart when the parse is
+// done.
+func (w *Walker) reference(node *ASTNode) {
+	x := w.xref
+	if x == nil {
+		return
+	}
+	index := len(x.shifted)
+	x.tokens[node.Token] = index
+	x.shifted = append(x.shifted, node.Token)
+	x.scopes = append(x.scopes, w.SymbolTable.CurrentScope)
+	if node.DataType != lexer.Unknown {
+		x.expressions = append(x.expressions, typedNode{first: index, last: index, node: node, dataType: node.DataType, scope: w.SymbolTable.CurrentScope})
+	}
+	if node.Type != "id" || index > 0 && x.shifted[index-1].Val == "module" && x.shifted[index-1].Type != lexer.STRING {
+		return
+	}
+	r := Reference{Token: index, Name: node.Token.Val, Scope: w.SymbolTable.CurrentScope, Site: w.SymbolTable.CurrentScope}
+	// in mod.name the name is a global of the module, and the module is no reference
+	if n := len(x.references); index >= 2 && x.shifted[index-1].Type == lexer.DELIMITER && x.shifted[index-1].Val == "." &&
+		n > 0 && x.references[n-1].Token == index-2 {
+		r.Name = x.references[n-1].Name + "." + r.Name
+		if item, _, err := w.SymbolTable.Lookup(r.Name); err == nil {
+			r.Item, r.Scope = item, w.SymbolTable.Modules[x.references[n-1].Name]
+		}
+		x.references[n-1] = r
+		return
+	}
+	for scope := w.SymbolTable.CurrentScope; scope != nil; scope = scope.Parent {
+		if item, ok := scope.Items[r.Name]; ok {
+			r.Item, r.Scope = item, scope
+			break
+		}
+	}
+	x.references = append(x.references, r)
+}
+
+// reduced records the node a reduction left on top of the token stack if its
+// type is known.
+func (w *Walker) reduced() {
+	x := w.xref
+	if x == nil {
+		return
+	}
+	node, ok := w.Tokens.Peek()
+	if !ok || node == nil {
+		return
+	}
+	dataType := w.TypeOf(node)
+	if dataType == lexer.Unknown {
+		return
+	}
+	first, last := node, node
+	for len(first.Children) > 0 {
+		first = first.Children[0]
+	}
+	for len(last.Children) > 0 {
+		last = last.Children[len(last.Children)-1]
+	}
+	i, ok1 := x.tokens[first.Token]
+	j, ok2 := x.tokens[last.Token]
+	if ok1 && ok2 {
+		x.expressions = append(x.expressions, typedNode{first: i, last: j, node: node, dataType: dataType, scope: w.SymbolTable.CurrentScope})
+	}
+}
+
+// Analysis is a parsed source with the identifiers resolved to their
+// declarations and the types of its expressions, the backbone of editor
+// features such as hovers.
+type Analysis struct {
+	Document    *lexer.Document
+	Walker      *Walker
+	Logs        []string
+	Identifiers []Reference // the identifiers of the source in order
+
+	expressions []typedNode
+	shifted     []*lexer.Token
+	scopes      []*Scope
+}
+
+// TypeInfo is what TypeAt finds under a position.
+type TypeInfo struct {
+	Text        string           // the identifier, or the source of the expression
+	Type        string           // the resolved type, such as int, float[10] or int*
+	Declaration *SymbolTableItem // the item the identifier names, see Definition for where; nil for expressions
+	Scope       *Scope           // the scope the identifier is declared in, or the expression is in
+}
+
+var defaultTables = sync.OnceValue(func() *ParserTables {
+	return NewParser().Tables()
+})
+
+// Analyze parses the source with the parser of the language for queries
+// about it. The table is built on the first call.
+func Analyze(source string) *Analysis {
+	return defaultTables().Analyze(source)
+}
+
+// Analyze parses the source in a new session for queries about it. Errors
+// are kept in the logs, the analysis covers what was parsed until then.
+func (t *ParserTables) Analyze(source string) *Analysis {
+	a := &Analysis{Document: lexer.NewDocument(source)}
+	for _, e := range a.Document.Errors {
+		a.Logs = append(a.Logs, fmt.Sprintf("Error: %v", e))
+	}
+	walker := t.NewSession()
+	walker.xref = &crossReference{tokens: map[*lexer.Token]int{}}
+	_, _ = t.run(context.Background(), walker, a.Document.Lexer(), func(s string) {
+		if strings.HasPrefix(s, "Error") || strings.HasPrefix(s, "Warning") {
+			a.Logs = append(a.Logs, strings.TrimSpace(s))
+		}
+	}, nil)
+	walker.xref.declarations(walker.SymbolTable.LegacyScopes)
+	a.Walker = walker
+	a.Identifiers = walker.xref.references
+	a.expressions = walker.xref.expressions
+	a.shifted, a.scopes = walker.xref.shifted, walker.xref.scopes
+	return a
+}
+
+// declarations marks the identifiers at the position an item of the scopes
+// was declared at as its declaration.
+func (x *crossReference) declarations(scopes []*Scope) {
+	declared := map[utils.Pair[int64, int64]]*Scope{}
+	for _, scope := range scopes {
+		for _, item := range scope.Items {
+			declared[utils.MakePair(item.Line, item.Pos)] = scope
+		}
+	}
+	for i, r := range x.references {
+		token := x.shifted[r.Token]
+		if scope, ok := declared[utils.MakePair(token.Line, token.Pos)]; ok {
+			if item, ok := scope.Items[r.Name]; ok && item.Line == token.Line && item.Pos == token.Pos {
+				x.references[i].Item, x.references[i].Scope, x.references[i].Declaration = item, scope, true
+			}
+		}
+	}
+}
+
+// TokenAt returns the index of the token under the position, lines counted
+// from 0 and positions from 1 like the lexer does.
+func (a *Analysis) TokenAt(line, pos int64) (int, error) {
+	offset, err := a.Document.Offset(line, pos)
+	if err != nil {
+		return -1, err
+	}
+	if i, ok := a.Document.TokenAt(offset); ok {
+		return i, nil
+	}
+	return -1, fmt.Errorf("no token at line %d, pos %d", line, pos)
+}
+
+// PositionOf returns the line and pos the token starts at, counted the way
+// TokenAt takes them.
+func (a *Analysis) PositionOf(token int) (line, pos int64) {
+	return a.Document.Position(a.Document.Spans[token].Start)
+}
+
+// ReferenceAt returns the identifier at the token, if it is one.
+func (a *Analysis) ReferenceAt(token int) (*Reference, bool) {
+	i, ok := slices.BinarySearchFunc(a.Identifiers, token, func(r Reference, token int) int { return cmp.Compare(r.Token, token) })
+	if !ok {
+		return nil, false
+	}
+	return &a.Identifiers[i], true
+}
+
+// Location is where a token is in the source, lines counted from 0 and
+// positions from 1.
+type Location struct {
+	Line, Pos int64
+	Length    int // in bytes
+}
+
+// LocationOf returns the location of the token.
+func (a *Analysis) LocationOf(token int) Location {
+	line, pos := a.PositionOf(token)
+	span := a.Document.Spans[token]
+	return Location{Line: line, Pos: pos, Length: span.End - span.Start}
+}
+
+// declaredAt returns the identifier under the position if it names an item.
+func (a *Analysis) declaredAt(line, pos int64) (*Reference, error) {
+	token, err := a.TokenAt(line, pos)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := a.ReferenceAt(token)
+	if !ok {
+		return nil, fmt.Errorf("no identifier at line %d, pos %d", line, pos)
+	}
+	if r.Item == nil {
+		return nil, fmt.Errorf("%s is not declared, at line %d, pos %d", r.Name, line, pos)
+	}
+	return r, nil
+}
+
+// Definition returns the location of the identifier declaring the item named
+// by the identifier under the position.
+func (a *Analysis) Definition(line, pos int64) (Location, error) {
+	r, err := a.declaredAt(line, pos)
+	if err != nil {
+		return Location{}, err
+	}
+	for _, d := range a.referencesTo(r.Item) {
+		if d.Declaration {
+			return a.LocationOf(d.Token), nil
+		}
+	}
+	return Location{}, fmt.Errorf("%s is a builtin, it has no declaration in the source", r.Name)
+}
+
+// References returns the locations of the identifiers naming the item named
+// by the identifier under the position, its declaration included, in the
+// order of the source.
+func (a *Analysis) References(line, pos int64) ([]Location, error) {
+	r, err := a.declaredAt(line, pos)
+	if err != nil {
+		return nil, err
+	}
+	var result []Location
+	for _, r := range a.referencesTo(r.Item) {
+		result = append(result, a.LocationOf(r.Token))
+	}
+	return result, nil
+}
+
+// TypeAt returns the type of the identifier under the position with its
+// declaration and scope, or else the one of the smallest expression around
+// it. Lines are counted from 0 and positions from 1 like the lexer does.
+func (a *Analysis) TypeAt(line, pos int64) (*TypeInfo, error) {
+	token, err := a.TokenAt(line, pos)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := a.ReferenceAt(token); ok {
+		if r.Item == nil {
+			return nil, fmt.Errorf("%s is not declared, at line %d, pos %d", r.Name, line, pos)
+		}
+		return &TypeInfo{Text: r.Name, Type: typeName(r.Item), Declaration: r.Item, Scope: r.Scope}, nil
+	}
+	var found *typedNode
+	for i, e := range a.expressions {
+		if e.first <= token && token <= e.last && (found == nil || e.last-e.first < found.last-found.first) {
+			found = &a.expressions[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no expression at line %d, pos %d", line, pos)
+	}
+	return &TypeInfo{Text: found.node.raw, Type: found.dataType.ToString(), Scope: found.scope}, nil
+}
+
+// typeName writes the type of the item the way it is declared.
+func typeName(item *SymbolTableItem) string {
+	switch {
+	case item.Type == SymbolTableItemTypeArray && item.Pointee != "":
+		return fmt.Sprintf("%s*[%d]", item.Pointee, item.ArraySize)
+	case item.Type == SymbolTableItemTypeArray:
+		return fmt.Sprintf("%s[%d]", item.UnderlyingType, item.ArraySize)
+	case item.Pointee != "":
+		return item.Pointee + "*"
+	}
+	return item.UnderlyingType
+}
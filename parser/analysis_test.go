@@ -0,0 +1,101 @@
+package parser_test
+
+import (
+	"slices"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestAnalysis_TypeAt(t *testing.T) {
+	src := "{\n    int a;\n    float b[4];\n    int* p;\n    a = 1;\n    {\n        float a;\n        a = b[2] + 1.5;\n    }\n    a = a + 2;\n    c = 1;\n}\n"
+	a := sharedParser().Tables().Analyze(src)
+
+	tests := []struct {
+		line, pos   int64
+		text, typ   string
+		declaration [2]int64 // line and pos of the declaration, if an identifier
+		level       int
+	}{
+		{1, 9, "a", "int", [2]int64{1, 9}, 1},
+		{4, 5, "a", "int", [2]int64{1, 9}, 1},
+		{2, 11, "b", "float[4]", [2]int64{2, 11}, 1},
+		{3, 10, "p", "int*", [2]int64{3, 10}, 1},
+		{7, 9, "a", "float", [2]int64{6, 15}, 2},
+		{7, 15, "2", "int", [2]int64{}, 2},
+		{7, 18, "b [ 2 ] + 1.5", "float", [2]int64{}, 2},
+		{9, 11, "a + 2", "int", [2]int64{}, 1},
+	}
+	for _, tt := range tests {
+		info, err := a.TypeAt(tt.line, tt.pos)
+		if err != nil {
+			t.Errorf("TypeAt(%d, %d): %v", tt.line, tt.pos, err)
+			continue
+		}
+		if info.Text != tt.text || info.Type != tt.typ || info.Scope.Level != tt.level {
+			t.Errorf("TypeAt(%d, %d) = %q of type %s in a scope of level %d, expected %q of type %s at level %d",
+				tt.line, tt.pos, info.Text, info.Type, info.Scope.Level, tt.text, tt.typ, tt.level)
+		}
+		if tt.declaration == [2]int64{} {
+			if info.Declaration != nil {
+				t.Errorf("TypeAt(%d, %d): expected no declaration for an expression", tt.line, tt.pos)
+			}
+		} else if info.Declaration == nil || [2]int64{info.Declaration.Line, info.Declaration.Pos} != tt.declaration {
+			t.Errorf("TypeAt(%d, %d): expected the declaration at %v, got %+v", tt.line, tt.pos, tt.declaration, info.Declaration)
+		}
+	}
+
+	if _, err := a.TypeAt(10, 5); err == nil {
+		t.Errorf("Expected an error for an undeclared identifier")
+	}
+	if _, err := a.TypeAt(0, 3); err == nil {
+		t.Errorf("Expected an error for a position without a token")
+	}
+	if _, err := a.TypeAt(40, 1); err == nil {
+		t.Errorf("Expected an error for a line past the end")
+	}
+
+	declarations := 0
+	for _, r := range a.Identifiers {
+		if r.Declaration {
+			declarations++
+		}
+	}
+	if declarations != 4 {
+		t.Errorf("Expected 4 declaring identifiers, got %d", declarations)
+	}
+}
+
+func TestAnalysis_References(t *testing.T) {
+	src := "{\n    int a;\n    a = 1;\n    {\n        int a;\n        a = abs(a);\n    }\n    a = a + 2;\n}\n"
+	a := sharedParser().Tables().Analyze(src)
+
+	definition, err := a.Definition(7, 9)
+	if err != nil || definition != (Location{Line: 1, Pos: 9, Length: 1}) {
+		t.Errorf("Expected the outer a to be defined at line 1, pos 9, got %v, %v", definition, err)
+	}
+	definition, err = a.Definition(5, 17)
+	if err != nil || definition != (Location{Line: 4, Pos: 13, Length: 1}) {
+		t.Errorf("Expected the inner a to be defined at line 4, pos 13, got %v, %v", definition, err)
+	}
+	if _, err := a.Definition(5, 13); err == nil {
+		t.Errorf("Expected an error for the definition of a builtin")
+	}
+
+	references, err := a.References(2, 5)
+	expected := []Location{{1, 9, 1}, {2, 5, 1}, {7, 5, 1}, {7, 9, 1}}
+	if err != nil || !slices.Equal(references, expected) {
+		t.Errorf("Expected the references of the outer a to be %v, got %v, %v", expected, references, err)
+	}
+	references, err = a.References(4, 13)
+	expected = []Location{{4, 13, 1}, {5, 9, 1}, {5, 17, 1}}
+	if err != nil || !slices.Equal(references, expected) {
+		t.Errorf("Expected the references of the inner a to be %v, got %v, %v", expected, references, err)
+	}
+	if references, err := a.References(5, 13); err != nil || !slices.Equal(references, []Location{{5, 13, 3}}) {
+		t.Errorf("Expected the single reference to abs, got %v, %v", references, err)
+	}
+	if _, err := a.References(7, 7); err == nil {
+		t.Errorf("Expected an error for a position without an identifier")
+	}
+}
@@ -0,0 +1,442 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+
+	. "app/utils/collections"
+)
+
+// ANTLRTokens maps the tokens defined by the lexer rules of an ANTLR grammar,
+// by name, to the terminals Reflect gives the tokens of the lexer.
+type ANTLRTokens map[string]Symbol
+
+// DefaultANTLRTokens are the usual names of identifiers, numbers and strings.
+var DefaultANTLRTokens = ANTLRTokens{
+	"ID": "id", "IDENT": "id", "IDENTIFIER": "id", "Identifier": "id", "NAME": "id",
+	"INT": "num", "INTEGER": "num", "NUMBER": "num", "DECIMAL": "num", "IntegerLiteral": "num",
+	"FLOAT": "real", "REAL": "real", "DOUBLE": "real", "FloatingLiteral": "real",
+	"STRING": "str", "STRING_LITERAL": "str", "StringLiteral": "str", "CHAR": "str",
+}
+
+// g4Token is a token of an ANTLR grammar. Actions, argument lists and
+// element options are read whole, with their brackets.
+type g4Token struct {
+	text      string
+	line, pos int64
+}
+
+// scanG4 splits the grammar into tokens, dropping the comments. Lines are
+// counted from 0 and positions from 1.
+func scanG4(src string) ([]g4Token, error) {
+	var tokens []g4Token
+	line, start := int64(0), 0
+	// skip returns the index after the bracket closing the one at i, counting the lines on the way
+	skip := func(i int, open, close byte) int {
+		depth := 0
+		for ; i < len(src); i++ {
+			switch src[i] {
+			case '\\':
+				i++
+			case '\n':
+				line, start = line+1, i+1
+			case open:
+				depth++
+			case close:
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return -1
+	}
+	for i := 0; i < len(src); {
+		c := src[i]
+		pos := int64(i-start) + 1
+		j := i + 1
+		switch {
+		case c == '\n':
+			line, start = line+1, i+1
+			i++
+			continue
+		case unicode.IsSpace(rune(c)):
+			i++
+			continue
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment, at line %d, pos %d", line, pos)
+			}
+			for _, b := range []byte(src[i : i+end]) {
+				if i++; b == '\n' {
+					line, start = line+1, i
+				}
+			}
+			i += 2
+			continue
+		case c == '\'':
+			for ; j < len(src) && src[j] != '\'' && src[j] != '\n'; j++ {
+				if src[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(src) || src[j] != '\'' {
+				return nil, fmt.Errorf("unterminated literal, at line %d, pos %d", line, pos)
+			}
+			j++
+		case c == '{' || c == '[' || c == '<':
+			j = skip(i, c, map[byte]byte{'{': '}', '[': ']', '<': '>'}[c])
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated %c, at line %d, pos %d", c, line, pos)
+			}
+		case c == '_' || unicode.IsLetter(rune(c)):
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "+=") || strings.HasPrefix(src[i:], ".."):
+			j = i + 2
+		}
+		tokens = append(tokens, g4Token{text: src[i:j], line: line, pos: pos})
+		i = j
+	}
+	return tokens, nil
+}
+
+// antlrRule is a rule of an ANTLR grammar with the tokens of its alternatives.
+type antlrRule struct {
+	name g4Token
+	body []g4Token
+}
+
+// rewriter rewrites the groups, options and repetitions of an extended
+// grammar with new nonterminals.
+type rewriter struct {
+	heads  Set[Symbol]
+	fresh  map[string]Symbol // nonterminals made for the groups and repetitions, by what they derive
+	result []Production
+}
+
+// antlrReader translates the parser rules of an ANTLR grammar to productions.
+type antlrReader struct {
+	rewriter
+	tokens    map[string]Symbol // terminal of each token of the lexer rules
+	spec      ANTLRTokens
+	body      []g4Token // of the rule being translated
+	i         int
+	used      map[Symbol]g4Token // first use of each rule referenced
+	terminals Set[Terminal]
+}
+
+// ReadANTLR reads the rules of an ANTLR grammar (.g4) into a grammar whose
+// start is its first parser rule. Lexer rules reading a single literal are
+// the terminal of the literal, like the literals of the parser rules, and
+// the others are mapped by name through the tokens, DefaultANTLRTokens if
+// nil, or else are terminals of their own name. Skipped tokens, fragments
+// and lexer modes are left out. Subrules, ?, * and + are rewritten with new
+// nonterminals named after the rule, and labels, actions, predicates and
+// options are ignored. The productions have no semantic rules.
+func ReadANTLR(r io.Reader, tokens ANTLRTokens) (*Grammar, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	all, err := scanG4(string(src))
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = DefaultANTLRTokens
+	}
+	rules, lexerRules, err := splitRules(all)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("the grammar has no parser rules")
+	}
+
+	reader := &antlrReader{
+		rewriter:  rewriter{heads: Set[Symbol]{}, fresh: map[string]Symbol{}},
+		tokens:    map[string]Symbol{},
+		spec:      tokens,
+		used:      map[Symbol]g4Token{},
+		terminals: Set[Terminal]{}.AddAll(EPSILON, TERMINATE),
+	}
+	for _, rule := range lexerRules {
+		body := rule.body
+		if arrow := slices.IndexFunc(body, func(t g4Token) bool { return t.text == "->" }); arrow >= 0 {
+			body = body[:arrow]
+		}
+		if len(body) == 1 && body[0].text[0] == '\'' {
+			reader.tokens[rule.name.text] = Symbol(unquoteG4(body[0].text))
+		}
+	}
+	for _, rule := range rules {
+		reader.heads.Add(Symbol(rule.name.text))
+	}
+	for _, rule := range rules {
+		reader.body, reader.i = rule.body, 0
+		made := len(reader.result)
+		bodies, err := reader.alternatives(rule.name.text)
+		if err != nil {
+			return nil, err
+		}
+		if reader.i < len(reader.body) {
+			t := reader.body[reader.i]
+			return nil, fmt.Errorf("unexpected %s in rule %s, at line %d, pos %d", t.text, rule.name.text, t.line, t.pos)
+		}
+		// the productions of the rule come before those of the nonterminals made for it
+		for i, body := range bodies {
+			reader.result = slices.Insert(reader.result, made+i, Production{Head: Symbol(rule.name.text), Body: body})
+		}
+	}
+	for symbol, t := range reader.used {
+		if !reader.heads.Contains(symbol) {
+			return nil, fmt.Errorf("rule %s is not defined, at line %d, pos %d", symbol, t.line, t.pos)
+		}
+	}
+
+	start := Symbol(rules[0].name.text)
+	return &Grammar{
+		AugmentedProduction: Production{Head: start + "'", Body: []Symbol{start}},
+		Productions:         reader.result,
+		Terminals:           reader.terminals,
+	}, nil
+}
+
+// splitRules splits the tokens into the parser and the lexer rules, skipping
+// the header, the prequel sections and the modes.
+func splitRules(tokens []g4Token) (rules, lexerRules []antlrRule, err error) {
+	for i := 0; i < len(tokens); {
+		t := tokens[i]
+		switch {
+		case t.text == "grammar" || t.text == "import" || t.text == "mode":
+			for i < len(tokens) && tokens[i].text != ";" {
+				i++
+			}
+			i++
+			continue
+		case t.text == "lexer" || t.text == "parser":
+			i++
+			continue
+		case t.text == "fragment":
+			// fragments only build other lexer rules
+			for i < len(tokens) && tokens[i].text != ";" {
+				i++
+			}
+			i++
+			continue
+		case t.text == "options" || t.text == "tokens" || t.text == "channels" || t.text == "@":
+			for i < len(tokens) && tokens[i].text[0] != '{' {
+				i++
+			}
+			i++
+			continue
+		case t.text == "catch" || t.text == "finally":
+			for i++; i < len(tokens) && (tokens[i].text[0] == '[' || tokens[i].text[0] == '{'); i++ {
+			}
+			continue
+		}
+		if !isG4Name(t.text) {
+			return nil, nil, fmt.Errorf("unexpected %s, at line %d, pos %d", t.text, t.line, t.pos)
+		}
+		// arguments, returns, locals and rule actions come before the colon
+		colon := i + 1
+		for colon < len(tokens) && tokens[colon].text != ":" && tokens[colon].text != ";" {
+			colon++
+		}
+		end := colon
+		for end < len(tokens) && tokens[end].text != ";" {
+			end++
+		}
+		if colon == len(tokens) || tokens[colon].text != ":" || end == len(tokens) {
+			return nil, nil, fmt.Errorf("rule %s is not terminated by ;, at line %d, pos %d", t.text, t.line, t.pos)
+		}
+		rule := antlrRule{name: t, body: tokens[colon+1 : end]}
+		if unicode.IsUpper(rune(t.text[0])) {
+			lexerRules = append(lexerRules, rule)
+		} else {
+			rules = append(rules, rule)
+		}
+		i = end + 1
+	}
+	return rules, lexerRules, nil
+}
+
+// isG4Name checks if the text is the name of a rule or a token.
+func isG4Name(text string) bool {
+	return text[0] == '_' || unicode.IsLetter(rune(text[0]))
+}
+
+// unquoteG4 returns the text of the literal.
+func unquoteG4(literal string) string {
+	return strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(literal[1 : len(literal)-1])
+}
+
+// peek returns the text of the next token of the rule, empty at the end.
+func (r *antlrReader) peek() string {
+	if r.i < len(r.body) {
+		return r.body[r.i].text
+	}
+	return ""
+}
+
+// alternatives reads the alternatives up to a closing parenthesis or the end
+// of the rule.
+func (r *antlrReader) alternatives(rule string) ([][]Symbol, error) {
+	var bodies [][]Symbol
+	for {
+		var body []Symbol
+		for t := r.peek(); t != "" && t != "|" && t != ")"; t = r.peek() {
+			switch {
+			case t == "#":
+				// the label of the alternative
+				r.i += 2
+			case t[0] == '{':
+				// actions, and predicates followed by ?
+				if r.i++; r.peek() == "?" {
+					r.i++
+				}
+			case t[0] == '<':
+				r.i++
+			default:
+				symbols, err := r.element(rule)
+				if err != nil {
+					return nil, err
+				}
+				body = append(body, symbols...)
+			}
+		}
+		if len(body) == 0 {
+			body = []Symbol{EPSILON}
+		}
+		bodies = append(bodies, body)
+		if r.peek() != "|" {
+			return bodies, nil
+		}
+		r.i++
+	}
+}
+
+// element reads an element of an alternative with its suffix, and returns
+// the symbols it stands for.
+func (r *antlrReader) element(rule string) ([]Symbol, error) {
+	if next := r.i + 1; next < len(r.body) && isG4Name(r.peek()) && (r.body[next].text == "=" || r.body[next].text == "+=") {
+		// labels
+		r.i += 2
+	}
+	t := r.body[r.i]
+	r.i++
+	var symbols []Symbol
+	switch {
+	case t.text == "(":
+		bodies, err := r.alternatives(rule)
+		if err != nil {
+			return nil, err
+		}
+		if r.peek() != ")" {
+			return nil, fmt.Errorf("( is not closed in rule %s, at line %d, pos %d", rule, t.line, t.pos)
+		}
+		r.i++
+		symbols = bodies[0]
+		if len(bodies) > 1 {
+			symbols = []Symbol{r.nonterminal(rule, "group", bodies)}
+		} else if slices.Equal(symbols, []Symbol{EPSILON}) {
+			symbols = nil
+		}
+	case t.text[0] == '\'':
+		symbol := Symbol(unquoteG4(t.text))
+		r.terminals.Add(Terminal(symbol))
+		symbols = []Symbol{symbol}
+	case t.text == "EOF":
+		// the end of the input is implied
+	case isG4Name(t.text) && unicode.IsUpper(rune(t.text[0])):
+		symbol, ok := r.tokens[t.text]
+		if !ok {
+			if symbol, ok = r.spec[t.text]; !ok {
+				symbol = Symbol(t.text)
+			}
+		}
+		r.terminals.Add(Terminal(symbol))
+		symbols = []Symbol{symbol}
+	case isG4Name(t.text):
+		if _, ok := r.used[Symbol(t.text)]; !ok {
+			r.used[Symbol(t.text)] = t
+		}
+		symbols = []Symbol{Symbol(t.text)}
+	default:
+		return nil, fmt.Errorf("%s is not supported in parser rules, at line %d, pos %d", t.text, t.line, t.pos)
+	}
+
+	suffix := r.peek()
+	if suffix != "?" && suffix != "*" && suffix != "+" {
+		return symbols, nil
+	}
+	if r.i++; r.peek() == "?" {
+		// non-greedy
+		r.i++
+	}
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+	if suffix == "?" {
+		return []Symbol{r.nonterminal(rule, "opt", [][]Symbol{symbols, {EPSILON}})}, nil
+	}
+	return []Symbol{r.list(rule, symbols, suffix == "+")}, nil
+}
+
+// nonterminal returns a new nonterminal of the rule deriving the bodies, or
+// the one made before for the same bodies.
+func (r *rewriter) nonterminal(rule, kind string, bodies [][]Symbol) Symbol {
+	key := fmt.Sprint(kind, bodies)
+	if symbol, ok := r.fresh[key]; ok {
+		return symbol
+	}
+	symbol := r.name(rule, kind)
+	r.fresh[key] = symbol
+	r.produce(symbol, bodies)
+	return symbol
+}
+
+// list returns the nonterminal of the rule deriving the symbols repeated, at
+// least once if nonempty. The list is left recursive, as suits LR parsing.
+func (r *rewriter) list(rule string, symbols []Symbol, nonempty bool) Symbol {
+	key := fmt.Sprint("list", nonempty, symbols)
+	if symbol, ok := r.fresh[key]; ok {
+		return symbol
+	}
+	symbol := r.name(rule, "list")
+	r.fresh[key] = symbol
+	first := []Symbol{EPSILON}
+	if nonempty {
+		first = symbols
+	}
+	r.produce(symbol, [][]Symbol{append([]Symbol{symbol}, symbols...), first})
+	return symbol
+}
+
+// name returns a name for a new nonterminal of the rule, rule_kind unless
+// it is taken.
+func (r *rewriter) name(rule, kind string) Symbol {
+	symbol := Symbol(fmt.Sprintf("%s_%s", rule, kind))
+	for n := 2; r.heads.Contains(symbol); n++ {
+		symbol = Symbol(fmt.Sprintf("%s_%s%d", rule, kind, n))
+	}
+	r.heads.Add(symbol)
+	return symbol
+}
+
+// produce adds the productions of the head.
+func (r *rewriter) produce(head Symbol, bodies [][]Symbol) {
+	for _, body := range bodies {
+		r.result = append(r.result, Production{Head: head, Body: body})
+	}
+}
@@ -0,0 +1,112 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+)
+
+const exprG4 = `grammar Expr;
+options { language = Go; }
+@header { package expr }
+
+/* a program is a list of statements */
+prog : stat+ EOF ;
+stat : expr ';'                       # print
+     | name=ID '=' expr SEMI          # assign
+     | 'if' '(' expr ')' stat ('else' stat)?
+     | '{' stat* '}'
+     ;
+expr : expr op=('*'|'/') expr
+     | expr ('+'|'-') expr
+     | INT
+     | ID
+     | '(' expr ')'
+     | ID '(' (expr (',' expr)*)? ')' { /* a call */ }
+     ;
+
+fragment DIGIT : [0-9] ;
+ID   : [a-zA-Z_]+ ;
+INT  : DIGIT+ ;
+SEMI : ';' ;
+WS   : [ \t\r\n]+ -> skip ; // blanks
+`
+
+func TestReadANTLR(t *testing.T) {
+	g, err := ReadANTLR(strings.NewReader(exprG4), nil)
+	if err != nil {
+		t.Fatalf("ReadANTLR: %v", err)
+	}
+	if g.AugmentedProduction.Head != "prog'" || !slices.Equal(g.AugmentedProduction.Body, []Symbol{"prog"}) {
+		t.Errorf("Expected prog to be the start, got %v", g.AugmentedProduction)
+	}
+	productions := map[string]bool{}
+	for _, p := range g.Productions {
+		body := make([]string, len(p.Body))
+		for i, symbol := range p.Body {
+			body[i] = string(symbol)
+		}
+		productions[string(p.Head)+" → "+strings.Join(body, " ")] = true
+	}
+	for _, expected := range []string{
+		"prog → prog_list",
+		"prog_list → prog_list stat",
+		"prog_list → stat",
+		"stat → id = expr ;",
+		"stat → if ( expr ) stat stat_opt",
+		"stat_opt → else stat",
+		"stat_opt → ε",
+		"stat → { stat_list }",
+		"stat_list → stat_list stat",
+		"stat_list → ε",
+		"expr → expr expr_group expr",
+		"expr_group → *",
+		"expr_group → /",
+		"expr → expr expr_group2 expr",
+		"expr_group2 → -",
+		"expr → num",
+		"expr → id ( expr_opt )",
+		"expr_opt → expr expr_list",
+		"expr_list → expr_list , expr",
+	} {
+		if !productions[expected] {
+			t.Errorf("Expected %s, got %v", expected, productions)
+		}
+	}
+	for _, terminal := range []Terminal{"id", "num", ";", "if", "else", "*", ",", EPSILON, TERMINATE} {
+		if !g.Terminals.Contains(terminal) {
+			t.Errorf("Expected the terminal %s, got %v", terminal, g.Terminals)
+		}
+	}
+	if g.Terminals.Contains("WS") || g.Terminals.Contains("DIGIT") || g.Terminals.Contains("SEMI") {
+		t.Errorf("Expected the skipped tokens and fragments to be left out, got %v", g.Terminals)
+	}
+
+	p := &Parser{Grammar: g}
+	p.BuildFirstSet()
+	p.BuildTable()
+	if p.Table.ShiftReduceConflicts == 0 {
+		t.Errorf("Expected the ambiguous expressions to conflict")
+	}
+	var last string
+	p.Tables().Parse(lexer.NewLexer(strings.NewReader("a = 1 ; if ( a ) { b = f ( a , 2 ) * 3 ; } else a ;")), func(s string) { last = s })
+	if last != "Parsing completed successfully." {
+		t.Errorf("Expected the program to parse with the imported grammar, got %q", last)
+	}
+
+	for src, expected := range map[string]string{
+		"grammar A;\na : b ;":              "rule b is not defined, at line 1, pos 5",
+		"grammar A;\na : ~'x' ;":           "~ is not supported in parser rules, at line 1, pos 5",
+		"grammar A;\na : 'x ;":             "unterminated literal, at line 1, pos 5",
+		"grammar A;\na : ( 'x' ;":          "( is not closed in rule a, at line 1, pos 5",
+		"lexer grammar L;\nA : 'a' ;":      "the grammar has no parser rules",
+		"grammar A;\na : 'x' ;\nb : 'y'\n": "rule b is not terminated by ;, at line 2, pos 1",
+	} {
+		if _, err := ReadANTLR(strings.NewReader(src), nil); err == nil || err.Error() != expected {
+			t.Errorf("%q: expected %q, got %v", src, expected, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package parser
+
+import (
+	. "app/utils/collections"
+)
+
+// slabSize is the number of states, or of items, allocated at once by an arena.
+const slabSize = 1024
+
+// arena allocates the states of the automaton and their items from slabs,
+// and keeps the buffers CLOSURE and GOTO work in between calls, so that
+// building the automaton of a large grammar does not leave millions of small
+// objects for the garbage collector. The states and the items it hands out
+// live as long as the parser, the buffers are overwritten by the next call,
+// so each worker of BuildStates has an arena of its own.
+type arena struct {
+	states []State   // the rest of the current slab of states
+	items  []LR1Item // the rest of the current slab of items
+
+	kernel     LR1Items              // the items GOTO moves the dot over
+	closure    LR1Items              // the items CLOSURE computes
+	lookaheads *OrderedSet[Terminal] // the lookaheads findLookaheads computes, sorted
+	canonical  []itemKey             // the kernel stateSet.find hashes
+}
+
+// state returns a state of the slab, initialized as the given one.
+func (a *arena) state(state State) *State {
+	if len(a.states) == 0 {
+		a.states = make([]State, slabSize)
+	}
+	s := &a.states[0]
+	*s = state
+	a.states = a.states[1:]
+	return s
+}
+
+// keep returns a copy of the items in the slab, to outlive the buffers.
+func (a *arena) keep(items LR1Items) LR1Items {
+	if len(items) > len(a.items) {
+		a.items = make([]LR1Item, max(slabSize, len(items)))
+	}
+	// the capacity is cut so that appending to the copy never overwrites the next one
+	kept := a.items[:len(items):len(items)]
+	copy(kept, items)
+	a.items = a.items[len(items):]
+	return kept
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strconv"
+
 	"app/lexer"
 )
 
@@ -14,18 +16,88 @@ type ASTNode struct {
 
 	Children []*ASTNode
 
-	Type    Symbol // Type of the node (e.g., statement, expression, declaration, etc.)
-	Payload any    // Additional data associated with the node (e.g., variable name, value, etc.)
+	Type     Symbol                  // Type of the node (e.g., statement, expression, declaration, etc.)
+	DataType lexer.TokenSpecificType // Data type of the value the node yields, Unknown if not resolved yet
+	Payload  any                     // Additional data associated with the node (e.g., variable name, value, etc.)
+
+	Attributes map[string]any // attributes attached by semantic actions, see ReduceContext.Set
+
+	code int // instructions emitted before the token was shifted, where the rules of statements insert their jumps
+}
+
+// Attribute returns the attribute of the key, nil if it is not set.
+func (n *ASTNode) Attribute(key string) any {
+	return n.Attributes[key]
+}
+
+// Trivia returns the comments around the node, the leading ones of its first
+// token and the trailing ones of its last token.
+func (n *ASTNode) Trivia() lexer.Trivia {
+	if len(n.Children) == 0 {
+		if n.Token == nil {
+			return lexer.Trivia{}
+		}
+		return n.Token.Trivia
+	}
+	return lexer.Trivia{
+		Leading:  n.Children[0].Trivia().Leading,
+		Trailing: n.Children[len(n.Children)-1].Trivia().Trailing,
+	}
+}
+
+// String returns the operand form of the node as used in three-address code,
+// which is the literal, the variable name, or the temporary holding its value.
+func (n *ASTNode) String() string {
+	if n.Token != nil && n.Token.Type == lexer.STRING {
+		return strconv.Quote(n.Token.Val)
+	}
+	if n.Token != nil && n.Token.Val != "" {
+		return n.Token.Val
+	}
+	return n.raw
 }
 
 type ASTNodeType int
 
 func (p *Parser) Token2ASTNode(token *lexer.Token) *ASTNode {
+	return Token2ASTNode(token)
+}
+
+// Token2ASTNode is Parser.Token2ASTNode, it depends on nothing but the token.
+// A char is the integer of its code, 'A' being 65, the num it is in the grammar.
+func Token2ASTNode(token *lexer.Token) *ASTNode {
+	if token.Type == lexer.CHAR {
+		code := *token
+		code.Type = lexer.INTEGER
+		code.Val = strconv.Itoa(int([]rune(token.Val + "\x00")[0]))
+		token = &code
+	}
 	return &ASTNode{
 		raw:      token.Val,
 		Token:    token,
 		Children: []*ASTNode{},
-		Type:     p.Reflect(token),
+		Type:     Reflect(token),
+		DataType: literalType(token),
 		Payload:  nil,
 	}
 }
+
+// literalType returns the data type of a literal token, or lexer.Unknown for
+// anything that is not a literal.
+func literalType(token *lexer.Token) lexer.TokenSpecificType {
+	switch token.Type {
+	case lexer.INTEGER:
+		return lexer.TypeInt
+	case lexer.FLOAT:
+		return lexer.TypeFloat
+	case lexer.STRING:
+		return lexer.TypeString
+	case lexer.CHAR:
+		return lexer.TypeByte
+	}
+	switch token.SpecificType() {
+	case lexer.ReservedWordTrue, lexer.ReservedWordFalse:
+		return lexer.TypeBool
+	}
+	return lexer.Unknown
+}
@@ -0,0 +1,314 @@
+// Package ast is the syntax tree of the language: declarations, statements
+// and expressions as typed nodes, built by the parser as it reduces, for the
+// phases after the parse to work on the program rather than on reductions.
+package ast
+
+// Position is where a node starts in the source, at the first character of
+// its first token, with the line counted from 0 and the position from 1 like
+// the lexer counts them.
+type Position struct {
+	Line, Pos int64
+}
+
+// Start returns where the node starts.
+func (p Position) Start() Position { return p }
+
+// SetStart moves the node, as the positions of a preprocessed source are
+// taken back to the file.
+func (p *Position) SetStart(start Position) { *p = start }
+
+// Node is a node of the tree.
+type Node interface {
+	Start() Position
+}
+
+// Stmt is a statement.
+type Stmt interface {
+	Node
+	stmt()
+}
+
+// Expr is an expression.
+type Expr interface {
+	Node
+	expr()
+}
+
+// Program is the root of the tree, the block of a file and the module it
+// declares, if any.
+type Program struct {
+	Position
+	Module string
+	Body   *Block
+}
+
+// Block is a block of declarations followed by statements, a statement too.
+type Block struct {
+	Position
+	Decls []*Decl
+	Stmts []Stmt
+}
+
+// Decl declares variables of a type, as in "static int a = 1, b[3];".
+type Decl struct {
+	Position
+	Storage     string // static, const or empty
+	Type        string // as written, such as int[3]
+	Declarators []*Declarator
+}
+
+// Declarator is a variable of a declaration, with the dimensions declared
+// after its name and its initial value, an expression or the elements of
+// an array.
+type Declarator struct {
+	Position
+	Name     string
+	Dims     []int64
+	Init     Expr   // nil if it has no initializer or one of elements
+	Elements []Expr // the initializer in braces
+}
+
+// DeclStmt is a declaration among the statements of a block.
+type DeclStmt struct {
+	Position
+	Decls []*Decl
+}
+
+// AssignStmt is "target = value;".
+type AssignStmt struct {
+	Position
+	Target Expr
+	Value  Expr
+}
+
+// IfStmt is an if statement, with a nil Else if it has none.
+type IfStmt struct {
+	Position
+	Cond Expr
+	Then Stmt
+	Else Stmt
+}
+
+// WhileStmt is "while (cond) body".
+type WhileStmt struct {
+	Position
+	Cond Expr
+	Body Stmt
+}
+
+// DoStmt is "do body while (cond);".
+type DoStmt struct {
+	Position
+	Body Stmt
+	Cond Expr
+}
+
+// BreakStmt is "break;".
+type BreakStmt struct {
+	Position
+}
+
+// SwitchStmt is a switch statement over its clauses.
+type SwitchStmt struct {
+	Position
+	Tag   Expr
+	Cases []*CaseClause
+}
+
+// CaseClause is a clause of a switch, with a nil Value for the default one.
+type CaseClause struct {
+	Position
+	Value Expr
+	Body  []Stmt
+}
+
+// CallStmt is a call whose value is discarded.
+type CallStmt struct {
+	Position
+	Call *CallExpr
+}
+
+// Ident is a name.
+type Ident struct {
+	Position
+	Name string
+}
+
+// SelectorExpr is a global of a module, "module.name".
+type SelectorExpr struct {
+	Position
+	Module string
+	Name   string
+}
+
+// IndexExpr is an element of an array.
+type IndexExpr struct {
+	Position
+	X     Expr
+	Index int64
+}
+
+// BasicLit is a literal, of the kind int, float, string or bool.
+type BasicLit struct {
+	Position
+	Kind  string
+	Value string
+}
+
+// BinaryExpr is "x op y".
+type BinaryExpr struct {
+	Position
+	Op   string
+	X, Y Expr
+}
+
+// UnaryExpr is "op x".
+type UnaryExpr struct {
+	Position
+	Op string
+	X  Expr
+}
+
+// AssignExpr is an assignment used as a value, "target = value".
+type AssignExpr struct {
+	Position
+	Target Expr
+	Value  Expr
+}
+
+// SeqExpr is "(a, b, c)", whose value is the last one.
+type SeqExpr struct {
+	Position
+	List []Expr
+}
+
+// CallExpr is the call of a function.
+type CallExpr struct {
+	Position
+	Func string
+	Args []Expr
+}
+
+// NewExpr allocates a value of the type, or an array of Len of them.
+type NewExpr struct {
+	Position
+	Type string
+	Len  int64 // 0 for a single value
+}
+
+func (*Block) stmt()      {}
+func (*DeclStmt) stmt()   {}
+func (*AssignStmt) stmt() {}
+func (*IfStmt) stmt()     {}
+func (*WhileStmt) stmt()  {}
+func (*DoStmt) stmt()     {}
+func (*BreakStmt) stmt()  {}
+func (*SwitchStmt) stmt() {}
+func (*CallStmt) stmt()   {}
+
+func (*Ident) expr()        {}
+func (*SelectorExpr) expr() {}
+func (*IndexExpr) expr()    {}
+func (*BasicLit) expr()     {}
+func (*BinaryExpr) expr()   {}
+func (*UnaryExpr) expr()    {}
+func (*AssignExpr) expr()   {}
+func (*SeqExpr) expr()      {}
+func (*CallExpr) expr()     {}
+func (*NewExpr) expr()      {}
+
+// Children returns the nodes right under the node, in the order of the source.
+func Children(n Node) []Node {
+	var children []Node
+	add := func(nodes ...Node) {
+		for _, child := range nodes {
+			if child != nil && !isNil(child) {
+				children = append(children, child)
+			}
+		}
+	}
+	switch n := n.(type) {
+	case *Program:
+		add(n.Body)
+	case *Block:
+		for _, d := range n.Decls {
+			add(d)
+		}
+		for _, s := range n.Stmts {
+			add(s)
+		}
+	case *Decl:
+		for _, d := range n.Declarators {
+			add(d)
+		}
+	case *Declarator:
+		add(n.Init)
+		for _, e := range n.Elements {
+			add(e)
+		}
+	case *DeclStmt:
+		for _, d := range n.Decls {
+			add(d)
+		}
+	case *AssignStmt:
+		add(n.Target, n.Value)
+	case *IfStmt:
+		add(n.Cond, n.Then, n.Else)
+	case *WhileStmt:
+		add(n.Cond, n.Body)
+	case *DoStmt:
+		add(n.Body, n.Cond)
+	case *SwitchStmt:
+		add(n.Tag)
+		for _, c := range n.Cases {
+			add(c)
+		}
+	case *CaseClause:
+		add(n.Value)
+		for _, s := range n.Body {
+			add(s)
+		}
+	case *CallStmt:
+		add(n.Call)
+	case *IndexExpr:
+		add(n.X)
+	case *BinaryExpr:
+		add(n.X, n.Y)
+	case *UnaryExpr:
+		add(n.X)
+	case *AssignExpr:
+		add(n.Target, n.Value)
+	case *SeqExpr:
+		for _, e := range n.List {
+			add(e)
+		}
+	case *CallExpr:
+		for _, e := range n.Args {
+			add(e)
+		}
+	}
+	return children
+}
+
+// isNil checks if the node is a nil pointer held by the interface, as an
+// absent Else or Value are.
+func isNil(n Node) bool {
+	switch n := n.(type) {
+	case *Block:
+		return n == nil
+	case *CallExpr:
+		return n == nil
+	}
+	return false
+}
+
+// Inspect visits the tree in depth-first order, calling f with each node
+// and descending into its children while f returns true.
+func Inspect(n Node, f func(Node) bool) {
+	if n == nil || isNil(n) || !f(n) {
+		return
+	}
+	for _, child := range Children(n) {
+		Inspect(child, f)
+	}
+}
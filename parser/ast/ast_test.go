@@ -0,0 +1,54 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser/ast"
+)
+
+func TestDump(t *testing.T) {
+	// { int a[3]; if (a[0] != 1) a[0] = new(int[4]); }
+	program := &Program{Position: Position{0, 1}, Body: &Block{
+		Position: Position{0, 1},
+		Decls: []*Decl{{Position: Position{0, 3}, Type: "int", Declarators: []*Declarator{
+			{Position: Position{0, 7}, Name: "a", Dims: []int64{3}},
+		}}},
+		Stmts: []Stmt{&IfStmt{
+			Position: Position{0, 13},
+			Cond:     &BinaryExpr{Position: Position{0, 17}, Op: "!=", X: &IndexExpr{Position: Position{0, 17}, X: &Ident{Position: Position{0, 17}, Name: "a"}}, Y: &BasicLit{Position: Position{0, 25}, Kind: "int", Value: "1"}},
+			Then:     &AssignStmt{Position: Position{0, 28}, Target: &IndexExpr{Position: Position{0, 28}, X: &Ident{Position: Position{0, 28}, Name: "a"}}, Value: &NewExpr{Position: Position{0, 35}, Type: "int", Len: 4}},
+		}},
+	}}
+	var b strings.Builder
+	if err := program.Dump(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Program (0:1)
+  Block (0:1)
+    Decl int (0:3)
+      Declarator a[3] (0:7)
+    IfStmt (0:13)
+      BinaryExpr != (0:17)
+        IndexExpr [0] (0:17)
+          Ident a (0:17)
+        BasicLit int 1 (0:25)
+      AssignStmt (0:28)
+        IndexExpr [0] (0:28)
+          Ident a (0:28)
+        NewExpr int[4] (0:35)
+`
+	if b.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, b.String())
+	}
+
+	var kinds []string
+	Inspect(program, func(n Node) bool {
+		kinds = append(kinds, strings.Fields(Describe(n))[0])
+		_, ok := n.(*BinaryExpr)
+		return !ok // the operands of the condition are skipped
+	})
+	if got := strings.Join(kinds, " "); got != "Program Block Decl Declarator IfStmt BinaryExpr AssignStmt IndexExpr Ident NewExpr" {
+		t.Errorf("Expected the nodes in depth-first order, got %s", got)
+	}
+}
@@ -0,0 +1,115 @@
+package ast
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Dump writes the tree under the node, a node per line indented by two spaces
+// per level, with what the node holds of its own and where it starts as
+// (line:pos).
+func Dump(w io.Writer, n Node) error {
+	b := bufio.NewWriter(w)
+	dump(b, n, 0)
+	return b.Flush()
+}
+
+// Dump writes the tree of the program, see the function Dump.
+func (p *Program) Dump(w io.Writer) error {
+	return Dump(w, p)
+}
+
+func dump(b *bufio.Writer, n Node, depth int) {
+	if n == nil || isNil(n) {
+		return
+	}
+	start := n.Start()
+	fmt.Fprintf(b, "%s%s (%d:%d)\n", strings.Repeat("  ", depth), Describe(n), start.Line, start.Pos)
+	for _, child := range Children(n) {
+		dump(b, child, depth+1)
+	}
+}
+
+// Describe returns the kind of the node followed by what it holds of its own,
+// its children left out, such as "BinaryExpr +" or "Decl static int".
+func Describe(n Node) string {
+	join := func(parts ...string) string {
+		var fields []string
+		for _, part := range parts {
+			if part != "" {
+				fields = append(fields, part)
+			}
+		}
+		return strings.Join(fields, " ")
+	}
+	switch n := n.(type) {
+	case *Program:
+		if n.Module != "" {
+			return join("Program", "module", n.Module)
+		}
+		return "Program"
+	case *Block:
+		return "Block"
+	case *Decl:
+		return join("Decl", n.Storage, n.Type)
+	case *Declarator:
+		name := n.Name
+		for _, dim := range n.Dims {
+			name += fmt.Sprintf("[%d]", dim)
+		}
+		if n.Elements != nil {
+			return join("Declarator", name, "= {...}")
+		}
+		return join("Declarator", name)
+	case *DeclStmt:
+		return "DeclStmt"
+	case *AssignStmt:
+		return "AssignStmt"
+	case *IfStmt:
+		if n.Else != nil {
+			return "IfStmt else"
+		}
+		return "IfStmt"
+	case *WhileStmt:
+		return "WhileStmt"
+	case *DoStmt:
+		return "DoStmt"
+	case *BreakStmt:
+		return "BreakStmt"
+	case *SwitchStmt:
+		return "SwitchStmt"
+	case *CaseClause:
+		if n.Value == nil {
+			return "CaseClause default"
+		}
+		return "CaseClause"
+	case *CallStmt:
+		return "CallStmt"
+	case *Ident:
+		return join("Ident", n.Name)
+	case *SelectorExpr:
+		return join("SelectorExpr", n.Module+"."+n.Name)
+	case *IndexExpr:
+		return fmt.Sprintf("IndexExpr [%d]", n.Index)
+	case *BasicLit:
+		return join("BasicLit", n.Kind, n.Value)
+	case *BinaryExpr:
+		return join("BinaryExpr", n.Op)
+	case *UnaryExpr:
+		return join("UnaryExpr", n.Op)
+	case *AssignExpr:
+		return "AssignExpr"
+	case *SeqExpr:
+		return "SeqExpr"
+	case *CallExpr:
+		return join("CallExpr", n.Func)
+	case *NewExpr:
+		if n.Len > 0 {
+			return fmt.Sprintf("NewExpr %s[%d]", n.Type, n.Len)
+		}
+		return join("NewExpr", n.Type)
+	}
+	return fmt.Sprintf("%T", n)
+}
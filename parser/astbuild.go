@@ -0,0 +1,360 @@
+package parser
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"app/parser/ast"
+)
+
+// syntaxKey is the attribute holding what the reductions built of the tree
+// of package ast under a node: a node of the tree, or a list of them for the
+// heads of lists such as decls and args.
+const syntaxKey = "syntax"
+
+// syntaxReduction is a reduction as seen by the builders of the tree.
+type syntaxReduction struct {
+	*ReduceContext
+}
+
+// syntaxBuilders build the value of the head of each production, keyed by
+// the production as "head → body". The unit productions missing are passed
+// through, the others build nothing.
+var syntaxBuilders = map[string]func(r syntaxReduction) any{
+	"program → block": func(r syntaxReduction) any {
+		return &ast.Program{Position: r.at(), Body: r.block(0)}
+	},
+	"program → module_decl block": func(r syntaxReduction) any {
+		module, _ := r.value(0).(string)
+		return &ast.Program{Position: r.at(), Module: module, Body: r.block(1)}
+	},
+	"module_decl → module id ;": func(r syntaxReduction) any { return r.text(1) },
+
+	"block → { decls stmts }": func(r syntaxReduction) any {
+		return &ast.Block{Position: r.at(), Decls: r.decls(1), Stmts: r.stmts(2)}
+	},
+	"block → { decls }": func(r syntaxReduction) any {
+		return &ast.Block{Position: r.at(), Decls: r.decls(1)}
+	},
+	"block → { stmts }": func(r syntaxReduction) any {
+		return &ast.Block{Position: r.at(), Stmts: r.stmts(1)}
+	},
+	"block → { }": func(r syntaxReduction) any { return &ast.Block{Position: r.at()} },
+
+	"decls → decls decl": func(r syntaxReduction) any {
+		if decl, ok := r.value(1).(*ast.Decl); ok {
+			return append(slices.Clip(r.decls(0)), decl)
+		}
+		return r.decls(0)
+	},
+	"decls → ε": func(r syntaxReduction) any { return []*ast.Decl{} },
+	"decl → type declarators ;": func(r syntaxReduction) any {
+		return &ast.Decl{Position: r.at(), Type: r.str(0), Declarators: r.declarators(1)}
+	},
+	"decl → storage type declarators ;": func(r syntaxReduction) any {
+		return &ast.Decl{Position: r.at(), Storage: r.str(0), Type: r.str(1), Declarators: r.declarators(2)}
+	},
+	"storage → static": func(r syntaxReduction) any { return r.text(0) },
+	"storage → const":  func(r syntaxReduction) any { return r.text(0) },
+	"declarators → declarators , init_declarator": func(r syntaxReduction) any {
+		return append(slices.Clip(r.declarators(0)), r.declarator(2))
+	},
+	"declarators → init_declarator": func(r syntaxReduction) any {
+		return []*ast.Declarator{r.declarator(0)}
+	},
+	"init_declarator → declarator = assign": func(r syntaxReduction) any {
+		d := *r.declarator(0)
+		d.Init = r.expr(2)
+		return &d
+	},
+	"init_declarator → declarator = { initializers }": func(r syntaxReduction) any {
+		d := *r.declarator(0)
+		d.Elements = r.exprs(3)
+		if d.Elements == nil {
+			d.Elements = []ast.Expr{}
+		}
+		return &d
+	},
+	"initializers → initializers , assign": func(r syntaxReduction) any { return r.appendExpr(0, 2) },
+	"initializers → assign":                func(r syntaxReduction) any { return []ast.Expr{r.expr(0)} },
+	"declarator → declarator [ num ]": func(r syntaxReduction) any {
+		d := *r.declarator(0)
+		d.Dims = append(slices.Clip(d.Dims), r.num(2))
+		return &d
+	},
+	"declarator → id": func(r syntaxReduction) any {
+		return &ast.Declarator{Position: r.at(), Name: r.text(0)}
+	},
+	"type → type [ num ]": func(r syntaxReduction) any { return r.str(0) + "[" + r.text(2) + "]" },
+	"type → type *":       func(r syntaxReduction) any { return r.str(0) + "*" },
+	"type → basic":        func(r syntaxReduction) any { return r.text(0) },
+
+	"stmts → stmts stmt":                     func(r syntaxReduction) any { return r.appendStmt(0, 1) },
+	"stmts → ε":                              func(r syntaxReduction) any { return []ast.Stmt{} },
+	"case_stmts → case_stmts matched_stmt":   func(r syntaxReduction) any { return r.appendStmt(0, 1) },
+	"case_stmts → case_stmts unmatched_stmt": func(r syntaxReduction) any { return r.appendStmt(0, 1) },
+	"case_stmts → ε":                         func(r syntaxReduction) any { return []ast.Stmt{} },
+	"stmt → decls": func(r syntaxReduction) any {
+		// the declarations among the statements, none if the decls are empty
+		if decls := r.decls(0); len(decls) > 0 {
+			return &ast.DeclStmt{Position: r.at(), Decls: decls}
+		}
+		return nil
+	},
+	"unmatched_stmt → if ( bool ) unmatched_stmt": func(r syntaxReduction) any {
+		return &ast.IfStmt{Position: r.at(), Cond: r.expr(2), Then: r.stmt(4)}
+	},
+	"unmatched_stmt → if ( bool ) matched_stmt else unmatched_stmt": func(r syntaxReduction) any {
+		return &ast.IfStmt{Position: r.at(), Cond: r.expr(2), Then: r.stmt(4), Else: r.stmt(6)}
+	},
+	"matched_stmt → if ( bool ) matched_stmt else matched_stmt": func(r syntaxReduction) any {
+		return &ast.IfStmt{Position: r.at(), Cond: r.expr(2), Then: r.stmt(4), Else: r.stmt(6)}
+	},
+	"matched_stmt → if ( bool ) matched_stmt": func(r syntaxReduction) any {
+		return &ast.IfStmt{Position: r.at(), Cond: r.expr(2), Then: r.stmt(4)}
+	},
+	"matched_stmt → loc = assign ;": func(r syntaxReduction) any {
+		return &ast.AssignStmt{Position: r.at(), Target: r.expr(0), Value: r.expr(2)}
+	},
+	"matched_stmt → while ( bool ) stmt": func(r syntaxReduction) any {
+		return &ast.WhileStmt{Position: r.at(), Cond: r.expr(2), Body: r.stmt(4)}
+	},
+	"matched_stmt → do stmt while ( bool ) ;": func(r syntaxReduction) any {
+		return &ast.DoStmt{Position: r.at(), Body: r.stmt(1), Cond: r.expr(4)}
+	},
+	"matched_stmt → break ;": func(r syntaxReduction) any { return &ast.BreakStmt{Position: r.at()} },
+	"matched_stmt → switch ( bool ) { cases }": func(r syntaxReduction) any {
+		cases, _ := r.value(5).([]*ast.CaseClause)
+		return &ast.SwitchStmt{Position: r.at(), Tag: r.expr(2), Cases: cases}
+	},
+	"cases → cases case_clause": func(r syntaxReduction) any {
+		cases, _ := r.value(0).([]*ast.CaseClause)
+		if clause, ok := r.value(1).(*ast.CaseClause); ok {
+			return append(slices.Clip(cases), clause)
+		}
+		return cases
+	},
+	"cases → case_clause": func(r syntaxReduction) any {
+		if clause, ok := r.value(0).(*ast.CaseClause); ok {
+			return []*ast.CaseClause{clause}
+		}
+		return []*ast.CaseClause{}
+	},
+	"case_clause → case bool : case_stmts": func(r syntaxReduction) any {
+		return &ast.CaseClause{Position: r.at(), Value: r.expr(1), Body: r.stmts(3)}
+	},
+	"case_clause → default : case_stmts": func(r syntaxReduction) any {
+		return &ast.CaseClause{Position: r.at(), Body: r.stmts(2)}
+	},
+	"matched_stmt → call ;": func(r syntaxReduction) any {
+		if call, ok := r.value(0).(*ast.CallExpr); ok {
+			return &ast.CallStmt{Position: r.at(), Call: call}
+		}
+		return nil
+	},
+
+	"loc → loc [ num ]": func(r syntaxReduction) any {
+		return &ast.IndexExpr{Position: r.at(), X: r.expr(0), Index: r.num(2)}
+	},
+	"loc → id . id": func(r syntaxReduction) any {
+		return &ast.SelectorExpr{Position: r.at(), Module: r.text(0), Name: r.text(2)}
+	},
+	"loc → * factor": unaryExpr,
+	"call → id ( args )": func(r syntaxReduction) any {
+		return &ast.CallExpr{Position: r.at(), Func: r.text(0), Args: r.exprs(2)}
+	},
+	"call → id ( )": func(r syntaxReduction) any {
+		return &ast.CallExpr{Position: r.at(), Func: r.text(0)}
+	},
+	"args → args , assign": func(r syntaxReduction) any { return r.appendExpr(0, 2) },
+	"args → assign":        func(r syntaxReduction) any { return []ast.Expr{r.expr(0)} },
+	"seq → seq , assign":   func(r syntaxReduction) any { return r.appendExpr(0, 2) },
+	"seq → assign":         func(r syntaxReduction) any { return []ast.Expr{r.expr(0)} },
+	"assign → loc = assign": func(r syntaxReduction) any {
+		return &ast.AssignExpr{Position: r.at(), Target: r.expr(0), Value: r.expr(2)}
+	},
+	"bool → bool || join":        binaryExpr,
+	"join → join && equality":    binaryExpr,
+	"equality → equality == rel": binaryExpr,
+	"equality → equality != rel": binaryExpr,
+	"rel → expr < expr":          binaryExpr,
+	"rel → expr <= expr":         binaryExpr,
+	"rel → expr >= expr":         binaryExpr,
+	"rel → expr > expr":          binaryExpr,
+	"expr → expr + term":         binaryExpr,
+	"expr → expr - term":         binaryExpr,
+	"term → term * unary":        binaryExpr,
+	"term → term / unary":        binaryExpr,
+	"term → term % unary":        binaryExpr,
+	"unary → ! unary":            unaryExpr,
+	"unary → - unary":            unaryExpr,
+	"unary → + unary":            unaryExpr,
+	"factor → ( seq )": func(r syntaxReduction) any {
+		// parentheses around a single expression leave no trace
+		list := r.exprs(1)
+		if len(list) == 1 {
+			return list[0]
+		}
+		return &ast.SeqExpr{Position: r.at(), List: list}
+	},
+	"factor → new ( basic )": func(r syntaxReduction) any {
+		return &ast.NewExpr{Position: r.at(), Type: r.text(2)}
+	},
+	"factor → new ( basic [ num ] )": func(r syntaxReduction) any {
+		return &ast.NewExpr{Position: r.at(), Type: r.text(2), Len: r.num(4)}
+	},
+}
+
+func binaryExpr(r syntaxReduction) any {
+	return &ast.BinaryExpr{Position: r.at(), Op: r.text(1), X: r.expr(0), Y: r.expr(2)}
+}
+
+func unaryExpr(r syntaxReduction) any {
+	return &ast.UnaryExpr{Position: r.at(), Op: r.text(0), X: r.expr(1)}
+}
+
+// buildSyntax is the hook building the tree of package ast as the session
+// reduces, see NewSession. The node of the program is kept as Walker.Program.
+func buildSyntax(c *ReduceContext) error {
+	// sessions driven by symbols alone have no nodes to build on
+	if c.Result == nil {
+		return nil
+	}
+	r := syntaxReduction{c}
+	var value any
+	if build, ok := syntaxBuilders[syntaxProduction(c.Production)]; ok {
+		value = build(r)
+	} else if c.Production.Length() == 1 {
+		value = r.value(0)
+	} else {
+		return nil
+	}
+	c.Set(syntaxKey, value)
+	if program, ok := value.(*ast.Program); ok {
+		c.Walker.Program = program
+	}
+	return nil
+}
+
+// syntaxProduction returns the production as "head → body", the key of
+// syntaxBuilders.
+func syntaxProduction(p *Production) string {
+	body := make([]string, len(p.Body))
+	for i, symbol := range p.Body {
+		body[i] = string(symbol)
+	}
+	return string(p.Head) + " → " + strings.Join(body, " ")
+}
+
+// at returns where the nodes matched start.
+func (r syntaxReduction) at() ast.Position {
+	span := r.Span()
+	return ast.Position{Line: span.Line, Pos: span.Pos}
+}
+
+// value returns what was built of the i-th node matched, the node of the
+// tree of its token if it is a leaf.
+func (r syntaxReduction) value(i int) any {
+	if i >= len(r.Nodes) {
+		return nil
+	}
+	n := r.Nodes[i]
+	if value, ok := n.Attributes[syntaxKey]; ok {
+		return value
+	}
+	if len(n.Children) > 0 || n.Token == nil {
+		return nil
+	}
+	at := ast.Position{Line: n.Token.Line, Pos: n.Token.Pos}
+	switch symbol := r.Production.Body[i]; symbol {
+	case "id":
+		return &ast.Ident{Position: at, Name: n.Token.Val}
+	case "num":
+		return &ast.BasicLit{Position: at, Kind: "int", Value: n.Token.Val}
+	case "real":
+		return &ast.BasicLit{Position: at, Kind: "float", Value: n.Token.Val}
+	case "str":
+		return &ast.BasicLit{Position: at, Kind: "string", Value: n.Token.Val}
+	case "true", "false":
+		return &ast.BasicLit{Position: at, Kind: "bool", Value: string(symbol)}
+	}
+	return nil
+}
+
+// text returns the text of the token of the i-th node matched.
+func (r syntaxReduction) text(i int) string {
+	if i >= len(r.Nodes) || r.Nodes[i].Token == nil {
+		return ""
+	}
+	return r.Nodes[i].Token.Val
+}
+
+// num returns the integer of the i-th node matched, 0 if it is not one.
+func (r syntaxReduction) num(i int) int64 {
+	n, _ := strconv.ParseInt(r.text(i), 0, 64)
+	return n
+}
+
+func (r syntaxReduction) str(i int) string {
+	s, _ := r.value(i).(string)
+	return s
+}
+
+func (r syntaxReduction) expr(i int) ast.Expr {
+	e, _ := r.value(i).(ast.Expr)
+	return e
+}
+
+func (r syntaxReduction) exprs(i int) []ast.Expr {
+	list, _ := r.value(i).([]ast.Expr)
+	return list
+}
+
+func (r syntaxReduction) stmt(i int) ast.Stmt {
+	s, _ := r.value(i).(ast.Stmt)
+	return s
+}
+
+func (r syntaxReduction) stmts(i int) []ast.Stmt {
+	list, _ := r.value(i).([]ast.Stmt)
+	return list
+}
+
+func (r syntaxReduction) block(i int) *ast.Block {
+	b, _ := r.value(i).(*ast.Block)
+	return b
+}
+
+func (r syntaxReduction) decls(i int) []*ast.Decl {
+	list, _ := r.value(i).([]*ast.Decl)
+	return list
+}
+
+func (r syntaxReduction) declarators(i int) []*ast.Declarator {
+	list, _ := r.value(i).([]*ast.Declarator)
+	return list
+}
+
+// declarator returns the declarator of the i-th node matched, an empty one
+// if the node has none.
+func (r syntaxReduction) declarator(i int) *ast.Declarator {
+	if d, ok := r.value(i).(*ast.Declarator); ok {
+		return d
+	}
+	return &ast.Declarator{}
+}
+
+// appendExpr returns the list of the i-th node with the j-th appended.
+func (r syntaxReduction) appendExpr(i, j int) []ast.Expr {
+	return append(slices.Clip(r.exprs(i)), r.expr(j))
+}
+
+// appendStmt returns the statements of the i-th node with the j-th appended,
+// if it is one.
+func (r syntaxReduction) appendStmt(i, j int) []ast.Stmt {
+	if s := r.stmt(j); s != nil {
+		return append(slices.Clip(r.stmts(i)), s)
+	}
+	return r.stmts(i)
+}
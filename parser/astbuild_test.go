@@ -0,0 +1,95 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+	"app/parser/ast"
+)
+
+func TestWalker_Program(t *testing.T) {
+	result, err := Compile(Options{
+		Source: strings.NewReader("module m;\n{\n    static int a[2], b = 1;\n    a[1] = (b + 2) * -b;\n    if (b < 2 && !false) b = a[0]; else { int c; c = b; }\n    switch (b) { case 1: break; default: printf(\"%d\", b); }\n}\n"),
+		Tables: sharedParser().Tables(),
+	})
+	if err != nil || result.Failed() {
+		t.Fatalf("Compile: %v %v", err, result.Diagnostics)
+	}
+	if result.Program == nil || result.Program != result.Walker.Program {
+		t.Fatalf("Expected the program of the session, got %v", result.Program)
+	}
+	var b strings.Builder
+	if err := result.Program.Dump(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Program module m (0:7)
+  Block (1:1)
+    Decl static int (2:10)
+      Declarator a[2] (2:16)
+      Declarator b (2:22)
+        BasicLit int 1 (2:26)
+    AssignStmt (3:5)
+      IndexExpr [1] (3:5)
+        Ident a (3:5)
+      BinaryExpr * (3:13)
+        BinaryExpr + (3:13)
+          Ident b (3:13)
+          BasicLit int 2 (3:17)
+        UnaryExpr - (3:22)
+          Ident b (3:23)
+    IfStmt else (4:6)
+      BinaryExpr && (4:9)
+        BinaryExpr < (4:9)
+          Ident b (4:9)
+          BasicLit int 2 (4:13)
+        UnaryExpr ! (4:18)
+          BasicLit bool false (4:23)
+      AssignStmt (4:26)
+        Ident b (4:26)
+        IndexExpr [0] (4:30)
+          Ident a (4:30)
+      Block (4:41)
+        Decl int (4:45)
+          Declarator c (4:47)
+        AssignStmt (4:50)
+          Ident c (4:50)
+          Ident b (4:54)
+    SwitchStmt (5:10)
+      Ident b (5:13)
+      CaseClause (5:21)
+        BasicLit int 1 (5:23)
+        BreakStmt (5:30)
+      CaseClause default (5:39)
+        CallStmt (5:47)
+          CallExpr printf (5:47)
+            BasicLit string %d (5:52)
+            Ident b (5:55)
+`
+	if b.String() != expected {
+		t.Errorf("Expected the tree\n%s\ngot\n%s", expected, b.String())
+	}
+
+	// the statements are typed nodes, for the phases after the parse to walk
+	calls := 0
+	ast.Inspect(result.Program, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && call.Func == "printf" && len(call.Args) == 2 {
+			calls++
+		}
+		return true
+	})
+	if calls != 1 {
+		t.Errorf("Expected the call of printf, found %d", calls)
+	}
+}
+
+func TestParser_Parse_Program(t *testing.T) {
+	w := sharedParser().Parse(lexer.NewLexer(strings.NewReader("{ int a; a = 1; }")), func(string) {})
+	if w.Program == nil || w.Program.Body == nil || len(w.Program.Body.Decls) != 1 || len(w.Program.Body.Stmts) != 1 {
+		t.Fatalf("Expected a block of a declaration and a statement, got %+v", w.Program)
+	}
+	if _, ok := w.Program.Body.Stmts[0].(*ast.AssignStmt); !ok {
+		t.Errorf("Expected an assignment, got %T", w.Program.Body.Stmts[0])
+	}
+}
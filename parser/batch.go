@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"app/utils"
+	"app/utils/report"
+)
+
+// BatchResult is the compilation of a file by CompileFiles.
+type BatchResult struct {
+	File    string
+	Result  *Result // nil if the file could not be read or the options not honored
+	Err     error
+	Elapsed time.Duration
+}
+
+// Summary counts the diagnostics of the file, the error, if any, as an
+// internal one.
+func (b BatchResult) Summary() DiagnosticsSummary {
+	if b.Result == nil {
+		s := DiagnosticsSummary{Fatal: true, Errors: 1, Categories: ErrorCounts{Internal: 1}}
+		if b.Err != nil {
+			s.FirstError = b.Err.Error()
+		}
+		return s
+	}
+	s := b.Result.Summary()
+	if b.Err != nil {
+		if s.Errors == 0 {
+			s.FirstError = b.Err.Error()
+		}
+		s.Errors++
+		s.Categories.Internal++
+	}
+	return s
+}
+
+// BatchFiles returns the files of the paths in their order, a directory
+// standing for the files right in it, in the order of their names.
+func BatchFiles(paths ...string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := utils.GetDirFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			files = append(files, entry.Path)
+		}
+	}
+	return files, nil
+}
+
+// CompileFiles compiles the files as Compile does with the options, all with
+// the same tables, those of the options or else the ones of the language,
+// built once. The files are compiled concurrently, workers of them at a time,
+// as many as GOMAXPROCS if workers is not positive, each in a session of its
+// own: its symbol table, its code and its diagnostics, a new collector for
+// each. The options but the source, the diagnostics and the line map are
+// those of every file, so Log, Archive, Hooks and Declare may be called from
+// several files at once. The results are in the order of the files.
+func CompileFiles(files []string, opts Options, workers int) []BatchResult {
+	if opts.Tables == nil {
+		opts.Tables = defaultTables()
+	}
+	opts.Diagnostics, opts.LineMap = nil, nil
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	results := make([]BatchResult, len(files))
+	slots := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, file := range files {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			st := time.Now()
+			results[i] = compileFile(file, opts)
+			results[i].Elapsed = time.Since(st)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// compileFile compiles the file with the options, a panic of the compiler
+// failing the file with a PanicError.
+func compileFile(file string, opts Options) (result BatchResult) {
+	defer func() {
+		if v := recover(); v != nil {
+			result = BatchResult{File: file, Err: &PanicError{Value: v, Stack: string(debug.Stack())}}
+		}
+	}()
+	f, err := os.Open(file)
+	if err != nil {
+		return BatchResult{File: file, Err: err}
+	}
+	defer f.Close()
+	opts.Source = f
+	compiled, err := Compile(opts)
+	return BatchResult{File: file, Result: compiled, Err: err}
+}
+
+// BatchCounts returns the errors of all the files and the number of files
+// failing, the exit code of the batch being that of the counts.
+func BatchCounts(results []BatchResult) (counts ErrorCounts, failed int) {
+	return BatchRecordCounts(BatchRecords(results))
+}
+
+// BatchRecordCounts returns the counts of BatchCounts of the records.
+func BatchRecordCounts(records []BatchRecord) (counts ErrorCounts, failed int) {
+	for _, r := range records {
+		counts.Merge(r.Summary.Categories)
+		if r.Summary.ExitCode() != ExitOK {
+			failed++
+		}
+	}
+	return counts, failed
+}
+
+// WriteBatch writes the summary of the files, a line each with its exit
+// code, its errors and warnings, the time it took and its first error, and
+// then the totals.
+func WriteBatch(w io.Writer, results []BatchResult) error {
+	return WriteBatchRecords(w, BatchRecords(results))
+}
+
+// WriteBatchRecords writes the summary of WriteBatch of the records.
+func WriteBatchRecords(w io.Writer, records []BatchRecord) error {
+	t := report.Table{Right: []int{1, 2, 3, 4}}
+	for _, r := range records {
+		s := r.Summary
+		t.Row(r.File, fmt.Sprint(s.ExitCode()), fmt.Sprintf("%d errors", s.Errors), fmt.Sprintf("%d warnings", s.Warnings),
+			fmt.Sprintf("%d ms", r.Elapsed), s.FirstError)
+	}
+	if err := t.Write(w); err != nil {
+		return err
+	}
+	counts, failed := BatchRecordCounts(records)
+	_, err := fmt.Fprintf(w, "%d files, %d failed, exit code %d\n", len(records), failed, counts.ExitCode())
+	return err
+}
@@ -0,0 +1,62 @@
+package parser_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestCompileFiles(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"a.in": "{ int a; a = 1; }",
+		"b.in": "{ int a; a = ; }",
+		"c.in": "{ int a; int b; b = a + 2; }",
+	}
+	for name, source := range sources {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "result"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.in")
+	if _, err := BatchFiles(dir, missing); err == nil {
+		t.Error("Expected a missing path to fail")
+	}
+	files, err := BatchFiles(dir)
+	if err != nil || len(files) != 3 || filepath.Base(files[0]) != "a.in" || filepath.Base(files[2]) != "c.in" {
+		t.Fatalf("Expected the files of the folder but its subfolder, got %v %v", files, err)
+	}
+
+	// a file declaring the same names as another has a symbol table of its own
+	results := CompileFiles(append(files, missing), Options{Tables: sharedParser().Tables()}, 2)
+	if len(results) != 4 {
+		t.Fatalf("Expected a result per file, got %d", len(results))
+	}
+	for i, expected := range []int{ExitOK, ExitSyntax, ExitOK, ExitInternal} {
+		if code := results[i].Summary().ExitCode(); code != expected || results[i].File != append(files, missing)[i] {
+			t.Errorf("Expected %s to exit with %d, got %d", results[i].File, expected, code)
+		}
+	}
+	if results[2].Result == nil || len(results[2].Result.TAC) == 0 || results[3].Result != nil || results[3].Err == nil {
+		t.Errorf("Expected the code of c.in and no result for the missing file, got %+v and %+v", results[2], results[3])
+	}
+	if counts, failed := BatchCounts(results); failed != 2 || counts.Syntax != 1 || counts.Internal != 1 || counts.ExitCode() != ExitInternal {
+		t.Errorf("Expected 2 files failing, one of a syntax error, got %d and %+v", failed, counts)
+	}
+
+	var b strings.Builder
+	if err := WriteBatch(&b, results); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[1], "  4  1 errors  0 warnings") || !strings.Contains(lines[3], "no such file") ||
+		lines[4] != "4 files, 2 failed, exit code 1" {
+		t.Errorf("Expected a line per file and the totals, got\n%s", b.String())
+	}
+}
@@ -0,0 +1,505 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	. "app/utils/collections"
+)
+
+// bnfArrows separate the head of a rule from its alternatives.
+var bnfArrows = []string{"->", "→", "::="}
+
+// scanBNF splits the grammar into its directives, the lines starting with a
+// %, and the tokens of its rules, dropping the comments. Lines are counted
+// from 0 and positions from 1.
+func scanBNF(src string) (directives [][]g4Token, rules []g4Token, err error) {
+	for line, text := range strings.Split(src, "\n") {
+		var tokens []g4Token
+		for i := 0; i < len(text); {
+			c, size := utf8.DecodeRuneInString(text[i:])
+			pos := int64(i) + 1
+			j := i + size
+			switch {
+			case unicode.IsSpace(c):
+				i = j
+				continue
+			case c == '#' || strings.HasPrefix(text[i:], "//"):
+				i = len(text)
+				continue
+			case strings.HasPrefix(text[i:], "{{"):
+				end := strings.Index(text[i+2:], "}}")
+				if end < 0 {
+					return nil, nil, fmt.Errorf("unterminated action, at line %d, pos %d", line, pos)
+				}
+				j = i + 2 + end + 2
+			case c == '\'' || c == '"':
+				for ; j < len(text) && text[j] != byte(c); j++ {
+					if text[j] == '\\' {
+						j++
+					}
+				}
+				if j >= len(text) {
+					return nil, nil, fmt.Errorf("unterminated literal, at line %d, pos %d", line, pos)
+				}
+				j++
+			case c == '%' || c == '_' || unicode.IsLetter(c):
+				for j < len(text) {
+					next, size := utf8.DecodeRuneInString(text[j:])
+					if next != '_' && !unicode.IsLetter(next) && !unicode.IsDigit(next) && (c != '%' || next != '-') {
+						break
+					}
+					j += size
+				}
+			case unicode.IsDigit(c):
+				for j < len(text) && unicode.IsDigit(rune(text[j])) {
+					j++
+				}
+			case slices.ContainsFunc(bnfArrows, func(arrow string) bool { return strings.HasPrefix(text[i:], arrow) }):
+				for _, arrow := range bnfArrows {
+					if strings.HasPrefix(text[i:], arrow) {
+						j = i + len(arrow)
+					}
+				}
+			case !strings.ContainsRune("|()[]{}?*+;", c):
+				return nil, nil, fmt.Errorf("unexpected %c, quote the terminals that are no names, at line %d, pos %d", c, line, pos)
+			}
+			tokens = append(tokens, g4Token{text: text[i:j], line: int64(line), pos: pos})
+			i = j
+		}
+		if len(tokens) > 0 && tokens[0].text[0] == '%' && tokens[0].text != "%empty" {
+			directives = append(directives, tokens)
+		} else {
+			rules = append(rules, tokens...)
+		}
+	}
+	return directives, rules, nil
+}
+
+// isBNFName checks if the text is a name of the grammar, which needs no
+// quotes.
+func isBNFName(text string) bool {
+	if text == "" || text == EPSILON {
+		return false
+	}
+	for i, c := range text {
+		if c != '_' && !unicode.IsLetter(c) && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// unquoteBNF returns the text of the literal.
+func unquoteBNF(literal string) (string, error) {
+	if literal[0] == '"' {
+		return strconv.Unquote(literal)
+	}
+	return unquoteG4(literal), nil
+}
+
+// quoteBNF returns the symbol as written in the grammar, quoted unless it
+// is a name.
+func quoteBNF(symbol string) string {
+	if isBNFName(symbol) {
+		return symbol
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(symbol) + "'"
+}
+
+// bnfReader translates the rules of a BNF grammar to productions.
+type bnfReader struct {
+	rewriter
+	tokens   []g4Token
+	i        int
+	used     []g4Token // the names in the bodies, in order
+	literals Set[Terminal]
+}
+
+// ReadBNF reads a grammar written as rules such as
+//
+//	stmt → id '=' expr ';' | block
+//
+// with -> or ::= for the arrow, one rule or more for each nonterminal, and
+// an optional ; at the end of each. The start is the head of the first rule
+// unless a line %start declares it. The terminals are the quoted literals
+// and the names declared by %token lines, every other name must be the head
+// of a rule. ε, %empty or nothing stands for the empty body. Parentheses
+// group alternatives, [x] and x? are optional, {x} and x* repeat zero times
+// or more, x+ once or more, rewritten with new nonterminals as ReadANTLR
+// does. %left, %right and %nonassoc lines declare the precedences, from the
+// loosest, %alias gives a terminal its name in the messages, and %expect and
+// %expect-rr the conflicts expected. # and // start comments. The
+// productions have no semantic rules, see AttachRules.
+func ReadBNF(r io.Reader) (*Grammar, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	directives, tokens, err := scanBNF(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &bnfReader{
+		rewriter: rewriter{heads: Set[Symbol]{}, fresh: map[string]Symbol{}},
+		tokens:   tokens,
+		literals: Set[Terminal]{},
+	}
+	for reader.i = range tokens {
+		if reader.header() {
+			reader.heads.Add(Symbol(tokens[reader.i].text))
+		}
+	}
+	reader.i = 0
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("the grammar has no rules")
+	}
+	for reader.i < len(tokens) {
+		head := tokens[reader.i]
+		if !reader.header() {
+			return nil, fmt.Errorf("expected the head of a rule, got %s, at line %d, pos %d", head.text, head.line, head.pos)
+		}
+		reader.i += 2
+		made := len(reader.result)
+		bodies, actions, err := reader.alternatives(head.text)
+		if err != nil {
+			return nil, err
+		}
+		if reader.peek() == ";" {
+			reader.i++
+		} else if reader.i < len(tokens) && !reader.header() {
+			t := tokens[reader.i]
+			return nil, fmt.Errorf("unexpected %s in rule %s, at line %d, pos %d", t.text, head.text, t.line, t.pos)
+		}
+		// the productions of the rule come before those of the nonterminals made for it
+		for i, body := range bodies {
+			production := Production{Head: Symbol(head.text), Body: body}
+			if slices.ContainsFunc(reader.result, production.Equals) {
+				return nil, fmt.Errorf("%s is defined twice, at line %d, pos %d", formatProduction(production), head.line, head.pos)
+			}
+			if actions[i] != nil {
+				if err := reader.action(&production, actions[i]); err != nil {
+					return nil, err
+				}
+			}
+			reader.result = slices.Insert(reader.result, made+i, production)
+		}
+	}
+
+	grammar := &Grammar{
+		Productions: reader.result,
+		Terminals:   Set[Terminal]{}.AddAll(EPSILON, TERMINATE).Union(reader.literals),
+	}
+	start, err := reader.directives(grammar, directives)
+	if err != nil {
+		return nil, err
+	}
+	if start == "" {
+		start = Symbol(tokens[0].text)
+	}
+	grammar.AugmentedProduction = Production{Head: start + "'", Body: []Symbol{start}}
+	for _, t := range reader.used {
+		if !reader.heads.Contains(Symbol(t.text)) && !grammar.Terminals.Contains(Terminal(t.text)) {
+			return nil, fmt.Errorf("%s is neither a token nor the head of a rule, at line %d, pos %d", t.text, t.line, t.pos)
+		}
+	}
+	return grammar, nil
+}
+
+// directives applies the directives to the grammar, and returns the start
+// they declare, empty if none does.
+func (r *bnfReader) directives(grammar *Grammar, directives [][]g4Token) (start Symbol, err error) {
+	level := 0
+	for _, directive := range directives {
+		name, args := directive[0], directive[1:]
+		symbols := make([]Symbol, len(args))
+		for i, arg := range args {
+			switch {
+			case name.text == "%expect" || name.text == "%expect-rr":
+			case arg.text[0] == '\'' || arg.text[0] == '"':
+				text, err := unquoteBNF(arg.text)
+				if err != nil || text == "" {
+					return "", fmt.Errorf("bad literal %s, at line %d, pos %d", arg.text, arg.line, arg.pos)
+				}
+				symbols[i] = Symbol(text)
+			case isBNFName(arg.text):
+				symbols[i] = Symbol(arg.text)
+			default:
+				return "", fmt.Errorf("unexpected %s in %s, at line %d, pos %d", arg.text, name.text, arg.line, arg.pos)
+			}
+		}
+		// terminal returns the terminal of the i-th argument
+		terminal := func(i int) (Terminal, error) {
+			if !grammar.IsTerminal(symbols[i]) {
+				return "", fmt.Errorf("%s in %s is not a token, at line %d, pos %d", symbols[i], name.text, args[i].line, args[i].pos)
+			}
+			return Terminal(symbols[i]), nil
+		}
+
+		switch name.text {
+		case "%token":
+			for i, symbol := range symbols {
+				if r.heads.Contains(symbol) {
+					return "", fmt.Errorf("%s is declared a token and is the head of a rule, at line %d, pos %d", symbol, args[i].line, args[i].pos)
+				}
+				grammar.Terminals.Add(Terminal(symbol))
+			}
+		case "%start":
+			if len(args) != 1 || !r.heads.Contains(symbols[0]) {
+				return "", fmt.Errorf("%%start expects the head of a rule, at line %d, pos %d", name.line, name.pos)
+			}
+			start = symbols[0]
+		case "%left", "%right", "%nonassoc":
+			level++
+			assoc := map[string]Associativity{"%left": Left, "%right": Right, "%nonassoc": NonAssoc}[name.text]
+			for i := range symbols {
+				t, err := terminal(i)
+				if err != nil {
+					return "", err
+				}
+				grammar.SetPrecedence(t, level, assoc)
+			}
+		case "%alias":
+			if len(args) != 2 || args[1].text[0] != '"' {
+				return "", fmt.Errorf("%%alias expects a token and a double-quoted name, at line %d, pos %d", name.line, name.pos)
+			}
+			t, err := terminal(0)
+			if err != nil {
+				return "", err
+			}
+			grammar.SetAlias(t, string(symbols[1]))
+		case "%expect", "%expect-rr":
+			n := -1
+			if len(args) == 1 {
+				if n, err = strconv.Atoi(args[0].text); err != nil {
+					n = -1
+				}
+			}
+			if n < 0 {
+				return "", fmt.Errorf("%s expects a number of conflicts, at line %d, pos %d", name.text, name.line, name.pos)
+			}
+			if name.text == "%expect" {
+				grammar.ExpectedConflicts = n
+			} else {
+				grammar.ExpectedReduceConflicts = n
+			}
+		default:
+			return "", fmt.Errorf("unknown directive %s, at line %d, pos %d", name.text, name.line, name.pos)
+		}
+	}
+	return start, nil
+}
+
+// peek returns the text of the next token, empty at the end.
+func (r *bnfReader) peek() string {
+	if r.i < len(r.tokens) {
+		return r.tokens[r.i].text
+	}
+	return ""
+}
+
+// header checks if the next tokens start a rule.
+func (r *bnfReader) header() bool {
+	return r.i+1 < len(r.tokens) && isBNFName(r.tokens[r.i].text) && slices.Contains(bnfArrows, r.tokens[r.i+1].text)
+}
+
+// alternatives reads the alternatives up to a closing bracket or the end of
+// the rule, with the action ending each, nil if it has none.
+func (r *bnfReader) alternatives(rule string) ([][]Symbol, []*g4Token, error) {
+	var bodies [][]Symbol
+	var actions []*g4Token
+	for {
+		var body []Symbol
+		var action *g4Token
+		for r.i < len(r.tokens) && !r.header() && !slices.Contains([]string{"|", ")", "]", "}", ";"}, r.peek()) {
+			if t := r.tokens[r.i]; action != nil {
+				return nil, nil, fmt.Errorf("unexpected %s after the action in rule %s, at line %d, pos %d", t.text, rule, t.line, t.pos)
+			} else if isBNFAction(t.text) {
+				action = &r.tokens[r.i]
+				r.i++
+				continue
+			}
+			symbols, err := r.element(rule)
+			if err != nil {
+				return nil, nil, err
+			}
+			body = append(body, symbols...)
+		}
+		if len(body) == 0 {
+			body = []Symbol{EPSILON}
+		}
+		bodies, actions = append(bodies, body), append(actions, action)
+		if r.peek() != "|" {
+			return bodies, actions, nil
+		}
+		r.i++
+	}
+}
+
+// isBNFAction checks if the token is an action, {{ ... }}.
+func isBNFAction(text string) bool {
+	return strings.HasPrefix(text, "{{") && strings.HasSuffix(text, "}}") && len(text) >= 4
+}
+
+// action compiles the action ending an alternative into the semantic rule of
+// the production.
+func (r *bnfReader) action(production *Production, t *g4Token) error {
+	src := t.text[2 : len(t.text)-2]
+	a, err := parseAction(src)
+	if err != nil {
+		e := err.(*actionSyntaxError)
+		return fmt.Errorf("%s in the action of %s, at line %d, pos %d", e.msg, formatProduction(*production), t.line, t.pos+1+int64(e.pos))
+	}
+	if a.uses > production.Length() {
+		return fmt.Errorf("$%d is past the %d symbols of %s, at line %d, pos %d", a.uses, production.Length(), formatProduction(*production), t.line, t.pos)
+	}
+	production.Rule = GenRuleTemplates.Reduce(a.run)
+	production.Action = strings.TrimSpace(src)
+	return nil
+}
+
+// element reads an element of an alternative with its suffix, and returns
+// the symbols it stands for.
+func (r *bnfReader) element(rule string) ([]Symbol, error) {
+	t := r.tokens[r.i]
+	r.i++
+	var symbols []Symbol
+	switch {
+	case t.text == EPSILON || t.text == "%empty":
+	case t.text == "(" || t.text == "[" || t.text == "{":
+		bodies, actions, err := r.alternatives(rule)
+		if err != nil {
+			return nil, err
+		}
+		for _, action := range actions {
+			if action != nil {
+				return nil, fmt.Errorf("an action must end an alternative of rule %s, not of a group, at line %d, pos %d", rule, action.line, action.pos)
+			}
+		}
+		if r.peek() != map[string]string{"(": ")", "[": "]", "{": "}"}[t.text] {
+			return nil, fmt.Errorf("%s is not closed in rule %s, at line %d, pos %d", t.text, rule, t.line, t.pos)
+		}
+		r.i++
+		symbols = bodies[0]
+		if len(bodies) > 1 {
+			symbols = []Symbol{r.nonterminal(rule, "group", bodies)}
+		} else if slices.Equal(symbols, []Symbol{EPSILON}) {
+			symbols = nil
+		}
+		if len(symbols) > 0 && t.text == "[" {
+			symbols = []Symbol{r.nonterminal(rule, "opt", [][]Symbol{symbols, {EPSILON}})}
+		} else if len(symbols) > 0 && t.text == "{" {
+			symbols = []Symbol{r.list(rule, symbols, false)}
+		}
+	case t.text[0] == '\'' || t.text[0] == '"':
+		text, err := unquoteBNF(t.text)
+		if err != nil || text == "" {
+			return nil, fmt.Errorf("bad literal %s in rule %s, at line %d, pos %d", t.text, rule, t.line, t.pos)
+		}
+		r.literals.Add(Terminal(text))
+		symbols = []Symbol{Symbol(text)}
+	case isBNFName(t.text):
+		r.used = append(r.used, t)
+		symbols = []Symbol{Symbol(t.text)}
+	default:
+		return nil, fmt.Errorf("unexpected %s in rule %s, at line %d, pos %d", t.text, rule, t.line, t.pos)
+	}
+
+	suffix := r.peek()
+	if suffix != "?" && suffix != "*" && suffix != "+" {
+		return symbols, nil
+	}
+	r.i++
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+	if suffix == "?" {
+		return []Symbol{r.nonterminal(rule, "opt", [][]Symbol{symbols, {EPSILON}})}, nil
+	}
+	return []Symbol{r.list(rule, symbols, suffix == "+")}, nil
+}
+
+// WriteBNF writes the grammar in the format ReadBNF reads, the productions
+// in their order, so that reading it back gives the same grammar, without
+// the semantic rules but the actions read with it.
+func (g *Grammar) WriteBNF(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%%start %s\n", quoteBNF(string(g.AugmentedProduction.Body[0])))
+	line := "%token"
+	for _, t := range slices.Sorted(maps.Keys(g.Terminals)) {
+		if t == EPSILON || t == TERMINATE {
+			continue
+		}
+		if len(line)+len(t) > 78 {
+			b.WriteString(line + "\n")
+			line = "%token"
+		}
+		line += " " + quoteBNF(string(t))
+	}
+	b.WriteString(line + "\n")
+	if g.ExpectedConflicts != 0 {
+		fmt.Fprintf(&b, "%%expect %d\n", g.ExpectedConflicts)
+	}
+	if g.ExpectedReduceConflicts != 0 {
+		fmt.Fprintf(&b, "%%expect-rr %d\n", g.ExpectedReduceConflicts)
+	}
+	levels := map[Precedence][]string{}
+	for _, t := range slices.Sorted(maps.Keys(g.Precedence)) {
+		levels[g.Precedence[t]] = append(levels[g.Precedence[t]], quoteBNF(string(t)))
+	}
+	for _, p := range slices.SortedFunc(maps.Keys(levels), func(a, b Precedence) int { return a.Level - b.Level }) {
+		fmt.Fprintf(&b, "%%%s %s\n", p.Assoc, strings.Join(levels[p], " "))
+	}
+	for _, t := range slices.Sorted(maps.Keys(g.Aliases)) {
+		fmt.Fprintf(&b, "%%alias %s %s\n", quoteBNF(string(t)), strconv.Quote(g.Aliases[t]))
+	}
+
+	for i, production := range g.Productions {
+		body := make([]string, len(production.Body))
+		for j, symbol := range production.Body {
+			body[j] = quoteBNF(string(symbol))
+			if symbol.IsEpsilon() {
+				body[j] = EPSILON
+			}
+		}
+		if len(body) == 0 {
+			body = []string{EPSILON}
+		}
+		if production.Action != "" {
+			body = append(body, "{{ "+production.Action+" }}")
+		}
+		if i > 0 && g.Productions[i-1].Head == production.Head {
+			fmt.Fprintf(&b, "%s| %s\n", strings.Repeat(" ", utf8.RuneCountInString(string(production.Head))+1), strings.Join(body, " "))
+		} else {
+			fmt.Fprintf(&b, "\n%s → %s\n", production.Head, strings.Join(body, " "))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// AttachRules gives each production of the grammar without a semantic rule
+// the rule of the same production of the other grammar, if it has it, and
+// returns the grammar. The productions of a grammar read by ReadBNF can so
+// keep the rules of NewGrammar while the others only fold their nodes.
+func (g *Grammar) AttachRules(from *Grammar) *Grammar {
+	if g.AugmentedProduction.Rule == nil && g.AugmentedProduction.Equals(from.AugmentedProduction) {
+		g.AugmentedProduction.Rule = from.AugmentedProduction.Rule
+	}
+	for i := range g.Productions {
+		if g.Productions[i].Rule != nil {
+			continue
+		}
+		if j := slices.IndexFunc(from.Productions, g.Productions[i].Equals); j >= 0 {
+			g.Productions[i].Rule = from.Productions[j].Rule
+		}
+	}
+	return g
+}
@@ -0,0 +1,140 @@
+package parser_test
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+)
+
+const exprBNF = `# statements and expressions
+%start prog
+%token id num
+%left '+' '-'
+%left '*' '/'
+%alias id "identifier"
+
+prog ::= stat+ ;
+stat -> id '=' expr ';'
+      | '{' { stat } '}'
+expr -> expr '+' expr | expr '-' expr
+      | expr '*' expr | expr '/' expr   // bound by the precedences
+      | num | id | '(' expr ')'
+      | id '(' [ expr ( ',' expr )* ] ')'
+`
+
+func TestReadBNF(t *testing.T) {
+	g, err := ReadBNF(strings.NewReader(exprBNF))
+	if err != nil {
+		t.Fatalf("ReadBNF: %v", err)
+	}
+	if g.AugmentedProduction.Head != "prog'" || !slices.Equal(g.AugmentedProduction.Body, []Symbol{"prog"}) {
+		t.Errorf("Expected prog to be the start, got %v", g.AugmentedProduction)
+	}
+	productions := []string{}
+	for _, p := range g.Productions {
+		body := make([]string, len(p.Body))
+		for i, symbol := range p.Body {
+			body[i] = string(symbol)
+		}
+		productions = append(productions, string(p.Head)+" → "+strings.Join(body, " "))
+	}
+	expected := []string{
+		"prog → prog_list",
+		"prog_list → prog_list stat",
+		"prog_list → stat",
+		"stat → id = expr ;",
+		"stat → { stat_list }",
+		"stat_list → stat_list stat",
+		"stat_list → ε",
+		"expr → expr + expr",
+		"expr → expr - expr",
+		"expr → expr * expr",
+		"expr → expr / expr",
+		"expr → num",
+		"expr → id",
+		"expr → ( expr )",
+		"expr → id ( expr_opt )",
+		"expr_list → expr_list , expr",
+		"expr_list → ε",
+		"expr_opt → expr expr_list",
+		"expr_opt → ε",
+	}
+	if !slices.Equal(productions, expected) {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(productions, "\n"))
+	}
+	for _, terminal := range []Terminal{"id", "num", "=", ";", "{", ",", EPSILON, TERMINATE} {
+		if !g.Terminals.Contains(terminal) {
+			t.Errorf("Expected the terminal %s, got %v", terminal, g.Terminals)
+		}
+	}
+	if g.Precedence["*"].Level <= g.Precedence["-"].Level || g.Name("id") != "identifier" {
+		t.Errorf("Expected the precedences and the aliases declared, got %v and %v", g.Precedence, g.Aliases)
+	}
+
+	p := &Parser{Grammar: g}
+	p.BuildFirstSet()
+	p.BuildTable()
+	if p.Table.ShiftReduceConflicts != 0 || p.Table.ReduceReduceConflicts != 0 {
+		t.Errorf("Expected the precedences to resolve the conflicts, got %v", p.Table.Conflicts)
+	}
+	var last string
+	p.Tables().Parse(lexer.NewLexer(strings.NewReader("a = 1 + 2 * f ( a , 3 ) ; { b = ( a ) - 4 / 5 ; }")), func(s string) { last = s })
+	if last != "Parsing completed successfully." {
+		t.Errorf("Expected the program to parse with the grammar read, got %q", last)
+	}
+
+	for src, expected := range map[string]string{
+		"a -> b\n":                 "b is neither a token nor the head of a rule, at line 0, pos 6",
+		"a -> 'x\n":                "unterminated literal, at line 0, pos 6",
+		"a -> ( 'x'\n":             "( is not closed in rule a, at line 0, pos 6",
+		"a -> x = 'y'\n":           "unexpected =, quote the terminals that are no names, at line 0, pos 8",
+		"'x' -> a\n":               "expected the head of a rule, got 'x', at line 0, pos 1",
+		"%token a\na -> 'x'\n":     "a is declared a token and is the head of a rule, at line 0, pos 8",
+		"a -> 'x'\na ::= 'x'\n":    "a → x is defined twice, at line 1, pos 1",
+		"a -> 'x'\n%left '+'\n":    "+ in %left is not a token, at line 1, pos 7",
+		"a -> 'x'\n%start b\n":     "%start expects the head of a rule, at line 1, pos 1",
+		"a -> 'x'\n%expect many\n": "%expect expects a number of conflicts, at line 1, pos 1",
+		"a -> 'x'\n%prec x\n":      "unknown directive %prec, at line 1, pos 1",
+		"# no rules\n%token a b\n": "the grammar has no rules",
+	} {
+		if _, err := ReadBNF(strings.NewReader(src)); err == nil || err.Error() != expected {
+			t.Errorf("%q: expected %q, got %v", src, expected, err)
+		}
+	}
+}
+
+func TestGrammar_WriteBNF(t *testing.T) {
+	g := NewGrammar()
+	var b strings.Builder
+	if err := g.WriteBNF(&b); err != nil {
+		t.Fatalf("WriteBNF: %v", err)
+	}
+	read, err := ReadBNF(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("ReadBNF: %v\n%s", err, b.String())
+	}
+	if !read.AugmentedProduction.Equals(g.AugmentedProduction) || len(read.Productions) != len(g.Productions) {
+		t.Fatalf("Expected the grammar written to read back the same, got\n%s", b.String())
+	}
+	for i := range g.Productions {
+		if !read.Productions[i].Equals(g.Productions[i]) || read.Productions[i].Rule != nil {
+			t.Errorf("Expected production %d to read back as %v without a rule, got %v", i, g.Productions[i], read.Productions[i])
+		}
+	}
+	if !read.Terminals.Equal(g.Terminals) || !maps.Equal(read.Aliases, g.Aliases) ||
+		read.ExpectedConflicts != g.ExpectedConflicts || read.ExpectedReduceConflicts != g.ExpectedReduceConflicts {
+		t.Errorf("Expected the terminals, the aliases and the conflicts to read back, got %v, %v, %d and %d",
+			read.Terminals, read.Aliases, read.ExpectedConflicts, read.ExpectedReduceConflicts)
+	}
+
+	read.AttachRules(g)
+	for i, production := range read.Productions {
+		if (production.Rule == nil) != (g.Productions[i].Rule == nil) {
+			t.Errorf("Expected production %d to get its rule back", i)
+		}
+	}
+}
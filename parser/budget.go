@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Budget bounds what compiling and running a program may use, for a front
+// end taking programs from anyone, such as a playground, so that a hostile
+// input fails with a BudgetError rather than exhausting the host. Where
+// Limits guard against the bugs of a grammar, a budget is set by whoever
+// serves the compiler. A bound of zero or less disables the check.
+type Budget struct {
+	MaxTokens       int   // tokens lexed
+	MaxStates       int   // states of the automaton BuildStates builds for a grammar
+	MaxInstructions int   // instructions of three-address code generated
+	MaxSteps        int   // quadruples the vm runs
+	MaxMemory       int64 // bytes of the source read, and of the cells the vm holds
+}
+
+// ErrBudgetExceeded is wrapped by every BudgetError.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetError is a resource of a Budget used beyond its bound.
+type BudgetError struct {
+	Resource string // what the bound counts, such as tokens
+	Limit    int64
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%v: more than %d %s", ErrBudgetExceeded, e.Limit, e.Resource)
+}
+
+func (e *BudgetError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// CheckBudget returns a BudgetError if the count of the resource exceeds the bound,
+// nil if it does not or the bound is disabled.
+func CheckBudget(resource string, count, limit int64) error {
+	if limit > 0 && count > limit {
+		return &BudgetError{Resource: resource, Limit: limit}
+	}
+	return nil
+}
+
+// budgetNames are the resources of ParseBudget, with the bound of each.
+var budgetNames = map[string]func(*Budget, int64){
+	"tokens":       func(b *Budget, n int64) { b.MaxTokens = int(n) },
+	"states":       func(b *Budget, n int64) { b.MaxStates = int(n) },
+	"instructions": func(b *Budget, n int64) { b.MaxInstructions = int(n) },
+	"steps":        func(b *Budget, n int64) { b.MaxSteps = int(n) },
+	"memory":       func(b *Budget, n int64) { b.MaxMemory = n },
+}
+
+// ParseBudget reads a budget written as bounds split by comma, such as
+// tokens=100000,memory=4M: tokens, states, instructions, steps and memory,
+// in bytes with an optional K, M or G suffix. An empty text is no budget.
+func ParseBudget(text string) (*Budget, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	budget := &Budget{}
+	for _, field := range strings.Split(text, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		set, known := budgetNames[name]
+		if !ok || !known {
+			return nil, fmt.Errorf("invalid bound %q of the budget, expected tokens, states, instructions, steps or memory=<n>", field)
+		}
+		unit := int64(1)
+		for i, suffix := range []string{"K", "M", "G"} {
+			if number, ok := strings.CutSuffix(value, suffix); ok && name == "memory" {
+				value, unit = number, 1<<(10*(i+1))
+			}
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid bound %q of the budget, expected a number", field)
+		}
+		set(budget, n*unit)
+	}
+	return budget, nil
+}
+
+// overBudget returns the BudgetError of the first resource of the parse used
+// beyond the budget, after the tokens lexed.
+func (w *Walker) overBudget(tokens int) error {
+	if w.source != nil {
+		if err := CheckBudget("bytes of source", w.source.read, w.budget.MaxMemory); err != nil {
+			return err
+		}
+	}
+	if err := CheckBudget("tokens", int64(tokens), int64(w.budget.MaxTokens)); err != nil {
+		return err
+	}
+	return CheckBudget("instructions", int64(len(w.ThreeAddress)), int64(w.budget.MaxInstructions))
+}
+
+// budgetReader is a reader failing with a BudgetError once more than max
+// bytes are read from it.
+type budgetReader struct {
+	r    io.Reader
+	read int64
+	max  int64
+}
+
+func (r *budgetReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if exceeded := CheckBudget("bytes of source", r.read, r.max); exceeded != nil {
+		return n, exceeded
+	}
+	return n, err
+}
@@ -0,0 +1,82 @@
+package parser_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+func TestParseBudget(t *testing.T) {
+	budget, err := ParseBudget("tokens=100, states=20,instructions=50,steps=1000,memory=4K")
+	if err != nil {
+		t.Fatalf("ParseBudget: %v", err)
+	}
+	if expected := (Budget{MaxTokens: 100, MaxStates: 20, MaxInstructions: 50, MaxSteps: 1000, MaxMemory: 4096}); *budget != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *budget)
+	}
+	if budget, err := ParseBudget(""); budget != nil || err != nil {
+		t.Errorf("Expected no budget, got %+v, %v", budget, err)
+	}
+	for _, text := range []string{"tokens", "depth=3", "tokens=-1", "steps=2K"} {
+		if _, err := ParseBudget(text); err == nil {
+			t.Errorf("Expected %q rejected", text)
+		}
+	}
+}
+
+func TestCompile_Budget(t *testing.T) {
+	src := "{\n    int a, b;\n    a = 1;\n    b = a + 2;\n}\n"
+	tables := sharedParser().Tables()
+	for _, test := range []struct {
+		budget   Budget
+		expected string
+	}{
+		{Budget{MaxTokens: 5}, "budget exceeded: more than 5 tokens, at line 1, pos 13"},
+		{Budget{MaxInstructions: 1}, "budget exceeded: more than 1 instructions, at line 3, pos 14"},
+		{Budget{MaxMemory: 16}, "budget exceeded: more than 16 bytes of source"},
+		{Budget{MaxTokens: 100, MaxInstructions: 100, MaxMemory: 1 << 10}, ""},
+	} {
+		result, err := Compile(Options{Source: strings.NewReader(src), Tables: tables, Budget: &test.budget})
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		fatal, ok := result.Fatal()
+		if test.expected == "" {
+			if ok || result.TAC == nil {
+				t.Errorf("Expected the program within %+v, got %v", test.budget, result.Diagnostics)
+			}
+			continue
+		}
+		if !ok || !strings.HasPrefix(fatal.Message, test.expected) || fatal.Category != InternalError || result.TAC != nil {
+			t.Errorf("Expected %q under %+v, got %v", test.expected, test.budget, result.Diagnostics)
+		}
+	}
+
+	// the budget of the tables is the one of a compilation without its own
+	budgeted := *tables
+	budgeted.Budget = Budget{MaxTokens: 3}
+	result, err := Compile(Options{Source: strings.NewReader(src), Tables: &budgeted})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if fatal, ok := result.Fatal(); !ok || !strings.Contains(fatal.Message, "more than 3 tokens") {
+		t.Errorf("Expected the budget of the tables, got %v", result.Diagnostics)
+	}
+}
+
+func TestParser_BuildStates_Budget(t *testing.T) {
+	grammar := grammars[0].Copy()
+	p := &Parser{Grammar: &grammar, Symbols: NewOrderedSet[Symbol](), FirstSet: FirstSet{}, States: States{}, Budget: Budget{MaxStates: 3}}
+	err := p.BuildStatesContext(context.Background())
+	var exceeded *BudgetError
+	if !errors.As(err, &exceeded) || !errors.Is(err, ErrBudgetExceeded) || exceeded.Resource != "states" || exceeded.Limit != 3 || len(p.States) != 0 {
+		t.Errorf("Expected the states beyond the budget, got %v and %d states", err, len(p.States))
+	}
+	if err := p.EnsureTableContext(context.Background()); err == nil || p.Table != nil {
+		t.Errorf("Expected no table beyond the budget, got %v", err)
+	}
+}
@@ -0,0 +1,468 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"app/lexer"
+	"app/parser/ir"
+	"app/utils"
+)
+
+// Builtin is an intrinsic function. Lower gets the call node and its
+// arguments, emits the code for the call and returns the node standing for
+// the value of the call.
+type Builtin struct {
+	Result lexer.TokenSpecificType   // type of the value, TypeVoid if there is none
+	Params []lexer.TokenSpecificType // types of the arguments, arrays are passed as pointers
+	Lower  Lowering
+
+	Variadic bool // takes any number of arguments after Params
+	Pure     bool // has no side effects, so a call is pointless unless its value is used
+}
+
+// Addressable checks if the function can be used as a value of type func,
+// which are the integer functions with a fixed number of integer arguments.
+func (b Builtin) Addressable() bool {
+	if b.Result != lexer.TypeInt || b.Variadic {
+		return false
+	}
+	for _, t := range b.Params {
+		if !IsIntegral(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Lowering emits the code for a call to an intrinsic function.
+type Lowering func(w *Walker, call *ASTNode, args []*ASTNode) (*ASTNode, error)
+
+// Builtins are the intrinsic functions known to the compiler, keyed by name.
+// They are registered in the prelude scope by DeclareBuiltins.
+var Builtins = map[string]Builtin{}
+
+func init() {
+	ints := func(n int) []lexer.TokenSpecificType {
+		return slices.Repeat([]lexer.TokenSpecificType{lexer.TypeInt}, n)
+	}
+	Builtins["printf"] = Builtin{Result: lexer.TypeVoid, Params: []lexer.TokenSpecificType{lexer.TypeString}, Variadic: true, Lower: Printf}
+	Builtins["readint"] = Builtin{Result: lexer.TypeInt, Lower: Read("read_int", lexer.TypeInt)}
+	Builtins["readfloat"] = Builtin{Result: lexer.TypeFloat, Lower: Read("read_float", lexer.TypeFloat)}
+	Builtins["abs"] = Builtin{Result: lexer.TypeInt, Params: ints(1), Lower: Abs, Pure: true}
+	Builtins["min"] = Builtin{Result: lexer.TypeInt, Params: ints(2), Lower: MinMax("<="), Pure: true}
+	Builtins["max"] = Builtin{Result: lexer.TypeInt, Params: ints(2), Lower: MinMax(">="), Pure: true}
+	Builtins["pow"] = Builtin{Result: lexer.TypeInt, Params: ints(2), Lower: Pow, Pure: true}
+	Builtins["sum"] = Builtin{Result: lexer.TypeInt, Params: []lexer.TokenSpecificType{lexer.TypePointer, lexer.TypeInt}, Lower: Sum, Pure: true}
+}
+
+// DeclareBuiltins registers every intrinsic function in the current scope,
+// which is meant to be the prelude scope enclosing the whole program.
+func (w *Walker) DeclareBuiltins() error {
+	for name, builtin := range Builtins {
+		err := w.SymbolTable.Register(&SymbolTableItem{
+			Variable:       name,
+			Type:           SymbolTableItemTypeBuiltin,
+			UnderlyingType: builtin.Result.ToString(),
+			VariableSize:   4,
+			ArraySize:      1,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Call handles call → id ( args ) | id ( ). The callee is resolved through the
+// symbol table and is either an intrinsic function, which lowers the call, a
+// function registered in the symbol table, which is called at its label, or
+// a variable of type func, which is called indirectly.
+func Call(w *Walker) error {
+	n := 3
+	if top, ok := w.Tokens.PeekAtK(1); ok && top.Type == "args" {
+		n = 4
+	}
+	children := w.Tokens.PopTopN(n)
+	if len(children) != n {
+		return fmt.Errorf("call: expected %d nodes on the token stack", n)
+	}
+	name := children[0].Token
+	var args []*ASTNode
+	if n == 4 {
+		args = children[2].Children
+	}
+	for i, arg := range args {
+		args[i] = w.Decay(arg)
+	}
+	raw := make([]string, 0, len(args))
+	for _, arg := range args {
+		raw = append(raw, arg.raw)
+	}
+	call := &ASTNode{
+		raw:      fmt.Sprintf("%s(%s)", name.Val, strings.Join(raw, ", ")),
+		Token:    &lexer.Token{Type: lexer.EXTRA},
+		Children: children,
+		Type:     "call",
+	}
+
+	item, err := w.SymbolTable.LookupFunction(name.Val)
+	if item == nil {
+		w.Tokens.Push(call)
+		return fmt.Errorf("undefined function %s, at line %d, pos %d", name.Val, name.Line, name.Pos)
+	}
+	if err != nil {
+		w.Tokens.Push(call)
+		return fmt.Errorf("%v, at line %d, pos %d", err, name.Line, name.Pos)
+	}
+	var result *ASTNode
+	switch item.Type {
+	case SymbolTableItemTypeVariable:
+		result, err = IndirectCall(w, call, name.Val, args)
+	case SymbolTableItemTypeFunction:
+		result, err = CallFunction(w, call, item, args)
+		if result == nil {
+			result = call
+			result.DataType = lexer.TypeVoid
+		}
+	default:
+		result, err = Builtins[name.Val].Lower(w, call, args)
+		if result == nil {
+			result = call
+			result.DataType = Builtins[name.Val].Result
+		}
+	}
+	w.Tokens.Push(result)
+	return err
+}
+
+// FactorCall handles factor → call. A call of a void function has no value
+// to use.
+func FactorCall(w *Walker) error {
+	n, ok := w.Tokens.Peek()
+	if !ok {
+		return fmt.Errorf("factor: expected 1 node on the token stack")
+	}
+	n.Type = "factor"
+	if n.DataType != lexer.TypeVoid {
+		return nil
+	}
+	// reported once, the value is unknown from now on
+	n.DataType = lexer.Unknown
+	name := n.Children[0].Token
+	return fmt.Errorf("%s returns void, its value cannot be used, at line %d, pos %d", n.raw, name.Line, name.Pos)
+}
+
+// CallStatement handles matched_stmt → call ;. The value of the call is
+// discarded, which is reported when the call has no side effects either.
+func CallStatement(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	if len(children) != 2 {
+		return fmt.Errorf("matched_stmt: expected 2 nodes on the token stack")
+	}
+	call := children[0]
+	w.Tokens.Push(call)
+	if len(call.Children) == 0 || call.Children[0].Token == nil {
+		return nil
+	}
+	name := call.Children[0].Token
+	if item, _, err := w.SymbolTable.Lookup(name.Val); err == nil && item.Type == SymbolTableItemTypeBuiltin && Builtins[name.Val].Pure {
+		w.Warnf("value of %s is discarded and the call has no side effects, at line %d, pos %d", call.raw, name.Line, name.Pos)
+	}
+	return nil
+}
+
+// EmitCall emits the parameters of a call followed by the call itself. If
+// dist is not empty, the return value is stored into it.
+func (w *Walker) EmitCall(dist string, function string, args ...any) {
+	for _, arg := range args {
+		w.EmitQuad(ir.Quad{Op: ir.Param, Arg1: fmt.Sprint(arg)})
+	}
+	w.EmitQuad(ir.Quad{Op: ir.Call, Arg1: function, Arg2: fmt.Sprint(len(args)), Result: dist})
+}
+
+// FunctionValue handles factor → loc. An intrinsic function named as a value
+// instead of being called yields its address, a value of type func; the
+// functions registered in the symbol table cannot be.
+func FunctionValue(w *Walker) error {
+	n, ok := w.Tokens.Peek()
+	if !ok {
+		return fmt.Errorf("factor: expected 1 node on the token stack")
+	}
+	if n.Token == nil || n.Token.Type != lexer.IDENTIFIER {
+		return nil
+	}
+	item, _, err := w.SymbolTable.Lookup(n.Token.Val)
+	if err == nil && item.Type == SymbolTableItemTypeFunction {
+		n.DataType = lexer.Unknown
+		return fmt.Errorf("%s cannot be used as a value, only called, at line %d, pos %d", n.Token.Val, n.Token.Line, n.Token.Pos)
+	}
+	if err != nil || item.Type != SymbolTableItemTypeBuiltin {
+		return nil
+	}
+	w.Tokens.Pop()
+	w.Tokens.Push(&ASTNode{
+		raw: n.raw,
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  "&" + n.Token.Val,
+		},
+		Children: []*ASTNode{n},
+		Type:     "factor",
+		DataType: lexer.TypeFunc,
+	})
+	if !Builtins[n.Token.Val].Addressable() {
+		return fmt.Errorf("%s cannot be used as a value, only integer functions with a fixed number of arguments can, at line %d, pos %d",
+			n.Token.Val, n.Token.Line, n.Token.Pos)
+	}
+	return nil
+}
+
+// IndirectCall emits a call through the function value held by the variable.
+// The callee is only known at run time, so the arguments are checked to be
+// integers and the number of them is checked by the runtime.
+func IndirectCall(w *Walker, call *ASTNode, function string, args []*ASTNode) (*ASTNode, error) {
+	result := w.NewTemp("call", lexer.TypeInt, call.raw, call.Children)
+	for _, arg := range args {
+		if t := w.TypeOf(arg); t != lexer.Unknown && !IsIntegral(t) {
+			return result, fmt.Errorf("%s expects integer arguments, got %s (%s)", call.raw, arg.raw, t.ToString())
+		}
+	}
+	for _, arg := range args {
+		w.EmitQuad(ir.Quad{Op: ir.Param, Arg1: fmt.Sprint(arg)})
+	}
+	w.EmitQuad(ir.Quad{Op: ir.ICall, Arg1: function, Arg2: fmt.Sprint(len(args)), Result: fmt.Sprint(result)})
+	return result, nil
+}
+
+// Printf lowers printf(format, args...) to one runtime print call per piece
+// of the format. The format must be a string literal, its verbs %d, %f, %s
+// and %c are checked against the types of the arguments.
+func Printf(w *Walker, call *ASTNode, args []*ASTNode) (*ASTNode, error) {
+	if len(args) == 0 || args[0].Token == nil || args[0].Token.Type != lexer.STRING || !IsLiteral(args[0]) {
+		return nil, fmt.Errorf("printf: the format must be a string literal, got %s", call.raw)
+	}
+	format := args[0].Token.Val
+	args = args[1:]
+
+	// the runtime function printing each piece and its argument
+	var pieces []utils.Pair[string, any]
+	text := strings.Builder{}
+	flush := func() {
+		if text.Len() > 0 {
+			pieces = append(pieces, utils.MakePair[string, any]("print_str", fmt.Sprintf("%q", text.String())))
+			text.Reset()
+		}
+	}
+
+	used := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			text.WriteByte(format[i])
+			continue
+		}
+		if i+1 == len(format) {
+			return nil, fmt.Errorf("printf: format %q ends with a lone %%", format)
+		}
+		i++
+		verb := format[i]
+		if verb == '%' {
+			text.WriteByte('%')
+			continue
+		}
+		if used == len(args) {
+			return nil, fmt.Errorf("printf: missing argument for %%%c in %q", verb, format)
+		}
+		arg := args[used]
+		used++
+		t := w.TypeOf(arg)
+		var function string
+		var valid bool
+		switch verb {
+		case 'd':
+			function, valid = "print_int", IsIntegral(t)
+		case 'f':
+			function, valid = "print_float", IsNumeric(t) && !IsIntegral(t)
+		case 's':
+			function, valid = "print_str", t == lexer.TypeString
+		case 'c':
+			function, valid = "print_char", IsIntegral(t)
+		default:
+			return nil, fmt.Errorf("printf: unsupported verb %%%c in %q", verb, format)
+		}
+		if !valid && t != lexer.Unknown {
+			return nil, fmt.Errorf("printf: %%%c expects a matching argument, got %s (%s)", verb, arg.raw, t.ToString())
+		}
+		flush()
+		pieces = append(pieces, utils.MakePair[string, any](function, arg))
+	}
+	flush()
+	if used != len(args) {
+		return nil, fmt.Errorf("printf: %d arguments given, but %q uses %d", len(args), format, used)
+	}
+
+	for _, p := range pieces {
+		w.EmitCall("", p.First, p.Second)
+	}
+	return nil, nil
+}
+
+// Read returns the lowering of an input intrinsic without arguments, which
+// reads a value of the given type from standard input through the runtime.
+func Read(function string, t lexer.TokenSpecificType) Lowering {
+	return func(w *Walker, call *ASTNode, args []*ASTNode) (*ASTNode, error) {
+		result := w.NewTemp("call", t, call.raw, call.Children)
+		if len(args) != 0 {
+			return result, fmt.Errorf("%s takes no arguments, got %d", call.raw, len(args))
+		}
+		w.EmitCall(result.String(), function)
+		return result, nil
+	}
+}
+
+// integerArgs checks that the call has n arguments and all of them are integers.
+func integerArgs(w *Walker, call *ASTNode, args []*ASTNode, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("%s takes %d arguments, got %d", call.raw, n, len(args))
+	}
+	for _, arg := range args {
+		if t := w.TypeOf(arg); t != lexer.Unknown && !IsIntegral(t) {
+			return fmt.Errorf("%s expects integer arguments, got %s (%s)", call.raw, arg.raw, t.ToString())
+		}
+	}
+	return nil
+}
+
+// Abs lowers abs(x) inline, negating the value if it is below zero.
+func Abs(w *Walker, call *ASTNode, args []*ASTNode) (*ASTNode, error) {
+	if err := integerArgs(w, call, args, 1); err != nil {
+		return w.NewTemp("call", lexer.TypeInt, call.raw, call.Children), err
+	}
+	if v, ok := IntLiteral(args[0]); ok {
+		if v == w.IntWidth.Min() {
+			// the negation of the smallest int is itself, as the code computes it
+			span := spanOf(call)
+			w.Warnf("abs(%d) overflows the %d-bit int and stays negative, at line %d, pos %d", v, w.IntWidth.Bits(), span.Line, span.Pos)
+		} else if v < 0 {
+			v = -v
+		}
+		return w.intLiteral(v, call.Children), nil
+	}
+	result := w.NewTemp("call", lexer.TypeInt, call.raw, call.Children)
+	end := w.NewLabel("abs")
+	w.Emit(result.String(), "", args[0])
+	w.EmitConditionalJump(result, ">=", "0", end)
+	w.Emit(result.String(), "minus", result)
+	w.EmitLabel(end)
+	return result, nil
+}
+
+// MinMax returns the inline lowering of min(a, b) or max(a, b), which keeps
+// the first argument if it compares to the second one with relop.
+func MinMax(relop string) Lowering {
+	return func(w *Walker, call *ASTNode, args []*ASTNode) (*ASTNode, error) {
+		if err := integerArgs(w, call, args, 2); err != nil {
+			return w.NewTemp("call", lexer.TypeInt, call.raw, call.Children), err
+		}
+		v1, ok1 := IntLiteral(args[0])
+		v2, ok2 := IntLiteral(args[1])
+		if ok1 && ok2 {
+			if relop == "<=" {
+				return NewIntLiteral(min(v1, v2), call.Children), nil
+			}
+			return NewIntLiteral(max(v1, v2), call.Children), nil
+		}
+		result := w.NewTemp("call", lexer.TypeInt, call.raw, call.Children)
+		kind := "max"
+		if relop == "<=" {
+			kind = "min"
+		}
+		end := w.NewLabel(kind)
+		w.Emit(result.String(), "", args[0])
+		w.EmitConditionalJump(result, relop, args[1], end)
+		w.Emit(result.String(), "", args[1])
+		w.EmitLabel(end)
+		return result, nil
+	}
+}
+
+// Pow lowers pow(base, exp) to a call of the runtime, folding literals.
+func Pow(w *Walker, call *ASTNode, args []*ASTNode) (*ASTNode, error) {
+	if err := integerArgs(w, call, args, 2); err != nil {
+		return w.NewTemp("call", lexer.TypeInt, call.raw, call.Children), err
+	}
+	base, ok1 := IntLiteral(args[0])
+	exp, ok2 := IntLiteral(args[1])
+	if ok1 && ok2 && exp >= 0 {
+		// by squaring, wrapped to the width as the machine computes it
+		v := int64(1)
+		for ; exp > 0; exp >>= 1 {
+			if exp&1 == 1 {
+				v = w.IntWidth.Wrap(v * base)
+			}
+			base = w.IntWidth.Wrap(base * base)
+		}
+		return w.intLiteral(v, call.Children), nil
+	}
+	result := w.NewTemp("call", lexer.TypeInt, call.raw, call.Children)
+	w.EmitCall(result.String(), "pow", args[0], args[1])
+	return result, nil
+}
+
+// New handles factor → new ( basic ) | new ( basic [ num ] ), which allocates
+// an object or an array of n objects of the type on the heap through the
+// alloc runtime, a bump allocator. The value is a pointer to the type, which
+// the node carries as its payload.
+func New(w *Walker) error {
+	n := 4
+	if top, ok := w.Tokens.PeekAtK(1); ok && top.Token.Val == "]" {
+		n = 7
+	}
+	children := w.Tokens.PopTopN(n)
+	if len(children) != n {
+		return fmt.Errorf("new: expected %d nodes on the token stack", n)
+	}
+	basic := children[2].Token
+	raw := fmt.Sprintf("new(%s)", basic.Val)
+	count := int64(1)
+	if n == 7 {
+		raw = fmt.Sprintf("new(%s[%s])", basic.Val, children[4].raw)
+		count, _ = IntLiteral(children[4])
+	}
+	result := w.NewTemp("factor", lexer.TypePointer, raw, children)
+	result.Payload = basic.SpecificType()
+	w.Tokens.Push(result)
+	if count <= 0 {
+		return fmt.Errorf("invalid array length %s, at line %d, pos %d", children[4].raw, children[4].Token.Line, children[4].Token.Pos)
+	}
+	size := w.basic(basic.SpecificType()).Size()
+	w.EmitCall(result.String(), "alloc", strconv.FormatInt(int64(size)*count, 10))
+	return nil
+}
+
+// Sum lowers sum(a, n), the sum of the first n elements of the integer array
+// a, to a call of the runtime with the base address of the array. A literal n
+// is checked against the declared length of the array.
+func Sum(w *Walker, call *ASTNode, args []*ASTNode) (*ASTNode, error) {
+	result := w.NewTemp("call", lexer.TypeInt, call.raw, call.Children)
+	if len(args) != 2 {
+		return result, fmt.Errorf("%s takes 2 arguments, got %d", call.raw, len(args))
+	}
+	array, _ := args[0].Payload.(*SymbolTableItem)
+	if array == nil {
+		return result, fmt.Errorf("%s expects an integer array, got %s (%s)", call.raw, args[0].raw, w.TypeOf(args[0]).ToString())
+	}
+	if !IsIntegral(lexer.ParseTypeName(array.UnderlyingType)) {
+		return result, fmt.Errorf("%s expects an integer array, got %s (%s array)", call.raw, args[0].raw, array.UnderlyingType)
+	}
+	if t := w.TypeOf(args[1]); t != lexer.Unknown && !IsIntegral(t) {
+		return result, fmt.Errorf("%s expects an integer length, got %s (%s)", call.raw, args[1].raw, t.ToString())
+	}
+	if n, ok := IntLiteral(args[1]); ok && (n < 0 || n > int64(array.ArraySize)) {
+		return result, fmt.Errorf("%s: length %d out of range, %s has %d elements", call.raw, n, array.Variable, array.ArraySize)
+	}
+	w.EmitCall(result.String(), "sum", args[0], args[1])
+	return result, nil
+}
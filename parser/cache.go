@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// tableFormat is mixed into the hashes of the grammars, bump it when the
+// construction of the table changes so that the tables saved are rebuilt.
+const tableFormat = "lr1-table-1"
+
+var ErrStaleTable = errors.New("the table was built for another grammar")
+
+// Hash returns a hash of what the table of the grammar depends on: its
+// productions, terminals, precedences and the conflicts it expects, as
+// WriteBNF writes them, so that two grammars with the same hash build the
+// same table.
+func (g *Grammar) Hash() string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, tableFormat+"\n")
+	_ = g.WriteBNF(h)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// savedTable is what Save writes.
+type savedTable struct {
+	Hash  string
+	Table *LRTable
+}
+
+// Save writes the table in gob with the hash of the grammar it was built for.
+func (t *LRTable) Save(w io.Writer, grammar *Grammar) error {
+	return gob.NewEncoder(w).Encode(savedTable{Hash: grammar.Hash(), Table: t})
+}
+
+// LoadLRTable reads a table written by Save, ErrStaleTable if it was built
+// for a grammar other than the one given.
+func LoadLRTable(r io.Reader, grammar *Grammar) (*LRTable, error) {
+	var saved savedTable
+	if err := gob.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("invalid table: %w", err)
+	}
+	if saved.Table == nil {
+		return nil, fmt.Errorf("invalid table: no table")
+	}
+	if saved.Hash != grammar.Hash() {
+		return nil, ErrStaleTable
+	}
+	t := saved.Table
+	if t.ActionTable == nil {
+		t.ActionTable = make(ActionTable)
+	}
+	if t.GotoTable == nil {
+		t.GotoTable = make(GotoTable)
+	}
+	return t, nil
+}
+
+// EnsureCachedTable sets the table of the parser to the one saved in the
+// file if it was built for the grammar, and otherwise builds it and saves it
+// there, returning whether the file was used. The states of the automaton
+// are not built when the table is loaded, the reports needing them build
+// them. An error writing the file leaves the parser with the table built.
+func (p *Parser) EnsureCachedTable(filename string) (cached bool, err error) {
+	if p.Table != nil {
+		return false, nil
+	}
+	if f, err := os.Open(filename); err == nil {
+		table, err := LoadLRTable(bufio.NewReader(f), p.Grammar)
+		_ = f.Close()
+		if err == nil {
+			p._mu.Lock()
+			p.Table = table
+			p._mu.Unlock()
+			return true, nil
+		}
+	}
+
+	p.EnsureTable()
+	f, err := os.Create(filename)
+	if err != nil {
+		return false, err
+	}
+	writer := bufio.NewWriter(f)
+	if err = p.Table.Save(writer, p.Grammar); err != nil {
+		_ = f.Close()
+		return false, err
+	}
+	if err = writer.Flush(); err != nil {
+		_ = f.Close()
+		return false, err
+	}
+	return false, f.Close()
+}
@@ -0,0 +1,64 @@
+package parser_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestLRTable_Save(t *testing.T) {
+	p := sharedParser()
+	var saved bytes.Buffer
+	if err := p.Table.Save(&saved, p.Grammar); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	table, err := LoadLRTable(bytes.NewReader(saved.Bytes()), p.Grammar)
+	if err != nil {
+		t.Fatalf("LoadLRTable: %v", err)
+	}
+	if !reflect.DeepEqual(table, p.Table) {
+		t.Errorf("Expected the table loaded to equal the one saved")
+	}
+
+	// any change the table depends on makes it stale
+	changed := p.Grammar.Copy()
+	changed.SetPrecedence("+", 1, Left)
+	for _, g := range []*Grammar{&changed, &grammars[0]} {
+		if _, err := LoadLRTable(bytes.NewReader(saved.Bytes()), g); !errors.Is(err, ErrStaleTable) {
+			t.Errorf("Expected the table to be stale for another grammar, got %v", err)
+		}
+	}
+	if _, err := LoadLRTable(strings.NewReader("not a table"), p.Grammar); err == nil || !strings.HasPrefix(err.Error(), "invalid table: ") {
+		t.Errorf("Expected an invalid table, got %v", err)
+	}
+}
+
+func TestParser_EnsureCachedTable(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "table.gob")
+	built := &Parser{Grammar: &grammars[0]}
+	if cached, err := built.EnsureCachedTable(filename); err != nil || cached {
+		t.Fatalf("Expected the table to be built and saved, got %v and %v", cached, err)
+	}
+	loaded := &Parser{Grammar: &grammars[0]}
+	if cached, err := loaded.EnsureCachedTable(filename); err != nil || !cached {
+		t.Fatalf("Expected the table saved to be loaded, got %v and %v", cached, err)
+	}
+	if !reflect.DeepEqual(loaded.Table, built.Table) || len(loaded.States) != 0 {
+		t.Errorf("Expected the table of the file without the states")
+	}
+	var report strings.Builder
+	if err := loaded.ReportConflicts(&report); err != nil || len(loaded.States) == 0 {
+		t.Errorf("Expected the report to build the states, got %v", err)
+	}
+
+	// a file of another grammar is rebuilt
+	other := &Parser{Grammar: &grammars[1]}
+	if cached, err := other.EnsureCachedTable(filename); err != nil || cached {
+		t.Errorf("Expected the stale table to be rebuilt, got %v and %v", cached, err)
+	}
+}
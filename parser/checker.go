@@ -0,0 +1,209 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+
+	"app/lexer"
+)
+
+// TypeOf resolves the data type of the value a node yields. Literals and
+// temporaries carry their type on the node, variables are looked up in the
+// symbol table. It returns lexer.Unknown when the type cannot be resolved.
+func (w *Walker) TypeOf(node *ASTNode) lexer.TokenSpecificType {
+	if node == nil {
+		return lexer.Unknown
+	}
+	if node.DataType != lexer.Unknown {
+		return node.DataType
+	}
+	if node.Token == nil || node.Token.Type != lexer.IDENTIFIER || w.SymbolTable == nil {
+		return lexer.Unknown
+	}
+	item, _, err := w.SymbolTable.Lookup(node.Token.Val)
+	if err != nil {
+		return lexer.Unknown
+	}
+	return lexer.ParseTypeName(item.UnderlyingType)
+}
+
+// Decay applies the array-to-pointer conversion to a value: an array named as
+// an argument stands for the address of its first element. The node returned
+// has type pointer and carries the symbol table item of the array as payload,
+// so that the element type and the declared length can still be checked.
+func (w *Walker) Decay(node *ASTNode) *ASTNode {
+	if node == nil || node.Token == nil || node.Token.Type != lexer.IDENTIFIER || w.SymbolTable == nil {
+		return node
+	}
+	item, _, err := w.SymbolTable.Lookup(node.Token.Val)
+	if err != nil || item.Type != SymbolTableItemTypeArray {
+		return node
+	}
+	return &ASTNode{
+		raw: node.raw,
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  "&" + node.Token.Val,
+		},
+		Children: []*ASTNode{node},
+		Type:     node.Type,
+		DataType: lexer.TypePointer,
+		Payload:  item,
+	}
+}
+
+// CheckWritable checks that the location, a variable or an element of an
+// array, may be assigned to, which is not the case for const variables.
+func (w *Walker) CheckWritable(loc *ASTNode) error {
+	base := loc
+	for len(base.Children) > 0 {
+		base = base.Children[0]
+	}
+	if base.Token == nil || base.Token.Type != lexer.IDENTIFIER || w.SymbolTable == nil {
+		return nil
+	}
+	item, _, err := w.SymbolTable.Lookup(base.Token.Val)
+	if err != nil || !item.Const {
+		return nil
+	}
+	what := "const " + item.Variable
+	if base != loc {
+		what = "element of const array " + item.Variable
+	}
+	return fmt.Errorf("cannot assign to %s (declared at line %d, pos %d), at line %d, pos %d",
+		what, item.Line, item.Pos, base.Token.Line, base.Token.Pos)
+}
+
+// CheckAssignable returns an error if the value cannot be stored into the
+// location, as the initializer of a declaration cannot: a whole array is not
+// assigned, and the type of the value must be Assignable to that of the
+// location, the one of its elements for an element of an array, and the one
+// pointed to for the location a pointer points to.
+func (w *Walker) CheckAssignable(loc, value *ASTNode) error {
+	if deref, ok := loc.Payload.(*Deref); ok {
+		if t := w.TypeOf(value); !Assignable(deref.Type, t) {
+			span := spanOf(loc)
+			return fmt.Errorf("cannot assign %s (%s) to %s (%s), at line %d, pos %d",
+				value.raw, t.ToString(), loc.raw, deref.Type.ToString(), span.Line, span.Pos)
+		}
+		return nil
+	}
+	base := loc
+	for len(base.Children) > 0 {
+		base = base.Children[0]
+	}
+	if base.Token == nil || base.Token.Type != lexer.IDENTIFIER || w.SymbolTable == nil {
+		return nil
+	}
+	item, _, err := w.SymbolTable.Lookup(base.Token.Val)
+	if err != nil {
+		return nil
+	}
+	dst := w.TypeOf(loc)
+	if item.Type == SymbolTableItemTypeArray {
+		if base == loc {
+			return fmt.Errorf("array %s cannot be assigned %s, at line %d, pos %d", item.Variable, value.raw, base.Token.Line, base.Token.Pos)
+		}
+		dst = lexer.ParseTypeName(item.UnderlyingType)
+	}
+	if t := w.TypeOf(value); !Assignable(dst, t) {
+		return fmt.Errorf("cannot assign %s (%s) to %s (%s), at line %d, pos %d",
+			value.raw, t.ToString(), loc.raw, dst.ToString(), base.Token.Line, base.Token.Pos)
+	}
+	if pointee, ok := value.Payload.(lexer.TokenSpecificType); ok && dst == lexer.TypePointer && pointee.ToString() != item.Pointee {
+		return fmt.Errorf("cannot assign %s (%s*) to %s (%s*), at line %d, pos %d",
+			value.raw, pointee.ToString(), loc.raw, item.Pointee, base.Token.Line, base.Token.Pos)
+	}
+	return nil
+}
+
+// IsIntegral checks if the type is one of the integer types.
+func IsIntegral(t lexer.TokenSpecificType) bool {
+	switch t {
+	case lexer.TypeInt, lexer.TypeInt8, lexer.TypeInt16, lexer.TypeInt32, lexer.TypeInt64,
+		lexer.TypeUnsignedInt, lexer.TypeUnsignedInt8, lexer.TypeUnsignedInt16, lexer.TypeUnsignedInt32, lexer.TypeUnsignedInt64,
+		lexer.TypeByte:
+		return true
+	}
+	return false
+}
+
+// IsNumeric checks if the type is an integer or a floating point type.
+func IsNumeric(t lexer.TokenSpecificType) bool {
+	switch t {
+	case lexer.TypeFloat, lexer.TypeFloat32, lexer.TypeFloat64:
+		return true
+	}
+	return IsIntegral(t)
+}
+
+// Assignable checks if a value of type src can be stored into a variable of
+// type dst. Integers widen to floating point types, but not the other way
+// round. Unknown types are accepted, since they have been reported already.
+func Assignable(dst, src lexer.TokenSpecificType) bool {
+	if dst == lexer.Unknown || src == lexer.Unknown || dst == src {
+		return true
+	}
+	if IsIntegral(dst) {
+		return IsIntegral(src)
+	}
+	if IsNumeric(dst) {
+		return IsNumeric(src)
+	}
+	return false
+}
+
+// IsLiteral checks if the node holds a constant known at compile time.
+func IsLiteral(node *ASTNode) bool {
+	if node == nil || node.Token == nil {
+		return false
+	}
+	switch node.Token.Type {
+	case lexer.INTEGER, lexer.FLOAT, lexer.STRING, lexer.CHAR:
+		return true
+	case lexer.RESERVED:
+		return node.Token.Val == "true" || node.Token.Val == "false"
+	}
+	return false
+}
+
+// IntLiteral returns the value of an integer literal node.
+// The second return value is false if the node is not an integer literal.
+func IntLiteral(node *ASTNode) (int64, bool) {
+	if node == nil || node.Token == nil || node.Token.Type != lexer.INTEGER {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(node.Token.Val, 0, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// NewIntLiteral creates a node holding a folded integer constant.
+func NewIntLiteral(v int64, children []*ASTNode) *ASTNode {
+	return NewLiteral(lexer.INTEGER, strconv.FormatInt(v, 10), lexer.TypeInt, children)
+}
+
+// NewLiteral creates a node holding a constant computed at compile time.
+func NewLiteral(tokenType lexer.ItemType, val string, dataType lexer.TokenSpecificType, children []*ASTNode) *ASTNode {
+	symbol := Symbol("num")
+	switch tokenType {
+	case lexer.FLOAT:
+		symbol = "real"
+	case lexer.STRING:
+		symbol = "str"
+	case lexer.RESERVED:
+		symbol = Symbol(val)
+	}
+	return &ASTNode{
+		raw: val,
+		Token: &lexer.Token{
+			Type: tokenType,
+			Val:  val,
+		},
+		Children: children,
+		Type:     symbol,
+		DataType: dataType,
+	}
+}
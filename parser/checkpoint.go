@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"maps"
+	"slices"
+
+	"app/parser/ast"
+	. "app/utils/collections"
+)
+
+// Checkpoint is the state of a session at some point of the parse, for
+// Rollback to return to once an alternative explored from there is given
+// up: the stacks of the driver, the symbol table, the environment of the
+// rules and what they emitted. The tokens are read by the caller, which
+// has to replay the ones read since.
+type Checkpoint struct {
+	states  Stack[int]
+	symbols Stack[Symbol]
+	tokens  Stack[*ASTNode]
+
+	table       *tableSnapshot
+	environment *Environment
+
+	threeAddress []string
+	lines        []int64
+	spans        []Span
+	docs         []Doc
+	warnings     []string
+	module       string
+	program      *ast.Program
+	errorCount   int
+	stopped      string
+	reducing     reduction
+
+	archived   map[string]*SymbolTableItem
+	read       int // tokens recorded for Compile
+	references []Reference
+	shifted    int // tokens shifted, recorded for Analyze
+	typed      int
+}
+
+// tableSnapshot is a copy of the scopes and the items of a symbol table.
+type tableSnapshot struct {
+	scopes       []*Scope
+	items        []map[string]*SymbolTableItem // of each scope
+	values       map[*SymbolTableItem]SymbolTableItem
+	current      *Scope
+	constants    map[string]*SymbolTableItem
+	modules      map[string]*Scope
+	addrCounter  int
+	frameAddr    int
+	frameSizes   []int // of each scope
+	constantAddr int
+	pruned       int
+}
+
+// Checkpoint returns the state of the session, which it can be rolled back
+// to any number of times.
+func (w *Walker) Checkpoint() *Checkpoint {
+	c := &Checkpoint{
+		states:       w.States.Copy(),
+		symbols:      w.Symbols.Copy(),
+		tokens:       w.Tokens.Copy(),
+		table:        w.SymbolTable.snapshot(),
+		environment:  w.Environment.copy(),
+		threeAddress: slices.Clone(w.ThreeAddress),
+		lines:        slices.Clone(w.Lines),
+		spans:        slices.Clone(w.Spans),
+		docs:         slices.Clone(w.Docs),
+		warnings:     slices.Clone(w.warnings),
+		module:       w.Module,
+		program:      w.Program,
+		errorCount:   w.errorCount,
+		stopped:      w.stopped,
+		reducing:     w.reducing,
+		archived:     maps.Clone(w.archived),
+	}
+	if w.tokens != nil {
+		c.read = len(*w.tokens)
+	}
+	if x := w.xref; x != nil {
+		c.references, c.shifted, c.typed = slices.Clone(x.references), len(x.shifted), len(x.expressions)
+	}
+	return c
+}
+
+// Rollback returns the session to the checkpoint, dropping the symbols
+// declared, the code emitted and the errors reported since. The items
+// declared before keep their identity, so the references to them held
+// elsewhere stay valid. The counts of a profile are not rolled back.
+func (w *Walker) Rollback(c *Checkpoint) {
+	w.States, w.Symbols, w.Tokens = c.states.Copy(), c.symbols.Copy(), c.tokens.Copy()
+	w.SymbolTable.restore(c.table)
+	*w.Environment = *c.environment.copy()
+	w.ThreeAddress, w.Lines, w.Spans = slices.Clone(c.threeAddress), slices.Clone(c.lines), slices.Clone(c.spans)
+	w.Docs, w.warnings = slices.Clone(c.docs), slices.Clone(c.warnings)
+	w.Module, w.errorCount, w.stopped, w.reducing = c.module, c.errorCount, c.stopped, c.reducing
+	w.Program, w.archived = c.program, maps.Clone(c.archived)
+	if w.tokens != nil && len(*w.tokens) > c.read {
+		*w.tokens = (*w.tokens)[:c.read]
+	}
+	if x := w.xref; x != nil && len(x.shifted) >= c.shifted {
+		for _, token := range x.shifted[c.shifted:] {
+			delete(x.tokens, token)
+		}
+		x.references = slices.Clone(c.references)
+		x.shifted, x.scopes, x.expressions = x.shifted[:c.shifted], x.scopes[:c.shifted], x.expressions[:c.typed]
+	}
+}
+
+// Speculate runs f from a checkpoint, and rolls the session back to it if f
+// fails, so that another alternative can be tried from the same state.
+func (w *Walker) Speculate(f func() error) error {
+	c := w.Checkpoint()
+	if err := f(); err != nil {
+		w.Rollback(c)
+		return err
+	}
+	return nil
+}
+
+// copy returns a copy of the environment whose stacks and declarators are
+// its own.
+func (e *Environment) copy() *Environment {
+	c := *e
+	c.CurrentDeclarators = slices.Clone(e.CurrentDeclarators)
+	c.BreakLabelStack, c.ItemStack = e.BreakLabelStack.Copy(), e.ItemStack.Copy()
+	return &c
+}
+
+// snapshot copies the scopes of the symbol table and the items in them.
+func (st *SymbolTable) snapshot() *tableSnapshot {
+	s := &tableSnapshot{
+		scopes:       slices.Clone(st.LegacyScopes),
+		values:       map[*SymbolTableItem]SymbolTableItem{},
+		current:      st.CurrentScope,
+		constants:    maps.Clone(st.Constants),
+		modules:      maps.Clone(st.Modules),
+		addrCounter:  st.addrCounter,
+		frameAddr:    st.frameAddr,
+		constantAddr: st.constantAddr,
+		pruned:       st.pruned,
+	}
+	for _, scope := range st.LegacyScopes {
+		s.items = append(s.items, maps.Clone(scope.Items))
+		s.frameSizes = append(s.frameSizes, scope.FrameSize)
+		for _, item := range scope.Items {
+			s.values[item] = item.copy()
+		}
+	}
+	for _, item := range st.Constants {
+		s.values[item] = item.copy()
+	}
+	return s
+}
+
+// restore returns the symbol table to the snapshot, writing the items back
+// in place.
+func (st *SymbolTable) restore(s *tableSnapshot) {
+	st.LegacyScopes = slices.Clone(s.scopes)
+	for i, scope := range st.LegacyScopes {
+		scope.Items, scope.FrameSize = maps.Clone(s.items[i]), s.frameSizes[i]
+	}
+	for item, value := range s.values {
+		*item = value.copy()
+	}
+	st.CurrentScope = s.current
+	st.Constants, st.Modules = maps.Clone(s.constants), maps.Clone(s.modules)
+	st.addrCounter, st.frameAddr, st.constantAddr, st.pruned = s.addrCounter, s.frameAddr, s.constantAddr, s.pruned
+}
+
+// copy returns the item with an initializer of its own.
+func (item *SymbolTableItem) copy() SymbolTableItem {
+	c := *item
+	c.Initializer = slices.Clone(item.Initializer)
+	c.Params = slices.Clone(item.Params)
+	return c
+}
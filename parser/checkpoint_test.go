@@ -0,0 +1,91 @@
+package parser_test
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+)
+
+// feed shifts the tokens of the source into the session, reducing as the
+// table says, the way the driver does.
+func feed(w *Walker, src string) error {
+	l := lexer.NewLexer(strings.NewReader(src))
+	for {
+		token, err := l.NextToken()
+		if errors.Is(err, io.EOF) || token.Type == lexer.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		symbol := Reflect(&token)
+		if symbol == "{" {
+			_ = w.SymbolTable.EnterScope()
+		}
+		for {
+			action, err := w.Next(symbol)
+			if err != nil {
+				return err
+			}
+			if action.Type != REDUCE {
+				break
+			}
+		}
+		if symbol == "}" {
+			_ = w.SymbolTable.ExitScope()
+		}
+		w.Tokens.Push(Token2ASTNode(&token))
+	}
+}
+
+func TestWalker_Rollback(t *testing.T) {
+	w := sharedParser().Tables().NewSession()
+	_ = w.SymbolTable.EnterScope()
+	if err := w.DeclareBuiltins(); err != nil {
+		t.Fatal(err)
+	}
+	if err := feed(w, "{ int a ; a = 1 ;"); err != nil {
+		t.Fatalf("feed: %v", err)
+	}
+	c := w.Checkpoint()
+	states, code := w.States.String(), slices.Clone(w.ThreeAddress)
+	a, _, _ := w.SymbolTable.Lookup("a")
+
+	for range 2 {
+		if err := feed(w, "int b ; b = a + 2 ; a = b ;"); err != nil {
+			t.Fatalf("feed: %v", err)
+		}
+		if _, _, err := w.SymbolTable.Lookup("b"); err != nil || len(w.ThreeAddress) == len(code) {
+			t.Fatalf("Expected b to be declared and code to be emitted, got %v", err)
+		}
+		w.Rollback(c)
+		if w.States.String() != states || !slices.Equal(w.ThreeAddress, code) {
+			t.Errorf("Expected the stacks and the code of the checkpoint, got %v and %v", w.States, w.ThreeAddress)
+		}
+		if _, _, err := w.SymbolTable.Lookup("b"); err == nil {
+			t.Errorf("Expected b to be dropped")
+		}
+		if item, _, err := w.SymbolTable.Lookup("a"); err != nil || item != a {
+			t.Errorf("Expected a to keep its identity, got %v", err)
+		}
+	}
+
+	// an alternative failing is rolled back by Speculate
+	err := w.Speculate(func() error { return feed(w, "int c ; a = = 2 ;") })
+	if err == nil || w.States.String() != states {
+		t.Errorf("Expected the alternative to fail and be rolled back, got %v and %v", err, w.States)
+	}
+	if _, _, err := w.SymbolTable.Lookup("c"); err == nil {
+		t.Errorf("Expected c to be dropped")
+	}
+	if err := w.Speculate(func() error { return feed(w, "a = 3 ; }") }); err != nil {
+		t.Fatalf("Expected the other alternative to parse, got %v", err)
+	}
+	if _, err := w.Next(TERMINATE); err != nil {
+		t.Errorf("Expected the program to be complete, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"app/parser"
+)
+
+// dataFormat is how the directives of the data segment of a target are
+// written, an empty string for those it has not.
+type dataFormat struct {
+	comment string
+	data    string
+	space   func(n int) string
+	values  func(width int, values []string) string
+	str     func(text string) string // the text escaped, terminated by a zero
+}
+
+// mipsData are the directives of MARS and SPIM.
+var mipsData = dataFormat{
+	comment: "#",
+	data:    ".data",
+	space:   func(n int) string { return fmt.Sprintf(".space %d", n) },
+	values: func(width int, values []string) string {
+		directive := map[int]string{1: ".byte", 2: ".half", 4: ".word"}[width]
+		if directive == "" {
+			return ""
+		}
+		return directive + " " + strings.Join(values, ", ")
+	},
+	str: func(text string) string { return fmt.Sprintf(".asciiz \"%s\"", text) },
+}
+
+// data writes the data segment: the variables and the temporaries at their
+// addresses from data, each variable at the label of its symbol, the initial
+// values of the globals and statics given one, the constant pool from pool
+// and the string literals of the code.
+func (g *generator) data(b *bytes.Buffer) error {
+	f := g.format
+	fmt.Fprintf(b, "\t%s\ndata:\n", f.data)
+	at := parser.InitialAddr
+	for _, item := range g.items {
+		if item.Address < at {
+			// a variable sharing the slot of another, see declare
+			continue
+		}
+		if item.Address > at {
+			fmt.Fprintf(b, "\t%s\n", f.space((item.Address-at)*4))
+		}
+		typ := item.UnderlyingType
+		if item.Type == parser.SymbolTableItemTypeArray {
+			typ += fmt.Sprintf("[%d]", item.ArraySize)
+		}
+		fmt.Fprintf(b, "%s:\t%s %s %s, at 0x%x\n", item.Symbol(), f.comment, item.Qualified(), typ, item.Address)
+		size := words(item) * 4
+		if len(item.Initializer) > 0 {
+			written, err := g.initializer(b, item)
+			if err != nil {
+				return err
+			}
+			size -= written
+		}
+		if size > 0 {
+			fmt.Fprintf(b, "\t%s\n", f.space(size))
+		}
+		at = item.Address + words(item)
+	}
+	if g.end > at {
+		fmt.Fprintf(b, "\t%s the temporaries\n\t%s\n", f.comment, f.space((g.end-at)*4))
+	}
+
+	b.WriteString("pool:\n")
+	at = parser.ConstantAddr
+	for _, addr := range slices.Sorted(maps.Keys(g.pool)) {
+		item := g.pool[addr]
+		if addr > at {
+			fmt.Fprintf(b, "\t%s\n", f.space((addr-at)*4))
+		}
+		size := words(item) * 4
+		if text, err := strconv.Unquote(item.Variable); err == nil {
+			g.writeString(b, text)
+			size -= len(text) + 1
+		} else {
+			v, err := g.operand(item.Variable)
+			if err != nil || v.memory != "" || v.address != "" {
+				return fmt.Errorf("invalid constant %s", item.Variable)
+			}
+			if poolFloat(item) && !v.float {
+				v.imm = int64(math.Float32bits(float32(v.imm)))
+			}
+			width, text := poolWidth(item), strconv.Itoa(int(int32(v.imm)))
+			if width == 8 {
+				text = strconv.FormatInt(v.imm, 10)
+			}
+			directive := f.values(width, []string{text})
+			if directive == "" {
+				return fmt.Errorf("constant %s of %d bytes is wider than the words of %s", item.Variable, width, g.arch)
+			}
+			fmt.Fprintf(b, "\t%s\t%s %s\n", directive, f.comment, item.Variable)
+			size -= width
+		}
+		if size > 0 {
+			fmt.Fprintf(b, "\t%s\n", f.space(size))
+		}
+		at = addr + words(item)
+	}
+
+	texts := make([]string, 0, len(g.strs))
+	for text := range g.strs {
+		texts = append(texts, text)
+	}
+	slices.SortFunc(texts, func(a, b string) int { return strings.Compare(g.strs[a], g.strs[b]) })
+	for _, text := range texts {
+		fmt.Fprintf(b, "%s:\n", g.strs[text])
+		g.writeString(b, text)
+	}
+	return nil
+}
+
+// initializer writes the initial values of the item, in its width, and
+// returns the bytes written.
+func (g *generator) initializer(b *bytes.Buffer, item *parser.SymbolTableItem) (int, error) {
+	float := g.slots[item.Qualified()].float
+	values := make([]string, len(item.Initializer))
+	for i, literal := range item.Initializer {
+		v, err := g.operand(literal)
+		if err != nil || v.memory != "" {
+			return 0, fmt.Errorf("invalid initializer %s of %s", literal, item.Qualified())
+		}
+		switch {
+		case v.address != "":
+			values[i] = v.address
+		case float && !v.float:
+			values[i] = strconv.Itoa(int(int32(math.Float32bits(float32(v.imm)))))
+		case item.VariableSize == 8:
+			values[i] = strconv.FormatInt(v.imm, 10)
+		default:
+			values[i] = strconv.Itoa(int(int32(v.imm)))
+		}
+	}
+	directive := g.format.values(item.VariableSize, values)
+	if directive == "" {
+		return 0, fmt.Errorf("%s of %d bytes is wider than the words of %s", item.Qualified(), item.VariableSize, g.arch)
+	}
+	fmt.Fprintf(b, "\t%s\n", directive)
+	return len(values) * item.VariableSize, nil
+}
+
+// writeString writes the text terminated by a zero, as a string if it is
+// printable and the target has them, and as bytes if not.
+func (g *generator) writeString(b *bytes.Buffer, text string) {
+	var quoted strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '\n':
+			quoted.WriteString(`\n`)
+		case c == '\t':
+			quoted.WriteString(`\t`)
+		case c == '"' || c == '\\':
+			quoted.WriteByte('\\')
+			quoted.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			g.writeBytes(b, text)
+			return
+		default:
+			quoted.WriteByte(c)
+		}
+	}
+	if directive := g.format.str(quoted.String()); directive != "" {
+		fmt.Fprintf(b, "\t%s\n", directive)
+		return
+	}
+	g.writeBytes(b, text)
+}
+
+func (g *generator) writeBytes(b *bytes.Buffer, text string) {
+	bytes := make([]string, 0, len(text)+1)
+	for _, c := range []byte(text) {
+		bytes = append(bytes, strconv.Itoa(int(c)))
+	}
+	fmt.Fprintf(b, "\t%s\n", g.format.values(1, append(bytes, "0")))
+}
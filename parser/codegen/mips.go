@@ -0,0 +1,718 @@
+// Package codegen lowers the quadruples of the parser to target code.
+package codegen
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"app/parser"
+	"app/parser/ir"
+)
+
+// MIPS writes the quadruples as a MIPS32 program for MARS and SPIM. Every
+// variable and temporary lives in the data segment at the address the symbol
+// table of the session gave it, which the instructions load from and store
+// to, the literals of the constant pool follow it. The expressions of
+// integers are lowered by the selector in $t0 to $t8, the rest quadruple by
+// quadruple. The arguments of a call are pushed on the stack and its value
+// is returned in $v0, the runtime functions the code calls are appended
+// after it, printing and reading through the syscalls.
+func MIPS(out io.Writer, w *parser.Walker, quads []ir.Quad) error {
+	g := newGenerator("MIPS32", 4, mipsData)
+	if err := g.declare(w); err != nil {
+		return err
+	}
+	s := newSelector(g, mipsMachine{g}, quads, g.quad)
+	for _, q := range quads {
+		if err := s.quad(q); err != nil {
+			return err
+		}
+	}
+	if err := s.flush(nil); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := g.data(&b); err != nil {
+		return err
+	}
+	fmt.Fprintf(&b, "\n\t.text\n\t.globl main\nmain:\n")
+	b.Write(g.text.Bytes())
+	fmt.Fprintf(&b, "\tli $v0, 10\n\tsyscall\n")
+	for _, name := range runtimeOrder {
+		if g.runtime[name] {
+			fmt.Fprintf(&b, "\n%s", runtime[name])
+		}
+	}
+	_, err := out.Write(b.Bytes())
+	return err
+}
+
+// mipsMachine writes the instructions the selector chooses as MIPS32.
+type mipsMachine struct {
+	g *generator
+}
+
+// mipsOps are the instructions of the operations named otherwise in MIPS.
+var mipsOps = map[string]string{"add": "addu", "sub": "subu", "addi": "addiu"}
+
+func (m mipsMachine) comment(tac string) {
+	fmt.Fprintf(&m.g.text, "\t# %s\n", tac)
+}
+
+func (m mipsMachine) registers() []string {
+	return []string{"$t0", "$t1", "$t2", "$t3", "$t4", "$t5", "$t6", "$t7", "$t8"}
+}
+
+func (m mipsMachine) zero() string {
+	return "$zero"
+}
+
+func (m mipsMachine) load(reg string, v value) error {
+	m.g.loadValue(reg, v)
+	return nil
+}
+
+func (m mipsMachine) store(reg, operand string) error {
+	return m.g.store(reg, operand)
+}
+
+func (m mipsMachine) op(op, d, s, t string) error {
+	if op == "neg" {
+		op, s, t = "subu", "$zero", s
+	}
+	m.g.emit("%s %s, %s, %s", cmp.Or(mipsOps[op], op), d, s, t)
+	return nil
+}
+
+func (m mipsMachine) immediate(op string, imm int64) bool {
+	if op == "sll" || op == "sra" {
+		return imm < 32
+	}
+	return imm >= math.MinInt16 && imm <= math.MaxInt16
+}
+
+func (m mipsMachine) opImm(op, d, s string, imm int64) error {
+	m.g.emit("%s %s, %s, %d", cmp.Or(mipsOps[op], op), d, s, imm)
+	return nil
+}
+
+func (m mipsMachine) branch(op, s, t, label string) error {
+	m.g.emit("%s %s, %s, %s", op, s, t, label)
+	return nil
+}
+
+func (m mipsMachine) push(reg string) error {
+	m.g.emit("addiu $sp, $sp, -4")
+	m.g.emit("sw %s, 0($sp)", reg)
+	return nil
+}
+
+// slot is where a variable lives, in bytes from the start of the data
+// segment, with the width of its elements.
+type slot struct {
+	item   *parser.SymbolTableItem
+	offset int
+	width  int
+	float  bool
+}
+
+// value is how an instruction reaches an operand: a word of memory, an
+// immediate or the address of a label.
+type value struct {
+	memory   string // the address, such as data+8, of an operand in memory
+	address  string // the label of an operand standing for its address
+	imm      int64  // the bits of an immediate, if neither
+	width    int    // bytes read and written in memory
+	unsigned bool
+	float    bool
+}
+
+type generator struct {
+	text    bytes.Buffer
+	slots   map[string]slot                 // the variables by their name in the code
+	items   []*parser.SymbolTableItem       // the variables in the order of their addresses
+	pool    map[int]*parser.SymbolTableItem // the literals of the constant pool by address
+	end     int                             // the address past the last variable or temporary
+	strs    map[string]string               // the labels of the string literals
+	floats  map[string]bool                 // the temporaries holding a float
+	runtime map[string]bool                 // the runtime functions called
+	labels  int
+
+	functions map[string]*parser.SymbolTableItem // the functions of the symbol table by entry label
+
+	arch     string // the name of the target in the errors
+	maxWidth int    // the bytes of the widest variable the target reads at once
+	intBits  int    // of the int of the program, see parser.IntWidth
+	format   dataFormat
+}
+
+func newGenerator(arch string, maxWidth int, format dataFormat) *generator {
+	return &generator{
+		slots:   map[string]slot{},
+		pool:    map[int]*parser.SymbolTableItem{},
+		strs:    map[string]string{},
+		floats:  map[string]bool{},
+		runtime: map[string]bool{},
+
+		functions: map[string]*parser.SymbolTableItem{},
+
+		arch:     arch,
+		maxWidth: maxWidth,
+		format:   format,
+	}
+}
+
+// declare gives the variables of the session their slots. The code names
+// variables rather than declarations, so the locals of the same name share
+// the slot of the largest of them. An int wider than the words of the target
+// fails.
+func (g *generator) declare(w *parser.Walker) error {
+	g.intBits = w.IntWidth.Bits()
+	if w.IntWidth.Bytes() > g.maxWidth {
+		return fmt.Errorf("the %d-bit int is wider than the words of %s", g.intBits, g.arch)
+	}
+	seen := map[*parser.SymbolTableItem]bool{}
+	add := func(item *parser.SymbolTableItem) {
+		if seen[item] || item.Type != parser.SymbolTableItemTypeVariable && item.Type != parser.SymbolTableItemTypeArray {
+			return
+		}
+		seen[item] = true
+		g.items = append(g.items, item)
+	}
+	for _, scope := range w.SymbolTable.LegacyScopes {
+		for _, item := range scope.Items {
+			if item.Type == parser.SymbolTableItemTypeFunction {
+				g.functions[item.Label] = item
+			} else if scope.Level >= 1 {
+				add(item)
+			}
+		}
+	}
+	for _, item := range w.Locals() {
+		add(item)
+	}
+	slices.SortFunc(g.items, func(a, b *parser.SymbolTableItem) int { return a.Address - b.Address })
+	g.end = parser.InitialAddr
+	for _, item := range g.items {
+		g.end = max(g.end, item.Address+words(item))
+		name := item.Qualified()
+		if s, ok := g.slots[name]; ok && words(s.item) >= words(item) {
+			continue
+		}
+		typ := item.UnderlyingType
+		g.slots[name] = slot{
+			item:   item,
+			offset: (item.Address - parser.InitialAddr) * 4,
+			width:  item.VariableSize,
+			float:  typ == "float" || typ == "float32",
+		}
+	}
+	for _, item := range w.SymbolTable.Constants {
+		g.pool[item.Address] = item
+	}
+	return nil
+}
+
+// words returns the words the symbol table gave the variable.
+func words(item *parser.SymbolTableItem) int {
+	return (item.VariableSize*max(item.ArraySize, 1) + 3) / 4
+}
+
+func (g *generator) emit(format string, args ...any) {
+	fmt.Fprintf(&g.text, "\t"+format+"\n", args...)
+}
+
+func (g *generator) newLabel() string {
+	g.labels++
+	return fmt.Sprintf("cmp_%d", g.labels)
+}
+
+// relations are the comparisons by the names the code gives them, as written
+// and as the parser emits them.
+var relations = map[string]string{
+	"<": "<", "<=": "<=", ">": ">", ">=": ">=", "==": "==", "!=": "!=",
+	"lt": "<", "le": "<=", "gt": ">", "ge": ">=", "eq": "==", "ne": "!=",
+}
+
+// branches and sets are the instructions jumping on and computing a relation
+// of integers.
+var (
+	branches = map[string]string{"<": "blt", "<=": "ble", ">": "bgt", ">=": "bge", "==": "beq", "!=": "bne"}
+	sets     = map[string]string{"<": "slt", "<=": "sle", ">": "sgt", ">=": "sge", "==": "seq", "!=": "sne"}
+)
+
+// arithmetic are the instructions of the operations on integers and floats.
+var arithmetic = map[string][2]string{
+	"+": {"addu", "add.s"}, "-": {"subu", "sub.s"}, "*": {"mul", "mul.s"}, "/": {"div", "div.s"},
+	"%": {"rem", ""}, "mod": {"rem", ""},
+}
+
+func (g *generator) quad(q ir.Quad) error {
+	if q.Op == ir.Label {
+		fmt.Fprintf(&g.text, "%s:\n", q.Result)
+		return nil
+	}
+	fmt.Fprintf(&g.text, "\t# %s\n", q.TAC())
+	switch {
+	case q.Op == ir.Goto:
+		g.emit("j %s", q.Result)
+	case q.Relop() != "":
+		return g.branch(q)
+	case q.Op == ir.Param:
+		if err := g.load("$t0", q.Arg1); err != nil {
+			return err
+		}
+		g.emit("addiu $sp, $sp, -4")
+		g.emit("sw $t0, 0($sp)")
+	case q.Op == ir.Call || q.Op == ir.ICall:
+		return g.call(q)
+	case q.Op == "ret":
+		if q.Arg1 != "" {
+			if err := g.load("$v0", q.Arg1); err != nil {
+				return err
+			}
+		}
+		g.emit("jr $ra")
+	case q.Op == ir.Copy:
+		return g.copy(q)
+	case q.Op == "itof":
+		if err := g.loadFloat("$f0", q.Arg1); err != nil {
+			return err
+		}
+		return g.storeFloat("$f0", q.Result)
+	case q.Op == "minus":
+		return g.minus(q)
+	case q.Op == "strcat" || q.Op == "streq" || q.Op == "strne":
+		for _, arg := range []string{q.Arg1, q.Arg2} {
+			if err := g.quad(ir.Quad{Op: ir.Param, Arg1: arg}); err != nil {
+				return err
+			}
+		}
+		return g.call(ir.Quad{Op: ir.Call, Arg1: q.Op, Arg2: "2", Result: q.Result})
+	case relations[q.Op] != "" && q.Arg2 != "":
+		return g.compare(q)
+	case arithmetic[q.Op][0] != "" && q.Arg2 != "":
+		return g.arithmetic(q)
+	default:
+		return fmt.Errorf("unsupported operation %s", q.Op)
+	}
+	return nil
+}
+
+func (g *generator) copy(q ir.Quad) error {
+	src, err := g.operand(q.Arg1)
+	if err != nil {
+		return err
+	}
+	dst, err := g.operand(q.Result)
+	if err != nil {
+		return err
+	}
+	if dst.float && !src.float && src.memory == "" && src.address == "" {
+		// an integer literal copied into a float
+		src.imm, src.float = int64(math.Float32bits(float32(src.imm))), true
+	}
+	g.setFloat(q.Result, src.float)
+	g.loadValue("$t0", src)
+	return g.store("$t0", q.Result)
+}
+
+func (g *generator) minus(q ir.Quad) error {
+	if g.isFloat(q.Arg1) {
+		if err := g.loadFloat("$f0", q.Arg1); err != nil {
+			return err
+		}
+		g.emit("neg.s $f0, $f0")
+		return g.storeFloat("$f0", q.Result)
+	}
+	if err := g.load("$t0", q.Arg1); err != nil {
+		return err
+	}
+	g.emit("subu $t0, $zero, $t0")
+	if err := extend(g, mipsMachine{g}, "$t0"); err != nil {
+		return err
+	}
+	return g.store("$t0", q.Result)
+}
+
+func (g *generator) arithmetic(q ir.Quad) error {
+	ops := arithmetic[q.Op]
+	if ops[1] != "" && (g.isFloat(q.Arg1) || g.isFloat(q.Arg2)) {
+		if err := g.loadFloats(q.Arg1, q.Arg2); err != nil {
+			return err
+		}
+		g.emit("%s $f0, $f0, $f2", ops[1])
+		return g.storeFloat("$f0", q.Result)
+	}
+	if err := g.loadInts(q.Arg1, q.Arg2); err != nil {
+		return err
+	}
+	g.emit("%s $t0, $t0, $t1", ops[0])
+	if err := extend(g, mipsMachine{g}, "$t0"); err != nil {
+		return err
+	}
+	return g.store("$t0", q.Result)
+}
+
+// compare stores 1 if the relation holds, 0 if not.
+func (g *generator) compare(q ir.Quad) error {
+	relation := relations[q.Op]
+	if g.isFloat(q.Arg1) || g.isFloat(q.Arg2) {
+		if err := g.loadFloats(q.Arg1, q.Arg2); err != nil {
+			return err
+		}
+		holds := g.floatCondition(relation)
+		end := g.newLabel()
+		g.emit("li $t0, 1")
+		g.emit("%s %s", holds, end)
+		g.emit("li $t0, 0")
+		fmt.Fprintf(&g.text, "%s:\n", end)
+	} else {
+		if err := g.loadInts(q.Arg1, q.Arg2); err != nil {
+			return err
+		}
+		g.emit("%s $t0, $t0, $t1", sets[relation])
+	}
+	g.setFloat(q.Result, false)
+	return g.store("$t0", q.Result)
+}
+
+func (g *generator) branch(q ir.Quad) error {
+	relation, ok := relations[q.Relop()]
+	if !ok {
+		return fmt.Errorf("unsupported relation %s", q.Relop())
+	}
+	if g.isFloat(q.Arg1) || g.isFloat(q.Arg2) {
+		if err := g.loadFloats(q.Arg1, q.Arg2); err != nil {
+			return err
+		}
+		g.emit("%s %s", g.floatCondition(relation), q.Result)
+		return nil
+	}
+	if err := g.loadInts(q.Arg1, q.Arg2); err != nil {
+		return err
+	}
+	g.emit("%s $t0, $t1, %s", branches[relation], q.Result)
+	return nil
+}
+
+// floatCondition compares $f0 with $f2 and returns the branch taken if the
+// relation holds.
+func (g *generator) floatCondition(relation string) string {
+	switch relation {
+	case "<":
+		g.emit("c.lt.s $f0, $f2")
+	case "<=":
+		g.emit("c.le.s $f0, $f2")
+	case ">":
+		g.emit("c.lt.s $f2, $f0")
+	case ">=":
+		g.emit("c.le.s $f2, $f0")
+	default:
+		g.emit("c.eq.s $f0, $f2")
+	}
+	if relation == "!=" {
+		return "bc1f"
+	}
+	return "bc1t"
+}
+
+// call jumps to the function, pops its arguments and stores its value. The
+// functions of the symbol table are jumped to at their entry label, which
+// another file assembled with the program defines.
+func (g *generator) call(q ir.Quad) error {
+	if q.Op == ir.ICall {
+		if err := g.load("$t0", q.Arg1); err != nil {
+			return err
+		}
+		g.emit("jalr $t0")
+	} else if function, ok := g.functions[q.Arg1]; ok {
+		g.emit("jal %s", function.Label)
+	} else {
+		if _, ok := runtime[q.Arg1]; !ok {
+			return fmt.Errorf("unknown function %s", q.Arg1)
+		}
+		g.use(q.Arg1)
+		g.emit("jal rt_%s", q.Arg1)
+	}
+	n, err := strconv.Atoi(q.Arg2)
+	if err != nil {
+		return fmt.Errorf("invalid number of parameters %s", q.Arg2)
+	}
+	if n > 0 {
+		g.emit("addiu $sp, $sp, %d", 4*n)
+	}
+	if q.Result == "" {
+		return nil
+	}
+	// read_float and the functions of a float type return the bits of the
+	// float, as the others return words
+	float := q.Op == ir.Call && q.Arg1 == "read_float"
+	if function, ok := g.functions[q.Arg1]; ok && q.Op == ir.Call {
+		float = function.UnderlyingType == "float" || function.UnderlyingType == "float32"
+	}
+	g.setFloat(q.Result, float)
+	return g.store("$v0", q.Result)
+}
+
+// use marks the runtime function as called, with those it calls.
+func (g *generator) use(name string) {
+	g.runtime[name] = true
+	if name == "strne" {
+		g.runtime["streq"] = true
+	}
+}
+
+// operand returns how to reach the operand: a string literal is the address
+// of its characters, a literal of the constant pool its value but for the
+// strings, &x the address of x or of the runtime function x.
+func (g *generator) operand(s string) (value, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		text, err := strconv.Unquote(s)
+		if err != nil {
+			return value{}, fmt.Errorf("invalid string %s", s)
+		}
+		return value{address: g.str(text)}, nil
+	case s == "true":
+		return value{imm: 1}, nil
+	case s == "false":
+		return value{imm: 0}, nil
+	case strings.HasPrefix(s, "&"):
+		name := s[1:]
+		if _, ok := runtime[name]; ok && g.slots[name].item == nil {
+			g.use(name)
+			return value{address: "rt_" + name}, nil
+		}
+		v, err := g.operand(name)
+		if err != nil || v.memory == "" {
+			return value{}, fmt.Errorf("cannot take the address of %s", name)
+		}
+		return value{address: v.memory}, nil
+	}
+	if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return value{imm: i}, nil
+	}
+	if isFloatLiteral(s) {
+		f, _ := strconv.ParseFloat(s, 32)
+		return value{imm: int64(math.Float32bits(float32(f))), float: true}, nil
+	}
+	var addr int
+	if _, err := fmt.Sscanf(s, "$(0x%x)", &addr); err == nil {
+		if addr < parser.ConstantAddr {
+			// a temporary, of a word or of an int wider than it
+			width := max(4, g.intBits/8)
+			g.end = max(g.end, addr+width/4)
+			return value{memory: fmt.Sprintf("data+%d", (addr-parser.InitialAddr)*4), width: width, float: g.floats[s]}, nil
+		}
+		item, ok := g.pool[addr]
+		if !ok {
+			return value{}, fmt.Errorf("no constant at %s", s)
+		}
+		where := fmt.Sprintf("pool+%d", (addr-parser.ConstantAddr)*4)
+		if strings.HasPrefix(item.Variable, `"`) {
+			return value{address: where}, nil
+		}
+		return value{memory: where, width: poolWidth(item), float: poolFloat(item)}, nil
+	}
+	return g.variable(s)
+}
+
+// variable returns the variable, x, or its element, x [ i ] at the index as
+// written or x[n] at the byte offset.
+func (g *generator) variable(s string) (value, error) {
+	name, offset := s, 0
+	fields := strings.Fields(s)
+	element := false
+	switch {
+	case len(fields) == 4 && fields[1] == "[" && fields[3] == "]":
+		i, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return value{}, fmt.Errorf("invalid index of %s", s)
+		}
+		name, offset, element = fields[0], i, true
+	case len(fields) > 4:
+		return value{}, fmt.Errorf("%s has more than one index", s)
+	case strings.HasSuffix(s, "]"):
+		before, index, _ := strings.Cut(strings.TrimSuffix(s, "]"), "[")
+		n, err := strconv.Atoi(index)
+		if err != nil {
+			return value{}, fmt.Errorf("invalid offset of %s", s)
+		}
+		name, offset = before, n
+	}
+	sl, ok := g.slots[name]
+	if !ok {
+		return value{}, fmt.Errorf("undefined %s", name)
+	}
+	if element {
+		offset *= sl.width
+	}
+	if !slices.Contains([]int{1, 2, 4, 8}, sl.width) || sl.width > g.maxWidth {
+		return value{}, fmt.Errorf("%s of %d bytes is wider than the words of %s", name, sl.width, g.arch)
+	}
+	if offset < 0 || offset+sl.width > words(sl.item)*4 {
+		return value{}, fmt.Errorf("%s is out of the bounds of %s", s, name)
+	}
+	return value{
+		memory:   fmt.Sprintf("data+%d", sl.offset+offset),
+		width:    sl.width,
+		unsigned: strings.HasPrefix(sl.item.UnderlyingType, "uint") || sl.item.UnderlyingType == "byte",
+		float:    sl.float,
+	}, nil
+}
+
+// poolWidth returns the bytes of the literal of the constant pool, a word but
+// for the integers of 8 bytes.
+func poolWidth(item *parser.SymbolTableItem) int {
+	if item.VariableSize == 8 && !poolFloat(item) {
+		return 8
+	}
+	return 4
+}
+
+// poolFloat checks if the constant is a float, written as one or of a float
+// type, as the float 1 of float f = 1.
+func poolFloat(item *parser.SymbolTableItem) bool {
+	return isFloatLiteral(item.Variable) || item.UnderlyingType == "float" || item.UnderlyingType == "float32"
+}
+
+// isFloatLiteral checks if the operand is a number but not an integer, which
+// rules out the names ParseFloat takes, such as inf.
+func isFloatLiteral(s string) bool {
+	digits := strings.TrimLeft(s, "-.")
+	if digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return false
+	}
+	if _, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 32)
+	return err == nil
+}
+
+// str returns the label of the string literal.
+func (g *generator) str(text string) string {
+	if label, ok := g.strs[text]; ok {
+		return label
+	}
+	label := fmt.Sprintf("str_%d", len(g.strs))
+	g.strs[text] = label
+	return label
+}
+
+func (g *generator) isFloat(operand string) bool {
+	v, err := g.operand(operand)
+	return err == nil && v.float
+}
+
+// setFloat records if the temporary holds a float. The type of a variable
+// is the declared one.
+func (g *generator) setFloat(operand string, float bool) {
+	if strings.HasPrefix(operand, "$(") {
+		g.floats[operand] = float
+	}
+}
+
+func (g *generator) load(reg, operand string) error {
+	v, err := g.operand(operand)
+	if err != nil {
+		return err
+	}
+	g.loadValue(reg, v)
+	return nil
+}
+
+func (g *generator) loadValue(reg string, v value) {
+	switch {
+	case v.memory != "":
+		g.emit("%s %s, %s", loads[v.width][boolIndex(v.unsigned)], reg, v.memory)
+	case v.address != "":
+		g.emit("la %s, %s", reg, v.address)
+	default:
+		g.emit("li %s, %d", reg, int32(v.imm))
+	}
+}
+
+// loads and stores are the instructions reading and writing memory by the
+// width, the loads of the unsigned ones second.
+var (
+	loads  = map[int][2]string{1: {"lb", "lbu"}, 2: {"lh", "lhu"}, 4: {"lw", "lw"}}
+	stores = map[int]string{1: "sb", 2: "sh", 4: "sw"}
+)
+
+func boolIndex(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (g *generator) loadInts(a, b string) error {
+	if err := g.load("$t0", a); err != nil {
+		return err
+	}
+	return g.load("$t1", b)
+}
+
+// loadFloat loads the operand into the float register, converting an
+// integer.
+func (g *generator) loadFloat(reg, operand string) error {
+	v, err := g.operand(operand)
+	if err != nil {
+		return err
+	}
+	switch {
+	case v.float && v.memory != "":
+		g.emit("lwc1 %s, %s", reg, v.memory)
+	case v.float || v.memory == "" && v.address == "":
+		if !v.float {
+			v.imm = int64(math.Float32bits(float32(v.imm)))
+		}
+		g.emit("li $t9, %d", int32(v.imm))
+		g.emit("mtc1 $t9, %s", reg)
+	default:
+		g.loadValue("$t9", v)
+		g.emit("mtc1 $t9, %s", reg)
+		g.emit("cvt.s.w %s, %s", reg, reg)
+	}
+	return nil
+}
+
+func (g *generator) loadFloats(a, b string) error {
+	if err := g.loadFloat("$f0", a); err != nil {
+		return err
+	}
+	return g.loadFloat("$f2", b)
+}
+
+func (g *generator) store(reg, operand string) error {
+	v, err := g.operand(operand)
+	if err != nil {
+		return err
+	}
+	if v.memory == "" {
+		return fmt.Errorf("cannot assign to %s", operand)
+	}
+	g.emit("%s %s, %s", stores[v.width], reg, v.memory)
+	return nil
+}
+
+func (g *generator) storeFloat(reg, operand string) error {
+	g.setFloat(operand, true)
+	v, err := g.operand(operand)
+	if err != nil {
+		return err
+	}
+	if v.memory == "" || v.width != 4 {
+		return fmt.Errorf("cannot assign a float to %s", operand)
+	}
+	g.emit("swc1 %s, %s", reg, v.memory)
+	return nil
+}
@@ -0,0 +1,182 @@
+package codegen_test
+
+import (
+	"strings"
+	"testing"
+
+	"app/parser"
+	. "app/parser/codegen"
+)
+
+func compile(t *testing.T, program string) string {
+	t.Helper()
+	result, err := parser.Compile(parser.Options{Source: strings.NewReader(program)})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("Expected the program to compile, got %v", result.Diagnostics)
+	}
+	var out strings.Builder
+	if err := MIPS(&out, result.Walker, result.Walker.Quads()); err != nil {
+		t.Fatalf("MIPS: %v", err)
+	}
+	return out.String()
+}
+
+func TestMIPS(t *testing.T) {
+	code := compile(t, `{
+    int[4] a = {1, 2, 3, 4};
+    int n;
+    float x, y = 1;
+    n = readint();
+    a[1] = abs(n) % 3;
+    x = n + 1.5;
+    printf("%d %f\n", a[1], x);
+}`)
+	for _, expected := range []string{
+		"\t# a int[4], at 0x10000000\n\t.word 1, 2, 3, 4\n",
+		"\t# n int, at 0x10000004\n\t.space 4\n",
+		"main:\n",
+		"\tjal rt_read_int\n",
+		"\trem $t0, $t0, $t1\n",
+		"\tsw $t0, data+4\n",
+		"\tcvt.s.w $f0, $f0\n",
+		"\tadd.s $f0, $f0, $f2\n",
+		"\tjal rt_print_int\n",
+		"\tjal rt_print_float\n",
+		"\tli $v0, 10\n\tsyscall\n",
+		"rt_print_float:\n\tlwc1 $f12, 0($sp)\n\tli $v0, 2\n\tsyscall\n",
+		".asciiz \" \"\n",
+		".asciiz \"\\n\"\n",
+		// the float 1 of the pool, written as a float
+		"pool:\n\t.word 1065353216\t# 1\n",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected %q in the code, got\n%s", expected, code)
+		}
+	}
+	if strings.Contains(code, "rt_strcat:") {
+		t.Errorf("Expected only the runtime functions called, got\n%s", code)
+	}
+}
+
+func TestMIPS_Branches(t *testing.T) {
+	code := compile(t, `{
+    int a, b, c;
+    a = readint();
+    b = readint();
+    c = max(a, b);
+}`)
+	for _, expected := range []string{"\tbge $t0, $t1, L_max_0\n", "L_max_0:\n"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected %q in the code, got\n%s", expected, code)
+		}
+	}
+}
+
+func TestMIPS_Loops(t *testing.T) {
+	code := compile(t, `{
+    int i;
+    i = 0;
+    while (i < 3) { i = i + 1; }
+    if (i > 100) { printf("big\n"); }
+}`)
+	for _, expected := range []string{"L_while_0:\n", "\tj L_while_0\n", "L_while_0_end:\n", "L_if_1_end:\n"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected %q in the code, got\n%s", expected, code)
+		}
+	}
+}
+
+func TestMIPS_Functions(t *testing.T) {
+	result, err := parser.Compile(parser.Options{
+		Source: strings.NewReader("{ float x; x = scale(2); }"),
+		Declare: func(st *parser.SymbolTable) error {
+			_, err := st.RegisterFunction("scale", []parser.Param{{Name: "n", Type: "int"}}, "float")
+			return err
+		},
+	})
+	if err != nil || result.Failed() {
+		t.Fatalf("Expected the program to compile, got %v, %v", result, err)
+	}
+	var out strings.Builder
+	if err := MIPS(&out, result.Walker, result.Walker.Quads()); err != nil {
+		t.Fatalf("MIPS: %v", err)
+	}
+	code := out.String()
+	if expected := "\tjal F_scale\n\taddiu $sp, $sp, 4\n"; !strings.Contains(code, expected) {
+		t.Errorf("Expected %q in the code, got\n%s", expected, code)
+	}
+	// the value is the bits of a float, stored as they are
+	if strings.Contains(code, "cvt.s.w") {
+		t.Errorf("Expected the value not converted, got\n%s", code)
+	}
+}
+
+func TestMIPS_Pointers(t *testing.T) {
+	code := compile(t, `{
+    int* p = new(int[2]);
+    byte* b = new(byte);
+    p[1] = 3;
+    *b = *p;
+    printf("%d\n", *b);
+}`)
+	for _, expected := range []string{
+		"\tjal rt_alloc\n",
+		"\tjal rt_store4\n",
+		"\tjal rt_load4\n",
+		"\tjal rt_store1\n",
+		"\tjal rt_loadu1\n",
+		"rt_store4:\n\tlw $t0, 8($sp)\n\tlw $t1, 4($sp)\n\taddu $t0, $t0, $t1\n\tlw $t1, 0($sp)\n\tsw $t1, 0($t0)\n",
+		"rt_loadu1:\n\tlw $t0, 4($sp)\n\tlw $t1, 0($sp)\n\taddu $t0, $t0, $t1\n\tlbu $v0, 0($t0)\n",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected %q in the code, got\n%s", expected, code)
+		}
+	}
+}
+
+func TestMIPS_Symbols(t *testing.T) {
+	code := compile(t, `{
+    int main, la;
+    { int la; la = 1; }
+    main = 2;
+}`)
+	// the variables named as the entry label and a mnemonic are labeled by
+	// their symbols, the locals of the same name apart
+	for _, expected := range []string{"_V4main:\t# main int", "_V2la:\t# la int", "_V2la_2:\t# la int", "main:\n"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected %q in the code, got\n%s", expected, code)
+		}
+	}
+}
+
+func TestMIPS_IntWidth(t *testing.T) {
+	generate := func(width parser.IntWidth) (string, error) {
+		result, err := parser.Compile(parser.Options{Source: strings.NewReader("{ int a, b; a = readint(); b = a * 3 + 1; }\n"), IntWidth: width})
+		if err != nil || result.Failed() {
+			t.Fatalf("Expected the program to compile, got %v, %v", result, err)
+		}
+		var out strings.Builder
+		err = MIPS(&out, result.Walker, result.Walker.Quads())
+		return out.String(), err
+	}
+	// the int of 16 bits is read and written by halves, and wraps in the
+	// registers
+	code, err := generate(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"\tlh $t0, data+0\n", "\tmul $t0, $t0, $t1\n\tsll $t0, $t0, 16\n\tsra $t0, $t0, 16\n", "\tsh $t0, data+4\n"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected %q in the code, got\n%s", expected, code)
+		}
+	}
+	if code, _ = generate(32); strings.Contains(code, "sra") || strings.Contains(code, "lh ") {
+		t.Errorf("Expected the words of the int of 32 bits as they are, got\n%s", code)
+	}
+	if _, err = generate(64); err == nil || err.Error() != "the 64-bit int is wider than the words of MIPS32" {
+		t.Errorf("Expected the int of 64 bits to fail, got %v", err)
+	}
+}
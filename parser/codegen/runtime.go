@@ -0,0 +1,231 @@
+package codegen
+
+// runtime are the functions the code calls, by the name the parser gives
+// them. The arguments are on the stack, the last one at 0($sp), and the
+// value is returned in $v0, a float as its bits.
+var runtime = map[string]string{
+	"print_int": `rt_print_int:
+	lw $a0, 0($sp)
+	li $v0, 1
+	syscall
+	jr $ra
+`,
+	"print_float": `rt_print_float:
+	lwc1 $f12, 0($sp)
+	li $v0, 2
+	syscall
+	jr $ra
+`,
+	"print_str": `rt_print_str:
+	lw $a0, 0($sp)
+	li $v0, 4
+	syscall
+	jr $ra
+`,
+	"print_char": `rt_print_char:
+	lw $a0, 0($sp)
+	li $v0, 11
+	syscall
+	jr $ra
+`,
+	"read_int": `rt_read_int:
+	li $v0, 5
+	syscall
+	jr $ra
+`,
+	"read_float": `rt_read_float:
+	li $v0, 6
+	syscall
+	mfc1 $v0, $f0
+	jr $ra
+`,
+	"alloc": `rt_alloc:
+	lw $a0, 0($sp)
+	li $v0, 9
+	syscall
+	jr $ra
+`,
+	"abs": `rt_abs:
+	lw $v0, 0($sp)
+	bgez $v0, rt_abs_end
+	subu $v0, $zero, $v0
+rt_abs_end:
+	jr $ra
+`,
+	"min": `rt_min:
+	lw $v0, 4($sp)
+	lw $t0, 0($sp)
+	ble $v0, $t0, rt_min_end
+	move $v0, $t0
+rt_min_end:
+	jr $ra
+`,
+	"max": `rt_max:
+	lw $v0, 4($sp)
+	lw $t0, 0($sp)
+	bge $v0, $t0, rt_max_end
+	move $v0, $t0
+rt_max_end:
+	jr $ra
+`,
+	// a negative exponent gives 1
+	"pow": `rt_pow:
+	lw $t0, 4($sp)
+	lw $t1, 0($sp)
+	li $v0, 1
+rt_pow_loop:
+	blez $t1, rt_pow_end
+	mul $v0, $v0, $t0
+	addiu $t1, $t1, -1
+	j rt_pow_loop
+rt_pow_end:
+	jr $ra
+`,
+	// the sum of the first n words from the address
+	"sum": `rt_sum:
+	lw $t0, 4($sp)
+	lw $t1, 0($sp)
+	li $v0, 0
+rt_sum_loop:
+	blez $t1, rt_sum_end
+	lw $t2, 0($t0)
+	addu $v0, $v0, $t2
+	addiu $t0, $t0, 4
+	addiu $t1, $t1, -1
+	j rt_sum_loop
+rt_sum_end:
+	jr $ra
+`,
+	// the value at the pointer past the offset, extended as the load does
+	"load1": `rt_load1:
+	lw $t0, 4($sp)
+	lw $t1, 0($sp)
+	addu $t0, $t0, $t1
+	lb $v0, 0($t0)
+	jr $ra
+`,
+	"loadu1": `rt_loadu1:
+	lw $t0, 4($sp)
+	lw $t1, 0($sp)
+	addu $t0, $t0, $t1
+	lbu $v0, 0($t0)
+	jr $ra
+`,
+	"load2": `rt_load2:
+	lw $t0, 4($sp)
+	lw $t1, 0($sp)
+	addu $t0, $t0, $t1
+	lh $v0, 0($t0)
+	jr $ra
+`,
+	"loadu2": `rt_loadu2:
+	lw $t0, 4($sp)
+	lw $t1, 0($sp)
+	addu $t0, $t0, $t1
+	lhu $v0, 0($t0)
+	jr $ra
+`,
+	"load4": `rt_load4:
+	lw $t0, 4($sp)
+	lw $t1, 0($sp)
+	addu $t0, $t0, $t1
+	lw $v0, 0($t0)
+	jr $ra
+`,
+	// the value stored at the pointer past the offset
+	"store1": `rt_store1:
+	lw $t0, 8($sp)
+	lw $t1, 4($sp)
+	addu $t0, $t0, $t1
+	lw $t1, 0($sp)
+	sb $t1, 0($t0)
+	jr $ra
+`,
+	"store2": `rt_store2:
+	lw $t0, 8($sp)
+	lw $t1, 4($sp)
+	addu $t0, $t0, $t1
+	lw $t1, 0($sp)
+	sh $t1, 0($t0)
+	jr $ra
+`,
+	"store4": `rt_store4:
+	lw $t0, 8($sp)
+	lw $t1, 4($sp)
+	addu $t0, $t0, $t1
+	lw $t1, 0($sp)
+	sw $t1, 0($t0)
+	jr $ra
+`,
+	// a new string on the heap holding both
+	"strcat": `rt_strcat:
+	lw $t0, 4($sp)
+	lw $t1, 0($sp)
+	li $a0, 1
+	move $t2, $t0
+rt_strcat_len1:
+	lb $t3, 0($t2)
+	addiu $t2, $t2, 1
+	beqz $t3, rt_strcat_len2_start
+	addiu $a0, $a0, 1
+	j rt_strcat_len1
+rt_strcat_len2_start:
+	move $t2, $t1
+rt_strcat_len2:
+	lb $t3, 0($t2)
+	addiu $t2, $t2, 1
+	beqz $t3, rt_strcat_alloc
+	addiu $a0, $a0, 1
+	j rt_strcat_len2
+rt_strcat_alloc:
+	li $v0, 9
+	syscall
+	move $t2, $v0
+rt_strcat_copy1:
+	lb $t3, 0($t0)
+	beqz $t3, rt_strcat_copy2
+	sb $t3, 0($t2)
+	addiu $t0, $t0, 1
+	addiu $t2, $t2, 1
+	j rt_strcat_copy1
+rt_strcat_copy2:
+	lb $t3, 0($t1)
+	sb $t3, 0($t2)
+	addiu $t1, $t1, 1
+	addiu $t2, $t2, 1
+	bnez $t3, rt_strcat_copy2
+	jr $ra
+`,
+	"streq": `rt_streq:
+	lw $t0, 4($sp)
+	lw $t1, 0($sp)
+rt_streq_loop:
+	lb $t2, 0($t0)
+	lb $t3, 0($t1)
+	bne $t2, $t3, rt_streq_false
+	beqz $t2, rt_streq_true
+	addiu $t0, $t0, 1
+	addiu $t1, $t1, 1
+	j rt_streq_loop
+rt_streq_true:
+	li $v0, 1
+	jr $ra
+rt_streq_false:
+	li $v0, 0
+	jr $ra
+`,
+	// streq on the same arguments, negated
+	"strne": `rt_strne:
+	move $t9, $ra
+	jal rt_streq
+	xori $v0, $v0, 1
+	jr $t9
+`,
+}
+
+// runtimeOrder is the order the functions are written in.
+var runtimeOrder = []string{
+	"print_int", "print_float", "print_str", "print_char", "read_int", "read_float",
+	"alloc", "abs", "min", "max", "pow", "sum", "strcat", "streq", "strne",
+	"load1", "loadu1", "load2", "loadu2", "load4", "store1", "store2", "store4",
+}
@@ -0,0 +1,444 @@
+package codegen
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+	"strings"
+
+	"app/parser/ir"
+)
+
+// tree is an expression of integers the selector covers with instructions:
+// an operand at a leaf, or an operation of the code on its children, minus
+// having one.
+type tree struct {
+	op       string
+	operand  string
+	v        value
+	children []*tree
+}
+
+func (t *tree) isLeaf() bool {
+	return t.op == ""
+}
+
+// imm returns the value of a leaf standing for an immediate.
+func (t *tree) imm() (int64, bool) {
+	if !t.isLeaf() || t.v.memory != "" || t.v.address != "" {
+		return 0, false
+	}
+	return int64(int32(t.v.imm)), true
+}
+
+// reads checks if the tree loads a leaf from the address of memory.
+func (t *tree) reads(memory string) bool {
+	if t.isLeaf() {
+		return t.v.memory == memory
+	}
+	return slices.ContainsFunc(t.children, func(c *tree) bool { return c.reads(memory) })
+}
+
+// machine is what the selector writes instructions through, naming the
+// operations as the templates of a target do: add, sub, mul, div, rem and
+// neg, the relations of setOps and branchOps, and addi, slti, sll and sra
+// with an immediate.
+type machine interface {
+	comment(tac string)
+	registers() []string // those the trees are computed in
+	zero() string        // the register always holding 0, empty if none
+	load(reg string, v value) error
+	store(reg, operand string) error
+	op(op, d, s, t string) error
+	// immediate checks if the machine has the operation with the immediate.
+	immediate(op string, imm int64) bool
+	opImm(op, d, s string, imm int64) error
+	branch(op, s, t, label string) error
+	push(reg string) error
+}
+
+// selector lowers the quadruples by maximal munch over trees of expressions.
+// A temporary written and read once is not stored but folded into the tree
+// of the quadruple reading it, and an operand written as an expression, such
+// as n * 4 + 1, is taken apart into its tree, so that an assignment, a
+// parameter or a conditional jump is the root of a tree the selector covers
+// with the largest instructions matching its top first: an addition or a
+// comparison with an immediate, a multiplication by a power of 2 as a shift,
+// a load at the address of a variable for a leaf and the zero register for 0,
+// the nodes in between computed in registers of their own. The quadruples
+// the trees leave out, the floats, the strings and the calls, go to the
+// generator of the backend as they are.
+type selector struct {
+	g        *generator
+	m        machine
+	fallback func(ir.Quad) error
+
+	uses, defs map[string]int
+	pending    []pendingTree // the trees of the temporaries folded, not read yet
+	free       []string
+}
+
+type pendingTree struct {
+	q    ir.Quad
+	tree *tree
+}
+
+func newSelector(g *generator, m machine, quads []ir.Quad, fallback func(ir.Quad) error) *selector {
+	s := &selector{g: g, m: m, fallback: fallback, uses: map[string]int{}, defs: map[string]int{}}
+	for _, q := range quads {
+		for _, arg := range []string{q.Arg1, q.Arg2} {
+			for _, field := range ir.ExprFields(arg) {
+				if strings.HasPrefix(field, "$(") {
+					s.uses[field]++
+				}
+			}
+		}
+		if q.Op != ir.Label && !q.IsJump() {
+			s.defs[q.Result]++
+		}
+	}
+	return s
+}
+
+// quad lowers the quadruple, or folds it into a later one.
+func (s *selector) quad(q ir.Quad) error {
+	wrap := func(err error) error {
+		if err != nil {
+			return fmt.Errorf("%s: %w", q.TAC(), err)
+		}
+		return nil
+	}
+	switch {
+	case q.Relop() != "":
+		relation, ok := relations[q.Relop()]
+		if !ok {
+			break
+		}
+		a, b, ok := s.leaves(q.Arg1, q.Arg2)
+		if !ok {
+			break
+		}
+		a, b = s.take(a), s.take(b)
+		if err := s.flush(nil); err != nil {
+			return err
+		}
+		s.m.comment(q.TAC())
+		return wrap(s.branch(branchOps[relation], a, b, q.Result))
+	case q.Op == ir.Param:
+		t, ok := s.tree(q.Arg1)
+		if !ok {
+			break
+		}
+		s.m.comment(q.TAC())
+		return wrap(s.root(s.take(t), func(reg string) error { return s.m.push(reg) }))
+	case q.Op == ir.Label || q.Op == ir.Goto || q.Op == ir.Call || q.Op == ir.ICall:
+	default:
+		t, ok := s.assignment(q)
+		if !ok {
+			break
+		}
+		t = s.take(t)
+		if strings.HasPrefix(q.Result, "$(") && s.defs[q.Result] == 1 && s.uses[q.Result] == 1 {
+			s.m.comment(q.TAC())
+			s.pending = append(s.pending, pendingTree{q, t})
+			return nil
+		}
+		dst, _ := s.g.operand(q.Result)
+		if err := s.flush(func(p *tree) bool { return p.reads(dst.memory) }); err != nil {
+			return err
+		}
+		s.m.comment(q.TAC())
+		s.g.setFloat(q.Result, false)
+		return wrap(s.root(t, func(reg string) error { return s.m.store(reg, q.Result) }))
+	}
+	// the generator reads the temporaries and the callee the variables from
+	// memory, and the trees do not go on past a label or a jump
+	if err := s.flush(nil); err != nil {
+		return err
+	}
+	return wrap(s.fallback(q))
+}
+
+// assignment returns the tree of the value the quadruple assigns, if it is
+// one of integers.
+func (s *selector) assignment(q ir.Quad) (*tree, bool) {
+	if q.Result == "" {
+		return nil, false
+	}
+	if dst, err := s.g.operand(q.Result); err != nil || dst.float || dst.memory == "" {
+		return nil, false
+	}
+	switch {
+	case q.Op == ir.Copy:
+		return s.tree(q.Arg1)
+	case q.Op == "minus":
+		a, ok := s.tree(q.Arg1)
+		return &tree{op: q.Op, children: []*tree{a}}, ok
+	case q.Arg2 != "" && (relations[q.Op] != "" || arithmeticOps[q.Op] != ""):
+		a, b, ok := s.leaves(q.Arg1, q.Arg2)
+		return &tree{op: q.Op, children: []*tree{a, b}}, ok
+	}
+	return nil, false
+}
+
+func (s *selector) leaves(a, b string) (*tree, *tree, bool) {
+	ta, ok := s.tree(a)
+	if !ok {
+		return nil, nil, false
+	}
+	tb, ok := s.tree(b)
+	return ta, tb, ok
+}
+
+// tree returns the tree of the operand, parsed if it is an expression, if
+// all its leaves are integers. A temporary pending is left as a leaf, for
+// take to replace once the quadruple is known to be lowered.
+func (s *selector) tree(operand string) (*tree, bool) {
+	e, ok := ir.ParseExpr(operand)
+	if !ok {
+		return nil, false
+	}
+	t := treeOf(e)
+	return t, s.resolve(t)
+}
+
+// treeOf returns the tree of the expression, its leaves to resolve.
+func treeOf(e *ir.Expr) *tree {
+	t := &tree{op: e.Op, operand: e.Operand}
+	for _, c := range e.Children {
+		t.children = append(t.children, treeOf(c))
+	}
+	return t
+}
+
+// resolve looks the leaves of the tree up, failing on the floats and the
+// operands the generator does not know.
+func (s *selector) resolve(t *tree) bool {
+	if !t.isLeaf() {
+		return !slices.ContainsFunc(t.children, func(c *tree) bool { return !s.resolve(c) })
+	}
+	if s.pendingIndex(t.operand) >= 0 {
+		return true
+	}
+	v, err := s.g.operand(t.operand)
+	if err != nil || v.float || v.width > 4 {
+		return false
+	}
+	t.v = v
+	return true
+}
+
+// take replaces the leaves of the tree that are pending temporaries by their
+// trees.
+func (s *selector) take(t *tree) *tree {
+	if !t.isLeaf() {
+		for i, c := range t.children {
+			t.children[i] = s.take(c)
+		}
+		return t
+	}
+	i := s.pendingIndex(t.operand)
+	if i < 0 {
+		return t
+	}
+	p := s.pending[i]
+	s.pending = slices.Delete(s.pending, i, i+1)
+	return p.tree
+}
+
+func (s *selector) pendingIndex(operand string) int {
+	return slices.IndexFunc(s.pending, func(p pendingTree) bool { return p.q.Result == operand })
+}
+
+// flush stores the pending trees matching, all of them if nil, into their
+// temporaries.
+func (s *selector) flush(match func(*tree) bool) error {
+	var kept []pendingTree
+	for _, p := range s.pending {
+		if match != nil && !match(p.tree) {
+			kept = append(kept, p)
+			continue
+		}
+		s.g.setFloat(p.q.Result, false)
+		if err := s.root(p.tree, func(reg string) error { return s.m.store(reg, p.q.Result) }); err != nil {
+			return fmt.Errorf("%s: %w", p.q.TAC(), err)
+		}
+	}
+	s.pending = kept
+	return nil
+}
+
+// root computes the tree into a register and hands it to use.
+func (s *selector) root(t *tree, use func(reg string) error) error {
+	s.free = slices.Clone(s.m.registers())
+	reg, err := s.munch(t)
+	if err != nil {
+		return err
+	}
+	return use(reg)
+}
+
+func (s *selector) branch(op string, a, b *tree, label string) error {
+	s.free = slices.Clone(s.m.registers())
+	ra, err := s.munch(a)
+	if err != nil {
+		return err
+	}
+	rb, err := s.munch(b)
+	if err != nil {
+		return err
+	}
+	return s.m.branch(op, ra, rb, label)
+}
+
+func (s *selector) alloc() (string, error) {
+	if len(s.free) == 0 {
+		return "", fmt.Errorf("the expression needs more than %d registers", len(s.m.registers()))
+	}
+	reg := s.free[0]
+	s.free = s.free[1:]
+	return reg, nil
+}
+
+// release gives the registers back to the pool, but the zero register.
+func (s *selector) release(regs ...string) {
+	for _, reg := range regs {
+		if reg != s.m.zero() && !slices.Contains(s.free, reg) {
+			s.free = append(s.free, reg)
+		}
+	}
+	slices.SortFunc(s.free, func(a, b string) int {
+		return slices.Index(s.m.registers(), a) - slices.Index(s.m.registers(), b)
+	})
+}
+
+// dest returns the register the operation on the registers writes to,
+// reusing the first one that is not the zero register.
+func (s *selector) dest(regs ...string) (string, error) {
+	for i, reg := range regs {
+		if reg != s.m.zero() {
+			s.release(regs[i+1:]...)
+			return reg, nil
+		}
+	}
+	return s.alloc()
+}
+
+// munch covers the tree with instructions, the largest matching its top
+// first, and returns the register holding its value.
+func (s *selector) munch(t *tree) (string, error) {
+	if t.isLeaf() {
+		if imm, ok := t.imm(); ok && imm == 0 && s.m.zero() != "" {
+			return s.m.zero(), nil
+		}
+		reg, err := s.alloc()
+		if err != nil {
+			return "", err
+		}
+		return reg, s.m.load(reg, t.v)
+	}
+	if len(t.children) == 1 {
+		reg, err := s.munch(t.children[0])
+		if err != nil {
+			return "", err
+		}
+		d, err := s.dest(reg)
+		if err != nil {
+			return "", err
+		}
+		if err := s.m.op("neg", d, reg, ""); err != nil {
+			return "", err
+		}
+		return d, extend(s.g, s.m, d)
+	}
+	if reg, ok, err := s.munchImm(t); ok || err != nil {
+		return reg, err
+	}
+	a, err := s.munch(t.children[0])
+	if err != nil {
+		return "", err
+	}
+	b, err := s.munch(t.children[1])
+	if err != nil {
+		return "", err
+	}
+	d, err := s.dest(a, b)
+	if err != nil {
+		return "", err
+	}
+	if relation := relations[t.op]; relation != "" {
+		return d, s.m.op(setOps[relation], d, a, b)
+	}
+	if err := s.m.op(arithmeticOps[t.op], d, a, b); err != nil {
+		return "", err
+	}
+	return d, extend(s.g, s.m, d)
+}
+
+// extend sign-extends the int computed in the register from the bits of the
+// int of the program to the word of the machine, shifting it left and back,
+// so that the arithmetic on an int narrower than the word wraps around it.
+// On a machine without sra the int wraps only when stored.
+func extend(g *generator, m machine, reg string) error {
+	shift := 8*g.maxWidth - g.intBits
+	if shift <= 0 || !m.immediate("sll", int64(shift)) || !m.immediate("sra", int64(shift)) {
+		return nil
+	}
+	if err := m.opImm("sll", reg, reg, int64(shift)); err != nil {
+		return err
+	}
+	return m.opImm("sra", reg, reg, int64(shift))
+}
+
+// munchImm covers the operation and its immediate child with a single
+// instruction: an addition or subtraction of the immediate, a comparison
+// less than it, or a multiplication by a power of 2 as a shift.
+func (s *selector) munchImm(t *tree) (string, bool, error) {
+	left, right := t.children[0], t.children[1]
+	imm, ok := right.imm()
+	op := ""
+	switch t.op {
+	case "+":
+		if !ok {
+			if imm, ok = left.imm(); ok {
+				left = right
+			}
+		}
+		op = "addi"
+	case "-":
+		op, imm = "addi", -imm
+	case "*":
+		if !ok || imm <= 0 || bits.OnesCount64(uint64(imm)) != 1 {
+			if imm, ok = left.imm(); ok {
+				left = right
+			}
+		}
+		if !ok || imm <= 0 || bits.OnesCount64(uint64(imm)) != 1 {
+			return "", false, nil
+		}
+		op, imm = "sll", int64(bits.TrailingZeros64(uint64(imm)))
+	default:
+		if relations[t.op] != "<" {
+			return "", false, nil
+		}
+		op = "slti"
+	}
+	if !ok || !s.m.immediate(op, imm) {
+		return "", false, nil
+	}
+	reg, err := s.munch(left)
+	if err != nil {
+		return "", true, err
+	}
+	d, err := s.dest(reg)
+	if err != nil {
+		return "", true, err
+	}
+	if err := s.m.opImm(op, d, reg, imm); err != nil {
+		return "", true, err
+	}
+	if op == "slti" {
+		return d, true, nil
+	}
+	return d, true, extend(s.g, s.m, d)
+}
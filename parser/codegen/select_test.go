@@ -0,0 +1,63 @@
+package codegen_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser/codegen"
+)
+
+func TestMIPS_Select(t *testing.T) {
+	code := compile(t, `{
+    int[4] a = {1, 2, 3, 4};
+    int n, b, c;
+    n = readint();
+    b = n * 4 + a[2] - 1;
+    c = -n;
+    if (n < 10) { c = 0; }
+}`)
+	for _, expected := range []string{
+		// the expression written in the code is taken apart, the
+		// multiplication by 4 shifted and the 1 subtracted as an immediate
+		"\t# $(0x10000008) = n * 4 + a [ 2 ]\n\t# b = $(0x10000008) - 1\n\tlw $t0, data+16\n\tsll $t0, $t0, 2\n\tlw $t1, data+8\n\taddu $t0, $t0, $t1\n\taddiu $t0, $t0, -1\n\tsw $t0, data+20\n",
+		// the temporary of minus n is folded into the copy
+		"\tlw $t0, data+16\n\tsubu $t0, $zero, $t0\n\tsw $t0, data+24\n",
+		"\tslti $t0, $t0, 10\n",
+		"\tsw $zero, data+24\n",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected %q in the code, got\n%s", expected, code)
+		}
+	}
+}
+
+func TestTarget_Select(t *testing.T) {
+	program := "{ int a, b; a = readint(); b = a * 8 + 2; }"
+	with := strings.Replace(toy, `"mul": "MUL {d}, {t}"`, `"mul": "MUL {d}, {t}", "add": "ADD {d}, {t}", "addi": "ADD {d}, #{imm}", "sll": "SHL {d}, #{imm}"`, 1)
+	with = strings.Replace(with, `"name": "toy"`, `"name": "toy", "syscalls": {"read_int": {"number": 5, "value": true}}`, 1)
+	with = strings.Replace(with, `"return": "A"`, `"return": "A", "syscall": "A"`, 1)
+	with = strings.Replace(with, `"halt": "HLT"`, `"halt": "HLT", "syscall": "SYS", "loadStack": "LD {d}, [SP+{offset}]"`, 1)
+	target, err := ReadTarget(strings.NewReader(with))
+	if err != nil {
+		t.Fatalf("ReadTarget: %v", err)
+	}
+	code, err := generate(t, target, program)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if expected := "\tLD A, [data+0]\n\tSHL A, #3\n\tADD A, #2\n\tST [data+4], A\n"; !strings.Contains(code, expected) {
+		t.Errorf("Expected %q in the code, got\n%s", expected, code)
+	}
+
+	// without the instructions taking an immediate, the immediates are loaded
+	without := strings.Replace(with, `, "addi": "ADD {d}, #{imm}", "sll": "SHL {d}, #{imm}"`, "", 1)
+	if target, err = ReadTarget(strings.NewReader(without)); err != nil {
+		t.Fatalf("ReadTarget: %v", err)
+	}
+	if code, err = generate(t, target, program); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if expected := "\tLD A, [data+0]\n\tSET B, #8\n\tMUL A, B\n\tSET B, #2\n\tADD A, B\n\tST [data+4], A\n"; !strings.Contains(code, expected) {
+		t.Errorf("Expected %q in the code, got\n%s", expected, code)
+	}
+}
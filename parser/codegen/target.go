@@ -0,0 +1,175 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Target describes a machine the quadruples can be lowered to without a
+// backend of its own: its registers, the syscalls the functions of the code
+// call and the templates of its instructions and directives. It is read from
+// a file, so that a toy target is a JSON file rather than Go code.
+type Target struct {
+	Name     string `json:"name"`
+	WordSize int    `json:"wordSize"` // bytes of a register and of a slot of the stack, 4 or 8
+	Comment  string `json:"comment"`  // the start of a comment to the end of the line
+	Entry    string `json:"entry"`    // the label the program starts at, main if empty
+	// Immediate is the bits of the immediates of addi and slti, signed, 16
+	// if 0 as in MIPS, 12 in RISC-V.
+	Immediate int `json:"immediate"`
+
+	Registers  Registers          `json:"registers"`
+	Syscalls   map[string]Syscall `json:"syscalls"` // by the function of the code they are, and exit
+	Directives Directives         `json:"directives"`
+
+	// Instructions are the templates of the instructions by the operation,
+	// one of TargetInstructions, with the placeholders of TargetPlaceholders.
+	// A template may hold several instructions, one per line.
+	Instructions map[string]string `json:"instructions"`
+}
+
+// Registers are the registers of a target the code is written with.
+type Registers struct {
+	Temporaries []string `json:"temporaries"` // the operands are loaded into, two at least
+	Zero        string   `json:"zero"`        // always holds 0, none if empty
+	Stack       string   `json:"stack"`       // the stack pointer, {sp} in the templates
+	Return      string   `json:"return"`      // where a function and a syscall return their value
+	Syscall     string   `json:"syscall"`     // the number of the syscall is loaded into
+	Arguments   []string `json:"arguments"`   // the arguments of a syscall are loaded into, in order
+}
+
+// Syscall is how a target implements a function of the code: the arguments
+// pushed are loaded into the argument registers, the first into the first,
+// and the number into the syscall register.
+type Syscall struct {
+	Number int  `json:"number"`
+	Value  bool `json:"value"` // returns a value in the return register
+}
+
+// Directives are the templates of the directives of the data segment, with
+// {n} a number of bytes, {values} numbers split by commas, {text} a string
+// escaped as in C and {label} a label.
+type Directives struct {
+	Data   string         `json:"data"`
+	Text   string         `json:"text"`
+	Global string         `json:"global"` // exports the entry, none if empty
+	Space  string         `json:"space"`  // reserves {n} bytes of zeros
+	String string         `json:"string"` // a string terminated by a zero, bytes if empty
+	Values map[int]string `json:"values"` // values of each width, 1 and 4 at least
+}
+
+// TargetInstructions are the operations a target gives a template for. The
+// loads and stores are by the width in bytes, loadu for the unsigned ones,
+// the loads if missing, and the relations set a register to 1 if they hold
+// or branch to {label}. addi, slti, sll and sra take an {imm} in place of
+// {t}, the selector falling back on the others without the first three; sll
+// and sra sign-extend an int narrower than the word, see parser.IntWidth,
+// which wraps only when stored without them. A target may leave out those its
+// programs do not need, the code using them failing to compile.
+var TargetInstructions = []string{
+	"load1", "load2", "load4", "load8", "loadu1", "loadu2",
+	"store1", "store2", "store4", "store8",
+	"li", "la", "add", "sub", "mul", "div", "rem", "neg", "addi", "slti", "sll", "sra",
+	"seq", "sne", "slt", "sle", "sgt", "sge", "beq", "bne", "blt", "ble", "bgt", "bge",
+	"jump", "call", "callr", "ret", "push", "free", "loadStack", "syscall", "halt",
+}
+
+// TargetPlaceholders are what the templates may hold: the destination and
+// source registers {d}, {s} and {t}, the address of a variable {addr}, a
+// label {label}, an immediate {imm}, bytes {n}, an offset from the stack
+// pointer {offset}, and the stack pointer {sp}, the return register {ret}
+// and the word size {word} of the target.
+var TargetPlaceholders = []string{"d", "s", "t", "addr", "label", "imm", "n", "offset", "sp", "ret", "word"}
+
+var placeholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// ReadTarget reads a target written in JSON as Target is, checking that it
+// has what every program needs.
+func ReadTarget(r io.Reader) (*Target, error) {
+	var t Target
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&t); err != nil {
+		return nil, fmt.Errorf("invalid target: %v", err)
+	}
+	if t.Name == "" {
+		return nil, fmt.Errorf("invalid target: no name")
+	}
+	if t.Entry == "" {
+		t.Entry = "main"
+	}
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("invalid target %s: %s", t.Name, fmt.Sprintf(format, args...))
+	}
+	if t.WordSize != 4 && t.WordSize != 8 {
+		return nil, invalid("word size %d, 4 or 8 expected", t.WordSize)
+	}
+	if len(t.Registers.Temporaries) < 2 {
+		return nil, invalid("%d temporary registers, 2 at least expected", len(t.Registers.Temporaries))
+	}
+	if t.Registers.Stack == "" || t.Registers.Return == "" {
+		return nil, invalid("no stack pointer or return register")
+	}
+	for _, name := range slices.Sorted(maps.Keys(t.Instructions)) {
+		if !slices.Contains(TargetInstructions, name) {
+			return nil, invalid("unknown instruction %s", name)
+		}
+		for _, m := range placeholder.FindAllStringSubmatch(t.Instructions[name], -1) {
+			if !slices.Contains(TargetPlaceholders, m[1]) {
+				return nil, invalid("unknown placeholder %s in the instruction %s", m[0], name)
+			}
+		}
+	}
+	for _, name := range []string{"load4", "store4", "li", "la", "jump"} {
+		if t.Instructions[name] == "" {
+			return nil, invalid("no instruction %s", name)
+		}
+	}
+	if len(t.Syscalls) > 0 && (t.Registers.Syscall == "" || t.Instructions["syscall"] == "" || t.Instructions["loadStack"] == "") {
+		return nil, invalid("syscalls without a syscall register or the instructions syscall and loadStack")
+	}
+	if _, ok := t.Syscalls["exit"]; !ok && t.Instructions["halt"] == "" {
+		return nil, invalid("no exit syscall or halt instruction to end the program")
+	}
+	d := t.Directives
+	if d.Data == "" || d.Text == "" || d.Space == "" || d.Values[1] == "" || d.Values[4] == "" {
+		return nil, invalid("no directive data, text, space or values of 1 and 4 bytes")
+	}
+	return &t, nil
+}
+
+// expand fills the placeholders of the template, one instruction per line.
+func (t *Target) expand(template string, args map[string]string) string {
+	replace := []string{"{sp}", t.Registers.Stack, "{ret}", t.Registers.Return, "{word}", strconv.Itoa(t.WordSize)}
+	for name, value := range args {
+		replace = append(replace, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(replace...).Replace(template)
+}
+
+// directives returns how the data segment of the target is written.
+func (t *Target) directives() dataFormat {
+	return dataFormat{
+		comment: t.Comment,
+		data:    t.Directives.Data,
+		space:   func(n int) string { return t.expand(t.Directives.Space, map[string]string{"n": strconv.Itoa(n)}) },
+		values: func(width int, values []string) string {
+			if t.Directives.Values[width] == "" {
+				return ""
+			}
+			return t.expand(t.Directives.Values[width], map[string]string{"values": strings.Join(values, ", ")})
+		},
+		str: func(text string) string {
+			if t.Directives.String == "" {
+				return ""
+			}
+			return t.expand(t.Directives.String, map[string]string{"text": text})
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package codegen_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"app/parser"
+	. "app/parser/codegen"
+)
+
+func generate(t *testing.T, target *Target, program string) (string, error) {
+	t.Helper()
+	result, err := parser.Compile(parser.Options{Source: strings.NewReader(program)})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("Expected the program to compile, got %v", result.Diagnostics)
+	}
+	var out strings.Builder
+	err = target.Generate(&out, result.Walker, result.Walker.Quads())
+	return out.String(), err
+}
+
+func TestTarget_Generate(t *testing.T) {
+	f, err := os.Open("targets/mips.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	mips, err := ReadTarget(f)
+	if err != nil {
+		t.Fatalf("ReadTarget: %v", err)
+	}
+	code, err := generate(t, mips, `{
+    int a, b;
+    int[3] c = {1, 2, 3};
+    a = readint();
+    b = a + c[1];
+    b = -b;
+    printf("%d\n", b);
+}`)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for _, expected := range []string{
+		"\t.data\ndata:\n",
+		"\t# c int[3], at 0x10000002\n\t.word 1, 2, 3\n",
+		"\t.text\n\t.globl main\nmain:\n",
+		"\tli $v0, 5\n\tsyscall\n\tsw $v0, data+20\n",
+		"\tlw $t0, data+0\n\tlw $t1, data+12\n\taddu $t0, $t0, $t1\n",
+		"\tsubu $t0, $zero, $t0\n",
+		"\taddiu $sp, $sp, -4\n\tsw $t0, 0($sp)\n\t# call print_int, 1\n\tlw $a0, 0($sp)\n\tli $v0, 1\n\tsyscall\n\taddiu $sp, $sp, 4\n",
+		"\tla $t0, str_0\n",
+		".asciiz \"\\n\"\n",
+		"\tli $v0, 10\n\tsyscall\n",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected %q in the code, got\n%s", expected, code)
+		}
+	}
+
+	if _, err := generate(t, mips, "{ float x; x = 1.5; x = -x; }"); err == nil || !strings.Contains(err.Error(), "the target mips32 has no floats") {
+		t.Errorf("Expected the floats rejected, got %v", err)
+	}
+}
+
+// toy is a target of another syntax, with no syscalls.
+const toy = `{
+  "name": "toy", "wordSize": 8, "comment": ";",
+  "registers": {"temporaries": ["A", "B"], "stack": "SP", "return": "A"},
+  "directives": {"data": "DATA", "text": "CODE", "space": "ZERO {n}", "values": {"1": "BYTES {values}", "4": "WORDS {values}"}},
+  "instructions": {
+    "load4": "LD {d}, [{addr}]", "store4": "ST [{addr}], {s}", "li": "SET {d}, #{imm}", "la": "SET {d}, {label}",
+    "mul": "MUL {d}, {t}", "blt": "CMP {s}, {t}\nJLT {label}", "jump": "JMP {label}", "halt": "HLT"
+  }
+}`
+
+func TestTarget_Toy(t *testing.T) {
+	target, err := ReadTarget(strings.NewReader(toy))
+	if err != nil {
+		t.Fatalf("ReadTarget: %v", err)
+	}
+	code, err := generate(t, target, "{ int a, b; a = 6; b = a * 7; }")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	expected := "\tLD A, [data+0]\n\tSET B, #7\n\tMUL A, B\n\tST [data+4], A\n"
+	if !strings.Contains(code, expected) || !strings.HasPrefix(code, "\tDATA\ndata:\n") || !strings.HasSuffix(code, "\tHLT\n") {
+		t.Errorf("Expected %q in the code, got\n%s", expected, code)
+	}
+
+	if _, err := generate(t, target, "{ int a; a = readint(); }"); err == nil || !strings.Contains(err.Error(), "the target toy has no function read_int") {
+		t.Errorf("Expected read_int unknown to the target, got %v", err)
+	}
+	if _, err := generate(t, target, "{ int a; a = 1 + 2; a = a + 1; }"); err == nil || !strings.Contains(err.Error(), "the target toy has no instruction add") {
+		t.Errorf("Expected add unknown to the target, got %v", err)
+	}
+}
+
+func TestReadTarget(t *testing.T) {
+	for _, test := range []struct {
+		replace, with, expected string
+	}{
+		{`"wordSize": 8`, `"wordSize": 2`, "invalid target toy: word size 2"},
+		{`["A", "B"]`, `["A"]`, "1 temporary registers"},
+		{`"halt"`, `"stop"`, "unknown instruction stop"},
+		{`#{imm}`, `#{value}`, "unknown placeholder {value} in the instruction li"},
+		{`"halt": "HLT"`, `"ret": "RET"`, "no exit syscall or halt instruction"},
+		{`"name": "toy"`, `"name": "toy", "syscalls": {"exit": {"number": 1}}`, "syscalls without a syscall register"},
+		{`"comment"`, `"comments"`, "unknown field"},
+	} {
+		_, err := ReadTarget(strings.NewReader(strings.Replace(toy, test.replace, test.with, 1)))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected an error with %q, got %v", test.expected, err)
+		}
+	}
+}
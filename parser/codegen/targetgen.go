@@ -0,0 +1,321 @@
+package codegen
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"app/parser"
+	"app/parser/ir"
+)
+
+// Generate writes the quadruples as a program of the target, laid out as
+// MIPS lays it out: the variables and temporaries at the addresses the symbol
+// table gave them from data, which the instructions load into the temporary
+// registers and store back to, the expressions lowered by the selector with
+// the instructions taking an immediate the target has, and the arguments of
+// a call pushed on the stack. The functions of the code are the syscalls of the target and
+// the functions of the symbol table; the target has no runtime functions and
+// no floats.
+func (t *Target) Generate(out io.Writer, w *parser.Walker, quads []ir.Quad) error {
+	g := &targetGenerator{generator: newGenerator(t.Name, t.WordSize, t.directives()), t: t}
+	if err := g.declare(w); err != nil {
+		return err
+	}
+	s := newSelector(g.generator, targetMachine{g}, quads, g.quad)
+	for _, q := range quads {
+		if err := s.quad(q); err != nil {
+			return err
+		}
+	}
+	if err := s.flush(nil); err != nil {
+		return err
+	}
+	for _, name := range runtimeOrder {
+		if g.runtime[name] {
+			return fmt.Errorf("the target %s has no function %s", t.Name, name)
+		}
+	}
+	if exit, ok := t.Syscalls["exit"]; ok {
+		if err := g.syscall(exit, 0); err != nil {
+			return err
+		}
+	} else if err := g.instr("halt", nil); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := g.data(&b); err != nil {
+		return err
+	}
+	fmt.Fprintf(&b, "\n\t%s\n", t.Directives.Text)
+	if t.Directives.Global != "" {
+		fmt.Fprintf(&b, "\t%s\n", t.expand(t.Directives.Global, map[string]string{"label": t.Entry}))
+	}
+	fmt.Fprintf(&b, "%s:\n", t.Entry)
+	b.Write(g.text.Bytes())
+	_, err := out.Write(b.Bytes())
+	return err
+}
+
+type targetGenerator struct {
+	*generator
+	t *Target
+}
+
+// targetMachine writes the instructions the selector chooses with the
+// templates of the target.
+type targetMachine struct {
+	g *targetGenerator
+}
+
+func (m targetMachine) comment(tac string) {
+	fmt.Fprintf(&m.g.text, "\t%s %s\n", m.g.t.Comment, tac)
+}
+
+func (m targetMachine) registers() []string {
+	return m.g.t.Registers.Temporaries
+}
+
+func (m targetMachine) zero() string {
+	return m.g.t.Registers.Zero
+}
+
+func (m targetMachine) load(reg string, v value) error {
+	return m.g.loadValue(reg, v)
+}
+
+func (m targetMachine) store(reg, operand string) error {
+	return m.g.store(reg, operand)
+}
+
+func (m targetMachine) op(op, d, s, t string) error {
+	return m.g.instr(op, map[string]string{"d": d, "s": s, "t": t})
+}
+
+func (m targetMachine) immediate(op string, imm int64) bool {
+	if m.g.t.Instructions[op] == "" {
+		return false
+	}
+	if op == "sll" || op == "sra" {
+		return imm < int64(8*m.g.t.WordSize)
+	}
+	bits := cmp.Or(m.g.t.Immediate, 16)
+	return imm >= -1<<(bits-1) && imm < 1<<(bits-1)
+}
+
+func (m targetMachine) opImm(op, d, s string, imm int64) error {
+	return m.g.instr(op, map[string]string{"d": d, "s": s, "imm": strconv.FormatInt(imm, 10)})
+}
+
+func (m targetMachine) branch(op, s, t, label string) error {
+	return m.g.instr(op, map[string]string{"s": s, "t": t, "label": label})
+}
+
+func (m targetMachine) push(reg string) error {
+	return m.g.instr("push", map[string]string{"s": reg})
+}
+
+// instr writes the instruction of the operation with the placeholders.
+func (g *targetGenerator) instr(op string, args map[string]string) error {
+	template := g.t.Instructions[op]
+	if template == "" {
+		return fmt.Errorf("the target %s has no instruction %s", g.t.Name, op)
+	}
+	for _, line := range strings.Split(g.t.expand(template, args), "\n") {
+		fmt.Fprintf(&g.text, "\t%s\n", line)
+	}
+	return nil
+}
+
+// branchOps and setOps are the operations jumping on and computing a
+// relation.
+var (
+	branchOps = map[string]string{"<": "blt", "<=": "ble", ">": "bgt", ">=": "bge", "==": "beq", "!=": "bne"}
+	setOps    = map[string]string{"<": "slt", "<=": "sle", ">": "sgt", ">=": "sge", "==": "seq", "!=": "sne"}
+)
+
+// arithmeticOps are the operations of the arithmetic on integers.
+var arithmeticOps = map[string]string{"+": "add", "-": "sub", "*": "mul", "/": "div", "%": "rem", "mod": "rem"}
+
+func (g *targetGenerator) quad(q ir.Quad) error {
+	if q.Op == ir.Label {
+		fmt.Fprintf(&g.text, "%s:\n", q.Result)
+		return nil
+	}
+	fmt.Fprintf(&g.text, "\t%s %s\n", g.t.Comment, q.TAC())
+	for _, operand := range []string{q.Arg1, q.Arg2} {
+		if g.isFloat(operand) {
+			return fmt.Errorf("the target %s has no floats", g.t.Name)
+		}
+	}
+	t0, t1 := g.t.Registers.Temporaries[0], g.t.Registers.Temporaries[1]
+	switch {
+	case q.Op == ir.Goto:
+		return g.instr("jump", map[string]string{"label": q.Result})
+	case q.Relop() != "":
+		relation, ok := relations[q.Relop()]
+		if !ok {
+			return fmt.Errorf("unsupported relation %s", q.Relop())
+		}
+		if err := g.loadInts(q.Arg1, q.Arg2); err != nil {
+			return err
+		}
+		return g.instr(branchOps[relation], map[string]string{"s": t0, "t": t1, "label": q.Result})
+	case q.Op == ir.Param:
+		if err := g.load(t0, q.Arg1); err != nil {
+			return err
+		}
+		return g.instr("push", map[string]string{"s": t0})
+	case q.Op == ir.Call || q.Op == ir.ICall:
+		return g.call(q)
+	case q.Op == "ret":
+		if q.Arg1 != "" {
+			if err := g.load(g.t.Registers.Return, q.Arg1); err != nil {
+				return err
+			}
+		}
+		return g.instr("ret", nil)
+	case q.Op == ir.Copy:
+		if err := g.load(t0, q.Arg1); err != nil {
+			return err
+		}
+		return g.store(t0, q.Result)
+	case q.Op == "minus":
+		if err := g.load(t0, q.Arg1); err != nil {
+			return err
+		}
+		if err := g.instr("neg", map[string]string{"d": t0, "s": t0}); err != nil {
+			return err
+		}
+		if err := extend(g.generator, targetMachine{g}, t0); err != nil {
+			return err
+		}
+		return g.store(t0, q.Result)
+	case relations[q.Op] != "" && q.Arg2 != "":
+		if err := g.loadInts(q.Arg1, q.Arg2); err != nil {
+			return err
+		}
+		if err := g.instr(setOps[relations[q.Op]], map[string]string{"d": t0, "s": t0, "t": t1}); err != nil {
+			return err
+		}
+		return g.store(t0, q.Result)
+	case arithmeticOps[q.Op] != "" && q.Arg2 != "":
+		if err := g.loadInts(q.Arg1, q.Arg2); err != nil {
+			return err
+		}
+		if err := g.instr(arithmeticOps[q.Op], map[string]string{"d": t0, "s": t0, "t": t1}); err != nil {
+			return err
+		}
+		if err := extend(g.generator, targetMachine{g}, t0); err != nil {
+			return err
+		}
+		return g.store(t0, q.Result)
+	}
+	return fmt.Errorf("unsupported operation %s", q.Op)
+}
+
+// call calls the function, a syscall of the target if it has one for it,
+// pops its arguments and stores its value.
+func (g *targetGenerator) call(q ir.Quad) error {
+	n, err := strconv.Atoi(q.Arg2)
+	if err != nil {
+		return fmt.Errorf("invalid number of parameters %s", q.Arg2)
+	}
+	syscall, isSyscall := g.t.Syscalls[q.Arg1]
+	function, isFunction := g.functions[q.Arg1]
+	switch {
+	case q.Op == ir.ICall:
+		if err := g.load(g.t.Registers.Temporaries[0], q.Arg1); err != nil {
+			return err
+		}
+		err = g.instr("callr", map[string]string{"s": g.t.Registers.Temporaries[0]})
+	case isFunction:
+		err = g.instr("call", map[string]string{"label": function.Label})
+	case isSyscall:
+		if q.Result != "" && !syscall.Value {
+			return fmt.Errorf("the syscall %s of the target %s has no value", q.Arg1, g.t.Name)
+		}
+		err = g.syscall(syscall, n)
+	default:
+		return fmt.Errorf("the target %s has no function %s", g.t.Name, q.Arg1)
+	}
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		if err := g.instr("free", map[string]string{"n": strconv.Itoa(n * g.t.WordSize)}); err != nil {
+			return err
+		}
+	}
+	if q.Result == "" {
+		return nil
+	}
+	g.setFloat(q.Result, false)
+	return g.store(g.t.Registers.Return, q.Result)
+}
+
+// syscall loads the n arguments on the stack, the last one at the top, into
+// the argument registers and the number into the syscall register.
+func (g *targetGenerator) syscall(s Syscall, n int) error {
+	arguments := g.t.Registers.Arguments
+	if n > len(arguments) {
+		return fmt.Errorf("%d arguments, the target %s passes %d to a syscall", n, g.t.Name, len(arguments))
+	}
+	for i := 0; i < n; i++ {
+		offset := strconv.Itoa((n - 1 - i) * g.t.WordSize)
+		if err := g.instr("loadStack", map[string]string{"d": arguments[i], "offset": offset}); err != nil {
+			return err
+		}
+	}
+	if err := g.instr("li", map[string]string{"d": g.t.Registers.Syscall, "imm": strconv.Itoa(s.Number)}); err != nil {
+		return err
+	}
+	return g.instr("syscall", nil)
+}
+
+func (g *targetGenerator) loadInts(a, b string) error {
+	if err := g.load(g.t.Registers.Temporaries[0], a); err != nil {
+		return err
+	}
+	return g.load(g.t.Registers.Temporaries[1], b)
+}
+
+func (g *targetGenerator) load(reg, operand string) error {
+	v, err := g.operand(operand)
+	if err != nil {
+		return err
+	}
+	return g.loadValue(reg, v)
+}
+
+func (g *targetGenerator) loadValue(reg string, v value) error {
+	switch {
+	case v.memory != "":
+		op := fmt.Sprintf("load%d", v.width)
+		if v.unsigned && g.t.Instructions["loadu"+op[4:]] != "" {
+			op = "loadu" + op[4:]
+		}
+		return g.instr(op, map[string]string{"d": reg, "addr": v.memory})
+	case v.address != "":
+		return g.instr("la", map[string]string{"d": reg, "label": v.address})
+	}
+	imm := v.imm
+	if g.t.WordSize == 4 {
+		imm = int64(int32(imm))
+	}
+	return g.instr("li", map[string]string{"d": reg, "imm": strconv.FormatInt(imm, 10)})
+}
+
+func (g *targetGenerator) store(reg, operand string) error {
+	v, err := g.operand(operand)
+	if err != nil {
+		return err
+	}
+	if v.memory == "" {
+		return fmt.Errorf("cannot assign to %s", operand)
+	}
+	return g.instr(fmt.Sprintf("store%d", v.width), map[string]string{"s": reg, "addr": v.memory})
+}
@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"app/utils/report"
+)
+
+// WriteJSON serializes the table to the writer, so that it can be compared
+// with the table of another version of the grammar later on.
+func (t *LRTable) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t)
+}
+
+// ReadTable reads a table serialized by WriteJSON.
+func ReadTable(r io.Reader) (*LRTable, error) {
+	t := &LRTable{}
+	if err := json.NewDecoder(r).Decode(t); err != nil {
+		return nil, fmt.Errorf("invalid table: %w", err)
+	}
+	if t.ActionTable == nil {
+		t.ActionTable = make(ActionTable)
+	}
+	if t.GotoTable == nil {
+		t.GotoTable = make(GotoTable)
+	}
+	return t, nil
+}
+
+// CellDiff is a cell of the action or goto table that differs between two
+// tables, an empty side means the cell is missing from that table.
+type CellDiff struct {
+	State  int
+	Symbol Symbol
+	Before string
+	After  string
+}
+
+// TableDiff is the difference between two tables, such as the ones built
+// before and after a change to the grammar.
+type TableDiff struct {
+	AddedStates   []int
+	RemovedStates []int
+	Cells         []CellDiff // cells of the states in both tables
+
+	ShiftReduceConflicts  [2]int // before and after
+	ReduceReduceConflicts [2]int
+}
+
+// Empty checks if the tables are the same.
+func (d *TableDiff) Empty() bool {
+	return len(d.AddedStates) == 0 && len(d.RemovedStates) == 0 && len(d.Cells) == 0 &&
+		d.ShiftReduceConflicts[0] == d.ShiftReduceConflicts[1] &&
+		d.ReduceReduceConflicts[0] == d.ReduceReduceConflicts[1]
+}
+
+// CompareTables compares the cells of the states the two tables share, and
+// reports the states only one of them has. States are matched by their index.
+func CompareTables(before, after *LRTable) *TableDiff {
+	d := &TableDiff{
+		ShiftReduceConflicts:  [2]int{before.ShiftReduceConflicts, after.ShiftReduceConflicts},
+		ReduceReduceConflicts: [2]int{before.ReduceReduceConflicts, after.ReduceReduceConflicts},
+	}
+	a, b := before.cells(), after.cells()
+	for _, state := range slices.Sorted(maps.Keys(b)) {
+		if _, ok := a[state]; !ok {
+			d.AddedStates = append(d.AddedStates, state)
+		}
+	}
+	for _, state := range slices.Sorted(maps.Keys(a)) {
+		if _, ok := b[state]; !ok {
+			d.RemovedStates = append(d.RemovedStates, state)
+			continue
+		}
+		symbols := slices.Collect(maps.Keys(a[state]))
+		for symbol := range b[state] {
+			if _, ok := a[state][symbol]; !ok {
+				symbols = append(symbols, symbol)
+			}
+		}
+		report.SortSymbols(symbols)
+		for _, symbol := range symbols {
+			if a[state][symbol] != b[state][symbol] {
+				d.Cells = append(d.Cells, CellDiff{State: state, Symbol: symbol, Before: a[state][symbol], After: b[state][symbol]})
+			}
+		}
+	}
+	return d
+}
+
+// cells returns the action and goto cells of each state, written as s3, r12,
+// acc or 7 for a goto.
+func (t *LRTable) cells() map[int]map[Symbol]string {
+	cells := map[int]map[Symbol]string{}
+	state := func(index int) map[Symbol]string {
+		if cells[index] == nil {
+			cells[index] = map[Symbol]string{}
+		}
+		return cells[index]
+	}
+	for index, actions := range t.ActionTable {
+		row := state(index)
+		for terminal, action := range actions {
+			row[Symbol(terminal)] = actionCell(action)
+		}
+	}
+	for index, gotos := range t.GotoTable {
+		row := state(index)
+		for symbol, next := range gotos {
+			row[symbol] = fmt.Sprint(next)
+		}
+	}
+	return cells
+}
+
+// actionCell writes the action the way a cell of the table shows it.
+func actionCell(a Action) string {
+	switch a.Type {
+	case SHIFT:
+		return fmt.Sprintf("s%d", a.Number)
+	case REDUCE:
+		return fmt.Sprintf("r%d", a.Number)
+	case ACCEPT:
+		return "acc"
+	}
+	return fmt.Sprintf("%s %d", a.Type, a.Number)
+}
+
+// Write writes the report of the difference to the writer.
+func (d *TableDiff) Write(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	if d.Empty() {
+		printf("The tables are the same.\n")
+		return err
+	}
+	printf("Shift/reduce conflicts: %d -> %d\n", d.ShiftReduceConflicts[0], d.ShiftReduceConflicts[1])
+	printf("Reduce/reduce conflicts: %d -> %d\n", d.ReduceReduceConflicts[0], d.ReduceReduceConflicts[1])
+	if len(d.AddedStates) > 0 {
+		printf("New states: %v\n", d.AddedStates)
+	}
+	if len(d.RemovedStates) > 0 {
+		printf("Removed states: %v\n", d.RemovedStates)
+	}
+	if len(d.Cells) > 0 {
+		printf("Differing cells: %d\n", len(d.Cells))
+	}
+	for _, cell := range d.Cells {
+		before, after := cell.Before, cell.After
+		if before == "" {
+			before = "-"
+		}
+		if after == "" {
+			after = "-"
+		}
+		printf("    [%d, %s] %s -> %s\n", cell.State, cell.Symbol, before, after)
+	}
+	return err
+}
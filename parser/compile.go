@@ -0,0 +1,312 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"app/diagnostics"
+	"app/lexer"
+	"app/parser/ast"
+	"app/preprocess"
+	"app/utils/charset"
+)
+
+// Options are what Compile compiles and how.
+type Options struct {
+	Source   io.Reader     // the program
+	Tables   *ParserTables // the tables to parse with, the ones of the default grammar if nil
+	Timeout  time.Duration // time limit of the parse, none if 0
+	Trace    bool          // records the steps of the parse
+	Profile  bool          // counts the reductions and the states of the parse
+	RegAlloc string        // register allocator of the code, linear if empty
+	Log      func(string)  // receives every message of the parse, as the logger of Parse does
+
+	PruneScopes bool               // drops the nested scopes once exited, see Walker.PruneScopes
+	Archive     func(*Scope) error // receives the scopes dropped
+	Lifetimes   bool               // marks the lifetimes of the locals in the code, see Walker.MarkLifetimes
+
+	Hooks []SemanticAction // run after every reduction, see Walker.OnReduce
+
+	// Declare registers the functions the program may call besides the
+	// intrinsic ones, see SymbolTable.RegisterFunction, in the prelude scope.
+	Declare func(*SymbolTable) error
+
+	// Lexer is the automaton of the rules of the tokens the source is lexed
+	// with, see lexer.CompileRules, the hand-written lexer if nil.
+	Lexer *lexer.DFA
+
+	// LineMap takes the positions of the source, the text the preprocessor
+	// expanded, back to the files it read, see preprocess.Expand, for the
+	// diagnostics, the tokens, the trees, the spans and the symbol table to
+	// refer to them. The snippets are then those of the files.
+	LineMap *preprocess.LineMap
+
+	// Budget bounds what the program may use, the tokens, the instructions
+	// and the bytes of the source, for programs from anyone; a program
+	// beyond it fails with a fatal "budget exceeded" error. The budget of
+	// the tables if nil.
+	Budget *Budget
+
+	// IntWidth is the width of int the program is compiled for, see
+	// IntWidth, 32 bits if zero.
+	IntWidth IntWidth
+
+	// Diagnostics receives the diagnostics as they are reported, besides
+	// Result.Diagnostics, with the snippets of the source once read, a new
+	// collector if nil.
+	Diagnostics *diagnostics.Collector
+}
+
+// Diagnostic is an error or a warning reported while compiling, with its
+// position and snippet, see diagnostics.Diagnostic. An error is fatal when it
+// stops the compilation, as syntax errors, unreadable input and the limits
+// do, the errors of the rules are recoverable.
+type Diagnostic struct {
+	diagnostics.Diagnostic
+	Fix *FixIt // the token to insert to go on past a syntax error, if one does
+}
+
+// The categories of the diagnostics. The errors of the rules and the warnings
+// are semantic ones, unreadable input, timeouts and the limits but
+// Limits.MaxErrors are internal ones.
+const (
+	LexicalError  = diagnostics.Lexical
+	SyntaxError   = diagnostics.Syntax
+	SemanticError = diagnostics.Semantic
+	InternalError = diagnostics.Internal
+)
+
+// TableStats is the size of the table a program is parsed with.
+type TableStats struct {
+	States                int
+	Productions           int
+	ActionEntries         int
+	GotoEntries           int
+	ShiftReduceConflicts  int
+	ReduceReduceConflicts int
+}
+
+// Stats returns the size of the table.
+func (t *ParserTables) Stats() TableStats {
+	stats := TableStats{
+		Productions:           len(t.Grammar.Productions),
+		ShiftReduceConflicts:  t.Table.ShiftReduceConflicts,
+		ReduceReduceConflicts: t.Table.ReduceReduceConflicts,
+	}
+	states := map[int]bool{}
+	for state, actions := range t.Table.ActionTable {
+		states[state] = true
+		stats.ActionEntries += len(actions)
+	}
+	for state, gotos := range t.Table.GotoTable {
+		states[state] = true
+		stats.GotoEntries += len(gotos)
+	}
+	stats.States = len(states)
+	return stats
+}
+
+// Result is everything compiling a program produces, for the command line,
+// the tests and any other front end to present as they see fit.
+type Result struct {
+	Tokens      []lexer.Token // the tokens read, in order
+	AST         *ASTNode      // the tree of the program, nil unless the parse completed
+	Program     *ast.Program  // the same as the nodes of package ast, nil unless the parse completed
+	Stats       TableStats
+	Walker      *Walker  // the session, with the symbol table and the code as generated
+	TAC         []string // the code optimized, in registers and laid out in the frame
+	Lines       []int64  // the source line of each instruction of TAC
+	Origins     []int64  // the instruction of Walker.ThreeAddress each one of TAC comes from, -1 for the frame
+	Frame       *Frame
+	Trace       *Trace   // the steps of the parse, if recorded
+	Profile     *Profile // the counts of the parse, if profiled
+	Diagnostics []Diagnostic
+	Collector   *diagnostics.Collector // the one of Options.Diagnostics
+	Encoding    charset.Encoding       // the encoding the source was detected in, as far as it was read
+	CRLF        bool                   // whether the lines of the source ended with \r\n or \r
+}
+
+// Failed checks if an error was reported.
+func (r *Result) Failed() bool {
+	for _, d := range r.Diagnostics {
+		if d.Severity == "Error" {
+			return true
+		}
+	}
+	return false
+}
+
+// Fatal returns the fatal error the compilation stopped at, if any.
+func (r *Result) Fatal() (Diagnostic, bool) {
+	for _, d := range r.Diagnostics {
+		if d.Fatal {
+			return d, true
+		}
+	}
+	return Diagnostic{}, false
+}
+
+// allocator returns the register allocator of the name, linear if empty.
+func allocator(name string) (Allocator, error) {
+	if name == "" {
+		name = "linear"
+	}
+	allocate, ok := Allocators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown register allocator %q, expected linear or color", name)
+	}
+	return allocate, nil
+}
+
+// CompileTAC optimizes the three-address code of the walker, allocates the
+// registers with the allocator of the name and lays out the stack frame of
+// the program. Constants are propagated, common subexpressions eliminated and
+// instructions simplified first, the integers folded in the width of int of
+// the walker, which may fold some of the jumps. The
+// markers of the lifetimes of the locals are dropped, those never alive at
+// the same time sharing a slot of the frame. The source line of each
+// instruction of the code is returned alongside it.
+func CompileTAC(walker *Walker, regalloc string) ([]string, []int64, *Frame, error) {
+	code, origins, frame, err := compileTAC(walker, regalloc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return code, frame.MapLines(remap(walker.Lines, origins, -1)), frame, nil
+}
+
+// compileTAC is CompileTAC returning, instead of the lines, the instruction
+// of the three-address code of the walker each instruction comes from, for
+// the code the frame wraps.
+func compileTAC(walker *Walker, regalloc string) ([]string, []int64, *Frame, error) {
+	allocate, err := allocator(regalloc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var lifetimes Lifetimes
+	if walker.lifetimes != nil {
+		lifetimes = LifetimesOf(walker.ThreeAddress)
+	}
+	code, origins := RunPasses(walker.ThreeAddress, Origins(len(walker.ThreeAddress)),
+		DropLifetimes, PropagateConstants, walker.IntWidth.EliminateCommonSubexpressions, walker.IntWidth.Peephole, ThreadJumps)
+	allocation := allocate(code, Registers)
+	frame := NewSharedFrame("main", walker.Locals(), code, allocation, lifetimes)
+	return frame.Apply(allocation.Apply(code)), origins, frame, nil
+}
+
+// Compile parses the program and compiles its code, the way the parser
+// target of the command line does. Errors in the program are diagnostics of
+// the result, the error is for options that cannot be honored. After a fatal
+// error the code is not compiled, the result has no TAC nor Frame.
+func Compile(opts Options) (*Result, error) {
+	if _, err := allocator(opts.RegAlloc); err != nil {
+		return nil, err
+	}
+	if !opts.IntWidth.Valid() {
+		return nil, fmt.Errorf("invalid int width %d, expected 16, 32 or 64", opts.IntWidth)
+	}
+	tables := opts.Tables
+	if tables == nil {
+		tables = defaultTables()
+	}
+
+	result := &Result{Stats: tables.Stats()}
+	if opts.Trace {
+		result.Trace = &Trace{}
+	}
+	result.Collector = opts.Diagnostics
+	if result.Collector == nil {
+		result.Collector = diagnostics.NewCollector("")
+	}
+	reported := result.Collector.Len()
+	walker := tables.NewSession()
+	completed := false
+	report := func(message string, fatal bool) {
+		if opts.Log != nil {
+			opts.Log(message)
+		}
+		for _, severity := range []string{diagnostics.Error, diagnostics.Warning} {
+			if text, ok := strings.CutPrefix(message, severity+": "); ok {
+				d := Diagnostic{Diagnostic: diagnostics.New(severity, SemanticError, strings.TrimSpace(text))}
+				if fatal && severity == diagnostics.Error {
+					d.Fatal, d.Category, d.Fix = true, walker.stopped, walker.fixIt
+				}
+				result.Diagnostics = append(result.Diagnostics, d)
+				result.Collector.Add(d.Diagnostic)
+			}
+		}
+		completed = completed || message == "Parsing completed successfully."
+	}
+	// the errors of the parse stop it, those of the rules do not
+	logger := func(message string) { report(message, true) }
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	walker.tokens = &result.Tokens
+	if opts.PruneScopes {
+		walker.PruneScopes(opts.Archive)
+	}
+	if opts.Lifetimes {
+		walker.MarkLifetimes()
+	}
+	for _, hook := range opts.Hooks {
+		walker.OnReduce(hook)
+	}
+	walker.declare = opts.Declare
+	walker.IntWidth = opts.IntWidth
+	if opts.Profile {
+		result.Profile = NewProfile()
+		walker.profile = result.Profile
+	}
+	walker.ruleErrors = func(err error) {
+		report(fmt.Sprintf("Error: %v\n", err), false)
+	}
+	if opts.Budget != nil {
+		walker.budget = *opts.Budget
+	}
+	input := opts.Source
+	if walker.budget.MaxMemory > 0 {
+		walker.source = &budgetReader{r: input, max: walker.budget.MaxMemory}
+		input = walker.source
+	}
+	// the source is lexed in UTF-8 with \n line endings whatever it was
+	// written in, and kept as such for the snippets of the diagnostics
+	converted := charset.NewReader(input)
+	var source bytes.Buffer
+	l := lexer.NewLexer(io.TeeReader(converted, &source))
+	if opts.Lexer != nil {
+		l = lexer.NewDFALexer(io.TeeReader(converted, &source), opts.Lexer)
+	}
+	_, _ = tables.run(ctx, walker, l, logger, result.Trace)
+	result.Encoding, result.CRLF = converted.Encoding(), converted.CRLF()
+	result.Collector.SetSource(source.String())
+	for i := range result.Diagnostics {
+		result.Diagnostics[i].Snippet = result.Collector.Snippet(result.Diagnostics[i].Line)
+	}
+	result.Walker = walker
+	if root, ok := walker.Tokens.Peek(); ok && completed {
+		result.AST = root
+		result.Program = walker.Program
+	}
+	if opts.LineMap != nil {
+		result.unexpand(opts.LineMap, reported)
+	}
+	if _, fatal := result.Fatal(); fatal {
+		return result, nil
+	}
+	code, origins, frame, err := compileTAC(walker, opts.RegAlloc)
+	if err != nil {
+		return result, err
+	}
+	result.TAC, result.Frame = code, frame
+	result.Lines = frame.MapLines(remap(walker.Lines, origins, -1))
+	result.Origins = frame.MapOrigins(origins)
+	return result, nil
+}
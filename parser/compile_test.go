@@ -0,0 +1,162 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"app/diagnostics"
+	"app/lexer"
+	. "app/parser"
+	"app/utils/charset"
+)
+
+func TestCompile(t *testing.T) {
+	tables := sharedParser().Tables()
+	var logs []string
+	result, err := Compile(Options{
+		Source: strings.NewReader("{\n    int a, b;\n    {\n        int c;\n        b = c + 1;\n    }\n    a = b * 2;\n}\n"),
+		Tables: tables,
+		Trace:  true,
+		Log:    func(message string) { logs = append(logs, message) },
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if result.Failed() || result.AST == nil {
+		t.Fatalf("Expected the program to compile, got %v", result.Diagnostics)
+	}
+	if len(result.Tokens) != 24 || result.Tokens[0].Val != "{" || result.Tokens[1].Type != lexer.TYPE {
+		t.Errorf("Expected the 24 tokens of the program, got %v", result.Tokens)
+	}
+	if !slices.ContainsFunc(result.Diagnostics, func(d Diagnostic) bool {
+		return d.Severity == "Warning" && strings.HasPrefix(d.Message, "c may be used before initialization")
+	}) {
+		t.Errorf("Expected a warning about c, got %v", result.Diagnostics)
+	}
+	if stats := result.Stats; stats.States == 0 || stats.ActionEntries == 0 || stats.GotoEntries == 0 ||
+		stats.ShiftReduceConflicts != tables.Table.ShiftReduceConflicts || stats.Productions != len(tables.Grammar.Productions) {
+		t.Errorf("Unexpected table stats %+v", stats)
+	}
+	if len(result.TAC) == 0 || len(result.Lines) != len(result.TAC) || result.Frame == nil || result.TAC[0] != "main:" {
+		t.Errorf("Expected the code laid out in the frame, got\n%s", strings.Join(result.TAC, "\n"))
+	}
+	if result.Trace == nil || len(result.Trace.Steps) == 0 || logs[len(logs)-1] != "Parsing completed successfully." {
+		t.Errorf("Expected the trace and the messages of the parse")
+	}
+
+	result, err = Compile(Options{Source: strings.NewReader("{\n    int a;\n    a = ;\n}\n"), Tables: tables})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if !result.Failed() || result.AST != nil {
+		t.Errorf("Expected the syntax error to be reported, got %v", result.Diagnostics)
+	}
+
+	if _, err := Compile(Options{Source: strings.NewReader("{}"), Tables: tables, RegAlloc: "none"}); err == nil {
+		t.Errorf("Expected an unknown allocator to be an error")
+	}
+}
+
+func TestCompile_MaxErrors(t *testing.T) {
+	tables := sharedParser().Tables()
+	src := "{\n    const int a;\n    const int b;\n    const int c;\n}\n"
+	result, err := Compile(Options{Source: strings.NewReader(src), Tables: tables})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if summary := result.Summary(); summary.Fatal || summary.Errors != 3 || !summary.Completed || result.TAC == nil {
+		t.Errorf("Expected the errors of the rules to be recoverable, got %+v", summary)
+	}
+
+	tables.Limits.MaxErrors = 2
+	result, err = Compile(Options{Source: strings.NewReader(src), Tables: tables})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	fatal, ok := result.Fatal()
+	if !ok || !strings.HasPrefix(fatal.Message, ErrTooManyErrors.Error()+": 2 errors reported, at line 2") {
+		t.Errorf("Expected the parse to stop after 2 errors, got %v", result.Diagnostics)
+	}
+	if summary := result.Summary(); summary.Errors != 3 || summary.Completed || result.TAC != nil || result.Frame != nil {
+		t.Errorf("Expected no code after the fatal error, got %+v", summary)
+	}
+
+	result, err = Compile(Options{Source: strings.NewReader("{\n    int a;\n    a = ;\n}\n"), Tables: tables})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if fatal, ok := result.Fatal(); !ok || !strings.Contains(fatal.Message, "no action found") {
+		t.Errorf("Expected the syntax error to be fatal, got %v", result.Diagnostics)
+	}
+}
+
+func TestCompile_Literals(t *testing.T) {
+	src := "{\n    int c;\n    float r;\n    c = 'A' + '\\n';\n    r = 1.5e2; /* /* nested */ */\n}\n"
+	result, err := Compile(Options{Source: strings.NewReader(src), Tables: sharedParser().Tables()})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("Expected the literals to compile, got %v", result.Diagnostics)
+	}
+	// 'A' + '\n' folded
+	code := strings.Join(result.TAC, "\n")
+	if !strings.Contains(code, "c = 75\n") || !strings.Contains(code, "r = 1.5e2\n") {
+		t.Errorf("Expected the chars as their codes and the float as written, got\n%s", code)
+	}
+}
+
+func TestCompile_Diagnostics(t *testing.T) {
+	collector := diagnostics.NewCollector("")
+	result, err := Compile(Options{
+		Source:      strings.NewReader("{\n    int a;\n    string s;\n    a = s % 2;\n    int a;\n}\n"),
+		Tables:      sharedParser().Tables(),
+		Diagnostics: collector,
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if result.Collector != collector || len(collector.All()) != len(result.Diagnostics) {
+		t.Fatalf("Expected the diagnostics collected, got %v", collector.All())
+	}
+	sorted := collector.Sorted()
+	// the rule names no position, the walker adds the one of the node it left
+	if d := sorted[0]; d.Line != 3 || d.Pos != 9 || d.Snippet != "    a = s % 2;" || !strings.HasSuffix(d.Message, ", at line 3, pos 9") {
+		t.Errorf("Expected the operands at line 3 with its snippet, got %+v", d)
+	}
+	if d := result.Diagnostics[1]; d.Line != 4 || d.Snippet != "    int a;" || d.Category != SemanticError {
+		t.Errorf("Expected a declared twice at line 4, got %+v", d)
+	}
+}
+
+// TestCompile_Encoding compiles a program written in GBK with \r\n line
+// endings and in UTF-8 with \n, which must give the same code and errors.
+func TestCompile_Encoding(t *testing.T) {
+	src := "{\n    int a;\n    string s;\n    s = \"中文\";\n    a = s % 2;\n}\n"
+	gbk := strings.ReplaceAll(strings.ReplaceAll(src, "中文", "\xd6\xd0\xce\xc4"), "\n", "\r\n")
+	var results [2]*Result
+	for i, source := range []string{src, gbk} {
+		result, err := Compile(Options{Source: strings.NewReader(source)})
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		results[i] = result
+	}
+	if results[0].Encoding != charset.UTF8 || results[0].CRLF || results[1].Encoding != charset.GBK || !results[1].CRLF {
+		t.Errorf("Expected UTF-8 and GBK with CRLF, got %v, %v and %v, %v", results[0].Encoding, results[0].CRLF, results[1].Encoding, results[1].CRLF)
+	}
+	messages := func(r *Result) []string {
+		var messages []string
+		for _, d := range r.Diagnostics {
+			messages = append(messages, d.Message+" | "+d.Snippet)
+		}
+		return messages
+	}
+	if a, b := messages(results[0]), messages(results[1]); len(a) == 0 || !slices.Equal(a, b) {
+		t.Errorf("Expected the same errors, got %q and %q", a, b)
+	}
+	if a, b := results[0].Walker.ThreeAddress, results[1].Walker.ThreeAddress; !slices.Equal(a, b) || !strings.Contains(strings.Join(b, "\n"), "中文") {
+		t.Errorf("Expected the same code with the string in UTF-8, got\n%q\nand\n%q", a, b)
+	}
+}
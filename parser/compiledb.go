@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+)
+
+// CompileCommand is the entry of a file in the compilation database, in the
+// spirit of the compile_commands.json of clang: where and how the file was
+// compiled, what it produced and how it went.
+type CompileCommand struct {
+	Directory   string             `json:"directory"` // the working directory the paths are relative to
+	File        string             `json:"file"`
+	Arguments   []string           `json:"arguments"` // the command line compiling the file alone
+	Output      string             `json:"output"`    // the log of the parse
+	Artifacts   []string           `json:"artifacts"` // the other files written, such as the code
+	Diagnostics DiagnosticsSummary `json:"diagnostics"`
+}
+
+// DiagnosticsSummary counts the diagnostics of a compilation.
+type DiagnosticsSummary struct {
+	Completed  bool        `json:"completed"` // the parse reached the end of the program
+	Fatal      bool        `json:"fatal"`     // an error stopped the compilation
+	Errors     int         `json:"errors"`
+	Warnings   int         `json:"warnings"`
+	FirstError string      `json:"firstError,omitempty"`
+	Categories ErrorCounts `json:"categories"`
+}
+
+// ErrorCounts counts the errors by category, see LexicalError.
+type ErrorCounts struct {
+	Lexical  int `json:"lexical"`
+	Syntax   int `json:"syntax"`
+	Semantic int `json:"semantic"`
+	Internal int `json:"internal"`
+}
+
+// Add counts an error of the category, an internal one if it is unknown.
+func (c *ErrorCounts) Add(category string) {
+	switch category {
+	case LexicalError:
+		c.Lexical++
+	case SyntaxError:
+		c.Syntax++
+	case SemanticError:
+		c.Semantic++
+	default:
+		c.Internal++
+	}
+}
+
+// Merge adds the counts of another.
+func (c *ErrorCounts) Merge(other ErrorCounts) {
+	c.Lexical += other.Lexical
+	c.Syntax += other.Syntax
+	c.Semantic += other.Semantic
+	c.Internal += other.Internal
+}
+
+// Summary counts the diagnostics of the result.
+func (r *Result) Summary() DiagnosticsSummary {
+	_, fatal := r.Fatal()
+	summary := DiagnosticsSummary{Completed: r.AST != nil, Fatal: fatal}
+	for _, d := range r.Diagnostics {
+		switch d.Severity {
+		case "Error":
+			if summary.Errors == 0 {
+				summary.FirstError = d.Message
+			}
+			summary.Errors++
+			summary.Categories.Add(d.Category)
+		case "Warning":
+			summary.Warnings++
+		}
+	}
+	return summary
+}
+
+// CompileDB is the compilation database, its commands sorted by file.
+type CompileDB []CompileCommand
+
+// ReadCompileDB reads the database written by WriteJSON.
+func ReadCompileDB(r io.Reader) (CompileDB, error) {
+	var db CompileDB
+	if err := json.NewDecoder(r).Decode(&db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Merge returns the database with the commands added, replacing those of the
+// same files, so that compiling some of the files of a project keeps the
+// entries of the others.
+func (db CompileDB) Merge(commands ...CompileCommand) CompileDB {
+	merged := slices.DeleteFunc(slices.Clone(db), func(c CompileCommand) bool {
+		return slices.ContainsFunc(commands, func(n CompileCommand) bool { return n.File == c.File })
+	})
+	merged = append(merged, commands...)
+	slices.SortStableFunc(merged, func(a, b CompileCommand) int { return strings.Compare(a.File, b.File) })
+	return merged
+}
+
+// WriteJSON writes the database to the writer.
+func (db CompileDB) WriteJSON(w io.Writer) error {
+	if db == nil {
+		db = CompileDB{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(db)
+}
@@ -0,0 +1,51 @@
+package parser_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestCompileDB(t *testing.T) {
+	tables := sharedParser().Tables()
+	result, err := Compile(Options{Source: strings.NewReader("{\n    int a;\n    a = ;\n    b = 1;\n}\n"), Tables: tables})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	summary := result.Summary()
+	if summary.Completed || summary.Errors == 0 || !strings.HasPrefix(summary.FirstError, result.Diagnostics[0].Message) {
+		t.Errorf("Expected the syntax error to be summarized, got %+v", summary)
+	}
+	result, err = Compile(Options{Source: strings.NewReader("{\n    {\n        int c;\n        c = c + 1;\n    }\n}\n"), Tables: tables})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if summary := result.Summary(); summary != (DiagnosticsSummary{Completed: true, Warnings: 1}) {
+		t.Errorf("Expected a warning about c, got %+v", summary)
+	}
+
+	db := CompileDB{
+		{File: "tests/parser/2.in", Artifacts: []string{"tests/parser/result/2.in.tac"}},
+		{File: "tests/parser/1.in"},
+	}.Merge(CompileCommand{File: "tests/parser/2.in", Diagnostics: result.Summary()}, CompileCommand{File: "tests/parser/3.in"})
+	if len(db) != 3 || db[0].File != "tests/parser/1.in" || db[1].Artifacts != nil || db[1].Diagnostics.Warnings != 1 || db[2].File != "tests/parser/3.in" {
+		t.Fatalf("Expected the entry of 2.in to be replaced and the entries sorted by file, got %+v", db)
+	}
+
+	var buf bytes.Buffer
+	if err := db.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"file": "tests/parser/1.in"`) || !strings.Contains(buf.String(), `"warnings": 1`) {
+		t.Errorf("Unexpected JSON\n%s", buf.String())
+	}
+	read, err := ReadCompileDB(&buf)
+	if err != nil {
+		t.Fatalf("ReadCompileDB: %v", err)
+	}
+	if len(read) != 3 || read[1].File != db[1].File || read[1].Diagnostics != db[1].Diagnostics {
+		t.Errorf("Expected the database to read back, got %+v", read)
+	}
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+
+	"app/lexer"
+	"app/utils"
+)
+
+// Completion is a word that can be written at a position, a symbol visible
+// there or a keyword.
+type Completion struct {
+	Label  string
+	Kind   string // the kind of the symbol, such as variable, array or builtin, or keyword
+	Detail string // the type of the symbol, empty for keywords
+	Depth  int    // how many scopes out of the one at the position the symbol is, -1 for keywords
+}
+
+// scopeAt returns the scope the position is in, given the index of the last
+// token starting before it, -1 if there is none.
+func (a *Analysis) scopeAt(token int) *Scope {
+	if len(a.scopes) == 0 {
+		return nil
+	}
+	if token >= len(a.scopes) {
+		// the parse stopped before the position, the scope it stopped in is the best guess
+		return a.scopes[len(a.scopes)-1]
+	}
+	// a block is entered before its opening brace is shifted, and left before its closing one is
+	if token < 0 && a.shifted[0].Val == "{" && a.scopes[0].Parent != nil {
+		return a.scopes[0].Parent
+	}
+	return a.scopes[max(token, 0)]
+}
+
+// keywords returns the keywords of the grammar and the basic types, sorted.
+func (a *Analysis) keywords() []string {
+	words := lexer.BasicTypes()
+	for _, keyword := range a.Walker.symbols().Category(KeywordSymbol) {
+		words = append(words, keyword.Literal)
+	}
+	slices.Sort(words)
+	return words
+}
+
+// Completions returns what can be written at the position: the symbols
+// visible there, found by walking the scope chain out of the scope of the
+// position and nearest first, then the keywords. Symbols of the same scope
+// are sorted by name, those declared after the position or shadowed are
+// left out. Lines are counted from 0 and positions from 1 like the lexer does.
+func (a *Analysis) Completions(line, pos int64) ([]Completion, error) {
+	offset, err := a.Document.Offset(line, pos)
+	if err != nil {
+		return nil, err
+	}
+	_, before := a.Document.TokensIn(0, offset)
+	token := before - 1
+	declared := map[utils.Pair[int64, int64]]int{}
+	for i, t := range a.shifted {
+		declared[utils.MakePair(t.Line, t.Pos)] = i
+	}
+
+	var result []Completion
+	seen := map[string]bool{}
+	depth := 0
+	for scope := a.scopeAt(token); scope != nil; scope = scope.Parent {
+		var items []Completion
+		for name, item := range scope.Items {
+			if i, ok := declared[utils.MakePair(item.Line, item.Pos)]; seen[name] || ok && i > token {
+				continue
+			}
+			seen[name] = true
+			items = append(items, Completion{Label: name, Kind: string(item.Type), Detail: typeName(item), Depth: depth})
+		}
+		slices.SortFunc(items, func(x, y Completion) int { return strings.Compare(x.Label, y.Label) })
+		result = append(result, items...)
+		depth++
+	}
+	for _, word := range a.keywords() {
+		result = append(result, Completion{Label: word, Kind: "keyword", Depth: -1})
+	}
+	return result, nil
+}
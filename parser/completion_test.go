@@ -0,0 +1,63 @@
+package parser_test
+
+import (
+	"slices"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestAnalysis_Completions(t *testing.T) {
+	src := "{\n    int a;\n    float b[4];\n    {\n        float a;\n        int c;\n        a = 1.5;\n        \n        int d;\n    }\n    \n}\n"
+	a := sharedParser().Tables().Analyze(src)
+
+	labels := func(completions []Completion, kind string) []string {
+		var result []string
+		for _, c := range completions {
+			if kind == "" && c.Kind != "keyword" && c.Kind != "builtin" || c.Kind == kind {
+				result = append(result, c.Label)
+			}
+		}
+		return result
+	}
+	// in the inner block, after c and before d
+	completions, err := a.Completions(7, 9)
+	if err != nil {
+		t.Fatalf("Completions: %v", err)
+	}
+	if got := labels(completions, ""); !slices.Equal(got, []string{"a", "c", "b"}) {
+		t.Errorf("Expected a and c of the inner block then b, got %v", got)
+	}
+	if i := slices.IndexFunc(completions, func(c Completion) bool { return c.Label == "a" }); completions[i].Detail != "float" || completions[i].Depth != 0 {
+		t.Errorf("Expected the inner a to shadow the outer one, got %+v", completions[i])
+	}
+	if i := slices.IndexFunc(completions, func(c Completion) bool { return c.Label == "b" }); completions[i].Detail != "float[4]" || completions[i].Depth != 1 || completions[i].Kind != "array" {
+		t.Errorf("Expected b one scope out, got %+v", completions[i])
+	}
+	builtins, keywords := labels(completions, "builtin"), labels(completions, "keyword")
+	if !slices.Contains(builtins, "abs") || !slices.Contains(keywords, "while") || !slices.Contains(keywords, "int") || slices.Contains(keywords, "goto") {
+		t.Errorf("Expected the builtins and the keywords of the grammar, got %v and %v", builtins, keywords)
+	}
+	if last := completions[len(completions)-1]; last.Kind != "keyword" || last.Depth != -1 {
+		t.Errorf("Expected the keywords last, got %+v", last)
+	}
+
+	// after the inner block
+	completions, err = a.Completions(10, 5)
+	if err != nil {
+		t.Fatalf("Completions: %v", err)
+	}
+	if got := labels(completions, ""); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("Expected only the outer a and b, got %v", got)
+	}
+
+	// an incomplete source is completed in the scope the parse stopped in
+	a = sharedParser().Tables().Analyze("{\n    int total;\n    {\n        int count;\n        count = \n")
+	completions, err = a.Completions(4, 17)
+	if err != nil {
+		t.Fatalf("Completions: %v", err)
+	}
+	if got := labels(completions, ""); !slices.Equal(got, []string{"count", "total"}) {
+		t.Errorf("Expected count then total, got %v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Conflict is a cell of the action table two actions compete for, the one
+// the table keeps, the shift or the first reduction registered, and the one
+// it drops.
+type Conflict struct {
+	State       int
+	Terminal    Terminal
+	Kept        Action
+	Dropped     Action
+	Productions []int // of the items asking for either action, by index in Grammar.Productions
+}
+
+// newConflict returns the conflict on the terminal in the state, the action
+// registered before and the one registered after competing for the cell.
+func newConflict(state *State, grammar *Grammar, terminal Terminal, kept, before, after Action) Conflict {
+	c := Conflict{State: state.Index, Terminal: terminal, Kept: kept, Dropped: after}
+	if kept == after {
+		c.Dropped = before
+	}
+	for _, item := range state.Items {
+		if !asks(item, terminal) || item.Production.Equals(grammar.AugmentedProduction) {
+			continue
+		}
+		index := grammar.GetIndex(item.Production)
+		if item.Dot < len(item.Production.Body) && !item.Production.Body[item.Dot].IsEpsilon() {
+			// a shifting item, unless the conflict is between reductions
+			if kept.Type == SHIFT || c.Dropped.Type == SHIFT {
+				c.Productions = append(c.Productions, index)
+			}
+		} else if reduction := (Action{Type: REDUCE, Number: index}); kept == reduction || c.Dropped == reduction {
+			c.Productions = append(c.Productions, index)
+		}
+	}
+	slices.Sort(c.Productions)
+	c.Productions = slices.Compact(c.Productions)
+	return c
+}
+
+// asks checks if the item asks for an action on the terminal: it shifts the
+// terminal or it is complete and the terminal is its lookahead.
+func asks(item LR1Item, terminal Terminal) bool {
+	if item.Dot == len(item.Production.Body) || item.Production.Body[item.Dot].IsEpsilon() {
+		return item.Lookahead == terminal
+	}
+	return item.Production.Body[item.Dot] == Symbol(terminal)
+}
+
+// Err returns ErrShiftReduce or ErrReduceReduce, the kind of the conflict.
+func (c Conflict) Err() error {
+	if c.Kept.Type == REDUCE && c.Dropped.Type == REDUCE {
+		return ErrReduceReduce
+	}
+	return ErrShiftReduce
+}
+
+// ReportConflicts writes the conflicts of the table to the writer, each with
+// the action kept, the one dropped and the items asking for them.
+func (p *Parser) ReportConflicts(w io.Writer) error {
+	p.EnsureTable()
+	p.EnsureStates()
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	printf("%d shift/reduce and %d reduce/reduce conflicts\n", p.Table.ShiftReduceConflicts, p.Table.ReduceReduceConflicts)
+	for _, c := range p.Table.Conflicts {
+		printf("\nI%d on %s: %v, %s kept, %s dropped\n", c.State, p.Grammar.Name(Symbol(c.Terminal)), c.Err(), actionCell(c.Kept), actionCell(c.Dropped))
+		if c.State >= len(p.States) {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, item := range p.States[c.State].Items {
+			if core := item.Core(); asks(item, c.Terminal) && slices.Contains(c.Productions, p.Grammar.GetIndex(item.Production)) && !seen[core] {
+				seen[core] = true
+				printf("    %s\n", core)
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package parser_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+func TestParser_ReportConflicts(t *testing.T) {
+	// the dangling else, and a reduce/reduce conflict between A -> a and B -> a on c
+	grammar := &Grammar{
+		AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
+		Productions: []Production{
+			{Head: "S", Body: []Symbol{"i", "S", "e", "S"}},
+			{Head: "S", Body: []Symbol{"i", "S"}},
+			{Head: "S", Body: []Symbol{"A", "c"}},
+			{Head: "S", Body: []Symbol{"B", "c"}},
+			{Head: "A", Body: []Symbol{"a"}},
+			{Head: "B", Body: []Symbol{"a"}},
+		},
+		Terminals: Set[Terminal]{}.AddAll("i", "e", "a", "c", EPSILON, TERMINATE),
+	}
+	p := &Parser{Grammar: grammar, Symbols: NewOrderedSet[Symbol](), FirstSet: FirstSet{}, States: States{}}
+	p.EnsureTable()
+	if len(p.Table.Conflicts) != p.Table.ShiftReduceConflicts+p.Table.ReduceReduceConflicts || len(p.Table.Conflicts) == 0 {
+		t.Fatalf("Expected a conflict per one counted, got %+v", p.Table.Conflicts)
+	}
+	kinds := map[error]int{}
+	for _, c := range p.Table.Conflicts {
+		kinds[c.Err()]++
+		if p.Table.ActionTable[c.State][c.Terminal] != c.Kept || c.Kept == c.Dropped {
+			t.Errorf("Expected the table to hold the action kept, got %+v", c)
+		}
+		switch {
+		case errors.Is(c.Err(), ErrShiftReduce):
+			if c.Terminal != "e" || c.Kept.Type != SHIFT || c.Dropped != (Action{Type: REDUCE, Number: 1}) || len(c.Productions) != 2 {
+				t.Errorf("Expected the shift of else to win over reducing S -> i S, got %+v", c)
+			}
+		case c.Terminal != "c" || c.Kept != (Action{Type: REDUCE, Number: 4}) || c.Dropped != (Action{Type: REDUCE, Number: 5}) || len(c.Productions) != 2:
+			t.Errorf("Expected the reduction by A -> a to win over B -> a, got %+v", c)
+		}
+	}
+	if kinds[ErrShiftReduce] != p.Table.ShiftReduceConflicts || kinds[ErrReduceReduce] != 1 {
+		t.Errorf("Expected the kinds to match the counts, got %v", kinds)
+	}
+
+	var buf strings.Builder
+	if err := p.ReportConflicts(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"reduce/reduce conflict, r4 kept, r5 dropped\n    A → a ·\n    B → a ·\n", "shift/reduce conflict, s", "    S → i S · e S\n    S → i S ·\n"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected the report to contain %q, got\n%s", expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"app/lexer"
+	"app/parser/ir"
+)
+
+// The statements of control flow, and the operators && and || evaluating
+// their right operand only if needed, are reduced once the code of their
+// parts is emitted, so their rules insert the jumps between the parts, at the
+// instruction the token before each part was shifted at, and emit the rest
+// after the last one. The tokens of the constructs enclosing them were
+// shifted before, at an instruction the insertion does not move.
+
+// IfStatement handles if ( bool ) stmt, which jumps over the statement if the
+// condition does not hold.
+func IfStatement(c *ReduceContext) error {
+	w := c.Walker
+	end := w.NewLabel("if") + "_end"
+	w.insert(c.Nodes[3], jumpIf(c.Nodes[2], false, end)...)
+	w.EmitLabel(end)
+	return nil
+}
+
+// IfElseStatement handles if ( bool ) stmt else stmt, which jumps to the else
+// statement if the condition does not hold, and over it from the end of the
+// first one.
+func IfElseStatement(c *ReduceContext) error {
+	w := c.Walker
+	label := w.NewLabel("if")
+	els, end := label+"_else", label+"_end"
+	w.insert(c.Nodes[5], ir.Quad{Op: ir.Goto, Result: end}, ir.Quad{Op: ir.Label, Result: els})
+	w.insert(c.Nodes[3], jumpIf(c.Nodes[2], false, els)...)
+	w.EmitLabel(end)
+	return nil
+}
+
+// WhileStatement handles while ( bool ) stmt, which tests the condition
+// before each run of the statement, out of the loop to its end label once it
+// does not hold, the one a break in the loop jumps to.
+func WhileStatement(c *ReduceContext) error {
+	w := c.Walker
+	keyword := c.Nodes[0]
+	begin := w.labelOf(keyword)
+	w.insert(c.Nodes[3], jumpIf(c.Nodes[2], false, begin+"_end")...)
+	w.insert(keyword, ir.Quad{Op: ir.Label, Result: begin})
+	w.EmitJump(begin)
+	w.EmitLabel(begin + "_end")
+	return nil
+}
+
+// DoWhileStatement handles do stmt while ( bool ) ;, which runs the statement
+// again as long as the condition holds after it.
+func DoWhileStatement(c *ReduceContext) error {
+	w := c.Walker
+	keyword := c.Nodes[0]
+	begin := w.labelOf(keyword)
+	w.insert(keyword, ir.Quad{Op: ir.Label, Result: begin})
+	for _, q := range jumpIf(c.Nodes[4], true, begin) {
+		w.EmitQuad(q)
+	}
+	w.EmitLabel(begin + "_end")
+	return nil
+}
+
+// BreakStatement handles break ;, which jumps to the end of the innermost
+// loop or switch the statement is in, the keyword of which is still on the
+// token stack.
+func BreakStatement(c *ReduceContext) error {
+	w := c.Walker
+	for k := len(c.Nodes); k < w.Tokens.Size(); k++ {
+		if n, _ := w.Tokens.PeekAtK(k); slices.Contains([]Symbol{"while", "do", "switch"}, n.Type) {
+			w.EmitJump(w.labelOf(n) + "_end")
+			return nil
+		}
+	}
+	return c.Errorf("break is not in a loop or a switch")
+}
+
+// Logical returns the action of the production of the operator && or ||,
+// head → a op b.
+func Logical(op string) SemanticAction {
+	return func(c *ReduceContext) error {
+		return logical(c, op)
+	}
+}
+
+// logical handles head → a op b for && and ||: the value of a is the result
+// if it decides it, false for && and true for ||, else that of b, computed
+// only then. Two literals are folded.
+func logical(c *ReduceContext, op string) error {
+	w := c.Walker
+	arg1, arg2 := c.Nodes[0], c.Nodes[2]
+	v1, ok1 := constant(arg1)
+	v2, ok2 := constant(arg2)
+	if ok1 && ok2 {
+		v := v1 != 0 && v2 != 0
+		if op == "||" {
+			v = v1 != 0 || v2 != 0
+		}
+		c.Result = NewLiteral(lexer.RESERVED, strconv.FormatBool(v), lexer.TypeBool, c.Nodes)
+		return nil
+	}
+	result := w.NewTemp(c.Production.Head, lexer.TypeBool, fmt.Sprintf("%s %s %s", arg1.raw, op, arg2.raw), c.Nodes)
+	end := w.NewLabel(map[string]string{"&&": "and", "||": "or"}[op]) + "_end"
+	w.insert(c.Nodes[1], ir.Quad{Op: ir.Copy, Arg1: arg1.String(), Result: result.String()},
+		ir.Quad{Op: ir.Jump(map[string]string{"&&": "==", "||": "!="}[op]), Arg1: result.String(), Arg2: "false", Result: end})
+	w.Emit(result.String(), "", arg2)
+	w.EmitLabel(end)
+	c.Result = result
+	return nil
+}
+
+// Not handles unary → ! unary, true if the operand is false or 0, folded
+// for a literal.
+func Not(c *ReduceContext) error {
+	w := c.Walker
+	arg := c.Nodes[1]
+	if v, ok := constant(arg); ok {
+		c.Result = NewLiteral(lexer.RESERVED, strconv.FormatBool(v == 0), lexer.TypeBool, c.Nodes)
+		return nil
+	}
+	c.Result = w.NewTemp(c.Production.Head, lexer.TypeBool, "!"+arg.raw, c.Nodes)
+	w.Emit(c.Result.String(), "eq", arg, "false")
+	return nil
+}
+
+// emitSwitch emits the jumps of switch ( bool ) { cases } to the case of the
+// value, or the default one, or the end if none, and the labels of the cases
+// before their statements, which fall through into those of the next one.
+func (w *Walker) emitSwitch(c *ReduceContext) {
+	value, cases := c.Nodes[2], c.Nodes[5].Children
+	label := w.labelOf(c.Nodes[0])
+	var dispatch []ir.Quad
+	fallback := label + "_end"
+	for i := len(cases) - 1; i >= 0; i-- {
+		keyword := cases[i].Children[0]
+		target := fmt.Sprintf("%s_case_%d", label, i)
+		w.insert(keyword, ir.Quad{Op: ir.Label, Result: target})
+		if keyword.Token.Val == "default" {
+			fallback = target
+			continue
+		}
+		v, _ := IntLiteral(cases[i].Children[1])
+		dispatch = append(dispatch, ir.Quad{Op: ir.Jump("=="), Arg1: value.String(), Arg2: strconv.FormatInt(v, 10), Result: target})
+	}
+	slices.Reverse(dispatch)
+	w.insert(c.Nodes[3], append(dispatch, ir.Quad{Op: ir.Goto, Result: fallback})...)
+	w.EmitLabel(label + "_end")
+}
+
+// labelOf returns the label of the statement of the keyword, issued the
+// first time it is asked for: a break in the statement needs the label of
+// its end, made by adding _end to it, before the statement is reduced.
+func (w *Walker) labelOf(keyword *ASTNode) string {
+	if label, ok := keyword.Attribute("label").(string); ok {
+		return label
+	}
+	label := w.NewLabel(keyword.Token.Val)
+	if keyword.Attributes == nil {
+		keyword.Attributes = map[string]any{}
+	}
+	keyword.Attributes["label"] = label
+	return label
+}
+
+// jumpIf returns the jump to the label taken if the condition is the value,
+// a goto if it is that constant, and none if it is the other.
+func jumpIf(cond *ASTNode, value bool, label string) []ir.Quad {
+	if IsLiteral(cond) && cond.Token.Type == lexer.RESERVED {
+		if cond.Token.Val == strconv.FormatBool(value) {
+			return []ir.Quad{{Op: ir.Goto, Result: label}}
+		}
+		return nil
+	}
+	relop := "=="
+	if value {
+		relop = "!="
+	}
+	return []ir.Quad{{Op: ir.Jump(relop), Arg1: cond.String(), Arg2: "false", Result: label}}
+}
+
+// insert inserts the quadruples into the code before the instruction the
+// token of the node was shifted at, with the line and the span of the token.
+func (w *Walker) insert(token *ASTNode, quads ...ir.Quad) {
+	if len(quads) == 0 {
+		return
+	}
+	code := make([]string, len(quads))
+	for i, q := range quads {
+		code[i] = q.TAC()
+	}
+	at := token.code
+	w.ThreeAddress = slices.Insert(w.ThreeAddress, at, code...)
+	if at <= len(w.Lines) {
+		w.Lines = slices.Insert(w.Lines, at, slices.Repeat([]int64{token.Token.Line}, len(code))...)
+	}
+	if at <= len(w.Spans) {
+		w.Spans = slices.Insert(w.Spans, at, slices.Repeat([]Span{spanOf(token)}, len(code))...)
+	}
+}
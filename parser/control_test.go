@@ -0,0 +1,46 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestControlFlow(t *testing.T) {
+	w := parseSource(t, `{ int i; i = 0; while (i < 3) { i = i + 1; } if (i > 100) { printf("big\n"); } }`)
+	// the jumps go between the code of the parts, the condition tested before each run of the body
+	expected := []string{
+		"i = 0",
+		"L_while_0:", "$(0x10000001) = i lt 3", "if $(0x10000001) == false goto L_while_0_end",
+		"$(0x10000002) = i + 1", "i = $(0x10000002)", "goto L_while_0", "L_while_0_end:",
+		"$(0x10000003) = i gt 100", "if $(0x10000003) == false goto L_if_1_end",
+		`param "big\n"`, "call print_str, 1", "L_if_1_end:",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %q, got %q", expected, w.ThreeAddress)
+	}
+	if len(w.Lines) != len(w.ThreeAddress) || len(w.Spans) != len(w.ThreeAddress) {
+		t.Errorf("Expected a line and a span per instruction, got %d and %d for %d", len(w.Lines), len(w.Spans), len(w.ThreeAddress))
+	}
+
+	// the right operand of && is computed only if the left one holds
+	w = parseSource(t, "{ int a; bool b; b = a > 1 && a < 5; }")
+	expected = []string{
+		"$(0x10000002) = a gt 1", "$(0x10000004) = $(0x10000002)", "if $(0x10000004) == false goto L_and_0_end",
+		"$(0x10000003) = a lt 5", "$(0x10000004) = $(0x10000003)", "L_and_0_end:", "b = $(0x10000004)",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %q, got %q", expected, w.ThreeAddress)
+	}
+
+	// a break out of any loop is an error
+	result, err := Compile(Options{Source: strings.NewReader("{ int a;\n  break; }")})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Message != "break is not in a loop or a switch, at line 1, pos 7" {
+		t.Errorf("Expected the break out of any loop reported, got %v", result.Diagnostics)
+	}
+}
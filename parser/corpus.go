@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Shard is a part of a corpus, the files Index of every Count of them, so
+// that Count runs, such as the jobs of a CI, compile the corpus together.
+// The zero Shard is the whole corpus.
+type Shard struct {
+	Index int // from 1 to Count
+	Count int
+}
+
+// ParseShard parses a shard written i/n, such as 2/4, the whole corpus if
+// empty.
+func ParseShard(s string) (Shard, error) {
+	if s == "" {
+		return Shard{}, nil
+	}
+	i, n, ok := strings.Cut(s, "/")
+	index, err1 := strconv.Atoi(i)
+	count, err2 := strconv.Atoi(n)
+	if !ok || err1 != nil || err2 != nil || count < 1 || index < 1 || index > count {
+		return Shard{}, fmt.Errorf("invalid shard %q, expected i/n with 1 <= i <= n", s)
+	}
+	return Shard{Index: index, Count: count}, nil
+}
+
+// Files returns the files of the shard, every Count-th from the Index-th, in
+// their order: the shards of the same files split them evenly and each file
+// is in a single one.
+func (s Shard) Files(files []string) []string {
+	if s.Count <= 1 {
+		return files
+	}
+	var shard []string
+	for i := s.Index - 1; i < len(files); i += s.Count {
+		shard = append(shard, files[i])
+	}
+	return shard
+}
+
+// PanicError is a panic of the compiler on a file, recovered so that the
+// other files of a batch still compile. The file fails with an internal
+// error.
+type PanicError struct {
+	Value any
+	Stack string // of the goroutine which panicked
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// BatchRecord is what a BatchResult tells of the file, the summary of its
+// diagnostics and the time it took, written as a line of JSON by
+// WriteBatchJSON for the shards of a corpus to be merged.
+type BatchRecord struct {
+	File    string             `json:"file"`
+	Summary DiagnosticsSummary `json:"summary"`
+	Elapsed int64              `json:"ms"`
+	Panic   string             `json:"panic,omitempty"` // the stack of the panic of the compiler, if any
+}
+
+// Record returns the record of the result.
+func (b BatchResult) Record() BatchRecord {
+	r := BatchRecord{File: b.File, Summary: b.Summary(), Elapsed: b.Elapsed.Milliseconds()}
+	if p := (*PanicError)(nil); errors.As(b.Err, &p) {
+		r.Panic = p.Stack
+	}
+	return r
+}
+
+// BatchRecords returns the records of the results in their order.
+func BatchRecords(results []BatchResult) []BatchRecord {
+	records := make([]BatchRecord, len(results))
+	for i, r := range results {
+		records[i] = r.Record()
+	}
+	return records
+}
+
+// WriteBatchJSON writes the records of the results, a line of JSON each.
+func WriteBatchJSON(w io.Writer, results []BatchResult) error {
+	encoder := json.NewEncoder(w)
+	for _, r := range results {
+		if err := encoder.Encode(r.Record()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MergeBatch reads the records WriteBatchJSON wrote of the shards of a
+// corpus, in any order, and returns them in the order of their files,
+// whatever the shards and the order of their files were, so that the merged
+// records of a corpus are the same however it was split. A file in two
+// shards fails.
+func MergeBatch(shards ...io.Reader) ([]BatchRecord, error) {
+	var records []BatchRecord
+	for i, r := range shards {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, 1<<24)
+		for line := 1; scanner.Scan(); line++ {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			var record BatchRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				return nil, fmt.Errorf("shard %d: invalid record at line %d: %v", i+1, line, err)
+			}
+			records = append(records, record)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("shard %d: %w", i+1, err)
+		}
+	}
+	slices.SortStableFunc(records, func(a, b BatchRecord) int { return cmp.Compare(a.File, b.File) })
+	for i := 1; i < len(records); i++ {
+		if records[i].File == records[i-1].File {
+			return nil, fmt.Errorf("%s is in two shards", records[i].File)
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,110 @@
+package parser_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestShard(t *testing.T) {
+	files := []string{"a", "b", "c", "d", "e", "f", "g"}
+	var all []string
+	for i := 1; i <= 3; i++ {
+		s, err := ParseShard(fmt.Sprintf("%d/3", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		shard := s.Files(files)
+		if len(shard) < 2 || len(shard) > 3 {
+			t.Errorf("Expected the files split evenly, got %v in %d/3", shard, i)
+		}
+		all = append(all, shard...)
+	}
+	slices.Sort(all)
+	if !slices.Equal(all, files) {
+		t.Errorf("Expected each file in a single shard, got %v", all)
+	}
+	if s, err := ParseShard(""); err != nil || !slices.Equal(s.Files(files), files) {
+		t.Errorf("Expected no shard to be the whole corpus, got %v %v", s, err)
+	}
+	for _, invalid := range []string{"0/3", "4/3", "1/0", "1", "a/b"} {
+		if _, err := ParseShard(invalid); err == nil {
+			t.Errorf("Expected %s to be invalid", invalid)
+		}
+	}
+}
+
+func TestCompileFiles_Panic(t *testing.T) {
+	dir := t.TempDir()
+	for name, source := range map[string]string{
+		"a.in": "{ int a; a = 1; }",
+		"b.in": "{ int a; a = a + 1; }",
+		"c.in": "{ int a; a = 2; }",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := BatchFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the compiler panics on the sums, those of b.in alone
+	opts := Options{Tables: sharedParser().Tables(), Hooks: []SemanticAction{func(c *ReduceContext) error {
+		if c.Production.Head == "expr" && len(c.Nodes) == 3 {
+			panic("sum")
+		}
+		return nil
+	}}}
+	results := CompileFiles(files, opts, 2)
+	var p *PanicError
+	if !errors.As(results[1].Err, &p) || p.Value != "sum" || !strings.Contains(p.Stack, "goroutine") {
+		t.Fatalf("Expected the panic of b.in recovered with its stack, got %v", results[1].Err)
+	}
+	if s := results[1].Summary(); s.ExitCode() != ExitInternal || s.FirstError != "panic: sum" {
+		t.Errorf("Expected b.in to fail with an internal error, got %+v", s)
+	}
+	if results[0].Summary().ExitCode() != ExitOK || results[2].Summary().ExitCode() != ExitOK {
+		t.Errorf("Expected the other files compiled, got %+v and %+v", results[0].Summary(), results[2].Summary())
+	}
+
+	// the shards merged in any order give the records of the whole corpus
+	var first, second bytes.Buffer
+	odd, _ := ParseShard("1/2")
+	even, _ := ParseShard("2/2")
+	if err := WriteBatchJSON(&first, CompileFiles(odd.Files(files), opts, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteBatchJSON(&second, CompileFiles(even.Files(files), opts, 1)); err != nil {
+		t.Fatal(err)
+	}
+	records, err := MergeBatch(bytes.NewReader(second.Bytes()), bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	whole := BatchRecords(results)
+	if len(records) != 3 {
+		t.Fatalf("Expected a record per file, got %+v", records)
+	}
+	for i, r := range records {
+		if r.File != whole[i].File || r.Summary != whole[i].Summary || (r.Panic == "") != (i != 1) {
+			t.Errorf("Expected the record of %s as the whole batch has it, got %+v", whole[i].File, r)
+		}
+	}
+	if counts, failed := BatchRecordCounts(records); failed != 1 || counts.Internal != 1 {
+		t.Errorf("Expected b.in to fail, got %d and %+v", failed, counts)
+	}
+	if _, err := MergeBatch(bytes.NewReader(first.Bytes()), bytes.NewReader(first.Bytes())); err == nil || !strings.Contains(err.Error(), "in two shards") {
+		t.Errorf("Expected a file in two shards to fail, got %v", err)
+	}
+	if _, err := MergeBatch(strings.NewReader("{\n")); err == nil || !strings.Contains(err.Error(), "at line 1") {
+		t.Errorf("Expected an invalid record to fail, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// CostClasses are the classes of the instructions of three-address code the
+// cost model charges, see CostModel.Class.
+var CostClasses = []string{"move", "add", "mul", "div", "compare", "logic", "convert", "string", "jump", "branch", "param", "call", "return", "stack"}
+
+// CostModel is what running three-address code costs, in cycles: a number
+// per class of instruction, and optionally one per access to memory, a
+// variable or a slot of the frame rather than a register or a constant.
+type CostModel struct {
+	Cycles map[string]int `json:"cycles"`
+	Memory int            `json:"memory"` // per operand read from or written to memory, 0 to leave memory out
+}
+
+// DefaultCostModel returns the model of a simple in-order processor, where
+// multiplications take a few cycles, divisions and the helpers of strings
+// many, and memory costs nothing more than registers.
+func DefaultCostModel() *CostModel {
+	return &CostModel{Cycles: map[string]int{
+		"move": 1, "add": 1, "mul": 3, "div": 10, "compare": 1, "logic": 1, "convert": 2, "string": 8,
+		"jump": 1, "branch": 2, "param": 1, "call": 5, "return": 2, "stack": 1,
+	}}
+}
+
+// ReadCostModel reads a model written in JSON as CostModel is, such as
+// {"cycles": {"div": 20}, "memory": 3}, over the default one: the classes
+// missing keep their default cost.
+func ReadCostModel(r io.Reader) (*CostModel, error) {
+	var read CostModel
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&read); err != nil {
+		return nil, fmt.Errorf("invalid cost model: %v", err)
+	}
+	m := DefaultCostModel()
+	for class, cycles := range read.Cycles {
+		if !slices.Contains(CostClasses, class) {
+			return nil, fmt.Errorf("invalid cost model: unknown class of instruction %s", class)
+		}
+		if cycles < 0 {
+			return nil, fmt.Errorf("invalid cost model: negative cost of %s", class)
+		}
+		m.Cycles[class] = cycles
+	}
+	if read.Memory < 0 {
+		return nil, fmt.Errorf("invalid cost model: negative cost of memory")
+	}
+	m.Memory = read.Memory
+	return m, nil
+}
+
+// costOperators are the classes of the operators of the assignments.
+var costOperators = map[string]string{
+	"+": "add", "-": "add", "minus": "add",
+	"*": "mul", "/": "div", "%": "div", "mod": "div",
+	"<": "compare", "<=": "compare", ">": "compare", ">=": "compare", "==": "compare", "!=": "compare",
+	"lt": "compare", "le": "compare", "gt": "compare", "ge": "compare", "eq": "compare", "ne": "compare",
+	"&&": "logic", "||": "logic", "!": "logic",
+	"itof":   "convert",
+	"strcat": "string", "streq": "string", "strne": "string",
+}
+
+// Class returns the class of the instruction, empty for a label and the
+// markers of a lifetime, which are not run.
+func (m *CostModel) Class(line string) string {
+	if _, _, ok := lifetimeOf(line); ok || strings.HasSuffix(line, ":") && !strings.Contains(line, " ") {
+		return ""
+	}
+	op, rest, _ := strings.Cut(line, " ")
+	switch op {
+	case "goto":
+		return "jump"
+	case "if":
+		return "branch"
+	case "param":
+		return "param"
+	case "call", "icall":
+		return "call"
+	case "ret":
+		return "return"
+	case "push", "pop":
+		return "stack"
+	}
+	_, value, ok := strings.Cut(rest, "= ")
+	if !ok {
+		return "move"
+	}
+	fields := operands(value)
+	if len(fields) > 0 && (fields[0] == "call" || fields[0] == "icall") {
+		return "call"
+	}
+	for _, field := range fields {
+		if class, ok := costOperators[field]; ok {
+			return class
+		}
+	}
+	return "move"
+}
+
+// MemoryAccesses returns how many operands of the instruction are in memory:
+// the variables, the temporaries left in memory, the slots of the frame and
+// the pushes and pops of the stack. Registers, constants, labels and the
+// functions called are not.
+func (m *CostModel) MemoryAccesses(line string) int {
+	switch op, _, _ := strings.Cut(line, " "); op {
+	case "push", "pop":
+		return 1
+	case "goto", "ret":
+		return 0
+	}
+	if m.Class(line) == "" {
+		return 0
+	}
+	n := 0
+	matches := operandPattern.FindAllStringIndex(line, -1)
+	for i, match := range matches {
+		operand := line[match[0]:match[1]]
+		previous := ""
+		if i > 0 {
+			previous = line[matches[i-1][0]:matches[i-1][1]]
+		}
+		switch {
+		case strings.HasPrefix(operand, `"`):
+		case strings.HasPrefix(operand, "$("):
+			n++
+		case operand == "fp" || operand == "sp":
+			if strings.HasPrefix(line[match[1]:], "[") {
+				n++
+			}
+		case slices.Contains(Registers, operand), slices.Contains(opcodes, operand),
+			operand == "true", operand == "false", operand == "push", operand == "pop", operand == "ret",
+			previous == "goto", previous == "call", previous == "icall":
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// ClassCost is what the instructions of a class cost in a run.
+type ClassCost struct {
+	Instructions int
+	Cycles       int
+}
+
+// CostReport is the cost of a run of the code.
+type CostReport struct {
+	Instructions   int // instructions run, the labels left out
+	MemoryAccesses int
+	Cycles         int // in total, memory included
+	Classes        map[string]ClassCost
+}
+
+// Run returns the cost of a run of the code in which the i-th instruction ran
+// counts[i] times, as vm.Run counts them. Without the counts each instruction
+// is charged once, the cost of a run of code without loops taking every
+// branch.
+func (m *CostModel) Run(code []string, counts []int) *CostReport {
+	r := &CostReport{Classes: map[string]ClassCost{}}
+	for i, line := range code {
+		times := 1
+		if counts != nil {
+			times = 0
+			if i < len(counts) {
+				times = counts[i]
+			}
+		}
+		class := m.Class(line)
+		if class == "" || times == 0 {
+			continue
+		}
+		accesses := m.MemoryAccesses(line) * times
+		cycles := m.Cycles[class]*times + m.Memory*accesses
+		c := r.Classes[class]
+		c.Instructions += times
+		c.Cycles += cycles
+		r.Classes[class] = c
+		r.Instructions += times
+		r.MemoryAccesses += accesses
+		r.Cycles += cycles
+	}
+	return r
+}
+
+// Write writes the report, the cost of each class run and the total.
+func (r *CostReport) Write(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	printf("%-8s %12s %12s\n", "Class", "Instructions", "Cycles")
+	for _, class := range slices.Sorted(maps.Keys(r.Classes)) {
+		printf("%-8s %12d %12d\n", class, r.Classes[class].Instructions, r.Classes[class].Cycles)
+	}
+	printf("\n%d instructions, %d memory accesses, %d cycles\n", r.Instructions, r.MemoryAccesses, r.Cycles)
+	return err
+}
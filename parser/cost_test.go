@@ -0,0 +1,74 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestCostModel_Run(t *testing.T) {
+	code := []string{
+		"main:",
+		"push fp",
+		"fp = sp",
+		"fp[-4] = r0",
+		"r0 = a [ 1 ] mod 8",
+		"r1 = r0 * 2",
+		"if r1 >= 0 goto L_abs_0",
+		"r1 = minus r1",
+		"L_abs_0:",
+		"i = r1",
+		`r2 = call printf, 1`,
+		"ret",
+	}
+	m := DefaultCostModel()
+	for i, expected := range []string{"", "stack", "move", "move", "div", "mul", "branch", "add", "", "move", "call", "return"} {
+		if class := m.Class(code[i]); class != expected {
+			t.Errorf("Expected %q to be of the class %q, got %q", code[i], expected, class)
+		}
+	}
+	for i, expected := range []int{0, 1, 0, 1, 1, 0, 0, 0, 0, 1, 0, 0} {
+		if n := m.MemoryAccesses(code[i]); n != expected {
+			t.Errorf("Expected %d accesses to memory by %q, got %d", expected, code[i], n)
+		}
+	}
+
+	r := m.Run(code, nil)
+	// 1 + 1 + 1 + 10 + 3 + 2 + 1 + 1 + 5 + 2 for the 10 instructions run once
+	if r.Instructions != 10 || r.Cycles != 27 || r.MemoryAccesses != 4 {
+		t.Errorf("Expected 10 instructions in 27 cycles, got %+v", r)
+	}
+	// a loop around the division run 5 times, with the memory charged
+	m.Memory = 3
+	r = m.Run(code, []int{0, 1, 1, 1, 5, 5, 5, 0, 0, 1, 1, 1})
+	if div := r.Classes["div"]; div.Instructions != 5 || div.Cycles != 5*10+5*3 {
+		t.Errorf("Expected 5 divisions reading memory, got %+v", div)
+	}
+	if r.Instructions != 21 || r.MemoryAccesses != 8 || r.Cycles != 1+1+1+50+15+10+1+5+2+8*3 {
+		t.Errorf("Expected the counts to be charged, got %+v", r)
+	}
+
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "div                 5           65\n") || !strings.HasSuffix(b.String(), "21 instructions, 8 memory accesses, 110 cycles\n") {
+		t.Errorf("Expected the classes and the total, got\n%s", b.String())
+	}
+}
+
+func TestReadCostModel(t *testing.T) {
+	m, err := ReadCostModel(strings.NewReader(`{"cycles": {"div": 20}, "memory": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Cycles["div"] != 20 || m.Cycles["mul"] != DefaultCostModel().Cycles["mul"] || m.Memory != 2 {
+		t.Errorf("Expected the division and the memory over the default model, got %+v", m)
+	}
+	for _, invalid := range []string{`{"cycles": {"fma": 1}}`, `{"cycles": {"add": -1}}`, `{"memroy": 1}`, `[`} {
+		if _, err := ReadCostModel(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+}
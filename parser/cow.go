@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"maps"
+	"slices"
+)
+
+// TableCopy is a copy-on-write copy of a table, made by LRTable.CopyOnWrite.
+// It shares the rows of the states with the table until it writes one, so
+// that a speculative change to a table built once, such as resolving a
+// conflict another way, costs the rows it touches and leaves the table alone.
+type TableCopy struct {
+	actions cowRows[Terminal, Action]
+	gotos   cowRows[Symbol, int]
+
+	ShiftReduceConflicts  int
+	ReduceReduceConflicts int
+	Conflicts             []Conflict
+}
+
+// cowRows are the rows of a table, shared with the table copied until written.
+type cowRows[K comparable, V any] struct {
+	rows  map[int]map[K]V
+	owned map[int]bool // the rows copied already
+}
+
+func newCowRows[K comparable, V any](rows map[int]map[K]V) cowRows[K, V] {
+	return cowRows[K, V]{rows: maps.Clone(rows), owned: map[int]bool{}}
+}
+
+// own copies the row of the state unless it is copied already, and returns
+// the rows for writing that row.
+func (c *cowRows[K, V]) own(state int) map[int]map[K]V {
+	if !c.owned[state] {
+		if row, ok := c.rows[state]; ok {
+			c.rows[state] = maps.Clone(row)
+		}
+		c.owned[state] = true
+	}
+	return c.rows
+}
+
+// CopyOnWrite returns a copy of the table which copies the row of a state
+// the first time it writes it. The table must not be written while the copy
+// is in use, as the rows it shares would change under it.
+func (t *LRTable) CopyOnWrite() *TableCopy {
+	return &TableCopy{
+		actions:               newCowRows(t.ActionTable),
+		gotos:                 newCowRows(t.GotoTable),
+		ShiftReduceConflicts:  t.ShiftReduceConflicts,
+		ReduceReduceConflicts: t.ReduceReduceConflicts,
+		Conflicts:             slices.Clone(t.Conflicts),
+	}
+}
+
+// Action returns the action of the state on the terminal.
+func (c *TableCopy) Action(state int, terminal Terminal) (Action, bool) {
+	action, ok := c.actions.rows[state][terminal]
+	return action, ok
+}
+
+// Goto returns the state the state enters on the symbol.
+func (c *TableCopy) Goto(state int, symbol Symbol) (int, bool) {
+	next, ok := c.gotos.rows[state][symbol]
+	return next, ok
+}
+
+// RegisterAction registers the action as ActionTable.Register does, in the
+// copy alone.
+func (c *TableCopy) RegisterAction(state int, action Action, terminal Terminal) error {
+	return ActionTable(c.actions.own(state)).Register(state, action, terminal)
+}
+
+// RegisterGoto registers the goto as GotoTable.Register does, in the copy alone.
+func (c *TableCopy) RegisterGoto(state, next int, symbol Symbol) error {
+	return GotoTable(c.gotos.own(state)).Register(state, next, symbol)
+}
+
+// RemoveAction removes the action of the state on the terminal, from the
+// copy alone.
+func (c *TableCopy) RemoveAction(state int, terminal Terminal) {
+	if _, ok := c.Action(state, terminal); ok {
+		delete(c.actions.own(state)[state], terminal)
+	}
+}
+
+// Table returns the copy as a table of its own, a deep copy sharing nothing
+// with the table it was made from.
+func (c *TableCopy) Table() *LRTable {
+	return &LRTable{
+		ActionTable:           ActionTable(c.actions.rows).Copy(),
+		GotoTable:             GotoTable(c.gotos.rows).Copy(),
+		ShiftReduceConflicts:  c.ShiftReduceConflicts,
+		ReduceReduceConflicts: c.ReduceReduceConflicts,
+		Conflicts:             slices.Clone(c.Conflicts),
+	}
+}
@@ -0,0 +1,54 @@
+package parser_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestLRTable_Copy(t *testing.T) {
+	p := &Parser{Grammar: &grammars[0]}
+	p.BuildFirstSet()
+	p.BuildTable()
+	before := p.Table.Copy()
+
+	c := p.Table.Copy()
+	_ = c.ActionTable.Register(0, Action{Type: SHIFT, Number: 99}, "=")
+	_ = c.GotoTable.Register(0, 99, "R")
+	if !reflect.DeepEqual(p.Table, before) {
+		t.Errorf("Expected writing the rows of a deep copy to leave the table alone")
+	}
+
+	cow := p.Table.CopyOnWrite()
+	if err := cow.RegisterAction(0, Action{Type: REDUCE, Number: 1}, "id"); err == nil {
+		t.Errorf("Expected the conflict with the shift on id to be reported")
+	}
+	_ = cow.RegisterGoto(0, 99, "R")
+	cow.RemoveAction(0, "*")
+	if !reflect.DeepEqual(p.Table, before) {
+		t.Errorf("Expected writing a copy on write to leave the table alone")
+	}
+	if _, ok := cow.Action(0, "*"); ok {
+		t.Errorf("Expected the action on * to be removed from the copy")
+	}
+	if next, ok := cow.Goto(0, "R"); !ok || next != 99 {
+		t.Errorf("Expected the copy to enter 99 on R, got %d", next)
+	}
+	for state, actions := range p.Table.ActionTable {
+		for terminal, action := range actions {
+			if got, ok := cow.Action(state, terminal); (state != 0 || terminal != "*") && (!ok || got != action) {
+				t.Errorf("Expected the copy to read %v of the table in state %d on %s, got %v", action, state, terminal, got)
+			}
+		}
+	}
+
+	table := cow.Table()
+	if _, ok := table.ActionTable[0]["*"]; ok || table.GotoTable[0]["R"] != 99 {
+		t.Errorf("Expected the table of the copy to hold its changes, got %v", table.ActionTable[0])
+	}
+	table.ActionTable[1]["$"] = Action{Type: ERROR}
+	if !reflect.DeepEqual(p.Table, before) {
+		t.Errorf("Expected the table of the copy to share no row with the table")
+	}
+}
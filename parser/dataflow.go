@@ -0,0 +1,346 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	. "app/utils/collections"
+)
+
+// Dataflow is a dataflow problem over the instructions of three-address code,
+// solved by iterating the transfer function of every instruction until the
+// facts stop changing.
+type Dataflow[T comparable] struct {
+	Forward bool // facts flow from an instruction to its successors, otherwise to its predecessors
+	Must    bool // facts must hold on every path, met by intersection rather than union
+
+	Boundary Set[T] // facts on entry to the code, or on exit for backward problems
+	Universe Set[T] // all the facts, the initial value of must problems
+
+	// Transfer computes the facts after the instruction from the ones before
+	// it, in the direction of the problem.
+	Transfer func(i int, facts Set[T]) Set[T]
+}
+
+// Solve returns the facts holding before and after each instruction.
+func (d *Dataflow[T]) Solve(code []string) (in, out []Set[T]) {
+	succ := successors(code)
+	pred := make([][]int, len(code))
+	for i, next := range succ {
+		for _, j := range next {
+			pred[j] = append(pred[j], i)
+		}
+	}
+	// the instructions the facts come from, and the ones the boundary reaches
+	from, boundary := pred, func(i int) bool { return i == 0 }
+	order := make([]int, len(code))
+	for i := range order {
+		order[i] = i
+	}
+	if !d.Forward {
+		from = succ
+		boundary = func(i int) bool { return i == len(code)-1 && !strings.HasPrefix(code[i], "goto ") }
+		slices.Reverse(order)
+	}
+
+	before := make([]Set[T], len(code))
+	after := make([]Set[T], len(code))
+	for i := range code {
+		before[i] = NewSet[T]()
+		if d.Must {
+			after[i] = d.Universe.Copy()
+		} else {
+			after[i] = NewSet[T]()
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, i := range order {
+			var facts Set[T]
+			meet := func(s Set[T]) {
+				if facts == nil {
+					facts = s.Copy()
+				} else if d.Must {
+					facts = facts.Intersection(s)
+				} else {
+					facts = facts.Union(s)
+				}
+			}
+			if boundary(i) {
+				meet(d.Boundary)
+			}
+			for _, j := range from[i] {
+				meet(after[j])
+			}
+			if facts == nil {
+				facts = NewSet[T]()
+			}
+			before[i] = facts
+			if next := d.Transfer(i, facts); !next.Equal(after[i]) {
+				after[i] = next
+				changed = true
+			}
+		}
+	}
+	if d.Forward {
+		return before, after
+	}
+	return after, before
+}
+
+// Definition is an instruction writing a whole variable. The value a variable
+// holds on entry to the code is a definition at line -1.
+type Definition struct {
+	Line     int
+	Variable string
+}
+
+func (d Definition) String() string {
+	if d.Line < 0 {
+		return fmt.Sprintf("%s@entry", d.Variable)
+	}
+	return fmt.Sprintf("%s@%d", d.Variable, d.Line)
+}
+
+// variables returns the variables the text of an operand reads: names and
+// temporaries, with the offset of an element dropped.
+func variables(text string) []string {
+	var result []string
+	for _, operand := range operandPattern.FindAllString(text, -1) {
+		if strings.HasPrefix(operand, `"`) {
+			continue
+		}
+		if strings.HasPrefix(operand, "$(") {
+			result = append(result, temps(operand)...)
+			continue
+		}
+		name, _, _ := strings.Cut(operand, "[")
+		if slices.Contains(opcodes, name) || name == "true" || name == "false" {
+			continue
+		}
+		result = append(result, name)
+	}
+	return result
+}
+
+// definitionOf returns the variable the instruction writes as a whole, stores
+// into elements of arrays do not count.
+func definitionOf(line string) (string, bool) {
+	if isLabel(line) || strings.HasPrefix(line, "if ") {
+		return "", false
+	}
+	dist, _, ok := strings.Cut(line, " = ")
+	if !ok {
+		return "", false
+	}
+	if isVariable(dist) {
+		return dist, true
+	}
+	return "", false
+}
+
+// isVariable checks if the operand is a single variable.
+func isVariable(operand string) bool {
+	v := variables(operand)
+	return len(v) == 1 && v[0] == operand
+}
+
+// usesOf returns the variables the instruction reads.
+func usesOf(line string) []string {
+	if _, _, ok := lifetimeOf(line); ok || isLabel(line) || strings.HasPrefix(line, "goto ") {
+		return nil
+	}
+	if i := strings.LastIndex(line, " goto "); i >= 0 {
+		line = line[:i]
+	}
+	dist, value, ok := strings.Cut(line, " = ")
+	if !ok || strings.HasPrefix(line, "if ") {
+		dist, value = "", line
+	}
+	// the callee of a direct call is a routine, not a variable
+	if rest, ok := strings.CutPrefix(value, "call "); ok {
+		_, value, _ = strings.Cut(rest, ",")
+	}
+	if _, ok := definitionOf(line); ok {
+		return variables(value)
+	}
+	return append(variables(dist), variables(value)...)
+}
+
+// Chains are the def-use chains of three-address code, with the reaching
+// definitions they are computed from.
+type Chains struct {
+	Reaching []Set[Definition]        // definitions reaching each instruction
+	Uses     map[int][]int            // instructions reading the value of each definition
+	Defs     map[int]map[string][]int // definitions of each variable read by each instruction
+}
+
+// ReachingDefinitions returns the definitions reaching each instruction, that
+// is the ones the value of a variable may come from. Every variable read or
+// written by the code is also defined on entry.
+func ReachingDefinitions(code []string) []Set[Definition] {
+	entry := NewSet[Definition]()
+	for _, line := range code {
+		if v, ok := definitionOf(line); ok {
+			entry.Add(Definition{Line: -1, Variable: v})
+		}
+		for _, v := range usesOf(line) {
+			entry.Add(Definition{Line: -1, Variable: v})
+		}
+	}
+	d := &Dataflow[Definition]{
+		Forward:  true,
+		Boundary: entry,
+		Transfer: func(i int, in Set[Definition]) Set[Definition] {
+			v, ok := definitionOf(code[i])
+			if !ok {
+				return in.Copy()
+			}
+			out := in.Filter(func(d Definition) bool { return d.Variable != v })
+			return out.Add(Definition{Line: i, Variable: v})
+		},
+	}
+	in, _ := d.Solve(code)
+	return in
+}
+
+// DefUseChains links the definitions of the code to the instructions reading
+// them, and back.
+func DefUseChains(code []string) *Chains {
+	c := &Chains{
+		Reaching: ReachingDefinitions(code),
+		Uses:     map[int][]int{},
+		Defs:     map[int]map[string][]int{},
+	}
+	for i, line := range code {
+		for _, v := range usesOf(line) {
+			if c.Defs[i] == nil {
+				c.Defs[i] = map[string][]int{}
+			}
+			if _, ok := c.Defs[i][v]; ok {
+				continue
+			}
+			defs := []int{}
+			for d := range c.Reaching[i] {
+				if d.Variable == v {
+					defs = append(defs, d.Line)
+				}
+			}
+			slices.Sort(defs)
+			c.Defs[i][v] = defs
+			for _, d := range defs {
+				if d >= 0 {
+					c.Uses[d] = append(c.Uses[d], i)
+				}
+			}
+		}
+	}
+	return c
+}
+
+// Uninitialized returns the local variables that may be read before they are
+// assigned, that is the ones whose value on entry reaches a use. Locals
+// sharing a name with a global are skipped, the code does not tell them apart.
+func (w *Walker) Uninitialized() []*SymbolTableItem {
+	globals := map[string]bool{}
+	for _, scope := range w.SymbolTable.LegacyScopes {
+		if scope.Level <= 1 {
+			for name := range scope.Items {
+				globals[name] = true
+			}
+		}
+	}
+	locals := map[string]*SymbolTableItem{}
+	for _, item := range w.Locals() {
+		if _, ok := locals[item.Variable]; !ok && item.Type == SymbolTableItemTypeVariable && !globals[item.Variable] {
+			locals[item.Variable] = item
+		}
+	}
+
+	var result []*SymbolTableItem
+	chains := DefUseChains(w.ThreeAddress)
+	for i := range w.ThreeAddress {
+		for v, defs := range chains.Defs[i] {
+			if item, ok := locals[v]; ok && slices.Contains(defs, -1) {
+				result = append(result, item)
+				delete(locals, v)
+			}
+		}
+	}
+	slices.SortFunc(result, func(a, b *SymbolTableItem) int { return a.Address - b.Address })
+	return result
+}
+
+// Expression is the value of an operation, held by the variable the
+// instruction computing it writes.
+type Expression struct {
+	Variable string
+	Value    string
+}
+
+// expressionOf returns the expression the instruction computes, in canonical
+// form. Calls and copies are not expressions, nor are operations overwriting
+// their operands.
+func expressionOf(line string) (Expression, bool) {
+	v, ok := definitionOf(line)
+	if !ok {
+		return Expression{}, false
+	}
+	_, value, _ := strings.Cut(line, " = ")
+	fields := strings.Fields(value)
+	if len(fields) < 2 || fields[0] == "call" || fields[0] == "icall" || slices.Contains(variables(value), v) {
+		return Expression{}, false
+	}
+	return Expression{Variable: v, Value: Canonical(value)}, true
+}
+
+// writtenBy returns the variable the instruction writes, as a whole or into
+// one of its elements.
+func writtenBy(line string) string {
+	if v, ok := definitionOf(line); ok {
+		return v
+	}
+	if isLabel(line) || strings.HasPrefix(line, "if ") {
+		return ""
+	}
+	dist, _, ok := strings.Cut(line, " = ")
+	if !ok {
+		return ""
+	}
+	if v := variables(dist); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// AvailableExpressions returns the expressions available before each
+// instruction, those computed on every path leading to it with neither their
+// operands nor the variable holding them written since.
+func AvailableExpressions(code []string) []Set[Expression] {
+	universe := NewSet[Expression]()
+	for _, line := range code {
+		if e, ok := expressionOf(line); ok {
+			universe.Add(e)
+		}
+	}
+	d := &Dataflow[Expression]{
+		Forward:  true,
+		Must:     true,
+		Universe: universe,
+		Transfer: func(i int, in Set[Expression]) Set[Expression] {
+			out := in.Copy()
+			if v := writtenBy(code[i]); v != "" {
+				out = in.Filter(func(e Expression) bool {
+					return e.Variable != v && !slices.Contains(variables(e.Value), v)
+				})
+			}
+			if e, ok := expressionOf(code[i]); ok {
+				out.Add(e)
+			}
+			return out
+		},
+	}
+	in, _ := d.Solve(code)
+	return in
+}
@@ -0,0 +1,118 @@
+package parser_test
+
+import (
+	"slices"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestDefUseChains(t *testing.T) {
+	code := []string{
+		"a = 1",
+		"if b < 0 goto L_if_0",
+		"a = 2",
+		"L_if_0:",
+		"$(0x10000001) = a mod 2",
+		"param $(0x10000001)",
+		"call print_int, 1",
+	}
+	chains := DefUseChains(code)
+	if defs := chains.Defs[4]["a"]; !slices.Equal(defs, []int{0, 2}) {
+		t.Errorf("Expected a to be defined at 0 and 2, got %v", defs)
+	}
+	if defs := chains.Defs[1]["b"]; !slices.Equal(defs, []int{-1}) {
+		t.Errorf("Expected b to come from the entry, got %v", defs)
+	}
+	if uses := chains.Uses[4]; !slices.Equal(uses, []int{5}) {
+		t.Errorf("Expected the temporary to be read by the param, got %v", uses)
+	}
+	if _, ok := chains.Defs[6]["print_int"]; ok {
+		t.Errorf("Expected the callee not to be read as a variable")
+	}
+	if !chains.Reaching[3].Contains(Definition{Line: 2, Variable: "a"}) || chains.Reaching[2].Contains(Definition{Line: 2, Variable: "a"}) {
+		t.Errorf("Expected a@2 to reach the label only, got %v and %v", chains.Reaching[2], chains.Reaching[3])
+	}
+
+	// the definition in the loop reaches its head again
+	loop := []string{"i = 0", "L_loop_0:", "i = i mod 2", "if i < 9 goto L_loop_0", "c = i"}
+	if defs := DefUseChains(loop).Defs[2]["i"]; !slices.Equal(defs, []int{0, 2}) {
+		t.Errorf("Expected i to be defined at 0 and 2, got %v", defs)
+	}
+}
+
+func TestWalker_Uninitialized(t *testing.T) {
+	w := parseSource(t, "{ int g; { int a, b, c; b = a; c = g; a = 1; } { int d; d = 1; c = d; } }")
+	got := []string{}
+	for _, item := range w.Uninitialized() {
+		got = append(got, item.Variable)
+	}
+	if !slices.Equal(got, []string{"a"}) {
+		t.Errorf("Expected only a to be read before initialization, got %v", got)
+	}
+}
+
+func TestPropagateConstants(t *testing.T) {
+	code := []string{
+		"a = 1",
+		"b = a",
+		"param &a",
+		"param b",
+		"if b < 2 goto L_if_0",
+		"c = 3",
+		"L_if_0:",
+		"d = c",
+		"i = 0",
+		"L_loop_0:",
+		"i = i mod 2",
+		"if i < 9 goto L_loop_0",
+	}
+	expected := []string{
+		"a = 1",
+		"b = 1",
+		"param &a",
+		"param 1",
+		"if 1 < 2 goto L_if_0",
+		"c = 3",
+		"L_if_0:",
+		"d = c",
+		"i = 0",
+		"L_loop_0:",
+		"i = i mod 2",
+		"if i < 9 goto L_loop_0",
+	}
+	got := PropagateConstants(code)
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	// the condition is now constant, so the jump is taken and c = 3 never runs
+	if got := ThreadJumps(got); slices.Contains(got, "c = 3") {
+		t.Errorf("Expected the dead assignment to be dropped, got %v", got)
+	}
+}
+
+func TestAvailableExpressions(t *testing.T) {
+	code := []string{
+		"t1 = a mod 3",
+		"if a < 0 goto L_if_0",
+		"t2 = b mod 3",
+		"L_if_0:",
+		"t3 = a mod 3",
+		"t4 = b mod 3",
+		"a = 1",
+		"t5 = a mod 3",
+	}
+	available := AvailableExpressions(code)
+	if e := (Expression{Variable: "t1", Value: "a mod 3"}); !available[4].Contains(e) || available[7].Contains(e) {
+		t.Errorf("Expected a mod 3 in t1 to be available until a is written, got %v and %v", available[4], available[7])
+	}
+	if e := (Expression{Variable: "t2", Value: "b mod 3"}); available[5].Contains(e) {
+		t.Errorf("Expected b mod 3 not to be available on the jump, got %v", available[5])
+	}
+
+	expected := slices.Clone(code)
+	expected[4] = "t3 = t1"
+	if got := EliminateCommonSubexpressions(code); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+)
+
+// markLines records the source line of the instructions emitted since the
+// last call, the line the constructs reduced meanwhile end on, and their
+// span, the one of the construct on top of the stack.
+func (w *Walker) markLines(line int64) {
+	for len(w.Lines) < len(w.ThreeAddress) {
+		w.Lines = append(w.Lines, line)
+	}
+	if len(w.Spans) < len(w.ThreeAddress) {
+		var span Span
+		if top, ok := w.Tokens.Peek(); ok {
+			span = spanOf(top)
+		}
+		for len(w.Spans) < len(w.ThreeAddress) {
+			w.Spans = append(w.Spans, span)
+		}
+	}
+}
+
+// Pass is a transformation of three-address code.
+type Pass func(code []string) []string
+
+// RunPasses runs the passes over the code in order, following the source
+// line of each instruction through them with TraceLines.
+func RunPasses(code []string, lines []int64, passes ...Pass) ([]string, []int64) {
+	for _, pass := range passes {
+		next := pass(code)
+		lines = TraceLines(code, lines, next)
+		code = next
+	}
+	return code, lines
+}
+
+// Origins returns the indices of n instructions, to trace through passes
+// with RunPasses, which gives the instruction of the code passed each
+// instruction of its result comes from.
+func Origins(n int) []int64 {
+	origins := make([]int64, n)
+	for i := range origins {
+		origins[i] = int64(i)
+	}
+	return origins
+}
+
+// remap returns the values of the instructions the origins are, none for
+// the ones of no instruction.
+func remap[T any](values []T, origins []int64, none T) []T {
+	result := make([]T, len(origins))
+	for i, origin := range origins {
+		result[i] = none
+		if origin >= 0 && origin < int64(len(values)) {
+			result[i] = values[origin]
+		}
+	}
+	return result
+}
+
+// TraceLines returns the source lines of the instructions a pass turned the
+// code into. An instruction comes from the first unused one of the same text,
+// or else from the next unused one writing the same variable, such as an
+// expression replaced by a copy. Instructions made up by the pass take the
+// line of the one before them, -1 at the start of the code.
+func TraceLines(before []string, lines []int64, after []string) []int64 {
+	line := func(i int) int64 {
+		if i < len(lines) {
+			return lines[i]
+		}
+		return -1
+	}
+	same := map[string][]int{}
+	for k, b := range before {
+		same[b] = append(same[b], k)
+	}
+	used := make([]bool, len(before))
+	result := make([]int64, len(after))
+	last := -1
+	for i, instruction := range after {
+		j := -1
+		for len(same[instruction]) > 0 && j < 0 {
+			if k := same[instruction][0]; !used[k] {
+				j = k
+			}
+			same[instruction] = same[instruction][1:]
+		}
+		if dist, _, ok := strings.Cut(instruction, " = "); j < 0 && ok {
+			for k := last + 1; k < len(before); k++ {
+				if !used[k] && strings.HasPrefix(before[k], dist+" = ") {
+					j = k
+					break
+				}
+			}
+		}
+		switch {
+		case j >= 0:
+			used[j] = true
+			last = j
+			result[i] = line(j)
+		case i > 0:
+			result[i] = result[i-1]
+		default:
+			result[i] = -1
+		}
+	}
+	return result
+}
+
+// MapLines returns the source lines of the code wrapped by Apply, given the
+// ones of the code: none for the prologue, and the last line of the code for
+// the epilogue.
+func (f *Frame) MapLines(lines []int64) []int64 {
+	end := int64(-1)
+	if len(lines) > 0 {
+		end = lines[len(lines)-1]
+	}
+	return mapFrame(f, lines, -1, end)
+}
+
+// MapOrigins returns the origins of the code wrapped by Apply, given the
+// ones of the code, -1 for the prologue and the epilogue.
+func (f *Frame) MapOrigins(origins []int64) []int64 {
+	return mapFrame(f, origins, -1, -1)
+}
+
+// mapFrame returns the values of the code wrapped by Apply, given the ones
+// of the code and the ones of the prologue and the epilogue.
+func mapFrame[T any](f *Frame, values []T, prologue, epilogue T) []T {
+	n := 3 + len(f.Saved)
+	if f.Size() > 0 {
+		n++
+	}
+	result := slices.Repeat([]T{prologue}, n)
+	result = append(result, values...)
+	return append(result, slices.Repeat([]T{epilogue}, len(f.Saved)+3)...)
+}
+
+// DebugInfo is the debug information of the generated code, the sidecar the
+// VM debugger reads to show the state of the program at the source level.
+// Lines are numbered from 0 like the positions of the lexer.
+type DebugInfo struct {
+	File      string          `json:"file"`
+	Functions []DebugFunction `json:"functions"`
+	Variables []DebugVariable `json:"variables"`
+	Lines     []int64         `json:"lines"` // source line of each instruction, -1 for generated ones
+}
+
+// DebugFunction is the range of instructions [Start, End) of a function.
+type DebugFunction struct {
+	Name  string `json:"name"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// DebugVariable is a variable with where it lives. Globals and statics have
+// an absolute address in the data segment, locals an offset from fp.
+type DebugVariable struct {
+	Name      string `json:"name"`
+	Symbol    string `json:"symbol"` // the name in the emitted code, see SymbolTableItem.Symbol
+	Type      string `json:"type"`
+	Size      int    `json:"size"`
+	ArraySize int    `json:"arraySize,omitempty"`
+	Scope     int    `json:"scope"`
+	Line      int64  `json:"line"`
+	Pos       int64  `json:"pos"`
+	Address   *int   `json:"address,omitempty"`
+	Offset    *int   `json:"offset,omitempty"`
+}
+
+// NewDebugInfo describes the code generated for the file, laid out in the
+// frame, with the source line of each of its instructions.
+func NewDebugInfo(file string, w *Walker, frame *Frame, code []string, lines []int64) *DebugInfo {
+	d := &DebugInfo{
+		File:      file,
+		Functions: []DebugFunction{{Name: frame.Function, Start: 0, End: len(code)}},
+		Variables: []DebugVariable{},
+		Lines:     lines,
+	}
+	for _, scope := range w.SymbolTable.LegacyScopes {
+		if scope.Level < 1 {
+			continue
+		}
+		items := slices.DeleteFunc(scope.SortedItems(), func(item *SymbolTableItem) bool {
+			return item.Type != SymbolTableItemTypeVariable && item.Type != SymbolTableItemTypeArray
+		})
+		for _, item := range items {
+			v := DebugVariable{
+				Name:   item.Qualified(),
+				Symbol: item.Symbol(),
+				Type:   item.UnderlyingType,
+				Size:   item.VariableSize,
+				Scope:  scope.ID,
+				Line:   item.Line,
+				Pos:    item.Pos,
+			}
+			if item.Type == SymbolTableItemTypeArray {
+				v.ArraySize = item.ArraySize
+			}
+			if offset, ok := frame.Offsets[item.Variable]; ok && scope.Level > 1 && !item.Static {
+				v.Offset = &offset
+			} else {
+				address := item.Address
+				v.Address = &address
+			}
+			d.Variables = append(d.Variables, v)
+		}
+	}
+	return d
+}
+
+// WriteJSON writes the debug information to the writer.
+func (d *DebugInfo) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
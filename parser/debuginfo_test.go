@@ -0,0 +1,75 @@
+package parser_test
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestTraceLines(t *testing.T) {
+	before := []string{"a = 1", "$(0x10000001) = a mod 3", "goto L_x_0", "L_x_0:", "b = $(0x10000001)"}
+	lines := []int64{1, 2, 2, 3, 3}
+	after := []string{"a = 1", "$(0x10000001) = b", "push fp", "b = $(0x10000001)"}
+	if got := TraceLines(before, lines, after); !slices.Equal(got, []int64{1, 2, 2, 3}) {
+		t.Errorf("Expected the lines to follow the instructions, got %v", got)
+	}
+	if got := TraceLines(before, lines[:1], []string{"main:", "b = $(0x10000001)"}); !slices.Equal(got, []int64{-1, -1}) {
+		t.Errorf("Expected -1 for instructions without a line, got %v", got)
+	}
+}
+
+func TestDebugInfo(t *testing.T) {
+	w := parseSource(t, "{ int g;\n{ int a;\nstatic int s;\na = readint();\ng = a % 3;\ns = abs(g); } }")
+	if len(w.Lines) != len(w.ThreeAddress) {
+		t.Fatalf("Expected a line for each instruction, got %v for %v", w.Lines, w.ThreeAddress)
+	}
+	code, lines := RunPasses(w.ThreeAddress, w.Lines, EliminateCommonSubexpressions, Peephole)
+	frame := NewFrame("main", w.Locals(), code, Allocation{})
+	applied := frame.Apply(code)
+	info := NewDebugInfo("test.in", w, frame, applied, frame.MapLines(lines))
+	if len(info.Lines) != len(applied) || info.Functions[0] != (DebugFunction{Name: "main", Start: 0, End: len(applied)}) {
+		t.Fatalf("Expected the lines and the range of main to cover the code, got %v and %v", info.Lines, info.Functions)
+	}
+	for i, instruction := range applied {
+		expected := int64(-1)
+		switch {
+		case strings.Contains(instruction, "read_int"):
+			expected = 3
+		case strings.Contains(instruction, "mod"):
+			expected = 4
+		case strings.HasPrefix(instruction, "if "):
+			expected = 5
+		}
+		if expected >= 0 && info.Lines[i] != expected {
+			t.Errorf("Expected %q at line %d, got %d", instruction, expected, info.Lines[i])
+		}
+	}
+	if info.Lines[0] != -1 {
+		t.Errorf("Expected the prologue to have no line, got %d", info.Lines[0])
+	}
+
+	var b strings.Builder
+	if err := info.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Variables []map[string]any `json:"variables"`
+	}
+	if err := json.Unmarshal([]byte(b.String()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	where := map[string]string{}
+	for _, v := range decoded.Variables {
+		if _, ok := v["offset"]; ok {
+			where[v["name"].(string)] = "frame"
+		} else if _, ok := v["address"]; ok {
+			where[v["name"].(string)] = "data"
+		}
+	}
+	if where["g"] != "data" || where["s"] != "data" || where["a"] != "frame" {
+		t.Errorf("Expected globals and statics in the data segment and locals in the frame, got %v", where)
+	}
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Doc is the documentation of a global declaration, written in /// comments
+// right before it.
+type Doc struct {
+	Names       []string
+	Declaration string // source of the declaration, e.g. "int a, b[10];"
+	Comment     string
+	Line        int64
+}
+
+// DocComment returns the text of the /// lines among the comments, with the
+// markers removed, or "" if there are none.
+func DocComment(comments string) string {
+	lines := []string{}
+	for _, line := range strings.Split(comments, "\n") {
+		if text, ok := strings.CutPrefix(strings.TrimSpace(line), "///"); ok {
+			lines = append(lines, strings.TrimPrefix(text, " "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NewDoc returns the documentation of the decl node declaring the declarators.
+func NewDoc(decl *ASTNode, declarators []*Declarator, comment string) Doc {
+	doc := Doc{
+		Declaration: strings.TrimSuffix(decl.raw, " ;") + ";",
+		Comment:     comment,
+	}
+	for _, d := range declarators {
+		doc.Names = append(doc.Names, d.Name)
+	}
+	if len(declarators) > 0 {
+		doc.Line = declarators[0].Token.Line
+	}
+	return doc
+}
+
+// WriteDocs writes the documentation as a Markdown summary.
+func WriteDocs(w io.Writer, title string, docs []Doc) error {
+	if _, err := fmt.Fprintf(w, "# %s\n", title); err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		_, err := fmt.Fprintf(w, "\n## %s\n\n```c\n%s\n```\n\n%s\n", strings.Join(doc.Names, ", "), doc.Declaration, doc.Comment)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"app/utils/report"
+)
+
+// Kernel returns the kernel items of the state, those its closure is computed
+// from: the items with the dot past the start of the body, and the items of
+// the augmented production of the grammar.
+func (state *State) Kernel(grammar *Grammar) LR1Items {
+	var kernel LR1Items
+	for _, item := range state.Items {
+		if item.Dot > 0 || item.Production.Equals(grammar.AugmentedProduction) {
+			kernel = append(kernel, item)
+		}
+	}
+	return kernel
+}
+
+// ExportDOT writes the LR(1) automaton in the DOT language of Graphviz: a
+// node per state listing its kernel items, and an edge per transition
+// labelled with its symbol. The states reducing are filled, the one
+// accepting is green, and those with conflicts in the table, when it is
+// built, are outlined in red.
+func (p *Parser) ExportDOT(w io.Writer) error {
+	p.EnsureStates()
+	conflicts := map[int]bool{}
+	if p.Table != nil {
+		for _, c := range p.Table.Conflicts {
+			conflicts[c.State] = true
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph LR1 {\n")
+	b.WriteString("    rankdir=LR;\n")
+	b.WriteString("    node [shape=box, fontname=\"monospace\"];\n")
+	for _, state := range p.States {
+		label := "I" + fmt.Sprint(state.Index) + "\\n"
+		for _, line := range formatItems(state.Kernel(p.Grammar)) {
+			label += escapeDOT(line) + "\\l"
+		}
+		attributes := ""
+		switch {
+		case state.accepts(p.Grammar):
+			attributes += `, style=filled, fillcolor="palegreen"`
+		case state.reduces(p.Grammar):
+			attributes += `, style=filled, fillcolor="lightyellow"`
+		}
+		if conflicts[state.Index] {
+			attributes += `, color="red", penwidth=2`
+		}
+		fmt.Fprintf(&b, "    I%d [label=\"%s\"%s];\n", state.Index, label, attributes)
+	}
+	for _, state := range p.States {
+		symbols := make([]Symbol, 0, len(state.Transitions))
+		for symbol := range state.Transitions {
+			symbols = append(symbols, symbol)
+		}
+		report.SortSymbols(symbols)
+		for _, symbol := range symbols {
+			fmt.Fprintf(&b, "    I%d -> I%d [label=\"%s\"];\n", state.Index, state.Transitions[symbol].Index, escapeDOT(string(symbol)))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// reduces returns whether the state has an item to reduce.
+func (state *State) reduces(grammar *Grammar) bool {
+	return slices.ContainsFunc(state.Items, func(item LR1Item) bool {
+		return (item.Dot == len(item.Production.Body) || item.Production.Body[item.Dot].IsEpsilon()) &&
+			!item.Production.Equals(grammar.AugmentedProduction)
+	})
+}
+
+// accepts returns whether the state accepts at the end of the input.
+func (state *State) accepts(grammar *Grammar) bool {
+	return slices.ContainsFunc(state.Items, func(item LR1Item) bool {
+		return item.Dot == len(item.Production.Body) && item.Lookahead == TERMINATE &&
+			item.Production.Equals(grammar.AugmentedProduction)
+	})
+}
+
+// escapeDOT escapes the text for a quoted string of DOT.
+func escapeDOT(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
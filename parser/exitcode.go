@@ -0,0 +1,37 @@
+package parser
+
+import "app/utils"
+
+// The exit codes of the compiler, one per category of the errors, so that a
+// script can tell how a program failed without reading the log. 2 is left
+// to the flags and to the panics of the Go runtime.
+const (
+	ExitOK       = 0
+	ExitInternal = 1
+	ExitLexical  = 3
+	ExitSyntax   = 4
+	ExitSemantic = 5
+)
+
+// ExitCode returns the exit code of the errors counted: ExitOK if there is
+// none, the code of the category coming first in the order internal,
+// lexical, syntax, semantic otherwise, as an earlier phase failing says the
+// more about the program.
+func (c ErrorCounts) ExitCode() int {
+	for _, n := range []utils.Pair[int, int]{
+		utils.MakePair(c.Internal, ExitInternal),
+		utils.MakePair(c.Lexical, ExitLexical),
+		utils.MakePair(c.Syntax, ExitSyntax),
+		utils.MakePair(c.Semantic, ExitSemantic),
+	} {
+		if count, code := n.Unpack(); count > 0 {
+			return code
+		}
+	}
+	return ExitOK
+}
+
+// ExitCode returns the exit code of the compilation.
+func (s DiagnosticsSummary) ExitCode() int {
+	return s.Categories.ExitCode()
+}
@@ -0,0 +1,42 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestDiagnosticsSummary_ExitCode(t *testing.T) {
+	tables := sharedParser().Tables()
+	for _, c := range []struct {
+		src        string
+		limits     Limits
+		categories ErrorCounts
+		code       int
+	}{
+		{src: "{\n    int a;\n    a = 1;\n}\n", code: ExitOK},
+		{src: "{\n    const int a;\n    const int b;\n}\n", categories: ErrorCounts{Semantic: 2}, code: ExitSemantic},
+		{src: "{\n    const int a;\n    const int b;\n}\n", limits: Limits{MaxErrors: 1}, categories: ErrorCounts{Semantic: 2}, code: ExitSemantic},
+		{src: "{\n    a = 1;\n}\n", categories: ErrorCounts{Semantic: 1}, code: ExitSemantic},
+		{src: "{\n    int a;\n    a = b + 1;\n}\n", categories: ErrorCounts{Semantic: 1}, code: ExitSemantic},
+		{src: "{\n    const int a;\n    a = ;\n}\n", categories: ErrorCounts{Syntax: 1, Semantic: 1}, code: ExitSyntax},
+		{src: "{\n    int a;\n    a = 1 @ 2;\n}\n", categories: ErrorCounts{Lexical: 1}, code: ExitLexical},
+		{src: "{\n    int a;\n    a = 1;\n}\n", limits: Limits{MaxSteps: 3}, categories: ErrorCounts{Internal: 1}, code: ExitInternal},
+	} {
+		tables.Limits = c.limits
+		result, err := Compile(Options{Source: strings.NewReader(c.src), Tables: tables})
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		summary := result.Summary()
+		if summary.Categories != c.categories || summary.ExitCode() != c.code {
+			t.Errorf("%q with %+v: expected %+v and exit code %d, got %+v and %d", c.src, c.limits, c.categories, c.code, summary.Categories, summary.ExitCode())
+		}
+	}
+
+	// the earliest phase failing decides across files
+	if code := (ErrorCounts{Syntax: 2, Semantic: 1, Lexical: 1}).ExitCode(); code != ExitLexical {
+		t.Errorf("Expected the lexical error to decide, got %d", code)
+	}
+}
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"app/lexer"
+	. "app/utils/collections"
+)
+
+// FixIt is a token to insert for the parse to go on past a syntax error,
+// right after the token before the error. Line and Pos are where that token
+// ends, Token the index of the token the text goes before among those read.
+type FixIt struct {
+	Text      string
+	Line, Pos int64
+	Token     int
+}
+
+func (f FixIt) String() string {
+	return fmt.Sprintf("insert %s at line %d, pos %d", f.Text, f.Line, f.Pos)
+}
+
+// Apply returns the source with the text inserted right after the token
+// before Token, separated from it by nothing.
+func (f FixIt) Apply(source string) (string, error) {
+	spans := lexer.NewDocument(source).Spans
+	if f.Token < 0 || f.Token > len(spans) {
+		return source, fmt.Errorf("token %d is past the end of the source", f.Token)
+	}
+	offset := 0
+	if f.Token > 0 {
+		offset = spans[f.Token-1].End
+	}
+	return source[:offset] + f.Text + source[offset:], nil
+}
+
+// fixPreference is the order the insertions are tried in, the separators and
+// the closing brackets a program most often misses first.
+var fixPreference = []Terminal{";", ")", "]", "}", ","}
+
+// insertable checks if the terminal is punctuation or an operator, standing
+// for a text of its own; the keywords such as true are values the program
+// meant, which a fix-it cannot guess, and id and num stand for classes of
+// tokens.
+func (w *Walker) insertable(terminal Terminal) bool {
+	info, _ := w.symbols().Lookup(Symbol(terminal))
+	return info.Category == PunctuationSymbol || info.Category == OperatorSymbol
+}
+
+// fix returns the single terminal that, inserted before the symbol, lets the
+// walker shift the symbol, trying the ones expected in the order of
+// fixPreference then in order. The parse is simulated on the states alone,
+// the walker is left as it is.
+func (w *Walker) fix(entry *ErrorEntry) (Terminal, bool) {
+	candidates := slices.Clone(entry.Expected)
+	slices.SortStableFunc(candidates, func(a, b Terminal) int {
+		i, j := slices.Index(fixPreference, a), slices.Index(fixPreference, b)
+		if i < 0 {
+			i = len(fixPreference)
+		}
+		if j < 0 {
+			j = len(fixPreference)
+		}
+		return i - j
+	})
+	for _, terminal := range candidates {
+		if w.insertable(terminal) && w.Table.accepts(w.Grammar, w.States.Copy(), terminal, entry.Terminal) {
+			return terminal, true
+		}
+	}
+	return "", false
+}
+
+// accepts checks if the terminals can be read in order from the states,
+// shifted or accepted after the reductions they trigger. The states are
+// changed as the parse would.
+func (t *LRTable) accepts(grammar *Grammar, states Stack[int], terminals ...Terminal) bool {
+	for _, terminal := range terminals {
+		for shifted := false; !shifted; {
+			state, _ := states.Peek()
+			action, ok := t.Lookup(state, terminal)
+			switch {
+			case !ok:
+				return false
+			case action.Type == ACCEPT:
+				return true
+			case action.Type == SHIFT:
+				states.Push(action.Number)
+				shifted = true
+			case action.Type == REDUCE:
+				production := grammar.Productions[action.Number]
+				for _, symbol := range production.Body {
+					if symbol != EPSILON {
+						states.Pop()
+					}
+				}
+				state, _ = states.Peek()
+				next, ok := t.Goto(state, production.Head)
+				if !ok {
+					return false
+				}
+				states.Push(next)
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// MaxFixes is the number of tokens Repair inserts at most.
+const MaxFixes = 32
+
+// Repair applies the fix-its of the syntax errors of the source one after
+// the other, parsing it again after each, until it parses or an error has
+// no fix-it. It returns the source repaired as far as it goes and the fixes
+// applied, in order; the positions of each are in the source as the fixes
+// before it left it.
+func Repair(source string, tables *ParserTables) (string, []FixIt, error) {
+	var fixes []FixIt
+	for range MaxFixes {
+		result, err := Compile(Options{Source: strings.NewReader(source), Tables: tables})
+		if err != nil {
+			return source, fixes, err
+		}
+		d, fatal := result.Fatal()
+		if !fatal || d.Fix == nil {
+			return source, fixes, nil
+		}
+		if source, err = d.Fix.Apply(source); err != nil {
+			return source, fixes, err
+		}
+		fixes = append(fixes, *d.Fix)
+	}
+	return source, fixes, fmt.Errorf("more than %d tokens missing", MaxFixes)
+}
@@ -0,0 +1,70 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestCompile_FixIt(t *testing.T) {
+	result, err := Compile(Options{
+		Source: strings.NewReader("{\n    int a, b;\n    a = 1\n    b = a;\n}\n"),
+		Tables: sharedParser().Tables(),
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	d, fatal := result.Fatal()
+	if !fatal || d.Fix == nil {
+		t.Fatalf("Expected a syntax error with a fix-it, got %v", result.Diagnostics)
+	}
+	if d.Fix.Text != ";" || d.Fix.Token != 9 || d.Fix.Line != 2 {
+		t.Errorf("Expected ; inserted before the token 9 on line 2, got %+v", *d.Fix)
+	}
+	if !strings.HasSuffix(d.Message, "; "+d.Fix.String()) {
+		t.Errorf("Expected the fix-it in the message, got %s", d.Message)
+	}
+
+	result, err = Compile(Options{Source: strings.NewReader("{\n    int a;\n    a = ;\n}\n"), Tables: sharedParser().Tables()})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if d, fatal := result.Fatal(); !fatal || d.Fix != nil {
+		t.Errorf("Expected a syntax error no single token fixes, got %v", result.Diagnostics)
+	} else if d.Line != 2 || d.Pos != 9 || !strings.HasSuffix(d.Message, ", at line 2, pos 9") {
+		t.Errorf("Expected the error at the ; it stopped at, got %+v", d)
+	}
+
+	// the lexer names the position of the literal it could not close
+	result, err = Compile(Options{Source: strings.NewReader("{\n    string s;\n    s = \"abc\n}\n"), Tables: sharedParser().Tables()})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if d, fatal := result.Fatal(); !fatal || d.Category != LexicalError || d.Line != 2 || d.Snippet != "    s = \"abc" {
+		t.Errorf("Expected the string not closed at line 2, got %v", result.Diagnostics)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	source := "{\n    int a, b;\n    a = 1\n    b = (a + 2;\n    if (a > b {\n        a = b;\n    }\n"
+	fixed, fixes, err := Repair(source, sharedParser().Tables())
+	if err != nil {
+		t.Fatalf("Repair: %v", err)
+	}
+	expected := "{\n    int a, b;\n    a = 1;\n    b = (a + 2);\n    if (a > b) {\n        a = b;\n    }}\n"
+	if fixed != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, fixed)
+	}
+	var texts []string
+	for _, fix := range fixes {
+		texts = append(texts, fix.Text)
+	}
+	if strings.Join(texts, " ") != "; ) ) }" {
+		t.Errorf("Expected ; ) ) } inserted, got %v", fixes)
+	}
+
+	if _, fixes, err := Repair("{\n    int a;\n    a = ;\n}\n", sharedParser().Tables()); err != nil || len(fixes) != 0 {
+		t.Errorf("Expected nothing repaired, got %v, %v", fixes, err)
+	}
+}
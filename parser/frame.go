@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Frame is the stack frame of a function. From the frame pointer fp down it
+// holds the saved registers, the local variables and the spilled temporaries,
+// each slot is addressed relative to fp. Globals and statics live in the data
+// segment and keep their absolute addresses.
+type Frame struct {
+	Function string
+	Offsets  map[string]int // offsets from fp of the locals and spilled temporaries
+	Saved    []string       // registers saved by the prologue, at fp-4, fp-8, ...
+
+	Locals, Temps int // bytes taken by the locals and by the temporaries
+}
+
+// Size returns the size of the frame in bytes.
+func (f *Frame) Size() int {
+	return 4*len(f.Saved) + f.Locals + f.Temps
+}
+
+// Locals returns the variables of the program that live on the stack, those
+// declared in nested blocks and not static, in the order of declaration. Of
+// the scopes pruned, only the largest local of each name is left, see
+// PruneScopes.
+func (w *Walker) Locals() []*SymbolTableItem {
+	locals := slices.Collect(maps.Values(w.archived))
+	for _, scope := range w.SymbolTable.LegacyScopes {
+		if scope.Level <= 1 {
+			continue
+		}
+		for _, item := range scope.Items {
+			if !item.Static && (item.Type == SymbolTableItemTypeVariable || item.Type == SymbolTableItemTypeArray) {
+				locals = append(locals, item)
+			}
+		}
+	}
+	slices.SortFunc(locals, func(a, b *SymbolTableItem) int { return a.Address - b.Address })
+	return locals
+}
+
+// NewFrame lays out the frame of the function running the code, with its
+// registers allocated. The three-address code names variables rather than
+// declarations, so locals of the same name in different blocks share a slot
+// large enough for each of them.
+func NewFrame(function string, locals []*SymbolTableItem, code []string, allocation Allocation) *Frame {
+	return NewSharedFrame(function, locals, code, allocation, nil)
+}
+
+// NewSharedFrame lays out the frame as NewFrame does, the locals never alive
+// at the same time by their lifetimes sharing a slot as well, each in the
+// first slot of the locals it does not interfere with.
+func NewSharedFrame(function string, locals []*SymbolTableItem, code []string, allocation Allocation, lifetimes Lifetimes) *Frame {
+	f := &Frame{Function: function, Offsets: map[string]int{}}
+	used := slices.Collect(maps.Values(allocation))
+	for _, r := range Registers {
+		if slices.Contains(used, r) {
+			f.Saved = append(f.Saved, r)
+		}
+	}
+	offset := 4 * len(f.Saved)
+
+	sizes := map[string]int{}
+	var names []string
+	for _, item := range locals {
+		size := (item.VariableSize*item.ArraySize + 3) / 4 * 4
+		if _, ok := sizes[item.Variable]; !ok {
+			names = append(names, item.Variable)
+		}
+		sizes[item.Variable] = max(sizes[item.Variable], size)
+	}
+	var slots [][]string
+	for _, name := range names {
+		i := slices.IndexFunc(slots, func(slot []string) bool {
+			return !slices.ContainsFunc(slot, func(other string) bool { return lifetimes.Interfere(name, other) })
+		})
+		if i < 0 {
+			slots = append(slots, nil)
+			i = len(slots) - 1
+		}
+		slots[i] = append(slots[i], name)
+	}
+	for _, slot := range slots {
+		size := 0
+		for _, name := range slot {
+			size = max(size, sizes[name])
+		}
+		offset += size
+		for _, name := range slot {
+			f.Offsets[name] = -offset
+		}
+		f.Locals += size
+	}
+
+	for _, line := range code {
+		for _, t := range temps(line) {
+			if _, ok := allocation[t]; ok {
+				continue
+			}
+			if _, ok := f.Offsets[t]; ok {
+				continue
+			}
+			offset += 4
+			f.Offsets[t] = -offset
+			f.Temps += 4
+		}
+	}
+	return f
+}
+
+// operandPattern matches the operands of three-address code: string
+// literals, temporaries, and names with an optional byte offset.
+var operandPattern = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|\$\(0x[0-9a-f]+\)|\b[A-Za-z_]\w*(?:\[\d+\])?`)
+
+// opcodes are the words of three-address code that are never operands.
+var opcodes = []string{"if", "goto", "param", "call", "icall", "minus", "itof", "mod", "eq", "ne", "lt", "le", "gt", "ge", "strcat", "streq", "strne"}
+
+// Apply wraps the code into the prologue and the epilogue of the frame and
+// addresses the locals and spilled temporaries relative to fp.
+func (f *Frame) Apply(code []string) []string {
+	result := []string{f.Function + ":", "push fp", "fp = sp"}
+	if size := f.Size(); size > 0 {
+		result = append(result, fmt.Sprintf("sp = sp - %d", size))
+	}
+	for i, r := range f.Saved {
+		result = append(result, fmt.Sprintf("fp[%d] = %s", -4*(i+1), r))
+	}
+	for _, line := range code {
+		if isLabel(line) {
+			result = append(result, line)
+			continue
+		}
+		result = append(result, operandPattern.ReplaceAllStringFunc(line, func(operand string) string {
+			name, index, _ := strings.Cut(strings.TrimSuffix(operand, "]"), "[")
+			if slices.Contains(opcodes, name) {
+				return operand
+			}
+			offset, ok := f.Offsets[name]
+			if !ok {
+				return operand
+			}
+			if index != "" {
+				i, _ := strconv.Atoi(index)
+				offset += i
+			}
+			return fmt.Sprintf("fp[%d]", offset)
+		}))
+	}
+	for i, r := range f.Saved {
+		result = append(result, fmt.Sprintf("%s = fp[%d]", r, -4*(i+1)))
+	}
+	return append(result, "sp = fp", "pop fp", "ret")
+}
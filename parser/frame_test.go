@@ -0,0 +1,102 @@
+package parser_test
+
+import (
+	"slices"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestFrame(t *testing.T) {
+	w := parseSource(t, "{ int g; { int a, b[2] = {a, 2}; a = g % a; } { byte a; a = 1; } }")
+	code := w.ThreeAddress
+	allocation := Allocation{}
+	frame := NewFrame("main", w.Locals(), code, allocation)
+	// a takes 4 bytes in the first block and 1 in the second, b takes 8
+	if frame.Locals != 12 || frame.Temps != 4 || frame.Size() != 16 {
+		t.Errorf("Expected 12 bytes of locals and 4 of temporaries, got %d and %d", frame.Locals, frame.Temps)
+	}
+	expected := []string{
+		"main:",
+		"push fp",
+		"fp = sp",
+		"sp = sp - 16",
+		"fp[-12] = fp[-4]",
+		"fp[-8] = 2",
+		"fp[-16] = g mod fp[-4]",
+		"fp[-4] = fp[-16]",
+		"fp[-4] = 1",
+		"sp = fp",
+		"pop fp",
+		"ret",
+	}
+	if got := frame.Apply(code); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	allocation = ColorGraph(code, Registers)
+	frame = NewFrame("main", w.Locals(), code, allocation)
+	got := frame.Apply(allocation.Apply(code))
+	if frame.Temps != 0 || !slices.Equal(frame.Saved, []string{"r0"}) || got[4] != "fp[-4] = r0" || got[len(got)-4] != "r0 = fp[-4]" {
+		t.Errorf("Expected the temporary in r0 saved by the prologue, got %v", got)
+	}
+}
+
+func TestSymbolTable_FrameOffsets(t *testing.T) {
+	st := NewSymbolTable(nil, nil)
+	st.FrameOffsets = true
+	declare := func(name string, size int, static bool) *SymbolTableItem {
+		t.Helper()
+		item := &SymbolTableItem{Variable: name, Type: SymbolTableItemTypeVariable, VariableSize: size, ArraySize: 1, Static: static}
+		if err := st.Register(item); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		return item
+	}
+	st.EnterScope()
+	g := declare("g", 4, false)
+
+	st.EnterFunctionScope()
+	a := declare("a", 8, false)
+	s := declare("s", 4, true)
+	st.EnterScope()
+	b := declare("b", 4, false)
+	c := declare("c", 1, false)
+	st.ExitScope()
+	st.EnterScope()
+	d := declare("d", 4, false)
+	st.EnterFunctionScope()
+	e := declare("e", 4, false)
+	inner := st.CurrentScope
+	st.ExitScope()
+	f := declare("f", 4, false)
+	st.ExitScope()
+	outer := st.CurrentScope
+	st.ExitScope()
+	h := declare("h", 4, false)
+
+	for _, test := range []struct {
+		item    *SymbolTableItem
+		address int
+		inFrame bool
+	}{
+		{g, InitialAddr, false},
+		{a, 0, true},
+		{s, InitialAddr + 1, false},
+		{b, 2, true},
+		{c, 3, true},
+		// the sibling block reuses the offsets of the first one
+		{d, 2, true},
+		{e, 0, true},
+		{f, 3, true},
+		{h, InitialAddr + 2, false},
+	} {
+		if test.item.Address != test.address || test.item.InFrame != test.inFrame {
+			t.Errorf("Expected %s at %#x, in the frame %v, got %#x, %v",
+				test.item.Variable, test.address, test.inFrame, test.item.Address, test.item.InFrame)
+		}
+	}
+	if outer.FrameSize != 4 || inner.FrameSize != 1 {
+		t.Errorf("Expected frames of 4 and 1 words, got %d and %d", outer.FrameSize, inner.FrameSize)
+	}
+}
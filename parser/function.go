@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"app/lexer"
+)
+
+// Param is a parameter of a function, by name and type name.
+type Param struct {
+	Name, Type string
+}
+
+func (p Param) String() string {
+	return p.Type + " " + p.Name
+}
+
+// RegisterFunction adds a function to the current scope, with its parameters
+// in order and the type of its value, void if it has none. A function takes
+// no storage, it is called at its entry label, F_ and its name.
+func (st *SymbolTable) RegisterFunction(name string, params []Param, result string) (*SymbolTableItem, error) {
+	seen := map[string]bool{}
+	for _, p := range params {
+		if seen[p.Name] {
+			return nil, fmt.Errorf("parameter %s of %s declared twice", p.Name, name)
+		}
+		seen[p.Name] = true
+		if t := lexer.ParseTypeName(p.Type); t == lexer.Unknown || t == lexer.TypeVoid {
+			return nil, fmt.Errorf("invalid type %s of parameter %s of %s", p.Type, p.Name, name)
+		}
+	}
+	if lexer.ParseTypeName(result) == lexer.Unknown {
+		return nil, fmt.Errorf("invalid result type %s of %s", result, name)
+	}
+	item := &SymbolTableItem{
+		Variable:       name,
+		Type:           SymbolTableItemTypeFunction,
+		UnderlyingType: result,
+		VariableSize:   4,
+		ArraySize:      1,
+		Params:         params,
+		Label:          "F_" + name,
+	}
+	if err := st.Register(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// LookupFunction searches for a callable item, as Lookup does, and reports
+// the items found that cannot be called.
+func (st *SymbolTable) LookupFunction(name string) (*SymbolTableItem, error) {
+	item, _, err := st.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if !item.Callable() {
+		return item, fmt.Errorf("%s is a %s, not a function", name, item.Type)
+	}
+	return item, nil
+}
+
+// Callable checks if the item can be called: a function, an intrinsic
+// function or a variable of type func.
+func (item *SymbolTableItem) Callable() bool {
+	switch item.Type {
+	case SymbolTableItemTypeFunction, SymbolTableItemTypeBuiltin:
+		return true
+	case SymbolTableItemTypeVariable:
+		return lexer.ParseTypeName(item.UnderlyingType) == lexer.TypeFunc
+	}
+	return false
+}
+
+// Signature returns the function as it would be declared, such as
+// int gcd(int a, int b).
+func (item *SymbolTableItem) Signature() string {
+	params := make([]string, len(item.Params))
+	for i, p := range item.Params {
+		params[i] = p.String()
+	}
+	return fmt.Sprintf("%s %s(%s)", item.UnderlyingType, item.Variable, strings.Join(params, ", "))
+}
+
+// CallFunction emits a call to the function at its entry label, once the
+// number of the arguments and their types are checked against its
+// parameters. The value of the call is a temporary of the result type.
+func CallFunction(w *Walker, call *ASTNode, function *SymbolTableItem, args []*ASTNode) (*ASTNode, error) {
+	t := lexer.ParseTypeName(function.UnderlyingType)
+	var result *ASTNode
+	if t != lexer.TypeVoid {
+		result = w.NewTemp("call", t, call.raw, call.Children)
+	}
+	if len(args) != len(function.Params) {
+		return result, fmt.Errorf("%s takes %d arguments, got %d", call.raw, len(function.Params), len(args))
+	}
+	for i, arg := range args {
+		p := function.Params[i]
+		if t := w.TypeOf(arg); !Assignable(lexer.ParseTypeName(p.Type), t) {
+			return result, fmt.Errorf("%s: cannot pass %s (%s) as %s (%s)", call.raw, arg.raw, t.ToString(), p.Name, p.Type)
+		}
+	}
+	dist := ""
+	if result != nil {
+		dist = result.String()
+	}
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	w.EmitCall(dist, function.Label, values...)
+	return result, nil
+}
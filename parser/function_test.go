@@ -0,0 +1,97 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestSymbolTable_RegisterFunction(t *testing.T) {
+	st := NewSymbolTable(nil, nil)
+	st.EnterScope()
+	item, err := st.RegisterFunction("gcd", []Param{{"a", "int"}, {"b", "int"}}, "int")
+	if err != nil {
+		t.Fatalf("RegisterFunction: %v", err)
+	}
+	if item.Label != "F_gcd" || item.Signature() != "int gcd(int a, int b)" {
+		t.Errorf("Expected int gcd(int a, int b) at F_gcd, got %s at %s", item.Signature(), item.Label)
+	}
+	if err := st.Register(&SymbolTableItem{Variable: "n", Type: SymbolTableItemTypeVariable, UnderlyingType: "int", VariableSize: 4}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	if found, err := st.LookupFunction("gcd"); err != nil || found != item {
+		t.Errorf("Expected gcd found, got %v, %v", found, err)
+	}
+	if _, err := st.LookupFunction("n"); err == nil || err.Error() != "n is a variable, not a function" {
+		t.Errorf("Expected n not callable, got %v", err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		params []Param
+		result string
+	}{
+		{"gcd", nil, "int"},
+		{"f", []Param{{"a", "int"}, {"a", "float"}}, "void"},
+		{"g", []Param{{"a", "void"}}, "int"},
+		{"h", nil, "integer"},
+	} {
+		if _, err := st.RegisterFunction(test.name, test.params, test.result); err == nil {
+			t.Errorf("Expected %s(%v) %s rejected", test.name, test.params, test.result)
+		}
+	}
+}
+
+func TestCompile_Functions(t *testing.T) {
+	declare := func(st *SymbolTable) error {
+		if _, err := st.RegisterFunction("gcd", []Param{{"a", "int"}, {"b", "int"}}, "int"); err != nil {
+			return err
+		}
+		_, err := st.RegisterFunction("log", []Param{{"x", "float"}}, "void")
+		return err
+	}
+	compile := func(program string) *Result {
+		t.Helper()
+		result, err := Compile(Options{Source: strings.NewReader(program), Declare: declare})
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		return result
+	}
+
+	result := compile("{ int a, b; a = gcd(12, b) + 1; log(a); }")
+	if result.Failed() {
+		t.Fatalf("Expected the program to compile, got %v", result.Diagnostics)
+	}
+	code := strings.Join(result.Walker.ThreeAddress, "\n")
+	for _, expected := range []string{"param 12", "call F_gcd, 2", "call F_log, 1"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected %q in the code, got\n%s", expected, code)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		program  string
+		expected string
+	}{
+		{"Count", "{ int a; a = gcd(a); }", "gcd(a) takes 2 arguments, got 1"},
+		{"Type", `{ int a; a = gcd(a, "x"); }`, "cannot pass x (string) as b (int)"},
+		{"Void", "{ int a; a = log(1.5); }", "log(1.5) returns void"},
+		{"Value", "{ int a; a = gcd; }", "gcd cannot be used as a value"},
+		{"Variable", "{ int a; a = a(1); }", "a is a variable, not a function"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := compile(test.program)
+			for _, d := range result.Diagnostics {
+				if strings.Contains(d.Message, test.expected) {
+					return
+				}
+			}
+			t.Errorf("Expected an error with %q, got %v", test.expected, result.Diagnostics)
+		})
+	}
+}
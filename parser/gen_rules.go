@@ -2,25 +2,39 @@ package parser
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 
 	"app/lexer"
 )
 
 var GenRules = struct {
 	AugmentedProduction                                   Rule
-	Program                                               Rule
+	Program, ModuleDecl                                   Rule
 	BlockDeclsStmts, BlockDecls, BlockStmts, BlockEpsilon Rule
 	Decls, DeclsEpsilon                                   Rule
-	Decl                                                  Rule
-	TypeArray, TypeBasic                                  Rule
+	Decl, DeclStorage, StorageStatic, StorageConst        Rule
+	DeclaratorsList, DeclaratorsSingle                    Rule
+	InitDeclaratorAssign, InitDeclarator                  Rule
+	InitDeclaratorList                                    Rule
+	InitializersList, InitializersAssign                  Rule
+	MatchedStmtCall, CallArgs, CallEmpty                  Rule
+	ArgsList, ArgsAssign                                  Rule
+	DeclaratorArray, DeclaratorId                         Rule
+	TypeArray, TypePointer, TypeBasic                     Rule
 	Stmts, StmtsEpsilon                                   Rule
 	StmtMatchedStmt, StmtUnmatchedStmt, StmtDecls         Rule
 	UnmatchedStmtIf, UnmatchedStmtIfElse                  Rule
 	MatchedStmtAssign, MatchedStmtIf, MatchedStmtIfElse   Rule
 	MatchedStmtWhile, MatchedStmtDoWhile                  Rule
 	MatchedStmtBreak, MatchedStmtBlock                    Rule
-	LocArray, LocId                                       Rule
+	MatchedStmtSwitch, CasesList, CasesClause             Rule
+	CaseClause, CaseDefault, CaseStmtsEpsilon             Rule
+	CaseStmtsMatched, CaseStmtsUnmatched                  Rule
+	LocArray, LocId, LocQualified, LocDeref               Rule
+	SeqComma, SeqAssign                                   Rule
+	AssignLoc, AssignBool                                 Rule
 	Bool, BoolJoin                                        Rule
 	Join, JoinEquality                                    Rule
 	Equality, NotEquality, EqualityRelational             Rule
@@ -28,14 +42,70 @@ var GenRules = struct {
 	RelationalLessEqual, RelationalGreaterEqual           Rule
 	RelationalExpr                                        Rule
 	ExprPlus, ExprMinus, ExprTerm                         Rule
-	TermMult, TermDiv, TermUnary                          Rule
-	UnaryNot, UnaryNeg, UnaryFactor                       Rule
-	FactorBool, FactorLoc, FactorNum, FactorReal          Rule
-	FactorTrue, FactorFalse                               Rule
+	TermMult, TermDiv, TermMod, TermUnary                 Rule
+	UnaryNot, UnaryNeg, UnaryPlus, UnaryFactor            Rule
+	FactorSeq, FactorLoc, FactorNum, FactorReal           Rule
+	FactorStr, FactorCall                                 Rule
+	FactorTrue, FactorFalse, FactorNew                    Rule
 }{
 	// MatchedStmtIf: debugPrintWhenRuleTriggered,
-	Equality:    Equality,
-	NotEquality: NotEquality,
+	Equality:               GenRuleTemplates.Comparison("equality", "=="),
+	NotEquality:            GenRuleTemplates.Comparison("equality", "!="),
+	RelationalLess:         GenRuleTemplates.Comparison("rel", "<"),
+	RelationalLessEqual:    GenRuleTemplates.Comparison("rel", "<="),
+	RelationalGreater:      GenRuleTemplates.Comparison("rel", ">"),
+	RelationalGreaterEqual: GenRuleTemplates.Comparison("rel", ">="),
+	ExprPlus:               Addition,
+	TermMod:                Modulo,
+	UnaryNeg:               Negation,
+	FactorNum:              IntegerLiteral,
+	UnaryPlus:              UnaryPlus,
+	Decl:                   GenRuleTemplates.Declaration(3),
+	DeclStorage:            GenRuleTemplates.Declaration(4),
+	StorageStatic:          StorageStatic,
+	StorageConst:           StorageConst,
+	TypeBasic:              TypeBasic,
+	TypeArray:              TypeArray,
+	TypePointer:            TypePointer,
+	FactorNew:              New,
+	DeclaratorsList:        DeclaratorsList,
+	DeclaratorsSingle:      GenRuleTemplates.Rename("declarators"),
+	InitDeclarator:         InitDeclarator,
+	InitDeclaratorAssign:   InitDeclaratorAssign,
+	InitDeclaratorList:     InitDeclaratorList,
+	InitializersList:       GenRuleTemplates.ListAppend("initializers"),
+	InitializersAssign:     GenRuleTemplates.ListStart("initializers"),
+	ArgsList:               GenRuleTemplates.ListAppend("args"),
+	ArgsAssign:             GenRuleTemplates.ListStart("args"),
+	CallArgs:               Call,
+	CallEmpty:              Call,
+	FactorCall:             FactorCall,
+	FactorLoc:              FactorLoc,
+	MatchedStmtCall:        CallStatement,
+	DeclaratorArray:        DeclaratorArray,
+	DeclaratorId:           DeclaratorId,
+	MatchedStmtAssign:      AssignStatement,
+	AssignLoc:              Assignment,
+	SeqComma:               GenRuleTemplates.Select(2, 3),
+	FactorSeq:              GenRuleTemplates.Select(1, 3),
+	ModuleDecl:             GenRuleTemplates.Reduce(ModuleDecl),
+	MatchedStmtSwitch:      GenRuleTemplates.Reduce(Switch),
+	UnmatchedStmtIf:        GenRuleTemplates.Reduce(IfStatement),
+	UnmatchedStmtIfElse:    GenRuleTemplates.Reduce(IfElseStatement),
+	MatchedStmtIf:          GenRuleTemplates.Reduce(IfStatement),
+	MatchedStmtIfElse:      GenRuleTemplates.Reduce(IfElseStatement),
+	MatchedStmtWhile:       GenRuleTemplates.Reduce(WhileStatement),
+	MatchedStmtDoWhile:     GenRuleTemplates.Reduce(DoWhileStatement),
+	MatchedStmtBreak:       GenRuleTemplates.Reduce(BreakStatement),
+	Bool:                   GenRuleTemplates.Reduce(Logical("||")),
+	Join:                   GenRuleTemplates.Reduce(Logical("&&")),
+	UnaryNot:               GenRuleTemplates.Reduce(Not),
+	CasesList:              GenRuleTemplates.Reduce(CasesAppend),
+	CasesClause:            GenRuleTemplates.ListStart("cases"),
+	LocId:                  LocId,
+	LocQualified:           LocQualified,
+	LocArray:               GenRuleTemplates.Reduce(LocIndex),
+	LocDeref:               GenRuleTemplates.Reduce(Dereference),
 }
 
 func debugPrintWhenRuleTriggered(w *Walker) error {
@@ -53,64 +123,796 @@ func (g *GenRuleTemplate) NOP() Rule {
 	return func(w *Walker) error { return nil }
 }
 
-func Equality(w *Walker) error {
-	arg1, _ := w.Tokens.PeekAtK(2)
-	arg2, _ := w.Tokens.PeekAtK(0)
-	result := w.SymbolTable.TempAddr(4)
-	w.Emit(strconv.Itoa(result), "eq", arg1, arg2)
+// Rename returns a rule for unit productions that keeps the node on top of
+// the token stack and only changes its type to the head of the production.
+func (g *GenRuleTemplate) Rename(head Symbol) Rule {
+	return func(w *Walker) error {
+		n, ok := w.Tokens.Peek()
+		if !ok {
+			return fmt.Errorf("%s: expected 1 node on the token stack", head)
+		}
+		n.Type = head
+		return nil
+	}
+}
+
+// Reduce returns a rule running the action with the context of the
+// reduction. The nodes matched are replaced by the Result of the context,
+// by default the nodes folded into one of the head, or the node if it is
+// the only one.
+func (g *GenRuleTemplate) Reduce(action SemanticAction) Rule {
+	return func(w *Walker) error {
+		c := w.reduceContext()
+		if len(c.Nodes) != w.reducing.production.Length() {
+			return fmt.Errorf("%s: expected %d nodes on the token stack", c.Production.Head, c.Production.Length())
+		}
+		err := action(c)
+		w.Tokens.TrimTopN(len(c.Nodes))
+		w.Tokens.Push(c.Result)
+		return err
+	}
+}
+
+// Declaration returns a rule for decl productions of n symbols, which ends
+// the declaration in the environment.
+func (g *GenRuleTemplate) Declaration(n int) Rule {
+	return func(w *Walker) error {
+		w.Environment.CurrentStatic = false
+		w.Environment.CurrentConst = false
+		w.Environment.CurrentPointee = ""
+		w.ReduceTokens("decl", n)
+		// the scope may already be left by now, so only global declarators are collected
+		declarators := w.Environment.CurrentDeclarators
+		w.Environment.CurrentDeclarators = nil
+		if len(declarators) == 0 {
+			return nil
+		}
+		node, _ := w.Tokens.Peek()
+		if doc := DocComment(node.Trivia().Leading); doc != "" {
+			w.Docs = append(w.Docs, NewDoc(node, declarators, doc))
+		}
+		return nil
+	}
+}
+
+// ListStart returns a rule for head → item, which starts a comma separated
+// list holding the items as its children.
+func (g *GenRuleTemplate) ListStart(head Symbol) Rule {
+	return func(w *Walker) error {
+		n, ok := w.Tokens.Pop()
+		if !ok {
+			return fmt.Errorf("%s: expected 1 node on the token stack", head)
+		}
+		w.Tokens.Push(&ASTNode{
+			raw:      n.raw,
+			Token:    &lexer.Token{Type: lexer.EXTRA},
+			Children: []*ASTNode{n},
+			Type:     head,
+		})
+		return nil
+	}
+}
+
+// ListAppend returns a rule for head → head , item, which appends the item
+// to the list started by ListStart.
+func (g *GenRuleTemplate) ListAppend(head Symbol) Rule {
+	return func(w *Walker) error {
+		children := w.Tokens.PopTopN(3)
+		if len(children) != 3 {
+			return fmt.Errorf("%s: expected 3 nodes on the token stack", head)
+		}
+		list := children[0]
+		list.raw = fmt.Sprintf("%s, %s", list.raw, children[2].raw)
+		list.Children = append(list.Children, children[2])
+		w.Tokens.Push(list)
+		return nil
+	}
+}
+
+// Select returns a rule that replaces the top n nodes of the token stack with
+// the i-th of them, e.g. Select(1, 3) passes the value of ( bool ) through.
+func (g *GenRuleTemplate) Select(i, n int) Rule {
+	return func(w *Walker) error {
+		children := w.Tokens.PopTopN(n)
+		if len(children) != n {
+			return fmt.Errorf("select: expected %d nodes on the token stack", n)
+		}
+		w.Tokens.Push(children[i])
+		return nil
+	}
+}
+
+// Declarator describes a single declarator of a declaration, e.g. b[10] in
+// int a, b[10], c; The element type comes from the environment.
+type Declarator struct {
+	Name  string
+	Dims  []int
+	Token *lexer.Token
+}
+
+// StorageStatic handles storage → static, marking the variables of the
+// declaration being reduced as static.
+func StorageStatic(w *Walker) error {
+	w.Environment.CurrentStatic = true
+	return GenRuleTemplates.Rename("storage")(w)
+}
+
+// StorageConst handles storage → const, marking the variables of the
+// declaration being reduced as read-only after their initialization.
+func StorageConst(w *Walker) error {
+	w.Environment.CurrentConst = true
+	return GenRuleTemplates.Rename("storage")(w)
+}
+
+// TypeBasic handles type → basic and starts a new declaration in the environment.
+func TypeBasic(w *Walker) error {
+	n, ok := w.Tokens.Peek()
+	if !ok {
+		return fmt.Errorf("type: expected 1 node on the token stack")
+	}
+	n.Type = "type"
+	env := w.Environment
+	env.CurrentType = SymbolTableItemTypeVariable
+	env.CurrentDataType = n.Token.SpecificType()
+	if n.Token.Type == lexer.RESERVED && n.Token.Val == "func" {
+		env.CurrentDataType = lexer.TypeFunc
+	}
+	env.CurrentDims = nil
+	return nil
+}
+
+// TypeArray handles type → type [ num ], which makes every declarator of the
+// declaration an array of the given length.
+func TypeArray(w *Walker) error {
+	children := w.Tokens.PopTopN(4)
+	if len(children) != 4 {
+		return fmt.Errorf("type: expected 4 nodes on the token stack")
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:      fmt.Sprintf("%s[%s]", children[0].raw, children[2].raw),
+		Token:    children[0].Token,
+		Children: children,
+		Type:     "type",
+	})
+	size, ok := IntLiteral(children[2])
+	if !ok || size <= 0 {
+		return fmt.Errorf("invalid array length %s, at line %d, pos %d", children[2].raw, children[2].Token.Line, children[2].Token.Pos)
+	}
+	w.Environment.CurrentType = SymbolTableItemTypeArray
+	w.Environment.CurrentDims = append(w.Environment.CurrentDims, int(size))
+	return nil
+}
+
+// TypePointer handles type → type *, which makes every declarator of the
+// declaration a pointer to the type.
+func TypePointer(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	if len(children) != 2 {
+		return fmt.Errorf("type: expected 2 nodes on the token stack")
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:      children[0].raw + "*",
+		Token:    children[0].Token,
+		Children: children,
+		Type:     "type",
+	})
+	env := w.Environment
+	if env.CurrentType == SymbolTableItemTypeArray || env.CurrentPointee != "" {
+		return fmt.Errorf("pointer to %s is not supported, at line %d, pos %d", children[0].raw, children[1].Token.Line, children[1].Token.Pos)
+	}
+	env.CurrentPointee = env.CurrentDataType.ToString()
+	env.CurrentDataType = lexer.TypePointer
+	return nil
+}
+
+// DeclaratorId handles declarator → id
+func DeclaratorId(w *Walker) error {
+	n, ok := w.Tokens.Peek()
+	if !ok {
+		return fmt.Errorf("declarator: expected 1 node on the token stack")
+	}
+	n.Type = "declarator"
+	n.Payload = &Declarator{Name: n.Token.Val, Token: n.Token}
+	return nil
+}
+
+// DeclaratorArray handles declarator → declarator [ num ]
+func DeclaratorArray(w *Walker) error {
+	children := w.Tokens.PopTopN(4)
+	if len(children) != 4 {
+		return fmt.Errorf("declarator: expected 4 nodes on the token stack")
+	}
+	d, _ := children[0].Payload.(*Declarator)
+	if d == nil {
+		return fmt.Errorf("declarator: missing declarator payload")
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:      fmt.Sprintf("%s[%s]", children[0].raw, children[2].raw),
+		Token:    children[0].Token,
+		Children: children,
+		Type:     "declarator",
+		Payload:  d,
+	})
+	size, ok := IntLiteral(children[2])
+	if !ok || size <= 0 {
+		return fmt.Errorf("invalid array length %s, at line %d, pos %d", children[2].raw, children[2].Token.Line, children[2].Token.Pos)
+	}
+	d.Dims = append(d.Dims, int(size))
+	return nil
+}
+
+// DeclaratorsList handles declarators → declarators , init_declarator
+func DeclaratorsList(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	if len(children) != 3 {
+		return fmt.Errorf("declarators: expected 3 nodes on the token stack")
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:      fmt.Sprintf("%s, %s", children[0].raw, children[2].raw),
+		Token:    &lexer.Token{Type: lexer.EXTRA},
+		Children: children,
+		Type:     "declarators",
+	})
+	return nil
+}
+
+// InitDeclarator handles init_declarator → declarator
+func InitDeclarator(w *Walker) error {
+	n, ok := w.Tokens.Peek()
+	if !ok {
+		return fmt.Errorf("init_declarator: expected 1 node on the token stack")
+	}
+	n.Type = "init_declarator"
+	d, _ := n.Payload.(*Declarator)
+	if d == nil {
+		return fmt.Errorf("init_declarator: missing declarator payload")
+	}
+	if err := w.Declare(d); err != nil {
+		return err
+	}
+	if w.Environment.CurrentConst {
+		return fmt.Errorf("const %s must be initialized, at line %d, pos %d", d.Name, d.Token.Line, d.Token.Pos)
+	}
+	return nil
+}
+
+// InitDeclaratorAssign handles init_declarator → declarator = assign. The
+// variable is allocated first and then initialized, constant initializers are
+// placed in the constant pool and copied from there. Static variables are
+// initialized once in the data segment, so their initializer must be constant.
+func InitDeclaratorAssign(w *Walker) error {
 	children := w.Tokens.PopTopN(3)
+	if len(children) != 3 {
+		return fmt.Errorf("init_declarator: expected 3 nodes on the token stack")
+	}
+	value := children[2]
 	w.Tokens.Push(&ASTNode{
-		raw: fmt.Sprintf("%s == %s", arg1.Token.Val, arg2.Token.Val),
-		Token: &lexer.Token{
-			Type: lexer.EXTRA,
-			Val:  fmt.Sprintf("$(0x%x)", result),
-		},
+		raw:      fmt.Sprintf("%s = %s", children[0].raw, value.raw),
+		Token:    children[0].Token,
 		Children: children,
+		Type:     "init_declarator",
+	})
+	d, _ := children[0].Payload.(*Declarator)
+	if d == nil {
+		return fmt.Errorf("init_declarator: missing declarator payload")
+	}
+	if err := w.Declare(d); err != nil {
+		return err
+	}
 
-		Type:    "equality",
-		Payload: "!dist:!ptr(size=4)",
+	item, _, err := w.SymbolTable.Lookup(d.Name)
+	if err != nil {
+		return err
+	}
+	if item.Type == SymbolTableItemTypeArray {
+		return fmt.Errorf("array %s cannot be initialized with %s, at line %d, pos %d", d.Name, value.raw, d.Token.Line, d.Token.Pos)
+	}
+	declared := lexer.ParseTypeName(item.UnderlyingType)
+	if t := w.TypeOf(value); !Assignable(declared, t) {
+		return fmt.Errorf("cannot initialize %s (%s) with %s (%s), at line %d, pos %d",
+			d.Name, item.UnderlyingType, value.raw, t.ToString(), d.Token.Line, d.Token.Pos)
+	}
+	if pointee, ok := value.Payload.(lexer.TokenSpecificType); ok && declared == lexer.TypePointer && pointee.ToString() != item.Pointee {
+		return fmt.Errorf("cannot initialize %s (%s*) with %s (%s*), at line %d, pos %d",
+			d.Name, item.Pointee, value.raw, pointee.ToString(), d.Token.Line, d.Token.Pos)
+	}
+
+	if item.Static {
+		if !IsLiteral(value) {
+			return fmt.Errorf("initializer %s of static %s is not constant, at line %d, pos %d",
+				value.raw, d.Name, d.Token.Line, d.Token.Pos)
+		}
+		item.Initializer = []string{value.String()}
+		return nil
+	}
+	if IsLiteral(value) {
+		size, typ := item.VariableSize, item.UnderlyingType
+		if value.Token.Type == lexer.STRING {
+			size, typ = len(value.Token.Val)+1, lexer.TypeString.ToString()
+		}
+		addr := w.SymbolTable.Constant(value.String(), typ, size)
+		w.Emit(item.Qualified(), "", fmt.Sprintf("$(0x%x)", addr))
+		return nil
+	}
+	w.Emit(item.Qualified(), "", w.convertFor(children[0], value))
+	return nil
+}
+
+// InitDeclaratorList handles init_declarator → declarator = { initializers }.
+// Elements missing from the list are zero-filled. Arrays of the outermost
+// block and static arrays live in the data segment and get their values
+// recorded on the symbol table item, other arrays are initialized by a store
+// per element.
+func InitDeclaratorList(w *Walker) error {
+	children := w.Tokens.PopTopN(5)
+	if len(children) != 5 {
+		return fmt.Errorf("init_declarator: expected 5 nodes on the token stack")
+	}
+	values := children[3].Children
+	w.Tokens.Push(&ASTNode{
+		raw:      fmt.Sprintf("%s = {%s}", children[0].raw, children[3].raw),
+		Token:    children[0].Token,
+		Children: children,
+		Type:     "init_declarator",
 	})
-	n, _ := w.Tokens.Peek()
-	fmt.Printf("emit($(%#x), eq, %s, %s)\n", result, arg1.Token.Val, arg2.Token.Val)
-	fmt.Println("Node: ", n.raw)
-	fmt.Println("Children:")
-	for _, child := range n.Children {
-		fmt.Println("  -", child.raw)
+	d, _ := children[0].Payload.(*Declarator)
+	if d == nil {
+		return fmt.Errorf("init_declarator: missing declarator payload")
+	}
+	if err := w.Declare(d); err != nil {
+		return err
 	}
-	fmt.Println("Token:", n.Token.Val)
-	fmt.Println("Type:", n.Type)
-	fmt.Println("Payload:", n.Payload)
-	fmt.Println()
 
+	item, _, err := w.SymbolTable.Lookup(d.Name)
+	if err != nil {
+		return err
+	}
+	if item.Type != SymbolTableItemTypeArray {
+		return fmt.Errorf("%s is not an array and cannot be initialized with a list, at line %d, pos %d", d.Name, d.Token.Line, d.Token.Pos)
+	}
+	if len(values) > item.ArraySize {
+		return fmt.Errorf("too many initializers for %s: %d given, %d declared, at line %d, pos %d",
+			d.Name, len(values), item.ArraySize, d.Token.Line, d.Token.Pos)
+	}
+	declared := lexer.ParseTypeName(item.UnderlyingType)
+	for _, value := range values {
+		if t := w.TypeOf(value); !Assignable(declared, t) {
+			return fmt.Errorf("cannot initialize element of %s (%s) with %s (%s), at line %d, pos %d",
+				d.Name, item.UnderlyingType, value.raw, t.ToString(), d.Token.Line, d.Token.Pos)
+		}
+	}
+
+	if w.SymbolTable.CurrentScope.Level <= 1 || item.Static {
+		item.Initializer = make([]string, item.ArraySize)
+		for i := range item.Initializer {
+			item.Initializer[i] = "0"
+			if i >= len(values) {
+				continue
+			}
+			if !IsLiteral(values[i]) {
+				return fmt.Errorf("initializer element %s of %s is not constant, at line %d, pos %d",
+					values[i].raw, d.Name, d.Token.Line, d.Token.Pos)
+			}
+			item.Initializer[i] = values[i].String()
+		}
+		return nil
+	}
+	for i := 0; i < item.ArraySize; i++ {
+		dist := fmt.Sprintf("%s[%d]", d.Name, i*item.VariableSize)
+		if i < len(values) {
+			w.Emit(dist, "", values[i])
+		} else {
+			w.Emit(dist, "", "0")
+		}
+	}
 	return nil
 }
 
-func NotEquality(w *Walker) error {
-	arg1, _ := w.Tokens.PeekAtK(2)
-	arg2, _ := w.Tokens.PeekAtK(0)
-	result := w.SymbolTable.TempAddr(4)
-	w.Emit(strconv.Itoa(result), "ne", arg1, arg2)
+// Declare registers a declarator in the current scope, using the type of the
+// declaration being reduced. The dimensions of the declarator are outer to
+// those of the type, the array stored flat, see Register.
+func (w *Walker) Declare(d *Declarator) error {
+	env := w.Environment
+	item := &SymbolTableItem{
+		Variable:       d.Name,
+		Type:           env.CurrentType,
+		Static:         env.CurrentStatic,
+		Const:          env.CurrentConst,
+		Pointee:        env.CurrentPointee,
+		UnderlyingType: env.CurrentDataType.ToString(),
+		ValueType:      ArrayOf(w.basic(env.CurrentDataType), append(slices.Clone(d.Dims), env.CurrentDims...)...),
+		Line:           d.Token.Line,
+		Pos:            d.Token.Pos,
+	}
+	if err := w.SymbolTable.Register(item); err != nil {
+		return fmt.Errorf("%w, at line %d, pos %d", err, d.Token.Line, d.Token.Pos)
+	}
+	w.beginLifetime(item)
+	if scope := w.SymbolTable.CurrentScope; w.Module != "" && scope.Level == 1 {
+		item.Module = w.Module
+		w.SymbolTable.Modules[w.Module] = scope
+	}
+	if w.SymbolTable.CurrentScope.Level <= 1 {
+		env.CurrentDeclarators = append(env.CurrentDeclarators, d)
+	}
+	return nil
+}
+
+// Modulo handles term → term % unary. Both operands must be integers; two
+// literals are folded at compile time, anything else is computed into a temporary.
+func Modulo(w *Walker) error {
 	children := w.Tokens.PopTopN(3)
+	if len(children) != 3 {
+		return fmt.Errorf("modulo: expected 3 nodes on the token stack")
+	}
+	arg1, arg2 := children[0], children[2]
+	raw := fmt.Sprintf("%s %% %s", arg1.raw, arg2.raw)
+
+	t1, t2 := w.TypeOf(arg1), w.TypeOf(arg2)
+	if (t1 != lexer.Unknown && !IsIntegral(t1)) || (t2 != lexer.Unknown && !IsIntegral(t2)) {
+		w.Tokens.Push(w.NewTemp("term", lexer.TypeInt, raw, children))
+		return fmt.Errorf("invalid operands to %%: %s (%s) and %s (%s), integers required",
+			arg1.raw, t1.ToString(), arg2.raw, t2.ToString())
+	}
+
+	v1, ok1 := IntLiteral(arg1)
+	v2, ok2 := IntLiteral(arg2)
+	if ok2 && v2 == 0 {
+		w.Tokens.Push(w.NewTemp("term", lexer.TypeInt, raw, children))
+		return fmt.Errorf("integer modulo by zero: %s", raw)
+	}
+	if ok1 && ok2 {
+		w.Tokens.Push(w.intLiteral(v1%v2, children))
+		return nil
+	}
+
+	result := w.NewTemp("term", lexer.TypeInt, raw, children)
+	w.Emit(result.String(), "mod", arg1, arg2)
+	w.Tokens.Push(result)
+	return nil
+}
+
+// IntegerLiteral handles factor → num, checking the literal against the range
+// of int of the session. The literal of a unary minus may be one more than
+// the largest int, the smallest one once negated.
+func IntegerLiteral(w *Walker) error {
+	n, ok := w.Tokens.Peek()
+	if !ok {
+		return fmt.Errorf("factor: expected 1 node on the token stack")
+	}
+	minus, _ := w.Tokens.PeekAtK(1)
+	negated := minus != nil && minus.Token != nil && minus.Token.Type == lexer.OPERATOR && minus.Token.Val == "-"
+	if err := w.IntWidth.CheckLiteral(n.Token.Val, negated); err != nil {
+		return fmt.Errorf("%w, at line %d, pos %d", err, n.Token.Line, n.Token.Pos)
+	}
+	return nil
+}
+
+// Negation handles unary → - unary. Negative literals are folded into a
+// single constant, anything else is negated into a temporary.
+func Negation(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	if len(children) != 2 {
+		return fmt.Errorf("negation: expected 2 nodes on the token stack")
+	}
+	arg := children[1]
+	raw := fmt.Sprintf("-%s", arg.raw)
+
+	t := w.TypeOf(arg)
+	if t != lexer.Unknown && !IsNumeric(t) {
+		w.Tokens.Push(w.NewTemp("unary", t, raw, children))
+		return fmt.Errorf("invalid operand to unary -: %s (%s)", arg.raw, t.ToString())
+	}
+
+	if v, ok := IntLiteral(arg); ok {
+		w.Tokens.Push(w.intLiteral(-v, children))
+		return nil
+	}
+	if arg.Token != nil && arg.Token.Type == lexer.FLOAT {
+		val := "-" + arg.Token.Val
+		if strings.HasPrefix(arg.Token.Val, "-") {
+			val = arg.Token.Val[1:]
+		}
+		w.Tokens.Push(NewLiteral(lexer.FLOAT, val, lexer.TypeFloat, children))
+		return nil
+	}
+
+	result := w.NewTemp("unary", t, raw, children)
+	w.Emit(result.String(), "minus", arg)
+	w.Tokens.Push(result)
+	return nil
+}
+
+// UnaryPlus handles unary → + unary, which only checks that the operand is
+// numeric and passes its value through.
+func UnaryPlus(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	if len(children) != 2 {
+		return fmt.Errorf("unary plus: expected 2 nodes on the token stack")
+	}
+	arg := children[1]
+	w.Tokens.Push(arg)
+	if t := w.TypeOf(arg); t != lexer.Unknown && !IsNumeric(t) {
+		return fmt.Errorf("invalid operand to unary +: %s (%s)", arg.raw, t.ToString())
+	}
+	return nil
+}
+
+// Assignment handles assign → loc = assign. The value is stored into the
+// location, which then stands for the value of the whole expression, so that
+// a = b = 0 reads the stored value back from b.
+func Assignment(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	if len(children) != 3 {
+		return fmt.Errorf("assignment: expected 3 nodes on the token stack")
+	}
+	loc, value := children[0], children[2]
+	result := &ASTNode{
+		raw:      fmt.Sprintf("%s = %s", loc.raw, value.raw),
+		Token:    loc.Token,
+		Children: children,
+		Type:     "assign",
+		DataType: loc.DataType,
+	}
+	w.Tokens.Push(result)
+	if err := w.CheckWritable(loc); err != nil {
+		return err
+	}
+	if err := w.CheckAssignable(loc, value); err != nil {
+		return err
+	}
+	if deref, ok := loc.Payload.(*Deref); ok {
+		// the value is read back as stored, wrapped to the type pointed to
+		w.store(deref, w.convertFor(loc, value))
+		result.Token = w.load(loc, deref).Token
+		return nil
+	}
+	w.Emit(loc.String(), "", w.convertFor(loc, value))
+	return nil
+}
+
+// AssignStatement handles matched_stmt → loc = assign ;
+func AssignStatement(w *Walker) error {
+	children := w.Tokens.PopTopN(4)
+	if len(children) != 4 {
+		return fmt.Errorf("assignment: expected 4 nodes on the token stack")
+	}
+	loc, value := children[0], children[2]
 	w.Tokens.Push(&ASTNode{
-		raw: fmt.Sprintf("%s != %s", arg1.Token.Val, arg2.Token.Val),
-		Token: &lexer.Token{
-			Type: lexer.EXTRA,
-			Val:  fmt.Sprintf("$(0x%x)", result),
-		},
+		raw:      fmt.Sprintf("%s = %s;", loc.raw, value.raw),
+		Token:    &lexer.Token{Type: lexer.EXTRA},
 		Children: children,
-		Type:     "not-equality",
-		Payload:  "!dist:!ptr(size=4)",
+		Type:     "matched_stmt",
 	})
-	n, _ := w.Tokens.Peek()
-	fmt.Printf("emit($(%#x), eq, %s, %s)\n", result, arg1.Token.Val, arg2.Token.Val)
-	fmt.Println("Node: ", n.raw)
-	fmt.Println("Children:")
-	for _, child := range n.Children {
-		fmt.Println("  -", child.raw)
-	}
-	fmt.Println("Token:", n.Token.Val)
-	fmt.Println("Type:", n.Type)
-	fmt.Println("Payload:", n.Payload)
-	fmt.Println()
+	if err := w.CheckWritable(loc); err != nil {
+		return err
+	}
+	if err := w.CheckAssignable(loc, value); err != nil {
+		return err
+	}
+	if deref, ok := loc.Payload.(*Deref); ok {
+		w.store(deref, w.convertFor(loc, value))
+		return nil
+	}
+	w.Emit(loc.String(), "", w.convertFor(loc, value))
+	return nil
+}
+
+// Addition handles expr → expr + term. Numbers are added, strings are
+// concatenated by the strcat runtime helper. Literal operands are folded,
+// folded strings are placed in the string pool.
+func Addition(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	if len(children) != 3 {
+		return fmt.Errorf("addition: expected 3 nodes on the token stack")
+	}
+	arg1, arg2 := children[0], children[2]
+	raw := fmt.Sprintf("%s + %s", arg1.raw, arg2.raw)
+	t1, t2 := w.TypeOf(arg1), w.TypeOf(arg2)
+
+	if t1 == lexer.TypeString || t2 == lexer.TypeString {
+		if t1 != t2 {
+			w.Tokens.Push(w.NewTemp("expr", lexer.TypeString, raw, children))
+			return fmt.Errorf("invalid operands to +: %s (%s) and %s (%s), cannot mix strings with other types",
+				arg1.raw, t1.ToString(), arg2.raw, t2.ToString())
+		}
+		if IsLiteral(arg1) && IsLiteral(arg2) {
+			s := arg1.Token.Val + arg2.Token.Val
+			w.SymbolTable.Constant(strconv.Quote(s), lexer.TypeString.ToString(), len(s)+1)
+			w.Tokens.Push(NewLiteral(lexer.STRING, s, lexer.TypeString, children))
+			return nil
+		}
+		result := w.NewTemp("expr", lexer.TypeString, raw, children)
+		w.Emit(result.String(), "strcat", arg1, arg2)
+		w.Tokens.Push(result)
+		return nil
+	}
+
+	if (t1 != lexer.Unknown && !IsNumeric(t1)) || (t2 != lexer.Unknown && !IsNumeric(t2)) {
+		w.Tokens.Push(w.NewTemp("expr", t1, raw, children))
+		return fmt.Errorf("invalid operands to +: %s (%s) and %s (%s)", arg1.raw, t1.ToString(), arg2.raw, t2.ToString())
+	}
+	v1, ok1 := IntLiteral(arg1)
+	v2, ok2 := IntLiteral(arg2)
+	if ok1 && ok2 {
+		w.Tokens.Push(w.intLiteral(v1+v2, children))
+		return nil
+	}
+	t := t1
+	if !IsIntegral(t2) && t2 != lexer.Unknown {
+		t = t2
+	}
+	result := w.NewTemp("expr", t, raw, children)
+	w.Emit(result.String(), "+", arg1, arg2)
+	w.Tokens.Push(result)
+	return nil
+}
+
+// StringComparison handles == and != between two strings, which are compared
+// by the streq runtime helper. Comparisons of two literals are folded.
+func StringComparison(w *Walker, op string) error {
+	children := w.Tokens.PopTopN(3)
+	if len(children) != 3 {
+		return fmt.Errorf("comparison: expected 3 nodes on the token stack")
+	}
+	arg1, arg2 := children[0], children[2]
+	raw := fmt.Sprintf("%s %s %s", arg1.raw, op, arg2.raw)
+	if t1, t2 := w.TypeOf(arg1), w.TypeOf(arg2); t1 != t2 {
+		w.Tokens.Push(w.NewTemp("equality", lexer.TypeBool, raw, children))
+		return fmt.Errorf("invalid operands to %s: %s (%s) and %s (%s), cannot compare strings with other types",
+			op, arg1.raw, t1.ToString(), arg2.raw, t2.ToString())
+	}
+	if IsLiteral(arg1) && IsLiteral(arg2) {
+		equal := arg1.Token.Val == arg2.Token.Val
+		val := strconv.FormatBool(equal == (op == "=="))
+		w.Tokens.Push(NewLiteral(lexer.RESERVED, val, lexer.TypeBool, children))
+		return nil
+	}
+	result := w.NewTemp("equality", lexer.TypeBool, raw, children)
+	if op == "==" {
+		w.Emit(result.String(), "streq", arg1, arg2)
+	} else {
+		w.Emit(result.String(), "strne", arg1, arg2)
+	}
+	w.Tokens.Push(result)
+	return nil
+}
+
+// stringOperands checks if either operand of the binary operation on top of
+// the token stack is a string.
+func stringOperands(w *Walker) bool {
+	arg1, _ := w.Tokens.PeekAtK(2)
+	arg2, _ := w.Tokens.PeekAtK(0)
+	return w.TypeOf(arg1) == lexer.TypeString || w.TypeOf(arg2) == lexer.TypeString
+}
+
+// comparisons are the instructions of three-address code for the comparison
+// operators.
+var comparisons = map[string]string{"==": "eq", "!=": "ne", "<": "lt", "<=": "le", ">": "gt", ">=": "ge"}
+
+// IsFloating checks if the type is a floating point type.
+func IsFloating(t lexer.TokenSpecificType) bool {
+	return IsNumeric(t) && !IsIntegral(t)
+}
+
+// arrayOf returns the symbol table item of the node if it names a whole array.
+func (w *Walker) arrayOf(node *ASTNode) *SymbolTableItem {
+	if node.Token == nil || node.Token.Type != lexer.IDENTIFIER || len(node.Children) > 0 {
+		return nil
+	}
+	item, _, err := w.SymbolTable.Lookup(node.Token.Val)
+	if err != nil || item.Type != SymbolTableItemTypeArray {
+		return nil
+	}
+	return item
+}
+
+// ToFloat converts an integer value to float: literals are converted at
+// compile time, anything else by itof into a temporary.
+func (w *Walker) ToFloat(node *ASTNode) *ASTNode {
+	if v, ok := IntLiteral(node); ok {
+		return NewLiteral(lexer.FLOAT, strconv.FormatFloat(float64(v), 'f', 1, 64), lexer.TypeFloat, []*ASTNode{node})
+	}
+	result := w.NewTemp("factor", lexer.TypeFloat, fmt.Sprintf("(float) %s", node.raw), []*ASTNode{node})
+	w.Emit(result.String(), "itof", node)
+	return result
+}
+
+// convertFor converts the value stored into the location to its type, an
+// integer stored into a float by ToFloat, for no copy of the code to hold an
+// integer the optimizations would take the float for.
+func (w *Walker) convertFor(loc, value *ASTNode) *ASTNode {
+	if IsFloating(w.TypeOf(loc)) && IsIntegral(w.TypeOf(value)) {
+		return w.ToFloat(value)
+	}
+	return value
+}
+
+// constant returns the value of a numeric or boolean literal.
+func constant(node *ASTNode) (float64, bool) {
+	if v, ok := IntLiteral(node); ok {
+		return float64(v), true
+	}
+	if !IsLiteral(node) {
+		return 0, false
+	}
+	switch node.Token.Type {
+	case lexer.FLOAT:
+		v, err := strconv.ParseFloat(node.Token.Val, 64)
+		return v, err == nil
+	case lexer.RESERVED:
+		if node.Token.Val == "true" {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// Comparison returns the rule of the equality or relational production of
+// the operator, head → a op b.
+func (g *GenRuleTemplate) Comparison(head Symbol, op string) Rule {
+	return func(w *Walker) error {
+		return Comparison(w, head, op)
+	}
+}
+
+// Comparison handles head → a op b for the comparison operators. Numbers of
+// any types are compared, the integer operand of a comparison with a float
+// converted first. Booleans can be compared for equality, with each other or
+// as 0 and 1 with integers, and strings too, by their contents. Arrays cannot be compared. The result is a bool, folded if
+// both operands are literals.
+func Comparison(w *Walker, head Symbol, op string) error {
+	equality := op == "==" || op == "!="
+	if equality && stringOperands(w) {
+		return StringComparison(w, op)
+	}
+	children := w.Tokens.PopTopN(3)
+	if len(children) != 3 {
+		return fmt.Errorf("comparison: expected 3 nodes on the token stack")
+	}
+	arg1, arg2 := children[0], children[2]
+	raw := fmt.Sprintf("%s %s %s", arg1.raw, op, arg2.raw)
+	for _, arg := range []*ASTNode{arg1, arg2} {
+		if item := w.arrayOf(arg); item != nil {
+			w.Tokens.Push(w.NewTemp(head, lexer.TypeBool, raw, children))
+			return fmt.Errorf("invalid operands to %s: %s is an array, declared at line %d, pos %d, and arrays cannot be compared",
+				op, arg.raw, item.Line, item.Pos)
+		}
+	}
+
+	t1, t2 := w.TypeOf(arg1), w.TypeOf(arg2)
+	valid := func(t, other lexer.TokenSpecificType) bool {
+		if t == lexer.TypeBool {
+			// booleans compare with booleans, or as 0 and 1 with integers
+			return equality && (other == lexer.Unknown || other == lexer.TypeBool || IsIntegral(other))
+		}
+		return t == lexer.Unknown || IsNumeric(t)
+	}
+	if !valid(t1, t2) || !valid(t2, t1) {
+		w.Tokens.Push(w.NewTemp(head, lexer.TypeBool, raw, children))
+		if equality {
+			return fmt.Errorf("invalid operands to %s: %s (%s) and %s (%s), numbers or booleans required",
+				op, arg1.raw, t1.ToString(), arg2.raw, t2.ToString())
+		}
+		return fmt.Errorf("invalid operands to %s: %s (%s) and %s (%s), numbers required",
+			op, arg1.raw, t1.ToString(), arg2.raw, t2.ToString())
+	}
+	if IsFloating(t1) && IsIntegral(t2) {
+		arg2 = w.ToFloat(arg2)
+	} else if IsIntegral(t1) && IsFloating(t2) {
+		arg1 = w.ToFloat(arg1)
+	}
+
+	if v1, ok1 := constant(arg1); ok1 {
+		if v2, ok2 := constant(arg2); ok2 {
+			result := map[string]bool{"==": v1 == v2, "!=": v1 != v2, "<": v1 < v2, "<=": v1 <= v2, ">": v1 > v2, ">=": v1 >= v2}[op]
+			w.Tokens.Push(NewLiteral(lexer.RESERVED, strconv.FormatBool(result), lexer.TypeBool, children))
+			return nil
+		}
+	}
+	result := w.NewTemp(head, lexer.TypeBool, raw, children)
+	w.Emit(result.String(), comparisons[op], arg1, arg2)
+	w.Tokens.Push(result)
 	return nil
 }
@@ -0,0 +1,593 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+)
+
+var sharedParser = sync.OnceValue(func() *Parser {
+	p := NewParser()
+	p.EnsureTable()
+	return p
+})
+
+func parseSource(t *testing.T, src string) *Walker {
+	t.Helper()
+	logs := []string{}
+	w := sharedParser().Parse(lexer.NewLexer(strings.NewReader(src)), func(s string) {
+		logs = append(logs, s)
+	})
+	if len(logs) == 0 {
+		t.Fatalf("failed to parse %q: no log", src)
+	}
+	if logs[len(logs)-1] != "Parsing completed successfully." {
+		t.Fatalf("failed to parse %q: %v", src, logs[len(logs)-1])
+	}
+	return w
+}
+
+func TestGenRules_Modulo(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name:     "Variable",
+			src:      "{ int a; a = a % 2; }",
+			expected: []string{"$(0x10000001) = a mod 2", "a = $(0x10000001)"},
+		},
+		{
+			name:     "Folded",
+			src:      "{ int a; a = 7 % 3; }",
+			expected: []string{"a = 1"},
+		},
+		{
+			name:     "Parenthesized",
+			src:      "{ int a; a = (a) % (5 % 3); }",
+			expected: []string{"$(0x10000001) = a mod 2", "a = $(0x10000001)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := parseSource(t, tt.src)
+			if !slices.Equal(w.ThreeAddress, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, w.ThreeAddress)
+			}
+		})
+	}
+}
+
+func TestGenRules_Unary(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name:     "Negation",
+			src:      "{ int a; a = -a; }",
+			expected: []string{"$(0x10000001) = minus a", "a = $(0x10000001)"},
+		},
+		{
+			name:     "FoldedLiteral",
+			src:      "{ int a; a = a % -(-3); }",
+			expected: []string{"$(0x10000001) = a mod 3", "a = $(0x10000001)"},
+		},
+		{
+			name:     "Plus",
+			src:      "{ int a; a = +a % +2; }",
+			expected: []string{"$(0x10000001) = a mod 2", "a = $(0x10000001)"},
+		},
+		{
+			name:     "BinaryMinus",
+			src:      "{ int a; a = -a - -1; }",
+			expected: []string{"$(0x10000001) = minus a", "a = $(0x10000001) - -1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := parseSource(t, tt.src)
+			if !slices.Equal(w.ThreeAddress, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, w.ThreeAddress)
+			}
+		})
+	}
+}
+
+func TestGenRules_Comma(t *testing.T) {
+	w := parseSource(t, "{ int a; a = (a % 2, -a, 7) % (1, a); }")
+	expected := []string{
+		"$(0x10000001) = a mod 2",
+		"$(0x10000002) = minus a",
+		"$(0x10000003) = 7 mod a",
+		"a = $(0x10000003)",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+}
+
+func TestGenRules_Assignment(t *testing.T) {
+	w := parseSource(t, "{ int a; int b; a = b = 5 % a; b = (a = 1, a % 2); }")
+	expected := []string{
+		"$(0x10000002) = 5 mod a",
+		"b = $(0x10000002)",
+		"a = b",
+		"a = 1",
+		"$(0x10000003) = a mod 2",
+		"b = $(0x10000003)",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+}
+
+func TestGenRules_Declarators(t *testing.T) {
+	w := parseSource(t, "{ int a, b[10], c; float[2] d, e[3]; }")
+	tests := []struct {
+		name      string
+		kind      SymbolTableItemType
+		address   int
+		arraySize int
+	}{
+		{name: "a", kind: SymbolTableItemTypeVariable, address: 0x10000000, arraySize: 1},
+		{name: "b", kind: SymbolTableItemTypeArray, address: 0x10000001, arraySize: 10},
+		{name: "c", kind: SymbolTableItemTypeVariable, address: 0x1000000b, arraySize: 1},
+		{name: "d", kind: SymbolTableItemTypeArray, address: 0x1000000c, arraySize: 2},
+		{name: "e", kind: SymbolTableItemTypeArray, address: 0x1000000e, arraySize: 6},
+	}
+	scope := w.SymbolTable.LegacyScopes[1]
+	for _, tt := range tests {
+		item, ok := scope.Items[tt.name]
+		if !ok {
+			t.Errorf("Expected %s to be declared", tt.name)
+			continue
+		}
+		if item.Type != tt.kind || item.Address != tt.address || item.ArraySize != tt.arraySize {
+			t.Errorf("Expected %s to be %s at %#x with %d elements, got %s at %#x with %d elements",
+				tt.name, tt.kind, tt.address, tt.arraySize, item.Type, item.Address, item.ArraySize)
+		}
+	}
+}
+
+func TestGenRules_Initializers(t *testing.T) {
+	w := parseSource(t, "{ int a = 7 % 3, b = a % 2; float f = 1; bool g = 1.5; }")
+	expected := []string{
+		"a = $(0x20000000)",
+		"$(0x10000001) = a mod 2",
+		"b = $(0x10000001)",
+		"f = $(0x20000001)",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+	// the int 1 and the float 1 have a slot each
+	if c := w.SymbolTable.Constants[ConstantKey("float", "1")]; c == nil || c.UnderlyingType != "float" || len(w.SymbolTable.Constants) != 2 {
+		t.Errorf("Expected the literals keyed by their type, got %v", w.SymbolTable.Constants)
+	}
+	if _, ok := w.SymbolTable.LegacyScopes[1].Items["g"]; !ok {
+		t.Errorf("Expected g to be declared despite the invalid initializer")
+	}
+}
+
+func TestGenRules_Assignments(t *testing.T) {
+	// what cannot initialize a variable cannot be assigned to it either
+	for src, expected := range map[string]string{
+		`{ int a; string s; a = s; }`:            "cannot assign s (string) to a (int), at line 0, pos 21",
+		`{ string s; s = 1 < 2; }`:               "cannot assign true (bool) to s (string), at line 0, pos 14",
+		`{ int a; float f; a = f; }`:             "cannot assign f (float) to a (int), at line 0, pos 20",
+		`{ int a[2]; int c[2]; a = c; }`:         "array a cannot be assigned c, at line 0, pos 24",
+		`{ int a[2]; a[1] = "x"; }`:              "cannot assign x (string) to a [ 1 ] (int), at line 0, pos 14",
+		`{ int a, b; string s; a = b = s; }`:     "cannot assign s (string) to b (int), at line 0, pos 28",
+		`{ int a; string s; if (a < 1) s = a; }`: "cannot assign a (int) to s (string), at line 0, pos 32",
+	} {
+		result, err := Compile(Options{Source: strings.NewReader(src), Tables: sharedParser().Tables()})
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		if !slices.ContainsFunc(result.Diagnostics, func(d Diagnostic) bool { return d.Message == expected }) {
+			t.Errorf("Expected %q for %s, got %v", expected, src, result.Diagnostics)
+		}
+	}
+	// an int widens to a float, as in a declaration
+	result, err := Compile(Options{Source: strings.NewReader("{ int a; float f; a = 1; f = a; f = a = 2; }"), Tables: sharedParser().Tables()})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", result.Diagnostics)
+	}
+}
+
+func TestGenRules_ArrayInitializers(t *testing.T) {
+	w := parseSource(t, "{ int a[3] = {1, 2 % 2}; { int b[3] = {4, a}; int c[2] = {1, 2, 3}; } }")
+	expected := []string{
+		"b[0] = 4",
+		"b[4] = a",
+		"b[8] = 0",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+	a := w.SymbolTable.LegacyScopes[1].Items["a"]
+	if !slices.Equal(a.Initializer, []string{"1", "0", "0"}) {
+		t.Errorf("Expected a to be initialized with [1 0 0], got %v", a.Initializer)
+	}
+	if len(w.SymbolTable.LegacyScopes) != 3 {
+		t.Errorf("Expected 3 scopes, got %d", len(w.SymbolTable.LegacyScopes))
+	}
+}
+
+func TestGenRules_Static(t *testing.T) {
+	w := parseSource(t, "{ int a; { static int b = 3, c[2] = {1}; int d = 4; } }")
+	expected := []string{"d = $(0x20000000)"}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+	inner := w.SymbolTable.LegacyScopes[2]
+	if b := inner.Items["b"]; !b.Static || !slices.Equal(b.Initializer, []string{"3"}) {
+		t.Errorf("Expected b to be static and initialized with [3], got %v", b)
+	}
+	if c := inner.Items["c"]; !c.Static || !slices.Equal(c.Initializer, []string{"1", "0"}) {
+		t.Errorf("Expected c to be static and initialized with [1 0], got %v", c)
+	}
+	if d := inner.Items["d"]; d.Static {
+		t.Errorf("Expected d not to be static")
+	}
+	if _, ok := w.SymbolTable.LegacyScopes[1].Items["b"]; ok {
+		t.Errorf("Expected b to be visible only in the declaring scope")
+	}
+}
+
+func TestGenRules_Const(t *testing.T) {
+	w := parseSource(t, "{ const int a = 1, t[2] = {1, 2}; const int u; int b; b = a; a = 2; t[0] = 3; b = a = 4; }")
+	expected := []string{"a = $(0x20000000)", "b = a", "b = a"}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+	scope := w.SymbolTable.LegacyScopes[1]
+	if !scope.Items["a"].Const || !scope.Items["t"].Const || scope.Items["b"].Const {
+		t.Errorf("Expected a and t to be const and b not to be")
+	}
+}
+
+func TestGenRules_Strings(t *testing.T) {
+	w := parseSource(t, `{ string s = "ab" + "c"; string u; bool b; u = s + "d"; b = u == s; b = "x" != "x"; }`)
+	expected := []string{
+		"s = $(0x20000000)",
+		`$(0x10000003) = s strcat "d"`,
+		"u = $(0x10000003)",
+		"$(0x10000004) = u streq s",
+		"b = $(0x10000004)",
+		"b = false",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+	if _, ok := w.SymbolTable.Constants[ConstantKey("string", `"abc"`)]; !ok {
+		t.Errorf("Expected the folded literal to be in the string pool")
+	}
+}
+
+func TestGenRules_Printf(t *testing.T) {
+	w := parseSource(t, `{ int a; float f; string s; printf("a=%d, %f%%%s\n", a % 2, f, s); printf("%d", f); }`)
+	expected := []string{
+		"$(0x10000003) = a mod 2",
+		`param "a="`,
+		"call print_str, 1",
+		"param $(0x10000003)",
+		"call print_int, 1",
+		`param ", "`,
+		"call print_str, 1",
+		"param f",
+		"call print_float, 1",
+		`param "%"`,
+		"call print_str, 1",
+		"param s",
+		"call print_str, 1",
+		`param "\n"`,
+		"call print_str, 1",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+}
+
+func TestGenRules_Read(t *testing.T) {
+	w := parseSource(t, `{ int a; float f; a = readint() % 2; f = readfloat(); }`)
+	expected := []string{
+		"$(0x10000002) = call read_int, 0",
+		"$(0x10000003) = $(0x10000002) mod 2",
+		"a = $(0x10000003)",
+		"$(0x10000004) = call read_float, 0",
+		"f = $(0x10000004)",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+}
+
+func TestGenRules_StandardFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name:     "Folded",
+			src:      "{ int a; a = abs(-3) + min(4, 2) + max(4, 2) + pow(2, 10); }",
+			expected: []string{"a = 1033"},
+		},
+		{
+			// by squaring, wrapped around the 32 bits
+			name:     "FoldedPow",
+			src:      "{ int a; a = pow(3, 2000000000); }",
+			expected: []string{"a = 632360961"},
+		},
+		{
+			name: "Abs",
+			src:  "{ int a; a = abs(a); }",
+			expected: []string{
+				"$(0x10000001) = a",
+				"if $(0x10000001) >= 0 goto L_abs_0",
+				"$(0x10000001) = minus $(0x10000001)",
+				"L_abs_0:",
+				"a = $(0x10000001)",
+			},
+		},
+		{
+			name: "Max",
+			src:  "{ int a; a = max(a, 1); }",
+			expected: []string{
+				"$(0x10000001) = a",
+				"if $(0x10000001) >= 1 goto L_max_0",
+				"$(0x10000001) = 1",
+				"L_max_0:",
+				"a = $(0x10000001)",
+			},
+		},
+		{
+			name:     "Pow",
+			src:      "{ int a; a = pow(a, 2); }",
+			expected: []string{"param a", "param 2", "$(0x10000001) = call pow, 2", "a = $(0x10000001)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := parseSource(t, tt.src)
+			if !slices.Equal(w.ThreeAddress, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, w.ThreeAddress)
+			}
+		})
+	}
+
+}
+
+func TestGenRules_FunctionValues(t *testing.T) {
+	w := parseSource(t, "{ func f = abs; int a; f = max; a = f(a, 1); f(); }")
+	expected := []string{
+		"f = &abs",
+		"f = &max",
+		"param a",
+		"param 1",
+		"$(0x10000002) = icall f, 2",
+		"a = $(0x10000002)",
+		"$(0x10000003) = icall f, 0",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+	f := w.SymbolTable.LegacyScopes[1].Items["f"]
+	if f == nil || f.UnderlyingType != "func" || f.VariableSize != 4 {
+		t.Errorf("Expected f to be a func of 4 bytes, got %v", f)
+	}
+
+	// printf is variadic and readfloat is not an integer function
+	w = parseSource(t, "{ func f; float x; f = printf; f = readfloat; f(x); }")
+	if slices.Contains(w.ThreeAddress, "param x") {
+		t.Errorf("Expected the float argument to be rejected, got %v", w.ThreeAddress)
+	}
+}
+
+func TestGenRules_ArrayDecay(t *testing.T) {
+	w := parseSource(t, "{ int a[4], n; float f[2]; n = sum(a, 4); n = sum(a, 5); n = sum(f, 1); n = abs(a); }")
+	expected := []string{
+		"param &a",
+		"param 4",
+		"$(0x10000007) = call sum, 2",
+		"n = $(0x10000007)",
+		"n = $(0x10000008)",
+		"n = $(0x10000009)",
+		"n = $(0x1000000a)",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+}
+
+func TestGenRules_New(t *testing.T) {
+	w := parseSource(t, "{ int* p = new(int); float* q = new(float[10]); int* r = new(float); int* s = new(int[0]); }")
+	expected := []string{
+		"param 4",
+		"$(0x10000000) = call alloc, 1",
+		"p = $(0x10000000)",
+		"param 40",
+		"$(0x10000002) = call alloc, 1",
+		"q = $(0x10000002)",
+		"param 4",
+		"$(0x10000004) = call alloc, 1",
+		"s = $(0x10000006)",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+	p := w.SymbolTable.LegacyScopes[1].Items["p"]
+	if p.UnderlyingType != "pointer" || p.Pointee != "int" || p.VariableSize != 4 {
+		t.Errorf("Expected p to be a pointer to int, got %v", p)
+	}
+}
+
+func TestGenRules_Dereference(t *testing.T) {
+	w := parseSource(t, "{ int* p = new(int[2]); uint8* b = new(uint8); int x; *p = 1; p[1] = *p; x = *b; }")
+	expected := []string{
+		"param 8",
+		"$(0x10000000) = call alloc, 1",
+		"p = $(0x10000000)",
+		"param 1",
+		"$(0x10000002) = call alloc, 1",
+		"b = $(0x10000002)",
+		"param p",
+		"param 0",
+		"param 1",
+		"call store4, 3",
+		"param p",
+		"param 0",
+		"$(0x10000005) = call load4, 2",
+		"param p",
+		"param 4",
+		"param $(0x10000005)",
+		"call store4, 3",
+		"param b",
+		"param 0",
+		"$(0x10000006) = call loadu1, 2",
+		"x = $(0x10000006)",
+	}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %v, got %v", expected, w.ThreeAddress)
+	}
+	for src, expected := range map[string]string{
+		`{ int a; a = *a; }`:                            "cannot dereference a (int), at line 0, pos 15",
+		`{ int* p = new(int); string s; *p = s; }`:      "cannot assign s (string) to * p (int), at line 0, pos 33",
+		`{ int* p = new(int[2]); int x; x = p[1][0]; }`: "cannot index p [ 1 ] (int), at line 0, pos 37",
+	} {
+		result, err := Compile(Options{Source: strings.NewReader(src), Tables: sharedParser().Tables()})
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		if !slices.ContainsFunc(result.Diagnostics, func(d Diagnostic) bool { return d.Message == expected }) {
+			t.Errorf("Expected %q for %s, got %v", expected, src, result.Diagnostics)
+		}
+	}
+}
+
+func TestASTNode_Trivia(t *testing.T) {
+	w := parseSource(t, "// program\n{ int a; a = 1; }  // done\n")
+	root, ok := w.Tokens.Peek()
+	if !ok {
+		t.Fatal("Expected the program node on the token stack")
+	}
+	expected := lexer.Trivia{Leading: "// program", Trailing: "// done"}
+	if root.Trivia() != expected {
+		t.Errorf("Expected %q, got %q", expected, root.Trivia())
+	}
+}
+
+func TestGenRules_Docs(t *testing.T) {
+	w := parseSource(t, `{
+	/// The counter.
+	/// Starts at one.
+	int a = 1, b;
+	// not documented
+	float f;
+	/// A table.
+	static int t[4];
+	{
+		/// Local, left out.
+		int l;
+	}
+}`)
+	if len(w.Docs) != 2 {
+		t.Fatalf("Expected 2 docs, got %v", w.Docs)
+	}
+	if doc := w.Docs[0]; !slices.Equal(doc.Names, []string{"a", "b"}) || doc.Declaration != "int a = 1, b;" || doc.Comment != "The counter.\nStarts at one." {
+		t.Errorf("Unexpected doc %+v", doc)
+	}
+
+	buf := &strings.Builder{}
+	if err := WriteDocs(buf, "test", w.Docs); err != nil {
+		t.Fatal(err)
+	}
+	expected := "# test\n\n## a, b\n\n```c\nint a = 1, b;\n```\n\nThe counter.\nStarts at one.\n\n## t\n\n```c\nstatic int t[4];\n```\n\nA table.\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestGenRules_Comparison(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name:     "Relational",
+			src:      "{ int a; bool b; b = a < 2; b = a >= a; }",
+			expected: []string{"$(0x10000002) = a lt 2", "b = $(0x10000002)", "$(0x10000003) = a ge a", "b = $(0x10000003)"},
+		},
+		{
+			name:     "Equality",
+			src:      "{ int a; bool b; b = a == 1; b = b != true; b = b == 1; }",
+			expected: []string{"$(0x10000002) = a eq 1", "b = $(0x10000002)", "$(0x10000003) = b ne true", "b = $(0x10000003)", "$(0x10000004) = b eq 1", "b = $(0x10000004)"},
+		},
+		{
+			name:     "Conversion",
+			src:      "{ int a; float f; bool b; b = a <= f; b = f > 2; }",
+			expected: []string{"$(0x10000003) = itof a", "$(0x10000004) = $(0x10000003) le f", "b = $(0x10000004)", "$(0x10000005) = f gt 2.0", "b = $(0x10000005)"},
+		},
+		{
+			name:     "Folded",
+			src:      "{ bool b; b = 1 < 2.5; b = 3 != 3; }",
+			expected: []string{"b = true", "b = false"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := parseSource(t, tt.src)
+			if !slices.Equal(w.ThreeAddress, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, w.ThreeAddress)
+			}
+		})
+	}
+
+	for src, expected := range map[string]string{
+		"{ int a[2]; int c[2]; bool b; b = a == c; }": "invalid operands to ==: a is an array",
+		"{ int a; bool b; b = a < true; }":            "invalid operands to <: a (int) and true (bool), numbers required",
+		"{ float f; bool b; b = f == b; }":            "invalid operands to ==: f (float) and b (bool)",
+		`{ string s; bool b; b = s < "x"; }`:          "invalid operands to <: s (string)",
+	} {
+		result, err := Compile(Options{Source: strings.NewReader(src), Tables: sharedParser().Tables()})
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		if !slices.ContainsFunc(result.Diagnostics, func(d Diagnostic) bool { return strings.HasPrefix(d.Message, expected) }) {
+			t.Errorf("Expected %q for %s, got %v", expected, src, result.Diagnostics)
+		}
+	}
+}
+
+func TestGenRules_Void(t *testing.T) {
+	compile := func(src string) []Diagnostic {
+		t.Helper()
+		result, err := Compile(Options{Source: strings.NewReader(src), Tables: sharedParser().Tables()})
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		return result.Diagnostics
+	}
+	diagnostics := compile(`{ int a; a = printf("x") + 1; }`)
+	if len(diagnostics) != 1 || diagnostics[0].Severity != "Error" || !strings.HasPrefix(diagnostics[0].Message, "printf(x) returns void, its value cannot be used") {
+		t.Errorf("Expected the void value to be reported once, got %v", diagnostics)
+	}
+
+	diagnostics = compile("{\n    int a;\n    a = readint();\n    abs(a);\n    readint();\n    printf(\"%d\", a);\n}\n")
+	if len(diagnostics) != 1 || diagnostics[0].Severity != "Warning" ||
+		diagnostics[0].Message != "value of abs(a) is discarded and the call has no side effects, at line 3, pos 7" {
+		t.Errorf("Expected only the call of abs to be reported, got %v", diagnostics)
+	}
+}
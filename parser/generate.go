@@ -0,0 +1,259 @@
+package parser
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"go/format"
+	"maps"
+	"slices"
+	"strconv"
+	"text/template"
+)
+
+// GenerateOptions are how Generate writes the standalone parser.
+type GenerateOptions struct {
+	Package string // package of the generated files, "lrparser" if empty
+	Driver  string // template of the driver, DefaultDriverTemplate if empty
+	Token   string // template of the tokens, DefaultTokenTemplate if empty
+}
+
+// GeneratedParser is the Go source of a standalone parser of the grammar,
+// which needs nothing but the standard library.
+type GeneratedParser struct {
+	Driver []byte // the tables and the loop driving them, parser.go
+	Token  []byte // the tokens and the lexer the driver reads, token.go
+}
+
+// GeneratorData is what the templates are executed with.
+type GeneratorData struct {
+	Package     string
+	End         Terminal   // the terminal of the end of the input
+	Terminals   []Terminal // sorted, without ε
+	Productions []GeneratedProduction
+	States      []GeneratedState // by index
+}
+
+// GeneratedProduction is a production as the driver sees it.
+type GeneratedProduction struct {
+	Index  int
+	Head   Symbol
+	Length int    // symbols popped when reducing, 0 for an ε-production
+	Text   string // "A → α"
+}
+
+// GeneratedState is the row of a state in the action and goto tables.
+type GeneratedState struct {
+	Index   int
+	Actions []GeneratedAction // sorted by terminal
+	Gotos   []GeneratedGoto   // sorted by symbol
+}
+
+// GeneratedAction is a cell of the action table.
+type GeneratedAction struct {
+	Terminal Terminal
+	Type     ActionType // SHIFT, REDUCE or ACCEPT
+	Number   int        // the state shifted to or the production reduced
+}
+
+// GeneratedGoto is a cell of the goto table.
+type GeneratedGoto struct {
+	Symbol Symbol
+	State  int
+}
+
+// DefaultTokenTemplate declares what the default driver needs of the tokens:
+// a Token type, a Lexer handing them out and kindOf, the terminal of a token.
+// A project with its own token type replaces it, say with a Token alias of
+// its type and a kindOf reading the kind of it.
+const DefaultTokenTemplate = `// Code generated by the LR(1) parser generator. DO NOT EDIT.
+
+package {{.Package}}
+
+// Token is a token of the input, its Kind the terminal of the grammar it is.
+type Token interface {
+	Kind() string
+}
+
+// Lexer hands out the tokens of the input, ok is false at the end of it.
+type Lexer interface {
+	Next() (token Token, ok bool)
+}
+
+// kindOf returns the terminal of the token.
+func kindOf(token Token) string {
+	return token.Kind()
+}
+
+// Terminals are the terminals of the grammar, kindOf returns one of them.
+var Terminals = []string{
+{{- range .Terminals}}
+	{{quote .}},
+{{- end}}
+}
+`
+
+// DefaultDriverTemplate is the tables of the grammar and the LR loop, which
+// needs of the token template the Token and Lexer types and kindOf.
+const DefaultDriverTemplate = `// Code generated by the LR(1) parser generator. DO NOT EDIT.
+
+package {{.Package}}
+
+import "fmt"
+
+const (
+	shift = iota + 1
+	reduce
+	accept
+)
+
+type action struct {
+	kind   int
+	number int
+}
+
+// Production is a production of the grammar.
+type Production struct {
+	Head   string
+	Length int // symbols popped when reducing
+	Text   string
+}
+
+// Productions are the productions of the grammar, by the numbers passed to the reductions.
+var Productions = []Production{
+{{- range .Productions}}
+	{ {{- quote .Head}}, {{.Length}}, {{quote .Text -}} },
+{{- end}}
+}
+
+var actions = []map[string]action{
+{{- range .States}}
+	{ {{- range $i, $a := .Actions}}{{if $i}}, {{end}}{{quote $a.Terminal}}: { {{- kind $a.Type}}, {{$a.Number -}} }{{end -}} },
+{{- end}}
+}
+
+var gotos = []map[string]int{
+{{- range .States}}
+	{ {{- range $i, $g := .Gotos}}{{if $i}}, {{end}}{{quote $g.Symbol}}: {{$g.State}}{{end -}} },
+{{- end}}
+}
+
+// Parse parses the tokens of the lexer. A token shifted is the value of its
+// symbol, and the value of the head of a production reduced is what reduce
+// returns of the values of the body, nil if reduce is nil. Parse returns
+// the value of the start symbol.
+func Parse(lexer Lexer, reduce func(production int, values []any) any) (any, error) {
+	states := []int{0}
+	values := []any{}
+	token, ok := lexer.Next()
+	for {
+		kind := {{quote .End}}
+		if ok {
+			kind = kindOf(token)
+		}
+		state := states[len(states)-1]
+		act, found := actions[state][kind]
+		if !found {
+			return nil, fmt.Errorf("syntax error: unexpected %s in state %d", kind, state)
+		}
+		switch act.kind {
+		case shift:
+			states = append(states, act.number)
+			values = append(values, token)
+			token, ok = lexer.Next()
+		case accept:
+			return values[len(values)-1], nil
+		default:
+			production := Productions[act.number]
+			body := values[len(values)-production.Length:]
+			var value any
+			if reduce != nil {
+				value = reduce(act.number, body)
+			}
+			states = states[:len(states)-production.Length]
+			values = append(values[:len(values)-production.Length], value)
+			states = append(states, gotos[states[len(states)-1]][production.Head])
+		}
+	}
+}
+`
+
+// generatorFuncs are the functions the templates may call besides the
+// builtin ones: quote, the Go literal of a string, and kind, the constant
+// of the driver for the type of an action.
+var generatorFuncs = template.FuncMap{
+	"quote": func(s any) string { return strconv.Quote(fmt.Sprint(s)) },
+	"kind": func(t ActionType) (string, error) {
+		switch t {
+		case SHIFT, REDUCE, ACCEPT:
+			return string(t), nil
+		}
+		return "", fmt.Errorf("no action %s in a generated parser", t)
+	},
+}
+
+// GeneratorData returns the data the templates are executed with.
+func (p *Parser) GeneratorData(pkg string) *GeneratorData {
+	p.EnsureTable()
+	p.EnsureStates()
+	data := &GeneratorData{Package: cmp.Or(pkg, "lrparser"), End: TERMINATE}
+	for terminal := range p.Grammar.Terminals {
+		if !terminal.IsEpsilon() {
+			data.Terminals = append(data.Terminals, terminal)
+		}
+	}
+	slices.Sort(data.Terminals)
+	for i, production := range p.Grammar.Productions {
+		data.Productions = append(data.Productions, GeneratedProduction{
+			Index:  i,
+			Head:   production.Head,
+			Length: production.Length(),
+			Text:   formatProduction(production),
+		})
+	}
+	for i := range p.States {
+		state := GeneratedState{Index: i}
+		for _, terminal := range p.Table.Expected(i) {
+			action, _ := p.Table.Lookup(i, terminal)
+			state.Actions = append(state.Actions, GeneratedAction{Terminal: terminal, Type: action.Type, Number: action.Number})
+		}
+		for _, symbol := range slices.Sorted(maps.Keys(p.Table.GotoTable[i])) {
+			state.Gotos = append(state.Gotos, GeneratedGoto{Symbol: symbol, State: p.Table.GotoTable[i][symbol]})
+		}
+		data.States = append(data.States, state)
+	}
+	return data
+}
+
+// Generate writes the parser of the grammar as Go source, to be embedded in
+// another project. The templates are text/template ones executed with the
+// GeneratorData, and their output must be valid Go, which is formatted.
+func (p *Parser) Generate(opts GenerateOptions) (*GeneratedParser, error) {
+	data := p.GeneratorData(opts.Package)
+	driver, err := generate("driver", cmp.Or(opts.Driver, DefaultDriverTemplate), data)
+	if err != nil {
+		return nil, err
+	}
+	token, err := generate("token", cmp.Or(opts.Token, DefaultTokenTemplate), data)
+	if err != nil {
+		return nil, err
+	}
+	return &GeneratedParser{Driver: driver, Token: token}, nil
+}
+
+// generate executes the template and formats its output.
+func generate(name, text string, data *GeneratorData) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(generatorFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template of the %s: %w", name, err)
+	}
+	buf := &bytes.Buffer{}
+	if err = tmpl.Execute(buf, data); err != nil {
+		return nil, fmt.Errorf("template of the %s: %w", name, err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("template of the %s does not make valid Go: %w", name, err)
+	}
+	return src, nil
+}
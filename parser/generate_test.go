@@ -0,0 +1,158 @@
+package parser_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+// tokenTemplate embeds the parser in a project whose tokens are structs.
+const tokenTemplate = `package {{.Package}}
+
+type Token struct {
+	Type, Text string
+}
+
+type Lexer interface {
+	Next() (Token, bool)
+}
+
+func kindOf(token Token) string {
+	return token.Type
+}
+`
+
+// mainProgram parses id + id * ( id ), bracketing every reduction of the
+// productions of two or more symbols.
+const mainProgram = `package main
+
+import (
+	"fmt"
+	"strings"
+
+	"example.com/calc/calc"
+)
+
+type lexer []calc.Token
+
+func (l *lexer) Next() (calc.Token, bool) {
+	if len(*l) == 0 {
+		return calc.Token{}, false
+	}
+	token := (*l)[0]
+	*l = (*l)[1:]
+	return token, true
+}
+
+func main() {
+	l := &lexer{}
+	for _, text := range strings.Fields("a + b * ( c )") {
+		kind := text
+		if text >= "a" && text <= "z" {
+			kind = "id"
+		}
+		*l = append(*l, calc.Token{Type: kind, Text: text})
+	}
+	value, err := calc.Parse(l, func(production int, values []any) any {
+		if len(values) == 1 {
+			if token, ok := values[0].(calc.Token); ok {
+				return token.Text
+			}
+			return values[0]
+		}
+		parts := []string{}
+		for _, v := range values {
+			if token, ok := v.(calc.Token); ok {
+				v = token.Text
+			}
+			parts = append(parts, fmt.Sprint(v))
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	})
+	fmt.Println(value, err)
+	_, err = calc.Parse(&lexer{{Type: "id"}, {Type: "id"}}, nil)
+	fmt.Println(err)
+}
+`
+
+func TestParser_Generate(t *testing.T) {
+	p := &Parser{
+		Grammar: &Grammar{
+			AugmentedProduction: Production{Head: "E'", Body: []Symbol{"E"}},
+			Productions: []Production{
+				{Head: "E", Body: []Symbol{"E", "+", "T"}},
+				{Head: "E", Body: []Symbol{"T"}},
+				{Head: "T", Body: []Symbol{"T", "*", "F"}},
+				{Head: "T", Body: []Symbol{"F"}},
+				{Head: "F", Body: []Symbol{"(", "E", ")"}},
+				{Head: "F", Body: []Symbol{"id"}},
+			},
+			Terminals: Set[Terminal]{}.AddAll("(", ")", "+", "*", "id", EPSILON, TERMINATE),
+		},
+	}
+	p.BuildFirstSet()
+	p.BuildTable()
+
+	generated, err := p.Generate(GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for _, expected := range []string{"package lrparser", `{"E", 3, "E → E + T"}`, "func Parse(lexer Lexer"} {
+		if !strings.Contains(string(generated.Driver), expected) {
+			t.Errorf("Expected the driver to contain %q, got\n%s", expected, generated.Driver)
+		}
+	}
+	if !strings.Contains(string(generated.Token), "Kind() string") || !strings.Contains(string(generated.Token), `"id",`) {
+		t.Errorf("Expected the default token interface, got\n%s", generated.Token)
+	}
+
+	for opts, expected := range map[GenerateOptions]string{
+		{Driver: "package x\n{{.Missing}}"}: "template of the driver: ",
+		{Token: "package {{.Package"}:       "template of the token: ",
+		{Token: "package x\nfunc {"}:        "template of the token does not make valid Go: ",
+	} {
+		if _, err := p.Generate(opts); err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("%q: expected an error starting with %q, got %v", opts, expected, err)
+		}
+	}
+
+	// the parser generated with the tokens of another project builds and runs there
+	goTool, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		t.Skip("no go tool to build the generated parser with")
+	}
+	generated, err = p.Generate(GenerateOptions{Package: "calc", Token: tokenTemplate})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod":         "module example.com/calc\n\ngo 1.21\n",
+		"main.go":        mainProgram,
+		"calc/parser.go": string(generated.Driver),
+		"calc/token.go":  string(generated.Token),
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(goTool, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s", err, out)
+	}
+	expected := "[a + [b * [( c )]]] <nil>\nsyntax error: unexpected id in state 5\n"
+	if string(out) != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+}
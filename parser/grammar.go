@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"maps"
 	"slices"
 
 	. "app/utils/collections"
@@ -10,13 +11,32 @@ type Grammar struct {
 	AugmentedProduction Production
 	Productions         []Production
 	Terminals           Set[Terminal]
+
+	// conflicts that are intended, such as the dangling else resolved by
+	// shifting, see Parser.CheckConflicts
+	ExpectedConflicts       int
+	ExpectedReduceConflicts int
+
+	// the precedences declared, which resolve the shift/reduce conflicts
+	// between the terminals and the productions they end, see SetPrecedence
+	Precedence map[Terminal]Precedence
+
+	// the names of the terminals in the messages and the reports, see Name
+	Aliases map[Terminal]string
+
+	// the FIRST and FOLLOW sets, computed on the first call of First, Follow
+	// or Nullable, and not copied by Copy
+	sets *grammarSets
 }
 
 func NewGrammar() *Grammar {
 	return &Grammar{
-		AugmentedProduction: AugmentedProduction,
-		Productions:         Productions,
-		Terminals:           Terminals,
+		AugmentedProduction:     AugmentedProduction,
+		Productions:             Productions,
+		Terminals:               Terminals,
+		ExpectedConflicts:       ExpectedConflicts,
+		ExpectedReduceConflicts: ExpectedReduceConflicts,
+		Aliases:                 Aliases,
 	}
 }
 
@@ -26,6 +46,11 @@ func (g *Grammar) Copy() Grammar {
 		AugmentedProduction: g.AugmentedProduction,
 		Productions:         slices.Clone(g.Productions),
 		Terminals:           g.Terminals.Copy(),
+
+		ExpectedConflicts:       g.ExpectedConflicts,
+		ExpectedReduceConflicts: g.ExpectedReduceConflicts,
+		Precedence:              maps.Clone(g.Precedence),
+		Aliases:                 maps.Clone(g.Aliases),
 	}
 }
 
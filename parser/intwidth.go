@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"app/lexer"
+)
+
+// IntWidth is the width of int in bits, 16, 32 or 64, for the same programs
+// to be compiled for machines of different words: the integer literals are
+// checked against its range, the constants folded wrap around it as the
+// machine computes them, the variables of int take its bytes and the
+// backends select the instructions of its width. The zero IntWidth is 32.
+type IntWidth int
+
+// IntWidths are the widths a program may be compiled with.
+var IntWidths = []IntWidth{16, 32, 64}
+
+// Valid checks if the width is one of IntWidths, or zero.
+func (width IntWidth) Valid() bool {
+	return width == 0 || slices.Contains(IntWidths, width)
+}
+
+// Bits returns the bits of int.
+func (width IntWidth) Bits() int {
+	if width == 0 {
+		return 32
+	}
+	return int(width)
+}
+
+// Bytes returns the bytes a value of int takes.
+func (width IntWidth) Bytes() int {
+	return width.Bits() / 8
+}
+
+// Min returns the smallest value of int.
+func (width IntWidth) Min() int64 {
+	return -1 << (width.Bits() - 1)
+}
+
+// Max returns the largest value of int.
+func (width IntWidth) Max() int64 {
+	return 1<<(width.Bits()-1) - 1
+}
+
+// Wrap returns the value in the two's complement of the width, as the
+// arithmetic of the machine leaves it: 32768 is -32768 in 16 bits.
+func (width IntWidth) Wrap(v int64) int64 {
+	shift := 64 - width.Bits()
+	return v << shift >> shift
+}
+
+// CheckLiteral checks that the integer literal, as written, is within the
+// range of int. The literal negated may be one more than Max, as -32768 is
+// the negation of 32768 in 16 bits.
+func (width IntWidth) CheckLiteral(text string, negated bool) error {
+	limit := uint64(width.Max())
+	if negated {
+		limit++
+	}
+	if v, err := strconv.ParseUint(text, 0, 64); err != nil || v > limit {
+		return fmt.Errorf("integer literal %s out of range of the %d-bit int", text, width.Bits())
+	}
+	return nil
+}
+
+// Simplify is Simplify folding the integers in the width.
+func (width IntWidth) Simplify(value string) string {
+	return simplify(value, width)
+}
+
+// Peephole is Peephole folding the integers in the width.
+func (width IntWidth) Peephole(code []string) []string {
+	return peephole(code, width)
+}
+
+// EliminateCommonSubexpressions is EliminateCommonSubexpressions folding the
+// integers in the width.
+func (width IntWidth) EliminateCommonSubexpressions(code []string) []string {
+	return eliminateCommon(code, width)
+}
+
+// basic returns the basic type of the kind, int in the width of the session.
+func (w *Walker) basic(kind lexer.TokenSpecificType) *BasicType {
+	return &BasicType{Kind: kind, IntWidth: w.IntWidth}
+}
+
+// intLiteral returns the node of a folded integer constant, wrapped around
+// the width of int of the session.
+func (w *Walker) intLiteral(v int64, children []*ASTNode) *ASTNode {
+	return NewIntLiteral(w.IntWidth.Wrap(v), children)
+}
@@ -0,0 +1,89 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser"
+	"app/parser/ir"
+)
+
+func TestIntWidth(t *testing.T) {
+	for _, test := range []struct {
+		width      IntWidth
+		bits       int
+		min, max   int64
+		wrapped    int64 // of max + 1
+		simplified string
+	}{
+		{16, 16, -32768, 32767, -32768, "-32768"},
+		{0, 32, -2147483648, 2147483647, -2147483648, "32768"},
+		{64, 64, -1 << 63, 1<<63 - 1, -1 << 63, "32768"},
+	} {
+		w := test.width
+		if w.Bits() != test.bits || w.Bytes() != test.bits/8 || w.Min() != test.min || w.Max() != test.max || w.Wrap(w.Max()+1) != test.wrapped {
+			t.Errorf("Expected %d bits from %d to %d, got %d from %d to %d", test.bits, test.min, test.max, w.Bits(), w.Min(), w.Max())
+		}
+		if s := w.Simplify("32767 + 1"); s != test.simplified {
+			t.Errorf("Expected 32767 + 1 folded to %s in %d bits, got %s", test.simplified, test.bits, s)
+		}
+	}
+	if Simplify("2147483647 * 2") != "-2" || !IntWidth(0).Valid() || IntWidth(8).Valid() {
+		t.Error("Expected the default width of 32 bits")
+	}
+	if err := IntWidth(16).CheckLiteral("32768", false); err == nil || err.Error() != "integer literal 32768 out of range of the 16-bit int" {
+		t.Errorf("Expected 32768 out of the range of 16 bits, got %v", err)
+	}
+	if IntWidth(16).CheckLiteral("32768", true) != nil || IntWidth(16).CheckLiteral("0x7fff", false) != nil {
+		t.Error("Expected -32768 and 0x7fff in the range of 16 bits")
+	}
+	quads := ir.Optimize([]ir.Quad{{Op: "*", Arg1: "300", Arg2: "300", Result: "a"}}, ir.FoldConstantsIn(16))
+	if quads[0].Arg1 != "24464" {
+		t.Errorf("Expected the quadruples folded in 16 bits, got %v", quads)
+	}
+}
+
+func TestCompile_IntWidth(t *testing.T) {
+	compile := func(width IntWidth, source string) *Result {
+		t.Helper()
+		result, err := Compile(Options{Source: strings.NewReader(source), IntWidth: width})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	result := compile(16, "{ int a; int b; a = 32767 + 1; b = -32768; b = 32768; }\n")
+	if len(result.Diagnostics) != 1 || !strings.HasPrefix(result.Diagnostics[0].Message, "integer literal 32768 out of range of the 16-bit int") {
+		t.Fatalf("Expected the literal beyond 16 bits alone to fail, got %v", result.Diagnostics)
+	}
+	if code := strings.Join(result.Walker.ThreeAddress, "\n"); !strings.Contains(code, "a = -32768") {
+		t.Errorf("Expected the sum folded around 16 bits, got\n%s", code)
+	}
+
+	for width, size := range map[IntWidth]int{16: 2, 0: 4, 64: 8} {
+		result = compile(width, "{ int a; int8 b; a = 2147483647; }\n")
+		item := result.Walker.SymbolTable.LegacyScopes[1].Items["a"]
+		if other := result.Walker.SymbolTable.LegacyScopes[1].Items["b"]; item == nil || item.VariableSize != size || other.VariableSize != 1 {
+			t.Errorf("Expected the int of %d bytes and the int8 of 1, got %+v", size, item)
+		}
+		if result.Failed() != (width == 16) {
+			t.Errorf("Expected 2147483647 out of the range of 16 bits alone, got %v", result.Diagnostics)
+		}
+	}
+
+	result = compile(64, "{ int a; a = pow(2, 9000000000000000000) + pow(3, 5); }\n")
+	if code := strings.Join(result.Walker.ThreeAddress, "\n"); code != "a = 243" {
+		t.Errorf("Expected pow folded by squaring around 64 bits, got\n%s", code)
+	}
+	result = compile(16, "{ int a; a = abs(-32768); }\n")
+	if code := strings.Join(result.Walker.ThreeAddress, "\n"); code != "a = -32768" {
+		t.Errorf("Expected abs of the smallest int to stay itself, got\n%s", code)
+	}
+	if len(result.Diagnostics) != 1 || !strings.HasPrefix(result.Diagnostics[0].Message, "abs(-32768) overflows the 16-bit int") {
+		t.Errorf("Expected a warning of abs, got %v", result.Diagnostics)
+	}
+
+	if _, err := Compile(Options{Source: strings.NewReader("{}\n"), IntWidth: 8}); err == nil {
+		t.Error("Expected an int of 8 bits to fail")
+	}
+}
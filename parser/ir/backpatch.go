@@ -0,0 +1,86 @@
+package ir
+
+// List is the jumps whose target is not known yet when they are emitted, by
+// the index of their quadruple, for Backpatch to fill in once it is: the
+// truelist, falselist and nextlist of the translation schemes.
+type List []int
+
+// Makelist returns the list of the jump at i alone.
+func Makelist(i int) List {
+	return List{i}
+}
+
+// Merge returns the jumps of the lists, in their order.
+func Merge(lists ...List) List {
+	var merged List
+	for _, l := range lists {
+		merged = append(merged, l...)
+	}
+	return merged
+}
+
+// Bool is a boolean expression translated into jumps, those taken when it
+// holds and those taken when it does not.
+type Bool struct {
+	True, False List
+}
+
+// NextQuad returns the index the next quadruple is emitted at.
+func (e *Emitter) NextQuad() int {
+	if e.Stream != nil {
+		return e.Stream.Len()
+	}
+	return len(e.Quads)
+}
+
+// Backpatch sets the label as the target of the jumps of the list.
+func (e *Emitter) Backpatch(l List, label string) {
+	for _, i := range l {
+		if e.Stream != nil {
+			e.Stream.Patch(i, label)
+		} else {
+			e.Quads[i].Result = label
+		}
+	}
+}
+
+// Mark places a new label for the kind of construct at the next quadruple
+// and returns it, the marker nonterminal M of the translation schemes.
+func (e *Emitter) Mark(kind string) string {
+	label := e.NewLabel(kind)
+	e.Label(label)
+	return label
+}
+
+// Hole emits a jump to a target not known yet and returns the list of it.
+func (e *Emitter) Hole() List {
+	l := Makelist(e.NextQuad())
+	e.Goto("")
+	return l
+}
+
+// Relop emits the jumps of the comparison arg1 relop arg2, to the targets
+// not known yet.
+func (e *Emitter) Relop(arg1, relop, arg2 string) Bool {
+	b := Bool{True: Makelist(e.NextQuad())}
+	e.If(arg1, relop, arg2, "")
+	b.False = e.Hole()
+	return b
+}
+
+// And returns b1 && b2, where b2 is emitted after the marker m.
+func (e *Emitter) And(b1 Bool, m string, b2 Bool) Bool {
+	e.Backpatch(b1.True, m)
+	return Bool{True: b2.True, False: Merge(b1.False, b2.False)}
+}
+
+// Or returns b1 || b2, where b2 is emitted after the marker m.
+func (e *Emitter) Or(b1 Bool, m string, b2 Bool) Bool {
+	e.Backpatch(b1.False, m)
+	return Bool{True: Merge(b1.True, b2.True), False: b2.False}
+}
+
+// Not returns !b.
+func Not(b Bool) Bool {
+	return Bool{True: b.False, False: b.True}
+}
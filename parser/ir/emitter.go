@@ -0,0 +1,92 @@
+package ir
+
+import "fmt"
+
+// Emitter is the stream of intermediate code the semantic actions append to,
+// with the temporaries and the labels they need. Without the functions it
+// names the temporaries t1, t2, ... and the labels L1, L2, ... itself, and
+// without a Stream it keeps the quadruples in Quads.
+type Emitter struct {
+	Quads []Quad
+
+	Temps  func() string            // allocates a temporary
+	Labels func(kind string) string // allocates a label for the kind of construct
+	Stream Stream                   // receives the quadruples instead of Quads
+
+	temps, labels int
+}
+
+// Stream is code kept elsewhere that an Emitter appends to, such as the
+// text of the code of the parser.
+type Stream interface {
+	Append(q Quad)
+	Len() int
+	Patch(i int, label string) // sets the target of the jump at i, see Emitter.Backpatch
+}
+
+// NewTemp returns a new temporary.
+func (e *Emitter) NewTemp() string {
+	if e.Temps != nil {
+		return e.Temps()
+	}
+	e.temps++
+	return fmt.Sprintf("t%d", e.temps)
+}
+
+// NewLabel returns a new label for the kind of construct, such as if or loop.
+func (e *Emitter) NewLabel(kind string) string {
+	if e.Labels != nil {
+		return e.Labels(kind)
+	}
+	e.labels++
+	return fmt.Sprintf("L%d", e.labels)
+}
+
+// Emit appends the quadruple.
+func (e *Emitter) Emit(q Quad) {
+	if e.Stream != nil {
+		e.Stream.Append(q)
+		return
+	}
+	e.Quads = append(e.Quads, q)
+}
+
+// Assign emits result = arg1 op arg2, result = op arg1 without arg2 or the
+// copy result = arg1 without op.
+func (e *Emitter) Assign(result, op, arg1, arg2 string) {
+	if op == "" {
+		op = Copy
+	}
+	e.Emit(Quad{Op: op, Arg1: arg1, Arg2: arg2, Result: result})
+}
+
+// Temp emits the operation into a new temporary and returns it.
+func (e *Emitter) Temp(op, arg1, arg2 string) string {
+	t := e.NewTemp()
+	e.Assign(t, op, arg1, arg2)
+	return t
+}
+
+// Label places the label at the next quadruple.
+func (e *Emitter) Label(label string) {
+	e.Emit(Quad{Op: Label, Result: label})
+}
+
+// Goto emits a jump to the label.
+func (e *Emitter) Goto(label string) {
+	e.Emit(Quad{Op: Goto, Result: label})
+}
+
+// If emits a jump to the label taken if arg1 relop arg2 holds.
+func (e *Emitter) If(arg1, relop, arg2, label string) {
+	e.Emit(Quad{Op: Jump(relop), Arg1: arg1, Arg2: arg2, Result: label})
+}
+
+// Call emits the parameters and the call of the function, its value stored
+// into result unless empty.
+func (e *Emitter) Call(result, function string, args ...string) {
+	for _, arg := range args {
+		e.Emit(Quad{Op: Param, Arg1: arg})
+	}
+	e.Emit(Quad{Op: Call, Arg1: function, Arg2: fmt.Sprint(len(args)), Result: result})
+}
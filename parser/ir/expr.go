@@ -0,0 +1,87 @@
+package ir
+
+import "strings"
+
+// Expr is an operand written as an expression, such as n * 4 + 1, as the
+// parser leaves the operations it has no rule of in the text of the code:
+// an operand at a leaf, or an operator on its two children.
+type Expr struct {
+	Op       string
+	Operand  string
+	Children []*Expr
+}
+
+// IsLeaf checks if the expression is a single operand.
+func (e *Expr) IsLeaf() bool {
+	return e.Op == ""
+}
+
+// precedences are the levels of the operators in an operand written as an
+// expression, the relations binding the least.
+var precedences = map[string]int{
+	"<": 1, "<=": 1, ">": 1, ">=": 1, "==": 1, "!=": 1,
+	"lt": 1, "le": 1, "gt": 1, "ge": 1, "eq": 1, "ne": 1,
+	"+": 2, "-": 2,
+	"*": 3, "/": 3, "%": 3, "mod": 3,
+}
+
+// ExprFields splits the operand at its spaces, keeping an index in brackets
+// with the name before it and a string literal whole.
+func ExprFields(operand string) []string {
+	if strings.HasPrefix(operand, `"`) {
+		return []string{operand}
+	}
+	var fields []string
+	for _, field := range strings.Fields(operand) {
+		n := len(fields)
+		if n > 0 && (strings.HasPrefix(field, "[") || strings.Count(fields[n-1], "[") > strings.Count(fields[n-1], "]")) {
+			fields[n-1] += " " + field
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// ParseExpr parses the operand into its tree by the precedences of the
+// operators, from the left, false if it is not made of operands and the
+// operators of the arithmetic and the relations.
+func ParseExpr(operand string) (*Expr, bool) {
+	fields := ExprFields(operand)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	p := &exprParser{fields: fields}
+	e := p.parse(0)
+	if e == nil || p.i != len(fields) {
+		return nil, false
+	}
+	return e, true
+}
+
+type exprParser struct {
+	fields []string
+	i      int
+}
+
+func (p *exprParser) parse(min int) *Expr {
+	if p.i >= len(p.fields) || precedences[p.fields[p.i]] != 0 {
+		return nil
+	}
+	left := &Expr{Operand: p.fields[p.i]}
+	p.i++
+	for p.i < len(p.fields) {
+		op := p.fields[p.i]
+		level := precedences[op]
+		if level == 0 || level < min {
+			break
+		}
+		p.i++
+		right := p.parse(level + 1)
+		if right == nil {
+			return nil
+		}
+		left = &Expr{Op: op, Children: []*Expr{left, right}}
+	}
+	return left
+}
@@ -0,0 +1,266 @@
+// Package ir is the intermediate code as quadruples, the instructions of
+// three-address code taken apart into their operator, operands and result,
+// with the text the parser emits them as and the classic dump of the
+// textbooks.
+package ir
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"app/utils/report"
+)
+
+// Quad is an instruction of three-address code:
+//
+//	result = arg1 op arg2    (op, arg1, arg2, result)
+//	result = op arg1         (op, arg1, -, result)
+//	result = arg1            (=, arg1, -, result)
+//	L:                       (label, -, -, L)
+//	goto L                   (goto, -, -, L)
+//	if arg1 relop arg2 goto L  (jrelop, arg1, arg2, L)
+//	param arg1               (param, arg1, -, -)
+//	result = call f, n       (call, f, n, result), the result empty for a call as a statement
+//	begin x                  (begin, x, -, -), the local x comes to life
+//	end x                    (end, x, -, -), the local x is dead from here
+//
+// and icall as call, the operands of the other instructions in Arg1 and Arg2,
+// such as (push, fp, -, -) or (ret, -, -, -).
+type Quad struct {
+	Op, Arg1, Arg2, Result string
+}
+
+// the operators of the instructions that are not assignments
+const (
+	Copy  = "="
+	Label = "label"
+	Goto  = "goto"
+	Param = "param"
+	Call  = "call"
+	ICall = "icall"
+	Begin = "begin"
+	End   = "end"
+)
+
+// Jump returns the operator of the conditional jump on the relation.
+func Jump(relop string) string {
+	return "j" + relop
+}
+
+// IsJump checks if the quadruple jumps, conditionally or not.
+func (q Quad) IsJump() bool {
+	return q.Op == Goto || q.Relop() != ""
+}
+
+// IsLifetime checks if the quadruple is the begin or the end of the lifetime
+// of a local, which no code runs for.
+func (q Quad) IsLifetime() bool {
+	return (q.Op == Begin || q.Op == End) && q.Result == ""
+}
+
+// Relop returns the relation of a conditional jump, empty for the other
+// instructions.
+func (q Quad) Relop() string {
+	if relop, ok := strings.CutPrefix(q.Op, "j"); ok && q.Arg2 != "" {
+		return relop
+	}
+	return ""
+}
+
+// String returns the quadruple as (op, arg1, arg2, result), with - for the
+// fields not used.
+func (q Quad) String() string {
+	field := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	return fmt.Sprintf("(%s, %s, %s, %s)", q.Op, field(q.Arg1), field(q.Arg2), field(q.Result))
+}
+
+// TAC returns the quadruple as the line of three-address code the parser
+// emits, which Parse reads back.
+func (q Quad) TAC() string {
+	switch {
+	case q.Op == Label:
+		return q.Result + ":"
+	case q.Op == Goto:
+		return "goto " + q.Result
+	case q.Relop() != "":
+		return fmt.Sprintf("if %s %s %s goto %s", q.Arg1, q.Relop(), q.Arg2, q.Result)
+	case q.Op == Call || q.Op == ICall:
+		if q.Result == "" {
+			return fmt.Sprintf("%s %s, %s", q.Op, q.Arg1, q.Arg2)
+		}
+		return fmt.Sprintf("%s = %s %s, %s", q.Result, q.Op, q.Arg1, q.Arg2)
+	case q.Result == "":
+		return strings.Join(nonEmpty(q.Op, q.Arg1, q.Arg2), " ")
+	case q.Op == Copy:
+		return q.Result + " = " + q.Arg1
+	case q.Arg2 == "":
+		return fmt.Sprintf("%s = %s %s", q.Result, q.Op, q.Arg1)
+	}
+	return fmt.Sprintf("%s = %s %s %s", q.Result, q.Arg1, q.Op, q.Arg2)
+}
+
+func nonEmpty(fields ...string) []string {
+	var result []string
+	for _, field := range fields {
+		if field != "" {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// Parse takes the line of three-address code apart, the inverse of TAC.
+func Parse(line string) Quad {
+	if label, ok := strings.CutSuffix(line, ":"); ok && !strings.Contains(label, " ") {
+		return Quad{Op: Label, Result: label}
+	}
+	if label, ok := strings.CutPrefix(line, "goto "); ok {
+		return Quad{Op: Goto, Result: label}
+	}
+	if condition, ok := strings.CutPrefix(line, "if "); ok {
+		if i := strings.LastIndex(condition, " goto "); i >= 0 {
+			if fields := operands(condition[:i]); len(fields) == 3 {
+				return Quad{Op: Jump(fields[1]), Arg1: fields[0], Arg2: fields[2], Result: condition[i+len(" goto "):]}
+			}
+		}
+	}
+	result, value, ok := strings.Cut(line, " = ")
+	if !ok {
+		// param x, call f, n, push fp, ret and the like
+		op, rest, _ := strings.Cut(line, " ")
+		if op == Call || op == ICall {
+			function, n, _ := strings.Cut(rest, ", ")
+			return Quad{Op: op, Arg1: function, Arg2: n}
+		}
+		fields := operands(rest)
+		q := Quad{Op: op}
+		if len(fields) > 0 {
+			q.Arg1 = strings.Join(fields[:len(fields)-len(fields)/2], " ")
+		}
+		if len(fields) > 1 {
+			q.Arg2 = strings.Join(fields[len(fields)-len(fields)/2:], " ")
+		}
+		return q
+	}
+	for _, op := range []string{Call, ICall} {
+		if call, ok := strings.CutPrefix(value, op+" "); ok {
+			function, n, _ := strings.Cut(call, ", ")
+			return Quad{Op: op, Arg1: function, Arg2: n, Result: result}
+		}
+	}
+	switch fields := operands(value); len(fields) {
+	case 2:
+		return Quad{Op: fields[0], Arg1: fields[1], Result: result}
+	case 3:
+		return Quad{Op: fields[1], Arg1: fields[0], Arg2: fields[2], Result: result}
+	}
+	return Quad{Op: Copy, Arg1: value, Result: result}
+}
+
+// ParseAll takes the lines of three-address code apart.
+func ParseAll(code []string) []Quad {
+	quads := make([]Quad, len(code))
+	for i, line := range code {
+		quads[i] = Parse(line)
+	}
+	return quads
+}
+
+// operands splits the text into its operands at the spaces outside of the
+// quoted strings, keeping an index in brackets with the operand before it.
+func operands(s string) []string {
+	var fields []string
+	start, quoted, depth := -1, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+			if start < 0 {
+				start = i
+			}
+		case quoted:
+		case c == '[':
+			depth++
+			if start < 0 {
+				// a [ 1 ] is a single operand
+				if len(fields) > 0 {
+					start = i - len(fields[len(fields)-1]) - 1
+					fields = fields[:len(fields)-1]
+				} else {
+					start = i
+				}
+			}
+		case c == ']':
+			depth--
+		case c == ' ' && depth == 0:
+			if start >= 0 {
+				fields, start = append(fields, s[start:i]), -1
+			}
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, s[start:])
+	}
+	return fields
+}
+
+// Dump writes the quadruples numbered from 0, one per line, in the classic
+// (op, arg1, arg2, result) form.
+func Dump(w io.Writer, quads []Quad) error {
+	b := bufio.NewWriter(w)
+	width := report.NumberWidth(len(quads) - 1)
+	for i, q := range quads {
+		fmt.Fprintf(b, "%*d: %s\n", width, i, q)
+	}
+	return b.Flush()
+}
+
+// LabStart is the number of the first quadruple in the format of the lab, as
+// the textbook numbers them.
+const LabStart = 100
+
+// DumpLab writes the quadruples in the format of the lab: numbered from
+// start, the labels dropped and the jumps to them jumping to the number of
+// the quadruple after, goto written as j. A label at the end stands for the
+// number past the last quadruple.
+func DumpLab(w io.Writer, quads []Quad, start int) error {
+	targets := map[string]int{}
+	n := start
+	for _, q := range quads {
+		if q.Op == Label {
+			targets[q.Result] = n
+		} else {
+			n++
+		}
+	}
+	b := bufio.NewWriter(w)
+	width := report.NumberWidth(max(n-1, start))
+	n = start
+	for _, q := range quads {
+		if q.Op == Label {
+			continue
+		}
+		if target, ok := targets[q.Result]; ok && q.IsJump() {
+			q.Result = fmt.Sprint(target)
+		}
+		if q.Op == Goto {
+			q.Op = "j"
+		}
+		fmt.Fprintf(b, "(%*d) %s\n", width, n, q)
+		n++
+	}
+	return b.Flush()
+}
@@ -0,0 +1,124 @@
+package ir_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser/ir"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected Quad
+	}{
+		{"a = b", Quad{Op: "=", Arg1: "b", Result: "a"}},
+		{"$(0x10000004) = minus a", Quad{Op: "minus", Arg1: "a", Result: "$(0x10000004)"}},
+		{"$(0x10000004) = a [ 1 ] mod 8", Quad{Op: "mod", Arg1: "a [ 1 ]", Arg2: "8", Result: "$(0x10000004)"}},
+		{"a [ 2 ] = b + 1", Quad{Op: "+", Arg1: "b", Arg2: "1", Result: "a [ 2 ]"}},
+		{`s = "a b" strcat "c"`, Quad{Op: "strcat", Arg1: `"a b"`, Arg2: `"c"`, Result: "s"}},
+		{"L_while_0:", Quad{Op: "label", Result: "L_while_0"}},
+		{"goto L_while_0", Quad{Op: "goto", Result: "L_while_0"}},
+		{"if a >= 0 goto L_abs_1", Quad{Op: "j>=", Arg1: "a", Arg2: "0", Result: "L_abs_1"}},
+		{"param a", Quad{Op: "param", Arg1: "a"}},
+		{"call printf, 2", Quad{Op: "call", Arg1: "printf", Arg2: "2"}},
+		{"t = icall f, 1", Quad{Op: "icall", Arg1: "f", Arg2: "1", Result: "t"}},
+		{"fp[-4] = r0", Quad{Op: "=", Arg1: "r0", Result: "fp[-4]"}},
+		{"sp = sp - 32", Quad{Op: "-", Arg1: "sp", Arg2: "32", Result: "sp"}},
+		{"push fp", Quad{Op: "push", Arg1: "fp"}},
+		{"ret", Quad{Op: "ret"}},
+		{"begin a", Quad{Op: "begin", Arg1: "a"}},
+		{"end a", Quad{Op: "end", Arg1: "a"}},
+	}
+	for _, tt := range tests {
+		q := Parse(tt.line)
+		if q != tt.expected {
+			t.Errorf("Expected %q to be %v, got %v", tt.line, tt.expected, q)
+		}
+		if line := q.TAC(); line != tt.line {
+			t.Errorf("Expected %v to be written as %q, got %q", q, tt.line, line)
+		}
+	}
+	if q := Parse("if a >= 0 goto L"); !q.IsJump() || q.Relop() != ">=" {
+		t.Errorf("Expected a conditional jump on >=, got %v", q)
+	}
+	if q := Parse("a = b"); q.IsJump() || q.Relop() != "" {
+		t.Errorf("Expected a copy not to jump, got %v", q)
+	}
+}
+
+func TestEmitter(t *testing.T) {
+	e := &Emitter{}
+	start, end := e.NewLabel("while"), e.NewLabel("while")
+	e.Label(start)
+	e.If("i", ">=", "n", end)
+	t1 := e.Temp("*", "i", "2")
+	e.Assign("a [ 0 ]", "", t1, "")
+	e.Call("", "print", t1)
+	e.Assign("i", "+", "i", "1")
+	e.Goto(start)
+	e.Label(end)
+
+	var b strings.Builder
+	if err := Dump(&b, e.Quads); err != nil {
+		t.Fatal(err)
+	}
+	expected := `0: (label, -, -, L1)
+1: (j>=, i, n, L2)
+2: (*, i, 2, t1)
+3: (=, t1, -, a [ 0 ])
+4: (param, t1, -, -)
+5: (call, print, 1, -)
+6: (+, i, 1, i)
+7: (goto, -, -, L1)
+8: (label, -, -, L2)
+`
+	if b.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, b.String())
+	}
+
+	code := &textStream{}
+	e = &Emitter{Temps: func() string { return "$(0x10000000)" }, Stream: code}
+	e.Assign(e.Temp("minus", "a", ""), "", "b", "")
+	if strings.Join(code.lines, "\n") != "$(0x10000000) = minus a\n$(0x10000000) = b" || len(e.Quads) != 0 {
+		t.Errorf("Expected the quadruples to be appended as code, got %q", code.lines)
+	}
+}
+
+func TestDumpLab(t *testing.T) {
+	e := &Emitter{}
+	start, end := e.NewLabel("while"), e.NewLabel("while")
+	e.Label(start)
+	e.If("i", ">=", "n", end)
+	e.Assign("i", "+", "i", "1")
+	e.Goto(start)
+	e.Label(end)
+
+	var b strings.Builder
+	if err := DumpLab(&b, e.Quads, LabStart); err != nil {
+		t.Fatal(err)
+	}
+	// L2 is past the last quadruple
+	expected := `(100) (j>=, i, n, 103)
+(101) (+, i, 1, i)
+(102) (j, -, -, 100)
+`
+	if b.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+// textStream keeps the code as text, as the parser does.
+type textStream struct {
+	lines []string
+}
+
+func (s *textStream) Append(q Quad) { s.lines = append(s.lines, q.TAC()) }
+
+func (s *textStream) Len() int { return len(s.lines) }
+
+func (s *textStream) Patch(i int, label string) {
+	q := Parse(s.lines[i])
+	q.Result = label
+	s.lines[i] = q.TAC()
+}
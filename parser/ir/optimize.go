@@ -0,0 +1,204 @@
+package ir
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Pass is an optimization of the code, returning the code optimized.
+type Pass func(quads []Quad) []Quad
+
+// DefaultPasses are the passes Optimize runs without any given.
+var DefaultPasses = []Pass{FoldConstants, PruneBranches, RemoveUnreachable}
+
+// Optimize runs the passes over the code in order, DefaultPasses if none are
+// given, and again as long as they change it, so that a pass can make use of
+// what the others did: a condition folded makes the code after its jump
+// unreachable. The code passed is left unchanged.
+func Optimize(quads []Quad, passes ...Pass) []Quad {
+	if len(passes) == 0 {
+		passes = DefaultPasses
+	}
+	quads = slices.Clone(quads)
+	// every round but the last removes or folds a quadruple
+	for range len(quads) + 1 {
+		before := slices.Clone(quads)
+		for _, pass := range passes {
+			quads = pass(quads)
+		}
+		if slices.Equal(quads, before) {
+			break
+		}
+	}
+	return quads
+}
+
+// FoldConstants propagates the integer constants assigned to variables to
+// the reads of them within each basic block, and computes the arithmetic and
+// the comparisons on constants. A conditional jump on constants becomes a
+// goto if it is taken, and is removed if not. The variables whose address is
+// taken are left alone, and a call forgets the constants known, as the
+// function may write the globals. The code must convert what it stores into
+// a float, as the walker does, for an integer constant to never be a float.
+func FoldConstants(quads []Quad) []Quad {
+	return foldConstants(quads, 64)
+}
+
+// FoldConstantsIn returns FoldConstants computing the integers in the bits
+// of the int of the code, 16, 32 or 64, wrapping around them as the machine
+// does.
+func FoldConstantsIn(bits int) Pass {
+	return func(quads []Quad) []Quad {
+		return foldConstants(quads, bits)
+	}
+}
+
+func foldConstants(quads []Quad, bits int) []Quad {
+	addressed := map[string]bool{}
+	for _, q := range quads {
+		for _, operand := range []string{q.Arg1, q.Arg2} {
+			if name, ok := strings.CutPrefix(operand, "&"); ok {
+				addressed[name] = true
+			}
+		}
+	}
+	var result []Quad
+	known := map[string]string{}
+	read := func(operand string) string {
+		if value, ok := known[operand]; ok {
+			return value
+		}
+		return operand
+	}
+	for i, q := range quads {
+		if q.Op == Label || i > 0 && (quads[i-1].IsJump() || quads[i-1].Op == "ret") {
+			clear(known)
+		}
+		switch {
+		case q.Relop() != "":
+			q.Arg1, q.Arg2 = read(q.Arg1), read(q.Arg2)
+			if taken, ok := compare(q.Arg1, q.Relop(), q.Arg2); ok {
+				if !taken {
+					continue
+				}
+				q = Quad{Op: Goto, Result: q.Result}
+			}
+		case q.Op == Param:
+			q.Arg1 = read(q.Arg1)
+		case q.Op == Call || q.Op == ICall:
+			clear(known)
+		case q.Op == Label || q.Op == Goto || q.Result == "":
+		default:
+			q.Arg1, q.Arg2 = read(q.Arg1), read(q.Arg2)
+			if value, ok := fold(q, bits); ok {
+				q = Quad{Op: Copy, Arg1: value, Result: q.Result}
+			}
+		}
+		if q.Result != "" && q.Op != Label && !q.IsJump() {
+			delete(known, q.Result)
+			if _, err := strconv.ParseInt(q.Arg1, 10, 64); err == nil && q.Op == Copy && isName(q.Result) && !addressed[q.Result] {
+				known[q.Result] = q.Arg1
+			}
+		}
+		result = append(result, q)
+	}
+	return result
+}
+
+// isName checks if the operand is a variable or a temporary on its own, not
+// an element of an array or a slot of the frame.
+func isName(operand string) bool {
+	return operand != "" && !strings.ContainsAny(operand, " [*&\"")
+}
+
+// fold returns the value of the operation on constants, if it is one, in
+// the bits.
+func fold(q Quad, bits int) (string, bool) {
+	shift := 64 - bits
+	wrap := func(v int64) string {
+		return strconv.FormatInt(v<<shift>>shift, 10)
+	}
+	x, errX := strconv.ParseInt(q.Arg1, 10, 64)
+	if errX != nil {
+		return "", false
+	}
+	if q.Arg2 == "" {
+		if q.Op == "minus" {
+			return wrap(-x), true
+		}
+		return "", false
+	}
+	y, errY := strconv.ParseInt(q.Arg2, 10, 64)
+	if errY != nil {
+		return "", false
+	}
+	switch q.Op {
+	case "+":
+		return wrap(x + y), true
+	case "-":
+		return wrap(x - y), true
+	case "*":
+		return wrap(x * y), true
+	case "/":
+		if y != 0 {
+			return wrap(x / y), true
+		}
+	case "%", "mod":
+		if y != 0 {
+			return wrap(x % y), true
+		}
+	}
+	if holds, ok := compare(q.Arg1, q.Op, q.Arg2); ok {
+		return strconv.FormatBool(holds), true
+	}
+	return "", false
+}
+
+// relations are the comparisons, as written and as the parser emits them.
+var relations = map[string]func(x, y int64) bool{
+	"<": func(x, y int64) bool { return x < y }, "lt": func(x, y int64) bool { return x < y },
+	"<=": func(x, y int64) bool { return x <= y }, "le": func(x, y int64) bool { return x <= y },
+	">": func(x, y int64) bool { return x > y }, "gt": func(x, y int64) bool { return x > y },
+	">=": func(x, y int64) bool { return x >= y }, "ge": func(x, y int64) bool { return x >= y },
+	"==": func(x, y int64) bool { return x == y }, "eq": func(x, y int64) bool { return x == y },
+	"!=": func(x, y int64) bool { return x != y }, "ne": func(x, y int64) bool { return x != y },
+}
+
+// compare returns if the relation holds between the constants, if they are.
+func compare(a, relop, b string) (holds bool, ok bool) {
+	relation, ok := relations[relop]
+	x, errX := strconv.ParseInt(a, 10, 64)
+	y, errY := strconv.ParseInt(b, 10, 64)
+	if !ok || errX != nil || errY != nil {
+		return false, false
+	}
+	return relation(x, y), true
+}
+
+// RemoveUnreachable removes the code after a goto or a ret up to the next
+// label jumped to, which no path reaches. The labels no jump targets are
+// removed with it.
+func RemoveUnreachable(quads []Quad) []Quad {
+	targets := map[string]bool{}
+	for _, q := range quads {
+		if q.IsJump() {
+			targets[q.Result] = true
+		}
+	}
+	var result []Quad
+	reachable := true
+	for _, q := range quads {
+		if q.Op == Label && targets[q.Result] {
+			reachable = true
+		}
+		if !reachable {
+			continue
+		}
+		result = append(result, q)
+		if q.Op == Goto || q.Op == "ret" {
+			reachable = false
+		}
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+package ir_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser/ir"
+)
+
+func code(quads []Quad) string {
+	lines := make([]string, len(quads))
+	for i, q := range quads {
+		lines[i] = q.TAC()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestFoldConstants(t *testing.T) {
+	quads := ParseAll([]string{
+		"a = 2",
+		"t1 = a * 3",
+		"b = t1 mod 4",
+		"p = &a",
+		"c [ 0 ] = b",
+		"if b < 3 goto L1",
+		"param b",
+		"call print, 1",
+		"t2 = minus a",
+		"L1:",
+		"d = b + 1",
+		"a = 5",
+		"e = a",
+	})
+	expected := `a = 2
+t1 = a * 3
+b = t1 mod 4
+p = &a
+c [ 0 ] = b
+if b < 3 goto L1
+param b
+call print, 1
+t2 = minus a
+L1:
+d = b + 1
+a = 5
+e = a`
+	// a has its address taken, so nothing is known of it
+	if got := code(FoldConstants(quads)); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+
+	quads = slices.Delete(slices.Clone(quads), 3, 4)
+	expected = `a = 2
+t1 = 6
+b = 2
+c [ 0 ] = 2
+goto L1
+param b
+call print, 1
+t2 = minus a
+L1:
+d = b + 1
+a = 5
+e = 5`
+	if got := code(FoldConstants(quads)); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestRemoveUnreachable(t *testing.T) {
+	quads := ParseAll([]string{
+		"goto L2",
+		"a = 1",
+		"L1:",
+		"b = 2",
+		"L2:",
+		"if a > 0 goto L3",
+		"goto L4",
+		"c = 3",
+		"L3:",
+		"L4:",
+		"ret",
+		"d = 4",
+	})
+	expected := `goto L2
+L2:
+if a > 0 goto L3
+goto L4
+L3:
+L4:
+ret`
+	if got := code(RemoveUnreachable(quads)); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	e := &Emitter{}
+	e.Assign("n", "", "10", "")
+	e.If("n", ">", "5", "L1")
+	e.Assign("a", "", "1", "")
+	e.Goto("L2")
+	e.Label("L1")
+	e.Assign("a", "", "2", "")
+	e.Label("L2")
+	e.Call("", "print", "a")
+	quads := slices.Clone(e.Quads)
+
+	// the jump folded leaves the code up to L1 unreachable, L2 is reached still
+	expected := `n = 10
+goto L1
+L1:
+a = 2
+L2:
+param a
+call print, 1`
+	if got := code(Optimize(e.Quads)); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+	if !slices.Equal(e.Quads, quads) {
+		t.Errorf("Expected the code passed to be left unchanged, got\n%s", code(e.Quads))
+	}
+	if got := code(Optimize(e.Quads, RemoveUnreachable)); got != code(quads) {
+		t.Errorf("Expected only the passes given to run, got\n%s", got)
+	}
+}
@@ -0,0 +1,417 @@
+package ir
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	. "app/utils/collections"
+)
+
+// Interval is the range [Lo, Hi] of the values an integer may hold,
+// math.MinInt64 and math.MaxInt64 standing for no bound.
+type Interval struct {
+	Lo, Hi int64
+}
+
+// Unbounded is the range of an integer nothing is known of.
+var Unbounded = Interval{math.MinInt64, math.MaxInt64}
+
+// Point returns the range of the constant.
+func Point(v int64) Interval {
+	return Interval{v, v}
+}
+
+// String returns the range as [0, 9], with -inf and +inf for no bound.
+func (a Interval) String() string {
+	bound := func(v int64) string {
+		switch v {
+		case math.MinInt64:
+			return "-inf"
+		case math.MaxInt64:
+			return "+inf"
+		}
+		return strconv.FormatInt(v, 10)
+	}
+	return fmt.Sprintf("[%s, %s]", bound(a.Lo), bound(a.Hi))
+}
+
+// Join returns the smallest range holding both.
+func (a Interval) Join(b Interval) Interval {
+	return Interval{min(a.Lo, b.Lo), max(a.Hi, b.Hi)}
+}
+
+// Meet returns the values in both ranges, false if there are none.
+func (a Interval) Meet(b Interval) (Interval, bool) {
+	c := Interval{max(a.Lo, b.Lo), min(a.Hi, b.Hi)}
+	return c, c.Lo <= c.Hi
+}
+
+// Within checks if every value of the range is in [lo, hi].
+func (a Interval) Within(lo, hi int64) bool {
+	return a.Lo >= lo && a.Hi <= hi
+}
+
+// saturate returns the bound computed in big numbers, clamped to the bounds
+// of an int64, which stand for no bound.
+func saturate(v float64) int64 {
+	switch {
+	case v <= math.MinInt64:
+		return math.MinInt64
+	case v >= math.MaxInt64:
+		return math.MaxInt64
+	}
+	return int64(v)
+}
+
+// arithmetic returns the range of the operation on values of the ranges,
+// Unbounded for the operations it does not know. A bound that is none stays
+// none, since the products and sums are computed in floats. A result that
+// may be beyond the int of the bits is Unbounded, as the machine wraps it
+// around: a sum, a difference or a product with a bound beyond or none, a
+// quotient or a negation with a bound beyond, such as the smallest int by -1.
+func arithmetic(op string, x, y Interval, bits int) Interval {
+	f := func(v int64) float64 {
+		switch v {
+		case math.MinInt64:
+			return math.Inf(-1)
+		case math.MaxInt64:
+			return math.Inf(1)
+		}
+		return float64(v)
+	}
+	limit := math.Ldexp(1, bits-1)
+	span := func(wraps bool, values ...float64) Interval {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, v := range values {
+			if math.IsNaN(v) {
+				return Unbounded
+			}
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if (wraps || !math.IsInf(lo, 0)) && lo < -limit || (wraps || !math.IsInf(hi, 0)) && hi >= limit {
+			return Unbounded
+		}
+		return Interval{saturate(lo), saturate(hi)}
+	}
+	switch op {
+	case "+":
+		return span(true, f(x.Lo)+f(y.Lo), f(x.Hi)+f(y.Hi))
+	case "-":
+		return span(true, f(x.Lo)-f(y.Hi), f(x.Hi)-f(y.Lo))
+	case "*":
+		return span(true, f(x.Lo)*f(y.Lo), f(x.Lo)*f(y.Hi), f(x.Hi)*f(y.Lo), f(x.Hi)*f(y.Hi))
+	case "/":
+		if y.Lo <= 0 && y.Hi >= 0 {
+			return Unbounded
+		}
+		// the bounds are truncated towards zero, as the quotient of integers is
+		return span(false, f(x.Lo)/f(y.Lo), f(x.Lo)/f(y.Hi), f(x.Hi)/f(y.Lo), f(x.Hi)/f(y.Hi))
+	case "%", "mod":
+		if y.Lo <= 0 || y.Hi == math.MaxInt64 {
+			return Unbounded
+		}
+		// the remainder has the sign of the dividend and is below the divisor
+		r := Interval{-(y.Hi - 1), y.Hi - 1}
+		if x.Lo >= 0 {
+			r.Lo = 0
+		}
+		if x.Hi <= 0 {
+			r.Hi = 0
+		}
+		r, _ = r.Meet(Interval{min(x.Lo, 0), max(x.Hi, 0)})
+		return r
+	case "minus":
+		// only the smallest int, or no bound below, negates beyond
+		if x.Lo == math.MinInt64 {
+			return Unbounded
+		}
+		return span(false, -f(x.Hi), -f(x.Lo))
+	}
+	return Unbounded
+}
+
+// negations are the relations holding when the others do not.
+var negations = map[string]string{
+	"<": ">=", "lt": "ge", ">=": "<", "ge": "lt",
+	">": "<=", "gt": "le", "<=": ">", "le": "gt",
+	"==": "!=", "eq": "ne", "!=": "==", "ne": "eq",
+}
+
+// refine returns the values of the ranges for which the relation holds,
+// false if there are none.
+func refine(relop string, x, y Interval) (Interval, Interval, bool) {
+	below := func(v int64) int64 { return max(v, math.MinInt64+1) - 1 }
+	above := func(v int64) int64 { return min(v, math.MaxInt64-1) + 1 }
+	ok1, ok2 := true, true
+	switch relop {
+	case "<", "lt":
+		x, ok1 = x.Meet(Interval{math.MinInt64, below(y.Hi)})
+		y, ok2 = y.Meet(Interval{above(x.Lo), math.MaxInt64})
+	case "<=", "le":
+		x, ok1 = x.Meet(Interval{math.MinInt64, y.Hi})
+		y, ok2 = y.Meet(Interval{x.Lo, math.MaxInt64})
+	case ">", "gt":
+		y, x, ok := refine("<", y, x)
+		return x, y, ok
+	case ">=", "ge":
+		y, x, ok := refine("<=", y, x)
+		return x, y, ok
+	case "==", "eq":
+		x, ok1 = x.Meet(y)
+		y = x
+	case "!=", "ne":
+		// only a constant can be cut off the bounds of the other range
+		if y.Lo == y.Hi && x.Lo == x.Hi {
+			ok1 = x.Lo != y.Lo
+		} else if y.Lo == y.Hi && x.Lo == y.Lo {
+			x.Lo++
+		} else if y.Lo == y.Hi && x.Hi == y.Lo {
+			x.Hi--
+		}
+	}
+	return x, y, ok1 && ok2
+}
+
+// decide returns if the relation holds between every values of the ranges,
+// with ok false if it holds for some only.
+func decide(relop string, x, y Interval) (holds bool, ok bool) {
+	_, _, sometimes := refine(relop, x, y)
+	_, _, sometimesNot := refine(negations[relop], x, y)
+	return sometimes, sometimes != sometimesNot
+}
+
+// state is the range of each variable known at a point of the code, those
+// not in it being unbounded.
+type state map[string]Interval
+
+func (s state) clone() state {
+	c := make(state, len(s))
+	for name, r := range s {
+		c[name] = r
+	}
+	return c
+}
+
+// Ranges is the range of the integer variables and temporaries before each
+// quadruple of the code, as AnalyzeRanges infers them.
+type Ranges struct {
+	in   []state // nil for the quadruples no path reaches
+	bits int     // of the int the arithmetic wraps around
+}
+
+// widenAfter is the number of times the ranges before a label may grow
+// before the bounds still growing are dropped, so that the analysis of a
+// loop ends.
+const widenAfter = 3
+
+// AnalyzeRanges infers the range of the integer variables and temporaries
+// before each quadruple, from the constants assigned to them, the arithmetic
+// on them and the conditional jumps, which bound their operands on both
+// ways out. A name is unbounded where the paths reaching a point disagree
+// and no range is known of the other one, and the bounds of a loop still
+// growing after a few rounds are dropped. Like FoldConstants, the analysis
+// knows nothing of the variables whose address is taken, forgets what it
+// knows at a call, and computes on int64 whatever the type of a variable,
+// the arithmetic that may wrap around the int of 64 bits being unbounded.
+func AnalyzeRanges(quads []Quad) *Ranges {
+	return AnalyzeRangesIn(quads, 64)
+}
+
+// AnalyzeRangesIn is AnalyzeRanges for an int of the bits, 16, 32 or 64, the
+// arithmetic that may wrap around it being unbounded.
+func AnalyzeRangesIn(quads []Quad, bits int) *Ranges {
+	addressed := map[string]bool{}
+	labels := map[string]int{}
+	for i, q := range quads {
+		for _, operand := range []string{q.Arg1, q.Arg2} {
+			if name, ok := strings.CutPrefix(operand, "&"); ok {
+				addressed[name] = true
+			}
+		}
+		if q.Op == Label {
+			labels[q.Result] = i
+		}
+	}
+	r := &Ranges{in: make([]state, len(quads)), bits: bits}
+	if len(quads) == 0 {
+		return r
+	}
+	grown := make([]int, len(quads))
+	work := NewQueue[int]()
+	work.Enqueue(0)
+	queued := map[int]bool{0: true}
+	r.in[0] = state{}
+	flow := func(to int, s state) {
+		if to >= len(quads) {
+			return
+		}
+		old := r.in[to]
+		if old == nil {
+			r.in[to] = s
+		} else {
+			joined := state{}
+			for name, a := range old {
+				if b, ok := s[name]; ok {
+					j := a.Join(b)
+					if grown[to] >= widenAfter && quads[to].Op == Label {
+						if j.Lo < a.Lo {
+							j.Lo = math.MinInt64
+						}
+						if j.Hi > a.Hi {
+							j.Hi = math.MaxInt64
+						}
+					}
+					if j != Unbounded {
+						joined[name] = j
+					}
+				}
+			}
+			if len(joined) == len(old) && equalStates(joined, old) {
+				return
+			}
+			grown[to]++
+			r.in[to] = joined
+		}
+		if !queued[to] {
+			queued[to] = true
+			work.Enqueue(to)
+		}
+	}
+	for !work.IsEmpty() {
+		i, _ := work.Dequeue()
+		queued[i] = false
+		q, s := quads[i], r.in[i].clone()
+		switch {
+		case q.Op == Goto:
+			if target, ok := labels[q.Result]; ok {
+				flow(target, s)
+			}
+			continue
+		case q.Relop() != "":
+			x, y := r.value(s, q.Arg1), r.value(s, q.Arg2)
+			if x2, y2, ok := refine(q.Relop(), x, y); ok {
+				if target, ok := labels[q.Result]; ok {
+					flow(target, bound(s.clone(), addressed, q.Arg1, x2, q.Arg2, y2))
+				}
+			}
+			if x2, y2, ok := refine(negations[q.Relop()], x, y); ok {
+				flow(i+1, bound(s, addressed, q.Arg1, x2, q.Arg2, y2))
+			}
+			continue
+		case q.Op == "ret":
+			continue
+		case q.Op == Call || q.Op == ICall:
+			clear(s)
+		case q.Op == Label || q.Op == Param || q.Result == "":
+		default:
+			v := r.value(s, q.Arg1)
+			if q.Op != Copy {
+				v = arithmetic(q.Op, v, r.value(s, q.Arg2), r.bits)
+			}
+			delete(s, q.Result)
+			if isName(q.Result) && !addressed[q.Result] && v != Unbounded {
+				s[q.Result] = v
+			}
+		}
+		flow(i+1, s)
+	}
+	return r
+}
+
+// bound sets the ranges of the operands that are names the analysis tracks.
+func bound(s state, addressed map[string]bool, a string, x Interval, b string, y Interval) state {
+	if isName(a) && !addressed[a] {
+		s[a] = x
+	}
+	if isName(b) && !addressed[b] {
+		s[b] = y
+	}
+	return s
+}
+
+func equalStates(a, b state) bool {
+	for name, r := range a {
+		if b[name] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// value returns the range of the operand in the state: a constant, a name
+// with a range known, and unbounded for the others.
+func (r *Ranges) value(s state, operand string) Interval {
+	if v, err := strconv.ParseInt(operand, 10, 64); err == nil {
+		return Point(v)
+	}
+	if v, ok := s[operand]; ok {
+		return v
+	}
+	return Unbounded
+}
+
+// Reachable checks if a path from the start of the code reaches the
+// quadruple, given the ranges of the conditions on the way.
+func (r *Ranges) Reachable(i int) bool {
+	return r.in[i] != nil
+}
+
+// At returns the range of the operand before the quadruple, unbounded for
+// the operands that are neither an integer constant nor a name with a range
+// known. Nothing reaches an unreachable quadruple, so its ranges are empty.
+func (r *Ranges) At(i int, operand string) Interval {
+	if r.in[i] == nil {
+		return Interval{math.MaxInt64, math.MinInt64}
+	}
+	return r.value(r.in[i], operand)
+}
+
+// InBounds checks if the element of an array of the length read or written
+// by the quadruple, x [ i ] with i a constant or a name, is within the array
+// whatever path leads there, so that it needs no check when run.
+func (r *Ranges) InBounds(i int, element string, length int) bool {
+	fields := strings.Fields(element)
+	if len(fields) != 4 || fields[1] != "[" || fields[3] != "]" {
+		return false
+	}
+	return r.At(i, fields[2]).Within(0, int64(length)-1)
+}
+
+// PruneBranches folds the conditional jumps the ranges decide, to a goto if
+// the jump is always taken, removed if never, and removes the quadruples no
+// path reaches but the labels, which RemoveUnreachable removes once no jump
+// targets them. It decides more than FoldConstants does, such as the test of
+// a loop on a variable the loop bounds.
+func PruneBranches(quads []Quad) []Quad {
+	return pruneBranches(quads, 64)
+}
+
+// PruneBranchesIn returns PruneBranches analyzing the ranges in the bits of
+// the int of the code, 16, 32 or 64, as FoldConstantsIn folds them.
+func PruneBranchesIn(bits int) Pass {
+	return func(quads []Quad) []Quad {
+		return pruneBranches(quads, bits)
+	}
+}
+
+func pruneBranches(quads []Quad, bits int) []Quad {
+	r := AnalyzeRangesIn(quads, bits)
+	var result []Quad
+	for i, q := range quads {
+		if !r.Reachable(i) && q.Op != Label {
+			continue
+		}
+		if relop := q.Relop(); relop != "" && r.Reachable(i) {
+			if taken, ok := decide(relop, r.At(i, q.Arg1), r.At(i, q.Arg2)); ok {
+				if !taken {
+					continue
+				}
+				q = Quad{Op: Goto, Result: q.Result}
+			}
+		}
+		result = append(result, q)
+	}
+	return result
+}
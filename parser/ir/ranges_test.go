@@ -0,0 +1,133 @@
+package ir_test
+
+import (
+	"math"
+	"testing"
+
+	. "app/parser/ir"
+)
+
+func TestAnalyzeRanges(t *testing.T) {
+	quads := ParseAll([]string{
+		"i = 0",
+		"L1:",
+		"if i >= 10 goto L2",
+		"a [ i ] = i",
+		"t1 = i * 2",
+		"t2 = t1 mod 4",
+		"i = i + 1",
+		"goto L1",
+		"L2:",
+		"t3 = minus i",
+		"t4 = call read_int, 0",
+		"ret",
+		"t5 = 1",
+	})
+	r := AnalyzeRanges(quads)
+	for _, test := range []struct {
+		i        int
+		operand  string
+		expected Interval
+	}{
+		{2, "i", Interval{0, math.MaxInt64}},
+		{3, "i", Interval{0, 9}},
+		{5, "t1", Interval{0, 18}},
+		{6, "t2", Interval{0, 3}},
+		{9, "i", Interval{10, math.MaxInt64}},
+		{10, "t3", Interval{math.MinInt64, -10}},
+		{11, "t4", Unbounded},
+		{3, "7", Point(7)},
+		{3, "x", Unbounded},
+	} {
+		if got := r.At(test.i, test.operand); got != test.expected {
+			t.Errorf("Expected %s at %d in %s, got %s", test.operand, test.i, test.expected, got)
+		}
+	}
+	if !r.InBounds(3, "a [ i ]", 10) || r.InBounds(3, "a [ i ]", 9) || r.InBounds(3, "a", 10) {
+		t.Errorf("Expected a [ i ] in the bounds of 10 elements only")
+	}
+	if r.Reachable(12) || !r.Reachable(11) {
+		t.Errorf("Expected the code after ret unreachable only")
+	}
+}
+
+func TestPruneBranches(t *testing.T) {
+	quads := ParseAll([]string{
+		"t1 = call read_int, 0",
+		"t2 = t1 mod 4",
+		"if t2 < 4 goto L1",
+		"param 1",
+		"call print_int, 1",
+		"L1:",
+		"i = 0",
+		"L2:",
+		"if i > 4 goto L3",
+		"i = i + 1",
+		"goto L2",
+		"L3:",
+		"if i < 5 goto L4",
+		"if i != t2 goto L4",
+		"param 2",
+		"call print_int, 1",
+		"L4:",
+	})
+	// the remainder is below 4 and the loop leaves i above 4
+	expected := `t1 = call read_int, 0
+t2 = t1 mod 4
+goto L1
+L1:
+i = 0
+L2:
+if i > 4 goto L3
+i = i + 1
+goto L2
+L3:
+goto L4
+L4:`
+	if got := code(PruneBranches(quads)); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestPruneBranchesIn(t *testing.T) {
+	quads := ParseAll([]string{
+		"if a lt 0 goto L2",
+		"if a le 32000 goto L1",
+		"goto L2",
+		"L1:",
+		"t = a + 1000",
+		"a = t",
+		"if a lt 0 goto L2",
+		"param a",
+		"call print_int, 1",
+		"L2:",
+	})
+	// a + 1000 is at most 33000, which wraps around 16 bits but not 32
+	if got := PruneBranchesIn(16)(quads); len(got) != len(quads) {
+		t.Errorf("Expected the test of the sum kept in 16 bits, got\n%s", code(got))
+	}
+	if got := PruneBranchesIn(32)(quads); len(got) != len(quads)-1 || got[6].Op == "lt" {
+		t.Errorf("Expected the test of the sum removed in 32 bits, got\n%s", code(got))
+	}
+
+	// the negation of the smallest int is itself
+	quads = ParseAll([]string{"if a lt -32768 goto L1", "if a gt 0 goto L1", "t = minus a", "u = t", "L1:"})
+	if got := AnalyzeRangesIn(quads, 16).At(3, "t"); got != Unbounded {
+		t.Errorf("Expected the negation unbounded in 16 bits, got %s", got)
+	}
+	if got := AnalyzeRangesIn(quads, 32).At(3, "t"); got != (Interval{0, 32768}) {
+		t.Errorf("Expected the negation in [0, 32768] in 32 bits, got %s", got)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	if got := (Interval{-3, 2}).Join(Point(5)); got != (Interval{-3, 5}) {
+		t.Errorf("Expected [-3, 5], got %s", got)
+	}
+	if _, ok := (Interval{0, 2}).Meet(Interval{3, 4}); ok {
+		t.Errorf("Expected [0, 2] and [3, 4] disjoint")
+	}
+	if got := Unbounded.String(); got != "[-inf, +inf]" {
+		t.Errorf("Expected [-inf, +inf], got %s", got)
+	}
+}
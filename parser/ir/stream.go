@@ -0,0 +1,95 @@
+package ir
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"iter"
+
+	"app/parser/ast"
+)
+
+// TranslateSeq returns the code Translate emits for the program as a
+// stream, for a program too large to keep its code in memory: the
+// quadruples are handed out as they are emitted, once no jump before them
+// waits for its target, so that only the code of the statement being
+// translated is held. With buffer above zero they are handed out by that
+// many at once instead. The emitter names the temporaries and the labels,
+// its Quads and Stream are not used. Translation stops when the loop over
+// the stream does.
+func TranslateSeq(e *Emitter, p *ast.Program, buffer int) iter.Seq[Quad] {
+	return func(yield func(Quad) bool) {
+		s := &window{yield: yield, buffer: buffer}
+		stream := *e
+		stream.Quads, stream.Stream = nil, s
+		defer func() {
+			if r := recover(); r != nil && r != errStopped {
+				panic(r)
+			}
+		}()
+		Translate(&stream, p)
+		s.release(true)
+		e.temps, e.labels = stream.temps, stream.labels
+	}
+}
+
+// errStopped is raised by a window whose loop stopped, to stop the translation.
+var errStopped = errors.New("stream stopped")
+
+// window is the quadruples of a stream emitted but not handed out yet,
+// those from the first jump whose target is not known on.
+type window struct {
+	quads  []Quad
+	base   int // the index of the first quadruple of quads
+	ready  int // the quadruples of quads that can be handed out
+	buffer int
+	yield  func(Quad) bool
+}
+
+func (s *window) Append(q Quad) {
+	s.quads = append(s.quads, q)
+	s.release(false)
+}
+
+func (s *window) Len() int {
+	return s.base + len(s.quads)
+}
+
+func (s *window) Patch(i int, label string) {
+	s.quads[i-s.base].Result = label
+	s.release(false)
+}
+
+// release hands out the quadruples before the first jump waiting for its
+// target, once there are as many as the buffer, or all of them at the end.
+func (s *window) release(end bool) {
+	for s.ready < len(s.quads) && !(s.quads[s.ready].IsJump() && s.quads[s.ready].Result == "") {
+		s.ready++
+	}
+	if end {
+		s.ready = len(s.quads)
+	}
+	if s.ready == 0 || !end && s.ready < s.buffer {
+		return
+	}
+	for _, q := range s.quads[:s.ready] {
+		if !s.yield(q) {
+			panic(errStopped)
+		}
+	}
+	s.base += s.ready
+	s.quads = append(s.quads[:0], s.quads[s.ready:]...)
+	s.ready = 0
+}
+
+// WriteTAC writes the quadruples as three-address code, a line each, as
+// they come.
+func WriteTAC(w io.Writer, quads iter.Seq[Quad]) error {
+	b := bufio.NewWriter(w)
+	for q := range quads {
+		if _, err := b.WriteString(q.TAC() + "\n"); err != nil {
+			return err
+		}
+	}
+	return b.Flush()
+}
@@ -0,0 +1,84 @@
+package ir_test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"app/parser"
+	"app/parser/ast"
+	. "app/parser/ir"
+)
+
+// program compiles the source and returns the tree of the program.
+func program(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	result, err := parser.Compile(parser.Options{Source: strings.NewReader(src)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Program == nil {
+		t.Fatalf("Expected the tree of %q, got %v", src, result.Diagnostics)
+	}
+	return result.Program
+}
+
+func TestTranslateSeq(t *testing.T) {
+	srcs := []string{
+		"{ int a, b; if (a < b && !(b == 0)) a = b; else b = a; a = 1; }",
+		"{ int i; while (i < 10 || i == 20) { if (i == 5) break; i = i + 1; } i = 0; }",
+		"{ int a; switch (a) { case 1: a = 2; case 2: a = 3; break; default: a = 4; } a = 5; }",
+	}
+	for _, src := range srcs {
+		p := program(t, src)
+		e := &Emitter{}
+		Translate(e, p)
+		for _, buffer := range []int{0, 1, 4, 1000} {
+			if quads := slices.Collect(TranslateSeq(&Emitter{}, p, buffer)); !slices.Equal(quads, e.Quads) {
+				t.Errorf("Expected the stream of %q by %d to be the code of Translate\n%v\ngot\n%v", src, buffer, e.Quads, quads)
+			}
+		}
+	}
+
+	// the code of a statement is handed out before the next one is translated
+	var b strings.Builder
+	b.WriteString("{ int a, b;")
+	for range 50 {
+		b.WriteString(" if (a < b) a = a + 1; else b = b * 2;")
+	}
+	b.WriteString(" }")
+	p := program(t, b.String())
+	yielded, temps := 0, 0
+	var seen []int
+	e := &Emitter{Temps: func() string {
+		temps++
+		seen = append(seen, yielded)
+		return fmt.Sprintf("t%d", temps)
+	}}
+	for range TranslateSeq(e, p, 0) {
+		yielded++
+	}
+	if total := len(seen); total != 100 || seen[total-1] < 400 {
+		t.Errorf("Expected most of the code handed out before the last temporary, got %d of %d", seen[len(seen)-1], yielded)
+	}
+
+	// the translation stops with the loop
+	n := 0
+	for range TranslateSeq(&Emitter{}, p, 0) {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Errorf("Expected the stream to stop after 3 quadruples, got %d", n)
+	}
+
+	var w strings.Builder
+	if err := WriteTAC(&w, TranslateSeq(&Emitter{}, program(t, srcs[0]), 2)); err != nil {
+		t.Fatalf("WriteTAC: %v", err)
+	}
+	if expected := translate(t, srcs[0]); w.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, w.String())
+	}
+}
@@ -0,0 +1,276 @@
+package ir
+
+import (
+	"fmt"
+
+	"app/parser/ast"
+	. "app/utils/collections"
+)
+
+// Translate emits the code of the program in a single pass over its tree,
+// the statements and the conditions by backpatching: a condition is emitted
+// as jumps whose targets are filled in once the code they jump to is placed,
+// and every statement leaves the list of jumps to the statement after it.
+// && and || are short-circuited. A break jumps past the innermost loop or
+// switch, and the cases of a switch fall through.
+func Translate(e *Emitter, p *ast.Program) {
+	t := &translator{Emitter: e}
+	t.patch(t.block(p.Body), "end")
+}
+
+type translator struct {
+	*Emitter
+	breaks Stack[List] // the breaks of the loops and switches, the innermost on top
+}
+
+// patch places a label at the next quadruple for the jumps of the list, if
+// there are any.
+func (t *translator) patch(l List, kind string) {
+	if len(l) > 0 {
+		t.Backpatch(l, t.Mark(kind))
+	}
+}
+
+// block emits the statements of the block, S → S1 M S2 for each one, and
+// returns its nextlist.
+func (t *translator) block(b *ast.Block) List {
+	t.decls(b.Decls)
+	var next List
+	for _, s := range b.Stmts {
+		t.patch(next, "next")
+		next = t.stmt(s)
+	}
+	return next
+}
+
+func (t *translator) decls(decls []*ast.Decl) {
+	for _, decl := range decls {
+		for _, d := range decl.Declarators {
+			if d.Init != nil {
+				t.Assign(d.Name, "", t.value(d.Init), "")
+			}
+			for i, element := range d.Elements {
+				t.Assign(index(d.Name, int64(i)), "", t.value(element), "")
+			}
+		}
+	}
+}
+
+func index(x string, i int64) string {
+	return fmt.Sprintf("%s [ %d ]", x, i)
+}
+
+// stmt emits the statement and returns its nextlist.
+func (t *translator) stmt(s ast.Stmt) List {
+	switch s := s.(type) {
+	case *ast.Block:
+		return t.block(s)
+	case *ast.DeclStmt:
+		t.decls(s.Decls)
+	case *ast.AssignStmt:
+		t.Assign(t.target(s.Target), "", t.value(s.Value), "")
+	case *ast.CallStmt:
+		t.call(s.Call, false)
+	case *ast.IfStmt:
+		b := t.cond(s.Cond)
+		t.Backpatch(b.True, t.Mark("then"))
+		next := t.stmt(s.Then)
+		if s.Else == nil {
+			return Merge(b.False, next)
+		}
+		next = Merge(next, t.Hole())
+		t.Backpatch(b.False, t.Mark("else"))
+		return Merge(next, t.stmt(s.Else))
+	case *ast.WhileStmt:
+		begin := t.Mark("while")
+		b := t.cond(s.Cond)
+		t.Backpatch(b.True, t.Mark("body"))
+		t.Backpatch(t.loop(s.Body), begin)
+		t.Goto(begin)
+		return Merge(b.False, t.popBreaks())
+	case *ast.DoStmt:
+		begin := t.Mark("do")
+		t.patch(t.loop(s.Body), "cond")
+		b := t.cond(s.Cond)
+		t.Backpatch(b.True, begin)
+		return Merge(b.False, t.popBreaks())
+	case *ast.BreakStmt:
+		if l, ok := t.breaks.Pop(); ok {
+			t.breaks.Push(Merge(l, t.Hole()))
+		}
+	case *ast.SwitchStmt:
+		return t.switchStmt(s)
+	}
+	return nil
+}
+
+// loop emits the body of a loop, whose breaks are taken by popBreaks, and
+// returns its nextlist.
+func (t *translator) loop(body ast.Stmt) List {
+	t.breaks.Push(nil)
+	return t.stmt(body)
+}
+
+func (t *translator) popBreaks() List {
+	l, _ := t.breaks.Pop()
+	return l
+}
+
+// switchStmt emits the tests of the cases, then their bodies in order, each
+// falling through into the next.
+func (t *translator) switchStmt(s *ast.SwitchStmt) List {
+	tag := t.value(s.Tag)
+	tests := make([]List, len(s.Cases))
+	deflt := -1
+	for i, c := range s.Cases {
+		if c.Value == nil {
+			deflt = i
+			continue
+		}
+		tests[i] = Makelist(t.NextQuad())
+		t.If(tag, "==", t.value(c.Value), "")
+	}
+	var next List
+	if deflt >= 0 {
+		tests[deflt] = t.Hole()
+	} else {
+		next = t.Hole()
+	}
+	t.breaks.Push(nil)
+	var falls List
+	for i, c := range s.Cases {
+		t.Backpatch(Merge(tests[i], falls), t.Mark("case"))
+		falls = nil
+		for _, stmt := range c.Body {
+			t.patch(falls, "next")
+			falls = t.stmt(stmt)
+		}
+	}
+	return Merge(next, falls, t.popBreaks())
+}
+
+// cond emits the expression as a condition, jumping by its value.
+func (t *translator) cond(x ast.Expr) Bool {
+	switch x := x.(type) {
+	case *ast.BinaryExpr:
+		switch x.Op {
+		case "&&":
+			b1 := t.cond(x.X)
+			m := t.Mark("and")
+			return t.And(b1, m, t.cond(x.Y))
+		case "||":
+			b1 := t.cond(x.X)
+			m := t.Mark("or")
+			return t.Or(b1, m, t.cond(x.Y))
+		case "<", "<=", ">", ">=", "==", "!=":
+			return t.Relop(t.value(x.X), x.Op, t.value(x.Y))
+		}
+	case *ast.UnaryExpr:
+		if x.Op == "!" {
+			return Not(t.cond(x.X))
+		}
+	case *ast.BasicLit:
+		if x.Kind == "bool" {
+			if x.Value == "true" {
+				return Bool{True: t.Hole()}
+			}
+			return Bool{False: t.Hole()}
+		}
+	case *ast.SeqExpr:
+		for _, y := range x.List[:len(x.List)-1] {
+			t.value(y)
+		}
+		return t.cond(x.List[len(x.List)-1])
+	}
+	return t.Relop(t.value(x), "!=", "0")
+}
+
+// value emits the expression and returns the operand holding its value.
+func (t *translator) value(x ast.Expr) string {
+	switch x := x.(type) {
+	case *ast.Ident:
+		return x.Name
+	case *ast.SelectorExpr:
+		return x.Module + "." + x.Name
+	case *ast.IndexExpr:
+		return index(t.value(x.X), x.Index)
+	case *ast.BasicLit:
+		return x.Value
+	case *ast.BinaryExpr:
+		if x.Op == "&&" || x.Op == "||" {
+			return t.boolValue(x)
+		}
+		arg1 := t.value(x.X)
+		return t.Temp(x.Op, arg1, t.value(x.Y))
+	case *ast.UnaryExpr:
+		switch x.Op {
+		case "!":
+			return t.boolValue(x)
+		case "-":
+			return t.Temp("minus", t.value(x.X), "")
+		case "*":
+			return t.Temp("*", t.value(x.X), "")
+		}
+		return t.value(x.X)
+	case *ast.AssignExpr:
+		target, value := t.target(x.Target), t.value(x.Value)
+		t.Assign(target, "", value, "")
+		if _, ok := x.Target.(*ast.UnaryExpr); ok {
+			// the value stored through the pointer
+			return value
+		}
+		return target
+	case *ast.SeqExpr:
+		var last string
+		for _, y := range x.List {
+			last = t.value(y)
+		}
+		return last
+	case *ast.CallExpr:
+		return t.call(x, true)
+	case *ast.NewExpr:
+		typ := x.Type
+		if x.Len > 0 {
+			typ = fmt.Sprintf("%s[%d]", x.Type, x.Len)
+		}
+		return t.Temp("new", typ, "")
+	}
+	return ""
+}
+
+// target returns the location an assignment stores into, * p for the value
+// the pointer p points to, as the code writes a store through it.
+func (t *translator) target(x ast.Expr) string {
+	if x, ok := x.(*ast.UnaryExpr); ok && x.Op == "*" {
+		return "* " + t.value(x.X)
+	}
+	return t.value(x)
+}
+
+// boolValue emits the condition and stores true or false into a temporary
+// as it holds or not.
+func (t *translator) boolValue(x ast.Expr) string {
+	b := t.cond(x)
+	result := t.NewTemp()
+	t.Backpatch(b.True, t.Mark("true"))
+	t.Assign(result, "", "true", "")
+	end := t.Hole()
+	t.Backpatch(b.False, t.Mark("false"))
+	t.Assign(result, "", "false", "")
+	t.Backpatch(end, t.Mark("end"))
+	return result
+}
+
+// call emits the call and returns the temporary holding its value, if used.
+func (t *translator) call(call *ast.CallExpr, used bool) string {
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		args[i] = t.value(arg)
+	}
+	var result string
+	if used {
+		result = t.NewTemp()
+	}
+	t.Call(result, call.Func, args...)
+	return result
+}
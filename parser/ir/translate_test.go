@@ -0,0 +1,134 @@
+package ir_test
+
+import (
+	"strings"
+	"testing"
+
+	"app/parser"
+	. "app/parser/ir"
+)
+
+// translate compiles the source and translates the tree of the program into
+// code, written as text.
+func translate(t *testing.T, src string) string {
+	t.Helper()
+	result, err := parser.Compile(parser.Options{Source: strings.NewReader(src)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Program == nil {
+		t.Fatalf("Expected the tree of %q, got %v", src, result.Diagnostics)
+	}
+	e := &Emitter{}
+	Translate(e, result.Program)
+	var b strings.Builder
+	for _, q := range e.Quads {
+		b.WriteString(q.TAC() + "\n")
+	}
+	return b.String()
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name: "IfElse",
+			src:  "{ int a, b; if (a < b && !(b == 0)) a = b; else b = a; a = 1; }",
+			expected: `if a < b goto L1
+goto L3
+L1:
+if b == 0 goto L3
+goto L2
+L2:
+a = b
+goto L4
+L3:
+b = a
+L4:
+a = 1
+`,
+		},
+		{
+			name: "While",
+			src:  "{ int i; while (i < 10 || i == 20) { if (i == 5) break; i = i + 1; } i = 0; }",
+			expected: `L1:
+if i < 10 goto L3
+goto L2
+L2:
+if i == 20 goto L3
+goto L6
+L3:
+if i == 5 goto L4
+goto L5
+L4:
+goto L6
+L5:
+t1 = i + 1
+i = t1
+goto L1
+L6:
+i = 0
+`,
+		},
+		{
+			name: "DoAndValue",
+			src:  "{ int i; bool b; do i = i - 1; while (i); b = i > 0 || true; }",
+			expected: `L1:
+t1 = i - 1
+i = t1
+if i != 0 goto L1
+goto L2
+L2:
+if i > 0 goto L4
+goto L3
+L3:
+goto L4
+L4:
+t2 = true
+goto L6
+L5:
+t2 = false
+L6:
+b = t2
+`,
+		},
+		{
+			name: "Switch",
+			src:  "{ int a; switch (a) { case 1: a = 2; case 2: a = 3; break; default: a = 4; } a = 5; }",
+			expected: `if a == 1 goto L1
+if a == 2 goto L2
+goto L3
+L1:
+a = 2
+L2:
+a = 3
+goto L4
+L3:
+a = 4
+L4:
+a = 5
+`,
+		},
+		{
+			name: "Pointers",
+			src:  "{ int* p = new(int); int a; *p = 1; a = *p + 2; }",
+			expected: `t1 = new int
+p = t1
+* p = 1
+t2 = * p
+t3 = t2 + 2
+a = t3
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := translate(t, tt.src); code != tt.expected {
+				t.Errorf("Expected\n%s\ngot\n%s", tt.expected, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"io"
+	"strings"
+
+	"app/lexer"
+	"app/utils/cbor"
+)
+
+// IRFormat names the schema of IRFile, bump it when the schema changes in a
+// way the readers have to know of.
+const IRFormat = "fzu-ir-1"
+
+// IRFile is what the compiler knows of a source, for the tools written in
+// other languages to read without parsing the text outputs: the tokens, the
+// tree and the code, with the diagnostics. It is written in CBOR by WriteIR,
+// a map per struct keyed by the names of the tags.
+type IRFile struct {
+	Format      string          `cbor:"format"`
+	Source      string          `cbor:"source"`
+	Tokens      []IRToken       `cbor:"tokens"`
+	AST         *IRNode         `cbor:"ast"`  // null unless the parse completed
+	Code        []IRInstruction `cbor:"code"` // the three-address code as generated
+	TAC         []IRInstruction `cbor:"tac"`  // the code optimized, in registers and laid out in the frame
+	Diagnostics []IRDiagnostic  `cbor:"diagnostics"`
+}
+
+// IRToken is a token of the source, with its line counted from 0 and its
+// position from 1 like the lexer counts them.
+type IRToken struct {
+	Kind     string `cbor:"kind"`     // the lexical class, one of those of irKinds
+	Terminal string `cbor:"terminal"` // the terminal of the grammar it is read as
+	Value    string `cbor:"value"`
+	Line     int64  `cbor:"line"`
+	Pos      int64  `cbor:"pos"`
+}
+
+// IRNode is a node of the tree, a token with its index in IRFile.Tokens or
+// a nonterminal over its children.
+type IRNode struct {
+	Symbol   string   `cbor:"symbol"`
+	Type     string   `cbor:"type,omitempty"` // the data type of its value, if resolved
+	Token    int      `cbor:"token"`          // -1 for a nonterminal
+	Children []IRNode `cbor:"children,omitempty"`
+}
+
+// IRInstruction is an instruction of three-address code, taken apart into
+// its operation, operands and destination, with the text it was read from.
+// Labels have the op "label" and their name as the result, jumps the op
+// "goto" or "if" and their label as the target, a copy the op "=", and the
+// other assignments the operator or the call computing the value.
+type IRInstruction struct {
+	Op     string   `cbor:"op"`
+	Args   []string `cbor:"args,omitempty"`
+	Result string   `cbor:"result,omitempty"`
+	Target string   `cbor:"target,omitempty"`
+	Text   string   `cbor:"text"`
+	Line   int64    `cbor:"line"`   // the source line, -1 for the frame
+	Origin int64    `cbor:"origin"` // the instruction of Code it comes from, -1 for the frame
+}
+
+// IRDiagnostic is an error or a warning of the compilation.
+type IRDiagnostic struct {
+	Severity string `cbor:"severity"`
+	Category string `cbor:"category"`
+	Message  string `cbor:"message"`
+	Fatal    bool   `cbor:"fatal"`
+}
+
+// irKinds names the lexical classes of the tokens.
+var irKinds = map[lexer.ItemType]string{
+	lexer.TYPE:       "type",
+	lexer.INTEGER:    "integer",
+	lexer.FLOAT:      "float",
+	lexer.STRING:     "string",
+	lexer.CHAR:       "char",
+	lexer.OPERATOR:   "operator",
+	lexer.DELIMITER:  "delimiter",
+	lexer.RESERVED:   "reserved",
+	lexer.IDENTIFIER: "identifier",
+}
+
+// IR returns what the compilation of the source knows of it as an IRFile.
+func (r *Result) IR(source string) *IRFile {
+	f := &IRFile{Format: IRFormat, Source: source, Tokens: []IRToken{}, Code: []IRInstruction{}, TAC: []IRInstruction{}, Diagnostics: []IRDiagnostic{}}
+	index := map[[2]int64]int{}
+	for i, token := range r.Tokens {
+		kind, ok := irKinds[token.Type]
+		if !ok {
+			kind = "other"
+		}
+		f.Tokens = append(f.Tokens, IRToken{Kind: kind, Terminal: string(Reflect(&token)), Value: token.Val, Line: token.Line, Pos: token.Pos})
+		index[[2]int64{token.Line, token.Pos}] = i
+	}
+	if r.AST != nil {
+		node := irNode(r.AST, index)
+		f.AST = &node
+	}
+	if r.Walker != nil {
+		for i, line := range r.Walker.ThreeAddress {
+			instruction := irInstruction(line)
+			instruction.Line, instruction.Origin = lineAt(r.Walker.Lines, i), int64(i)
+			f.Code = append(f.Code, instruction)
+		}
+	}
+	for i, line := range r.TAC {
+		instruction := irInstruction(line)
+		instruction.Line, instruction.Origin = lineAt(r.Lines, i), lineAt(r.Origins, i)
+		f.TAC = append(f.TAC, instruction)
+	}
+	for _, d := range r.Diagnostics {
+		f.Diagnostics = append(f.Diagnostics, IRDiagnostic{Severity: d.Severity, Category: d.Category, Message: d.Message, Fatal: d.Fatal})
+	}
+	return f
+}
+
+// lineAt returns the i-th entry of the numbers, -1 if there are not as many.
+func lineAt(numbers []int64, i int) int64 {
+	if i < len(numbers) {
+		return numbers[i]
+	}
+	return -1
+}
+
+// irNode returns the node and its children, the tokens found by their place.
+func irNode(n *ASTNode, index map[[2]int64]int) IRNode {
+	node := IRNode{Symbol: string(n.Type), Token: -1}
+	if n.DataType != lexer.Unknown {
+		node.Type = n.DataType.ToString()
+	}
+	if n.Token != nil && len(n.Children) == 0 {
+		if i, ok := index[[2]int64{n.Token.Line, n.Token.Pos}]; ok {
+			node.Token = i
+		}
+	}
+	for _, child := range n.Children {
+		node.Children = append(node.Children, irNode(child, index))
+	}
+	return node
+}
+
+// irInstruction takes the instruction apart.
+func irInstruction(line string) IRInstruction {
+	instruction := IRInstruction{Text: line}
+	if label, ok := strings.CutSuffix(line, ":"); ok && !strings.Contains(label, " ") {
+		instruction.Op, instruction.Result = "label", label
+		return instruction
+	}
+	if target, ok := strings.CutPrefix(line, "goto "); ok {
+		instruction.Op, instruction.Target = "goto", target
+		return instruction
+	}
+	if condition, ok := strings.CutPrefix(line, "if "); ok {
+		if i := strings.LastIndex(condition, " goto "); i >= 0 {
+			instruction.Op, instruction.Target = "if", condition[i+len(" goto "):]
+			instruction.Args = operands(condition[:i])
+			return instruction
+		}
+	}
+	dist, value, ok := strings.Cut(line, " = ")
+	if !ok || strings.HasPrefix(value, "{") {
+		if ok {
+			// an initializer of an array
+			instruction.Op, instruction.Result = "init", dist
+			instruction.Args = strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, "{"), "}"), ", ")
+			return instruction
+		}
+		// push fp, param x, call f, 2, ret and the like
+		op, rest, _ := strings.Cut(line, " ")
+		instruction.Op = op
+		if rest != "" {
+			instruction.Args = strings.Split(rest, ", ")
+		}
+		return instruction
+	}
+	instruction.Result = dist
+	if function, ok := strings.CutPrefix(value, "call "); ok {
+		instruction.Op, instruction.Args = "call", strings.Split(function, ", ")
+		return instruction
+	}
+	if function, ok := strings.CutPrefix(value, "icall "); ok {
+		instruction.Op, instruction.Args = "icall", strings.Split(function, ", ")
+		return instruction
+	}
+	switch fields := operands(value); len(fields) {
+	case 2:
+		instruction.Op, instruction.Args = fields[0], fields[1:]
+	case 3:
+		instruction.Op, instruction.Args = fields[1], []string{fields[0], fields[2]}
+	default:
+		instruction.Op, instruction.Args = "=", []string{value}
+	}
+	return instruction
+}
+
+// operands splits the text at the spaces outside of the quoted strings.
+func operands(s string) []string {
+	var fields []string
+	start, quoted := -1, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+			if start < 0 {
+				start = i
+			}
+		case c == ' ' && !quoted:
+			if start >= 0 {
+				fields, start = append(fields, s[start:i]), -1
+			}
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, s[start:])
+	}
+	return fields
+}
+
+// WriteIR writes the file in CBOR.
+func WriteIR(w io.Writer, f *IRFile) error {
+	return cbor.Encode(w, f)
+}
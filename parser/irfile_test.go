@@ -0,0 +1,88 @@
+package parser_test
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+	"app/utils/cbor"
+)
+
+func TestResult_IR(t *testing.T) {
+	result, err := Compile(Options{
+		Source: strings.NewReader("{\n    int a, b;\n    b = 1;\n    a = abs(b - 4);\n    b = b * 2;\n    printf(\"a b %d\", a);\n}\n"),
+		Tables: sharedParser().Tables(),
+	})
+	if err != nil || result.Failed() {
+		t.Fatalf("Compile: %v %v", err, result.Diagnostics)
+	}
+	f := result.IR("x.in")
+	if f.Format != IRFormat || f.Source != "x.in" || len(f.Tokens) != len(result.Tokens) || len(f.TAC) != len(result.TAC) {
+		t.Fatalf("Expected the tokens and the code of the program, got %+v", f)
+	}
+	if token := f.Tokens[1]; token != (IRToken{Kind: "type", Terminal: "basic", Value: "int", Line: 1, Pos: result.Tokens[1].Pos}) {
+		t.Errorf("Expected the second token to be int, got %+v", token)
+	}
+
+	// the tokens kept in the tree are its leaves, in order
+	var leaves []int
+	var walk func(n IRNode)
+	walk = func(n IRNode) {
+		if n.Token >= 0 {
+			leaves = append(leaves, n.Token)
+			if len(n.Children) > 0 {
+				t.Errorf("Expected the node of token %d to be a leaf, got %+v", n.Token, n)
+			}
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(*f.AST)
+	if f.AST.Symbol != "block" || len(leaves) < len(f.Tokens)/2 || !slices.IsSorted(leaves) || leaves[0] != 0 {
+		t.Errorf("Expected the tokens to be the leaves of the tree, got %v", leaves)
+	}
+
+	find := func(code []IRInstruction, op string) *IRInstruction {
+		i := slices.IndexFunc(code, func(instruction IRInstruction) bool { return instruction.Op == op })
+		if i < 0 {
+			t.Fatalf("Expected an instruction %s in %+v", op, code)
+		}
+		return &code[i]
+	}
+	jump, label := find(f.Code, "if"), find(f.Code, "label")
+	if len(jump.Args) != 3 || jump.Args[1] != ">=" || jump.Args[2] != "0" || jump.Target != label.Result || jump.Line != 3 {
+		t.Errorf("Expected the jump of abs to its end, got %+v and %+v", jump, label)
+	}
+	if negation := find(f.Code, "minus"); !slices.Equal(negation.Args, []string{jump.Args[0]}) || negation.Result != jump.Args[0] {
+		t.Errorf("Expected the negation of abs, got %+v", negation)
+	}
+	if product := find(f.Code, "*"); !slices.Equal(product.Args, []string{"b", "2"}) || product.Result == "" {
+		t.Errorf("Expected the product, got %+v", product)
+	}
+	if param := find(f.Code, "param"); !slices.Equal(param.Args, []string{`"a b "`}) {
+		t.Errorf("Expected the string to stay one operand, got %+v", param)
+	}
+	if label := find(f.TAC, "label"); label.Result != "main" || label.Origin != -1 {
+		t.Errorf("Expected the code to start at main, got %+v", label)
+	}
+
+	var b bytes.Buffer
+	if err := WriteIR(&b, f); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := cbor.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := decoded.(map[any]any)
+	tokens, tac := m["tokens"].([]any), m["tac"].([]any)
+	if m["format"] != IRFormat || m["ast"].(map[any]any)["symbol"] != "block" || len(tokens) != len(f.Tokens) || len(tac) != len(f.TAC) {
+		t.Errorf("Expected the file to decode with its schema, got %v", m)
+	}
+	if first := tokens[0].(map[any]any); first["value"] != "{" || first["line"] != uint64(0) || first["pos"] != uint64(result.Tokens[0].Pos) {
+		t.Errorf("Expected the first token to decode, got %v", first)
+	}
+}
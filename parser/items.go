@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"app/utils/report"
+)
+
+// Core returns the item in the textbook notation without its lookahead, e.g. "A → α · β".
+func (i *LR1Item) Core() string {
+	body := make([]string, 0, len(i.Production.Body)+1)
+	for j, symbol := range i.Production.Body {
+		if j == i.Dot {
+			body = append(body, "·")
+		}
+		if symbol != EPSILON {
+			body = append(body, string(symbol))
+		}
+	}
+	if i.Dot >= len(i.Production.Body) {
+		body = append(body, "·")
+	}
+	return fmt.Sprintf("%s → %s", i.Production.Head, strings.Join(body, " "))
+}
+
+// FormatItems returns the items of the state in the textbook notation,
+// one "[A → α · β, a/b]" line per core with the lookaheads merged.
+func (state *State) FormatItems() []string {
+	return formatItems(state.Items)
+}
+
+// formatItems returns the items in the notation of FormatItems.
+func formatItems(items LR1Items) []string {
+	cores := []string{}
+	lookaheads := map[string][]string{}
+	for _, item := range items {
+		core := item.Core()
+		if _, ok := lookaheads[core]; !ok {
+			cores = append(cores, core)
+		}
+		lookaheads[core] = append(lookaheads[core], string(item.Lookahead))
+	}
+	lines := make([]string, 0, len(cores))
+	for _, core := range cores {
+		slices.Sort(lookaheads[core])
+		lines = append(lines, fmt.Sprintf("[%s, %s]", core, strings.Join(slices.Compact(lookaheads[core]), "/")))
+	}
+	return lines
+}
+
+// WriteItems writes every state's item set and its transitions to the writer,
+// which is the canonical collection of LR(1) item sets of the grammar.
+func (p *Parser) WriteItems(w io.Writer) error {
+	p.EnsureStates()
+	for _, state := range p.States {
+		if _, err := fmt.Fprintf(w, "I%d:\n", state.Index); err != nil {
+			return err
+		}
+		for _, line := range state.FormatItems() {
+			if _, err := fmt.Fprintf(w, "    %s\n", line); err != nil {
+				return err
+			}
+		}
+		symbols := make([]Symbol, 0, len(state.Transitions))
+		for symbol := range state.Transitions {
+			symbols = append(symbols, symbol)
+		}
+		report.SortSymbols(symbols)
+		for _, symbol := range symbols {
+			if _, err := fmt.Fprintf(w, "    GOTO(I%d, %s) = I%d\n", state.Index, symbol, state.Transitions[symbol].Index); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteLabItems writes every state's item set in the format of the lab, the
+// sets numbered from I0 and an item per line and lookahead, A → α · β, a,
+// without the transitions WriteItems adds.
+func (p *Parser) WriteLabItems(w io.Writer) error {
+	p.EnsureStates()
+	for _, state := range p.States {
+		if _, err := fmt.Fprintf(w, "I%d:\n", state.Index); err != nil {
+			return err
+		}
+		// the items of a core together, in the order of the lookaheads
+		cores := []string{}
+		lookaheads := map[string][]string{}
+		for _, item := range state.Items {
+			core := item.Core()
+			if _, ok := lookaheads[core]; !ok {
+				cores = append(cores, core)
+			}
+			lookaheads[core] = append(lookaheads[core], string(item.Lookahead))
+		}
+		for _, core := range cores {
+			slices.Sort(lookaheads[core])
+			for _, lookahead := range slices.Compact(lookaheads[core]) {
+				if _, err := fmt.Fprintf(w, "    %s, %s\n", core, lookahead); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
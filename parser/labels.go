@@ -0,0 +1,28 @@
+package parser
+
+import "fmt"
+
+// LabelAllocator issues the labels of the generated code. A label is named
+// after the construct it belongs to, or the function if none is given, and
+// numbered by a single counter, e.g. L_main_3 or L_while_7. Labels are thus
+// unique across functions and passes, and code can be merged or inlined
+// without renaming them. Labels made by adding a suffix to an issued one,
+// such as L_while_7_end, are unique as well.
+type LabelAllocator struct {
+	Function string // function the code belongs to, main if empty
+
+	next int
+}
+
+// New returns a new label for the kind of construct.
+func (a *LabelAllocator) New(kind string) string {
+	if kind == "" {
+		kind = a.Function
+	}
+	if kind == "" {
+		kind = "main"
+	}
+	label := fmt.Sprintf("L_%s_%d", kind, a.next)
+	a.next++
+	return label
+}
@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	. "app/utils/collections"
+)
+
+// LALRMerge is a state of the LALR(1) automaton made of several states of
+// the canonical LR(1) one, those with the same core.
+type LALRMerge struct {
+	States    []int          // the canonical states merged, in order
+	Conflicts []LALRConflict // the reduce/reduce conflicts the merge introduces
+}
+
+// LALRConflict is a lookahead on which a merged state reduces by more
+// productions than any of the states merged does.
+type LALRConflict struct {
+	Lookahead Terminal
+	Items     []string // the items reduced, in the notation of Core
+}
+
+// LALRReport compares the canonical LR(1) automaton with the LALR(1) one
+// built by merging its states of the same core.
+type LALRReport struct {
+	Canonical int // states of the canonical automaton
+	States    int // states of the LALR automaton
+	Merges    []LALRMerge
+}
+
+// reductions returns the items of the state to reduce by on each lookahead.
+func (p *Parser) reductions(state *State) map[Terminal]Set[string] {
+	reduces := map[Terminal]Set[string]{}
+	for _, item := range state.Items {
+		if item.Dot < len(item.Production.Body) && !item.Production.Body[item.Dot].IsEpsilon() ||
+			item.Production.Equals(p.Grammar.AugmentedProduction) {
+			continue
+		}
+		if reduces[item.Lookahead] == nil {
+			reduces[item.Lookahead] = Set[string]{}
+		}
+		reduces[item.Lookahead].Add(item.Core())
+	}
+	return reduces
+}
+
+// LALRReport returns which states of the parser would merge under LALR(1),
+// and the reduce/reduce conflicts the merges would introduce. Merging never
+// introduces shift/reduce conflicts, as the states merged shift the same
+// symbols and one of them would have the conflict already.
+func (p *Parser) LALRReport() *LALRReport {
+	p.EnsureStates()
+	cores := map[string][]int{}
+	var order []string
+	for i, state := range p.States {
+		items := Set[string]{}
+		for _, item := range state.Items {
+			items.Add(item.Core())
+		}
+		core := strings.Join(slices.Sorted(maps.Keys(items)), "\n")
+		if _, ok := cores[core]; !ok {
+			order = append(order, core)
+		}
+		cores[core] = append(cores[core], i)
+	}
+
+	r := &LALRReport{Canonical: len(p.States), States: len(cores)}
+	for _, core := range order {
+		states := cores[core]
+		if len(states) < 2 {
+			continue
+		}
+		merge := LALRMerge{States: states}
+		merged := map[Terminal]Set[string]{}
+		most := map[Terminal]int{}
+		for _, i := range states {
+			for lookahead, items := range p.reductions(p.States[i]) {
+				if merged[lookahead] == nil {
+					merged[lookahead] = Set[string]{}
+				}
+				merged[lookahead].AddAll(slices.Collect(maps.Keys(items))...)
+				most[lookahead] = max(most[lookahead], len(items))
+			}
+		}
+		for _, lookahead := range slices.Sorted(maps.Keys(merged)) {
+			if items := merged[lookahead]; len(items) > 1 && len(items) > most[lookahead] {
+				merge.Conflicts = append(merge.Conflicts, LALRConflict{Lookahead: lookahead, Items: slices.Sorted(maps.Keys(items))})
+			}
+		}
+		r.Merges = append(r.Merges, merge)
+	}
+	return r
+}
+
+// Conflicts returns the number of reduce/reduce conflicts the merges introduce.
+func (r *LALRReport) Conflicts() int {
+	n := 0
+	for _, merge := range r.Merges {
+		n += len(merge.Conflicts)
+	}
+	return n
+}
+
+// Write writes the report to the writer, a line per merged state followed
+// by the conflicts it introduces.
+func (r *LALRReport) Write(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	printf("Canonical LR(1): %d states, LALR(1): %d states, %d merged\n\n", r.Canonical, r.States, len(r.Merges))
+	for _, merge := range r.Merges {
+		names := make([]string, len(merge.States))
+		for i, state := range merge.States {
+			names[i] = fmt.Sprintf("I%d", state)
+		}
+		printf("%s\n", strings.Join(names, " + "))
+		for _, conflict := range merge.Conflicts {
+			printf("    reduce/reduce on %s: %s\n", conflict.Lookahead, strings.Join(conflict.Items, ", "))
+		}
+	}
+	if n := r.Conflicts(); n > 0 {
+		printf("\nThe merges introduce %d reduce/reduce conflicts.\n", n)
+	} else {
+		printf("\nThe merges introduce no reduce/reduce conflicts.\n")
+	}
+	return err
+}
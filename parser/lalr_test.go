@@ -0,0 +1,61 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+func TestParser_LALRReport(t *testing.T) {
+	// LR(1) but not LALR(1): S → a A d | b B d | a B e | b A e, A → c, B → c
+	p := &Parser{
+		Grammar: &Grammar{
+			AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
+			Productions: []Production{
+				{Head: "S", Body: []Symbol{"a", "A", "d"}},
+				{Head: "S", Body: []Symbol{"b", "B", "d"}},
+				{Head: "S", Body: []Symbol{"a", "B", "e"}},
+				{Head: "S", Body: []Symbol{"b", "A", "e"}},
+				{Head: "A", Body: []Symbol{"c"}},
+				{Head: "B", Body: []Symbol{"c"}},
+			},
+			Terminals: Set[Terminal]{}.AddAll("a", "b", "c", "d", "e", EPSILON, TERMINATE),
+		},
+	}
+	p.BuildFirstSet()
+	p.BuildTable()
+	if p.Table.ReduceReduceConflicts != 0 {
+		t.Fatalf("Expected the grammar to be LR(1)")
+	}
+	report := p.LALRReport()
+	if report.Canonical != len(p.States) || report.States != report.Canonical-1 || len(report.Merges) != 1 {
+		t.Fatalf("Expected the two states reducing c to merge, got %+v", report)
+	}
+	merge := report.Merges[0]
+	if len(merge.States) != 2 || len(merge.Conflicts) != 2 || report.Conflicts() != 2 {
+		t.Fatalf("Expected conflicts on d and e, got %+v", merge)
+	}
+	for i, lookahead := range []Terminal{"d", "e"} {
+		if c := merge.Conflicts[i]; c.Lookahead != lookahead || !slices.Equal(c.Items, []string{"A → c ·", "B → c ·"}) {
+			t.Errorf("Expected A → c and B → c to conflict on %s, got %+v", lookahead, c)
+		}
+	}
+	b := &strings.Builder{}
+	if err := report.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "    reduce/reduce on d: A → c ·, B → c ·\n") || !strings.Contains(b.String(), "introduce 2 reduce/reduce") {
+		t.Errorf("Unexpected report:\n%s", b)
+	}
+
+	// S → L = R | R is LALR(1), its merged states add no conflict
+	lalr := &Parser{Grammar: &grammars[0]}
+	lalr.BuildFirstSet()
+	lalr.BuildTable()
+	if report := lalr.LALRReport(); len(report.Merges) == 0 || report.Conflicts() != 0 {
+		t.Errorf("Expected merges without conflicts, got %+v", report)
+	}
+}
@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"slices"
+)
+
+// LayoutItem is a variable with where the symbol table and the frame put it.
+// Globals and statics are in the data segment at the address the symbol table
+// gave them, in words from its start, the locals at an offset from fp.
+type LayoutItem struct {
+	Name      string
+	Type      string // as declared, such as int[6]
+	Static    bool   // a static of a nested block, in the data segment
+	Address   int    // in the data segment, 0 for a local
+	Offset    int    // in bytes from the start of the data segment, or from fp for a local
+	Size      int    // in bytes of the value
+	Padding   int    // bytes of its slot past the value, up to a word or to the largest local of the name
+	Line, Pos int64
+}
+
+// ScopeLayout is the variables of a scope, in the order of their addresses.
+type ScopeLayout struct {
+	ID, Level int
+	Items     []LayoutItem
+	Data      int // bytes of the slots of the items in the data segment
+	Stack     int // bytes of the slots of the items in the frame
+}
+
+// MemoryLayout is the report of where the variables of a program live, scope
+// by scope, with the frame of the function running them.
+type MemoryLayout struct {
+	Scopes []ScopeLayout
+	Frame  *Frame
+}
+
+// NewMemoryLayout returns the layout of the variables the session declared,
+// the locals at their offsets in the frame. The prelude is left out.
+func NewMemoryLayout(w *Walker, frame *Frame) *MemoryLayout {
+	l := &MemoryLayout{Frame: frame}
+	for _, scope := range w.SymbolTable.LegacyScopes {
+		if scope.Level < 1 {
+			continue
+		}
+		items := slices.DeleteFunc(scope.SortedItems(), func(item *SymbolTableItem) bool {
+			return item.Type != SymbolTableItemTypeVariable && item.Type != SymbolTableItemTypeArray
+		})
+		s := ScopeLayout{ID: scope.ID, Level: scope.Level}
+		for _, item := range items {
+			size := item.VariableSize * max(item.ArraySize, 1)
+			slot := (size + 3) / 4 * 4
+			li := LayoutItem{
+				Name:   item.Qualified(),
+				Type:   item.UnderlyingType,
+				Static: item.Static && scope.Level > 1,
+				Size:   size,
+				Line:   item.Line,
+				Pos:    item.Pos,
+			}
+			if item.Type == SymbolTableItemTypeArray {
+				li.Type += fmt.Sprintf("[%d]", item.ArraySize)
+			}
+			if offset, ok := frame.Offsets[item.Variable]; ok && scope.Level > 1 && !item.Static {
+				// locals of the same name share the slot of the largest
+				li.Offset = offset
+				slot = frame.slot(item.Variable)
+				s.Stack += slot
+			} else {
+				li.Address, li.Offset = item.Address, (item.Address-InitialAddr)*4
+				s.Data += slot
+			}
+			li.Padding = slot - size
+			s.Items = append(s.Items, li)
+		}
+		l.Scopes = append(l.Scopes, s)
+	}
+	return l
+}
+
+// slot returns the bytes of the slot of the local, from its offset to the
+// next slot up.
+func (f *Frame) slot(name string) int {
+	offset := f.Offsets[name]
+	next := -4 * len(f.Saved)
+	for _, other := range f.Offsets {
+		if other > offset && other < next {
+			next = other
+		}
+	}
+	return next - offset
+}
+
+// Write writes the report, a table of the variables per scope with its
+// footprint in the data segment and in the frame, followed by the frame.
+func (l *MemoryLayout) Write(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	for _, s := range l.Scopes {
+		printf("Scope %d, level %d: %d bytes in the data segment, %d in the frame of %s\n", s.ID, s.Level, s.Data, s.Stack, l.Frame.Function)
+		if len(s.Items) == 0 {
+			printf("    no variables\n\n")
+			continue
+		}
+		names := make([]string, len(s.Items))
+		width := len("Name")
+		for i, item := range s.Items {
+			names[i] = item.Name
+			if item.Static {
+				names[i] += " (static)"
+			}
+			width = max(width, len(names[i]), len(item.Type))
+		}
+		printf("    %-*s %-*s %12s %12s %8s %8s  %s\n", width, "Name", width, "Type", "Address", "Offset", "Size", "Padding", "Declared")
+		for i, item := range s.Items {
+			address, offset := fmt.Sprintf("0x%08x", item.Address), fmt.Sprint(item.Offset)
+			if item.Address == 0 {
+				address, offset = "-", fmt.Sprintf("fp[%d]", item.Offset)
+			}
+			printf("    %-*s %-*s %12s %12s %8d %8d  %d:%d\n", width, names[i], width, item.Type, address, offset, item.Size, item.Padding, item.Line, item.Pos)
+		}
+		printf("\n")
+	}
+	f := l.Frame
+	printf("Frame of %s, %d bytes: %d of saved registers, %d of locals, %d of temporaries\n", f.Function, f.Size(), 4*len(f.Saved), f.Locals, f.Temps)
+	return err
+}
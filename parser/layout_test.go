@@ -0,0 +1,59 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestMemoryLayout(t *testing.T) {
+	result, err := Compile(Options{
+		Source: strings.NewReader("{\n    int a;\n    bool c;\n    static bool s[3];\n    {\n        int x;\n        bool c2;\n        static int t;\n        x = a + 1;\n    }\n}\n"),
+		Tables: sharedParser().Tables(),
+	})
+	if err != nil || result.Failed() {
+		t.Fatalf("Compile: %v %v", err, result.Diagnostics)
+	}
+	layout := NewMemoryLayout(result.Walker, result.Frame)
+	if len(layout.Scopes) != 2 {
+		t.Fatalf("Expected the global scope and the block, got %+v", layout.Scopes)
+	}
+	global, block := layout.Scopes[0], layout.Scopes[1]
+	if global.Data != 12 || global.Stack != 0 || len(global.Items) != 3 {
+		t.Errorf("Expected 3 globals in 12 bytes of data, got %+v", global)
+	}
+	// a bool takes a word of which 3 bytes are padding
+	if c := global.Items[1]; c.Name != "c" || c.Offset != 4 || c.Size != 1 || c.Padding != 3 {
+		t.Errorf("Expected c after a, padded to a word, got %+v", c)
+	}
+	if s := global.Items[2]; s.Type != "bool[3]" || s.Size != 3 || s.Padding != 1 {
+		t.Errorf("Expected the array s of 3 bytes, got %+v", s)
+	}
+	if block.Data != 4 || block.Stack != 8 {
+		t.Errorf("Expected the static in the data segment and 2 locals in the frame, got %+v", block)
+	}
+	for _, item := range block.Items {
+		if offset := result.Frame.Offsets[item.Name]; !item.Static && (item.Address != 0 || item.Offset != offset || offset >= 0) {
+			t.Errorf("Expected %s at fp[%d], got %+v", item.Name, offset, item)
+		}
+		if item.Static && (item.Name != "t" || item.Address == 0) {
+			t.Errorf("Expected the static t at an address, got %+v", item)
+		}
+	}
+
+	var b strings.Builder
+	if err := layout.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"Scope 1, level 1: 12 bytes in the data segment, 0 in the frame of main",
+		"Scope 2, level 2: 4 bytes in the data segment, 8 in the frame of main",
+		"t (static)",
+		"Frame of main, 12 bytes:",
+	} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("Expected %q in the report, got\n%s", line, b.String())
+		}
+	}
+}
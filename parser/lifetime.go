@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"app/parser/ir"
+	. "app/utils/collections"
+)
+
+// MarkLifetimes makes the session emit the lifetimes of the locals into the
+// code, begin x where the local x is declared and end x where its scope is
+// exited, through the ExitFunction hook of the symbol table. The begin is not
+// emitted when the scope is entered, since its locals are only known as they
+// are declared, and statements may declare them anywhere in a block. A local
+// shadowing another one of the same name is not marked, the code naming both
+// alike: the lifetime of the outer one covers it. The markers run no code,
+// see DropLifetimes, and let the frame share a slot between the locals never
+// alive at the same time, see LifetimesOf.
+func (w *Walker) MarkLifetimes() {
+	w.lifetimes = NewSet[*SymbolTableItem]()
+	exit := w.SymbolTable.ExitFunction
+	w.SymbolTable.ExitFunction = func(scope *Scope) error {
+		if exit != nil {
+			if err := exit(scope); err != nil {
+				return err
+			}
+		}
+		items := slices.Collect(maps.Values(scope.Items))
+		// the locals end in the reverse order of their declaration
+		slices.SortFunc(items, func(a, b *SymbolTableItem) int { return b.Address - a.Address })
+		for _, item := range items {
+			if w.lifetimes.Contains(item) {
+				w.EmitQuad(ir.Quad{Op: ir.End, Arg1: item.Variable})
+			}
+		}
+		return nil
+	}
+}
+
+// beginLifetime emits the begin of the lifetime of the item just declared,
+// if it is a local and does not shadow one.
+func (w *Walker) beginLifetime(item *SymbolTableItem) {
+	if w.lifetimes == nil || item.Level <= 1 || item.Static ||
+		item.Type != SymbolTableItemTypeVariable && item.Type != SymbolTableItemTypeArray {
+		return
+	}
+	for scope := w.SymbolTable.CurrentScope.Parent; scope != nil; scope = scope.Parent {
+		if outer, ok := scope.Items[item.Variable]; ok && w.lifetimes.Contains(outer) {
+			return
+		}
+	}
+	w.lifetimes.Add(item)
+	w.EmitQuad(ir.Quad{Op: ir.Begin, Arg1: item.Variable})
+}
+
+// lifetimeOf returns the marker and the local of an instruction marking a
+// lifetime, false for the others.
+func lifetimeOf(line string) (string, string, bool) {
+	if !strings.HasPrefix(line, ir.Begin+" ") && !strings.HasPrefix(line, ir.End+" ") {
+		return "", "", false
+	}
+	q := ir.Parse(line)
+	return q.Op, q.Arg1, q.IsLifetime()
+}
+
+// DropLifetimes removes the markers of the lifetimes from the code, for the
+// passes and the targets that do not know them.
+func DropLifetimes(code []string) []string {
+	return slices.DeleteFunc(slices.Clone(code), func(line string) bool {
+		_, _, ok := lifetimeOf(line)
+		return ok
+	})
+}
+
+// Lifetimes maps each local the code marks the lifetime of to the locals
+// alive at the same time as it, on some path.
+type Lifetimes map[string]Set[string]
+
+// LifetimesOf returns the lifetimes the markers of the code give. A local is
+// alive from a begin of it to the next end on every path, those leaving a
+// block by a jump included, so two locals interfere if either begins where
+// the other is alive. The locals not marked are in none, nor are those the
+// code reads or writes where they are not alive, such as a local named as a
+// global is: the code does not tell them apart.
+func LifetimesOf(code []string) Lifetimes {
+	d := &Dataflow[string]{
+		Forward: true,
+		Transfer: func(i int, alive Set[string]) Set[string] {
+			marker, local, ok := lifetimeOf(code[i])
+			switch {
+			case !ok:
+				return alive.Copy()
+			case marker == ir.Begin:
+				return alive.Copy().Add(local)
+			}
+			return alive.Copy().Remove(local)
+		},
+	}
+	in, out := d.Solve(code)
+	l := Lifetimes{}
+	escaped := NewSet[string]()
+	for i, alive := range out {
+		marker, local, ok := lifetimeOf(code[i])
+		if !ok {
+			for _, v := range append(usesOf(code[i]), writtenBy(code[i])) {
+				if !in[i].Contains(v) {
+					escaped.Add(v)
+				}
+			}
+			continue
+		}
+		if marker != ir.Begin {
+			continue
+		}
+		if l[local] == nil {
+			l[local] = NewSet[string]()
+		}
+		for other := range alive {
+			if other == local {
+				continue
+			}
+			if l[other] == nil {
+				l[other] = NewSet[string]()
+			}
+			l[local].Add(other)
+			l[other].Add(local)
+		}
+	}
+	for v := range escaped {
+		delete(l, v)
+	}
+	return l
+}
+
+// Interfere checks if the locals may be alive at the same time, as those
+// not marked may be with any other.
+func (l Lifetimes) Interfere(a, b string) bool {
+	others, ok := l[a]
+	if _, marked := l[b]; !ok || !marked {
+		return a != b
+	}
+	return others.Contains(b)
+}
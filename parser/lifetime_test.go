@@ -0,0 +1,93 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestWalker_MarkLifetimes(t *testing.T) {
+	source := "{ int g; g = 1; { int a; int b[2]; static int s; a = g; b[0] = a; { int a; a = 2; } } { int c; c = g; g = c; } }"
+	r, err := Compile(Options{Source: strings.NewReader(source), Lifetimes: true})
+	if err != nil || r.Failed() {
+		t.Fatalf("Expected the program to compile, got %v %v", err, r.Diagnostics)
+	}
+	// the globals, the statics and the a shadowing a are not marked, and the
+	// locals end in the reverse order of their declaration
+	expected := []string{
+		"g = 1",
+		"begin a",
+		"begin b",
+		"a = g",
+		"b [ 0 ] = a",
+		"a = 2",
+		"end b",
+		"end a",
+		"begin c",
+		"c = g",
+		"g = c",
+		"end c",
+	}
+	if !slices.Equal(r.Walker.ThreeAddress, expected) {
+		t.Errorf("Expected the code\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(r.Walker.ThreeAddress, "\n"))
+	}
+	if uninitialized := r.Walker.Uninitialized(); len(uninitialized) != 0 {
+		t.Errorf("Expected the markers to read no local, got %v uninitialized", uninitialized)
+	}
+	if slices.ContainsFunc(r.TAC, func(line string) bool { return strings.HasPrefix(line, "begin ") || strings.HasPrefix(line, "end ") }) {
+		t.Errorf("Expected the markers dropped from the code compiled, got %v", r.TAC)
+	}
+	// c shares the slot of a, b is alive with a
+	if offsets := r.Frame.Offsets; offsets["c"] != offsets["a"] || offsets["b"] == offsets["a"] || r.Frame.Locals != 12 {
+		t.Errorf("Expected c to share the slot of a in 12 bytes of locals, got %v in %d", offsets, r.Frame.Locals)
+	}
+
+	r, _ = Compile(Options{Source: strings.NewReader(source)})
+	if len(r.Walker.ThreeAddress) != len(expected)-6 || r.Frame.Locals != 16 {
+		t.Errorf("Expected no markers and a slot each without lifetimes, got %v in %d bytes", r.Walker.ThreeAddress, r.Frame.Locals)
+	}
+}
+
+func TestLifetimesOf(t *testing.T) {
+	l := LifetimesOf([]string{
+		"begin a",
+		"if a < 1 goto L1",
+		"begin b",
+		"b = a",
+		"end b",
+		"goto L2",
+		"L1:",
+		"end a",
+		"L2:",
+		"begin c",
+		"c = 1",
+		"end c",
+		"begin d",
+		"end d",
+		"d = 2",
+	})
+	// a is still alive at L2 on the way through b, and d is written once dead
+	for _, test := range []struct {
+		a, b      string
+		interfere bool
+	}{
+		{"a", "b", true},
+		{"a", "c", true},
+		{"b", "c", false},
+		{"c", "c", false},
+		{"d", "c", true},
+		{"e", "c", true},
+	} {
+		if interfere := l.Interfere(test.a, test.b); interfere != test.interfere {
+			t.Errorf("Expected %s and %s to interfere %v, got %v", test.a, test.b, test.interfere, interfere)
+		}
+	}
+	if _, ok := l["d"]; ok {
+		t.Errorf("Expected d not to be marked, got %v", l)
+	}
+	if code := DropLifetimes([]string{"begin a", "a = 1", "end = a", "end a"}); !slices.Equal(code, []string{"a = 1", "end = a"}) {
+		t.Errorf("Expected the markers alone dropped, got %v", code)
+	}
+}
@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"app/lexer"
+	. "app/utils/collections"
+	"app/utils/report"
+)
+
+// LL1Table is the predictive parsing table of a grammar, built from its
+// FIRST and FOLLOW sets for the lab to compare with the LR table: the
+// production expanding a nonterminal on the next terminal. A grammar is
+// LL(1) if no cell asks for two productions. The others still have a table,
+// keeping the first production of each cell, as the LR table keeps one
+// action.
+type LL1Table struct {
+	Grammar   *Grammar
+	Table     map[Symbol]map[Terminal]int // the index of the production in Grammar.Productions
+	Conflicts []LL1Conflict               // in the order of the nonterminals, then of the terminals
+}
+
+// LL1Conflict is a cell of the LL(1) table asked for by several productions.
+type LL1Conflict struct {
+	Nonterminal Symbol
+	Terminal    Terminal
+	Productions []int // the productions in their order, the first one kept
+}
+
+func (c LL1Conflict) String() string {
+	return fmt.Sprintf("LL(1) conflict at M[%s, %s]: productions %s", c.Nonterminal, c.Terminal, strings.Join(productionNumbers(c.Productions), ", "))
+}
+
+// productionNumbers returns the indexes of the productions as text, to be
+// joined.
+func productionNumbers(productions []int) []string {
+	numbers := make([]string, len(productions))
+	for i, production := range productions {
+		numbers[i] = fmt.Sprint(production)
+	}
+	return numbers
+}
+
+// BuildLL1 builds the LL(1) table of the grammar: the production A → α is
+// put in M[A, a] for every terminal a of FIRST(α), and in M[A, b] for every
+// terminal b of FOLLOW(A) if α derives ε, $ included.
+func (g *Grammar) BuildLL1() *LL1Table {
+	t := &LL1Table{Grammar: g, Table: map[Symbol]map[Terminal]int{}}
+	cells := map[Symbol]map[Terminal][]int{}
+	for i, production := range g.Productions {
+		row := cells[production.Head]
+		if row == nil {
+			row = map[Terminal][]int{}
+			cells[production.Head] = row
+		}
+		first := g.FirstOf(production.Body...)
+		for terminal := range first.All() {
+			if !terminal.IsEpsilon() {
+				row[terminal] = append(row[terminal], i)
+			}
+		}
+		if first.Contains(EPSILON) {
+			for terminal := range g.Follow(production.Head).All() {
+				row[terminal] = append(row[terminal], i)
+			}
+		}
+	}
+	for _, head := range g.Heads() {
+		t.Table[head] = map[Terminal]int{}
+		for _, terminal := range slices.SortedFunc(maps.Keys(cells[head]), report.CompareSymbols[Terminal]) {
+			productions := cells[head][terminal]
+			t.Table[head][terminal] = productions[0]
+			if len(productions) > 1 {
+				t.Conflicts = append(t.Conflicts, LL1Conflict{Nonterminal: head, Terminal: terminal, Productions: productions})
+			}
+		}
+	}
+	return t
+}
+
+// IsLL1 checks if no cell of the table is asked for by several productions.
+func (t *LL1Table) IsLL1() bool {
+	return len(t.Conflicts) == 0
+}
+
+// Lookup returns the production expanding the nonterminal on the terminal,
+// false if the cell is empty.
+func (t *LL1Table) Lookup(nonterminal Symbol, terminal Terminal) (int, bool) {
+	production, ok := t.Table[nonterminal][terminal]
+	return production, ok
+}
+
+// Expected returns the terminals the nonterminal has a production on, in the
+// order of the columns.
+func (t *LL1Table) Expected(nonterminal Symbol) []Terminal {
+	return slices.SortedFunc(maps.Keys(t.Table[nonterminal]), report.CompareSymbols[Terminal])
+}
+
+// Write writes the table as the textbook draws it, a row per nonterminal in
+// the order of its rules and a column per terminal with $ last, each cell
+// the productions asking for it split by |, and then the conflicts with
+// their productions.
+func (t *LL1Table) Write(w io.Writer) error {
+	g := t.Grammar
+	format := func(production int) string {
+		p := g.Productions[production]
+		if p.Length() == 0 {
+			p.Body = []Symbol{EPSILON}
+		}
+		return formatProduction(p)
+	}
+	heads := g.Heads()
+	columns := NewSortedSet(report.CompareSymbols[Terminal])
+	cells := map[Symbol]map[Terminal]string{}
+	for _, head := range heads {
+		cells[head] = map[Terminal]string{}
+		for terminal, production := range t.Table[head] {
+			columns.Add(terminal)
+			cells[head][terminal] = format(production)
+		}
+	}
+	for _, c := range t.Conflicts {
+		alternatives := make([]string, len(c.Productions))
+		for i, production := range c.Productions {
+			alternatives[i] = format(production)
+		}
+		cells[c.Nonterminal][c.Terminal] = strings.Join(alternatives, " | ")
+	}
+	table := report.Table{Sep: " | "}
+	header := []string{""}
+	for terminal := range columns.All() {
+		header = append(header, string(terminal))
+	}
+	// the empty cell last closes the rows with a |
+	table.Row(append(header, "")...)
+	for _, head := range heads {
+		row := []string{string(head)}
+		for terminal := range columns.All() {
+			row = append(row, cells[head][terminal])
+		}
+		table.Row(append(row, "")...)
+	}
+	var b strings.Builder
+	for _, line := range table.Lines() {
+		b.WriteString(line + "\n")
+	}
+	if t.IsLL1() {
+		b.WriteString("\nLL(1), no conflicts\n")
+	} else {
+		fmt.Fprintf(&b, "\n%d LL(1) conflicts\n", len(t.Conflicts))
+	}
+	for _, c := range t.Conflicts {
+		fmt.Fprintf(&b, "\nM[%s, %s]:\n", c.Nonterminal, g.Name(Symbol(c.Terminal)))
+		for _, production := range c.Productions {
+			fmt.Fprintf(&b, "    %d: %s\n", production, format(production))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// LL1Error is where a predictive parse fails: the terminal read when
+// Nonterminal is to be expanded and has no production on it, or when
+// Expected alone, the terminal on top of the stack, can be matched.
+type LL1Error struct {
+	Nonterminal Symbol
+	Terminal    Terminal
+	Expected    []Terminal
+	Symbols     *SymbolRegistry // names the terminals in the message, see Grammar.Symbols
+}
+
+func (e *LL1Error) Error() string {
+	if e.Nonterminal == "" {
+		return fmt.Sprintf("expected %s, found %s", names(e.Symbols, e.Expected), names(e.Symbols, []Terminal{e.Terminal}))
+	}
+	message := fmt.Sprintf("no production of %s on symbol %s", e.Nonterminal, names(e.Symbols, []Terminal{e.Terminal}))
+	if len(e.Expected) > 0 {
+		message += ", expected " + names(e.Symbols, e.Expected)
+	}
+	return message
+}
+
+// ParseLL1 parses the tokens of the lexer top-down with the table, and
+// returns the productions of the leftmost derivation of the program, in the
+// order they are expanded. The tokens are read as terminals as the LR parse
+// reads them, by Reflect, without the initializer lists it tells apart from
+// blocks. The limits bound the stack and the steps, a left-recursive grammar
+// growing its stack on a conflict forever. The first error stops the parse,
+// at its position.
+func ParseLL1(t *LL1Table, l *lexer.Lexer, limits Limits) ([]int, error) {
+	g := t.Grammar
+	stack := Stack[Symbol]{}
+	stack.PushAll(TERMINATE, g.Start())
+	var derivation []int
+	token, err := l.Next()
+	for steps := 1; ; steps++ {
+		if err != nil && !errors.Is(err, io.EOF) {
+			return derivation, err
+		}
+		if errors.Is(err, io.EOF) {
+			token.Type = lexer.EOF
+		}
+		if limits.MaxDepth > 0 && stack.Len() > limits.MaxDepth {
+			return derivation, fmt.Errorf("%w: stack depth exceeds %d, at line %d, pos %d", ErrResourceLimit, limits.MaxDepth, token.Line, token.Pos)
+		}
+		if limits.MaxSteps > 0 && steps > limits.MaxSteps {
+			return derivation, fmt.Errorf("%w: more than %d steps, at line %d, pos %d", ErrResourceLimit, limits.MaxSteps, token.Line, token.Pos)
+		}
+		terminal := Terminal(Reflect(&token))
+		top, _ := stack.Pop()
+		switch {
+		case top.IsEpsilon():
+		case g.IsTerminal(top) || top == TERMINATE:
+			if Terminal(top) != terminal {
+				return derivation, fmt.Errorf("%w, at line %d, pos %d", &LL1Error{Terminal: terminal, Expected: []Terminal{Terminal(top)}, Symbols: g.Symbols()}, token.Line, token.Pos)
+			}
+			if top == TERMINATE {
+				return derivation, nil
+			}
+			token, err = l.Next()
+		default:
+			production, ok := t.Lookup(top, terminal)
+			if !ok {
+				return derivation, fmt.Errorf("%w, at line %d, pos %d", &LL1Error{Nonterminal: top, Terminal: terminal, Expected: t.Expected(top), Symbols: g.Symbols()}, token.Line, token.Pos)
+			}
+			derivation = append(derivation, production)
+			body := g.Productions[production].Body
+			for i := len(body) - 1; i >= 0; i-- {
+				stack.Push(body[i])
+			}
+		}
+	}
+}
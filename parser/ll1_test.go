@@ -0,0 +1,139 @@
+package parser_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+func expressionGrammar() *Grammar {
+	return &Grammar{
+		Productions: []Production{
+			{Head: "E", Body: []Symbol{"T", "E'"}},
+			{Head: "E'", Body: []Symbol{"+", "T", "E'"}},
+			{Head: "E'", Body: []Symbol{EPSILON}},
+			{Head: "T", Body: []Symbol{"F", "T'"}},
+			{Head: "T'", Body: []Symbol{"*", "F", "T'"}},
+			{Head: "T'", Body: []Symbol{}},
+			{Head: "F", Body: []Symbol{"(", "E", ")"}},
+			{Head: "F", Body: []Symbol{"id"}},
+		},
+		Terminals: NewSet[Terminal]().AddAll("id", "+", "*", "(", ")", EPSILON),
+	}
+}
+
+func TestGrammar_BuildLL1(t *testing.T) {
+	table := expressionGrammar().BuildLL1()
+	if !table.IsLL1() {
+		t.Fatalf("Expected the expression grammar to be LL(1), got %v", table.Conflicts)
+	}
+	for _, test := range []struct {
+		nonterminal Symbol
+		terminal    Terminal
+		production  int
+		ok          bool
+	}{
+		{"E", "id", 0, true},
+		{"E", "(", 0, true},
+		{"E", "+", 0, false},
+		{"E'", "+", 1, true},
+		{"E'", ")", 2, true},
+		{"E'", "$", 2, true},
+		{"T'", "+", 5, true},
+		{"T'", "*", 4, true},
+		{"F", "id", 7, true},
+		{"F", "(", 6, true},
+	} {
+		if production, ok := table.Lookup(test.nonterminal, test.terminal); ok != test.ok || ok && production != test.production {
+			t.Errorf("Expected M[%s, %s] = %d (%v), got %d (%v)", test.nonterminal, test.terminal, test.production, test.ok, production, ok)
+		}
+	}
+	if expected := table.Expected("T'"); !slices.Equal(expected, []Terminal{")", "*", "+", "$"}) {
+		t.Errorf("Expected T' on ), *, + and $, got %v", expected)
+	}
+
+	var b strings.Builder
+	if err := table.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(b.String(), "\n")
+	if !strings.Contains(lines[0], "| id ") || strings.Index(lines[0], "$") < strings.Index(lines[0], "| id ") ||
+		!strings.Contains(b.String(), "E' → ε") || !strings.Contains(b.String(), "T' → ε") || !strings.Contains(b.String(), "LL(1), no conflicts") {
+		t.Errorf("Expected the table with a column per terminal and $ last, got\n%s", b.String())
+	}
+
+	left := &Grammar{
+		Productions: []Production{
+			{Head: "E", Body: []Symbol{"E", "+", "T"}},
+			{Head: "E", Body: []Symbol{"T"}},
+			{Head: "T", Body: []Symbol{"id"}},
+		},
+		Terminals: NewSet[Terminal]().AddAll("id", "+", EPSILON),
+	}
+	table = left.BuildLL1()
+	if len(table.Conflicts) != 1 || table.Conflicts[0].Nonterminal != "E" || table.Conflicts[0].Terminal != "id" ||
+		!slices.Equal(table.Conflicts[0].Productions, []int{0, 1}) {
+		t.Fatalf("Expected the left recursion to conflict at M[E, id], got %v", table.Conflicts)
+	}
+	if s := table.Conflicts[0].String(); s != "LL(1) conflict at M[E, id]: productions 0, 1" {
+		t.Errorf("Expected the conflict to name its productions, got %q", s)
+	}
+	b.Reset()
+	table.Write(&b)
+	if !strings.Contains(b.String(), "E → E + T | E → T") || !strings.Contains(b.String(), "1 LL(1) conflicts") {
+		t.Errorf("Expected the conflict in its cell and listed, got\n%s", b.String())
+	}
+
+	if NewGrammar().BuildLL1().IsLL1() {
+		t.Error("Expected the left-recursive grammar of the experiment not to be LL(1)")
+	}
+}
+
+func TestParseLL1(t *testing.T) {
+	table := expressionGrammar().BuildLL1()
+	derivation, err := ParseLL1(table, lexer.NewLexer(strings.NewReader("id + id * id\n")), DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// E → T E', T → F T', F → id, T' → ε, E' → + T E', T → F T', F → id,
+	// T' → * F T', F → id, T' → ε, E' → ε
+	if expected := []int{0, 3, 7, 5, 1, 3, 7, 4, 7, 5, 2}; !slices.Equal(derivation, expected) {
+		t.Errorf("Expected the leftmost derivation %v, got %v", expected, derivation)
+	}
+
+	_, err = ParseLL1(table, lexer.NewLexer(strings.NewReader("id +\n  * id")), DefaultLimits)
+	var e *LL1Error
+	if !errors.As(err, &e) || e.Nonterminal != "T" || e.Terminal != "*" {
+		t.Fatalf("Expected no production of T on *, got %v", err)
+	}
+	if err.Error() != "no production of T on symbol *, expected (, id, at line 1, pos 3" {
+		t.Errorf("Expected the error at the position of *, got %q", err)
+	}
+
+	_, err = ParseLL1(table, lexer.NewLexer(strings.NewReader("( id id\n")), DefaultLimits)
+	if err == nil || !strings.HasPrefix(err.Error(), "no production of T' on symbol id") {
+		t.Errorf("Expected id not to follow id, got %v", err)
+	}
+	_, err = ParseLL1(table, lexer.NewLexer(strings.NewReader("( id\n")), DefaultLimits)
+	if err == nil || !strings.HasPrefix(err.Error(), "expected ), found $") {
+		t.Errorf("Expected ) to be missing, got %v", err)
+	}
+
+	left := &Grammar{
+		Productions: []Production{
+			{Head: "E", Body: []Symbol{"E", "+", "T"}},
+			{Head: "E", Body: []Symbol{"T"}},
+			{Head: "T", Body: []Symbol{"id"}},
+		},
+		Terminals: NewSet[Terminal]().AddAll("id", "+", EPSILON),
+	}
+	_, err = ParseLL1(left.BuildLL1(), lexer.NewLexer(strings.NewReader("id + id\n")), Limits{MaxDepth: 100})
+	if !errors.Is(err, ErrResourceLimit) {
+		t.Errorf("Expected the left recursion to exceed the depth, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	. "app/utils/collections"
+)
+
+// Loop is a natural loop of three-address code, entered through its header
+// and closed by the jumps back to it.
+type Loop struct {
+	Header int   // the label the loop starts with
+	Body   []int // instructions of the loop in order, the header first
+}
+
+// Label returns the label of the header.
+func (l *Loop) Label(code []string) string {
+	label, _ := labelOf(code[l.Header])
+	return label
+}
+
+// FindLoops returns the natural loops of the code, ordered by their header.
+// A jump to a label above it is a back edge, and the loop it closes holds the
+// instructions reaching the jump without passing the header. Loops sharing a
+// header are merged.
+func FindLoops(code []string) []*Loop {
+	succ := successors(code)
+	pred := make([][]int, len(code))
+	for i, next := range succ {
+		for _, j := range next {
+			pred[j] = append(pred[j], i)
+		}
+	}
+	bodies := map[int]map[int]bool{}
+	for i, next := range succ {
+		for _, h := range next {
+			if h > i || !isLabel(code[h]) {
+				continue
+			}
+			body := bodies[h]
+			if body == nil {
+				body = map[int]bool{h: true}
+				bodies[h] = body
+			}
+			stack := NewStack[int]()
+			stack.Push(i)
+			for !stack.IsEmpty() {
+				n, _ := stack.Pop()
+				if body[n] {
+					continue
+				}
+				body[n] = true
+				stack.PushAll(pred[n]...)
+			}
+		}
+	}
+	loops := make([]*Loop, 0, len(bodies))
+	for _, h := range slices.Sorted(maps.Keys(bodies)) {
+		loops = append(loops, &Loop{Header: h, Body: slices.Sorted(maps.Keys(bodies[h]))})
+	}
+	return loops
+}
+
+// InductionVariable is a variable whose value in a loop is a linear function
+// Scale * Base + Offset of a basic induction variable Base, which changes by
+// the same Step in every iteration. For basic ones Base is the variable
+// itself, with a Scale of 1 and no Offset.
+type InductionVariable struct {
+	Variable string
+	Base     string
+	Scale    int64
+	Offset   int64
+	Step     int64 // change per iteration, of Base for derived ones
+	Line     int   // instruction writing the variable in the loop
+}
+
+// Basic checks if the variable is a basic induction variable.
+func (v *InductionVariable) Basic() bool {
+	return v.Variable == v.Base
+}
+
+func (v *InductionVariable) String() string {
+	if v.Basic() {
+		return fmt.Sprintf("basic %s, step %d", v.Variable, v.Step)
+	}
+	return fmt.Sprintf("derived %s = %d * %s + %d", v.Variable, v.Scale, v.Base, v.Offset)
+}
+
+// linear is an instruction computing operand op constant, or its reverse for
+// the commutative operators, as a linear function of the operand.
+func linear(value string) (operand string, scale, offset int64, ok bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 1 {
+		return fields[0], 1, 0, true
+	}
+	if len(fields) == 2 && fields[0] == "minus" {
+		return fields[1], -1, 0, true
+	}
+	if len(fields) != 3 {
+		return "", 0, 0, false
+	}
+	a, op, b := fields[0], fields[1], fields[2]
+	c, err := strconv.ParseInt(b, 0, 64)
+	if err != nil && (op == "+" || op == "*") {
+		// the constant may come first
+		if c, err = strconv.ParseInt(a, 0, 64); err == nil {
+			a = b
+		}
+	}
+	if err != nil {
+		return "", 0, 0, false
+	}
+	switch op {
+	case "+":
+		return a, 1, c, true
+	case "-":
+		return a, 1, -c, true
+	case "*":
+		return a, c, 0, true
+	}
+	return "", 0, 0, false
+}
+
+// InductionVariables returns the induction variables of the loop. A basic
+// one is written once in the loop, adding a constant to itself directly or
+// through a temporary. A derived one is written once in the loop as a linear
+// function of another induction variable, with its basic one not written in
+// between.
+func (l *Loop) InductionVariables(code []string) []*InductionVariable {
+	defs := map[string][]int{}
+	for _, i := range l.Body {
+		if v, ok := definitionOf(code[i]); ok {
+			defs[v] = append(defs[v], i)
+		} else if v := writtenBy(code[i]); v != "" {
+			// a store into an element, the array is no induction variable
+			defs[v] = append(defs[v], i, i)
+		}
+	}
+	// the linear function of each instruction writing a variable once in the loop
+	type step struct {
+		operand       string
+		scale, offset int64
+	}
+	steps := map[string]step{}
+	for v, lines := range defs {
+		if len(lines) != 1 {
+			continue
+		}
+		_, value, _ := strings.Cut(code[lines[0]], " = ")
+		if operand, scale, offset, ok := linear(value); ok {
+			steps[v] = step{operand, scale, offset}
+		}
+	}
+
+	found := map[string]*InductionVariable{}
+	for v, s := range steps {
+		switch {
+		case s.operand == v && s.scale == 1 && s.offset != 0:
+			found[v] = &InductionVariable{Variable: v, Base: v, Scale: 1, Step: s.offset, Line: defs[v][0]}
+		case s.scale == 1 && s.offset == 0:
+			// v = t, with t = v + c written once in the loop
+			if t, ok := steps[s.operand]; ok && t.operand == v && t.scale == 1 && t.offset != 0 {
+				found[v] = &InductionVariable{Variable: v, Base: v, Scale: 1, Step: t.offset, Line: defs[v][0]}
+			}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, v := range slices.Sorted(maps.Keys(steps)) {
+			s := steps[v]
+			from, ok := found[s.operand]
+			if _, done := found[v]; done || !ok {
+				continue
+			}
+			base := found[from.Base]
+			// the basic variable must hold the same value where both are written
+			if !from.Basic() && between(l.Body, base.Line, from.Line, defs[v][0]) {
+				continue
+			}
+			found[v] = &InductionVariable{
+				Variable: v,
+				Base:     from.Base,
+				Scale:    from.Scale * s.scale,
+				Offset:   from.Offset*s.scale + s.offset,
+				Step:     base.Step,
+				Line:     defs[v][0],
+			}
+			changed = true
+		}
+	}
+
+	result := slices.Collect(maps.Values(found))
+	slices.SortFunc(result, func(a, b *InductionVariable) int {
+		if a.Basic() != b.Basic() {
+			if a.Basic() {
+				return -1
+			}
+			return 1
+		}
+		return a.Line - b.Line
+	})
+	return result
+}
+
+// between checks if the instruction x runs after a and before b in an
+// iteration of the loop.
+func between(body []int, x, a, b int) bool {
+	i, j, k := slices.Index(body, a), slices.Index(body, b), slices.Index(body, x)
+	if i <= j {
+		return i < k && k < j
+	}
+	return k > i || k < j
+}
+
+// WriteLoops writes a report of the loops of the code with their induction
+// variables to the writer.
+func WriteLoops(w io.Writer, code []string) error {
+	loops := FindLoops(code)
+	if len(loops) == 0 {
+		_, err := fmt.Fprintln(w, "no loops")
+		return err
+	}
+	for _, l := range loops {
+		if _, err := fmt.Fprintf(w, "loop %s, lines %d-%d\n", l.Label(code), l.Header, l.Body[len(l.Body)-1]); err != nil {
+			return err
+		}
+		variables := l.InductionVariables(code)
+		if len(variables) == 0 {
+			if _, err := fmt.Fprintln(w, "    no induction variables"); err != nil {
+				return err
+			}
+		}
+		for _, v := range variables {
+			if _, err := fmt.Fprintf(w, "    %s\n", v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
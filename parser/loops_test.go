@@ -0,0 +1,79 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestFindLoops(t *testing.T) {
+	code := []string{
+		"i = 0",
+		"L_loop_0:",
+		"if i >= 10 goto L_loop_0_end",
+		"j = 0",
+		"L_loop_1:",
+		"j = j + 1",
+		"if j < 5 goto L_loop_1",
+		"i = i + 1",
+		"goto L_loop_0",
+		"L_loop_0_end:",
+	}
+	loops := FindLoops(code)
+	if len(loops) != 2 {
+		t.Fatalf("Expected 2 loops, got %d", len(loops))
+	}
+	if loops[0].Label(code) != "L_loop_0" || !slices.Equal(loops[0].Body, []int{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("Expected the outer loop to span lines 1-8, got %s %v", loops[0].Label(code), loops[0].Body)
+	}
+	if loops[1].Label(code) != "L_loop_1" || !slices.Equal(loops[1].Body, []int{4, 5, 6}) {
+		t.Errorf("Expected the inner loop to span lines 4-6, got %s %v", loops[1].Label(code), loops[1].Body)
+	}
+	if len(FindLoops(code[:3])) != 0 {
+		t.Errorf("Expected no loops without back edges")
+	}
+}
+
+func TestInductionVariables(t *testing.T) {
+	code := []string{
+		"i = 0",
+		"L_loop_0:",
+		"$(0x10000001) = i * 4",
+		"$(0x10000002) = $(0x10000001) + 8",
+		"x = a [ 1 ]",
+		"$(0x10000003) = i + 2",
+		"i = $(0x10000003)",
+		"$(0x10000004) = $(0x10000002) + 1",
+		"$(0x10000005) = 3 * i",
+		"k = k mod 2",
+		"s = s + x",
+		"if i < 10 goto L_loop_0",
+	}
+	loops := FindLoops(code)
+	if len(loops) != 1 {
+		t.Fatalf("Expected 1 loop, got %d", len(loops))
+	}
+	got := []string{}
+	for _, v := range loops[0].InductionVariables(code) {
+		got = append(got, v.String())
+	}
+	// s is not increased by a constant, and $(0x10000004) is computed from
+	// $(0x10000002) after i is increased
+	expected := []string{
+		"basic i, step 2",
+		"derived $(0x10000001) = 4 * i + 0",
+		"derived $(0x10000002) = 4 * i + 8",
+		"derived $(0x10000003) = 1 * i + 2",
+		"derived $(0x10000005) = 3 * i + 0",
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	var b strings.Builder
+	if err := WriteLoops(&b, code); err != nil || !strings.HasPrefix(b.String(), "loop L_loop_0, lines 1-11\n    basic i, step 2\n") {
+		t.Errorf("Expected the report of the loop, got %q, %v", b.String(), err)
+	}
+}
@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Symbol returns the name of the item in the emitted code, the same in every
+// backend and in the debug information. The names of the program are taken
+// as they are by no assembler, since a variable may be named as a mnemonic,
+// a register, a directive or a label of the backends, such as la, sp, data,
+// main or str_0, and the locals of the same name in different blocks must
+// not clash. A symbol is thus mangled: _V for a variable or an array, _F for
+// a function, _C for a constant, _B for a builtin, then the module of the
+// item if it has one and the name, each after its length in bytes, then the
+// ID of the scope of a local after an underscore: _V1a, _V3lib5count,
+// _V1i_7. No name the
+// backends make up starts with _ and a capital letter, the lengths make two
+// items of different names, modules or scopes differ, and Demangle reads a
+// symbol back. A function keeps its entry label, F_ and its name, which the
+// functions defined in other files are called by.
+func (item *SymbolTableItem) Symbol() string {
+	if item.Type == SymbolTableItemTypeFunction && item.Label != "" {
+		return item.Label
+	}
+	kind := "V"
+	switch item.Type {
+	case SymbolTableItemTypeFunction:
+		kind = "F"
+	case SymbolTableItemTypeConstant:
+		kind = "C"
+	case SymbolTableItemTypeBuiltin:
+		kind = "B"
+	}
+	var b strings.Builder
+	b.WriteString("_" + kind)
+	if item.Module != "" {
+		component(&b, item.Module)
+	}
+	component(&b, item.Variable)
+	if item.Level > 1 {
+		fmt.Fprintf(&b, "_%d", item.Scope)
+	}
+	return b.String()
+}
+
+// component writes a name of a symbol after its length, or as X, the length
+// of its hex and the hex of its bytes if it has any an assembler would not
+// take, such as the letters of other scripts the lexer reads.
+func component(b *strings.Builder, name string) {
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c != '_' && (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			fmt.Fprintf(b, "X%d%s", 2*len(name), hex.EncodeToString([]byte(name)))
+			return
+		}
+	}
+	fmt.Fprintf(b, "%d%s", len(name), name)
+}
+
+// Mangled is a symbol read back by Demangle.
+type Mangled struct {
+	Kind   SymbolTableItemType // variable, function, constant or builtin
+	Module string
+	Name   string
+	Scope  int // the ID of the scope of a local, -1 for a global
+}
+
+// Demangle reads a symbol made by Symbol back, failing on the others.
+func Demangle(symbol string) (Mangled, error) {
+	invalid := fmt.Errorf("%s is not a mangled symbol", symbol)
+	if len(symbol) < 2 || symbol[0] != '_' {
+		return Mangled{}, invalid
+	}
+	m := Mangled{Scope: -1}
+	switch symbol[1] {
+	case 'V':
+		m.Kind = SymbolTableItemTypeVariable
+	case 'F':
+		m.Kind = SymbolTableItemTypeFunction
+	case 'C':
+		m.Kind = SymbolTableItemTypeConstant
+	case 'B':
+		m.Kind = SymbolTableItemTypeBuiltin
+	default:
+		return Mangled{}, invalid
+	}
+	rest := symbol[2:]
+	var parts []string
+	for rest != "" && (rest[0] == 'X' || rest[0] >= '0' && rest[0] <= '9') {
+		encoded := rest[0] == 'X'
+		rest = strings.TrimPrefix(rest, "X")
+		digits := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if digits < 0 {
+			digits = len(rest)
+		}
+		n, err := strconv.Atoi(rest[:digits])
+		if err != nil || n == 0 || digits+n > len(rest) {
+			return Mangled{}, invalid
+		}
+		name := rest[digits : digits+n]
+		if encoded {
+			decoded, err := hex.DecodeString(name)
+			if err != nil {
+				return Mangled{}, invalid
+			}
+			name = string(decoded)
+		}
+		parts, rest = append(parts, name), rest[digits+n:]
+	}
+	if scope, ok := strings.CutPrefix(rest, "_"); ok {
+		n, err := strconv.Atoi(scope)
+		if err != nil || n < 0 {
+			return Mangled{}, invalid
+		}
+		m.Scope, rest = n, ""
+	}
+	switch {
+	case rest != "" || len(parts) == 0 || len(parts) > 2:
+		return Mangled{}, invalid
+	case len(parts) == 2:
+		m.Module = parts[0]
+	}
+	m.Name = parts[len(parts)-1]
+	return m, nil
+}
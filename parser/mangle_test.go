@@ -0,0 +1,63 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestSymbolTableItem_Symbol(t *testing.T) {
+	for _, tc := range []struct {
+		item     SymbolTableItem
+		expected string
+	}{
+		{SymbolTableItem{Variable: "a", Type: SymbolTableItemTypeVariable, Level: 1}, "_V1a"},
+		{SymbolTableItem{Variable: "count", Type: SymbolTableItemTypeArray, Module: "lib", Level: 1}, "_V3lib5count"},
+		{SymbolTableItem{Variable: "i", Type: SymbolTableItemTypeVariable, Scope: 7, Level: 2}, "_V1i_7"},
+		{SymbolTableItem{Variable: "read_int", Type: SymbolTableItemTypeBuiltin}, "_B8read_int"},
+		{SymbolTableItem{Variable: "名", Type: SymbolTableItemTypeVariable, Level: 1}, "_VX6e5908d"},
+		{SymbolTableItem{Variable: "gcd", Type: SymbolTableItemTypeFunction, Label: "F_gcd"}, "F_gcd"},
+	} {
+		symbol := tc.item.Symbol()
+		if symbol != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.item.Variable, tc.expected, symbol)
+			continue
+		}
+		if tc.item.Label != "" {
+			continue
+		}
+		m, err := Demangle(symbol)
+		scope := -1
+		if tc.item.Level > 1 {
+			scope = tc.item.Scope
+		}
+		if err != nil || m.Name != tc.item.Variable || m.Module != tc.item.Module || m.Scope != scope {
+			t.Errorf("%s: expected %s read back, got %+v, %v", symbol, tc.item.Variable, m, err)
+		}
+	}
+	for _, symbol := range []string{"main", "_V", "_V2a", "_Q1a", "_V1a_x", "_VX2zz", "_V1a1b1c"} {
+		if _, err := Demangle(symbol); err == nil {
+			t.Errorf("Expected %s not to be read back", symbol)
+		}
+	}
+}
+
+func TestCompile_Symbols(t *testing.T) {
+	result, err := Compile(Options{Source: strings.NewReader("{ int data; { int la; } { float la; } }"), Tables: sharedParser().Tables()})
+	if err != nil || result.Failed() {
+		t.Fatalf("Expected the program to compile, got %v, %v", result, err)
+	}
+	symbols := map[string]bool{}
+	for _, scope := range result.Walker.SymbolTable.LegacyScopes {
+		for _, item := range scope.Items {
+			if item.Type == SymbolTableItemTypeVariable {
+				symbols[item.Symbol()] = true
+			}
+		}
+	}
+	// the locals of the same name in two blocks have symbols of their own
+	if len(symbols) != 3 || !symbols["_V4data"] {
+		t.Errorf("Expected three symbols, data a global, got %v", symbols)
+	}
+}
@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"fmt"
+
+	"app/lexer"
+)
+
+// Qualified returns the name the item has in the code: the globals of a
+// module are named mod.name, so that the ones of different modules do not
+// clash once their files are linked.
+func (item *SymbolTableItem) Qualified() string {
+	if item.Module != "" {
+		return item.Module + "." + item.Variable
+	}
+	return item.Variable
+}
+
+// ModuleDecl handles module_decl → module id ;, the globals of the file
+// declared afterwards belong to the module.
+func ModuleDecl(c *ReduceContext) error {
+	name := c.Nodes[1].Token.Val
+	c.Result.raw = fmt.Sprintf("module %s;", name)
+	if c.Walker.Module != "" {
+		return c.Errorf("module %s is already declared as %s", name, c.Walker.Module)
+	}
+	c.Walker.Module = name
+	return nil
+}
+
+// LocId handles loc → id, naming a global of a module by its qualified name.
+// An identifier declared in no scope is an error where it is used.
+func LocId(w *Walker) error {
+	n, ok := w.Tokens.Peek()
+	if !ok {
+		return fmt.Errorf("loc: expected 1 node on the token stack")
+	}
+	if n.Token == nil || n.Token.Type != lexer.IDENTIFIER {
+		return nil
+	}
+	item, _, err := w.SymbolTable.Lookup(n.Token.Val)
+	if err != nil {
+		return fmt.Errorf("%w, at line %d, pos %d", err, n.Token.Line, n.Token.Pos)
+	}
+	if item.Module != "" {
+		// the token read is kept under the node, as it is in the source
+		w.Tokens.Pop()
+		w.Tokens.Push(&ASTNode{
+			raw:      n.raw,
+			Token:    &lexer.Token{Type: lexer.IDENTIFIER, Val: item.Qualified(), Line: n.Token.Line, Pos: n.Token.Pos},
+			Children: []*ASTNode{n},
+			Type:     n.Type,
+			DataType: n.DataType,
+			Payload:  n.Payload,
+		})
+	}
+	return nil
+}
+
+// LocQualified handles loc → id . id, a global of a module named with the
+// module, which is how the globals of the other modules are reached.
+func LocQualified(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	if len(children) != 3 {
+		return fmt.Errorf("loc: expected 3 nodes on the token stack")
+	}
+	module, name := children[0].Token, children[2].Token
+	qualified := module.Val + "." + name.Val
+	// the node stands for the variable itself, as a plain id does
+	n := &ASTNode{
+		raw:      qualified,
+		Token:    &lexer.Token{Type: lexer.IDENTIFIER, Val: qualified, Line: module.Line, Pos: module.Pos},
+		Children: []*ASTNode{},
+		Type:     "loc",
+	}
+	w.Tokens.Push(n)
+	// an unknown module is left to the linker, the globals of the modules
+	// compiled with the file are checked
+	if _, ok := w.SymbolTable.Modules[module.Val]; !ok {
+		return nil
+	}
+	if _, _, err := w.SymbolTable.Lookup(qualified); err != nil {
+		return fmt.Errorf("%w, at line %d, pos %d", err, module.Line, module.Pos)
+	}
+	return nil
+}
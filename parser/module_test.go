@@ -0,0 +1,73 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+)
+
+func TestModule(t *testing.T) {
+	w := sharedParser().Parse(lexer.NewLexer(strings.NewReader("module geo;\n{\n    int count;\n    count = 1;\n    geo.count = count + 2;\n}\n")), func(string) {})
+	if w.Module != "geo" {
+		t.Errorf("Expected the module geo, got %q", w.Module)
+	}
+	code := strings.Join(w.ThreeAddress, "\n")
+	if !strings.Contains(code, "geo.count = 1") || !strings.Contains(code, "geo.count + 2") || strings.Contains(code, " count") {
+		t.Errorf("Expected count to be named geo.count, got\n%s", code)
+	}
+
+	sources := []Source{
+		{Name: "geo.in", Text: "module geo;\n{\n    int count;\n    count = 2;\n}\n"},
+		{Name: "main.in", Text: "{\n    int count;\n    count = geo.count + num.count;\n}\n"},
+		{Name: "num.in", Text: "module num;\n{\n    int count;\n    count = geo.count * 3;\n}\n"},
+	}
+	var logs []string
+	program, err := sharedParser().Tables().CompileProgram(sources, func(name, message string) {
+		if strings.HasPrefix(message, "Error") {
+			logs = append(logs, name+": "+message)
+		}
+	})
+	if err != nil || len(logs) > 0 {
+		t.Fatalf("CompileProgram: %v %v", err, logs)
+	}
+	linked, err := program.Link()
+	if err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	code = strings.Join(linked.ThreeAddress, "\n")
+	for _, expected := range []string{"geo.count = 2", "geo.count + num.count", "count = ", "geo.count * 3"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected %q in the linked code, got\n%s", expected, code)
+		}
+	}
+	if !slices.ContainsFunc(linked.ThreeAddress, func(line string) bool { return strings.HasPrefix(line, "count = ") }) {
+		t.Errorf("Expected the count of main.in unqualified, got\n%s", code)
+	}
+
+	// the globals of a module are not visible unqualified
+	program, err = sharedParser().Tables().CompileProgram([]Source{sources[0], {Name: "c.in", Text: "{\n    int x;\n    x = count;\n}\n"}}, func(string, string) {})
+	if err != nil {
+		t.Fatalf("CompileProgram: %v", err)
+	}
+	if _, err := program.Link(); err == nil || !strings.Contains(err.Error(), "undefined reference to count in c.in") {
+		t.Errorf("Expected count to be undefined in c.in, got %v", err)
+	}
+	// a module declared twice
+	_, err = sharedParser().Tables().CompileProgram([]Source{sources[0], {Name: "d.in", Text: "module geo;\n{\n    int y;\n}\n"}}, func(string, string) {})
+	if err == nil || !strings.Contains(err.Error(), "module geo is declared by both geo.in and d.in") {
+		t.Errorf("Expected geo to be reported as declared twice, got %v", err)
+	}
+	// an unknown module or member
+	for _, src := range []string{"{\n    int x;\n    x = geo.count;\n}\n", "module geo;\n{\n    int x;\n    x = geo.size;\n}\n"} {
+		program, err = sharedParser().Tables().CompileProgram([]Source{{Name: "e.in", Text: src}}, func(string, string) {})
+		if err != nil {
+			t.Fatalf("CompileProgram: %v", err)
+		}
+		if _, err := program.Link(); err == nil || !strings.Contains(err.Error(), "undefined reference to geo.") {
+			t.Errorf("Expected the qualified name to be undefined in\n%s\ngot %v", src, err)
+		}
+	}
+}
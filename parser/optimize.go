@@ -0,0 +1,418 @@
+package parser
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ThreadJumps simplifies the control flow of three-address code once the
+// constants are folded. It repeats until nothing changes:
+//   - a jump to a goto jumps to the target of the goto instead,
+//   - a conditional jump comparing two integer constants becomes a goto or
+//     is dropped,
+//   - a goto to the instruction right after it is dropped,
+//   - the code after a goto up to the next label is never run and dropped,
+//   - labels no jump refers to are dropped.
+func ThreadJumps(code []string) []string {
+	for {
+		next, changed := threadJumps(code)
+		if !changed {
+			return next
+		}
+		code = next
+	}
+}
+
+// threadJumps runs a single round of ThreadJumps.
+func threadJumps(code []string) ([]string, bool) {
+	changed := false
+	// where each label leads to, the first instruction after it
+	targets := map[string]int{}
+	for i, line := range code {
+		if label, ok := labelOf(line); ok {
+			j := i
+			for j < len(code) && isLabel(code[j]) {
+				j++
+			}
+			targets[label] = j
+		}
+	}
+	final := func(label string) string {
+		seen := map[string]bool{label: true}
+		for {
+			i, ok := targets[label]
+			if !ok || i == len(code) || !strings.HasPrefix(code[i], "goto ") {
+				return label
+			}
+			next := strings.TrimPrefix(code[i], "goto ")
+			if seen[next] {
+				return label
+			}
+			seen[next] = true
+			label = next
+		}
+	}
+
+	result := make([]string, 0, len(code))
+	unreachable := false
+	for i, line := range code {
+		if isLabel(line) {
+			unreachable = false
+		} else if unreachable {
+			changed = true
+			continue
+		}
+		if target, ok := jumpTarget(line); ok {
+			if t := final(target); t != target {
+				line = strings.TrimSuffix(line, target) + t
+				target = t
+				changed = true
+			}
+			if taken, ok := constantCondition(line); ok {
+				changed = true
+				if !taken {
+					continue
+				}
+				line = "goto " + target
+			}
+			if strings.HasPrefix(line, "goto ") {
+				unreachable = true
+				if fallsThrough(code[i+1:], target) {
+					changed = true
+					continue
+				}
+			}
+		}
+		result = append(result, line)
+	}
+
+	used := map[string]bool{}
+	for _, line := range result {
+		if target, ok := jumpTarget(line); ok {
+			used[target] = true
+		}
+	}
+	code = result[:0]
+	for _, line := range result {
+		if label, ok := labelOf(line); ok && !used[label] {
+			changed = true
+			continue
+		}
+		code = append(code, line)
+	}
+	return code, changed
+}
+
+// isLabel checks if the instruction is a label such as L3:
+func isLabel(line string) bool {
+	_, ok := labelOf(line)
+	return ok
+}
+
+// labelOf returns the name of the label the instruction defines.
+func labelOf(line string) (string, bool) {
+	label, ok := strings.CutSuffix(line, ":")
+	if !ok || len(label) < 2 || label[0] != 'L' {
+		return "", false
+	}
+	for _, c := range label[1:] {
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return "", false
+		}
+	}
+	return label, true
+}
+
+// jumpTarget returns the label a goto or a conditional jump jumps to.
+func jumpTarget(line string) (string, bool) {
+	if target, ok := strings.CutPrefix(line, "goto "); ok {
+		return target, true
+	}
+	if !strings.HasPrefix(line, "if ") {
+		return "", false
+	}
+	i := strings.LastIndex(line, " goto ")
+	if i < 0 {
+		return "", false
+	}
+	return line[i+len(" goto "):], true
+}
+
+// fallsThrough checks if the label is reached from the end of an instruction
+// followed by the code, that is one of the labels right after it.
+func fallsThrough(code []string, target string) bool {
+	for _, line := range code {
+		label, ok := labelOf(line)
+		if !ok {
+			return false
+		}
+		if label == target {
+			return true
+		}
+	}
+	return false
+}
+
+// constantCondition evaluates a conditional jump comparing two integer
+// constants, the second return value is false for any other instruction.
+func constantCondition(line string) (taken bool, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 6 || fields[0] != "if" || fields[4] != "goto" {
+		return false, false
+	}
+	a, err1 := strconv.ParseInt(fields[1], 0, 64)
+	b, err2 := strconv.ParseInt(fields[3], 0, 64)
+	if err1 != nil || err2 != nil {
+		return false, false
+	}
+	switch fields[2] {
+	case "<":
+		return a < b, true
+	case "<=":
+		return a <= b, true
+	case ">":
+		return a > b, true
+	case ">=":
+		return a >= b, true
+	case "==":
+		return a == b, true
+	case "!=":
+		return a != b, true
+	}
+	return false, false
+}
+
+// LayoutBlocks reorders the basic blocks of three-address code so that the
+// target of a goto follows it where possible, which turns the jump into a
+// fallthrough and drops it. Blocks falling through into each other are kept
+// together in chains, the first chain stays first, and a chain is placed
+// right after the goto to its head, so a loop body jumped to from its head
+// ends up contiguous with it. Other chains keep their original order, but the
+// one running off the end of the code stays last, as nothing follows it.
+func LayoutBlocks(code []string) []string {
+	// chains of instructions, each ending with a goto or the end of the code
+	var chains [][]string
+	start := 0
+	for i, line := range code {
+		if strings.HasPrefix(line, "goto ") {
+			chains = append(chains, code[start:i+1])
+			start = i + 1
+		}
+	}
+	var exit []string
+	if start < len(code) {
+		exit = code[start:]
+	}
+	heads := map[string]int{}
+	for i, chain := range chains {
+		for _, line := range chain {
+			label, ok := labelOf(line)
+			if !ok {
+				break
+			}
+			heads[label] = i
+		}
+	}
+
+	result := make([]string, 0, len(code))
+	placed := make([]bool, len(chains))
+	for i := range chains {
+		for j := i; j != -1 && !placed[j]; {
+			placed[j] = true
+			chain := chains[j]
+			j = -1
+			last := chain[len(chain)-1]
+			if target, ok := strings.CutPrefix(last, "goto "); ok {
+				if k, ok := heads[target]; ok && !placed[k] {
+					chain, j = chain[:len(chain)-1], k
+				}
+			}
+			result = append(result, chain...)
+		}
+	}
+	if n := len(result); n > 0 && len(exit) > 0 {
+		if target, ok := strings.CutPrefix(result[n-1], "goto "); ok && fallsThrough(exit, target) {
+			result = result[:n-1]
+		}
+	}
+	return append(result, exit...)
+}
+
+// PropagateConstants replaces the reads of a variable by the integer constant
+// it holds, when every definition reaching the read assigns that constant.
+// It repeats until nothing changes, so constants flow through copies, and
+// conditions they make constant are left for ThreadJumps to fold.
+func PropagateConstants(code []string) []string {
+	for changed := true; changed; {
+		changed = false
+		chains := DefUseChains(code)
+		next := make([]string, len(code))
+		for i, line := range code {
+			values := map[string]string{}
+			for v, defs := range chains.Defs[i] {
+				if value, ok := constantOf(code, v, defs); ok {
+					values[v] = value
+				}
+			}
+			next[i] = replaceReads(line, values)
+			if next[i] != line {
+				changed = true
+			}
+		}
+		code = next
+	}
+	return code
+}
+
+// constantOf returns the integer constant all the definitions assign to the variable.
+func constantOf(code []string, variable string, defs []int) (string, bool) {
+	value := ""
+	for _, d := range defs {
+		if d < 0 {
+			return "", false
+		}
+		v, ok := strings.CutPrefix(code[d], variable+" = ")
+		if !ok {
+			return "", false
+		}
+		if _, err := strconv.ParseInt(v, 0, 64); err != nil || (value != "" && v != value) {
+			return "", false
+		}
+		value = v
+	}
+	return value, value != ""
+}
+
+// replaceReads replaces the variables the instruction reads by their values.
+// Addresses taken with & and the written variable are left alone.
+func replaceReads(line string, values map[string]string) string {
+	if len(values) == 0 {
+		return line
+	}
+	prefix := ""
+	if _, ok := definitionOf(line); ok {
+		dist, value, _ := strings.Cut(line, " = ")
+		prefix, line = dist+" = ", value
+	}
+	if rest, ok := strings.CutPrefix(line, "call "); ok {
+		callee, args, _ := strings.Cut(rest, ",")
+		prefix, line = prefix+"call "+callee+",", args
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range operandPattern.FindAllStringIndex(line, -1) {
+		value, ok := values[line[loc[0]:loc[1]]]
+		if !ok || (loc[0] > 0 && line[loc[0]-1] == '&') || strings.HasSuffix(line[:loc[0]], "goto ") {
+			continue
+		}
+		b.WriteString(line[last:loc[0]])
+		b.WriteString(value)
+		last = loc[1]
+	}
+	b.WriteString(line[last:])
+	return prefix + b.String()
+}
+
+// EliminateCommonSubexpressions replaces the computation of an expression
+// already available in a variable by a copy of that variable, within basic
+// blocks by LocalValueNumbering and then on every path across them. A
+// temporary written once as a copy of another one written once is then
+// replaced by it, so that the expressions built on top of it match as well.
+// It repeats until nothing changes.
+func EliminateCommonSubexpressions(code []string) []string {
+	return eliminateCommon(code, 0)
+}
+
+func eliminateCommon(code []string, width IntWidth) []string {
+	code = localValueNumbering(code, width)
+	for {
+		next, changed := eliminateCommonSubexpressions(code)
+		if !changed {
+			return next
+		}
+		code = next
+	}
+}
+
+// eliminateCommonSubexpressions runs a single round of EliminateCommonSubexpressions.
+func eliminateCommonSubexpressions(code []string) ([]string, bool) {
+	changed := false
+	available := AvailableExpressions(code)
+	result := slices.Clone(code)
+	for i, line := range code {
+		e, ok := expressionOf(line)
+		if !ok {
+			continue
+		}
+		var holders []string
+		for a := range available[i] {
+			if a.Value == e.Value && a.Variable != e.Variable {
+				holders = append(holders, a.Variable)
+			}
+		}
+		if len(holders) > 0 {
+			result[i] = e.Variable + " = " + slices.Min(holders)
+			changed = true
+		}
+	}
+
+	writes := map[string]int{}
+	for _, line := range result {
+		if v, ok := definitionOf(line); ok {
+			writes[v]++
+		}
+	}
+	copies := map[string]string{}
+	for _, line := range result {
+		dist, value, ok := strings.Cut(line, " = ")
+		if ok && dist != value && writes[dist] == 1 && writes[value] == 1 && isTemp(dist) && isTemp(value) {
+			copies[dist] = value
+		}
+	}
+	if len(copies) == 0 {
+		return result, changed
+	}
+	for t, value := range copies {
+		for copies[value] != "" {
+			value = copies[value]
+		}
+		copies[t] = value
+	}
+	code = result[:0]
+	for _, line := range result {
+		if v, ok := definitionOf(line); ok && copies[v] != "" {
+			continue
+		}
+		code = append(code, replaceReads(line, copies))
+	}
+	return code, true
+}
+
+// isTemp checks if the operand is a temporary.
+func isTemp(operand string) bool {
+	t := temps(operand)
+	return len(t) == 1 && t[0] == operand
+}
+
+// Peephole simplifies each instruction on its own with the algebraic
+// identities of Simplify, and drops the copies of a variable into itself.
+func Peephole(code []string) []string {
+	return peephole(code, 0)
+}
+
+func peephole(code []string, width IntWidth) []string {
+	result := make([]string, 0, len(code))
+	for _, line := range code {
+		if dist, ok := definitionOf(line); ok {
+			_, value, _ := strings.Cut(line, " = ")
+			if value = simplify(value, width); value == dist {
+				continue
+			}
+			line = dist + " = " + value
+		}
+		result = append(result, line)
+	}
+	return result
+}
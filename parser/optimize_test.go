@@ -0,0 +1,111 @@
+package parser_test
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestThreadJumps(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     []string
+		expected []string
+	}{
+		{
+			name:     "Chain",
+			code:     []string{"if a < b goto L0", "x = 1", "L0:", "goto L1", "x = 2", "L1:", "goto L2", "x = 3", "L2:", "y = x"},
+			expected: []string{"if a < b goto L2", "x = 1", "L2:", "y = x"},
+		},
+		{
+			name:     "Named",
+			code:     []string{"if a < b goto L_while_7", "x = 1", "L_while_7:", "goto L_while_7_end", "L_while_7_end:", "y = x"},
+			expected: []string{"if a < b goto L_while_7_end", "x = 1", "L_while_7_end:", "y = x"},
+		},
+		{
+			name:     "ConstantTaken",
+			code:     []string{"if 1 < 2 goto L0", "x = 1", "L0:", "y = x"},
+			expected: []string{"y = x"},
+		},
+		{
+			name:     "ConstantNotTaken",
+			code:     []string{"if 3 <= 2 goto L0", "x = 1", "L0:", "y = x"},
+			expected: []string{"x = 1", "y = x"},
+		},
+		{
+			name:     "Loop",
+			code:     []string{"L0:", "goto L1", "L1:", "goto L0"},
+			expected: []string{"L0:", "goto L0", "L1:", "goto L1"},
+		},
+		{
+			name:     "Variable",
+			code:     []string{"$(0x10000001) = a", "if $(0x10000001) >= 0 goto L0", "$(0x10000001) = minus $(0x10000001)", "L0:"},
+			expected: []string{"$(0x10000001) = a", "if $(0x10000001) >= 0 goto L0", "$(0x10000001) = minus $(0x10000001)", "L0:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ThreadJumps(tt.code); !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLayoutBlocks(t *testing.T) {
+	code := []string{"x = 0", "goto L1", "L0:", "y = 1", "goto L2", "L1:", "x = 1", "goto L0", "L2:", "y = x"}
+	expected := []string{"x = 0", "L1:", "x = 1", "L0:", "y = 1", "L2:", "y = x"}
+	if got := LayoutBlocks(code); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	// a loop falling through into its exit has no goto to drop
+	code = []string{"L0:", "x = x + 1", "if x < 10 goto L0", "y = x"}
+	if got := LayoutBlocks(code); !slices.Equal(got, code) {
+		t.Errorf("Expected %v, got %v", code, got)
+	}
+	// the code running off the end stays last, even if a goto jumps to it
+	code = []string{"if x == 1 goto L1", "goto L2", "L1:", "y = 1", "goto L3", "L2:", "y = 3", "L3:", "z = y"}
+	expected = slices.Clone(code)
+	if got := LayoutBlocks(code); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestEliminateCommonSubexpressions(t *testing.T) {
+	src, err := os.ReadFile("../tests/parser/6.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := parseSource(t, string(src)).ThreeAddress
+	count := func(code []string, value string) int {
+		n := 0
+		for _, line := range code {
+			if strings.HasSuffix(line, " = "+value) {
+				n++
+			}
+		}
+		return n
+	}
+	got := EliminateCommonSubexpressions(code)
+	// a[1] % 8 is computed again only after a[2] and a[1] are written, in
+	// each branch of the if and where they join
+	if n := count(got, "a [ 1 ] mod 8"); count(code, "a [ 1 ] mod 8") != 6 || n != 4 {
+		t.Errorf("Expected a[1] %% 8 to be computed 4 times, got %d in %v", n, got)
+	}
+	// a[2] % 8 is reused across the blocks of max
+	if n := count(got, "a [ 2 ] mod 8"); n != 1 {
+		t.Errorf("Expected a[2] %% 8 to be computed once, got %d in %v", n, got)
+	}
+	for _, line := range got {
+		// the conditions of if and while are computed but not jumped on
+		if strings.HasPrefix(line, "L_") || strings.Contains(line, " eq ") {
+			continue
+		}
+		if v, _, _ := strings.Cut(line, " = "); strings.HasPrefix(v, "$(") && !slices.ContainsFunc(got, func(l string) bool { return l != line && strings.Contains(l, v) }) {
+			t.Errorf("Expected no unread temporary, got %s in %v", line, got)
+		}
+	}
+}
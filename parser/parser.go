@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -13,62 +15,210 @@ import (
 // Parse is the main function that parses the input tokens using the LR(1) parser algorithm.
 // It takes a lexer.Lexer instance and a logger function as arguments.
 // The logger function is used to log messages during the parsing process.
-func (p *Parser) Parse(l *lexer.Lexer, logger func(string)) {
-	walker := p.NewWalker()
-	walker.SymbolTable.EnterScope()
+// It returns the walker used for the parse, which holds the symbol table and
+// the emitted three-address code.
+func (p *Parser) Parse(l *lexer.Lexer, logger func(string)) *Walker {
+	return p.Tables().Parse(l, logger)
+}
+
+// Parse parses the input in a new session, see Parser.Parse.
+func (t *ParserTables) Parse(l *lexer.Lexer, logger func(string)) *Session {
+	walker, _ := t.parse(context.Background(), l, logger, nil)
+	return walker
+}
+
+// ParseContext is Parse that stops once the context is done. Code is generated
+// while parsing, so this bounds the whole compilation of the input. The error
+// is the context's error, the other errors are reported to the logger as usual.
+func (p *Parser) ParseContext(ctx context.Context, l *lexer.Lexer, logger func(string)) (*Walker, error) {
+	if err := p.EnsureTableContext(ctx); err != nil {
+		logger(fmt.Sprintf("Error: %v", err))
+		return nil, err
+	}
+	return p.Tables().parse(ctx, l, logger, nil)
+}
+
+// parse drives a new session over the tokens, recording the steps into the trace if given.
+func (t *ParserTables) parse(ctx context.Context, l *lexer.Lexer, logger func(string), trace *Trace) (*Session, error) {
+	return t.run(ctx, t.NewSession(), l, logger, trace)
+}
+
+// run drives the walker over the tokens, see parse.
+func (t *ParserTables) run(ctx context.Context, walker *Walker, l *lexer.Lexer, logger func(string), trace *Trace) (*Session, error) {
+	// the outermost scope is the prelude holding the intrinsic functions and
+	// those of Options.Declare, unless the symbol table is shared with the
+	// other files of a program
+	if walker.SymbolTable.CurrentScope == nil {
+		walker.SymbolTable.EnterScope()
+		err := walker.DeclareBuiltins()
+		if err == nil && walker.declare != nil {
+			err = walker.declare(walker.SymbolTable)
+		}
+		if err != nil {
+			walker.stopped = InternalError
+			logger(fmt.Sprintf("Error: %v", err))
+			return walker, nil
+		}
+	}
+	previous := Symbol("")
+	inInitializer := false
+	steps := 0
+	shifted := int64(0)  // line of the last token shifted, the end of the constructs reduced
+	var last lexer.Token // the token shifted last, which a fix-it goes after
+	read := 0            // the tokens shifted, the index of the next one
+	lexed := 0           // the tokens lexed, see Budget.MaxTokens
 	for {
-		token, err := l.NextToken()
+		if err := ctx.Err(); err != nil {
+			walker.stopped = InternalError
+			logger(fmt.Sprintf("Error: %v", err))
+			return walker, err
+		}
+		token, err := l.Next()
+		lexed++
 		if err != nil && !errors.Is(err, io.EOF) {
+			walker.stopped = LexicalError
+			if errors.Is(err, ErrBudgetExceeded) {
+				walker.stopped = InternalError
+			}
 			logger(fmt.Sprintf("Error: %v", err))
-			return
+			return walker, nil
 		}
 
 		if errors.Is(err, io.EOF) {
 			token.Type = lexer.EOF
 		}
-		symbol := p.Reflect(&token)
-		if token.SpecificType() == lexer.DelimiterLeftBrace {
+		symbol := Reflect(&token)
+		if walker.tokens != nil && symbol != TERMINATE {
+			*walker.tokens = append(*walker.tokens, token)
+		}
+		if trace != nil {
+			if symbol == TERMINATE {
+				trace.Input = append(trace.Input, TERMINATE)
+			} else {
+				trace.Input = append(trace.Input, token.Val)
+			}
+			trace.Terminals = append(trace.Terminals, symbol)
+		}
+		// braces right after = delimit an initializer list rather than a block
+		if token.SpecificType() == lexer.DelimiterLeftBrace && previous == "=" {
+			inInitializer = true
+		} else if token.SpecificType() == lexer.DelimiterLeftBrace {
 			walker.SymbolTable.EnterScope()
 		}
 
 		for {
 			logger(fmt.Sprintf("State: %v\nSymbols: %v\nSymbol: %s\n", walker.States, walker.Symbols, symbol))
+			var step Step
+			if trace != nil {
+				step = trace.snapshot(walker)
+			}
 			action, err := walker.Next(symbol)
+			walker.markLines(shifted)
+			if trace != nil {
+				step.Action, step.Type, step.Number = walker.describe(action), action.Type, action.Number
+				if action.Type == REDUCE {
+					step.Goto, _ = walker.States.Peek()
+				}
+				if err != nil {
+					step.Action, step.Type = fmt.Sprintf("error: %v", err), ERROR
+				}
+				trace.Steps = append(trace.Steps, step)
+			}
 			if err != nil {
+				var entry *ErrorEntry
+				if errors.As(err, &entry) {
+					entry.Token = &token
+					if terminal, ok := walker.fix(entry); ok {
+						entry.Fix = &FixIt{Text: string(terminal), Line: last.Line, Pos: last.Pos + int64(len(last.Val)), Token: read}
+						walker.fixIt = entry.Fix
+					}
+				}
+				walker.stopped = SyntaxError
 				logger(fmt.Sprintf("Error: %v", err))
-				return
+				return walker, nil
+			}
+			steps++
+			if err := cmp.Or(t.Limits.Check(walker, steps), walker.overBudget(lexed)); err != nil {
+				// too many errors of the rules stop the parse as the limits and the budget do
+				walker.stopped = InternalError
+				if errors.Is(err, ErrTooManyErrors) {
+					walker.stopped = SemanticError
+				}
+				logger(fmt.Sprintf("Error: %v, at line %d, pos %d", err, token.Line, token.Pos))
+				return walker, nil
 			}
 			logger(fmt.Sprintf("Token: (%s, %s), Action: %v\n\n", token.Type.ToString(), token.Val, action))
 			if action.Type != REDUCE {
 				break
 			}
+			walker.reduced()
 		}
 
 		if token.SpecificType() == lexer.DelimiterRightBrace {
-			walker.SymbolTable.ExitScope()
+			if inInitializer {
+				inInitializer = false
+			} else if err := walker.SymbolTable.ExitScope(); err != nil {
+				// the scope could not be archived, see SymbolTable.Prune
+				walker.stopped = InternalError
+				logger(fmt.Sprintf("Error: %v, at line %d, pos %d", err, token.Line, token.Pos))
+				return walker, nil
+			}
 		}
 
 		if symbol == TERMINATE {
+			var origins []int64
+			walker.ThreeAddress, origins = RunPasses(walker.ThreeAddress, Origins(len(walker.ThreeAddress)), ThreadJumps, LayoutBlocks, ThreadJumps)
+			walker.Lines, walker.Spans = remap(walker.Lines, origins, -1), remap(walker.Spans, origins, Span{})
+			for _, warning := range walker.warnings {
+				logger(fmt.Sprintf("Warning: %s\n", warning))
+			}
+			for _, item := range walker.Uninitialized() {
+				logger(fmt.Sprintf("Warning: %s may be used before initialization, declared at line %d, pos %d\n", item.Variable, item.Line, item.Pos))
+			}
 			logger("Parsing completed successfully.")
 			break
 		}
 
-		walker.Tokens.Push(p.Token2ASTNode(&token))
+		node := Token2ASTNode(&token)
+		node.code = len(walker.ThreeAddress)
+		walker.reference(node)
+		walker.Tokens.Push(node)
+		previous = symbol
+		shifted = token.Line
+		last = token
+		read++
 	}
+	return walker, nil
 }
 
 // Reflect converts a lexer.Token to a Symbol.
 // It maps specific token types to corresponding symbols and returns the symbol representation.
 func (p *Parser) Reflect(token *lexer.Token) Symbol {
+	return Reflect(token)
+}
+
+// Reflect is Parser.Reflect, it depends on nothing but the token.
+func Reflect(token *lexer.Token) Symbol {
 	switch token.Type {
-	case lexer.INTEGER:
+	case lexer.INTEGER, lexer.CHAR:
 		return "num"
 	case lexer.FLOAT:
 		return "real"
+	case lexer.STRING:
+		return "str"
 	case lexer.IDENTIFIER:
+		if token.Val == "new" {
+			return "new"
+		}
 		return "id"
 	case lexer.TYPE:
 		return "basic"
+	case lexer.RESERVED:
+		// function values are declared with func as their type
+		if token.Val == "func" {
+			return "basic"
+		}
+		return Symbol(token.Val)
 	case lexer.EOF:
 		return TERMINATE
 	default:
@@ -83,24 +233,34 @@ func (p *Parser) EnsureFirstSet() {
 }
 
 func (p *Parser) EnsureSymbols() {
-	if len(p.Symbols) == 0 {
+	if p.Symbols.Size() == 0 {
 		p.BuildSymbols()
 	}
 }
 
 func (p *Parser) EnsureStates() {
+	_ = p.EnsureStatesContext(context.Background())
+}
+
+func (p *Parser) EnsureStatesContext(ctx context.Context) error {
 	if len(p.States) == 0 {
-		p.BuildStates()
+		return p.BuildStatesContext(ctx)
 	}
+	return nil
 }
 
 func (p *Parser) EnsureTable() {
+	_ = p.EnsureTableContext(context.Background())
+}
+
+func (p *Parser) EnsureTableContext(ctx context.Context) error {
 	p._mu.Lock()
 	defer p._mu.Unlock()
 	if p.Table == nil {
 		p.OptimizedHeadsCheck()
-		p.BuildTable()
+		return p.BuildTableContext(ctx)
 	}
+	return nil
 }
 
 func (p *Parser) OptimizedHeadsCheck() {
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"app/lexer"
+)
+
+// Deref is the payload of the location a pointer points to, *p or p[i]: the
+// value is loaded from the bytes at Offset past the pointer, and stored into
+// them, by calls of the runtime, as the code has no instruction reaching the
+// memory of the heap.
+type Deref struct {
+	Pointer *ASTNode
+	Offset  int64
+	Type    lexer.TokenSpecificType // type pointed to
+}
+
+// Dereference handles loc → * factor, the value the pointer points to.
+func Dereference(c *ReduceContext) error {
+	pointer := c.Nodes[1]
+	pointee, ok := c.Walker.pointee(pointer)
+	if t := c.Walker.TypeOf(pointer); !ok && t != lexer.Unknown {
+		return c.Errorf("cannot dereference %s (%s)", pointer.raw, t.ToString())
+	} else if !ok {
+		return nil
+	}
+	c.Result.DataType = pointee
+	c.Result.Payload = &Deref{Pointer: pointer, Type: pointee}
+	return nil
+}
+
+// LocIndex handles loc → loc [ num ]. An element of an array is left to the
+// code reading and writing the array; indexing a pointer is the element of
+// its type that many after the one it points to.
+func LocIndex(c *ReduceContext) error {
+	pointer, index := c.Nodes[0], c.Nodes[2]
+	if _, ok := pointer.Payload.(*Deref); ok {
+		return c.Errorf("cannot index %s (%s)", pointer.raw, pointer.DataType.ToString())
+	}
+	pointee, ok := c.Walker.pointee(pointer)
+	if !ok {
+		return nil
+	}
+	i, _ := IntLiteral(index)
+	c.Result.DataType = pointee
+	c.Result.Payload = &Deref{Pointer: pointer, Offset: i * int64(c.Walker.basic(pointee).Size()), Type: pointee}
+	return nil
+}
+
+// FactorLoc handles factor → loc, loading the value a pointer points to, or
+// taking the address of a function named as a value.
+func FactorLoc(w *Walker) error {
+	n, ok := w.Tokens.Peek()
+	if !ok {
+		return fmt.Errorf("factor: expected 1 node on the token stack")
+	}
+	deref, ok := n.Payload.(*Deref)
+	if !ok {
+		return FunctionValue(w)
+	}
+	w.Tokens.Pop()
+	w.Tokens.Push(w.load(n, deref))
+	return nil
+}
+
+// load emits the load of the value the pointer points to into a temporary.
+func (w *Walker) load(loc *ASTNode, deref *Deref) *ASTNode {
+	result := w.NewTemp("factor", deref.Type, loc.raw, []*ASTNode{loc})
+	w.EmitCall(result.String(), w.access("load", deref.Type), deref.Pointer, deref.Offset)
+	return result
+}
+
+// store emits the store of the value where the pointer points.
+func (w *Walker) store(deref *Deref, value *ASTNode) {
+	w.EmitCall("", w.access("store", deref.Type), deref.Pointer, deref.Offset, value)
+}
+
+// access returns the runtime function loading or storing a value of the
+// type, by the bytes of it, such as load4, a load of an unsigned integer
+// narrower than a word being loadu1 or loadu2, as the loads of the machine.
+func (w *Walker) access(op string, t lexer.TokenSpecificType) string {
+	size := w.basic(t).Size()
+	if name := t.ToString(); op == "load" && size < 4 && IsIntegral(t) && (strings.HasPrefix(name, "uint") || name == "byte") {
+		op += "u"
+	}
+	return fmt.Sprintf("%s%d", op, size)
+}
+
+// pointee returns the type the value points to, for a variable or an element
+// of an array of pointers, and for the value of new.
+func (w *Walker) pointee(n *ASTNode) (lexer.TokenSpecificType, bool) {
+	if t, ok := n.Payload.(lexer.TokenSpecificType); ok && n.DataType == lexer.TypePointer {
+		return t, true
+	}
+	base := n
+	if len(n.Children) == 4 && n.Children[1].Token != nil && n.Children[1].Token.Val == "[" {
+		base = n.Children[0]
+	}
+	if base.Token == nil || base.Token.Type != lexer.IDENTIFIER || w.SymbolTable == nil {
+		return lexer.Unknown, false
+	}
+	item, _, err := w.SymbolTable.Lookup(base.Token.Val)
+	if err != nil || item.Pointee == "" || (base == n) != (item.Type != SymbolTableItemTypeArray) {
+		return lexer.Unknown, false
+	}
+	return lexer.ParseTypeName(item.Pointee), true
+}
@@ -0,0 +1,85 @@
+package parser
+
+// Associativity resolves a shift/reduce conflict between a production and a
+// terminal of the same precedence, as yacc's %left, %right and %nonassoc do.
+type Associativity int
+
+const (
+	Left     Associativity = iota // reduce, a - b - c is (a - b) - c
+	Right                         // shift, a = b = c is a = (b = c)
+	NonAssoc                      // neither, a < b < c is a syntax error
+)
+
+func (a Associativity) String() string {
+	switch a {
+	case Left:
+		return "left"
+	case Right:
+		return "right"
+	}
+	return "nonassoc"
+}
+
+// Precedence is the precedence declared for a terminal, the higher the level
+// the tighter it binds.
+type Precedence struct {
+	Level int
+	Assoc Associativity
+}
+
+// SetPrecedence declares the precedence and the associativity of the
+// terminal, eg. g.SetPrecedence("*", 2, Left) after g.SetPrecedence("+", 1,
+// Left), and returns the grammar.
+func (g *Grammar) SetPrecedence(terminal Terminal, level int, assoc Associativity) *Grammar {
+	if g.Precedence == nil {
+		g.Precedence = map[Terminal]Precedence{}
+	}
+	g.Precedence[terminal] = Precedence{Level: level, Assoc: assoc}
+	return g
+}
+
+// ProductionPrecedence returns the precedence of the production, the one of
+// the last terminal of its body with a precedence declared, as in yacc.
+func (g *Grammar) ProductionPrecedence(production Production) (Precedence, bool) {
+	for i := len(production.Body) - 1; i >= 0; i-- {
+		if p := g.Symbol(production.Body[i]).Precedence; p != nil {
+			return *p, true
+		}
+	}
+	return Precedence{}, false
+}
+
+// resolve resolves a shift/reduce conflict on the terminal with the
+// precedences declared: the reduction wins if its production binds tighter
+// than the terminal, or as tight and is left associative, the shift wins if
+// it binds looser, or as tight and is right associative, and neither does if
+// it is nonassociative, leaving the cell an error. ok is false if the two
+// actions are no shift and reduction or either has no precedence.
+func (g *Grammar) resolve(terminal Terminal, a, b Action) (winner Action, ok bool) {
+	shift, reduce := a, b
+	if shift.Type == REDUCE {
+		shift, reduce = b, a
+	}
+	if shift.Type != SHIFT || reduce.Type != REDUCE || reduce.Number >= len(g.Productions) {
+		return Action{}, false
+	}
+	token, ok := g.Precedence[terminal]
+	if !ok {
+		return Action{}, false
+	}
+	production, ok := g.ProductionPrecedence(g.Productions[reduce.Number])
+	if !ok {
+		return Action{}, false
+	}
+	switch {
+	case production.Level > token.Level:
+		return reduce, true
+	case production.Level < token.Level:
+		return shift, true
+	case token.Assoc == Left:
+		return reduce, true
+	case token.Assoc == Right:
+		return shift, true
+	}
+	return Action{Type: ERROR}, true
+}
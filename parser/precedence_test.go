@@ -0,0 +1,81 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+// postfix parses the terminals with the walker and returns them in the order
+// the productions of the operators reduce them, "" on a syntax error.
+func postfix(p *Parser, input string) string {
+	w := p.Tables().NewSession()
+	out := []string{}
+	for _, symbol := range append(strings.Fields(input), string(TERMINATE)) {
+		for {
+			action, err := w.Next(Symbol(symbol))
+			if err != nil {
+				return ""
+			}
+			if action.Type == SHIFT && symbol == "id" {
+				out = append(out, "id")
+			}
+			if action.Type != REDUCE {
+				break
+			}
+			if body := p.Grammar.Productions[action.Number].Body; len(body) == 3 {
+				out = append(out, string(body[1]))
+			}
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func TestGrammar_SetPrecedence(t *testing.T) {
+	grammar := &Grammar{
+		AugmentedProduction: Production{Head: "S", Body: []Symbol{"E"}},
+		Productions: []Production{
+			{Head: "E", Body: []Symbol{"E", "+", "E"}},
+			{Head: "E", Body: []Symbol{"E", "*", "E"}},
+			{Head: "E", Body: []Symbol{"E", "=", "E"}},
+			{Head: "E", Body: []Symbol{"E", "<", "E"}},
+			{Head: "E", Body: []Symbol{"id"}},
+		},
+		Terminals: Set[Terminal]{}.AddAll("+", "*", "=", "<", "id", EPSILON, TERMINATE),
+	}
+	ambiguous := &Parser{Grammar: grammar, Symbols: NewOrderedSet[Symbol](), FirstSet: FirstSet{}, States: States{}}
+	ambiguous.EnsureTable()
+	if ambiguous.Table.ShiftReduceConflicts == 0 {
+		t.Fatalf("Expected the grammar to be ambiguous without precedences")
+	}
+
+	g := grammar.Copy()
+	g.SetPrecedence("=", 1, Right).SetPrecedence("<", 2, NonAssoc).SetPrecedence("+", 3, Left).SetPrecedence("*", 4, Left)
+	p := &Parser{Grammar: &g, Symbols: NewOrderedSet[Symbol](), FirstSet: FirstSet{}, States: States{}}
+	p.EnsureTable()
+	if p.Table.ShiftReduceConflicts != 0 || p.Table.ReduceReduceConflicts != 0 || len(p.Table.Conflicts) != 0 {
+		t.Errorf("Expected the precedences to resolve every conflict, got %+v", p.Table.Conflicts)
+	}
+	for input, expected := range map[string]string{
+		"id + id * id":   "id id id * +",
+		"id * id + id":   "id id * id +",
+		"id + id + id":   "id id + id +",
+		"id = id = id":   "id id id = =",
+		"id < id + id":   "id id id + <",
+		"id = id < id":   "id id id < =",
+		"id < id < id":   "",
+		"id + id < id":   "id id + id <",
+		"id * id = id":   "id id * id =",
+		"id = id * id":   "id id id * =",
+		"id + id = id <": "",
+	} {
+		if got := postfix(p, input); got != expected {
+			t.Errorf("%s: expected %q, got %q", input, expected, got)
+		}
+	}
+	if grammar.Precedence != nil {
+		t.Errorf("Expected the copy to have precedences of its own")
+	}
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"app/diagnostics"
+	"app/lexer"
+	"app/parser/ast"
+	"app/preprocess"
+)
+
+var positionOf = regexp.MustCompile(`at line (\d+), pos (\d+)`)
+
+// unexpand takes every position of the result, in the text the preprocessor
+// expanded, back to the file, line and pos it comes from: the diagnostics
+// from the index of the collector on, the tokens, the trees, the spans and
+// the lines of the code and the declarations of the symbol table, but the
+// ones at pos 0, which the parser makes up. The fix-its are left as they are,
+// they are edits of the text parsed.
+func (r *Result) unexpand(m *preprocess.LineMap, from int) {
+	file := func(p preprocess.Position) string {
+		if p.File == m.Main() {
+			return ""
+		}
+		return p.File
+	}
+	move := func(d *diagnostics.Diagnostic) {
+		d.Message = positionOf.ReplaceAllStringFunc(d.Message, func(at string) string {
+			matches := positionOf.FindStringSubmatch(at)
+			line, _ := strconv.ParseInt(matches[1], 10, 64)
+			pos, _ := strconv.ParseInt(matches[2], 10, 64)
+			p := m.Lookup(line, pos)
+			if f := file(p); f != "" {
+				return fmt.Sprintf("at line %d, pos %d of %s", p.Line, p.Pos, f)
+			}
+			return fmt.Sprintf("at line %d, pos %d", p.Line, p.Pos)
+		})
+		if d.Line < 0 {
+			return
+		}
+		p := m.Lookup(d.Line, d.Pos)
+		d.File, d.Line, d.Pos, d.Snippet = file(p), p.Line, p.Pos, m.Snippet(p.File, p.Line)
+	}
+	for i := range r.Diagnostics {
+		move(&r.Diagnostics[i].Diagnostic)
+	}
+	r.Collector.Remap(from, move)
+
+	token := func(t *lexer.Token) {
+		if t.Pos == 0 {
+			return
+		}
+		p := m.Lookup(t.Line, t.Pos)
+		t.File, t.Line, t.Pos = file(p), p.Line, p.Pos
+	}
+	for i := range r.Tokens {
+		token(&r.Tokens[i])
+	}
+	if r.AST != nil {
+		seen := map[*lexer.Token]bool{}
+		var walk func(n *ASTNode)
+		walk = func(n *ASTNode) {
+			if n.Token != nil && !seen[n.Token] {
+				seen[n.Token] = true
+				token(n.Token)
+			}
+			for _, child := range n.Children {
+				walk(child)
+			}
+		}
+		walk(r.AST)
+	}
+	if r.Program != nil {
+		ast.Inspect(r.Program, func(n ast.Node) bool {
+			if node, ok := n.(interface{ SetStart(ast.Position) }); ok && n.Start().Pos != 0 {
+				p := m.Lookup(n.Start().Line, n.Start().Pos)
+				node.SetStart(ast.Position{Line: p.Line, Pos: p.Pos})
+			}
+			return true
+		})
+	}
+
+	w := r.Walker
+	for i, span := range w.Spans {
+		if span == (Span{}) {
+			continue
+		}
+		start, end := m.Lookup(span.Line, span.Pos), m.End(span.EndLine, span.EndPos)
+		if end.File != start.File {
+			// the span goes on into an include, it ends where the first file does
+			end = m.End(span.Line, span.Pos+1)
+		}
+		w.Spans[i] = Span{File: file(start), Line: start.Line, Pos: start.Pos, EndLine: end.Line, EndPos: end.Pos}
+	}
+	for i, line := range w.Lines {
+		if line >= 0 {
+			w.Lines[i] = m.Lookup(line, 1).Line
+		}
+	}
+	items := map[*SymbolTableItem]bool{}
+	for _, scope := range w.SymbolTable.LegacyScopes {
+		for _, item := range scope.Items {
+			if item.Pos == 0 || items[item] {
+				continue
+			}
+			items[item] = true
+			p := m.Lookup(item.Line, item.Pos)
+			item.Line, item.Pos = p.Line, p.Pos
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package parser_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	. "app/parser"
+	"app/preprocess"
+)
+
+func TestCompile_LineMap(t *testing.T) {
+	text, m, err := preprocess.Expand("main.txt", func(path string) (string, error) {
+		switch path {
+		case "main.txt":
+			return "#define T int\n{\n    T a;\n#include \"lib.txt\"\n    a = 1;\n}\n", nil
+		case "lib.txt":
+			return "    string s;\n    float a;\n", nil
+		}
+		return "", os.ErrNotExist
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Compile(Options{Source: strings.NewReader(text), Tables: sharedParser().Tables(), LineMap: m})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if len(result.Diagnostics) != 1 {
+		t.Fatalf("Expected a declared twice, got %v", result.Diagnostics)
+	}
+	d := result.Diagnostics[0]
+	if d.File != "lib.txt" || d.Line != 1 || d.Snippet != "    float a;" || !strings.HasSuffix(d.Message, ", at line 1, pos 11 of lib.txt") {
+		t.Errorf("Expected a declared twice at line 1 of lib.txt, got %+v", d)
+	}
+	if all := result.Collector.All(); len(all) != 1 || all[0] != d.Diagnostic {
+		t.Errorf("Expected the collector to be remapped too, got %+v", all)
+	}
+
+	var a, s bool
+	for _, token := range result.Tokens {
+		switch token.Val {
+		case "int":
+			if token.File != "" || token.Line != 2 || token.Pos != 5 {
+				t.Errorf("Expected int at the T of line 2, got %+v", token)
+			}
+		case "a":
+			a = a || token.File == "" && token.Line == 2 && token.Pos == 7
+		case "s":
+			s = token.File == "lib.txt" && token.Line == 0 && token.Pos == 13
+		}
+	}
+	if !a || !s {
+		t.Errorf("Expected a at line 2 and s in lib.txt, got %v", result.Tokens)
+	}
+	declared := false
+	for _, scope := range result.Walker.SymbolTable.LegacyScopes {
+		if item, ok := scope.Items["a"]; ok {
+			declared = item.Line == 2 && item.Pos == 7
+		}
+	}
+	if !declared {
+		t.Error("Expected a declared at line 2, pos 7")
+	}
+	for _, span := range result.Walker.Spans {
+		if span.File == "" && span.Line > 5 || span.File == "lib.txt" && span.Line > 1 {
+			t.Errorf("Expected the spans within the files, got %+v", span)
+		}
+	}
+	for _, line := range result.Lines {
+		if line > 5 {
+			t.Errorf("Expected the lines of the code within main.txt, got %v", result.Lines)
+			break
+		}
+	}
+}
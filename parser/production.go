@@ -10,7 +10,8 @@ type Production struct {
 	Head Symbol
 	Body []Symbol
 
-	Rule Rule
+	Rule   Rule
+	Action string // the action the rule is compiled from, if read from a grammar, see ParseAction
 }
 
 type Rule func(*Walker) error
@@ -24,13 +25,27 @@ func (p *Production) Equals(other Production) bool {
 }
 
 // HandleRule executes the rule associated with the production if it is not nil.
+// Productions without a rule still fold their matched nodes into one, so that
+// the token stack stays in step with the symbol stack for the rules that follow.
 func (p *Production) HandleRule(walker *Walker) error {
 	if p.Rule == nil {
+		walker.ReduceTokens(p.Head, p.Length())
 		return nil
 	}
 	return p.Rule(walker)
 }
 
+// Length returns the number of symbols in the body, not counting EPSILON.
+func (p *Production) Length() int {
+	n := 0
+	for _, symbol := range p.Body {
+		if !symbol.IsEpsilon() {
+			n++
+		}
+	}
+	return n
+}
+
 type Symbol string
 
 // IsEpsilon checks if the symbol is equal to EPSILON.
@@ -52,32 +67,53 @@ const (
 
 var Terminals = Set[Terminal]{}.AddAll(
 	// Brackets and punctuation
-	"{", "}", ";", "[", "]", "(", ")",
+	"{", "}", ";", "[", "]", "(", ")", ",", ".", ":",
 
 	// Arithmetic operators
-	"+", "-", "*", "/",
+	"+", "-", "*", "/", "%",
 
 	// Logical and comparison operators
 	"||", "&&", "==", "!=", "<", "<=", ">", ">=", "!", "=", "!=",
 
 	// Keywords
-	"if", "else", "while", "do", "break",
+	"if", "else", "while", "do", "break", "static", "const", "new", "module",
+	"switch", "case", "default",
 
 	// Literals
 	"true", "false",
 
 	// Types
-	"basic", "id", "num", "real",
+	"basic", "id", "num", "real", "str",
 
 	// Special symbols
 	EPSILON, TERMINATE,
 )
 
+// Aliases are the names of the terminals standing for a class of tokens, for
+// the messages, eg. "expected identifier" rather than "expected id".
+var Aliases = map[Terminal]string{
+	"basic":   "type",
+	"id":      "identifier",
+	"num":     "integer",
+	"real":    "real number",
+	"str":     "string",
+	TERMINATE: "end of input",
+}
+
 var AugmentedProduction = Production{
 	Head: "program'",
 	Body: []Symbol{"program"},
 }
 
+// ExpectedConflicts and ExpectedReduceConflicts are the conflicts the grammar
+// below is known to have, such as the dangling else resolved by shifting.
+// Strict mode fails when the table has any other number, so update them
+// together with the productions.
+var (
+	ExpectedConflicts       = 237
+	ExpectedReduceConflicts = 85
+)
+
 var OptimizedSymbols = Set[Symbol]{}.AddAll()
 
 var Productions = []Production{
@@ -87,6 +123,18 @@ var Productions = []Production{
 		Body: []Symbol{"block"},
 		Rule: GenRules.Program,
 	},
+	// program → module_decl block
+	{
+		Head: "program",
+		Body: []Symbol{"module_decl", "block"},
+		Rule: GenRules.Program,
+	},
+	// module_decl → module id ;
+	{
+		Head: "module_decl",
+		Body: []Symbol{"module", "id", ";"},
+		Rule: GenRules.ModuleDecl,
+	},
 	// block → { decls stmts }
 	// ** optimized to combined_decls_stmts **
 	// block → { combined_decls_stmts }
@@ -122,18 +170,88 @@ var Productions = []Production{
 		Body: []Symbol{EPSILON}, // ε
 		Rule: GenRules.DeclsEpsilon,
 	},
-	// decl → type id;
+	// decl → type declarators ; | storage type declarators ;
 	{
 		Head: "decl",
-		Body: []Symbol{"type", "id", ";"},
+		Body: []Symbol{"type", "declarators", ";"},
 		Rule: GenRules.Decl,
 	},
-	// type → type[num] | basic
+	{
+		Head: "decl",
+		Body: []Symbol{"storage", "type", "declarators", ";"},
+		Rule: GenRules.DeclStorage,
+	},
+	// storage → static | const
+	{
+		Head: "storage",
+		Body: []Symbol{"static"},
+		Rule: GenRules.StorageStatic,
+	},
+	{
+		Head: "storage",
+		Body: []Symbol{"const"},
+		Rule: GenRules.StorageConst,
+	},
+	// declarators → declarators , init_declarator | init_declarator
+	{
+		Head: "declarators",
+		Body: []Symbol{"declarators", ",", "init_declarator"},
+		Rule: GenRules.DeclaratorsList,
+	},
+	{
+		Head: "declarators",
+		Body: []Symbol{"init_declarator"},
+		Rule: GenRules.DeclaratorsSingle,
+	},
+	// init_declarator → declarator = assign | declarator | declarator = { initializers }
+	{
+		Head: "init_declarator",
+		Body: []Symbol{"declarator", "=", "assign"},
+		Rule: GenRules.InitDeclaratorAssign,
+	},
+	{
+		Head: "init_declarator",
+		Body: []Symbol{"declarator"},
+		Rule: GenRules.InitDeclarator,
+	},
+	{
+		Head: "init_declarator",
+		Body: []Symbol{"declarator", "=", "{", "initializers", "}"},
+		Rule: GenRules.InitDeclaratorList,
+	},
+	// initializers → initializers , assign | assign
+	{
+		Head: "initializers",
+		Body: []Symbol{"initializers", ",", "assign"},
+		Rule: GenRules.InitializersList,
+	},
+	{
+		Head: "initializers",
+		Body: []Symbol{"assign"},
+		Rule: GenRules.InitializersAssign,
+	},
+	// declarator → declarator[num] | id
+	{
+		Head: "declarator",
+		Body: []Symbol{"declarator", "[", "num", "]"},
+		Rule: GenRules.DeclaratorArray,
+	},
+	{
+		Head: "declarator",
+		Body: []Symbol{"id"},
+		Rule: GenRules.DeclaratorId,
+	},
+	// type → type[num] | type * | basic
 	{
 		Head: "type",
 		Body: []Symbol{"type", "[", "num", "]"},
 		Rule: GenRules.TypeArray,
 	},
+	{
+		Head: "type",
+		Body: []Symbol{"type", "*"},
+		Rule: GenRules.TypePointer,
+	},
 	{
 		Head: "type",
 		Body: []Symbol{"basic"},
@@ -179,10 +297,10 @@ var Productions = []Production{
 		Body: []Symbol{"if", "(", "bool", ")", "matched_stmt", "else", "unmatched_stmt"},
 		Rule: GenRules.UnmatchedStmtIfElse,
 	},
-	// matched_stmt → loc = bool ;
+	// matched_stmt → loc = assign ;
 	{
 		Head: "matched_stmt",
-		Body: []Symbol{"loc", "=", "bool", ";"},
+		Body: []Symbol{"loc", "=", "assign", ";"},
 		Rule: GenRules.MatchedStmtAssign,
 	},
 	// matched_stmt → if ( bool ) matched_stmt else matched_stmt | if ( bool ) matched_stmt
@@ -214,13 +332,64 @@ var Productions = []Production{
 		Body: []Symbol{"break", ";"},
 		Rule: GenRules.MatchedStmtBreak,
 	},
+	// matched_stmt → switch ( bool ) { cases }
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"switch", "(", "bool", ")", "{", "cases", "}"},
+		Rule: GenRules.MatchedStmtSwitch,
+	},
+	// cases → cases case_clause | case_clause
+	{
+		Head: "cases",
+		Body: []Symbol{"cases", "case_clause"},
+		Rule: GenRules.CasesList,
+	},
+	{
+		Head: "cases",
+		Body: []Symbol{"case_clause"},
+		Rule: GenRules.CasesClause,
+	},
+	// case_clause → case bool : case_stmts | default : case_stmts
+	{
+		Head: "case_clause",
+		Body: []Symbol{"case", "bool", ":", "case_stmts"},
+		Rule: GenRules.CaseClause,
+	},
+	{
+		Head: "case_clause",
+		Body: []Symbol{"default", ":", "case_stmts"},
+		Rule: GenRules.CaseDefault,
+	},
+	// case_stmts → case_stmts matched_stmt | case_stmts unmatched_stmt | ε
+	// declarations are only allowed in the blocks of a case
+	{
+		Head: "case_stmts",
+		Body: []Symbol{"case_stmts", "matched_stmt"},
+		Rule: GenRules.CaseStmtsMatched,
+	},
+	{
+		Head: "case_stmts",
+		Body: []Symbol{"case_stmts", "unmatched_stmt"},
+		Rule: GenRules.CaseStmtsUnmatched,
+	},
+	{
+		Head: "case_stmts",
+		Body: []Symbol{EPSILON}, // ε
+		Rule: GenRules.CaseStmtsEpsilon,
+	},
+	// matched_stmt → call ;
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"call", ";"},
+		Rule: GenRules.MatchedStmtCall,
+	},
 	// matched_stmt → block
 	{
 		Head: "matched_stmt",
 		Body: []Symbol{"block"},
 		Rule: GenRules.MatchedStmtBlock,
 	},
-	// loc → loc[num] | id
+	// loc → loc[num] | id | id . id | * factor
 	{
 		Head: "loc",
 		Body: []Symbol{"loc", "[", "num", "]"},
@@ -231,6 +400,64 @@ var Productions = []Production{
 		Body: []Symbol{"id"},
 		Rule: GenRules.LocId,
 	},
+	{
+		Head: "loc",
+		Body: []Symbol{"id", ".", "id"},
+		Rule: GenRules.LocQualified,
+	},
+	{
+		Head: "loc",
+		Body: []Symbol{"*", "factor"},
+		Rule: GenRules.LocDeref,
+	},
+	// call → id ( args ) | id ( )
+	{
+		Head: "call",
+		Body: []Symbol{"id", "(", "args", ")"},
+		Rule: GenRules.CallArgs,
+	},
+	{
+		Head: "call",
+		Body: []Symbol{"id", "(", ")"},
+		Rule: GenRules.CallEmpty,
+	},
+	// args → args , assign | assign
+	// ** assign rather than seq, commas here separate the arguments **
+	{
+		Head: "args",
+		Body: []Symbol{"args", ",", "assign"},
+		Rule: GenRules.ArgsList,
+	},
+	{
+		Head: "args",
+		Body: []Symbol{"assign"},
+		Rule: GenRules.ArgsAssign,
+	},
+	// seq → seq , assign | assign
+	// ** comma expression, only allowed inside parentheses so that it never
+	// swallows the commas of a list **
+	{
+		Head: "seq",
+		Body: []Symbol{"seq", ",", "assign"},
+		Rule: GenRules.SeqComma,
+	},
+	{
+		Head: "seq",
+		Body: []Symbol{"assign"},
+		Rule: GenRules.SeqAssign,
+	},
+	// assign → loc = assign | bool
+	// ** right recursive, so a = b = 0 assigns b first **
+	{
+		Head: "assign",
+		Body: []Symbol{"loc", "=", "assign"},
+		Rule: GenRules.AssignLoc,
+	},
+	{
+		Head: "assign",
+		Body: []Symbol{"bool"},
+		Rule: GenRules.AssignBool,
+	},
 	// bool → bool || join | join
 	{
 		Head: "bool",
@@ -311,7 +538,7 @@ var Productions = []Production{
 		Body: []Symbol{"term"},
 		Rule: GenRules.ExprTerm,
 	},
-	// term → term*unary | term/unary | unary
+	// term → term*unary | term/unary | term%unary | unary
 	{
 		Head: "term",
 		Body: []Symbol{"term", "*", "unary"},
@@ -322,12 +549,17 @@ var Productions = []Production{
 		Body: []Symbol{"term", "/", "unary"},
 		Rule: GenRules.TermDiv,
 	},
+	{
+		Head: "term",
+		Body: []Symbol{"term", "%", "unary"},
+		Rule: GenRules.TermMod,
+	},
 	{
 		Head: "term",
 		Body: []Symbol{"unary"},
 		Rule: GenRules.TermUnary,
 	},
-	// unary → !unary | -unary | factor
+	// unary → !unary | -unary | +unary | factor
 	{
 		Head: "unary",
 		Body: []Symbol{"!", "unary"},
@@ -338,16 +570,22 @@ var Productions = []Production{
 		Body: []Symbol{"-", "unary"},
 		Rule: GenRules.UnaryNeg,
 	},
+	{
+		Head: "unary",
+		Body: []Symbol{"+", "unary"},
+		Rule: GenRules.UnaryPlus,
+	},
 	{
 		Head: "unary",
 		Body: []Symbol{"factor"},
 		Rule: GenRules.UnaryFactor,
 	},
-	// factor → (bool) | loc | num | real | true | false
+	// factor → (seq) | loc | num | real | str | call | true | false
+	//        | new ( basic ) | new ( basic [ num ] )
 	{
 		Head: "factor",
-		Body: []Symbol{"(", "bool", ")"},
-		Rule: GenRules.FactorBool,
+		Body: []Symbol{"(", "seq", ")"},
+		Rule: GenRules.FactorSeq,
 	},
 	{
 		Head: "factor",
@@ -364,6 +602,16 @@ var Productions = []Production{
 		Body: []Symbol{"real"},
 		Rule: GenRules.FactorReal,
 	},
+	{
+		Head: "factor",
+		Body: []Symbol{"str"},
+		Rule: GenRules.FactorStr,
+	},
+	{
+		Head: "factor",
+		Body: []Symbol{"call"},
+		Rule: GenRules.FactorCall,
+	},
 	{
 		Head: "factor",
 		Body: []Symbol{"true"},
@@ -374,4 +622,14 @@ var Productions = []Production{
 		Body: []Symbol{"false"},
 		Rule: GenRules.FactorFalse,
 	},
+	{
+		Head: "factor",
+		Body: []Symbol{"new", "(", "basic", ")"},
+		Rule: GenRules.FactorNew,
+	},
+	{
+		Head: "factor",
+		Body: []Symbol{"new", "(", "basic", "[", "num", "]", ")"},
+		Rule: GenRules.FactorNew,
+	},
 }
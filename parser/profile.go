@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Profile counts how often each production is reduced and each state is
+// entered, over the parses of one or more programs.
+type Profile struct {
+	Reductions map[int]int // by index of the production in the grammar
+	Visits     map[int]int // by state, every push onto the state stack
+}
+
+// NewProfile returns an empty profile.
+func NewProfile() *Profile {
+	return &Profile{Reductions: map[int]int{}, Visits: map[int]int{}}
+}
+
+// Merge adds the counts of the other profile to the profile.
+func (p *Profile) Merge(other *Profile) {
+	for production, n := range other.Reductions {
+		p.Reductions[production] += n
+	}
+	for state, n := range other.Visits {
+		p.Visits[state] += n
+	}
+}
+
+// hottest returns the keys of the counts, the highest count first.
+func hottest(counts map[int]int) []int {
+	return slices.SortedFunc(maps.Keys(counts), func(a, b int) int {
+		return cmp.Or(counts[b]-counts[a], a-b)
+	})
+}
+
+// Write writes the report of the profile to the writer: the productions
+// reduced, the most reduced first, the productions never reduced, and the
+// states most entered, at most top of them, all of them if top is 0. The
+// hot productions, those making up the first half of the reductions, are
+// marked with a *.
+func (p *Profile) Write(w io.Writer, grammar *Grammar, top int) error {
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	bar := func(n, most int) string {
+		// bars are at most 40 wide, and at least 1
+		return strings.Repeat("#", max(1, n*40/most))
+	}
+
+	total := 0
+	for _, n := range p.Reductions {
+		total += n
+	}
+	productions := hottest(p.Reductions)
+	printf("Reductions: %d of %d productions, %d in all\n", len(productions), len(grammar.Productions), total)
+	seen := 0
+	for _, i := range productions {
+		n := p.Reductions[i]
+		hot := " "
+		if seen*2 < total {
+			hot = "*"
+		}
+		seen += n
+		printf("%s r%-4d %8d %5.1f%% %-40s %s\n", hot, i, n, float64(n)*100/float64(total), bar(n, p.Reductions[productions[0]]), formatProduction(grammar.Productions[i]))
+	}
+
+	printf("\nNever reduced:\n")
+	never := 0
+	for i, production := range grammar.Productions {
+		if p.Reductions[i] == 0 {
+			printf("  r%-4d %s\n", i, formatProduction(production))
+			never++
+		}
+	}
+	if never == 0 {
+		printf("    none\n")
+	}
+
+	states := hottest(p.Visits)
+	if top > 0 && len(states) > top {
+		states = states[:top]
+	}
+	printf("\nStates most entered:\n")
+	for _, state := range states {
+		n := p.Visits[state]
+		printf("  %-8s %8d %s\n", fmt.Sprintf("I%d", state), n, bar(n, p.Visits[states[0]]))
+	}
+	return err
+}
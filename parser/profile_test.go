@@ -0,0 +1,73 @@
+package parser_test
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestProfile(t *testing.T) {
+	parser := sharedParser()
+	reduced := map[int]int{}
+	result, err := Compile(Options{
+		Source:  strings.NewReader("{\n    int a, b;\n    a = 1;\n    b = a + 2;\n}\n"),
+		Tables:  parser.Tables(),
+		Profile: true,
+		Hooks: []SemanticAction{func(ctx *ReduceContext) error {
+			reduced[ctx.Index]++
+			return nil
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if result.Profile == nil {
+		t.Fatalf("Expected a profile")
+	}
+	if !maps.Equal(result.Profile.Reductions, reduced) {
+		t.Errorf("Expected the reductions %v, got %v", reduced, result.Profile.Reductions)
+	}
+	visits := 0
+	for _, n := range result.Profile.Visits {
+		visits += n
+	}
+	// every token is shifted and every reduction goes to a state
+	reductions := 0
+	for _, n := range reduced {
+		reductions += n
+	}
+	if visits != len(result.Tokens)+reductions {
+		t.Errorf("Expected %d visits, got %d", len(result.Tokens)+reductions, visits)
+	}
+
+	total := NewProfile()
+	total.Merge(result.Profile)
+	total.Merge(result.Profile)
+	for i, n := range reduced {
+		if total.Reductions[i] != 2*n {
+			t.Errorf("Expected the merge to sum the reductions of r%d, got %d", i, total.Reductions[i])
+		}
+	}
+
+	var sb strings.Builder
+	if err := result.Profile.Write(&sb, parser.Grammar, 3); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	report := sb.String()
+	if !strings.HasPrefix(report, "Reductions: ") || !strings.Contains(report, "\n* r") {
+		t.Errorf("Expected the reductions with the hot ones marked, got %s", report)
+	}
+	if !strings.Contains(report, "Never reduced:") {
+		t.Errorf("Expected the productions never reduced, got %s", report)
+	}
+	states := report[strings.Index(report, "States most entered:"):]
+	if n := strings.Count(states, "\n  I"); n != 3 {
+		t.Errorf("Expected the 3 hottest states, got %d in %s", n, states)
+	}
+
+	if result, _ := Compile(Options{Source: strings.NewReader("{}"), Tables: parser.Tables()}); result.Profile != nil {
+		t.Errorf("Expected no profile unless asked for")
+	}
+}
@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"app/lexer"
+)
+
+// Source is a file of a program.
+type Source struct {
+	Name string
+	Text string
+}
+
+// Unit is a file of a program with the session it was compiled in.
+type Unit struct {
+	Source  Source
+	Walker  *Walker
+	Globals map[string]*SymbolTableItem // the globals the file declares
+
+	undefined []error // identifiers naming nothing in the program
+}
+
+// Program is files compiled with a shared global scope: the globals each file
+// declares are visible in the others, wherever they come in the order. The
+// globals of a file declaring a module are only visible in the others by
+// their qualified name, mod.name. The files share the symbol table, so that
+// addresses, constants and labels are unique across them.
+type Program struct {
+	Units       []*Unit
+	SymbolTable *SymbolTable
+}
+
+// globalsOf returns the items of the outermost blocks among the scopes.
+func globalsOf(scopes []*Scope) map[string]*SymbolTableItem {
+	globals := map[string]*SymbolTableItem{}
+	for _, scope := range scopes {
+		if scope.Level == 1 {
+			maps.Copy(globals, scope.Items)
+		}
+	}
+	return globals
+}
+
+// CompileProgram compiles the files as one program, in order. The globals of
+// every file are first collected by compiling it alone, and a global defined
+// by two files outside of modules, or a module declared by two files, is an
+// error. Each file is then compiled with the globals of the others in scope,
+// those of the files after it as they were collected.
+func (t *ParserTables) CompileProgram(sources []Source, logger func(name, message string)) (*Program, error) {
+	declared := make([]map[string]*SymbolTableItem, len(sources))
+	modules := map[string]*Scope{}
+	defined := map[string]int{}
+	declaring := map[string]int{} // the file declaring each module
+	var errs []error
+	for i, source := range sources {
+		w := t.NewSession()
+		if _, err := t.run(context.Background(), w, lexer.NewLexer(strings.NewReader(source.Text)), func(string) {}, nil); err != nil {
+			return nil, err
+		}
+		declared[i] = globalsOf(w.SymbolTable.LegacyScopes)
+		if w.Module != "" {
+			// the globals of a module are reached through its name only
+			if j, ok := declaring[w.Module]; ok {
+				errs = append(errs, fmt.Errorf("module %s is declared by both %s and %s", w.Module, sources[j].Name, source.Name))
+			}
+			declaring[w.Module] = i
+			if scope, ok := w.SymbolTable.Modules[w.Module]; ok {
+				modules[w.Module] = scope
+			}
+			declared[i] = nil
+			continue
+		}
+		for _, name := range slices.Sorted(maps.Keys(declared[i])) {
+			j, ok := defined[name]
+			if !ok {
+				defined[name] = i
+				continue
+			}
+			first, second := declared[j][name], declared[i][name]
+			errs = append(errs, fmt.Errorf("%s is defined in %s, at line %d, pos %d, and in %s, at line %d, pos %d",
+				name, sources[j].Name, first.Line, first.Pos, source.Name, second.Line, second.Pos))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	p := &Program{SymbolTable: NewSymbolTable(nil, nil)}
+	// the modules of the files after the one compiled as they were collected
+	maps.Copy(p.SymbolTable.Modules, modules)
+	p.SymbolTable.EnterScope()
+	if err := (&Walker{SymbolTable: p.SymbolTable}).DeclareBuiltins(); err != nil {
+		return nil, err
+	}
+	prelude := p.SymbolTable.CurrentScope
+	labels := LabelAllocator{}
+	for i, source := range sources {
+		// the globals of the other files, seen through a scope between the
+		// prelude and the outermost block of the file
+		externs := &Scope{ID: len(p.SymbolTable.LegacyScopes), Level: 0, Items: map[string]*SymbolTableItem{}, Parent: prelude}
+		for j := range sources {
+			switch {
+			case j < i && p.Units[j].Walker.Module != "":
+			case j < i:
+				maps.Copy(externs.Items, p.Units[j].Globals)
+			case j > i:
+				maps.Copy(externs.Items, declared[j])
+			}
+		}
+		p.SymbolTable.LegacyScopes = append(p.SymbolTable.LegacyScopes, externs)
+		p.SymbolTable.CurrentScope = externs
+		from := len(p.SymbolTable.LegacyScopes)
+
+		w := t.NewSession()
+		w.SymbolTable = p.SymbolTable
+		w.Environment.Labels = labels
+		w.xref = &crossReference{tokens: map[*lexer.Token]int{}}
+		if _, err := t.run(context.Background(), w, lexer.NewLexer(strings.NewReader(source.Text)), func(message string) {
+			logger(source.Name, message)
+		}, nil); err != nil {
+			return nil, err
+		}
+		labels = w.Environment.Labels
+		p.SymbolTable.CurrentScope = prelude
+
+		scopes := p.SymbolTable.LegacyScopes[from:]
+		w.xref.declarations(scopes)
+		unit := &Unit{Source: source, Walker: w, Globals: globalsOf(scopes)}
+		for _, r := range w.xref.references {
+			if r.Item == nil {
+				token := w.xref.shifted[r.Token]
+				unit.undefined = append(unit.undefined, fmt.Errorf("undefined reference to %s in %s, at line %d, pos %d", r.Name, source.Name, token.Line, token.Pos))
+			}
+		}
+		p.Units = append(p.Units, unit)
+	}
+	return p, nil
+}
+
+// Link merges the code of the files into the code of one program, in their
+// order, and returns it in a walker over the shared symbol table. It fails
+// if an identifier of a file names nothing declared in the file or among the
+// globals of the others.
+func (p *Program) Link() (*Walker, error) {
+	linked := &Walker{SymbolTable: p.SymbolTable, Environment: NewEnvironment()}
+	var errs []error
+	for _, u := range p.Units {
+		errs = append(errs, u.undefined...)
+		linked.ThreeAddress = append(linked.ThreeAddress, u.Walker.ThreeAddress...)
+		linked.Lines = append(linked.Lines, u.Walker.Lines...)
+		linked.Docs = append(linked.Docs, u.Walker.Docs...)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(p.Units) > 0 {
+		linked.Grammar, linked.Table = p.Units[0].Walker.Grammar, p.Units[0].Walker.Table
+	}
+	return linked, nil
+}
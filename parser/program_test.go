@@ -0,0 +1,72 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestCompileProgram(t *testing.T) {
+	sources := []Source{
+		{Name: "a.in", Text: "{\n    int x;\n    float f;\n    x = y + 1;\n    f = abs(x);\n}\n"},
+		{Name: "b.in", Text: "{\n    int y;\n    y = x % 3;\n    y = abs(y);\n}\n"},
+	}
+	var logs []string
+	program, err := sharedParser().Tables().CompileProgram(sources, func(name, message string) {
+		if strings.HasPrefix(message, "Error") {
+			logs = append(logs, name+": "+message)
+		}
+	})
+	if err != nil || len(logs) > 0 {
+		t.Fatalf("CompileProgram: %v %v", err, logs)
+	}
+	if program.Units[0].Globals["x"] == nil || program.Units[1].Globals["y"] == nil || len(program.Units[1].Globals) != 1 {
+		t.Errorf("Expected the globals of each file, got %v and %v", program.Units[0].Globals, program.Units[1].Globals)
+	}
+	addresses := []int{}
+	for _, u := range program.Units {
+		for _, item := range u.Globals {
+			addresses = append(addresses, item.Address)
+		}
+	}
+	if slices.Sort(addresses); len(slices.Compact(addresses)) != 3 {
+		t.Errorf("Expected the globals to have their own addresses, got %v", addresses)
+	}
+
+	linked, err := program.Link()
+	if err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	code := strings.Join(linked.ThreeAddress, "\n")
+	if i, j := strings.Index(code, "y + 1"), strings.Index(code, "x mod 3"); i < 0 || j < i {
+		t.Errorf("Expected the code of the files in order, got\n%s", code)
+	}
+	labels := map[string]bool{}
+	for _, line := range linked.ThreeAddress {
+		if label, ok := strings.CutSuffix(line, ":"); ok {
+			if labels[label] {
+				t.Errorf("Expected the labels to be unique across the files, %s is defined twice", label)
+			}
+			labels[label] = true
+		}
+	}
+	if len(labels) == 0 || len(linked.Lines) != len(linked.ThreeAddress) {
+		t.Errorf("Expected labels and a line for each instruction, got\n%s", code)
+	}
+
+	// a global defined twice
+	_, err = sharedParser().Tables().CompileProgram([]Source{sources[0], {Name: "c.in", Text: "{\n    int x;\n}\n"}}, func(string, string) {})
+	if err == nil || !strings.Contains(err.Error(), "x is defined in a.in, at line 1") || !strings.Contains(err.Error(), "c.in") {
+		t.Errorf("Expected x to be reported as defined twice, got %v", err)
+	}
+	// a name defined by none of the files
+	program, err = sharedParser().Tables().CompileProgram(sources[:1], func(string, string) {})
+	if err != nil {
+		t.Fatalf("CompileProgram: %v", err)
+	}
+	if _, err := program.Link(); err == nil || !strings.Contains(err.Error(), "undefined reference to y in a.in") {
+		t.Errorf("Expected y to be undefined, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// the measures of the railroad diagrams, in pixels
+const (
+	railCharWidth = 8  // of a character of the monospace font of 13px
+	railBoxHeight = 24 // of the box of a symbol
+	railRowHeight = 40 // between the tracks of two alternatives
+	railGap       = 16 // between two boxes of a track
+	railRadius    = 10 // of the curves joining the tracks to the rails
+	railMargin    = 20 // around the diagram
+)
+
+// Heads returns the heads of the productions of the grammar in the order
+// they are first met, the augmented production left out.
+func (g *Grammar) Heads() []Symbol {
+	var heads []Symbol
+	seen := map[Symbol]bool{}
+	for _, production := range g.Productions {
+		if !seen[production.Head] {
+			seen[production.Head] = true
+			heads = append(heads, production.Head)
+		}
+	}
+	return heads
+}
+
+// RailroadSVG returns the railroad diagram of the productions of the head as
+// a standalone SVG image: a track per alternative between the rail entering
+// on the left and the one leaving on the right, with the terminals in
+// rounded boxes and the nonterminals in square ones. An empty body is a
+// track without boxes.
+func (g *Grammar) RailroadSVG(head Symbol) string {
+	var alternatives [][]Symbol
+	for _, production := range g.Productions {
+		if production.Head != head {
+			continue
+		}
+		var track []Symbol
+		for _, symbol := range production.Body {
+			if !symbol.IsEpsilon() {
+				track = append(track, symbol)
+			}
+		}
+		alternatives = append(alternatives, track)
+	}
+	if len(alternatives) == 0 {
+		alternatives = [][]Symbol{nil}
+	}
+
+	boxWidth := func(symbol Symbol) int {
+		return utf8.RuneCountInString(string(symbol))*railCharWidth + 2*railGap
+	}
+	trackWidth := 0
+	for _, track := range alternatives {
+		width := 0
+		for _, symbol := range track {
+			width += boxWidth(symbol) + railGap
+		}
+		trackWidth = max(trackWidth, width)
+	}
+	// the rails run down from the first track on both sides
+	left := railMargin + 2*railRadius
+	right := left + 2*railRadius + railGap + trackWidth + 2*railRadius
+	width := right + 2*railRadius + railMargin
+	top := railMargin + railBoxHeight/2
+	height := top + (len(alternatives)-1)*railRowHeight + railBoxHeight/2 + railMargin
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	b.WriteString(`<style>path { fill: none; stroke: #333; stroke-width: 1.5; } rect { fill: #fff; stroke: #333; stroke-width: 1.5; } ` +
+		`rect.terminal { fill: #eef6e8; } text { font: 13px monospace; text-anchor: middle; dominant-baseline: central; }</style>` + "\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(string(head)))
+	// the ends of the diagram
+	fmt.Fprintf(&b, `<path d="M %d %d v %d M %d %d h %d"/>`+"\n", railMargin, top-railRadius, 2*railRadius, railMargin, top, left-railMargin)
+	fmt.Fprintf(&b, `<path d="M %d %d h %d M %d %d v %d"/>`+"\n", right, top, width-railMargin-right, width-railMargin, top-railRadius, 2*railRadius)
+	for i, track := range alternatives {
+		y := top + i*railRowHeight
+		start, end := left+2*railRadius, right-2*railRadius
+		if i == 0 {
+			fmt.Fprintf(&b, `<path d="M %d %d h %d"/>`+"\n", left, y, start-left)
+			fmt.Fprintf(&b, `<path d="M %d %d h %d"/>`+"\n", end, y, right-end)
+		} else {
+			// down from the rail on the left, up to the one on the right
+			fmt.Fprintf(&b, `<path d="M %d %d q %d 0 %d %d v %d q 0 %d %d %d"/>`+"\n",
+				left, top, railRadius, railRadius, railRadius, y-top-2*railRadius, railRadius, railRadius, railRadius)
+			fmt.Fprintf(&b, `<path d="M %d %d q %d 0 %d %d v %d q 0 %d %d %d"/>`+"\n",
+				right, top, -railRadius, -railRadius, railRadius, y-top-2*railRadius, railRadius, -railRadius, railRadius)
+		}
+		x := start
+		if len(track) > 0 {
+			fmt.Fprintf(&b, `<path d="M %d %d h %d"/>`+"\n", x, y, railGap)
+			x += railGap
+		}
+		for _, symbol := range track {
+			w := boxWidth(symbol)
+			class, rounded := "nonterminal", 0
+			if g.IsTerminal(symbol) {
+				class, rounded = "terminal", railBoxHeight/2
+			}
+			fmt.Fprintf(&b, `<rect class="%s" x="%d" y="%d" width="%d" height="%d" rx="%d"/>`+"\n", class, x, y-railBoxHeight/2, w, railBoxHeight, rounded)
+			fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", x+w/2, y, html.EscapeString(string(symbol)))
+			x += w
+			fmt.Fprintf(&b, `<path d="M %d %d h %d"/>`+"\n", x, y, railGap)
+			x += railGap
+		}
+		if x < end {
+			fmt.Fprintf(&b, `<path d="M %d %d h %d"/>`+"\n", x, y, end-x)
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// WriteRailroad writes a standalone HTML page with the railroad diagram of
+// every nonterminal of the grammar, in the order of Heads, each under a
+// heading its name is the anchor of.
+func (g *Grammar) WriteRailroad(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Grammar</title>\n")
+	b.WriteString("<style>body { font-family: sans-serif; margin: 2em; } h2 { font: bold 15px monospace; margin: 1.5em 0 0.3em; }</style>\n")
+	b.WriteString("</head>\n<body>\n")
+	for _, head := range g.Heads() {
+		name := html.EscapeString(string(head))
+		fmt.Fprintf(&b, "<h2 id=\"%s\">%s</h2>\n", name, name)
+		b.WriteString(g.RailroadSVG(head))
+	}
+	b.WriteString("</body>\n</html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,63 @@
+package parser_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestGrammar_RailroadSVG(t *testing.T) {
+	g, err := ReadBNF(strings.NewReader(exprBNF + "cmp -> expr '<' expr | %empty\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if heads := g.Heads(); !slices.Equal(heads, []Symbol{"prog", "prog_list", "stat", "stat_list", "expr", "expr_list", "expr_opt", "cmp"}) {
+		t.Errorf("Expected the heads in the order of the rules, got %v", heads)
+	}
+
+	svg := g.RailroadSVG("cmp")
+	var boxes, texts []string
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("Expected well-formed SVG, got %v\n%s", err, svg)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "rect" {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "class" {
+					boxes = append(boxes, attr.Value)
+				}
+			}
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "text" {
+			var text string
+			if err := decoder.DecodeElement(&text, &start); err != nil {
+				t.Fatal(err)
+			}
+			texts = append(texts, text)
+		}
+	}
+	if !slices.Equal(boxes, []string{"nonterminal", "terminal", "nonterminal"}) || !slices.Equal(texts, []string{"expr", "<", "expr"}) {
+		t.Errorf("Expected the boxes of expr < expr, got %v and %v\n%s", boxes, texts, svg)
+	}
+	// a track per alternative, the second one reached by the curves of the rails
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width=`) || strings.Count(svg, " q ") != 4 {
+		t.Errorf("Expected two tracks, got\n%s", svg)
+	}
+
+	var b strings.Builder
+	if err := g.WriteRailroad(&b); err != nil {
+		t.Fatal(err)
+	}
+	if page := b.String(); strings.Count(page, "<svg ") != 8 || !strings.Contains(page, `<h2 id="expr_opt">expr_opt</h2>`) {
+		t.Errorf("Expected a diagram per head, got\n%s", page)
+	}
+}
@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"app/lexer"
+)
+
+// Span is where a node is in the source, from the first character of its
+// first token to the end of its last one.
+type Span struct {
+	File    string `json:"file,omitempty"` // the file included the span starts in once preprocessed
+	Line    int64  `json:"line"`
+	Pos     int64  `json:"pos"`
+	EndLine int64  `json:"endLine"`
+	EndPos  int64  `json:"endPos"`
+}
+
+// ReduceContext is what a reduction hands to the semantic actions: the
+// production, the nodes it matched with where they are in the source, and
+// the state it happens in.
+type ReduceContext struct {
+	Walker     *Walker
+	Production *Production
+	Index      int        // index of the production in the grammar
+	State      int        // state on top of the stack when reducing
+	Nodes      []*ASTNode // the nodes matched, one per symbol of the body
+	Spans      []Span     // where each node is, zero for the ones matching no token
+	Result     *ASTNode   // the node the reduction leaves on the token stack
+}
+
+// SemanticAction is a semantic action given the context of the reduction.
+type SemanticAction func(*ReduceContext) error
+
+// reduction is the production being reduced, for the contexts of its actions.
+type reduction struct {
+	production *Production
+	index      int
+	state      int
+}
+
+// firstToken returns the leftmost token under the node, nil if there is none.
+func firstToken(n *ASTNode) *lexer.Token {
+	if len(n.Children) == 0 {
+		if n.Token == nil || n.Token.Type == lexer.EXTRA {
+			return nil
+		}
+		return n.Token
+	}
+	for _, child := range n.Children {
+		if token := firstToken(child); token != nil {
+			return token
+		}
+	}
+	return nil
+}
+
+// lastToken returns the rightmost token under the node, nil if there is none.
+func lastToken(n *ASTNode) *lexer.Token {
+	if len(n.Children) == 0 {
+		return firstToken(n)
+	}
+	for i := len(n.Children) - 1; i >= 0; i-- {
+		if token := lastToken(n.Children[i]); token != nil {
+			return token
+		}
+	}
+	return nil
+}
+
+// spanOf returns where the node is in the source, zero if it matches no token.
+func spanOf(n *ASTNode) Span {
+	first, last := firstToken(n), lastToken(n)
+	if first == nil {
+		return Span{}
+	}
+	return Span{Line: first.Line, Pos: first.Pos, EndLine: last.Line, EndPos: last.Pos + int64(len(last.Val))}
+}
+
+// positioned returns the error of a rule at the start of the node the rule
+// left, if the message names no position, so that every diagnostic has one.
+func (w *Walker) positioned(err error) error {
+	if strings.Contains(err.Error(), "at line ") {
+		return err
+	}
+	if n, ok := w.Tokens.Peek(); ok {
+		if span := spanOf(n); span != (Span{}) {
+			return fmt.Errorf("%w, at line %d, pos %d", err, span.Line, span.Pos)
+		}
+	}
+	return err
+}
+
+// foldNodes returns the node of the head the nodes are reduced to, the node
+// itself if there is only one. A temporary among the nodes stands for the
+// value computed into it rather than its text, so that the code of -a - 1
+// reads the negation it emitted.
+func foldNodes(head Symbol, children []*ASTNode) *ASTNode {
+	if len(children) == 1 {
+		return children[0]
+	}
+	raw := make([]string, 0, len(children))
+	for _, child := range children {
+		if child.Token != nil && child.Token.Type == lexer.EXTRA && child.Token.Val != "" {
+			raw = append(raw, child.Token.Val)
+		} else {
+			raw = append(raw, child.raw)
+		}
+	}
+	return &ASTNode{
+		raw: strings.Join(raw, " "),
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+		},
+		Children: children,
+		Type:     head,
+	}
+}
+
+// reduceContext returns the context of the reduction in progress, with the
+// nodes it matched still on the token stack.
+func (w *Walker) reduceContext() *ReduceContext {
+	r := w.reducing
+	c := &ReduceContext{Walker: w, Production: r.production, Index: r.index, State: r.state}
+	for k := min(r.production.Length(), w.Tokens.Size()) - 1; k >= 0; k-- {
+		n, _ := w.Tokens.PeekAtK(k)
+		c.Nodes = append(c.Nodes, n)
+		c.Spans = append(c.Spans, spanOf(n))
+	}
+	c.Result = foldNodes(r.production.Head, c.Nodes)
+	return c
+}
+
+// Span returns where the nodes matched are in the source, zero if they match
+// no token.
+func (c *ReduceContext) Span() Span {
+	var span Span
+	for _, s := range c.Spans {
+		if s == (Span{}) {
+			continue
+		}
+		if span == (Span{}) {
+			span = s
+		}
+		span.EndLine, span.EndPos = s.EndLine, s.EndPos
+	}
+	return span
+}
+
+// Errorf returns the error at the start of the nodes matched.
+func (c *ReduceContext) Errorf(format string, args ...any) error {
+	span := c.Span()
+	return fmt.Errorf(format+", at line %d, pos %d", append(args, span.Line, span.Pos)...)
+}
+
+// Set attaches the attribute to the node the reduction leaves.
+func (c *ReduceContext) Set(key string, value any) {
+	if c.Result.Attributes == nil {
+		c.Result.Attributes = map[string]any{}
+	}
+	c.Result.Attributes[key] = value
+}
+
+// OnReduce registers a hook run after every reduction of the session, once
+// the semantic rule of the production has run, with the node the rule left
+// as Result.
+func (w *Walker) OnReduce(hook SemanticAction) {
+	w.hooks = append(w.hooks, hook)
+}
@@ -0,0 +1,50 @@
+package parser_test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestReduceContext(t *testing.T) {
+	var sums []*ReduceContext
+	reductions := 0
+	result, err := Compile(Options{
+		Source: strings.NewReader("module geo;\n{\n    int a;\n    a = 1 +\n        a;\n}\n"),
+		Tables: sharedParser().Tables(),
+		Hooks: []SemanticAction{func(c *ReduceContext) error {
+			reductions++
+			if c.Production.Head == "expr" && len(c.Nodes) == 3 {
+				sums = append(sums, c)
+			}
+			c.Set("head", c.Production.Head)
+			return nil
+		}},
+	})
+	if err != nil || result.Failed() {
+		t.Fatalf("Compile: %v %v", err, result.Diagnostics)
+	}
+	if reductions == 0 || result.AST.Attribute("head") != Symbol("program") {
+		t.Errorf("Expected the hook to run for every reduction up to program, got %v", result.AST.Attribute("head"))
+	}
+	if len(sums) != 1 {
+		t.Fatalf("Expected one sum, got %d", len(sums))
+	}
+	c := sums[0]
+	if c.Nodes[1].Token.Val != "+" || c.Result == c.Nodes[0] || c.Index != slices.IndexFunc(c.Walker.Grammar.Productions, c.Production.Equals) || c.Walker.Module != "geo" {
+		t.Errorf("Unexpected context %+v", c)
+	}
+	// 1 + on line 3, a on line 4
+	if c.Spans[0].Line != 3 || c.Spans[2].Line != 4 || c.Spans[2].EndPos-c.Spans[2].Pos != 1 {
+		t.Errorf("Unexpected spans %+v", c.Spans)
+	}
+	if span := c.Span(); span.Line != 3 || span.EndLine != 4 || span.Pos != c.Spans[0].Pos {
+		t.Errorf("Unexpected span %+v", span)
+	}
+	if err := c.Errorf("bad %s", "sum"); err.Error() != fmt.Sprintf("bad sum, at line 3, pos %d", c.Spans[0].Pos) {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	. "app/utils/collections"
+)
+
+// Registers are the machine registers temporaries are allocated to.
+var Registers = []string{"r0", "r1", "r2", "r3", "r4", "r5", "r6", "r7"}
+
+// Allocation maps temporaries to the registers holding them. Temporaries
+// missing from it are spilled and stay in memory.
+type Allocation map[string]string
+
+// Allocator assigns registers to the temporaries of three-address code.
+type Allocator func(code []string, registers []string) Allocation
+
+// Allocators are the register allocators selectable by name.
+var Allocators = map[string]Allocator{
+	"linear": LinearScan,
+	"color":  ColorGraph,
+}
+
+var tempPattern = regexp.MustCompile(`\$\(0x[0-9a-f]+\)`)
+
+// temps returns the temporaries in the operand, constants in the constant
+// pool are addressed the same way but are not temporaries.
+func temps(operand string) []string {
+	var result []string
+	for _, t := range tempPattern.FindAllString(operand, -1) {
+		var addr int
+		if _, err := fmt.Sscanf(t, "$(0x%x)", &addr); err == nil && addr < ConstantAddr {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// defUse returns the temporary the instruction writes, if any, and the ones it reads.
+func defUse(line string) (def string, uses []string) {
+	if isLabel(line) || strings.HasPrefix(line, "goto ") {
+		return "", nil
+	}
+	dist, value, ok := strings.Cut(line, " = ")
+	if !ok || strings.HasPrefix(line, "if ") {
+		return "", temps(line)
+	}
+	if t := temps(dist); len(t) == 1 && t[0] == dist {
+		return dist, temps(value)
+	}
+	return "", append(temps(dist), temps(value)...)
+}
+
+// successors returns the instructions that may run after each instruction.
+func successors(code []string) [][]int {
+	labels := map[string]int{}
+	for i, line := range code {
+		if label, ok := labelOf(line); ok {
+			labels[label] = i
+		}
+	}
+	succ := make([][]int, len(code))
+	for i, line := range code {
+		if !strings.HasPrefix(line, "goto ") && i+1 < len(code) {
+			succ[i] = append(succ[i], i+1)
+		}
+		if target, ok := jumpTarget(line); ok {
+			if j, ok := labels[target]; ok {
+				succ[i] = append(succ[i], j)
+			}
+		}
+	}
+	return succ
+}
+
+// Liveness returns the temporaries live after each instruction, that is the
+// ones read later before being written again.
+func Liveness(code []string) []Set[string] {
+	d := &Dataflow[string]{
+		Transfer: func(i int, out Set[string]) Set[string] {
+			def, uses := defUse(code[i])
+			return out.Copy().Remove(def).AddAll(uses...)
+		},
+	}
+	_, out := d.Solve(code)
+	return out
+}
+
+// LinearScan allocates registers to the live intervals of the temporaries in
+// order of their start, spilling the interval ending last when all registers
+// are taken. An interval spans from the first to the last instruction the
+// temporary is live at, including any holes in between.
+func LinearScan(code []string, registers []string) Allocation {
+	type interval struct {
+		temp       string
+		start, end int
+	}
+	spans := map[string]*interval{}
+	see := func(t string, i int) {
+		if s, ok := spans[t]; ok {
+			s.start, s.end = min(s.start, i), max(s.end, i)
+		} else {
+			spans[t] = &interval{temp: t, start: i, end: i}
+		}
+	}
+	for i, live := range Liveness(code) {
+		def, uses := defUse(code[i])
+		if def != "" {
+			see(def, i)
+		}
+		for _, t := range uses {
+			see(t, i)
+		}
+		for t := range live {
+			see(t, i)
+		}
+	}
+	intervals := make([]*interval, 0, len(spans))
+	for _, s := range spans {
+		intervals = append(intervals, s)
+	}
+	slices.SortFunc(intervals, func(a, b *interval) int {
+		if a.start != b.start {
+			return a.start - b.start
+		}
+		return strings.Compare(a.temp, b.temp)
+	})
+
+	allocation := Allocation{}
+	free := slices.Clone(registers)
+	var active []*interval
+	for _, current := range intervals {
+		// expire the intervals ended before this one starts
+		active = slices.DeleteFunc(active, func(s *interval) bool {
+			if s.end < current.start {
+				free = append(free, allocation[s.temp])
+				return true
+			}
+			return false
+		})
+		if len(free) > 0 {
+			allocation[current.temp], free = free[0], free[1:]
+			active = append(active, current)
+			continue
+		}
+		if len(active) == 0 {
+			continue
+		}
+		last := slices.MaxFunc(active, func(a, b *interval) int { return a.end - b.end })
+		if last.end > current.end {
+			allocation[current.temp] = allocation[last.temp]
+			delete(allocation, last.temp)
+			active = slices.DeleteFunc(active, func(s *interval) bool { return s == last })
+			active = append(active, current)
+		}
+	}
+	return allocation
+}
+
+// ColorGraph allocates registers in the style of Chaitin and Briggs by
+// coloring the interference graph, in which two temporaries are adjacent if
+// they are live at the same time. Nodes of fewer neighbors than registers are
+// removed first, when there are none the node of most neighbors is removed
+// optimistically. Nodes are then colored in the reverse order, and the ones
+// left without a color are spilled.
+func ColorGraph(code []string, registers []string) Allocation {
+	graph := map[string]Set[string]{}
+	node := func(t string) {
+		if _, ok := graph[t]; !ok {
+			graph[t] = NewSet[string]()
+		}
+	}
+	for i, live := range Liveness(code) {
+		def, uses := defUse(code[i])
+		for _, t := range uses {
+			node(t)
+		}
+		together := live.Copy()
+		if def != "" {
+			together.Add(def)
+		}
+		for a := range together {
+			node(a)
+			for b := range together {
+				if a != b {
+					graph[a].Add(b)
+				}
+			}
+		}
+	}
+
+	// simplify
+	removed := NewSet[string]()
+	degree := func(t string) int {
+		return graph[t].Difference(removed).Size()
+	}
+	nodes := make([]string, 0, len(graph))
+	for t := range graph {
+		nodes = append(nodes, t)
+	}
+	slices.Sort(nodes)
+	var stack []string
+	for len(stack) < len(nodes) {
+		pick := ""
+		for _, t := range nodes {
+			if !removed.Contains(t) && degree(t) < len(registers) {
+				pick = t
+				break
+			}
+		}
+		if pick == "" {
+			for _, t := range nodes {
+				if !removed.Contains(t) && (pick == "" || degree(t) > degree(pick)) {
+					pick = t
+				}
+			}
+		}
+		removed.Add(pick)
+		stack = append(stack, pick)
+	}
+
+	// select
+	allocation := Allocation{}
+	for i := len(stack) - 1; i >= 0; i-- {
+		t := stack[i]
+		used := NewSet[string]()
+		for n := range graph[t] {
+			if r, ok := allocation[n]; ok {
+				used.Add(r)
+			}
+		}
+		for _, r := range registers {
+			if !used.Contains(r) {
+				allocation[t] = r
+				break
+			}
+		}
+	}
+	return allocation
+}
+
+// Apply rewrites the code to use the registers in place of the temporaries.
+func (a Allocation) Apply(code []string) []string {
+	result := make([]string, len(code))
+	for i, line := range code {
+		result[i] = tempPattern.ReplaceAllStringFunc(line, func(t string) string {
+			if r, ok := a[t]; ok {
+				return r
+			}
+			return t
+		})
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package parser_test
+
+import (
+	"maps"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestAllocators(t *testing.T) {
+	// t1 is live before and after t2, but not while t2 is
+	code := []string{
+		"$(0x10000001) = a",
+		"b = $(0x10000001)",
+		"$(0x10000002) = c",
+		"d = $(0x10000002)",
+		"$(0x10000001) = e",
+		"f = $(0x10000001)",
+		"g = $(0x20000000)",
+	}
+	registers := []string{"r0"}
+	tests := []struct {
+		name      string
+		allocator Allocator
+		expected  Allocation
+	}{
+		{name: "Linear", allocator: LinearScan, expected: Allocation{"$(0x10000002)": "r0"}},
+		{name: "Color", allocator: ColorGraph, expected: Allocation{"$(0x10000001)": "r0", "$(0x10000002)": "r0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.allocator(code, registers); !maps.Equal(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	got := Allocators["color"](code, registers).Apply(code)
+	if got[1] != "b = r0" || got[6] != "g = $(0x20000000)" {
+		t.Errorf("Expected temporaries to be replaced by registers, got %v", got)
+	}
+}
+
+func TestColorGraph_Interference(t *testing.T) {
+	code := []string{
+		"$(0x10000001) = a",
+		"$(0x10000002) = b",
+		"if $(0x10000001) < $(0x10000002) goto L0",
+		"$(0x10000003) = $(0x10000001) + 1",
+		"c = $(0x10000003)",
+		"L0:",
+		"d = $(0x10000002)",
+	}
+	allocation := ColorGraph(code, Registers)
+	if allocation["$(0x10000001)"] == allocation["$(0x10000002)"] {
+		t.Errorf("Expected temporaries live at the same time to get different registers, got %v", allocation)
+	}
+	if allocation["$(0x10000003)"] == allocation["$(0x10000002)"] {
+		t.Errorf("Expected the temporary live across the branch to keep its register, got %v", allocation)
+	}
+}
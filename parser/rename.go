@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"app/lexer"
+)
+
+// referencesTo returns the identifiers naming the item, its declaration
+// included, in the order of the source.
+func (a *Analysis) referencesTo(item *SymbolTableItem) []Reference {
+	var result []Reference
+	for _, r := range a.Identifiers {
+		if r.Item == item {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// encloses checks if the scope is the inner one or one of its parents.
+func encloses(scope, inner *Scope) bool {
+	for s := inner; s != nil; s = s.Parent {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Rename returns the source with the item named by the identifier under the
+// position, lines counted from 0 and positions from 1, renamed to the name
+// everywhere it is referred to. It fails if the name is not an identifier,
+// or if it collides with another item: one declared with that name in the
+// same scope, one shadowing it where it is referred to, or one referred to by
+// that name in its scope, which would be shadowed by it.
+func (a *Analysis) Rename(line, pos int64, name string) (string, error) {
+	r, err := a.declaredAt(line, pos)
+	if err != nil {
+		return "", err
+	}
+	item, scope := r.Item, r.Scope
+	if scope.Level < 1 {
+		return "", fmt.Errorf("%s is a builtin and cannot be renamed", item.Variable)
+	}
+	if d := lexer.NewDocument(name); len(d.Errors) > 0 || len(d.Tokens) != 1 || d.Tokens[0].Type != lexer.IDENTIFIER || d.Tokens[0].Val != name || name == "new" {
+		return "", fmt.Errorf("%q is not a valid identifier", name)
+	}
+	if name == item.Variable {
+		return a.Document.Text, nil
+	}
+	at := func(token int) string {
+		line, pos := a.PositionOf(token)
+		return fmt.Sprintf("line %d, pos %d", line, pos)
+	}
+	references := a.referencesTo(item)
+	if _, ok := scope.Items[name]; ok {
+		return "", fmt.Errorf("%s is already declared in the scope of %s, declared at %s", name, item.Variable, at(references[0].Token))
+	}
+	for _, r := range references {
+		for s := r.Site; s != scope; s = s.Parent {
+			if _, ok := s.Items[name]; ok {
+				return "", fmt.Errorf("%s at %s would refer to the %s declared in between", item.Variable, at(r.Token), name)
+			}
+		}
+	}
+	for _, r := range a.Identifiers {
+		// a use of the name after the declaration, inside the scope, and of an outer item or none
+		if r.Name != name || r.Token < references[0].Token || !encloses(scope, r.Site) || r.Item != nil && encloses(scope, r.Scope) {
+			continue
+		}
+		return "", fmt.Errorf("%s at %s would refer to the renamed %s", name, at(r.Token), item.Variable)
+	}
+
+	text := a.Document.Text
+	var b strings.Builder
+	last := 0
+	for _, r := range references {
+		span := a.Document.Spans[r.Token]
+		b.WriteString(text[last:span.Start])
+		b.WriteString(name)
+		last = span.End
+	}
+	b.WriteString(text[last:])
+	return b.String(), nil
+}
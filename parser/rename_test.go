@@ -0,0 +1,46 @@
+package parser_test
+
+import (
+	"testing"
+)
+
+func TestAnalysis_Rename(t *testing.T) {
+	src := "{\n    int a, b;\n    a = 1;\n    {\n        int b;\n        b = a + 2;\n    }\n    {\n        a = abs(b);\n    }\n}\n"
+	a := sharedParser().Tables().Analyze(src)
+
+	renamed, err := a.Rename(2, 5, "x")
+	if err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	expected := "{\n    int x, b;\n    x = 1;\n    {\n        int b;\n        b = x + 2;\n    }\n    {\n        x = abs(b);\n    }\n}\n"
+	if renamed != expected {
+		t.Errorf("Expected every reference to a renamed, got\n%s", renamed)
+	}
+	// the inner b is renamed alone
+	renamed, err = a.Rename(4, 13, "c")
+	if err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	expected = "{\n    int a, b;\n    a = 1;\n    {\n        int c;\n        c = a + 2;\n    }\n    {\n        a = abs(b);\n    }\n}\n"
+	if renamed != expected {
+		t.Errorf("Expected the inner b to be renamed, got\n%s", renamed)
+	}
+
+	for _, tt := range []struct {
+		line, pos int64
+		name      string
+	}{
+		{1, 9, "b"},   // declared in the same scope
+		{1, 9, "int"}, // not an identifier
+		{1, 9, "a b"}, // not an identifier
+		{1, 10, "x"},  // no identifier under the position
+		{5, 17, "b"},  // the inner b would shadow it
+		{4, 13, "a"},  // the a read next to it would refer to it
+		{8, 13, "x"},  // a builtin
+		{1, 9, "abs"}, // abs would refer to it
+	} {
+		if _, err := a.Rename(tt.line, tt.pos, tt.name); err == nil {
+			t.Errorf("Rename(%d, %d, %q): expected an error", tt.line, tt.pos, tt.name)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// PruneScopes makes the symbol table drop the nested scopes once exited, see
+// SymbolTable.Prune, passing them to archive first if not nil. Of the locals
+// of the scopes dropped, the largest of each name is kept for the frame, see
+// Locals, as the frame gives a name a single slot, so the memory held grows
+// with the names of the locals rather than with the scopes. The reports
+// reading the scopes, such as the memory layout and the debug information,
+// only see those still kept, and a Rollback cannot take a scope back from
+// the archive.
+func (w *Walker) PruneScopes(archive func(*Scope) error) {
+	w.SymbolTable.Prune = true
+	if w.archived == nil {
+		w.archived = map[string]*SymbolTableItem{}
+	}
+	w.SymbolTable.Archive = func(scope *Scope) error {
+		if archive != nil {
+			if err := archive(scope); err != nil {
+				return err
+			}
+		}
+		for _, item := range scope.Items {
+			if !item.Static && (item.Type == SymbolTableItemTypeVariable || item.Type == SymbolTableItemTypeArray) {
+				w.archive(item)
+			}
+		}
+		return nil
+	}
+}
+
+// archive keeps the local for the frame if larger than the one of its name
+// kept, the first by address on a tie, whatever the order of the items.
+func (w *Walker) archive(item *SymbolTableItem) {
+	size := func(item *SymbolTableItem) int { return item.VariableSize * item.ArraySize }
+	if kept, ok := w.archived[item.Variable]; ok && cmp.Or(cmp.Compare(size(kept), size(item)), cmp.Compare(item.Address, kept.Address)) >= 0 {
+		return
+	}
+	w.archived[item.Variable] = item
+}
+
+// SortedItems returns the items of the scope in the order of their
+// addresses, then of their names, as every report lists them.
+func (scope *Scope) SortedItems() []*SymbolTableItem {
+	return slices.SortedFunc(maps.Values(scope.Items), func(a, b *SymbolTableItem) int {
+		return cmp.Or(cmp.Compare(a.Address, b.Address), strings.Compare(a.Variable, b.Variable))
+	})
+}
+
+// WriteScope writes the items of the scope, in the order of their addresses,
+// one per line with its type, its address and where it is declared.
+func WriteScope(out io.Writer, scope *Scope) error {
+	parent := -1
+	if scope.Parent != nil {
+		parent = scope.Parent.ID
+	}
+	if _, err := fmt.Fprintf(out, "Scope %d, level %d, in scope %d:\n", scope.ID, scope.Level, parent); err != nil {
+		return err
+	}
+	for _, item := range scope.SortedItems() {
+		typ := item.UnderlyingType
+		if item.Type == SymbolTableItemTypeArray {
+			typ += fmt.Sprintf("[%d]", item.ArraySize)
+		}
+		if item.Static {
+			typ = "static " + typ
+		}
+		if _, err := fmt.Fprintf(out, "    %s %s at 0x%x, declared at line %d, pos %d\n", item.Variable, typ, item.Address, item.Line, item.Pos); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(out)
+	return err
+}
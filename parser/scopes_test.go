@@ -0,0 +1,71 @@
+package parser_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestWalker_PruneScopes(t *testing.T) {
+	src := "{\n    int a;\n    {\n        int b, c[2];\n        b = 1;\n        c[1] = b;\n        {\n            static int d;\n            d = b;\n        }\n    }\n    a = 2;\n}\n"
+	tables := sharedParser().Tables()
+	kept, err := Compile(Options{Source: strings.NewReader(src), Tables: tables})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	var archived strings.Builder
+	pruned, err := Compile(Options{Source: strings.NewReader(src), Tables: tables, PruneScopes: true, Archive: func(scope *Scope) error {
+		return WriteScope(&archived, scope)
+	}})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	for _, scope := range pruned.Walker.SymbolTable.LegacyScopes {
+		if scope.Level > 1 {
+			t.Errorf("Expected the nested scope %d to be pruned", scope.ID)
+		}
+	}
+	expected := "Scope 3, level 3, in scope 2:\n" +
+		"    d static int at 0x10000004, declared at line 7, pos 24\n\n" +
+		"Scope 2, level 2, in scope 1:\n" +
+		"    b int at 0x10000001, declared at line 3, pos 13\n" +
+		"    c int[2] at 0x10000002, declared at line 3, pos 16\n\n"
+	if archived.String() != expected {
+		t.Errorf("Expected the scopes archived as\n%s\ngot\n%s", expected, archived.String())
+	}
+	if !slices.Equal(pruned.TAC, kept.TAC) || pruned.Frame.Locals != kept.Frame.Locals || pruned.Frame.Locals != 12 {
+		t.Errorf("Expected the locals of the scopes pruned in the frame, got\n%s", strings.Join(pruned.TAC, "\n"))
+	}
+
+	// a single local of each name is kept of the scopes pruned, the largest
+	siblings := "{\n    int a;\n    { int b; b = 1; }\n    { int b[4]; b[3] = 2; }\n    { float b; b = 3; }\n    { int c; c = 4; }\n}\n"
+	kept, err = Compile(Options{Source: strings.NewReader(siblings), Tables: tables})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	pruned, err = Compile(Options{Source: strings.NewReader(siblings), Tables: tables, PruneScopes: true})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	locals := pruned.Walker.Locals()
+	if len(locals) != 2 || locals[0].Variable != "b" || locals[0].ArraySize != 4 || locals[1].Variable != "c" {
+		t.Errorf("Expected b[4] and c kept, got %v", locals)
+	}
+	if len(kept.Walker.Locals()) != 4 || pruned.Frame.Locals != kept.Frame.Locals {
+		t.Errorf("Expected the frame of the locals kept alike, got %d bytes for %d", pruned.Frame.Locals, kept.Frame.Locals)
+	}
+
+	failed, err := Compile(Options{Source: strings.NewReader(src), Tables: tables, PruneScopes: true, Archive: func(*Scope) error {
+		return errors.New("disk full")
+	}})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if fatal, ok := failed.Fatal(); !ok || fatal.Message != "disk full, at line 9, pos 9" {
+		t.Errorf("Expected the archive to stop the parse, got %v", failed.Diagnostics)
+	}
+}
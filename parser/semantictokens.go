@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+
+	"app/lexer"
+)
+
+// SemanticTokensLegend lists the types and the modifiers of the semantic
+// tokens, the legend an LSP server announces: a token is encoded with the
+// index of its type and the bits of the indexes of its modifiers.
+var SemanticTokensLegend = struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}{
+	TokenTypes:     []string{"keyword", "type", "variable", "constant", "function", "namespace", "number", "string", "operator"},
+	TokenModifiers: []string{"declaration", "readonly", "static", "defaultLibrary"},
+}
+
+// SemanticToken is a token of the source classified for highlighting.
+type SemanticToken struct {
+	Location
+	Type      string   // one of SemanticTokensLegend.TokenTypes
+	Modifiers []string // some of SemanticTokensLegend.TokenModifiers
+}
+
+// SemanticTokens classifies the tokens of the source, in order, from their
+// lexical type and, for the identifiers, the item they resolve to: builtins
+// are functions, const variables are constants, module names are namespaces
+// and undeclared names are variables. Delimiters are left out.
+func (a *Analysis) SemanticTokens() []SemanticToken {
+	var result []SemanticToken
+	for i, token := range a.Document.Tokens {
+		t := SemanticToken{Location: a.LocationOf(i)}
+		switch token.Type {
+		case lexer.RESERVED:
+			t.Type = "keyword"
+		case lexer.TYPE:
+			t.Type = "type"
+		case lexer.INTEGER, lexer.FLOAT:
+			t.Type = "number"
+		case lexer.STRING, lexer.CHAR:
+			t.Type = "string"
+		case lexer.OPERATOR:
+			t.Type = "operator"
+		case lexer.IDENTIFIER:
+			t.Type, t.Modifiers = a.classify(i)
+		default:
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// classify returns the type and the modifiers of the identifier at the token.
+func (a *Analysis) classify(token int) (string, []string) {
+	r, ok := a.ReferenceAt(token)
+	if !ok {
+		// the names of modules, declared or qualifying a global, are no references
+		return "namespace", nil
+	}
+	if r.Item == nil {
+		return "variable", nil
+	}
+	var modifiers []string
+	if r.Declaration {
+		modifiers = append(modifiers, "declaration")
+	}
+	if r.Item.Type == SymbolTableItemTypeBuiltin {
+		return "function", append(modifiers, "defaultLibrary")
+	}
+	if r.Item.Type == SymbolTableItemTypeFunction {
+		return "function", modifiers
+	}
+	if r.Item.Static {
+		modifiers = append(modifiers, "static")
+	}
+	if r.Item.Const {
+		return "constant", append(modifiers, "readonly")
+	}
+	return "variable", modifiers
+}
+
+// EncodeSemanticTokens encodes the tokens the way LSP's
+// textDocument/semanticTokens answers them: five integers per token, its
+// line and start relative to the previous token, its length, the index of
+// its type and the bits of its modifiers in SemanticTokensLegend. Starts
+// are counted from 0, in bytes.
+func EncodeSemanticTokens(tokens []SemanticToken) []uint32 {
+	data := make([]uint32, 0, 5*len(tokens))
+	line, start := int64(0), int64(0)
+	for _, t := range tokens {
+		if t.Line != line {
+			start = 0
+		}
+		modifiers := 0
+		for _, m := range t.Modifiers {
+			modifiers |= 1 << slices.Index(SemanticTokensLegend.TokenModifiers, m)
+		}
+		data = append(data, uint32(t.Line-line), uint32(t.Pos-1-start), uint32(t.Length),
+			uint32(slices.Index(SemanticTokensLegend.TokenTypes, t.Type)), uint32(modifiers))
+		line, start = t.Line, t.Pos-1
+	}
+	return data
+}
+
+// WriteSemanticTokens writes the tokens encoded with the legend as JSON, for
+// a client to decode them.
+func WriteSemanticTokens(w io.Writer, tokens []SemanticToken) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Legend any      `json:"legend"`
+		Data   []uint32 `json:"data"`
+	}{SemanticTokensLegend, EncodeSemanticTokens(tokens)})
+}
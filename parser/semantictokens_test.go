@@ -0,0 +1,36 @@
+package parser_test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestAnalysis_SemanticTokens(t *testing.T) {
+	src := "module geo;\n{\n    const int k = 3;\n    static float s;\n    s = abs(k) + geo.s * 1.5;\n    printf(\"%d\", undeclared);\n}\n"
+	tokens := sharedParser().Tables().Analyze(src).SemanticTokens()
+	var got []string
+	for _, token := range tokens {
+		got = append(got, strings.TrimSpace(fmt.Sprintf("%d:%d:%d %s %s", token.Line, token.Pos, token.Length, token.Type, strings.Join(token.Modifiers, ","))))
+	}
+	expected := []string{
+		"0:1:6 keyword", "0:8:3 namespace",
+		"2:5:5 keyword", "2:11:3 type", "2:15:1 constant declaration,readonly", "2:17:1 operator", "2:19:1 number",
+		"3:5:6 keyword", "3:12:5 type", "3:18:1 variable declaration,static",
+		"4:5:1 variable static", "4:7:1 operator", "4:9:3 function defaultLibrary", "4:13:1 constant readonly", "4:16:1 operator",
+		"4:18:3 namespace", "4:22:1 variable static", "4:24:1 operator", "4:26:3 number",
+		"5:5:6 function defaultLibrary", "5:12:4 string", "5:18:10 variable",
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// module geo, then const on the third line with the bits of declaration and readonly
+	data := EncodeSemanticTokens(tokens)
+	if len(data) != 5*len(tokens) || !slices.Equal(data[:15], []uint32{0, 0, 6, 0, 0, 0, 7, 3, 5, 0, 2, 4, 5, 0, 0}) || !slices.Equal(data[20:25], []uint32{0, 4, 1, 3, 3}) {
+		t.Errorf("Expected the tokens encoded relative to each other, got %v", data)
+	}
+}
@@ -0,0 +1,214 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	. "app/utils/collections"
+	"app/utils/report"
+)
+
+// grammarSets are the FIRST and FOLLOW sets of the nonterminals of a
+// grammar and those deriving ε, computed together once by computeSets.
+type grammarSets struct {
+	first    map[Symbol]*OrderedSet[Terminal]
+	follow   map[Symbol]*OrderedSet[Terminal]
+	nullable Set[Symbol]
+}
+
+// setsMu guards the sets of every grammar, so that the sessions sharing the
+// tables may ask for them at once.
+var setsMu sync.Mutex
+
+// ensureSets returns the sets of the grammar, computing them on the first call.
+// A grammar changed afterwards keeps the sets computed before, so it is to be
+// changed on a copy, which computes its own.
+func (g *Grammar) ensureSets() *grammarSets {
+	setsMu.Lock()
+	defer setsMu.Unlock()
+	if g.sets == nil {
+		g.sets = g.computeSets()
+	}
+	return g.sets
+}
+
+// Start returns the start symbol of the grammar, the body of the augmented
+// production, or the head of the first production if there is none.
+func (g *Grammar) Start() Symbol {
+	if len(g.AugmentedProduction.Body) > 0 {
+		return g.AugmentedProduction.Body[0]
+	}
+	if len(g.Productions) > 0 {
+		return g.Productions[0].Head
+	}
+	return ""
+}
+
+// computeSets computes the nullable nonterminals, then the FIRST and the
+// FOLLOW sets, each as a fixed point over the productions. The sets are
+// sorted by the names of the terminals, as those of BuildFirstSet are.
+func (g *Grammar) computeSets() *grammarSets {
+	s := &grammarSets{first: map[Symbol]*OrderedSet[Terminal]{}, follow: map[Symbol]*OrderedSet[Terminal]{}, nullable: NewSet[Symbol]()}
+	for _, production := range g.Productions {
+		s.first[production.Head] = NewSortedSet(cmp.Compare[Terminal])
+		s.follow[production.Head] = NewSortedSet(cmp.Compare[Terminal])
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, production := range g.Productions {
+			if !s.nullable.Contains(production.Head) && s.nullableBody(production.Body) {
+				s.nullable.Add(production.Head)
+				changed = true
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, production := range g.Productions {
+			first := s.first[production.Head]
+			size := first.Size()
+			for terminal := range s.firstOf(production.Body).All() {
+				if !terminal.IsEpsilon() {
+					first.Add(terminal)
+				}
+			}
+			changed = changed || first.Size() != size
+		}
+	}
+	for head := range s.nullable {
+		s.first[head].Add(EPSILON)
+	}
+
+	if start := g.Start(); s.follow[start] != nil {
+		s.follow[start].Add(TERMINATE)
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, production := range g.Productions {
+			for i, symbol := range production.Body {
+				follow, ok := s.follow[symbol]
+				if !ok {
+					continue
+				}
+				size := follow.Size()
+				rest := s.firstOf(production.Body[i+1:])
+				for terminal := range rest.All() {
+					if !terminal.IsEpsilon() {
+						follow.Add(terminal)
+					}
+				}
+				if rest.Contains(EPSILON) {
+					follow.AddAll(s.follow[production.Head].Elements()...)
+				}
+				changed = changed || follow.Size() != size
+			}
+		}
+	}
+	return s
+}
+
+// nullableBody checks if every symbol of the body derives ε, as an empty body does.
+func (s *grammarSets) nullableBody(body []Symbol) bool {
+	for _, symbol := range body {
+		if !symbol.IsEpsilon() && !s.nullable.Contains(symbol) {
+			return false
+		}
+	}
+	return true
+}
+
+// firstOf returns the FIRST set of the symbols as computed so far, with ε if
+// they all derive it.
+func (s *grammarSets) firstOf(symbols []Symbol) *OrderedSet[Terminal] {
+	first := NewSortedSet(cmp.Compare[Terminal])
+	for _, symbol := range symbols {
+		if symbol.IsEpsilon() {
+			continue
+		}
+		set, ok := s.first[symbol]
+		if !ok {
+			first.Add(Terminal(symbol))
+			return first
+		}
+		for terminal := range set.All() {
+			if !terminal.IsEpsilon() {
+				first.Add(terminal)
+			}
+		}
+		if !s.nullable.Contains(symbol) {
+			return first
+		}
+	}
+	return first.Add(EPSILON)
+}
+
+// First returns the FIRST set of the symbol: the terminals the strings it
+// derives start with, and ε if it derives the empty string. The FIRST set of
+// a terminal is the terminal itself. The set is a copy, sorted by the names
+// of the terminals.
+func (g *Grammar) First(symbol Symbol) *OrderedSet[Terminal] {
+	s := g.ensureSets()
+	if first, ok := s.first[symbol]; ok {
+		return first.Copy()
+	}
+	return NewSortedSet(cmp.Compare[Terminal]).Add(Terminal(symbol))
+}
+
+// FirstOf returns the FIRST set of a string of symbols, such as the rest of a
+// body, with ε if every symbol derives it, as the empty string does.
+func (g *Grammar) FirstOf(symbols ...Symbol) *OrderedSet[Terminal] {
+	return g.ensureSets().firstOf(symbols)
+}
+
+// Follow returns the FOLLOW set of the nonterminal: the terminals that can
+// come right after it in a sentential form, with $ for the end of input. The
+// set is a copy, sorted by the names of the terminals, and empty for a symbol
+// that heads no production.
+func (g *Grammar) Follow(nonterminal Symbol) *OrderedSet[Terminal] {
+	if follow, ok := g.ensureSets().follow[nonterminal]; ok {
+		return follow.Copy()
+	}
+	return NewSortedSet(cmp.Compare[Terminal])
+}
+
+// Nullable checks if the nonterminal derives the empty string.
+func (g *Grammar) Nullable(nonterminal Symbol) bool {
+	return g.ensureSets().nullable.Contains(nonterminal)
+}
+
+// WriteSets writes the FIRST and FOLLOW sets of the nonterminals in the order
+// of their first productions, a nonterminal per line, marked nullable if it
+// derives ε, as the lab hands them in:
+//
+//	E       FIRST = { (, id }       FOLLOW = { ), $ }
+//	E'      FIRST = { +, ε }        FOLLOW = { ), $ }       nullable
+func (g *Grammar) WriteSets(w io.Writer) error {
+	var t report.Table
+	for _, head := range g.Heads() {
+		nullable := ""
+		if g.Nullable(head) {
+			nullable = "nullable"
+		}
+		t.Row(string(head), "FIRST = "+setString(g.First(head)), "FOLLOW = "+setString(g.Follow(head)), nullable)
+	}
+	return t.Write(w)
+}
+
+// setString writes the terminals of the set split by commas, between braces,
+// in the order of the columns of the tables.
+func setString(set *OrderedSet[Terminal]) string {
+	terminals := make([]string, 0, set.Size())
+	for terminal := range set.All() {
+		terminals = append(terminals, string(terminal))
+	}
+	if len(terminals) == 0 {
+		return "{ }"
+	}
+	report.SortSymbols(terminals)
+	return fmt.Sprintf("{ %s }", strings.Join(terminals, ", "))
+}
@@ -0,0 +1,104 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+func TestGrammar_Sets(t *testing.T) {
+	g := &Grammar{
+		Productions: []Production{
+			{Head: "E", Body: []Symbol{"T", "E'"}},
+			{Head: "E'", Body: []Symbol{"+", "T", "E'"}},
+			{Head: "E'", Body: []Symbol{EPSILON}},
+			{Head: "T", Body: []Symbol{"F", "T'"}},
+			{Head: "T'", Body: []Symbol{"*", "F", "T'"}},
+			{Head: "T'", Body: []Symbol{}},
+			{Head: "F", Body: []Symbol{"(", "E", ")"}},
+			{Head: "F", Body: []Symbol{"id"}},
+		},
+		Terminals: NewSet[Terminal]().AddAll("id", "+", "*", "(", ")", EPSILON),
+	}
+	for _, test := range []struct {
+		symbol   Symbol
+		first    []Terminal
+		follow   []Terminal
+		nullable bool
+	}{
+		{"E", []Terminal{"(", "id"}, []Terminal{"$", ")"}, false},
+		{"E'", []Terminal{"+", EPSILON}, []Terminal{"$", ")"}, true},
+		{"T", []Terminal{"(", "id"}, []Terminal{"$", ")", "+"}, false},
+		{"T'", []Terminal{"*", EPSILON}, []Terminal{"$", ")", "+"}, true},
+		{"F", []Terminal{"(", "id"}, []Terminal{"$", ")", "*", "+"}, false},
+	} {
+		if first := g.First(test.symbol).Elements(); !slices.Equal(first, test.first) {
+			t.Errorf("Expected FIRST(%s) = %v, got %v", test.symbol, test.first, first)
+		}
+		if follow := g.Follow(test.symbol).Elements(); !slices.Equal(follow, test.follow) {
+			t.Errorf("Expected FOLLOW(%s) = %v, got %v", test.symbol, test.follow, follow)
+		}
+		if nullable := g.Nullable(test.symbol); nullable != test.nullable {
+			t.Errorf("Expected %s nullable %v, got %v", test.symbol, test.nullable, nullable)
+		}
+	}
+	if first := g.First("+").Elements(); !slices.Equal(first, []Terminal{"+"}) {
+		t.Errorf("Expected the FIRST set of a terminal to be itself, got %v", first)
+	}
+	if first := g.FirstOf("E'", "T'").Elements(); !slices.Equal(first, []Terminal{"*", "+", EPSILON}) {
+		t.Errorf("Expected FIRST(E' T') = {*, +, ε}, got %v", first)
+	}
+	if first := g.FirstOf("T'", ")").Elements(); !slices.Equal(first, []Terminal{")", "*"}) {
+		t.Errorf("Expected FIRST(T' )) = {), *}, got %v", first)
+	}
+
+	// the sets returned are copies of the cached ones
+	g.First("E").Add("x")
+	g.Follow("E").Add("x")
+	if g.First("E").Contains("x") || g.Follow("E").Contains("x") {
+		t.Errorf("Expected the cached sets unchanged by the caller")
+	}
+
+	var b strings.Builder
+	if err := g.WriteSets(&b); err != nil {
+		t.Fatalf("WriteSets: %v", err)
+	}
+	expected := "" +
+		"E   FIRST = { (, id }  FOLLOW = { ), $ }\n" +
+		"E'  FIRST = { +, ε }   FOLLOW = { ), $ }        nullable\n" +
+		"T   FIRST = { (, id }  FOLLOW = { ), +, $ }\n" +
+		"T'  FIRST = { *, ε }   FOLLOW = { ), +, $ }     nullable\n" +
+		"F   FIRST = { (, id }  FOLLOW = { ), *, +, $ }\n"
+	if b.String() != expected {
+		t.Errorf("Expected the sets\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+// TestGrammar_Sets_Default checks the sets of the grammar of the experiment
+// against the FIRST sets the parser builds its lookaheads from.
+func TestGrammar_Sets_Default(t *testing.T) {
+	g := NewGrammar()
+	p := sharedParser()
+	for _, head := range g.Heads() {
+		if !g.First(head).Equal(p.FirstSet[head]) {
+			t.Errorf("Expected FIRST(%s) = %v, got %v", head, p.FirstSet[head], g.First(head))
+		}
+		if g.Nullable(head) != g.First(head).Contains(EPSILON) {
+			t.Errorf("Expected %s nullable if its FIRST set has ε", head)
+		}
+	}
+	if follow := g.Follow(g.Start()); !follow.Contains(TERMINATE) {
+		t.Errorf("Expected $ in FOLLOW(%s), got %v", g.Start(), follow)
+	}
+	if follow := g.Follow("stmt"); !follow.Contains("}") {
+		t.Errorf("Expected } in FOLLOW(stmt), got %v", follow)
+	}
+	copied := g.Copy()
+	copied.Productions = append(copied.Productions, Production{Head: "stmt", Body: []Symbol{"@"}})
+	if !copied.First("stmt").Contains("@") || g.First("stmt").Contains("@") {
+		t.Errorf("Expected a copy to compute its own sets")
+	}
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// SourceMap maps the assembly, the code of Result.TAC in registers and laid
+// out in the frame, back to the three-address code as generated, and that
+// back to the source, so that a bug of the optimizer can be traced to the
+// instruction it broke and a debugger can show the three side by side.
+type SourceMap struct {
+	File     string         `json:"file"`
+	Assembly []AssemblyLine `json:"assembly"`
+	TAC      []TACLine      `json:"tac"`
+}
+
+// AssemblyLine is an instruction of the assembly with the one of the
+// three-address code it comes from.
+type AssemblyLine struct {
+	Code string `json:"code"`
+	TAC  int64  `json:"tac"` // index in SourceMap.TAC, -1 for the code of the frame
+}
+
+// TACLine is an instruction of the three-address code with where the
+// construct emitting it is.
+type TACLine struct {
+	Code string `json:"code"`
+	Span *Span  `json:"span,omitempty"` // nil if the construct matches no token
+}
+
+// NewSourceMap returns the source map of the code compiled for the file.
+func NewSourceMap(file string, r *Result) *SourceMap {
+	m := &SourceMap{File: file, Assembly: []AssemblyLine{}, TAC: []TACLine{}}
+	for i, code := range r.TAC {
+		origin := int64(-1)
+		if i < len(r.Origins) {
+			origin = r.Origins[i]
+		}
+		m.Assembly = append(m.Assembly, AssemblyLine{Code: code, TAC: origin})
+	}
+	for i, code := range r.Walker.ThreeAddress {
+		line := TACLine{Code: code}
+		if i < len(r.Walker.Spans) && r.Walker.Spans[i] != (Span{}) {
+			span := r.Walker.Spans[i]
+			line.Span = &span
+		}
+		m.TAC = append(m.TAC, line)
+	}
+	return m
+}
+
+// Lookup returns the instruction of the three-address code the one of the
+// assembly comes from, and where its construct is, ok is false for the code
+// of the frame and the instructions of no construct.
+func (m *SourceMap) Lookup(assembly int) (tac int64, span Span, ok bool) {
+	if assembly < 0 || assembly >= len(m.Assembly) {
+		return -1, Span{}, false
+	}
+	tac = m.Assembly[assembly].TAC
+	if tac < 0 || tac >= int64(len(m.TAC)) || m.TAC[tac].Span == nil {
+		return tac, Span{}, false
+	}
+	return tac, *m.TAC[tac].Span, true
+}
+
+// WriteJSON writes the source map to the writer.
+func (m *SourceMap) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
@@ -0,0 +1,66 @@
+package parser_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestSourceMap(t *testing.T) {
+	result, err := Compile(Options{
+		Source: strings.NewReader("{\n    int a, b;\n    a = 1;\n    b = a * 2 + 3;\n}\n"),
+		Tables: sharedParser().Tables(),
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if len(result.Origins) != len(result.TAC) || len(result.Walker.Spans) != len(result.Walker.ThreeAddress) {
+		t.Fatalf("Expected an origin per instruction and a span per TAC, got %d for %d and %d for %d",
+			len(result.Origins), len(result.TAC), len(result.Walker.Spans), len(result.Walker.ThreeAddress))
+	}
+	m := NewSourceMap("test.in", result)
+	if m.Assembly[0].Code != "main:" || m.Assembly[0].TAC != -1 {
+		t.Errorf("Expected the prologue to come from no TAC, got %v", m.Assembly[0])
+	}
+	if _, _, ok := m.Lookup(0); ok {
+		t.Errorf("Expected no source for the prologue")
+	}
+
+	found := 0
+	for i, line := range m.Assembly {
+		tac, span, ok := m.Lookup(i)
+		switch {
+		case line.Code == "a = 1":
+			found++
+			if !ok || m.TAC[tac].Code != "a = 1" || span.Line != 2 || span.EndLine != 2 {
+				t.Errorf("Expected a = 1 to come from line 2, got TAC %d at %v", tac, span)
+			}
+		case strings.HasSuffix(line.Code, " = 1 * 2 + 3"):
+			// the constant propagated into the expression is traced back to it
+			found++
+			if !ok || !strings.HasSuffix(m.TAC[tac].Code, " = a * 2 + 3") || span.Line != 3 {
+				t.Errorf("Expected %s to come from a * 2 + 3 on line 3, got TAC %d at %v", line.Code, tac, span)
+			}
+			if result.Lines[i] != 3 {
+				t.Errorf("Expected the debug line of %s to be 3, got %d", line.Code, result.Lines[i])
+			}
+		}
+	}
+	if found != 2 {
+		t.Errorf("Expected the two instructions of the assignments, got %v", m.Assembly)
+	}
+
+	var sb strings.Builder
+	if err := m.WriteJSON(&sb); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var decoded SourceMap
+	if err := json.Unmarshal([]byte(sb.String()), &decoded); err != nil || len(decoded.TAC) != len(m.TAC) || decoded.TAC[0].Span == nil {
+		t.Errorf("Expected the map to survive JSON, got %v, %v", decoded, err)
+	}
+	if !strings.Contains(sb.String(), `"endLine": 2`) {
+		t.Errorf("Expected the spans in the JSON, got %s", sb.String())
+	}
+}
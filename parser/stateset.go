@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// stateShards is the number of locks of a stateSet, so that the workers
+// finding kernels rarely wait for one another.
+const stateShards = 64
+
+// stateSet finds the states of the automaton by their kernels, the items
+// GOTO moves the dot over, which decide the closure and so the state: two
+// kernels are the same state if they hold the same items, in whatever
+// order, so they are hashed and compared in a canonical order, that of the
+// productions in the grammar, the dots and the lookaheads. It is safe for
+// concurrent use by the workers of BuildStates.
+type stateSet struct {
+	productions map[string]int // the index of each production by its head and body
+	bodies      map[body]int   // the same by the array of its body, shared by its items
+	shards      [stateShards]struct {
+		sync.Mutex
+		entries map[uint64][]*stateEntry
+	}
+}
+
+// stateEntry is a kernel of the set, with the state it is, nil until the
+// state is numbered
+type stateEntry struct {
+	mu        sync.Mutex
+	canonical []itemKey
+	kernel    LR1Items // the kernel as first found, which the state is closed from
+	rank      int      // where it was first found, the lowest wins
+	state     *State
+}
+
+// body is a body of a production by its array, which the items of the
+// production share, so that an item finds its production with no key built
+type body struct {
+	head  Symbol
+	first *Symbol
+	n     int
+}
+
+func bodyOf(production Production) body {
+	if len(production.Body) == 0 {
+		return body{head: production.Head}
+	}
+	return body{production.Head, &production.Body[0], len(production.Body)}
+}
+
+// itemKey is an item of a kernel in the canonical order
+type itemKey struct {
+	production, dot int
+	lookahead       Terminal
+}
+
+func newStateSet(g *Grammar) *stateSet {
+	s := &stateSet{productions: map[string]int{}, bodies: map[body]int{}}
+	for i, production := range append([]Production{g.AugmentedProduction}, g.Productions...) {
+		if _, ok := s.productions[productionKey(production)]; !ok {
+			s.productions[productionKey(production)] = i
+		}
+		s.bodies[bodyOf(production)] = s.productions[productionKey(production)]
+	}
+	for i := range s.shards {
+		s.shards[i].entries = map[uint64][]*stateEntry{}
+	}
+	return s
+}
+
+func productionKey(production Production) string {
+	var b strings.Builder
+	b.WriteString(string(production.Head))
+	for _, symbol := range production.Body {
+		b.WriteByte(0)
+		b.WriteString(string(symbol))
+	}
+	return b.String()
+}
+
+// find returns the entry of the kernel, adding it if it is new. The kernel
+// found at the lowest rank is the one the entry keeps, whichever worker gets
+// there first, so that the items of the state are in the same order on every
+// run; the kernel is copied, and may be a buffer of the caller.
+func (s *stateSet) find(a *arena, kernel LR1Items, rank int) *stateEntry {
+	canonical := a.canonical[:0]
+	for _, item := range kernel {
+		production, ok := s.bodies[bodyOf(item.Production)]
+		if !ok {
+			production = s.productions[productionKey(item.Production)]
+		}
+		canonical = append(canonical, itemKey{production, item.Dot, item.Lookahead})
+	}
+	slices.SortFunc(canonical, func(x, y itemKey) int {
+		return cmp.Or(cmp.Compare(x.production, y.production), cmp.Compare(x.dot, y.dot), cmp.Compare(x.lookahead, y.lookahead))
+	})
+	canonical = slices.Compact(canonical)
+	a.canonical = canonical
+
+	// FNV-1a over the items
+	hash := uint64(14695981039346656037)
+	mix := func(b byte) { hash = (hash ^ uint64(b)) * 1099511628211 }
+	for _, key := range canonical {
+		for _, n := range []int{key.production, key.dot} {
+			for ; n > 0x7f; n >>= 7 {
+				mix(byte(n) | 0x80)
+			}
+			mix(byte(n))
+		}
+		for i := 0; i < len(key.lookahead); i++ {
+			mix(key.lookahead[i])
+		}
+		mix(0)
+	}
+
+	shard := &s.shards[hash%stateShards]
+	shard.Lock()
+	var entry *stateEntry
+	for _, e := range shard.entries[hash] {
+		if slices.Equal(e.canonical, canonical) {
+			entry = e
+			break
+		}
+	}
+	if entry == nil {
+		entry = &stateEntry{canonical: slices.Clone(canonical), kernel: slices.Clone(kernel), rank: rank}
+		shard.entries[hash] = append(shard.entries[hash], entry)
+		shard.Unlock()
+		return entry
+	}
+	shard.Unlock()
+
+	entry.mu.Lock()
+	if rank < entry.rank && entry.state == nil {
+		entry.kernel, entry.rank = slices.Clone(kernel), rank
+	}
+	entry.mu.Unlock()
+	return entry
+}
+
+// parallel calls f for 0 to n-1 on the workers, each call with the number
+// of the worker making it, stopping early once the context is done
+func parallel(ctx context.Context, workers, n int, f func(worker, i int)) error {
+	if n < workers {
+		workers = n
+	}
+	var next int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				mu.Lock()
+				i := next
+				next++
+				mu.Unlock()
+				if i >= n {
+					return
+				}
+				f(w, i)
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	. "app/utils/collections"
+)
+
+// StateStats is the size of a state of the automaton and the conflicts in
+// its row of the action table, counted as conflicting cells.
+type StateStats struct {
+	State        int
+	Items        int
+	Incoming     int // transitions into the state
+	ShiftReduce  int
+	ReduceReduce int
+}
+
+// Heat is how many conflicting cells a nonterminal takes part in, as the head
+// of a production reduced or shifted through in the cell.
+type Heat struct {
+	Nonterminal Symbol
+	Conflicts   int
+}
+
+// AutomatonStats is the report of the states of the automaton, with the
+// conflicts aggregated over the nonterminals to tell where the grammar needs
+// work first.
+type AutomatonStats struct {
+	States  []StateStats
+	HeatMap []Heat // the hottest first
+}
+
+// AutomatonStats returns the report of the states of the parser.
+func (p *Parser) AutomatonStats() *AutomatonStats {
+	p.EnsureStates()
+	s := &AutomatonStats{States: make([]StateStats, len(p.States))}
+	heat := map[Symbol]int{}
+	for i, state := range p.States {
+		s.States[i].State, s.States[i].Items = state.Index, len(state.Items)
+		for _, next := range state.Transitions {
+			s.States[next.Index].Incoming++
+		}
+
+		shifts := map[Terminal]Set[Symbol]{}
+		reduces := map[Terminal]map[int]Symbol{}
+		for _, item := range state.Items {
+			if item.Dot == len(item.Production.Body) || item.Production.Body[item.Dot].IsEpsilon() {
+				if item.Production.Equals(p.Grammar.AugmentedProduction) {
+					continue
+				}
+				if reduces[item.Lookahead] == nil {
+					reduces[item.Lookahead] = map[int]Symbol{}
+				}
+				reduces[item.Lookahead][p.Grammar.GetIndex(item.Production)] = item.Production.Head
+			} else if terminal, ok := p.Grammar.Terminal(item.Production.Body[item.Dot]); ok {
+				if shifts[terminal] == nil {
+					shifts[terminal] = Set[Symbol]{}
+				}
+				shifts[terminal].Add(item.Production.Head)
+			}
+		}
+		for terminal, productions := range reduces {
+			if len(shifts[terminal]) == 0 && len(productions) < 2 {
+				continue
+			}
+			if len(shifts[terminal]) > 0 {
+				s.States[i].ShiftReduce++
+			}
+			if len(productions) > 1 {
+				s.States[i].ReduceReduce++
+			}
+			involved := Set[Symbol]{}.AddAll(slices.Collect(maps.Values(productions))...)
+			for head := range shifts[terminal] {
+				involved.Add(head)
+			}
+			for head := range involved {
+				heat[head]++
+			}
+		}
+	}
+	for nonterminal, conflicts := range heat {
+		s.HeatMap = append(s.HeatMap, Heat{Nonterminal: nonterminal, Conflicts: conflicts})
+	}
+	slices.SortFunc(s.HeatMap, func(a, b Heat) int {
+		return cmp.Or(b.Conflicts-a.Conflicts, strings.Compare(string(a.Nonterminal), string(b.Nonterminal)))
+	})
+	return s
+}
+
+// Write writes the report to the writer, a row per state and the heat map
+// drawn with bars as long as the conflicts of each nonterminal.
+func (s *AutomatonStats) Write(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	printf("%-8s %6s %9s %4s %4s\n", "State", "Items", "Incoming", "S/R", "R/R")
+	for _, state := range s.States {
+		printf("%-8s %6d %9d %4d %4d\n", fmt.Sprintf("I%d", state.State), state.Items, state.Incoming, state.ShiftReduce, state.ReduceReduce)
+	}
+	printf("\nConflict heat map:\n")
+	if len(s.HeatMap) == 0 {
+		printf("    no conflicts\n")
+		return err
+	}
+	width := 0
+	for _, h := range s.HeatMap {
+		width = max(width, len(h.Nonterminal))
+	}
+	hottest := s.HeatMap[0].Conflicts
+	for _, h := range s.HeatMap {
+		// bars are at most 40 wide, and at least 1
+		bar := max(1, h.Conflicts*40/hottest)
+		printf("    %-*s %5d %s\n", width, h.Nonterminal, h.Conflicts, strings.Repeat("#", bar))
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+func TestParser_AutomatonStats(t *testing.T) {
+	// the dangling else, S → i S | i S e S | a
+	p := &Parser{
+		Grammar: &Grammar{
+			AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
+			Productions: []Production{
+				{Head: "S", Body: []Symbol{"i", "S"}},
+				{Head: "S", Body: []Symbol{"i", "S", "e", "S"}},
+				{Head: "S", Body: []Symbol{"a"}},
+			},
+			Terminals: Set[Terminal]{}.AddAll("i", "e", "a", EPSILON, TERMINATE),
+		},
+	}
+	p.BuildFirstSet()
+	p.BuildTable()
+	stats := p.AutomatonStats()
+	if len(stats.States) != len(p.States) {
+		t.Fatalf("Expected a row per state, got %d for %d states", len(stats.States), len(p.States))
+	}
+	conflicts, transitions, incoming := 0, 0, 0
+	for i, state := range stats.States {
+		conflicts += state.ShiftReduce
+		incoming += state.Incoming
+		transitions += len(p.States[i].Transitions)
+		if state.State != i || state.Items != len(p.States[i].Items) || state.ReduceReduce != 0 {
+			t.Errorf("Unexpected row %+v", state)
+		}
+	}
+	if conflicts == 0 || conflicts > p.Table.ShiftReduceConflicts || incoming != transitions || stats.States[0].Incoming != 0 {
+		t.Errorf("Expected the shift/reduce conflicts on e and every transition counted once, got %+v", stats.States)
+	}
+	if len(stats.HeatMap) != 1 || stats.HeatMap[0].Nonterminal != "S" || stats.HeatMap[0].Conflicts != conflicts {
+		t.Errorf("Expected S to take part in every conflict, got %v", stats.HeatMap)
+	}
+
+	b := &strings.Builder{}
+	if err := stats.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "State") || !strings.Contains(b.String(), "Conflict heat map:\n    S ") {
+		t.Errorf("Unexpected report:\n%s", b)
+	}
+}
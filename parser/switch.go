@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+
+	"app/lexer"
+)
+
+// Checks are the optional checks of the analysis, off by default.
+type Checks struct {
+	SwitchDefault bool // warns about a switch without a default case
+	NoFallthrough bool // forbids a case to fall through into the next one
+}
+
+// CasesAppend handles cases → cases case_clause, which appends the clause to
+// the list started by cases → case_clause.
+func CasesAppend(c *ReduceContext) error {
+	list, clause := c.Nodes[0], c.Nodes[1]
+	list.raw = fmt.Sprintf("%s %s", list.raw, clause.raw)
+	list.Children = append(list.Children, clause)
+	c.Result = list
+	return nil
+}
+
+// breaks checks if the statement is a break, or a block ending with one.
+func breaks(stmt *ASTNode) bool {
+	if token := firstToken(stmt); token == nil || token.Val != "break" && token.Val != "{" {
+		return false
+	} else if token.Val == "break" {
+		return true
+	}
+	for i := len(stmt.Children) - 1; i >= 0; i-- {
+		if stmts := stmt.Children[i]; stmts.Type == "stmts" {
+			return len(stmts.Children) == 2 && breaks(stmts.Children[1])
+		}
+	}
+	return false
+}
+
+// Switch handles matched_stmt → switch ( bool ) { cases }. The value switched
+// on must be an integer, and so must the value of each case, a constant once
+// folded. Two cases of the same value, or two default cases, are errors. The
+// Checks of the session report a switch without a default case, and forbid a
+// case with statements to fall through into the next one, i.e. not to end with
+// a break. Once checked, the switch jumps to the case of the value, see
+// emitSwitch.
+func Switch(c *ReduceContext) error {
+	w := c.Walker
+	value, cases := c.Nodes[2], c.Nodes[5].Children
+	if t := w.TypeOf(value); t != lexer.Unknown && !IsIntegral(t) {
+		return c.Errorf("cannot switch on %s (%s), an integer is required", value.raw, t.ToString())
+	}
+
+	var errs []error
+	seen := map[int64]*lexer.Token{}
+	var deflt *lexer.Token
+	for i, clause := range cases {
+		label := clause.Children[0].Token
+		body := clause.Children[len(clause.Children)-1]
+		if w.Checks.NoFallthrough && i < len(cases)-1 && len(body.Children) == 2 && !breaks(body.Children[1]) {
+			errs = append(errs, fmt.Errorf("%s falls through into the next case, at line %d, pos %d",
+				caseName(clause), label.Line, label.Pos))
+		}
+
+		if label.Val == "default" {
+			if deflt != nil {
+				errs = append(errs, fmt.Errorf("multiple default cases, the first at line %d, pos %d, at line %d, pos %d",
+					deflt.Line, deflt.Pos, label.Line, label.Pos))
+			}
+			deflt = label
+			continue
+		}
+		constant := clause.Children[1]
+		v, ok := IntLiteral(constant)
+		if !ok {
+			errs = append(errs, fmt.Errorf("case %s is not an integer constant, at line %d, pos %d", constant.raw, label.Line, label.Pos))
+			continue
+		}
+		if first, ok := seen[v]; ok {
+			errs = append(errs, fmt.Errorf("duplicate case %d, the first at line %d, pos %d, at line %d, pos %d",
+				v, first.Line, first.Pos, label.Line, label.Pos))
+			continue
+		}
+		seen[v] = label
+	}
+	if deflt == nil && w.Checks.SwitchDefault {
+		span := c.Span()
+		w.Warnf("switch on %s has no default case, at line %d, pos %d", value.raw, span.Line, span.Pos)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	w.emitSwitch(c)
+	return nil
+}
+
+// caseName names the clause in messages, e.g. case 1 or default.
+func caseName(clause *ASTNode) string {
+	if len(clause.Children) == 3 {
+		return "default"
+	}
+	return "case " + clause.Children[1].raw
+}
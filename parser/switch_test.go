@@ -0,0 +1,48 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestSwitch(t *testing.T) {
+	tables := *sharedParser().Tables()
+	compile := func(src string) ([]Diagnostic, []string) {
+		t.Helper()
+		result, err := Compile(Options{Source: strings.NewReader(src), Tables: &tables})
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		return result.Diagnostics, result.Walker.ThreeAddress
+	}
+
+	src := "{\n    int a;\n    a = readint();\n    switch (a) {\n    case 1:\n    case 2:\n        a = 3;\n        break;\n    case 3: {\n        int b;\n        b = a;\n        break;\n    }\n    case 4:\n        if (a == 4) a = 5;\n    case 5:\n        a = 6;\n    }\n}\n"
+	diagnostics, code := compile(src)
+	if len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics without the checks, got %v", diagnostics)
+	}
+	if !slices.Contains(code, "b = a") || !slices.Contains(code, "a = 6") {
+		t.Errorf("Expected the statements of the cases, got %v", code)
+	}
+
+	tables.Checks = Checks{SwitchDefault: true, NoFallthrough: true}
+	diagnostics, _ = compile(src)
+	if len(diagnostics) != 2 || !strings.HasPrefix(diagnostics[0].Message, "case 4 falls through into the next case, at line 13") ||
+		!strings.HasPrefix(diagnostics[1].Message, "switch on a has no default case") || diagnostics[1].Severity != "Warning" {
+		t.Errorf("Expected the fallthrough of case 4 and the missing default, got %v", diagnostics)
+	}
+
+	for src, expected := range map[string]string{
+		"{ int a; switch (a) { case 1: a = 1; break; case 0 + 1: break; default: break; } }": "duplicate case 1, the first at line 0",
+		"{ int a; switch (a) { default: break; default: break; } }":                          "multiple default cases",
+		"{ int a; switch (a) { case a: break; default: break; } }":                           "case a is not an integer constant",
+		"{ float f; switch (f) { default: break; } }":                                        "cannot switch on f (float), an integer is required",
+	} {
+		if diagnostics, _ := compile(src); len(diagnostics) != 1 || !strings.HasPrefix(diagnostics[0].Message, expected) {
+			t.Errorf("Expected %q for %s, got %v", expected, src, diagnostics)
+		}
+	}
+}
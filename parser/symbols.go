@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+
+	"app/lexer"
+	. "app/utils/collections"
+	"app/utils/report"
+)
+
+// SymbolCategory is the kind of a symbol of a grammar.
+type SymbolCategory int
+
+const (
+	NonterminalSymbol SymbolCategory = iota
+	KeywordSymbol                    // a word spelled as itself, such as if
+	OperatorSymbol                   // such as + or ==, and the terminals of no other kind
+	PunctuationSymbol                // the brackets and the separators the lexer delimits with
+	TokenClassSymbol                 // a class of tokens of many texts, such as id
+	SpecialSymbol                    // ε and the end of input $
+)
+
+func (c SymbolCategory) String() string {
+	switch c {
+	case KeywordSymbol:
+		return "keyword"
+	case OperatorSymbol:
+		return "operator"
+	case PunctuationSymbol:
+		return "punctuation"
+	case TokenClassSymbol:
+		return "token"
+	case SpecialSymbol:
+		return "special"
+	}
+	return "nonterminal"
+}
+
+// TokenClasses are the terminals Reflect gives the tokens of a class, such
+// as id for every identifier, rather than those spelled as their text.
+var TokenClasses = Set[Terminal]{}.AddAll("basic", "id", "num", "real", "str")
+
+// SymbolInfo is what a grammar tells of a symbol.
+type SymbolInfo struct {
+	Symbol     Symbol
+	Name       string // in the messages and the reports, its alias or the symbol itself
+	Category   SymbolCategory
+	Precedence *Precedence // declared for the terminal, nil if none
+	Literal    string      // the text of its tokens, empty for the classes of tokens and the nonterminals
+}
+
+// IsTerminal checks if the symbol is a terminal, ε and $ included.
+func (s SymbolInfo) IsTerminal() bool {
+	return s.Category != NonterminalSymbol
+}
+
+// Terminal returns the terminal of the symbol, false for a nonterminal.
+func (s SymbolInfo) Terminal() (Terminal, bool) {
+	return Terminal(s.Symbol), s.IsTerminal()
+}
+
+// Symbol returns what the grammar tells of the symbol. The category of a
+// terminal is that the lexer reads its text with: a reserved word, an
+// operator or a delimiter, or else a word spelled as itself, such as new, or
+// another operator. A class of tokens is one of TokenClasses, or a word the
+// lexer does not reserve with an alias, such as a %token of a grammar read.
+func (g *Grammar) Symbol(symbol Symbol) SymbolInfo {
+	info := SymbolInfo{Symbol: symbol, Name: string(symbol)}
+	terminal := Terminal(symbol)
+	if !g.Terminals.Contains(terminal) {
+		return info
+	}
+	alias, aliased := g.Aliases[terminal]
+	if aliased {
+		info.Name = alias
+	}
+	if p, ok := g.Precedence[terminal]; ok {
+		info.Precedence = &p
+	}
+	text := string(symbol)
+	word := !strings.ContainsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' })
+	switch {
+	case symbol == EPSILON || symbol == TERMINATE:
+		info.Category = SpecialSymbol
+		return info
+	case TokenClasses.Contains(terminal) || word && aliased && !lexer.IsReservedWord(text):
+		info.Category = TokenClassSymbol
+		return info
+	case lexer.IsDelimiter(text):
+		info.Category = PunctuationSymbol
+	case lexer.IsOperator(text):
+		info.Category = OperatorSymbol
+	case word:
+		info.Category = KeywordSymbol
+	default:
+		info.Category = OperatorSymbol
+	}
+	info.Literal = text
+	return info
+}
+
+// Terminal returns the terminal of the symbol, false if it is a nonterminal
+// of the grammar.
+func (g *Grammar) Terminal(symbol Symbol) (Terminal, bool) {
+	return Terminal(symbol), g.IsTerminal(symbol)
+}
+
+// SymbolRegistry holds what a grammar tells of each of its symbols, classed
+// by the tables of the lexer, for the parser, the exporters and the
+// diagnostics to name and class them alike. It is a snapshot of the grammar,
+// taken once it is built.
+type SymbolRegistry struct {
+	symbols []SymbolInfo // the terminals in the order of report.CompareSymbols, then the nonterminals in order
+	index   map[Symbol]int
+}
+
+// Symbols returns the registry of the symbols of the grammar: its terminals
+// and the heads and the symbols of its productions.
+func (g *Grammar) Symbols() *SymbolRegistry {
+	seen := Set[Symbol]{}
+	for terminal := range g.Terminals {
+		seen.Add(Symbol(terminal))
+	}
+	for _, production := range append([]Production{g.AugmentedProduction}, g.Productions...) {
+		seen.Add(production.Head)
+		for _, symbol := range production.Body {
+			seen.Add(symbol)
+		}
+	}
+	seen.Remove("")
+	r := &SymbolRegistry{index: map[Symbol]int{}}
+	for _, symbol := range seen.Elements() {
+		r.symbols = append(r.symbols, g.Symbol(symbol))
+	}
+	slices.SortFunc(r.symbols, func(a, b SymbolInfo) int {
+		if a.IsTerminal() != b.IsTerminal() {
+			if a.IsTerminal() {
+				return -1
+			}
+			return 1
+		}
+		return report.CompareSymbols(a.Symbol, b.Symbol)
+	})
+	for i, info := range r.symbols {
+		r.index[info.Symbol] = i
+	}
+	return r
+}
+
+// Lookup returns what the registry holds of the symbol, false if it is not
+// a symbol of the grammar.
+func (r *SymbolRegistry) Lookup(symbol Symbol) (SymbolInfo, bool) {
+	if r == nil {
+		return SymbolInfo{}, false
+	}
+	i, ok := r.index[symbol]
+	if !ok {
+		return SymbolInfo{}, false
+	}
+	return r.symbols[i], true
+}
+
+// Name returns the name of the symbol in the messages, the symbol itself if
+// the registry does not hold it.
+func (r *SymbolRegistry) Name(symbol Symbol) string {
+	if info, ok := r.Lookup(symbol); ok {
+		return info.Name
+	}
+	return string(symbol)
+}
+
+// All returns the symbols, the terminals first, each group in order.
+func (r *SymbolRegistry) All() []SymbolInfo {
+	return slices.Clone(r.symbols)
+}
+
+// Category returns the symbols of the category in order.
+func (r *SymbolRegistry) Category(category SymbolCategory) []SymbolInfo {
+	var symbols []SymbolInfo
+	for _, info := range r.symbols {
+		if info.Category == category {
+			symbols = append(symbols, info)
+		}
+	}
+	return symbols
+}
+
+// Write writes the registry as a table, a symbol per line with its
+// category, its name, its text, - if none, and its precedence.
+func (r *SymbolRegistry) Write(w io.Writer) error {
+	t := report.Table{}
+	t.Row("symbol", "category", "name", "literal", "precedence")
+	for _, info := range r.symbols {
+		precedence := ""
+		if info.Precedence != nil {
+			precedence = fmt.Sprintf("%d %s", info.Precedence.Level, info.Precedence.Assoc)
+		}
+		t.Row(string(info.Symbol), info.Category.String(), info.Name, cmp.Or(info.Literal, "-"), precedence)
+	}
+	return t.Write(w)
+}
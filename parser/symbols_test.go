@@ -0,0 +1,88 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestGrammar_Symbols(t *testing.T) {
+	g, err := ReadBNF(strings.NewReader(`
+%token NUMBER
+%left '+'
+%left '*'
+%alias NUMBER "number"
+stmt → 'print' expr ';' | expr '->' stmt
+expr → expr '+' expr | expr '*' expr | '(' expr ')' | NUMBER
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := g.Symbols()
+	for symbol, expected := range map[Symbol]SymbolInfo{
+		"NUMBER":  {Symbol: "NUMBER", Name: "number", Category: TokenClassSymbol},
+		"print":   {Symbol: "print", Name: "print", Category: KeywordSymbol, Literal: "print"},
+		"+":       {Symbol: "+", Name: "+", Category: OperatorSymbol, Literal: "+", Precedence: &Precedence{Level: 1, Assoc: Left}},
+		"->":      {Symbol: "->", Name: "->", Category: OperatorSymbol, Literal: "->"},
+		";":       {Symbol: ";", Name: ";", Category: PunctuationSymbol, Literal: ";"},
+		TERMINATE: {Symbol: TERMINATE, Name: TERMINATE, Category: SpecialSymbol},
+		"expr":    {Symbol: "expr", Name: "expr", Category: NonterminalSymbol},
+	} {
+		info, ok := r.Lookup(symbol)
+		if !ok || info.Symbol != expected.Symbol || info.Name != expected.Name || info.Category != expected.Category || info.Literal != expected.Literal ||
+			(info.Precedence == nil) != (expected.Precedence == nil) || info.Precedence != nil && *info.Precedence != *expected.Precedence {
+			t.Errorf("Expected %s to be %+v, got %+v", symbol, expected, info)
+		}
+	}
+	if _, ok := r.Lookup("missing"); ok {
+		t.Error("Expected a symbol not of the grammar to be missing")
+	}
+	if terminal, ok := g.Terminal("expr"); ok {
+		t.Errorf("Expected expr to be no terminal, got %s", terminal)
+	}
+	if p, ok := g.ProductionPrecedence(g.Productions[3]); !ok || p.Level != 2 {
+		t.Errorf("Expected the precedence of the product, got %+v", p)
+	}
+
+	all := r.All()
+	last := slices.IndexFunc(all, func(s SymbolInfo) bool { return !s.IsTerminal() })
+	if last < 0 || all[last-1].Symbol != TERMINATE || all[len(all)-1].Symbol != "stmt'" {
+		t.Errorf("Expected the terminals with $ last, then the nonterminals, got %v", all)
+	}
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(b.String(), "\n")
+	if lines[0] != "symbol  category     name    literal  precedence" || !slices.Contains(lines, "*       operator     *       *        2 left") ||
+		!slices.Contains(lines, "NUMBER  token        number  -") {
+		t.Errorf("Expected a line per symbol, got\n%s", b.String())
+	}
+
+	// the messages name the terminals alike
+	entry := &ErrorEntry{State: 1, Terminal: TERMINATE, Expected: []Terminal{"NUMBER", "("}, Symbols: r}
+	if entry.Error() != "no action found for state 1 and symbol $, expected number, (" {
+		t.Errorf("Expected the names of the terminals, got %q", entry.Error())
+	}
+}
+
+func TestGrammar_Symbols_Default(t *testing.T) {
+	r := NewGrammar().Symbols()
+	var keywords []string
+	for _, keyword := range r.Category(KeywordSymbol) {
+		keywords = append(keywords, keyword.Literal)
+	}
+	if !slices.Contains(keywords, "while") || !slices.Contains(keywords, "new") || slices.Contains(keywords, "id") {
+		t.Errorf("Expected the keywords of the grammar, got %v", keywords)
+	}
+	for _, class := range r.Category(TokenClassSymbol) {
+		if !TokenClasses.Contains(Terminal(class.Symbol)) || class.Literal != "" {
+			t.Errorf("Expected the classes of tokens of Reflect, got %+v", class)
+		}
+	}
+	if r.Name("id") != "identifier" || r.Name(TERMINATE) != "end of input" || r.Name("block") != "block" {
+		t.Errorf("Expected the aliases, got %s, %s and %s", r.Name("id"), r.Name(TERMINATE), r.Name("block"))
+	}
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// ScopeExport is a scope of the symbol table as Export writes it.
+type ScopeExport struct {
+	ID     int          `json:"id"`
+	Level  int          `json:"level"`
+	Parent int          `json:"parent"` // -1 for the prelude
+	Items  []ItemExport `json:"items"`  // in the order of their addresses
+}
+
+// ItemExport is an item of a scope as Export writes it.
+type ItemExport struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"` // variable, array, builtin, function...
+	Type     string `json:"type"` // the type of the value, such as int[4]
+	Address  int    `json:"address"`
+	Size     int    `json:"size"` // bytes, the elements of an array together
+	Static   bool   `json:"static,omitempty"`
+	Const    bool   `json:"const,omitempty"`
+	InFrame  bool   `json:"inFrame,omitempty"`
+	Line     int64  `json:"line"`
+	Pos      int64  `json:"pos"`
+	Function string `json:"function,omitempty"` // the signature of a function
+}
+
+// Scopes returns every scope of LegacyScopes in order, with their items in
+// the order of their addresses, then of their names, as WriteScope lists
+// them.
+func (st *SymbolTable) Scopes() []ScopeExport {
+	scopes := make([]ScopeExport, 0, len(st.LegacyScopes))
+	for _, scope := range st.LegacyScopes {
+		s := ScopeExport{ID: scope.ID, Level: scope.Level, Parent: -1, Items: []ItemExport{}}
+		if scope.Parent != nil {
+			s.Parent = scope.Parent.ID
+		}
+		for _, item := range scope.SortedItems() {
+			typ := item.UnderlyingType
+			if item.ValueType != nil {
+				typ = item.ValueType.String()
+			} else if item.Type == SymbolTableItemTypeArray {
+				typ += fmt.Sprintf("[%d]", item.ArraySize)
+			}
+			export := ItemExport{
+				Name:    item.Variable,
+				Kind:    string(item.Type),
+				Type:    typ,
+				Address: item.Address,
+				Size:    item.VariableSize * max(item.ArraySize, 1),
+				Static:  item.Static,
+				Const:   item.Const,
+				InFrame: item.InFrame,
+				Line:    item.Line,
+				Pos:     item.Pos,
+			}
+			if item.Type == SymbolTableItemTypeFunction {
+				export.Function = item.Signature()
+			}
+			s.Items = append(s.Items, export)
+		}
+		scopes = append(scopes, s)
+	}
+	return scopes
+}
+
+// The formats of Export.
+const (
+	ExportJSON = "json"
+	ExportHTML = "html"
+)
+
+var symbolTableTemplate = template.Must(template.New("symtab").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Symbol table</title>
+<style>
+body { font-family: monospace; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+td, th { border: 1px solid #999; padding: 2px 6px; text-align: left; }
+th { background: #eee; }
+</style>
+</head>
+<body>
+<h1>Symbol table</h1>
+{{range .}}<h2 id="scope-{{.ID}}">Scope {{.ID}}, level {{.Level}}{{if ge .Parent 0}}, in <a href="#scope-{{.Parent}}">scope {{.Parent}}</a>{{end}}</h2>
+<table>
+<tr><th>Name</th><th>Kind</th><th>Type</th><th>Address</th><th>Size</th><th>Line</th><th>Pos</th></tr>
+{{range .Items}}<tr><td>{{.Name}}</td><td>{{.Kind}}{{if .Static}} static{{end}}{{if .Const}} const{{end}}</td><td>{{or .Function .Type}}</td><td>{{if .InFrame}}frame+{{.Address}}{{else}}{{printf "0x%x" .Address}}{{end}}</td><td>{{.Size}}</td><td>{{.Line}}</td><td>{{.Pos}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// Export writes every scope of LegacyScopes, as Scopes returns them, in the
+// format: ExportJSON, an array of the scopes, or ExportHTML, a standalone
+// page with a table per scope. The scopes pruned are not in LegacyScopes
+// any more, see Prune.
+func (st *SymbolTable) Export(w io.Writer, format string) error {
+	switch format {
+	case ExportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(st.Scopes())
+	case ExportHTML:
+		return symbolTableTemplate.Execute(w, st.Scopes())
+	}
+	return fmt.Errorf("unknown format %s of the symbol table, json or html expected", format)
+}
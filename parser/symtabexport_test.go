@@ -0,0 +1,52 @@
+package parser_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestSymbolTable_Export(t *testing.T) {
+	w := parseSource(t, "{\n    int a[2][3];\n    {\n        static float x;\n        string s;\n    }\n}\n")
+	st := w.SymbolTable
+
+	var out strings.Builder
+	if err := st.Export(&out, ExportJSON); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	var scopes []ScopeExport
+	if err := json.Unmarshal([]byte(out.String()), &scopes); err != nil {
+		t.Fatalf("Expected JSON, got %v\n%s", err, out.String())
+	}
+	if len(scopes) != len(st.LegacyScopes) || scopes[0].Parent != -1 || scopes[0].Items[0].Kind != "builtin" {
+		t.Fatalf("Expected every scope from the prelude on, got %+v", scopes)
+	}
+	global, nested := scopes[1], scopes[2]
+	expected := ItemExport{Name: "a", Kind: "array", Type: "int[2][3]", Address: InitialAddr, Size: 24, Line: 1, Pos: 9}
+	if global.Level != 1 || len(global.Items) != 1 || global.Items[0] != expected {
+		t.Errorf("Expected the globals %+v, got %+v", expected, global)
+	}
+	if nested.Parent != global.ID || len(nested.Items) != 2 || !nested.Items[0].Static || nested.Items[1].Name != "s" || nested.Items[1].Size != 4 {
+		t.Errorf("Expected x then s in a scope of the globals, got %+v", nested)
+	}
+
+	out.Reset()
+	if err := st.Export(&out, ExportHTML); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	for _, expected := range []string{
+		`<h2 id="scope-2">Scope 2, level 2, in <a href="#scope-1">scope 1</a></h2>`,
+		"<tr><td>a</td><td>array</td><td>int[2][3]</td><td>0x10000000</td><td>24</td><td>1</td><td>9</td></tr>",
+		"<td>x</td><td>variable static</td><td>float</td>",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in the page, got\n%s", expected, out.String())
+		}
+	}
+
+	if err := st.Export(&out, "xml"); err == nil {
+		t.Error("Expected the format xml rejected")
+	}
+}
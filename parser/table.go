@@ -1,36 +1,171 @@
 package parser
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"maps"
+	"slices"
+	"strings"
+
+	"app/lexer"
 )
 
 func (p *Parser) BuildTable() {
-	p.EnsureStates()
+	_ = p.BuildTableContext(context.Background())
+}
+
+// BuildTableContext is BuildTable that gives up once the context is done,
+// leaving the table nil and returning the context's error.
+func (p *Parser) BuildTableContext(ctx context.Context) error {
+	if err := p.EnsureStatesContext(ctx); err != nil {
+		return err
+	}
 
-	p.Table = &LRTable{
+	table := &LRTable{
 		ActionTable: make(ActionTable),
 		GotoTable:   make(GotoTable),
 	}
 
 	for _, state := range p.States {
-		p.Table.Insert(state, p.Grammar)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		table.Insert(state, p.Grammar)
+	}
+	p.Table = table
+	return nil
+}
+
+// CheckConflicts compares the conflicts of the table with the ones the grammar
+// declares as intended, any difference is an error. It is the strict mode
+// counterpart of yacc's %expect.
+func (p *Parser) CheckConflicts() error {
+	p.EnsureTable()
+	sr, rr := p.Table.ShiftReduceConflicts, p.Table.ReduceReduceConflicts
+	if sr != p.Grammar.ExpectedConflicts || rr != p.Grammar.ExpectedReduceConflicts {
+		return fmt.Errorf("%w: %d shift/reduce and %d reduce/reduce, expected %d and %d",
+			ErrUnexpectedConflicts, sr, rr, p.Grammar.ExpectedConflicts, p.Grammar.ExpectedReduceConflicts)
 	}
+	return nil
 }
 
 type LRTable struct {
 	ActionTable ActionTable
 	GotoTable   GotoTable
+
+	// conflicts met while building the table, resolved by keeping the shift
+	// or the first reduction registered
+	ShiftReduceConflicts  int
+	ReduceReduceConflicts int
+	Conflicts             []Conflict `json:",omitempty"` // in the order of the states
+}
+
+// Copy returns a deep copy of the table.
+func (t *LRTable) Copy() *LRTable {
+	c := *t
+	c.ActionTable, c.GotoTable = t.ActionTable.Copy(), t.GotoTable.Copy()
+	c.Conflicts = slices.Clone(t.Conflicts)
+	return &c
+}
+
+// Lookup returns the action of the state on the terminal. A cell without an
+// action is an error entry, for which the action is an ERROR one and ok is
+// false, see ErrorEntry for what the state expects instead.
+func (t *LRTable) Lookup(state int, terminal Terminal) (action Action, ok bool) {
+	action, ok = t.ActionTable[state][terminal]
+	if !ok {
+		return Action{Type: ERROR}, false
+	}
+	return action, true
+}
+
+// Goto returns the state entered from the state on the nonterminal.
+func (t *LRTable) Goto(state int, symbol Symbol) (int, bool) {
+	next, ok := t.GotoTable[state][symbol]
+	return next, ok
+}
+
+// Expected returns the terminals the state has an action on, in order.
+func (t *LRTable) Expected(state int) []Terminal {
+	return slices.Sorted(maps.Keys(t.ActionTable[state]))
+}
+
+// ErrorEntry is a cell of the action table without an action, where the
+// parse fails. It carries the terminals the state expects instead, the hint
+// to report the error and recover from it with.
+type ErrorEntry struct {
+	State    int
+	Terminal Terminal
+	Expected []Terminal
+	Symbols  *SymbolRegistry // names the terminals in the message, see Grammar.Symbols
+	Fix      *FixIt          // the token whose insertion lets the parse go on, if one does
+	Token    *lexer.Token    // the token the parse stopped at, whose position the message ends with if known
+}
+
+func (e *ErrorEntry) Error() string {
+	message := fmt.Sprintf("no action found for state %d and symbol %s", e.State, names(e.Symbols, []Terminal{e.Terminal}))
+	if len(e.Expected) > 0 {
+		message += ", expected " + names(e.Symbols, e.Expected)
+	}
+	if e.Token != nil {
+		message += fmt.Sprintf(", at line %d, pos %d", e.Token.Line, e.Token.Pos)
+	}
+	if e.Fix != nil {
+		message += "; " + e.Fix.String()
+	}
+	return message
+}
+
+// ErrorEntry returns the error entry of the state on the terminal, nil if
+// the cell has an action.
+func (t *LRTable) ErrorEntry(state int, terminal Terminal) *ErrorEntry {
+	if _, ok := t.Lookup(state, terminal); ok {
+		return nil
+	}
+	return &ErrorEntry{State: state, Terminal: terminal, Expected: t.Expected(state)}
+}
+
+var (
+	ErrShiftReduce         = errors.New("shift/reduce conflict")
+	ErrReduceReduce        = errors.New("reduce/reduce conflict")
+	ErrUnexpectedConflicts = errors.New("unexpected conflicts")
+)
+
+// ConflictError is a cell of the action table an action is registered in
+// while it holds another. It wraps ErrShiftReduce or ErrReduceReduce, its
+// kind, for errors.Is.
+type ConflictError struct {
+	State      int
+	Terminal   Terminal
+	Registered Action // the action the cell held
+	Action     Action // the action registered in it
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v in action table: state %d, terminal %s[%s] %d, [%s] %d",
+		e.Unwrap(), e.State, e.Terminal, e.Registered.Type, e.Registered.Number, e.Action.Type, e.Action.Number)
 }
 
-func (t LRTable) Insert(state *State, grammar *Grammar) {
+// Unwrap returns ErrShiftReduce or ErrReduceReduce, the kind of the conflict.
+func (e *ConflictError) Unwrap() error {
+	if e.Registered.Type == REDUCE && e.Action.Type == REDUCE {
+		return ErrReduceReduce
+	}
+	return ErrShiftReduce
+}
+
+func (t *LRTable) Insert(state *State, grammar *Grammar) {
 	var err error
+	nonassoc := map[Terminal]bool{} // the cells left errors by %nonassoc
 	for _, item := range state.Items {
+		var action Action
+		var terminal Terminal
 		if item.Dot == len(item.Production.Body) || item.Production.Body[item.Dot].IsEpsilon() {
 			if item.Lookahead == TERMINATE && item.Production.Equals(grammar.AugmentedProduction) {
-				err = t.ActionTable.Register(state.Index, Action{Type: ACCEPT, Number: 0}, TERMINATE)
+				action, terminal = Action{Type: ACCEPT, Number: 0}, TERMINATE
 			} else {
-				err = t.ActionTable.Register(state.Index, Action{Type: REDUCE, Number: grammar.GetIndex(item.Production)}, item.Lookahead)
+				action, terminal = Action{Type: REDUCE, Number: grammar.GetIndex(item.Production)}, item.Lookahead
 			}
 		} else {
 			symbol := item.Production.Body[item.Dot]
@@ -38,13 +173,33 @@ func (t LRTable) Insert(state *State, grammar *Grammar) {
 				continue
 			}
 			if grammar.IsNonTerminal(symbol) {
-				err = t.GotoTable.Register(state.Index, state.Transitions[symbol].Index, symbol)
+				_ = t.GotoTable.Register(state.Index, state.Transitions[symbol].Index, symbol)
+				continue
+			}
+			action, terminal = Action{Type: SHIFT, Number: state.Transitions[symbol].Index}, Terminal(symbol)
+		}
+		if nonassoc[terminal] {
+			continue
+		}
+		registered, exists := t.ActionTable[state.Index][terminal]
+		if winner, ok := grammar.resolve(terminal, registered, action); exists && ok {
+			// resolved by the precedences, which is no conflict
+			if winner.Type == ERROR {
+				nonassoc[terminal] = true
+				delete(t.ActionTable[state.Index], terminal)
 			} else {
-				err = t.ActionTable.Register(state.Index, Action{Type: SHIFT, Number: state.Transitions[symbol].Index}, Terminal(symbol))
+				t.ActionTable[state.Index][terminal] = winner
 			}
+			continue
+		}
+		err = t.ActionTable.Register(state.Index, action, terminal)
+		if errors.Is(err, ErrShiftReduce) {
+			t.ShiftReduceConflicts++
+		} else if errors.Is(err, ErrReduceReduce) {
+			t.ReduceReduceConflicts++
 		}
 		if err != nil {
-			// fmt.Printf("when inserting : %v\n", err)
+			t.Conflicts = append(t.Conflicts, newConflict(state, grammar, terminal, t.ActionTable[state.Index][terminal], registered, action))
 		}
 	}
 }
@@ -56,8 +211,10 @@ type Action struct {
 
 type ActionTable map[int]map[Terminal]Action
 
+// Copy returns a deep copy of the table, the rows of the states copied too, so
+// that writing either table leaves the other alone.
 func (t ActionTable) Copy() ActionTable {
-	return maps.Clone(t)
+	return copyRows(t)
 }
 
 func (t ActionTable) Register(stateIndex int, action Action, terminal Terminal) error {
@@ -65,11 +222,15 @@ func (t ActionTable) Register(stateIndex int, action Action, terminal Terminal)
 		t[stateIndex] = make(map[Terminal]Action)
 	}
 
-	if _, exists := t[stateIndex][terminal]; exists {
-		if t[stateIndex][terminal].Type == SHIFT && action.Type == REDUCE {
-			return fmt.Errorf("conflict in action table: state %d, terminal %s[shift] %d, [reduce] %d", stateIndex, terminal, t[stateIndex][terminal].Number, action.Number)
-		} else if t[stateIndex][terminal].Type == REDUCE && action.Type == REDUCE {
-			return fmt.Errorf("conflict in action table: state %d, terminal %s[reduce] %d, [reduce] %d", stateIndex, terminal, t[stateIndex][terminal].Number, action.Number)
+	if registered, exists := t[stateIndex][terminal]; exists {
+		conflict := &ConflictError{State: stateIndex, Terminal: terminal, Registered: registered, Action: action}
+		switch {
+		case registered.Type == SHIFT && action.Type == REDUCE, registered.Type == REDUCE && action.Type == REDUCE:
+			return conflict
+		case registered.Type == REDUCE && action.Type == SHIFT:
+			// the shift wins
+			t[stateIndex][terminal] = action
+			return conflict
 		}
 	}
 
@@ -79,8 +240,21 @@ func (t ActionTable) Register(stateIndex int, action Action, terminal Terminal)
 
 type GotoTable map[int]map[Symbol]int
 
+// Copy returns a deep copy of the table, as ActionTable.Copy does.
 func (t GotoTable) Copy() GotoTable {
-	return maps.Clone(t)
+	return copyRows(t)
+}
+
+// copyRows copies the rows of a table and the map holding them.
+func copyRows[K comparable, V any](rows map[int]map[K]V) map[int]map[K]V {
+	if rows == nil {
+		return nil
+	}
+	c := make(map[int]map[K]V, len(rows))
+	for state, row := range rows {
+		c[state] = maps.Clone(row)
+	}
+	return c
 }
 
 func (t GotoTable) Register(stateIndex, nextStateIndex int, symbol Symbol) error {
@@ -113,10 +287,30 @@ type SymbolTableItem struct {
 	Address  int
 
 	UnderlyingType string
+	ValueType      Type // the type of the value, which Register sizes the item by if set
+	InFrame        bool // Address is an offset in the frame of its function, see SymbolTable.FrameOffsets
+
+	// Static variables live in the global data region for the whole run,
+	// but their name is only visible in the declaring scope.
+	Static bool
+
+	// Const variables may only be written by their initializer.
+	Const bool
+
+	Pointee string // type pointed to by pointers
+
+	Module string // module of a global declared by a file with a module declaration
 
 	VariableSize int
 	ArraySize    int
 
+	Initializer []string // initial values of data segment items, one per element
+
+	Params []Param // parameters of a function, in order
+	Label  string  // entry label of a function
+
+	Scope, Level int // the ID and the level of the scope declaring the item, see Symbol
+
 	Line, Pos int64
 }
 
@@ -126,6 +320,8 @@ const (
 	SymbolTableItemTypeVariable SymbolTableItemType = "variable"
 	SymbolTableItemTypeArray    SymbolTableItemType = "array"
 	SymbolTableItemTypeConstant SymbolTableItemType = "constant"
+	SymbolTableItemTypeBuiltin  SymbolTableItemType = "builtin"
+	SymbolTableItemTypeFunction SymbolTableItemType = "function"
 	SymbolTableItemTypeUnknown  SymbolTableItemType = "unknown"
 )
 
@@ -134,6 +330,10 @@ type Scope struct {
 	Level  int
 	Items  map[string]*SymbolTableItem
 	Parent *Scope
+
+	Function   bool // the scope of a function, which has a frame of its own, see EnterFunctionScope
+	FrameSize  int  // words the locals of a function scope take at most in its frame
+	frameStart int  // offset in the frame of the function when the scope was entered
 }
 
 type SymbolTable struct {
@@ -142,13 +342,36 @@ type SymbolTable struct {
 	EnterFunction func(*Scope) error
 	ExitFunction  func(*Scope) error
 
+	Constants map[string]*SymbolTableItem // constant pool, keyed by the type and the literal, see ConstantKey
+	Modules   map[string]*Scope           // outermost block of each module, for qualified names
+
+	// Prune drops the nested scopes from LegacyScopes once exited, passing
+	// them to Archive first if set, so that the scopes kept are bounded by
+	// the nesting of the input rather than its length. The prelude and the
+	// globals are kept.
+	Prune   bool
+	Archive func(*Scope) error
+
+	// FrameOffsets addresses the locals of a function, those of its scope and
+	// of the scopes nested in it but the statics, by their offset in words
+	// from the start of its frame rather than in the data segment, so that
+	// the locals of two functions do not take the same growing region. Each
+	// function starts at offset 0, and sibling blocks share the offsets after
+	// those of their parent. The globals keep their absolute addresses.
+	FrameOffsets bool
+
 	addrCounter  int
+	frameAddr    int // next offset in the frame of the current function
 	constantAddr int
+	pruned       int // scopes dropped so far, which the IDs count
 }
 
+// The addresses the symbol table gives, in words: the variables and the
+// temporaries from InitialAddr up, the literals of the constant pool from
+// ConstantAddr up.
 const (
-	initialAddr  = 0x10000000
-	constantAddr = 0x20000000
+	InitialAddr  = 0x10000000
+	ConstantAddr = 0x20000000
 )
 
 // NewSymbolTable creates a new symbol table with the specified enter and exit functions.
@@ -159,28 +382,45 @@ func NewSymbolTable(enter, exit func(*Scope) error) *SymbolTable {
 		CurrentScope:  nil,
 		EnterFunction: enter,
 		ExitFunction:  exit,
-		addrCounter:   initialAddr,
-		constantAddr:  constantAddr,
+		Constants:     make(map[string]*SymbolTableItem),
+		Modules:       make(map[string]*Scope),
+		addrCounter:   InitialAddr,
+		constantAddr:  ConstantAddr,
 	}
 }
 
 // EnterScope creates a new scope and sets it as the current scope in the symbol table.
 func (st *SymbolTable) EnterScope() error {
+	return st.enterScope(false)
+}
+
+// EnterFunctionScope enters the scope of a function, as EnterScope does. With
+// FrameOffsets its locals are addressed from the start of a frame of its own,
+// whose size its scope has once exited.
+func (st *SymbolTable) EnterFunctionScope() error {
+	return st.enterScope(true)
+}
+
+func (st *SymbolTable) enterScope(function bool) error {
 	if st.CurrentScope == nil {
 		st.CurrentScope = &Scope{
-			ID:     len(st.LegacyScopes),
+			ID:     len(st.LegacyScopes) + st.pruned,
 			Level:  0,
 			Items:  make(map[string]*SymbolTableItem),
 			Parent: nil,
 		}
 	} else {
 		st.CurrentScope = &Scope{
-			ID:     len(st.LegacyScopes),
+			ID:     len(st.LegacyScopes) + st.pruned,
 			Level:  st.CurrentScope.Level + 1,
 			Items:  make(map[string]*SymbolTableItem),
 			Parent: st.CurrentScope,
 		}
 	}
+	st.CurrentScope.Function, st.CurrentScope.frameStart = function, st.frameAddr
+	if function {
+		st.frameAddr = 0
+	}
 	st.LegacyScopes = append(st.LegacyScopes, st.CurrentScope)
 
 	if st.EnterFunction != nil {
@@ -196,48 +436,134 @@ func (st *SymbolTable) ExitScope() error {
 		return fmt.Errorf("no scope to exit")
 	}
 
+	if function := st.Function(); function != nil {
+		function.FrameSize = max(function.FrameSize, st.frameAddr)
+	}
 	if st.ExitFunction != nil {
 		if err := st.ExitFunction(st.CurrentScope); err != nil {
 			return err
 		}
 	}
 
+	exited := st.CurrentScope
+	st.frameAddr = exited.frameStart
 	st.CurrentScope = st.CurrentScope.Parent
+	if st.Prune && exited.Level > 1 {
+		return st.prune(exited)
+	}
 	return nil
 }
 
+// prune drops the scope exited from LegacyScopes once archived. A scope the
+// archive fails on is kept.
+func (st *SymbolTable) prune(scope *Scope) error {
+	if st.Archive != nil {
+		if err := st.Archive(scope); err != nil {
+			return err
+		}
+	}
+	if i := slices.Index(st.LegacyScopes, scope); i >= 0 {
+		st.LegacyScopes = slices.Delete(st.LegacyScopes, i, i+1)
+		st.pruned++
+	}
+	return nil
+}
+
+// RedeclarationError is an item registered in a scope already holding one of
+// the same name.
+type RedeclarationError struct {
+	Name     string
+	Scope    *Scope
+	Previous *SymbolTableItem // the item the scope holds
+}
+
+func (e *RedeclarationError) Error() string {
+	return fmt.Sprintf("item %s already exists in scope", e.Name)
+}
+
+// UndefinedError is a name Lookup finds no item for: in no scope, or among
+// the globals of the module of a qualified name, or the module itself if
+// Name is empty.
+type UndefinedError struct {
+	Name   string
+	Module string // the module of a qualified name
+}
+
+func (e *UndefinedError) Error() string {
+	switch {
+	case e.Name == "":
+		return fmt.Sprintf("module %s not found", e.Module)
+	case e.Module != "":
+		return fmt.Sprintf("item %s not found in module %s", e.Name, e.Module)
+	}
+	return fmt.Sprintf("item %s not found in any scope", e.Name)
+}
+
 // Register adds a new item to the current scope in the symbol table.
 // It checks for conflicts and ensures that the item is valid before adding it.
+// An item with a ValueType is sized by it, see sizeOf.
 func (st *SymbolTable) Register(item *SymbolTableItem) error {
 	if st.CurrentScope == nil {
 		return fmt.Errorf("no scope to register item")
 	}
 
 	if _, exists := st.CurrentScope.Items[item.Variable]; exists {
-		return fmt.Errorf("item %s already exists in scope", item.Variable)
+		return &RedeclarationError{Name: item.Variable, Scope: st.CurrentScope, Previous: st.CurrentScope.Items[item.Variable]}
 	}
 
+	if item.ValueType != nil {
+		sizeOf(item)
+	}
 	if item.VariableSize <= 0 {
 		return fmt.Errorf("invalid variable size for item %s", item.Variable)
 	}
 	st.CurrentScope.Items[item.Variable] = item
+	item.Scope, item.Level = st.CurrentScope.ID, st.CurrentScope.Level
+	size := 0
 	switch item.Type {
 	case SymbolTableItemTypeVariable:
-		item.Address = st.addrCounter
-		st.addrCounter += item.VariableSize / 4
-		if item.VariableSize/4*4 != item.VariableSize {
-			st.addrCounter++
-		}
+		size = item.VariableSize
 	case SymbolTableItemTypeArray:
-		item.Address = st.addrCounter
-		st.addrCounter += item.VariableSize * item.ArraySize / 4
-		if item.VariableSize*item.ArraySize/4*4 != item.VariableSize*item.ArraySize {
-			st.addrCounter++
+		size = item.VariableSize * item.ArraySize
+	}
+	if size == 0 {
+		return nil
+	}
+	counter := &st.addrCounter
+	if st.FrameOffsets && !item.Static && st.Function() != nil {
+		counter, item.InFrame = &st.frameAddr, true
+	}
+	item.Address = *counter
+	*counter += (size + 3) / 4
+	return nil
+}
+
+// Function returns the scope of the function the current scope is in, nil
+// outside any.
+func (st *SymbolTable) Function() *Scope {
+	for scope := st.CurrentScope; scope != nil; scope = scope.Parent {
+		if scope.Function {
+			return scope
 		}
 	}
 	return nil
 }
 
+// sizeOf sets the sizes of the item from its ValueType: an array has the
+// size of its innermost elements and the number of them, all its dimensions
+// flattened, and the other types have their own size. The underlying type is
+// that of the elements if not set.
+func sizeOf(item *SymbolTableItem) {
+	elem, n := Elem(item.ValueType)
+	item.VariableSize, item.ArraySize = elem.Size(), n
+	if _, ok := item.ValueType.(*ArrayType); ok && item.Type == SymbolTableItemTypeVariable {
+		item.Type = SymbolTableItemTypeArray
+	}
+	if item.UnderlyingType == "" {
+		item.UnderlyingType = elem.String()
+	}
+}
+
 // Lookup searches for an item in the symbol table.
 // It checks the current scope and its parent scopes until it finds the item or returns an error.
 // It returns the item, a boolean indicating if it was found in the current scope, and an error if any.
@@ -247,6 +573,18 @@ func (st *SymbolTable) Lookup(variable string) (item *SymbolTableItem, findInCur
 		return nil, false, fmt.Errorf("no scope to lookup item")
 	}
 
+	// a qualified name, mod.name, is looked up among the globals of the module
+	if module, name, ok := strings.Cut(variable, "."); ok {
+		scope, exists := st.Modules[module]
+		if !exists {
+			return nil, false, &UndefinedError{Module: module}
+		}
+		if item, exists := scope.Items[name]; exists && item.Module == module {
+			return item, scope == st.CurrentScope, nil
+		}
+		return nil, false, &UndefinedError{Name: name, Module: module}
+	}
+
 	scope := st.CurrentScope
 	for scope != nil {
 		if item, exists := scope.Items[variable]; exists {
@@ -255,7 +593,13 @@ func (st *SymbolTable) Lookup(variable string) (item *SymbolTableItem, findInCur
 		scope = scope.Parent
 	}
 
-	return nil, false, fmt.Errorf("item %s not found in any scope", variable)
+	return nil, false, &UndefinedError{Name: variable}
+}
+
+// ConstantKey returns the key of the literal of the type in the constant
+// pool, as float 2.5.
+func ConstantKey(typ, literal string) string {
+	return typ + " " + literal
 }
 
 func (st *SymbolTable) TempAddr(size int) int {
@@ -266,3 +610,27 @@ func (st *SymbolTable) TempAddr(size int) int {
 	}
 	return addr
 }
+
+// Constant returns the address of a literal of the type in the constant pool,
+// allocating it on first use. Equal literals of the same type share the same
+// slot, while the int 1 and the float 1 have one each, of their own size.
+func (st *SymbolTable) Constant(value, typ string, size int) int {
+	key := ConstantKey(typ, value)
+	if item, exists := st.Constants[key]; exists {
+		return item.Address
+	}
+	item := &SymbolTableItem{
+		Variable:       value,
+		Type:           SymbolTableItemTypeConstant,
+		Address:        st.constantAddr,
+		UnderlyingType: typ,
+		VariableSize:   size,
+		ArraySize:      1,
+	}
+	st.Constants[key] = item
+	st.constantAddr += size / 4
+	if size/4*4 != size {
+		st.constantAddr++
+	}
+	return item.Address
+}
@@ -1,9 +1,15 @@
 package parser_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"app/lexer"
 	. "app/parser"
 	. "app/utils/collections"
 )
@@ -35,7 +41,7 @@ func TestParser_BuildTable(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &Parser{
 				Grammar:  &tt.grammar,
-				Symbols:  Set[Symbol]{},
+				Symbols:  NewOrderedSet[Symbol](),
 				FirstSet: FirstSet{},
 				States:   States{},
 			}
@@ -68,3 +74,199 @@ func TestParser_BuildTable(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_BuildTableContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := NewParser()
+	if err := p.EnsureTableContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	if p.Table != nil || len(p.States) != 0 {
+		t.Errorf("Expected no table and states after cancellation")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := p.EnsureTableContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	logs := []string{}
+	_, err := sharedParser().ParseContext(ctx, lexer.NewLexer(strings.NewReader("{ int a; }")), func(s string) {
+		logs = append(logs, s)
+	})
+	if !errors.Is(err, context.Canceled) || logs[len(logs)-1] != "Error: context canceled" {
+		t.Errorf("Expected the parse to be canceled, got %v, %v", err, logs)
+	}
+}
+
+func TestParser_CheckConflicts(t *testing.T) {
+	// the dangling else: S -> i S . e S and S -> i S . on e
+	grammar := &Grammar{
+		AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
+		Productions: []Production{
+			{Head: "S", Body: []Symbol{"i", "S", "e", "S"}},
+			{Head: "S", Body: []Symbol{"i", "S"}},
+			{Head: "S", Body: []Symbol{"a"}},
+		},
+		Terminals: Set[Terminal]{}.AddAll("i", "e", "a", EPSILON, TERMINATE),
+	}
+	p := &Parser{Grammar: grammar, Symbols: NewOrderedSet[Symbol](), FirstSet: FirstSet{}, States: States{}}
+	p.EnsureTable()
+	if p.Table.ShiftReduceConflicts == 0 || p.Table.ReduceReduceConflicts != 0 {
+		t.Fatalf("Expected only shift/reduce conflicts, got %d and %d", p.Table.ShiftReduceConflicts, p.Table.ReduceReduceConflicts)
+	}
+	for _, state := range p.States {
+		if _, ok := state.Transitions["e"]; ok && p.Table.ActionTable[state.Index]["e"].Type != SHIFT {
+			t.Errorf("Expected the shift to win on e in state %d", state.Index)
+		}
+	}
+	if err := p.CheckConflicts(); !errors.Is(err, ErrUnexpectedConflicts) {
+		t.Errorf("Expected %v, got %v", ErrUnexpectedConflicts, err)
+	}
+	grammar.ExpectedConflicts = p.Table.ShiftReduceConflicts
+	if err := p.CheckConflicts(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if err := sharedParser().CheckConflicts(); err != nil {
+		t.Errorf("The conflicts of the grammar changed, update ExpectedConflicts: %v", err)
+	}
+}
+
+func TestCompareTables(t *testing.T) {
+	build := func(grammar Grammar) *LRTable {
+		p := &Parser{Grammar: &grammar, Symbols: NewOrderedSet[Symbol](), FirstSet: FirstSet{}, States: States{}}
+		p.EnsureTable()
+		return p.Table
+	}
+	before := build(grammars[0])
+
+	var buf strings.Builder
+	if err := before.WriteJSON(&buf); err != nil {
+		t.Fatalf("Failed to write the table: %v", err)
+	}
+	read, err := ReadTable(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("Failed to read the table: %v", err)
+	}
+	if d := CompareTables(before, read); !d.Empty() {
+		t.Errorf("Expected the table read back to be the same, got %+v", d)
+	}
+	if _, err := ReadTable(strings.NewReader("{")); err == nil {
+		t.Errorf("Expected an error for a truncated table")
+	}
+
+	// L -> & R adds the states of the new operator
+	grammar := grammars[0]
+	grammar.Productions = append(slices.Clone(grammar.Productions), Production{Head: "L", Body: []Symbol{"&", "R"}})
+	grammar.Terminals = Set[Terminal]{}.AddAll("*", "&", "=", "id", EPSILON, TERMINATE)
+	after := build(grammar)
+	d := CompareTables(before, after)
+	if len(d.AddedStates) == 0 || len(d.RemovedStates) != 0 {
+		t.Errorf("Expected only new states, got %v and %v", d.AddedStates, d.RemovedStates)
+	}
+	for _, cell := range d.Cells {
+		if cell.Symbol == "&" && cell.Before != "" {
+			t.Errorf("Expected no shift on & before, got %v", cell)
+		}
+	}
+	if !slices.ContainsFunc(d.Cells, func(cell CellDiff) bool { return cell.Symbol == "&" && cell.After != "" }) {
+		t.Errorf("Expected the shifts on & to be reported, got %v", d.Cells)
+	}
+	if d := CompareTables(after, before); len(d.RemovedStates) != len(CompareTables(before, after).AddedStates) {
+		t.Errorf("Expected the new states to be removed the other way round, got %v", d.RemovedStates)
+	}
+
+	before.ShiftReduceConflicts = 1
+	buf.Reset()
+	if err := CompareTables(before, read).Write(&buf); err != nil || !strings.Contains(buf.String(), "Shift/reduce conflicts: 1 -> 0") {
+		t.Errorf("Expected the conflict counts in the report, got %q, %v", buf.String(), err)
+	}
+}
+
+func TestLRTable_Lookup(t *testing.T) {
+	grammar := grammars[0].Copy()
+	grammar.SetAlias("id", "identifier")
+	p := &Parser{Grammar: &grammar, Symbols: NewOrderedSet[Symbol](), FirstSet: FirstSet{}, States: States{}}
+	p.EnsureTable()
+	if action, ok := p.Table.Lookup(0, "id"); !ok || action.Type != SHIFT {
+		t.Errorf("Expected a shift on id in state 0, got %v", action)
+	}
+	if next, ok := p.Table.Goto(0, "S"); !ok || next != p.States[0].Transitions["S"].Index {
+		t.Errorf("Expected the goto on S in state 0, got %d", next)
+	}
+	if action, ok := p.Table.Lookup(0, "="); ok || action.Type != ERROR || p.Table.ErrorEntry(0, "id") != nil {
+		t.Errorf("Expected an error entry on = alone in state 0, got %v", action)
+	}
+	entry := p.Table.ErrorEntry(0, "=")
+	if !slices.Equal(entry.Expected, []Terminal{"*", "id"}) {
+		t.Errorf("Expected * and id to be expected in state 0, got %v", entry.Expected)
+	}
+
+	// the syntax errors of the walker are the error entries, naming the terminals by their aliases
+	w := p.Tables().NewSession()
+	_, err := w.Next("=")
+	if !errors.As(err, &entry) || entry.State != 0 || entry.Terminal != "=" || err.Error() != "no action found for state 0 and symbol =, expected *, identifier" {
+		t.Errorf("Expected the error entry of state 0 on =, got %v", err)
+	}
+}
+
+func TestActionTable_Register(t *testing.T) {
+	table := ActionTable{}
+	shift, reduce := Action{Type: SHIFT, Number: 4}, Action{Type: REDUCE, Number: 2}
+	if err := table.Register(1, reduce, "id"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	err := table.Register(1, shift, "id")
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrShiftReduce) || conflict.State != 1 || conflict.Terminal != "id" ||
+		conflict.Registered != reduce || conflict.Action != shift || table[1]["id"] != shift {
+		t.Errorf("Expected a shift/reduce conflict the shift wins, got %v and %v", err, table[1]["id"])
+	}
+	if err.Error() != "shift/reduce conflict in action table: state 1, terminal id[reduce] 2, [shift] 4" {
+		t.Errorf("Expected the cell and both actions in the message, got %q", err)
+	}
+	err = table.Register(1, Action{Type: REDUCE, Number: 3}, "+")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	err = table.Register(1, reduce, "+")
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrReduceReduce) || conflict.Registered.Number != 3 || table[1]["+"].Number != 3 {
+		t.Errorf("Expected a reduce/reduce conflict the first reduction wins, got %v", err)
+	}
+}
+
+func TestSymbolTable_Errors(t *testing.T) {
+	st := NewSymbolTable(nil, nil)
+	st.EnterScope()
+	n := &SymbolTableItem{Variable: "n", Type: SymbolTableItemTypeVariable, UnderlyingType: "int", VariableSize: 4}
+	if err := st.Register(n); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	err := st.Register(&SymbolTableItem{Variable: "n", Type: SymbolTableItemTypeVariable, UnderlyingType: "float", VariableSize: 4})
+	var redeclared *RedeclarationError
+	if !errors.As(fmt.Errorf("%w, at line 2, pos 9", err), &redeclared) || redeclared.Name != "n" ||
+		redeclared.Scope != st.CurrentScope || redeclared.Previous != n || err.Error() != "item n already exists in scope" {
+		t.Errorf("Expected n redeclared in the current scope, got %v", err)
+	}
+
+	st.Modules["geo"] = st.CurrentScope
+	var undefined *UndefinedError
+	for name, expected := range map[string]UndefinedError{
+		"m":         {Name: "m"},
+		"num.count": {Module: "num"},
+		"geo.count": {Name: "count", Module: "geo"},
+	} {
+		_, _, err := st.Lookup(name)
+		if !errors.As(err, &undefined) || *undefined != expected {
+			t.Errorf("Expected %s undefined as %+v, got %v", name, expected, err)
+		}
+	}
+	if _, _, err := st.Lookup("geo.count"); err.Error() != "item count not found in module geo" {
+		t.Errorf("Expected the module in the message, got %q", err)
+	}
+}
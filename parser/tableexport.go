@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"app/utils"
+	"app/utils/report"
+)
+
+// columns returns the terminals of the action table in order with the end of
+// input last, followed by the nonterminals of the goto table in order, the
+// columns of the table as the textbook lays it out.
+func (t *LRTable) columns() (terminals, nonterminals []Symbol) {
+	seen := map[Symbol]bool{}
+	for _, row := range t.ActionTable {
+		for terminal := range row {
+			seen[Symbol(terminal)] = true
+		}
+	}
+	terminals = report.SortedSymbols(maps.Keys(seen))
+
+	seen = map[Symbol]bool{}
+	for _, row := range t.GotoTable {
+		for symbol := range row {
+			seen[symbol] = true
+		}
+	}
+	return terminals, report.SortedSymbols(maps.Keys(seen))
+}
+
+// states returns the number of rows of the table, one past the highest state.
+func (t *LRTable) states() int {
+	n := 0
+	for state := range t.ActionTable {
+		n = max(n, state+1)
+	}
+	for state := range t.GotoTable {
+		n = max(n, state+1)
+	}
+	return n
+}
+
+// WriteCSV writes the ACTION and GOTO tables as CSV, a row per state and a
+// column per terminal then per nonterminal, with the cells written as s3,
+// r12, acc or 7 for a goto and the errors left empty.
+func (t *LRTable) WriteCSV(w io.Writer) error {
+	terminals, nonterminals := t.columns()
+	cells := t.cells()
+	writer := csv.NewWriter(w)
+	header := []string{"state"}
+	for _, symbol := range append(slices.Clone(terminals), nonterminals...) {
+		header = append(header, string(symbol))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for state := range t.states() {
+		record := []string{fmt.Sprint(state)}
+		for _, symbol := range header[1:] {
+			record = append(record, cells[state][Symbol(symbol)])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteLab writes the ACTION and GOTO tables as text in the format of the
+// lab: the ACTION and GOTO headers over the symbols, a row per state from 0,
+// the cells laid out like WriteCSV, each column as wide as its widest cell
+// and two spaces apart, and | between the state, the actions and the gotos.
+func (t *LRTable) WriteLab(w io.Writer) error {
+	terminals, nonterminals := t.columns()
+	cells := t.cells()
+	n := t.states()
+	width := func(symbol Symbol) int {
+		width := utils.Width(string(symbol))
+		for state := range n {
+			width = max(width, utils.Width(cells[state][symbol]))
+		}
+		return width + 2
+	}
+	group := func(symbols []Symbol, cell func(Symbol) string) string {
+		var b strings.Builder
+		for _, symbol := range symbols {
+			b.WriteString(utils.PadRight(cell(symbol), width(symbol)))
+		}
+		return b.String()
+	}
+	stateWidth := max(utils.Width("状态"), report.NumberWidth(n-1)) + 2
+	headers := [2]string{
+		utils.PadRight("", stateWidth) + "| " + utils.PadRight("ACTION", utils.Width(group(terminals, func(Symbol) string { return "" }))) + "| GOTO",
+		utils.PadRight("状态", stateWidth) + "| " + group(terminals, func(s Symbol) string { return string(s) }) + "| " + group(nonterminals, func(s Symbol) string { return string(s) }),
+	}
+	for _, header := range headers {
+		if _, err := fmt.Fprintln(w, strings.TrimRight(header, " ")); err != nil {
+			return err
+		}
+	}
+	for state := range n {
+		row := utils.PadRight(fmt.Sprint(state), stateWidth) + "| " +
+			group(terminals, func(s Symbol) string { return cells[state][s] }) + "| " +
+			group(nonterminals, func(s Symbol) string { return cells[state][s] })
+		if _, err := fmt.Fprintln(w, strings.TrimRight(row, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var tableTemplate = template.Must(template.New("table").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: monospace; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #999; padding: 2px 6px; text-align: center; }
+thead th { position: sticky; top: 0; background: #eee; }
+th.goto, td.goto { background: #f4f8ff; }
+td.conflict { background: #ffd6d6; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.ShiftReduce}} shift/reduce and {{.ReduceReduce}} reduce/reduce conflicts, the cells with one are red and give the action dropped.</p>
+<table>
+<thead>
+<tr><th rowspan="2">State</th><th colspan="{{len .Terminals}}">ACTION</th>{{if .Nonterminals}}<th class="goto" colspan="{{len .Nonterminals}}">GOTO</th>{{end}}</tr>
+<tr>{{range .Terminals}}<th>{{.}}</th>{{end}}{{range .Nonterminals}}<th class="goto">{{.}}</th>{{end}}</tr>
+</thead>
+<tbody>
+{{range .Rows}}<tr><th>{{.State}}</th>{{range .Actions}}<td{{if .Dropped}} class="conflict" title="dropped {{.Dropped}}"{{end}}>{{.Text}}</td>{{end}}{{range .Gotos}}<td class="goto">{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`))
+
+// tableCell is a cell of the action table in the page of WriteHTML.
+type tableCell struct {
+	Text    string
+	Dropped string // the actions the conflicts of the cell dropped
+}
+
+// WriteHTML writes the ACTION and GOTO tables as a standalone HTML page laid
+// out like WriteCSV, with the cells of the conflicts marked.
+func (t *LRTable) WriteHTML(w io.Writer, title string) error {
+	terminals, nonterminals := t.columns()
+	cells := t.cells()
+	dropped := map[int]map[Terminal]string{}
+	for _, c := range t.Conflicts {
+		if dropped[c.State] == nil {
+			dropped[c.State] = map[Terminal]string{}
+		}
+		if dropped[c.State][c.Terminal] != "" {
+			dropped[c.State][c.Terminal] += ", "
+		}
+		dropped[c.State][c.Terminal] += actionCell(c.Dropped)
+	}
+
+	type row struct {
+		State   int
+		Actions []tableCell
+		Gotos   []string
+	}
+	rows := make([]row, t.states())
+	for state := range rows {
+		rows[state].State = state
+		for _, terminal := range terminals {
+			rows[state].Actions = append(rows[state].Actions, tableCell{
+				Text:    cells[state][terminal],
+				Dropped: dropped[state][Terminal(terminal)],
+			})
+		}
+		for _, symbol := range nonterminals {
+			rows[state].Gotos = append(rows[state].Gotos, cells[state][symbol])
+		}
+	}
+	return tableTemplate.Execute(w, struct {
+		Title                     string
+		ShiftReduce, ReduceReduce int
+		Terminals, Nonterminals   []Symbol
+		Rows                      []row
+	}{title, t.ShiftReduceConflicts, t.ReduceReduceConflicts, terminals, nonterminals, rows})
+}
@@ -0,0 +1,144 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+func TestLRTable_WriteCSV(t *testing.T) {
+	p := &Parser{
+		Grammar: &Grammar{
+			AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
+			Productions: []Production{
+				{Head: "S", Body: []Symbol{"B", "B"}},
+				{Head: "B", Body: []Symbol{"a", "B"}},
+				{Head: "B", Body: []Symbol{"b"}},
+			},
+			Terminals: Set[Terminal]{}.AddAll("a", "b", EPSILON, TERMINATE),
+		},
+	}
+	p.BuildFirstSet()
+	p.BuildTable()
+	var b strings.Builder
+	if err := p.Table.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := `state,a,b,$,B,S
+0,s3,s4,,2,1
+1,,,acc,,
+2,s6,s7,,5,
+3,s3,s4,,8,
+4,r2,r2,,,
+5,,,r0,,
+6,s6,s7,,9,
+7,,,r2,,
+8,r1,r1,,,
+9,,,r1,,
+`
+	if b.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+func TestLRTable_WriteHTML(t *testing.T) {
+	p := &Parser{
+		Grammar: &Grammar{
+			AugmentedProduction: Production{Head: "E'", Body: []Symbol{"E"}},
+			Productions: []Production{
+				{Head: "E", Body: []Symbol{"E", "+", "E"}},
+				{Head: "E", Body: []Symbol{"id"}},
+			},
+			Terminals: Set[Terminal]{}.AddAll("+", "id", EPSILON, TERMINATE),
+		},
+	}
+	p.BuildFirstSet()
+	p.BuildTable()
+	var b strings.Builder
+	if err := p.Table.WriteHTML(&b, "E → E + E"); err != nil {
+		t.Fatal(err)
+	}
+	page := b.String()
+	for _, expected := range []string{
+		"<title>E → E &#43; E</title>",
+		"<p>1 shift/reduce and 0 reduce/reduce conflicts",
+		`<tr><th rowspan="2">State</th><th colspan="3">ACTION</th><th class="goto" colspan="1">GOTO</th></tr>`,
+		`<tr><th>&#43;</th><th>id</th><th>$</th><th class="goto">E</th></tr>`,
+		`<tr><th>0</th><td></td><td>s2</td><td></td><td class="goto">1</td></tr>`,
+		`<td class="conflict" title="dropped r0">s3</td>`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected %q in\n%s", expected, page)
+		}
+	}
+}
+
+func TestLRTable_WriteLab(t *testing.T) {
+	p := &Parser{
+		Grammar: &Grammar{
+			AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
+			Productions: []Production{
+				{Head: "S", Body: []Symbol{"B", "B"}},
+				{Head: "B", Body: []Symbol{"a", "B"}},
+				{Head: "B", Body: []Symbol{"b"}},
+			},
+			Terminals: Set[Terminal]{}.AddAll("a", "b", EPSILON, TERMINATE),
+		},
+	}
+	p.BuildFirstSet()
+	p.BuildTable()
+	var b strings.Builder
+	if err := p.Table.WriteLab(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := `      | ACTION       | GOTO
+状态  | a   b   $    | B  S
+0     | s3  s4       | 2  1
+1     |         acc  |
+2     | s6  s7       | 5
+3     | s3  s4       | 8
+4     | r2  r2       |
+5     |         r0   |
+6     | s6  s7       | 9
+7     |         r2   |
+8     | r1  r1       |
+9     |         r1   |
+`
+	if b.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	if err := p.WriteLabItems(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected = `I0:
+    S' → · S, $
+    S → · B B, $
+    B → · a B, a
+    B → · a B, b
+    B → · b, a
+    B → · b, b
+
+I1:
+    S' → S ·, $
+
+I2:
+    S → B · B, $
+    B → · a B, $
+    B → · b, $
+
+I3:
+    B → a · B, a
+    B → a · B, b
+    B → · a B, a
+    B → · a B, b
+    B → · b, a
+    B → · b, b
+`
+	if items := b.String(); !strings.HasPrefix(items, expected) {
+		t.Errorf("Expected the items to start with\n%s\ngot\n%s", expected, items)
+	}
+}
@@ -0,0 +1,264 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"app/lexer"
+)
+
+// Step is a snapshot of the parser taken right before an action is performed.
+type Step struct {
+	States  []int
+	Symbols []Symbol
+	Input   int    // index of the lookahead token in Trace.Input
+	Action  string // the action performed, e.g. "shift 5" or "reduce expr → expr + term"
+
+	Type   ActionType // the type of the action, ERROR if it failed
+	Number int        // the state shifted to or the production reduced by
+	Goto   int        // the state gone to after a reduction
+}
+
+// Trace records every step of a parse, it can be replayed to show how the
+// input was recognized.
+type Trace struct {
+	Input     []string // tokens read from the lexer, in order
+	Terminals []Symbol // the terminals the tokens of Input are read as
+	Steps     []Step
+}
+
+// ParseTrace parses the input like Parse and records the steps taken.
+func (p *Parser) ParseTrace(l *lexer.Lexer, logger func(string)) (*Walker, *Trace) {
+	trace := &Trace{}
+	walker, _ := p.Tables().parse(context.Background(), l, logger, trace)
+	return walker, trace
+}
+
+// ParseWithTrace parses the input like Parse and writes its steps to w as
+// WriteText does, followed by the rightmost derivation of the input if asked
+// and the parse accepted it.
+func (p *Parser) ParseWithTrace(l *lexer.Lexer, w io.Writer, derivation bool) (*Walker, error) {
+	walker, trace := p.ParseTrace(l, func(string) {})
+	if err := trace.WriteText(w); err != nil {
+		return walker, err
+	}
+	if !derivation || !trace.Accepted() {
+		return walker, nil
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return walker, err
+	}
+	return walker, trace.WriteDerivation(w)
+}
+
+// traceInput is the tokens of the remaining input a line of the text of a
+// trace shows.
+const traceInput = 6
+
+// WriteText writes a line per step: its number from 1, the state stack from
+// the bottom, the remaining input, the first tokens of it only, and the
+// action, "shift 5", "reduce by 12: expr → expr + term, goto 7", "accept" or
+// the error.
+func (t *Trace) WriteText(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	lines := make([][3]string, len(t.Steps))
+	widths := [2]int{len("states"), len("input")}
+	for i, step := range t.Steps {
+		states := make([]string, len(step.States))
+		for j, state := range step.States {
+			states[j] = strconv.Itoa(state)
+		}
+		input := t.Input[min(max(step.Input, 0), len(t.Input)):]
+		rest := strings.Join(input[:min(len(input), traceInput)], " ")
+		if len(input) > traceInput {
+			rest += " ..."
+		}
+		action := step.Action
+		if step.Type == REDUCE {
+			_, production, _ := strings.Cut(step.Action, " ")
+			action = fmt.Sprintf("reduce by %d: %s, goto %d", step.Number, production, step.Goto)
+		}
+		lines[i] = [3]string{strings.Join(states, " "), rest, action}
+		widths[0] = max(widths[0], utf8.RuneCountInString(lines[i][0]))
+		widths[1] = max(widths[1], utf8.RuneCountInString(lines[i][1]))
+	}
+	width := len(strconv.Itoa(len(t.Steps)))
+	fmt.Fprintf(b, "%*s  %-*s  %-*s  %s\n", width, "#", widths[0], "states", widths[1], "input", "action")
+	for i, line := range lines {
+		fmt.Fprintf(b, "%*d  %-*s  %-*s  %s\n", width, i+1, widths[0], line[0], widths[1], line[1], line[2])
+	}
+	return b.Flush()
+}
+
+// Accepted checks if the parse traced accepted the input.
+func (t *Trace) Accepted() bool {
+	return len(t.Steps) > 0 && t.Steps[len(t.Steps)-1].Type == ACCEPT
+}
+
+// Derivation returns the rightmost derivation of the input the parse
+// accepted, its right sentential forms from the start symbol to the
+// terminals of the input: an LR parse finds it backwards, each reduction
+// undoing the step of the derivation that rewrote the rightmost nonterminal.
+func (t *Trace) Derivation() ([][]Symbol, error) {
+	if !t.Accepted() {
+		return nil, fmt.Errorf("no derivation, the parse did not accept the input")
+	}
+	var forms [][]Symbol
+	for _, step := range t.Steps {
+		if step.Type != REDUCE {
+			continue
+		}
+		// the stack and the input left before the reduction
+		form := slices.Clone(step.Symbols)
+		for _, terminal := range t.Terminals[step.Input:] {
+			if terminal != TERMINATE {
+				form = append(form, terminal)
+			}
+		}
+		forms = append(forms, form)
+	}
+	// the start symbol is all the stack holds once accepted
+	forms = append(forms, t.Steps[len(t.Steps)-1].Symbols)
+	slices.Reverse(forms)
+	return forms, nil
+}
+
+// WriteDerivation writes the rightmost derivation of the input, a sentential
+// form per line, each after the first derived from the one above by ⇒.
+func (t *Trace) WriteDerivation(w io.Writer) error {
+	forms, err := t.Derivation()
+	if err != nil {
+		return err
+	}
+	b := bufio.NewWriter(w)
+	for i, form := range forms {
+		symbols := make([]string, len(form))
+		for j, symbol := range form {
+			symbols[j] = string(symbol)
+		}
+		if i > 0 {
+			b.WriteString("⇒ ")
+		}
+		if len(form) == 0 {
+			symbols = []string{string(EPSILON)}
+		}
+		fmt.Fprintln(b, strings.Join(symbols, " "))
+	}
+	return b.Flush()
+}
+
+// snapshot returns a step holding the current stacks of the walker, the
+// action is filled in once it has been performed.
+func (t *Trace) snapshot(w *Walker) Step {
+	step := Step{Input: len(t.Input) - 1}
+	w.States.Foreach(func(state int) {
+		step.States = append(step.States, state)
+	})
+	w.Symbols.Foreach(func(symbol Symbol) {
+		step.Symbols = append(step.Symbols, symbol)
+	})
+	return step
+}
+
+// describe returns the text of an action as shown in a trace.
+func (w *Walker) describe(action Action) string {
+	switch action.Type {
+	case SHIFT, GOTO:
+		return fmt.Sprintf("%s %d", action.Type, action.Number)
+	case REDUCE:
+		return "reduce " + formatProduction(w.Grammar.Productions[action.Number])
+	default:
+		return string(action.Type)
+	}
+}
+
+// formatProduction returns the production in the textbook notation, e.g. "A → α".
+func formatProduction(production Production) string {
+	body := make([]string, len(production.Body))
+	for i, symbol := range production.Body {
+		body[i] = string(symbol)
+	}
+	return fmt.Sprintf("%s → %s", production.Head, strings.Join(body, " "))
+}
+
+var traceTemplate = template.Must(template.New("trace").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: monospace; margin: 2em; }
+table { border-collapse: collapse; margin-top: 1em; }
+td, th { border: 1px solid #999; padding: 4px 8px; text-align: left; vertical-align: top; }
+.current { background: #ffe08a; }
+.consumed { color: #aaa; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<button id="first">|&lt;</button>
+<button id="prev">&lt; Prev</button>
+<span id="counter"></span>
+<button id="next">Next &gt;</button>
+<button id="last">&gt;|</button>
+<table>
+<tr><th>States</th><td id="states"></td></tr>
+<tr><th>Symbols</th><td id="symbols"></td></tr>
+<tr><th>Input</th><td id="input"></td></tr>
+<tr><th>Action</th><td id="action"></td></tr>
+</table>
+<script>
+const trace = {{.Trace}};
+let current = 0;
+function text(s) {
+	const span = document.createElement("span");
+	span.textContent = s;
+	return span;
+}
+function show() {
+	const step = trace.Steps[current];
+	document.getElementById("counter").textContent = (current + 1) + " / " + trace.Steps.length;
+	document.getElementById("states").textContent = (step.States || []).join(" ");
+	document.getElementById("symbols").textContent = (step.Symbols || []).join(" ");
+	document.getElementById("action").textContent = step.Action;
+	const input = document.getElementById("input");
+	input.replaceChildren();
+	(trace.Input || []).forEach((token, i) => {
+		const span = text(token + " ");
+		if (i < step.Input) span.className = "consumed";
+		if (i === step.Input) span.className = "current";
+		input.appendChild(span);
+	});
+}
+function go(i) {
+	current = Math.max(0, Math.min(trace.Steps.length - 1, i));
+	show();
+}
+document.getElementById("first").onclick = () => go(0);
+document.getElementById("prev").onclick = () => go(current - 1);
+document.getElementById("next").onclick = () => go(current + 1);
+document.getElementById("last").onclick = () => go(trace.Steps.length - 1);
+document.addEventListener("keydown", e => {
+	if (e.key === "ArrowLeft") go(current - 1);
+	if (e.key === "ArrowRight") go(current + 1);
+});
+if (trace.Steps && trace.Steps.length > 0) show();
+</script>
+</body>
+</html>
+`))
+
+// WriteHTML writes a standalone HTML page replaying the trace step by step.
+func (t *Trace) WriteHTML(w io.Writer, title string) error {
+	return traceTemplate.Execute(w, struct {
+		Title string
+		Trace *Trace
+	}{title, t})
+}
@@ -0,0 +1,62 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+)
+
+func TestParser_ParseTrace(t *testing.T) {
+	_, trace := sharedParser().ParseTrace(lexer.NewLexer(strings.NewReader("{ int a; a = 1; }")), func(string) {})
+	if len(trace.Input) != 10 || trace.Input[0] != "{" || trace.Input[9] != "$" {
+		t.Fatalf("Unexpected input %v", trace.Input)
+	}
+	first, last := trace.Steps[0], trace.Steps[len(trace.Steps)-1]
+	if !strings.HasPrefix(first.Action, "shift ") || first.Input != 0 || len(first.States) != 1 {
+		t.Errorf("Unexpected first step %+v", first)
+	}
+	if last.Action != "accept" || last.Input != 9 {
+		t.Errorf("Unexpected last step %+v", last)
+	}
+	if !slices.ContainsFunc(trace.Steps, func(step Step) bool { return step.Action == "reduce decl → type declarators ;" }) {
+		t.Errorf("Expected a reduction by decl → type declarators ;")
+	}
+
+	html := &strings.Builder{}
+	if err := trace.WriteHTML(html, "a < b"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), "<title>a &lt; b</title>") || !strings.Contains(html.String(), `"Action":"accept"`) {
+		t.Errorf("Unexpected HTML:\n%s", html.String())
+	}
+}
+
+func TestParser_ParseWithTrace(t *testing.T) {
+	var b strings.Builder
+	walker, err := sharedParser().ParseWithTrace(lexer.NewLexer(strings.NewReader("{ int a; a = 1; }")), &b, true)
+	if err != nil || walker == nil {
+		t.Fatalf("Expected the input parsed, got %v", err)
+	}
+	text, derivation, _ := strings.Cut(b.String(), "\n\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if fields := strings.Fields(lines[0]); !slices.Equal(fields, []string{"#", "states", "input", "action"}) {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); len(fields) < 9 || fields[0] != "1" || fields[1] != "0" || fields[2] != "{" || fields[8] != "..." || fields[9] != "shift" {
+		t.Errorf("Unexpected first step %q", lines[1])
+	}
+	if fields := strings.Fields(lines[len(lines)-1]); !slices.Equal(fields[len(fields)-2:], []string{"$", "accept"}) {
+		t.Errorf("Unexpected last step %q", lines[len(lines)-1])
+	}
+	if !strings.Contains(text, ": decl → type declarators ;, goto ") {
+		t.Errorf("Expected the reduction by decl → type declarators ; and its goto, got\n%s", text)
+	}
+
+	forms := strings.Split(strings.TrimSuffix(derivation, "\n"), "\n")
+	if forms[0] != "program" || forms[len(forms)-1] != "⇒ { basic id ; id = num ; }" {
+		t.Errorf("Expected the derivation from the start symbol to the input, got\n%s", derivation)
+	}
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"slices"
 	"sync"
@@ -10,7 +11,7 @@ import (
 
 type Parser struct {
 	Grammar *Grammar
-	Symbols Set[Symbol]
+	Symbols *OrderedSet[Symbol] // in the order the grammar declares them, see BuildSymbols
 
 	FirstSet FirstSet
 
@@ -18,21 +19,61 @@ type Parser struct {
 
 	Table *LRTable
 
+	Limits Limits
+	Budget Budget // what a program may use, see Compile, and the states built for the grammar
+	Checks Checks
+
+	Workers int // the goroutines BuildStates computes the states on, GOMAXPROCS if zero or less
+
 	_mu sync.Mutex
 }
 
+// Limits bounds the resources a single parse may use, so that pathological
+// inputs or grammar bugs fail with ErrResourceLimit instead of hanging.
+// A limit of zero or less disables the check.
+type Limits struct {
+	MaxDepth  int // maximum size of the state stack
+	MaxSteps  int // maximum number of actions performed
+	MaxErrors int // number of errors reported by the rules after which the parse stops
+}
+
+// DefaultLimits are the limits of a parser created by NewParser.
+var DefaultLimits = Limits{MaxDepth: 10000, MaxSteps: 10000000}
+
+// ErrResourceLimit is returned when a parse exceeds the parser's Limits.
+var ErrResourceLimit = errors.New("parser resource limit exceeded")
+
+// ErrTooManyErrors is returned when the rules report Limits.MaxErrors errors.
+var ErrTooManyErrors = errors.New("too many errors, stopping")
+
+// Check returns an error if the walker has gone beyond the limits after the given number of steps.
+func (l Limits) Check(w *Walker, steps int) error {
+	if l.MaxErrors > 0 && w.errorCount >= l.MaxErrors {
+		return fmt.Errorf("%w: %d errors reported", ErrTooManyErrors, w.errorCount)
+	}
+	if l.MaxDepth > 0 && w.States.Size() > l.MaxDepth {
+		return fmt.Errorf("%w: stack depth exceeds %d", ErrResourceLimit, l.MaxDepth)
+	}
+	if l.MaxSteps > 0 && steps > l.MaxSteps {
+		return fmt.Errorf("%w: more than %d steps", ErrResourceLimit, l.MaxSteps)
+	}
+	return nil
+}
+
 func NewParser() *Parser {
 	return &Parser{
 		Grammar:  NewGrammar(),
-		Symbols:  Set[Symbol]{},
+		Symbols:  NewOrderedSet[Symbol](),
 		FirstSet: FirstSet{},
 		States:   States{},
+		Limits:   DefaultLimits,
 
 		_mu: sync.Mutex{},
 	}
 }
 
-type FirstSet map[Symbol]Set[Terminal]
+// FirstSet is the FIRST set of each symbol, sorted by the names of the terminals
+type FirstSet map[Symbol]*OrderedSet[Terminal]
 
 type State struct {
 	Index       int
@@ -0,0 +1,239 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"app/lexer"
+)
+
+// Type is the type of a value in the symbol table: a basic type, an array or
+// a record.
+type Type interface {
+	Size() int  // bytes a value takes
+	Align() int // bytes the address of a value is a multiple of
+	String() string
+}
+
+// BasicType is a type the language names, such as int or float32.
+type BasicType struct {
+	Kind     lexer.TokenSpecificType
+	IntWidth IntWidth // of int, see Walker.IntWidth
+}
+
+// Basic returns the basic type of the kind.
+func Basic(kind lexer.TokenSpecificType) *BasicType {
+	return &BasicType{Kind: kind}
+}
+
+// Size returns the size of the kind, a word for the strings, the functions
+// and the pointers, which refer to their value, and the bytes of its width
+// for int.
+func (t *BasicType) Size() int {
+	switch t.Kind {
+	case lexer.TypeString, lexer.TypeFunc, lexer.TypePointer:
+		return 4
+	case lexer.TypeInt:
+		return t.IntWidth.Bytes()
+	}
+	return lexer.SizeOf(t.Kind)
+}
+
+func (t *BasicType) Align() int {
+	return max(t.Size(), 1)
+}
+
+func (t *BasicType) String() string {
+	return t.Kind.ToString()
+}
+
+// ArrayType is a fixed number of elements of a type, one after the other.
+type ArrayType struct {
+	Elem Type
+	Len  int
+}
+
+// ArrayOf returns the array of the dimensions, the first the outermost, of
+// elements of the type.
+func ArrayOf(elem Type, dims ...int) Type {
+	for i := len(dims) - 1; i >= 0; i-- {
+		elem = &ArrayType{Elem: elem, Len: dims[i]}
+	}
+	return elem
+}
+
+func (t *ArrayType) Size() int {
+	return t.Len * t.Elem.Size()
+}
+
+func (t *ArrayType) Align() int {
+	return t.Elem.Align()
+}
+
+// String returns the type as declared, the element then the dimensions,
+// such as int[2][3].
+func (t *ArrayType) String() string {
+	dims := ""
+	var elem Type = t
+	for array, ok := elem.(*ArrayType); ok; array, ok = elem.(*ArrayType) {
+		dims += fmt.Sprintf("[%d]", array.Len)
+		elem = array.Elem
+	}
+	return elem.String() + dims
+}
+
+// Field is a field of a record, at its offset in bytes from the start.
+type Field struct {
+	Name   string
+	Type   Type
+	Offset int
+}
+
+// RecordType is a struct, its fields laid out in order, each aligned.
+type RecordType struct {
+	Name   string // empty for an anonymous struct
+	Fields []Field
+	size   int
+	align  int
+}
+
+// NewRecordType lays out the fields of a record in order, each at the next
+// offset aligned for its type, and the size rounded up to the alignment of
+// the record, so that the elements of an array of them are aligned too. The
+// offsets given are ignored.
+func NewRecordType(name string, fields ...Field) (*RecordType, error) {
+	r := &RecordType{Name: name, Fields: make([]Field, len(fields)), align: 1}
+	seen := map[string]bool{}
+	for i, f := range fields {
+		if seen[f.Name] {
+			return nil, fmt.Errorf("field %s of struct %s declared twice", f.Name, name)
+		}
+		seen[f.Name] = true
+		if f.Type == nil || f.Type.Size() <= 0 {
+			return nil, fmt.Errorf("invalid type of field %s of struct %s", f.Name, name)
+		}
+		align := f.Type.Align()
+		r.size = (r.size + align - 1) / align * align
+		r.Fields[i] = Field{Name: f.Name, Type: f.Type, Offset: r.size}
+		r.size += f.Type.Size()
+		r.align = max(r.align, align)
+	}
+	r.size = (r.size + r.align - 1) / r.align * r.align
+	return r, nil
+}
+
+func (t *RecordType) Size() int {
+	return t.size
+}
+
+func (t *RecordType) Align() int {
+	return t.align
+}
+
+// String returns struct and the name of the record, or its fields if it has
+// none.
+func (t *RecordType) String() string {
+	if t.Name != "" {
+		return "struct " + t.Name
+	}
+	fields := make([]string, len(t.Fields))
+	for i, f := range t.Fields {
+		fields[i] = f.Type.String() + " " + f.Name
+	}
+	return "struct { " + strings.Join(fields, "; ") + " }"
+}
+
+// Field returns the field of the name.
+func (t *RecordType) Field(name string) (Field, bool) {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Equal checks if the types are the same structurally: basic types of the
+// same kind, arrays of the same length of equal elements, or records with
+// the same fields in the same order, whatever their names.
+func Equal(a, b Type) bool {
+	switch a := a.(type) {
+	case *BasicType:
+		b, ok := b.(*BasicType)
+		return ok && a.Kind == b.Kind
+	case *ArrayType:
+		b, ok := b.(*ArrayType)
+		return ok && a.Len == b.Len && Equal(a.Elem, b.Elem)
+	case *RecordType:
+		b, ok := b.(*RecordType)
+		if !ok || len(a.Fields) != len(b.Fields) {
+			return false
+		}
+		for i, f := range a.Fields {
+			if f.Name != b.Fields[i].Name || !Equal(f.Type, b.Fields[i].Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Elem returns the type of the elements of the innermost array, the type
+// itself if it is no array, and the number of them.
+func Elem(t Type) (Type, int) {
+	n := 1
+	for array, ok := t.(*ArrayType); ok; array, ok = t.(*ArrayType) {
+		n *= array.Len
+		t = array.Elem
+	}
+	return t, n
+}
+
+// Offset returns the offset in bytes from the start of a value of the type
+// of the part the path selects, and the type of that part. Each step of the
+// path is an int indexing an array or a string naming a field of a record.
+func Offset(t Type, path ...any) (int, Type, error) {
+	offset := 0
+	for _, step := range path {
+		switch step := step.(type) {
+		case int:
+			array, ok := t.(*ArrayType)
+			if !ok {
+				return 0, nil, fmt.Errorf("%s is not an array", t)
+			}
+			if step < 0 || step >= array.Len {
+				return 0, nil, fmt.Errorf("index %d out of range of %s", step, t)
+			}
+			offset += step * array.Elem.Size()
+			t = array.Elem
+		case string:
+			record, ok := t.(*RecordType)
+			if !ok {
+				return 0, nil, fmt.Errorf("%s is not a struct", t)
+			}
+			f, ok := record.Field(step)
+			if !ok {
+				return 0, nil, fmt.Errorf("%s has no field %s", t, step)
+			}
+			offset += f.Offset
+			t = f.Type
+		default:
+			return 0, nil, fmt.Errorf("invalid step %v of a path", step)
+		}
+	}
+	return offset, t, nil
+}
+
+// AddressOf returns the address in bytes of the part of the item the path
+// selects, as Offset does, and its type. The item must have a ValueType.
+func (item *SymbolTableItem) AddressOf(path ...any) (int, Type, error) {
+	if item.ValueType == nil {
+		return 0, nil, fmt.Errorf("%s has no type", item.Variable)
+	}
+	offset, t, err := Offset(item.ValueType, path...)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", item.Variable, err)
+	}
+	return item.Address*4 + offset, t, nil
+}
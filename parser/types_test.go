@@ -0,0 +1,136 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+)
+
+func TestNewRecordType(t *testing.T) {
+	point, err := NewRecordType("point",
+		Field{Name: "tag", Type: Basic(lexer.TypeInt8)},
+		Field{Name: "x", Type: Basic(lexer.TypeInt)},
+		Field{Name: "y", Type: Basic(lexer.TypeFloat)},
+	)
+	if err != nil {
+		t.Fatalf("NewRecordType: %v", err)
+	}
+	if point.Size() != 12 || point.Align() != 4 {
+		t.Errorf("Expected size 12 aligned to 4, got %d aligned to %d", point.Size(), point.Align())
+	}
+	for name, offset := range map[string]int{"tag": 0, "x": 4, "y": 8} {
+		if f, ok := point.Field(name); !ok || f.Offset != offset {
+			t.Errorf("Expected %s at %d, got %v", name, offset, f)
+		}
+	}
+
+	tail, err := NewRecordType("", Field{Name: "n", Type: Basic(lexer.TypeInt64)}, Field{Name: "c", Type: Basic(lexer.TypeInt8)})
+	if err != nil {
+		t.Fatalf("NewRecordType: %v", err)
+	}
+	if tail.Size() != 16 || tail.String() != "struct { int64 n; int8 c }" {
+		t.Errorf("Expected struct { int64 n; int8 c } of 16 bytes, got %s of %d", tail, tail.Size())
+	}
+
+	if _, err := NewRecordType("p", Field{Name: "a", Type: Basic(lexer.TypeInt)}, Field{Name: "a", Type: Basic(lexer.TypeInt)}); err == nil {
+		t.Error("Expected a field declared twice rejected")
+	}
+	if _, err := NewRecordType("p", Field{Name: "a", Type: Basic(lexer.TypeVoid)}); err == nil {
+		t.Error("Expected a void field rejected")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	fields := []Field{{Name: "x", Type: Basic(lexer.TypeInt)}, {Name: "y", Type: ArrayOf(Basic(lexer.TypeFloat), 2)}}
+	a, _ := NewRecordType("a", fields...)
+	b, _ := NewRecordType("b", fields...)
+	c, _ := NewRecordType("a", fields[1], fields[0])
+
+	tests := []struct {
+		a, b     Type
+		expected bool
+	}{
+		{Basic(lexer.TypeInt), Basic(lexer.TypeInt), true},
+		{Basic(lexer.TypeInt), Basic(lexer.TypeInt32), false},
+		{ArrayOf(Basic(lexer.TypeInt), 2, 3), ArrayOf(ArrayOf(Basic(lexer.TypeInt), 3), 2), true},
+		{ArrayOf(Basic(lexer.TypeInt), 2, 3), ArrayOf(Basic(lexer.TypeInt), 3, 2), false},
+		{a, b, true},
+		{a, c, false},
+		{ArrayOf(a, 4), ArrayOf(b, 4), true},
+		{a, Basic(lexer.TypeInt), false},
+	}
+	for _, test := range tests {
+		if Equal(test.a, test.b) != test.expected {
+			t.Errorf("Expected Equal(%s, %s) to be %v", test.a, test.b, test.expected)
+		}
+	}
+}
+
+func TestOffset(t *testing.T) {
+	point, _ := NewRecordType("point", Field{Name: "x", Type: Basic(lexer.TypeInt8)}, Field{Name: "y", Type: ArrayOf(Basic(lexer.TypeInt), 3)})
+	points := ArrayOf(point, 2, 5)
+	if points.String() != "struct point[2][5]" || points.Size() != 2*5*16 {
+		t.Errorf("Expected struct point[2][5] of 160 bytes, got %s of %d", points, points.Size())
+	}
+
+	offset, part, err := Offset(points, 1, 2, "y", 1)
+	if err != nil {
+		t.Fatalf("Offset: %v", err)
+	}
+	if expected := (1*5+2)*16 + 4 + 4; offset != expected || !Equal(part, Basic(lexer.TypeInt)) {
+		t.Errorf("Expected an int at %d, got %s at %d", expected, part, offset)
+	}
+
+	for _, test := range []struct {
+		path     []any
+		expected string
+	}{
+		{[]any{"x"}, "struct point[2][5] is not a struct"},
+		{[]any{2}, "index 2 out of range of struct point[2][5]"},
+		{[]any{0, 0, 0}, "struct point is not an array"},
+		{[]any{0, 0, "z"}, "struct point has no field z"},
+		{[]any{0, 0, "x", 0}, "int8 is not an array"},
+		{[]any{1.5}, "invalid step 1.5"},
+	} {
+		if _, _, err := Offset(points, test.path...); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected an error with %q for %v, got %v", test.expected, test.path, err)
+		}
+	}
+}
+
+func TestSymbolTable_RegisterType(t *testing.T) {
+	point, _ := NewRecordType("point", Field{Name: "x", Type: Basic(lexer.TypeInt)}, Field{Name: "y", Type: Basic(lexer.TypeInt16)})
+	st := NewSymbolTable(nil, nil)
+	st.EnterScope()
+	n := &SymbolTableItem{Variable: "n", Type: SymbolTableItemTypeVariable, ValueType: Basic(lexer.TypeInt)}
+	a := &SymbolTableItem{Variable: "a", Type: SymbolTableItemTypeVariable, ValueType: ArrayOf(point, 3)}
+	for _, item := range []*SymbolTableItem{n, a} {
+		if err := st.Register(item); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	if a.Type != SymbolTableItemTypeArray || a.VariableSize != 8 || a.ArraySize != 3 || a.UnderlyingType != "struct point" {
+		t.Errorf("Expected an array of 3 struct point of 8 bytes, got %s of %d %s of %d bytes",
+			a.Type, a.ArraySize, a.UnderlyingType, a.VariableSize)
+	}
+	if a.Address != n.Address+1 {
+		t.Errorf("Expected a right after n, got %d and %d", n.Address, a.Address)
+	}
+
+	address, part, err := a.AddressOf(1, "y")
+	if err != nil {
+		t.Fatalf("AddressOf: %v", err)
+	}
+	if expected := a.Address*4 + 8 + 4; address != expected || part.String() != "int16" {
+		t.Errorf("Expected a[1].y an int16 at %d, got %s at %d", expected, part, address)
+	}
+	if _, _, err := a.AddressOf(3); err == nil || !strings.HasPrefix(err.Error(), "a: ") {
+		t.Errorf("Expected a[3] out of range, got %v", err)
+	}
+
+	if err := st.Register(&SymbolTableItem{Variable: "v", Type: SymbolTableItemTypeVariable, ValueType: Basic(lexer.TypeVoid)}); err == nil {
+		t.Error("Expected a void variable rejected")
+	}
+}
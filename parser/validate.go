@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	. "app/utils/collections"
+)
+
+// GrammarReport is what Grammar.Validate finds wrong with a grammar, each
+// in the order of the productions. Such a grammar still has a table, but
+// its faults show there as conflicts or states that are hard to trace back.
+type GrammarReport struct {
+	Start        Symbol
+	Unreachable  []Symbol     // nonterminals the start symbol derives no sentential form with
+	Unproductive []Symbol     // nonterminals deriving no string of terminals, such as those heading no production
+	Duplicates   []Production // productions given more than once, each once, an empty body the same as ε
+	Unused       []Terminal   // terminals in no production but ε and $
+}
+
+// Validate checks the grammar for the nonterminals that are unreachable
+// from the start symbol or unproductive, the productions given twice and
+// the terminals never used.
+func (g *Grammar) Validate() *GrammarReport {
+	r := &GrammarReport{Start: g.Start()}
+	nonterminals := NewOrderedSet[Symbol]()
+	used := NewSet[Terminal]()
+	for _, production := range g.Productions {
+		nonterminals.Add(production.Head)
+	}
+	for _, production := range g.Productions {
+		for _, symbol := range production.Body {
+			if terminal, ok := g.Terminal(symbol); ok {
+				used.Add(terminal)
+			} else if !symbol.IsEpsilon() {
+				nonterminals.Add(symbol)
+			}
+		}
+	}
+
+	reached := NewSet[Symbol]().Add(r.Start)
+	work := Stack[Symbol]{}
+	work.Push(r.Start)
+	for !work.IsEmpty() {
+		head, _ := work.Pop()
+		for _, production := range g.Productions {
+			if production.Head != head {
+				continue
+			}
+			for _, symbol := range production.Body {
+				if !g.IsTerminal(symbol) && !symbol.IsEpsilon() && !reached.Contains(symbol) {
+					reached.Add(symbol)
+					work.Push(symbol)
+				}
+			}
+		}
+	}
+
+	productive := NewSet[Symbol]()
+	for changed := true; changed; {
+		changed = false
+		for _, production := range g.Productions {
+			if productive.Contains(production.Head) {
+				continue
+			}
+			if !slices.ContainsFunc(production.Body, func(symbol Symbol) bool {
+				return !g.IsTerminal(symbol) && !symbol.IsEpsilon() && !productive.Contains(symbol)
+			}) {
+				productive.Add(production.Head)
+				changed = true
+			}
+		}
+	}
+
+	for symbol := range nonterminals.All() {
+		if !reached.Contains(symbol) {
+			r.Unreachable = append(r.Unreachable, symbol)
+		}
+		if !productive.Contains(symbol) {
+			r.Unproductive = append(r.Unproductive, symbol)
+		}
+	}
+
+	seen := map[string]int{}
+	for _, production := range g.Productions {
+		key := formatProduction(Production{Head: production.Head, Body: slices.DeleteFunc(slices.Clone(production.Body), func(s Symbol) bool { return s.IsEpsilon() })})
+		if seen[key]++; seen[key] == 2 {
+			r.Duplicates = append(r.Duplicates, production)
+		}
+	}
+
+	for _, terminal := range slices.Sorted(maps.Keys(g.Terminals)) {
+		if terminal != EPSILON && terminal != TERMINATE && !used.Contains(terminal) {
+			r.Unused = append(r.Unused, terminal)
+		}
+	}
+	return r
+}
+
+// OK checks if the report found nothing wrong.
+func (r *GrammarReport) OK() bool {
+	return len(r.Unreachable)+len(r.Unproductive)+len(r.Duplicates)+len(r.Unused) == 0
+}
+
+// Err returns the faults that make the grammar wrong as an error, nil if
+// there are none. The terminals unused are left out, the lexer reading
+// more tokens than a grammar needs.
+func (r *GrammarReport) Err() error {
+	var errs []error
+	if len(r.Unreachable) > 0 {
+		errs = append(errs, fmt.Errorf("unreachable from %s: %s", r.Start, joinSymbols(r.Unreachable)))
+	}
+	if len(r.Unproductive) > 0 {
+		errs = append(errs, fmt.Errorf("deriving no string of terminals: %s", joinSymbols(r.Unproductive)))
+	}
+	for _, production := range r.Duplicates {
+		if production.Length() == 0 {
+			production.Body = []Symbol{EPSILON}
+		}
+		errs = append(errs, fmt.Errorf("duplicate production %s", formatProduction(production)))
+	}
+	return errors.Join(errs...)
+}
+
+// String returns the faults of the report a line each, with the terminals
+// unused.
+func (r *GrammarReport) String() string {
+	var b strings.Builder
+	if err := r.Err(); err != nil {
+		b.WriteString(err.Error() + "\n")
+	}
+	if len(r.Unused) > 0 {
+		fmt.Fprintf(&b, "unused terminals: %s\n", joinSymbols(r.Unused))
+	}
+	return b.String()
+}
+
+// joinSymbols joins the symbols by commas.
+func joinSymbols[T ~string](symbols []T) string {
+	s := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		s[i] = string(symbol)
+	}
+	return strings.Join(s, ", ")
+}
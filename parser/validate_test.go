@@ -0,0 +1,58 @@
+package parser_test
+
+import (
+	"slices"
+	"testing"
+
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+func TestGrammar_Validate(t *testing.T) {
+	g := &Grammar{
+		Productions: []Production{
+			{Head: "S", Body: []Symbol{"A", "b"}},
+			{Head: "S", Body: []Symbol{"a"}},
+			{Head: "A", Body: []Symbol{"a", "A"}},
+			{Head: "A", Body: []Symbol{EPSILON}},
+			{Head: "A", Body: []Symbol{}},
+			{Head: "S", Body: []Symbol{"B", "c"}},
+			{Head: "B", Body: []Symbol{"b", "B"}},
+			{Head: "C", Body: []Symbol{"c"}},
+			{Head: "S", Body: []Symbol{"a"}},
+			{Head: "S", Body: []Symbol{"D"}},
+		},
+		Terminals: NewSet[Terminal]().AddAll("a", "b", "c", "d", EPSILON, TERMINATE),
+	}
+	r := g.Validate()
+	if r.Start != "S" || !slices.Equal(r.Unreachable, []Symbol{"C"}) || !slices.Equal(r.Unproductive, []Symbol{"B", "D"}) ||
+		!slices.Equal(r.Unused, []Terminal{"d"}) || len(r.Duplicates) != 2 || r.OK() {
+		t.Fatalf("Expected C unreachable, B and D unproductive and d unused, got %+v", r)
+	}
+	if r.Duplicates[0].Head != "A" || r.Duplicates[1].Head != "S" || !slices.Equal(r.Duplicates[1].Body, []Symbol{"a"}) {
+		t.Errorf("Expected A → ε and S → a duplicated, got %v", r.Duplicates)
+	}
+	expected := "unreachable from S: C\n" +
+		"deriving no string of terminals: B, D\n" +
+		"duplicate production A → ε\n" +
+		"duplicate production S → a\n" +
+		"unused terminals: d\n"
+	if r.String() != expected {
+		t.Errorf("Expected the report\n%s\ngot\n%s", expected, r.String())
+	}
+
+	fixed := &Grammar{
+		Productions: []Production{{Head: "S", Body: []Symbol{"A", "b"}}, {Head: "A", Body: []Symbol{EPSILON}}},
+		Terminals:   NewSet[Terminal]().AddAll("b", EPSILON, TERMINATE),
+	}
+	if r := fixed.Validate(); !r.OK() || r.Err() != nil || r.String() != "" {
+		t.Errorf("Expected nothing wrong, got %v", r)
+	}
+
+	// the second alternative of the unmatched statements never ends, so
+	// if statements without else are matched ones
+	r = NewGrammar().Validate()
+	if !slices.Equal(r.Unproductive, []Symbol{"unmatched_stmt"}) || len(r.Unreachable)+len(r.Duplicates)+len(r.Unused) != 0 {
+		t.Errorf("Expected unmatched_stmt alone unproductive in the grammar of the experiment, got %v", r)
+	}
+}
@@ -0,0 +1,204 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// commutative are the operators whose operands may be swapped.
+var commutative = []string{"+", "*", "eq", "ne", "streq", "strne"}
+
+// Canonical returns the value with the operands of a commutative operator in
+// order, so that a + b and b + a are written the same.
+func Canonical(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 3 && slices.Contains(commutative, fields[1]) && fields[0] > fields[2] {
+		return fields[2] + " " + fields[1] + " " + fields[0]
+	}
+	return value
+}
+
+// Simplify applies the algebraic identities x + 0 = x, x - 0 = x, x * 1 = x,
+// x / 1 = x, x * 0 = 0 and x mod 1 = 0 to the value, and folds operations and
+// comparisons on two integer constants, wrapping around the 32 bits of int,
+// see IntWidth.Simplify.
+func Simplify(value string) string {
+	return simplify(value, 0)
+}
+
+func simplify(value string, width IntWidth) string {
+	fields := strings.Fields(value)
+	if len(fields) != 3 {
+		return value
+	}
+	a, op, b := fields[0], fields[1], fields[2]
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if i, j, ok := integers(a, b); ok {
+		switch op {
+		case "+":
+			return strconv.FormatInt(width.Wrap(i+j), 10)
+		case "-":
+			return strconv.FormatInt(width.Wrap(i-j), 10)
+		case "*":
+			return strconv.FormatInt(width.Wrap(i*j), 10)
+		case "mod":
+			if j != 0 {
+				return strconv.FormatInt(i%j, 10)
+			}
+		case "eq", "ne", "lt", "le", "gt", "ge":
+			return strconv.FormatBool(map[string]bool{"eq": i == j, "ne": i != j, "lt": i < j, "le": i <= j, "gt": i > j, "ge": i >= j}[op])
+		}
+		return value
+	}
+	switch {
+	case op == "+" && errB == nil && y == 0, op == "-" && errB == nil && y == 0,
+		op == "*" && errB == nil && y == 1, op == "/" && errB == nil && y == 1:
+		return a
+	case op == "+" && errA == nil && x == 0, op == "*" && errA == nil && x == 1:
+		return b
+	case op == "*" && (errA == nil && x == 0 || errB == nil && y == 0):
+		return "0"
+	case op == "mod" && errB == nil && y == 1:
+		return "0"
+	}
+	return value
+}
+
+// integers parses both operands as integer constants.
+func integers(a, b string) (int64, int64, bool) {
+	i, err1 := strconv.ParseInt(a, 0, 64)
+	j, err2 := strconv.ParseInt(b, 0, 64)
+	return i, j, err1 == nil && err2 == nil
+}
+
+// ValueNumbering numbers the values computed in a basic block, so that equal
+// values get the same number whichever variables they are computed from.
+// Constants and the variables live on entry to the block are numbered on
+// first use, every expression by its operator and the numbers of its
+// operands, with the operands of commutative operators in order.
+type ValueNumbering struct {
+	next    int
+	values  map[string]int   // number of each variable and constant
+	exprs   map[string]int   // number of each expression over numbers
+	holders map[int][]string // variables holding each number, in the order written
+}
+
+// NewValueNumbering creates the value numbering of an empty block.
+func NewValueNumbering() *ValueNumbering {
+	return &ValueNumbering{values: map[string]int{}, exprs: map[string]int{}, holders: map[int][]string{}}
+}
+
+// fresh returns a number no value has yet.
+func (vn *ValueNumbering) fresh() int {
+	vn.next++
+	return vn.next
+}
+
+// Number returns the number of the variable or constant.
+func (vn *ValueNumbering) Number(operand string) int {
+	if n, ok := vn.values[operand]; ok {
+		return n
+	}
+	n := vn.fresh()
+	vn.values[operand] = n
+	if isVariable(operand) {
+		vn.holders[n] = append(vn.holders[n], operand)
+	}
+	return n
+}
+
+// Value returns the number of the value, and whether it has no side effects
+// so that computing it again gives the same number. Calls and values the
+// numbering does not understand get a new number each time.
+func (vn *ValueNumbering) Value(value string) (int, bool) {
+	fields := strings.Fields(value)
+	var key string
+	switch {
+	case len(fields) == 1:
+		return vn.Number(fields[0]), true
+	case len(fields) == 2 && (fields[0] == "minus" || fields[0] == "itof"):
+		key = fmt.Sprintf("%s #%d", fields[0], vn.Number(fields[1]))
+	case len(fields) == 3 && !slices.Contains(opcodes, fields[0]):
+		a, b := vn.Number(fields[0]), vn.Number(fields[2])
+		if slices.Contains(commutative, fields[1]) && a > b {
+			a, b = b, a
+		}
+		key = fmt.Sprintf("#%d %s #%d", a, fields[1], b)
+	case len(fields) == 4 && fields[1] == "[" && fields[3] == "]":
+		// an element of an array, numbered by the array until it is written
+		key = fmt.Sprintf("#%d [ #%d ]", vn.Number(fields[0]), vn.Number(fields[2]))
+	default:
+		return vn.fresh(), false
+	}
+	if n, ok := vn.exprs[key]; ok {
+		return n, true
+	}
+	n := vn.fresh()
+	vn.exprs[key] = n
+	return n, true
+}
+
+// Holder returns the first variable still holding the number.
+func (vn *ValueNumbering) Holder(n int) (string, bool) {
+	if len(vn.holders[n]) == 0 {
+		return "", false
+	}
+	return vn.holders[n][0], true
+}
+
+// Assign records that the variable now holds the number.
+func (vn *ValueNumbering) Assign(variable string, n int) {
+	if old, ok := vn.values[variable]; ok {
+		vn.holders[old] = slices.DeleteFunc(vn.holders[old], func(v string) bool { return v == variable })
+	}
+	vn.values[variable] = n
+	vn.holders[n] = append(vn.holders[n], variable)
+}
+
+// Kill records that the variable, or one of its elements, is written with a
+// value the numbering does not know.
+func (vn *ValueNumbering) Kill(variable string) {
+	vn.Assign(variable, vn.fresh())
+}
+
+// LocalValueNumbering numbers the values of each basic block, simplifying
+// them first, and replaces the computation of a value some variable already
+// holds by a copy of it. Recomputing the value a variable holds into itself
+// is dropped. Blocks start at labels, a conditional jump does not end one as
+// the values are the same on both of its sides.
+func LocalValueNumbering(code []string) []string {
+	return localValueNumbering(code, 0)
+}
+
+func localValueNumbering(code []string, width IntWidth) []string {
+	result := make([]string, 0, len(code))
+	vn := NewValueNumbering()
+	for _, line := range code {
+		if isLabel(line) {
+			vn = NewValueNumbering()
+		}
+		dist, ok := definitionOf(line)
+		if !ok {
+			if v := writtenBy(line); v != "" {
+				vn.Kill(v)
+			}
+			result = append(result, line)
+			continue
+		}
+		_, value, _ := strings.Cut(line, " = ")
+		value = simplify(value, width)
+		n, pure := vn.Value(value)
+		if pure && slices.Contains(vn.holders[n], dist) {
+			continue
+		}
+		if holder, ok := vn.Holder(n); ok && pure && len(strings.Fields(value)) > 1 {
+			value = holder
+		}
+		vn.Assign(dist, n)
+		result = append(result, dist+" = "+value)
+	}
+	return result
+}
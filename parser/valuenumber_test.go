@@ -0,0 +1,89 @@
+package parser_test
+
+import (
+	"slices"
+	"testing"
+
+	. "app/parser"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"x + 0", "x"},
+		{"0 + x", "x"},
+		{"x - 0", "x"},
+		{"x * 1", "x"},
+		{"1.0 * x", "x"},
+		{"x * 0", "0"},
+		{"0 * x", "0"},
+		{"x / 1", "x"},
+		{"x mod 1", "0"},
+		{"3 + 4", "7"},
+		{"7 mod 0", "7 mod 0"},
+		{"1 lt 2", "true"},
+		{"2 ne 2", "false"},
+		{"x + 1", "x + 1"},
+		{"0 - x", "0 - x"},
+		{"call f, 0", "call f, 0"},
+	}
+	for _, tt := range tests {
+		if got := Simplify(tt.value); got != tt.expected {
+			t.Errorf("Simplify(%q): expected %q, got %q", tt.value, tt.expected, got)
+		}
+	}
+	if Canonical("b + a") != "a + b" || Canonical("b mod a") != "b mod a" {
+		t.Errorf("Expected only commutative operands to be ordered")
+	}
+}
+
+func TestLocalValueNumbering(t *testing.T) {
+	code := []string{
+		"$(0x10000001) = a + b",
+		"$(0x10000002) = b + a",
+		"c = a",
+		"$(0x10000003) = c + b",
+		"$(0x10000004) = b mod a",
+		"$(0x10000005) = a mod b",
+		"$(0x10000006) = x * 1",
+		"$(0x10000007) = $(0x10000006) + 0",
+		"y = y * 1",
+		"$(0x10000008) = v [ 1 ]",
+		"v [ 1 ] = a",
+		"$(0x10000009) = v [ 1 ]",
+		"$(0x1000000a) = call read_int, 0",
+		"$(0x1000000b) = call read_int, 0",
+		"a = 1",
+		"$(0x1000000c) = a + b",
+		"L_abs_0:",
+		"$(0x1000000d) = c + b",
+	}
+	expected := []string{
+		"$(0x10000001) = a + b",
+		"$(0x10000002) = $(0x10000001)",
+		"c = a",
+		"$(0x10000003) = $(0x10000001)",
+		"$(0x10000004) = b mod a",
+		"$(0x10000005) = a mod b",
+		"$(0x10000006) = x",
+		"$(0x10000007) = $(0x10000006)",
+		"$(0x10000008) = v [ 1 ]",
+		"v [ 1 ] = a",
+		"$(0x10000009) = v [ 1 ]",
+		"$(0x1000000a) = call read_int, 0",
+		"$(0x1000000b) = call read_int, 0",
+		"a = 1",
+		"$(0x1000000c) = a + b",
+		"L_abs_0:",
+		"$(0x1000000d) = c + b",
+	}
+	if got := LocalValueNumbering(code); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	if got := Peephole([]string{"a = a + 0", "b = a * 0", "if a < 0 goto L_abs_0"}); !slices.Equal(got, []string{"b = 0", "if a < 0 goto L_abs_0"}) {
+		t.Errorf("Expected the identities to be applied, got %v", got)
+	}
+}
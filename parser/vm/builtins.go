@@ -0,0 +1,249 @@
+package vm
+
+import (
+	"fmt"
+	"strconv"
+
+	"app/parser"
+	"app/parser/ir"
+)
+
+// builtin is a function of the runtime, by the name the parser calls it,
+// taking its arguments in order and returning its value, nil for none.
+type builtin func(m *Machine, args []any) (any, error)
+
+var builtins map[string]builtin
+
+func init() {
+	builtins = map[string]builtin{
+		"print_int":   printer(func(v any) (string, bool) { i, ok := v.(int64); return strconv.FormatInt(i, 10), ok }),
+		"print_float": printer(func(v any) (string, bool) { f, ok := toFloat(v); return formatFloat(f), ok }),
+		"print_str":   printer(func(v any) (string, bool) { s, ok := v.(string); return s, ok }),
+		"print_char":  printer(func(v any) (string, bool) { i, ok := v.(int64); return string(rune(i)), ok }),
+		"read_int":    reader[int64],
+		"read_float":  reader[float64],
+		"abs":         ints(1, func(x []int64) int64 { return max(x[0], -x[0]) }),
+		"min":         ints(2, func(x []int64) int64 { return min(x[0], x[1]) }),
+		"max":         ints(2, func(x []int64) int64 { return max(x[0], x[1]) }),
+		// a negative exponent gives 1, as the runtime of codegen does
+		"pow": ints(2, func(x []int64) int64 {
+			result := int64(1)
+			for range x[1] {
+				result *= x[0]
+			}
+			return result
+		}),
+		"sum":   sum,
+		"alloc": alloc,
+	}
+	for _, size := range []int{1, 2, 4, 8} {
+		builtins[fmt.Sprintf("load%d", size)] = load(size, false)
+		builtins[fmt.Sprintf("store%d", size)] = store(size)
+	}
+	builtins["loadu1"] = load(1, true)
+	builtins["loadu2"] = load(2, true)
+}
+
+func printer(format func(any) (string, bool)) builtin {
+	return func(m *Machine, args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected 1 argument, got %d", len(args))
+		}
+		text, ok := format(args[0])
+		if !ok {
+			return nil, fmt.Errorf("cannot print %v", args[0])
+		}
+		_, err := fmt.Fprint(m.out, text)
+		return nil, err
+	}
+}
+
+// formatFloat writes the float as short as it reads back, in the precision
+// of a float32 if it is one.
+func formatFloat(f float64) string {
+	if float64(float32(f)) == f {
+		return strconv.FormatFloat(f, 'f', -1, 32)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// reader reads a number of the type from the input, skipping the spaces
+// before it.
+func reader[T int64 | float64](m *Machine, args []any) (any, error) {
+	if m.in == nil {
+		return nil, fmt.Errorf("no input to read")
+	}
+	var v T
+	if _, err := fmt.Fscan(m.in, &v); err != nil {
+		return nil, fmt.Errorf("reading the input: %w", err)
+	}
+	return v, nil
+}
+
+func ints(n int, f func([]int64) int64) builtin {
+	return func(m *Machine, args []any) (any, error) {
+		if len(args) != n {
+			return nil, fmt.Errorf("expected %d arguments, got %d", n, len(args))
+		}
+		x := make([]int64, n)
+		for i, arg := range args {
+			v, ok := arg.(int64)
+			if !ok {
+				return nil, fmt.Errorf("argument %v is not an integer", arg)
+			}
+			x[i] = v
+		}
+		return f(x), nil
+	}
+}
+
+// sum adds the first n elements of the array the pointer points into.
+func sum(m *Machine, args []any) (any, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected 2 arguments, got %d", len(args))
+	}
+	p, okP := args[0].(Pointer)
+	n, okN := args[1].(int64)
+	if !okP || !okN {
+		return nil, fmt.Errorf("expected a pointer and an integer, got %v and %v", args[0], args[1])
+	}
+	width := 4
+	for _, item := range m.items {
+		if start := item.Address * 4; int(p) >= start && int(p) < start+size(item) {
+			width = item.VariableSize
+		}
+	}
+	total := int64(0)
+	for i := range int(n) {
+		if v, ok := m.memory[int(p)+i*width].(int64); ok {
+			total += v
+		}
+	}
+	return total, nil
+}
+
+// alloc returns a new block of the bytes from the heap, never freed.
+func alloc(m *Machine, args []any) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected 1 argument, got %d", len(args))
+	}
+	n, ok := args[0].(int64)
+	if !ok || n < 0 {
+		return nil, fmt.Errorf("invalid size %v", args[0])
+	}
+	p := Pointer(m.heap)
+	m.heap += (int(n) + 3) / 4 * 4
+	return p, nil
+}
+
+// address returns the byte the pointer and the offset reach.
+func address(pointer, offset any) (int, error) {
+	p, okP := pointer.(Pointer)
+	n, okN := offset.(int64)
+	if !okP || !okN {
+		return 0, fmt.Errorf("expected a pointer and an integer, got %v and %v", pointer, offset)
+	}
+	return int(p) + int(n), nil
+}
+
+// load returns the builtin reading the integer of the bytes at the pointer
+// past the offset, as the load of the machine of the size extends it, or
+// the value of another type stored there.
+func load(size int, unsigned bool) builtin {
+	return func(m *Machine, args []any) (any, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("expected 2 arguments, got %d", len(args))
+		}
+		addr, err := address(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		v, ok := m.memory[addr]
+		if !ok {
+			return int64(0), nil
+		}
+		if i, ok := v.(int64); ok {
+			return extend(i, size, unsigned), nil
+		}
+		return v, nil
+	}
+}
+
+// store returns the builtin writing the value to the bytes at the pointer
+// past the offset, an integer cut to the size and a float of 4 bytes rounded
+// to float32.
+func store(size int) builtin {
+	return func(m *Machine, args []any) (any, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("expected 3 arguments, got %d", len(args))
+		}
+		addr, err := address(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		v := args[2]
+		switch x := v.(type) {
+		case int64:
+			v = extend(x, size, false)
+		case float64:
+			if size == 4 {
+				v = float64(float32(x))
+			}
+		}
+		m.used += cellSize(v) - cellSize(m.memory[addr])
+		m.memory[addr] = v
+		return nil, parser.CheckBudget("bytes of memory", m.used, m.Budget.MaxMemory)
+	}
+}
+
+// extend returns the integer of the low bytes of the size of i, extended
+// with its sign or with zeros.
+func extend(i int64, size int, unsigned bool) int64 {
+	shift := 64 - 8*size
+	if unsigned {
+		return int64(uint64(i) << shift >> shift)
+	}
+	return i << shift >> shift
+}
+
+// call runs the builtin or the extern the quadruple calls on the parameters
+// pushed for it, directly or through the Function an icall reads, and stores
+// its value.
+func (m *Machine) call(q ir.Quad) error {
+	name := q.Arg1
+	if q.Op == ir.ICall {
+		v, err := m.value(q.Arg1)
+		if err != nil {
+			return err
+		}
+		f, ok := v.(Function)
+		if !ok {
+			return fmt.Errorf("%s holds no function", q.Arg1)
+		}
+		name = string(f)
+	}
+	function, ok := builtins[name]
+	if extern, defined := m.externs[name]; defined {
+		function, ok = func(m *Machine, args []any) (any, error) { return extern(args) }, true
+	}
+	if !ok {
+		return fmt.Errorf("unknown function %s", name)
+	}
+	n, err := strconv.Atoi(q.Arg2)
+	if err != nil || n < 0 || n > len(m.params) {
+		return fmt.Errorf("invalid number of parameters %s", q.Arg2)
+	}
+	args := m.params[len(m.params)-n:]
+	m.params = m.params[:len(m.params)-n]
+	v, err := function(m, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if q.Result == "" {
+		return nil
+	}
+	if v == nil {
+		return fmt.Errorf("%s returns no value", name)
+	}
+	return m.store(q.Result, v)
+}
@@ -0,0 +1,605 @@
+// Package vm runs the quadruples of the parser, so that the tests can check
+// what a program prints rather than the code it compiles to.
+package vm
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"app/lexer"
+	"app/parser"
+	"app/parser/ir"
+)
+
+// DefaultMaxSteps is the number of quadruples Run executes at most.
+const DefaultMaxSteps = 10000000
+
+// The addresses, in words like those of the symbol table, of the names it
+// does not know, such as the temporaries of an ir.Emitter on its own, and of
+// the memory new allocates.
+const (
+	ScratchAddr = 0x30000000
+	HeapAddr    = 0x40000000
+)
+
+// Pointer is the address of a cell in bytes, the value of &x and of new.
+type Pointer int
+
+// Function is a builtin, the value of &abs and of a func variable holding it.
+type Function string
+
+// Machine runs quadruples against a memory of cells keyed by the addresses
+// the symbol table gave the variables, in bytes. A cell holds an int64, a
+// float64, a string, a Pointer or a Function; the cells of a variable hold
+// values of its type, in its width, and those of the temporaries what was
+// stored.
+type Machine struct {
+	MaxSteps int   // quadruples run at most, none if 0
+	Counts   []int // the times each quadruple ran, by index, after Run
+
+	// Budget bounds the quadruples run and the bytes of the cells, 8 a cell
+	// and the bytes of the strings besides, failing with a parser.BudgetError
+	Budget parser.Budget
+
+	used int64 // the bytes of the cells, see Budget.MaxMemory
+
+	in      *bufio.Reader
+	out     io.Writer
+	memory  map[int]any
+	items   map[string]*parser.SymbolTableItem // the variables by their name in the code
+	pool    map[int]*parser.SymbolTableItem    // the literals of the constant pool by address
+	scratch map[string]int
+	heap    int
+	params  []any
+	externs map[string]Extern
+}
+
+// Extern is a function the host defines for the program, such as one
+// registered by parser.SymbolTable.RegisterFunction, taking the arguments
+// in order and returning its value, nil for none.
+type Extern func(args []any) (any, error)
+
+// Define makes the calls to the label run the function.
+func (m *Machine) Define(label string, f Extern) {
+	m.externs[label] = f
+}
+
+// New returns a machine with the variables of the symbol table, the nested
+// scopes included, and their initial values. The locals of scopes pruned
+// from it are unknown names, which get cells of their own.
+func New(symtab *parser.SymbolTable, stdin io.Reader, stdout io.Writer) (*Machine, error) {
+	m := &Machine{
+		MaxSteps: DefaultMaxSteps,
+		out:      stdout,
+		memory:   map[int]any{},
+		items:    map[string]*parser.SymbolTableItem{},
+		pool:     map[int]*parser.SymbolTableItem{},
+		scratch:  map[string]int{},
+		heap:     HeapAddr * 4,
+		externs:  map[string]Extern{},
+	}
+	if stdin != nil {
+		m.in = bufio.NewReader(stdin)
+	}
+	if m.out == nil {
+		m.out = io.Discard
+	}
+	for _, scope := range symtab.LegacyScopes {
+		if scope.Level < 1 {
+			continue
+		}
+		for _, item := range scope.Items {
+			if item.Type != parser.SymbolTableItemTypeVariable && item.Type != parser.SymbolTableItemTypeArray {
+				continue
+			}
+			// the code names variables rather than declarations, see codegen
+			name := item.Qualified()
+			if other, ok := m.items[name]; !ok || size(other) < size(item) {
+				m.items[name] = item
+			}
+		}
+	}
+	for _, item := range symtab.Constants {
+		m.pool[item.Address] = item
+	}
+	for _, item := range m.items {
+		for i, literal := range item.Initializer {
+			v, err := m.value(literal)
+			if err != nil {
+				return nil, fmt.Errorf("initializer %s of %s: %w", literal, item.Qualified(), err)
+			}
+			v = convert(item, v)
+			m.memory[item.Address*4+i*item.VariableSize] = v
+			m.used += cellSize(v)
+		}
+	}
+	return m, nil
+}
+
+// Run executes the code on a new machine and returns the times each
+// quadruple ran, which CostModel.Run takes to charge the run.
+func Run(quads []ir.Quad, symtab *parser.SymbolTable, stdin io.Reader, stdout io.Writer) ([]int, error) {
+	m, err := New(symtab, stdin, stdout)
+	if err != nil {
+		return nil, err
+	}
+	err = m.Run(quads)
+	return m.Counts, err
+}
+
+// Run executes the code from its first quadruple until it runs past the last
+// one or a ret, and stops at the first error.
+func (m *Machine) Run(quads []ir.Quad) error {
+	labels := map[string]int{}
+	for i, q := range quads {
+		if q.Op == ir.Label {
+			if _, ok := labels[q.Result]; ok {
+				return fmt.Errorf("label %s is placed twice", q.Result)
+			}
+			labels[q.Result] = i
+		}
+	}
+	m.Counts = make([]int, len(quads))
+	for pc, steps := 0, 0; pc < len(quads); steps++ {
+		if m.MaxSteps > 0 && steps >= m.MaxSteps {
+			return fmt.Errorf("more than %d quadruples run", m.MaxSteps)
+		}
+		if err := parser.CheckBudget("steps", int64(steps+1), int64(m.Budget.MaxSteps)); err != nil {
+			return err
+		}
+		q := quads[pc]
+		m.Counts[pc]++
+		pc++
+		if q.Op == "ret" {
+			return nil
+		}
+		jump, err := m.step(q)
+		if err != nil {
+			return fmt.Errorf("%d: %s: %w", pc-1, q.TAC(), err)
+		}
+		if jump != "" {
+			target, ok := labels[jump]
+			if !ok {
+				return fmt.Errorf("%d: %s: undefined label %s", pc-1, q.TAC(), jump)
+			}
+			pc = target
+		}
+	}
+	return nil
+}
+
+// step executes the quadruple and returns the label it jumps to, if any.
+func (m *Machine) step(q ir.Quad) (string, error) {
+	switch {
+	case q.Op == ir.Label:
+	case q.Op == ir.Goto:
+		return q.Result, nil
+	case q.Relop() != "":
+		x, y, err := m.values(q.Arg1, q.Arg2)
+		if err != nil {
+			return "", err
+		}
+		holds, err := compare(q.Relop(), x, y)
+		if holds {
+			return q.Result, err
+		}
+		return "", err
+	case q.Op == ir.Param:
+		v, err := m.value(q.Arg1)
+		m.params = append(m.params, v)
+		return "", err
+	case q.Op == ir.Call || q.Op == ir.ICall:
+		return "", m.call(q)
+	case q.Op == ir.Copy:
+		v, err := m.value(q.Arg1)
+		if err != nil {
+			return "", err
+		}
+		return "", m.store(q.Result, v)
+	case q.Op == "minus":
+		v, err := m.value(q.Arg1)
+		if err != nil {
+			return "", err
+		}
+		switch v := v.(type) {
+		case int64:
+			return "", m.store(q.Result, -v)
+		case float64:
+			return "", m.store(q.Result, -v)
+		}
+		return "", fmt.Errorf("cannot negate %v", v)
+	case q.Op == "itof":
+		v, err := m.value(q.Arg1)
+		if err != nil {
+			return "", err
+		}
+		f, ok := toFloat(v)
+		if !ok {
+			return "", fmt.Errorf("cannot convert %v to float", v)
+		}
+		return "", m.store(q.Result, f)
+	default:
+		x, y, err := m.values(q.Arg1, q.Arg2)
+		if err != nil {
+			return "", err
+		}
+		v, err := operate(q.Op, x, y)
+		if err != nil {
+			return "", err
+		}
+		return "", m.store(q.Result, v)
+	}
+	return "", nil
+}
+
+func (m *Machine) values(a, b string) (any, any, error) {
+	x, err := m.value(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	y, err := m.value(b)
+	return x, y, err
+}
+
+// Value returns the value of the operand as the code reads it, such as x,
+// a [ 1 ] or a literal, for the tests to check the memory after a run.
+func (m *Machine) Value(operand string) (any, error) {
+	return m.value(operand)
+}
+
+// value reads the operand: a literal, the address of a variable or of a
+// builtin, a literal of the constant pool, an expression or a location.
+func (m *Machine) value(s string) (any, error) {
+	switch {
+	case s == "":
+		return nil, fmt.Errorf("missing operand")
+	case strings.HasPrefix(s, `"`):
+		text, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", s)
+		}
+		return text, nil
+	case strings.HasPrefix(s, "'"):
+		r, _, tail, err := strconv.UnquoteChar(strings.TrimSuffix(s[1:], "'"), '\'')
+		if err != nil || tail != "" {
+			return nil, fmt.Errorf("invalid character %s", s)
+		}
+		return int64(r), nil
+	case s == "true":
+		return int64(1), nil
+	case s == "false":
+		return int64(0), nil
+	case strings.HasPrefix(s, "&"):
+		name := s[1:]
+		if _, ok := builtins[name]; ok && m.items[name] == nil {
+			return Function(name), nil
+		}
+		addr, _, err := m.location(name)
+		return Pointer(addr), err
+	}
+	if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return i, nil
+	}
+	if isFloatLiteral(s) {
+		f, _ := strconv.ParseFloat(s, 64)
+		return f, nil
+	}
+	var addr int
+	if _, err := fmt.Sscanf(s, "$(0x%x)", &addr); err == nil && addr >= parser.ConstantAddr {
+		item, ok := m.pool[addr]
+		if !ok {
+			return nil, fmt.Errorf("no constant at %s", s)
+		}
+		return m.value(item.Variable)
+	}
+	if e, ok := ir.ParseExpr(s); ok && !e.IsLeaf() {
+		return m.eval(e)
+	}
+	addr, item, err := m.location(s)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := m.memory[addr]; ok {
+		return v, nil
+	}
+	return zero(item), nil
+}
+
+// eval computes an operand written as an expression, such as i * i gt n.
+func (m *Machine) eval(e *ir.Expr) (any, error) {
+	if e.IsLeaf() {
+		return m.value(e.Operand)
+	}
+	x, err := m.eval(e.Children[0])
+	if err != nil {
+		return nil, err
+	}
+	y, err := m.eval(e.Children[1])
+	if err != nil {
+		return nil, err
+	}
+	return operate(e.Op, x, y)
+}
+
+// store writes the value to the location, in the type of the variable.
+func (m *Machine) store(s string, v any) error {
+	addr, item, err := m.location(s)
+	if err != nil {
+		return err
+	}
+	v = convert(item, v)
+	m.used += cellSize(v) - cellSize(m.memory[addr])
+	m.memory[addr] = v
+	return parser.CheckBudget("bytes of memory", m.used, m.Budget.MaxMemory)
+}
+
+// cellSize returns the bytes a cell holding the value takes, none for no cell.
+func cellSize(v any) int64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return 8 + int64(len(v))
+	}
+	return 8
+}
+
+// location returns the address of the operand, with the variable it is in
+// if the symbol table has it: a temporary, a variable, an element x [ i ]
+// at the index as written or x[n] at the byte offset. A name the symbol
+// table does not know gets a scratch cell.
+func (m *Machine) location(s string) (int, *parser.SymbolTableItem, error) {
+	var addr int
+	if _, err := fmt.Sscanf(s, "$(0x%x)", &addr); err == nil {
+		if addr >= parser.ConstantAddr {
+			return 0, nil, fmt.Errorf("cannot assign to the constant %s", s)
+		}
+		return addr * 4, nil, nil
+	}
+	name, offset, element := s, 0, false
+	fields := strings.Fields(s)
+	switch {
+	case len(fields) == 4 && fields[1] == "[" && fields[3] == "]":
+		i, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid index of %s", s)
+		}
+		name, offset, element = fields[0], i, true
+	case len(fields) > 4:
+		return 0, nil, fmt.Errorf("%s has more than one index", s)
+	case strings.HasSuffix(s, "]"):
+		before, index, _ := strings.Cut(strings.TrimSuffix(s, "]"), "[")
+		n, err := strconv.Atoi(index)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid offset of %s", s)
+		}
+		name, offset = before, n
+	case len(fields) != 1:
+		return 0, nil, fmt.Errorf("invalid operand %s", s)
+	}
+	item, ok := m.items[name]
+	if !ok {
+		if offset != 0 || element {
+			return 0, nil, fmt.Errorf("undefined array %s", name)
+		}
+		if _, ok := m.scratch[name]; !ok {
+			m.scratch[name] = (ScratchAddr + len(m.scratch)) * 4
+		}
+		return m.scratch[name], nil, nil
+	}
+	if element {
+		offset *= item.VariableSize
+	}
+	if offset < 0 || offset+item.VariableSize > size(item) {
+		return 0, nil, fmt.Errorf("%s is out of the bounds of %s", s, name)
+	}
+	return item.Address*4 + offset, item, nil
+}
+
+// size returns the bytes of the variable.
+func size(item *parser.SymbolTableItem) int {
+	return item.VariableSize * max(item.ArraySize, 1)
+}
+
+// zero returns the value of a variable never written.
+func zero(item *parser.SymbolTableItem) any {
+	if item == nil {
+		return int64(0)
+	}
+	switch {
+	case item.UnderlyingType == "string":
+		return ""
+	case parser.IsFloating(lexer.ParseTypeName(item.UnderlyingType)):
+		return 0.0
+	}
+	return int64(0)
+}
+
+// convert returns the value as the variable holds it: integers wrapped to
+// its width, floats rounded to float32 in 4 bytes, an integer stored into a
+// float converted. The temporaries hold the value as is.
+func convert(item *parser.SymbolTableItem, v any) any {
+	if item == nil {
+		return v
+	}
+	typ := lexer.ParseTypeName(item.UnderlyingType)
+	switch {
+	case parser.IsFloating(typ):
+		f, ok := toFloat(v)
+		if !ok {
+			return v
+		}
+		if item.VariableSize == 4 {
+			return float64(float32(f))
+		}
+		return f
+	case parser.IsIntegral(typ):
+		var i int64
+		switch v := v.(type) {
+		case int64:
+			i = v
+		case float64:
+			i = int64(v)
+		default:
+			return v
+		}
+		unsigned := strings.HasPrefix(item.UnderlyingType, "uint") || item.UnderlyingType == "byte"
+		switch {
+		case item.VariableSize == 1 && unsigned:
+			return int64(uint8(i))
+		case item.VariableSize == 1:
+			return int64(int8(i))
+		case item.VariableSize == 2 && unsigned:
+			return int64(uint16(i))
+		case item.VariableSize == 2:
+			return int64(int16(i))
+		case item.VariableSize == 4 && unsigned:
+			return int64(uint32(i))
+		case item.VariableSize == 4:
+			return int64(int32(i))
+		}
+		return i
+	case item.UnderlyingType == "bool":
+		if i, ok := v.(int64); ok && i != 0 {
+			return int64(1)
+		}
+	}
+	return v
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// operate computes the arithmetic, the comparisons and the operations on
+// strings, on floats if either number is one.
+func operate(op string, x, y any) (any, error) {
+	switch op {
+	case "strcat":
+		op = "+"
+	case "streq":
+		op = "=="
+	case "strne":
+		op = "!="
+	}
+	if _, ok := relations[op]; ok {
+		holds, err := compare(op, x, y)
+		if holds {
+			return int64(1), err
+		}
+		return int64(0), err
+	}
+	if a, ok := x.(string); ok && op == "+" {
+		if b, ok := y.(string); ok {
+			return a + b, nil
+		}
+	}
+	a, okA := x.(int64)
+	b, okB := y.(int64)
+	if okA && okB {
+		switch op {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		case "/", "%", "mod":
+			if b == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if op == "/" {
+				return a / b, nil
+			}
+			return a % b, nil
+		}
+		return nil, fmt.Errorf("unsupported operation %s", op)
+	}
+	f, okF := toFloat(x)
+	g, okG := toFloat(y)
+	if !okF || !okG {
+		return nil, fmt.Errorf("cannot compute %v %s %v", x, op, y)
+	}
+	switch op {
+	case "+":
+		return f + g, nil
+	case "-":
+		return f - g, nil
+	case "*":
+		return f * g, nil
+	case "/":
+		return f / g, nil
+	}
+	return nil, fmt.Errorf("unsupported operation %s on floats", op)
+}
+
+// relations are the comparisons by the names the code gives them, as written
+// and as the parser emits them.
+var relations = map[string]string{
+	"<": "<", "<=": "<=", ">": ">", ">=": ">=", "==": "==", "!=": "!=",
+	"lt": "<", "le": "<=", "gt": ">", "ge": ">=", "eq": "==", "ne": "!=",
+}
+
+// compare checks if the relation holds: numbers by value, on floats if
+// either is one, the other values by equality alone.
+func compare(op string, x, y any) (bool, error) {
+	relation, ok := relations[op]
+	if !ok {
+		return false, fmt.Errorf("unsupported relation %s", op)
+	}
+	var c int
+	a, okA := x.(int64)
+	b, okB := y.(int64)
+	f, okF := toFloat(x)
+	g, okG := toFloat(y)
+	switch {
+	case okA && okB:
+		c = cmp.Compare(a, b)
+	case okF && okG:
+		if math.IsNaN(f) || math.IsNaN(g) {
+			return relation == "!=", nil
+		}
+		c = cmp.Compare(f, g)
+	case relation == "==":
+		return x == y, nil
+	case relation == "!=":
+		return x != y, nil
+	default:
+		return false, fmt.Errorf("cannot compare %v %s %v", x, op, y)
+	}
+	switch relation {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	case "==":
+		return c == 0, nil
+	}
+	return c != 0, nil
+}
+
+// isFloatLiteral checks if the operand is a number but not an integer, which
+// rules out the names ParseFloat takes, such as inf.
+func isFloatLiteral(s string) bool {
+	digits := strings.TrimLeft(s, "-.")
+	if digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
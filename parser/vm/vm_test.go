@@ -0,0 +1,257 @@
+package vm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"app/parser"
+	"app/parser/ir"
+	. "app/parser/vm"
+)
+
+func compile(t *testing.T, src string) *parser.Result {
+	t.Helper()
+	result, err := parser.Compile(parser.Options{Source: strings.NewReader(src)})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("Expected the program to compile, got %v", result.Diagnostics)
+	}
+	return result
+}
+
+func TestRun(t *testing.T) {
+	result := compile(t, `{
+    int a[4] = {1, 2, 3, 4}, n;
+    static int k = 5;
+    float x, y;
+    string s, t;
+    func f = max;
+    int8 b[3];
+    x = readfloat();
+    y = -(x + 1);
+    n = readint();
+    n = -n + k % 3;
+    b[1] = 300;
+    s = "ab";
+    t = s + "cd";
+    n = sum(a, 4) + pow(n, 3) + f(n, 2) + abs(n);
+    printf("%d %f %s %d\n", n, y, t, b[1]);
+}`)
+	var out strings.Builder
+	counts, err := Run(result.Walker.Quads(), result.Walker.SymbolTable, strings.NewReader("2.5\n7\n"), &out)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// -7 + 5 % 3 is -5, and 10 - 125 + 2 + 5 is -108; 300 wraps to 44 in an int8
+	if expected := "-108 -3.5 abcd 44\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+	if len(counts) != len(result.Walker.ThreeAddress) {
+		t.Fatalf("Expected a count per quadruple, got %d for %d", len(counts), len(result.Walker.ThreeAddress))
+	}
+	report := parser.DefaultCostModel().Run(result.Walker.ThreeAddress, counts)
+	if report.Instructions == 0 {
+		t.Errorf("Expected the run charged, got %+v", report)
+	}
+}
+
+func TestRun_ControlFlow(t *testing.T) {
+	result := compile(t, "{ int i, s; while (i < 10) { i = i + 1; if (i % 2 == 0 || i == 5) s = s + i; } }")
+	e := &ir.Emitter{}
+	ir.Translate(e, result.Program)
+	m, err := New(result.Walker.SymbolTable, nil, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := m.Run(e.Quads); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for operand, expected := range map[string]int64{"i": 10, "s": 35} {
+		if v, err := m.Value(operand); err != nil || v != expected {
+			t.Errorf("Expected %s = %d, got %v, %v", operand, expected, v, err)
+		}
+	}
+	// the loop test runs once more than the body
+	if test := m.Counts[1]; test != 11 {
+		t.Errorf("Expected the test of the loop run 11 times, got %d", test)
+	}
+}
+
+func TestRun_Statements(t *testing.T) {
+	// the code of the walker jumps on its own, the breaks out of the
+	// innermost loop or switch and the right operand of && computed only if
+	// needed
+	result := compile(t, `{
+    int i, s;
+    if (i != 0 && 10 / i > 1) s = 1;
+    do {
+        i = i + 1;
+        if (i == 2) s = s + 10; else { s = s + 1; }
+        if (i > 5 || s < 0) break;
+    } while (true);
+    while (i < 20 && !(s < 0)) {
+        switch (i) {
+        case 6: s = s + 100;
+        case 7: s = s + 1000; break;
+        default: i = i * i;
+        }
+        i = i + 1;
+    }
+    printf("%d %d\n", i, s);
+}`)
+	var out strings.Builder
+	if _, err := Run(result.Walker.Quads(), result.Walker.SymbolTable, nil, &out); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if expected := "65 2115\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestRun_Layout(t *testing.T) {
+	// the blocks jumped to are moved after their jumps, but the code after
+	// the switch still runs last, not falling into the case moved after it
+	for src, expected := range map[string]string{
+		`{ int i, t, u; i = 10; switch (i) { case 10: t = 1; break; default: t = 3; } u = u + 1; printf("%d %d\n", t, u); }`: "1 1\n",
+		`{ int i, t, u; i = 10; if (i == 10) t = 1; else t = 3; u = u + 1; printf("%d %d\n", t, u); }`:                       "1 1\n",
+	} {
+		result := compile(t, src)
+		var out strings.Builder
+		if _, err := Run(result.Walker.Quads(), result.Walker.SymbolTable, nil, &out); err != nil {
+			t.Fatalf("Run %s: %v", src, err)
+		}
+		if out.String() != expected {
+			t.Errorf("Expected %q of %s, got %q", expected, src, out.String())
+		}
+	}
+}
+
+func TestRun_Pointers(t *testing.T) {
+	// the values are stored where the pointers point and loaded back, cut to
+	// the types pointed to
+	src := `{
+		int* p = new(int[3]); byte* b = new(byte); float* f = new(float);
+		*p = 5; p[2] = 7; *f = 2;
+		int y = (*b = 257) + 1;
+		printf("%d %d %d %d %d %f\n", *p, p[1], p[2], *b, y, *f + 0.5);
+	}`
+	result := compile(t, src)
+	var out strings.Builder
+	if _, err := Run(result.Walker.Quads(), result.Walker.SymbolTable, nil, &out); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if expected := "5 0 7 1 2 2.5\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestRun_Optimized(t *testing.T) {
+	// the integers stored into floats are converted, the constants folded
+	// are those of the integers alone
+	result := compile(t, "{ float x, y; int i; x = 1; x = x / 2; i = 3; y = i; y = y / 2; printf(\"%f %f\\n\", x, y); }")
+	quads := ir.Optimize(result.Walker.Quads(), ir.FoldConstantsIn(32), ir.PruneBranchesIn(32), ir.RemoveUnreachable)
+	var out strings.Builder
+	if _, err := Run(quads, result.Walker.SymbolTable, nil, &out); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if expected := "0.5 1.5\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     []string
+		expected string
+	}{
+		{"DivisionByZero", []string{"x = 0", "y = 1 / x"}, "1: y = 1 / x: division by zero"},
+		{"UndefinedLabel", []string{"goto L1"}, "undefined label L1"},
+		{"NoInput", []string{"x = call read_int, 0"}, "read_int: no input to read"},
+		{"Steps", []string{"L1:", "goto L1"}, "more than 100 quadruples run"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := New(&parser.SymbolTable{}, nil, nil)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			m.MaxSteps = 100
+			err = m.Run(ir.ParseAll(test.code))
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected an error with %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestRun_Budget(t *testing.T) {
+	counting := compile(t, "{ int i, s; while (i < 100) { s = s + i; i = i + 1; } }")
+	loop := &ir.Emitter{}
+	ir.Translate(loop, counting.Program)
+	text := compile(t, `{ string s, t; s = "abcdefghijklmnopqrstuvwxyz"; t = s + s + s; }`)
+	for _, test := range []struct {
+		result   *parser.Result
+		quads    []ir.Quad
+		budget   parser.Budget
+		expected string
+	}{
+		{counting, loop.Quads, parser.Budget{MaxSteps: 50}, "budget exceeded: more than 50 steps"},
+		{counting, loop.Quads, parser.Budget{MaxSteps: 1000}, ""},
+		{text, text.Walker.Quads(), parser.Budget{MaxMemory: 100}, "budget exceeded: more than 100 bytes of memory"},
+		{text, text.Walker.Quads(), parser.Budget{MaxMemory: 1 << 10}, ""},
+	} {
+		m, err := New(test.result.Walker.SymbolTable, nil, nil)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		m.Budget = test.budget
+		err = m.Run(test.quads)
+		if test.expected == "" {
+			if err != nil {
+				t.Errorf("Expected the run within %+v, got %v", test.budget, err)
+			}
+			continue
+		}
+		var exceeded *parser.BudgetError
+		if !errors.As(err, &exceeded) || !errors.Is(err, parser.ErrBudgetExceeded) || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected %q under %+v, got %v", test.expected, test.budget, err)
+		}
+	}
+}
+
+func TestRun_Externs(t *testing.T) {
+	result, err := parser.Compile(parser.Options{
+		Source: strings.NewReader("{ int a; a = gcd(12, 18) + 1; }"),
+		Declare: func(st *parser.SymbolTable) error {
+			_, err := st.RegisterFunction("gcd", []parser.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, "int")
+			return err
+		},
+	})
+	if err != nil || result.Failed() {
+		t.Fatalf("Expected the program to compile, got %v, %v", result, err)
+	}
+	m, err := New(result.Walker.SymbolTable, nil, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := m.Run(result.Walker.Quads()); err == nil || !strings.Contains(err.Error(), "unknown function F_gcd") {
+		t.Errorf("Expected F_gcd undefined, got %v", err)
+	}
+	m.Define("F_gcd", func(args []any) (any, error) {
+		a, b := args[0].(int64), args[1].(int64)
+		for b != 0 {
+			a, b = b, a%b
+		}
+		return a, nil
+	})
+	if err := m.Run(result.Walker.Quads()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if v, err := m.Value("a"); err != nil || v != int64(7) {
+		t.Errorf("Expected a = 7, got %v, %v", v, err)
+	}
+}
@@ -1,9 +1,12 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 
 	"app/lexer"
+	"app/parser/ast"
+	"app/parser/ir"
 	. "app/utils/collections"
 )
 
@@ -22,27 +25,55 @@ type Walker struct {
 
 	Environment  *Environment
 	ThreeAddress []string
+	Lines        []int64  // source line of each instruction of ThreeAddress
+	Spans        []Span   // where the construct emitting each instruction of ThreeAddress is
+	Docs         []Doc    // documentation of the global declarations
+	Module       string   // module the file declares, if any
+	Checks       Checks   // optional checks of the analysis
+	IntWidth     IntWidth // width of int, see Options.IntWidth
 
-	ast *AbstractSyntaxTree
+	Program *ast.Program // the tree of the program once reduced, see package ast
+
+	ast        *AbstractSyntaxTree
+	xref       *crossReference // identifiers and expressions recorded for Analyze
+	tokens     *[]lexer.Token  // the tokens read, recorded for Compile
+	ruleErrors func(error)     // receives the errors of the rules, which are printed if nil
+	errorCount int             // errors reported by the rules, see Limits.MaxErrors
+	stopped    string          // category of the error stopping the parse, if any
+	reducing   reduction       // the production being reduced
+	hooks      []SemanticAction
+	warnings   []string                    // reported by the rules, logged once the parse completes
+	profile    *Profile                    // counts of the reductions and the states, if profiled
+	archived   map[string]*SymbolTableItem // the largest local of each name of the scopes pruned, see PruneScopes
+	lifetimes  Set[*SymbolTableItem]       // the locals whose lifetimes are marked, see MarkLifetimes
+	fixIt      *FixIt                      // the fix-it of the syntax error stopping the parse, if any
+	declare    func(*SymbolTable) error    // registers the functions of the host in the prelude, see Options.Declare
+	budget     Budget                      // what the parse may use, see Options.Budget
+	source     *budgetReader               // the source read under the budget, nil if read otherwise
+	registry   *SymbolRegistry             // of the grammar, see symbols
 }
 
 type Environment struct {
-	CurrentType      SymbolTableItemType
-	CurrentDataType  lexer.TokenSpecificType
-	CurrentDataSize  int
-	CurrentArraySize int
-	CurrentVariable  string
+	CurrentType     SymbolTableItemType
+	CurrentDataType lexer.TokenSpecificType
+	CurrentDims     []int // array dimensions of the type, the outermost first
+	CurrentVariable string
+	CurrentStatic   bool
+	CurrentConst    bool
+	CurrentPointee  string // type pointed to if the declaration is of pointers
+
+	CurrentDeclarators []*Declarator // global ones declared so far by the current declaration
 
 	CurrentUnary any
 
-	LabelCounter    int
-	BreakLabelStack Stack[int]
+	Labels          LabelAllocator
+	BreakLabelStack Stack[string]
 	ItemStack       Stack[any]
 }
 
 // NewEnvironment creates a new Environment instance and initializes it.
 // The Environment is used to store the current state of the parser, including
-// the current type, data type, data size, array size, variable name, etc.
+// the current type, data type, array dimensions, variable name, etc.
 // It is used to keep track of the current context during parsing and code generation.
 // The Environment is reset to its initial state when a new parsing context is created.
 func NewEnvironment() *Environment {
@@ -59,12 +90,15 @@ func NewEnvironment() *Environment {
 func (env *Environment) Reset() {
 	env.CurrentType = SymbolTableItemTypeUnknown
 	env.CurrentDataType = 0xff
-	env.CurrentDataSize = -1
-	env.CurrentArraySize = -1
+	env.CurrentDims = nil
 	env.CurrentVariable = ""
+	env.CurrentStatic = false
+	env.CurrentConst = false
+	env.CurrentPointee = ""
+	env.CurrentDeclarators = nil
 	env.CurrentUnary = nil
-	env.LabelCounter = 0
-	env.BreakLabelStack = Stack[int]{}
+	env.Labels = LabelAllocator{}
+	env.BreakLabelStack = Stack[string]{}
 }
 
 // NewWalker creates a new Walker instance and initializes it with the
@@ -72,23 +106,59 @@ func (env *Environment) Reset() {
 // and perform actions based on the grammar rules. It maintains a stack of
 // states and symbols, as well as a symbol table for managing variables and types.
 func (p *Parser) NewWalker() *Walker {
+	return p.Tables().NewSession()
+}
+
+// Session is the state of a single parse: the stacks, the symbol table and
+// the generated code. Sessions are cheap, one is created for every input.
+type Session = Walker
+
+// ParserTables is the immutable outcome of building a parser, the grammar and
+// its LR(1) table. Nothing writes to it once built, so it can be shared by any
+// number of sessions parsing concurrently.
+type ParserTables struct {
+	Grammar *Grammar
+	Table   *LRTable
+	Limits  Limits
+	Budget  Budget
+	Checks  Checks
+	Symbols *SymbolRegistry // of the grammar, built once for the sessions
+}
+
+// Tables builds the parser's table if needed and returns it for sharing.
+// The parser must not be modified afterwards.
+func (p *Parser) Tables() *ParserTables {
 	p.EnsureTable()
+	return &ParserTables{Grammar: p.Grammar, Table: p.Table, Limits: p.Limits, Budget: p.Budget, Checks: p.Checks, Symbols: p.Grammar.Symbols()}
+}
 
-	g := p.Grammar.Copy()
+// NewSession creates a session that reads the shared tables.
+func (t *ParserTables) NewSession() *Session {
 	states := Stack[int]{}
 	states.Push(0)
 	symbols := Stack[Symbol]{}
-	return &Walker{
-		Table: LRTable{
-			ActionTable: p.Table.ActionTable.Copy(),
-			GotoTable:   p.Table.GotoTable.Copy(),
-		},
-		Grammar:     &g,
+	w := &Walker{
+		Table:       *t.Table,
+		Grammar:     t.Grammar,
 		States:      states,
 		Symbols:     symbols,
 		SymbolTable: NewSymbolTable(nil, nil),
 		Environment: NewEnvironment(),
+		Checks:      t.Checks,
+		budget:      t.Budget,
+		registry:    t.Symbols,
+	}
+	w.OnReduce(buildSyntax)
+	return w
+}
+
+// symbols returns the registry of the symbols of the grammar, built on the
+// first call if the session was not given the one of its tables.
+func (w *Walker) symbols() *SymbolRegistry {
+	if w.registry == nil {
+		w.registry = w.Grammar.Symbols()
 	}
+	return w.registry
 }
 
 // Next processes the next symbol in the parsing process. It takes a symbol as input
@@ -103,19 +173,38 @@ func (p *Parser) NewWalker() *Walker {
 // If there is an error, it returns an error message.
 func (w *Walker) Next(symbol Symbol) (action Action, err error) {
 	topState, _ := w.States.Peek()
-	if w.Grammar.IsTerminal(symbol) {
-		action, ok := w.Table.ActionTable[topState][Terminal(symbol)]
+	if terminal, ok := w.Grammar.Terminal(symbol); ok {
+		action, ok := w.Table.Lookup(topState, terminal)
 		if !ok {
-			return Action{Type: ERROR}, fmt.Errorf("no action found for state %d and symbol %s", topState, symbol)
+			entry := w.Table.ErrorEntry(topState, terminal)
+			entry.Symbols = w.symbols()
+			return action, entry
 		}
 		switch action.Type {
 		case SHIFT:
-			w.States.Push(action.Number)
-			w.Symbols.Push(symbol)
+			w.push(action.Number, symbol)
 			return Action{Type: SHIFT, Number: action.Number}, nil
 		case REDUCE:
 			production := w.Grammar.Productions[action.Number]
-			if err := production.HandleRule(w); err != nil {
+			w.reducing = reduction{production: &production, index: action.Number, state: topState}
+			var c *ReduceContext
+			if len(w.hooks) > 0 {
+				c = w.reduceContext()
+			}
+			err := production.HandleRule(w)
+			if c != nil {
+				c.Result, _ = w.Tokens.Peek()
+				for _, hook := range w.hooks {
+					err = errors.Join(err, hook(c))
+				}
+			}
+			if err != nil {
+				w.errorCount++
+				err = w.positioned(err)
+			}
+			if err != nil && w.ruleErrors != nil {
+				w.ruleErrors(err)
+			} else if err != nil {
 				fmt.Println("Error handling rule:", err)
 			}
 			for i := range production.Body {
@@ -126,28 +215,38 @@ func (w *Walker) Next(symbol Symbol) (action Action, err error) {
 				w.Symbols.Pop()
 			}
 			topState, _ = w.States.Peek()
-			gotoState, ok := w.Table.GotoTable[topState][production.Head]
+			next, ok := w.Table.Goto(topState, production.Head)
 			if !ok {
 				return Action{Type: ERROR}, fmt.Errorf("no goto state found for state %d and symbol %s", topState, production.Head)
 			}
-			w.Symbols.Push(production.Head)
-			w.States.Push(gotoState)
+			if w.profile != nil {
+				w.profile.Reductions[action.Number]++
+			}
+			w.push(next, production.Head)
 			return Action{Type: REDUCE, Number: action.Number}, nil
 		case ACCEPT:
 			return Action{Type: ACCEPT, Number: 0}, nil
 		}
 	} else {
-		action, ok := w.Table.GotoTable[topState][symbol]
+		next, ok := w.Table.Goto(topState, symbol)
 		if !ok {
 			return Action{Type: ERROR}, fmt.Errorf("no goto state found for state %d and symbol %s", topState, symbol)
 		}
-		w.States.Push(action)
-		w.Symbols.Push(symbol)
-		return Action{Type: GOTO, Number: action}, nil
+		w.push(next, symbol)
+		return Action{Type: GOTO, Number: next}, nil
 	}
 	return Action{Type: ERROR}, fmt.Errorf("unexpected state %d and symbol %s", topState, symbol)
 }
 
+// push enters the state on the symbol.
+func (w *Walker) push(state int, symbol Symbol) {
+	w.States.Push(state)
+	w.Symbols.Push(symbol)
+	if w.profile != nil {
+		w.profile.Visits[state]++
+	}
+}
+
 // Reset resets the Walker's state, symbol, and token stacks to their initial state.
 // It clears the stacks and pushes the initial state (0) onto the state stack.
 func (w *Walker) Reset() {
@@ -157,33 +256,111 @@ func (w *Walker) Reset() {
 	w.States.Push(0)
 }
 
-func (w *Walker) NewLabel() int {
-	w.Environment.LabelCounter++
-	return w.Environment.LabelCounter - 1
+// ReduceTokens replaces the top n nodes of the token stack with a single node
+// of the given type. A single node is left untouched, so that values flow
+// through unit productions such as factor → loc.
+func (w *Walker) ReduceTokens(head Symbol, n int) {
+	if n == 1 {
+		return
+	}
+	w.Tokens.Push(foldNodes(head, w.Tokens.PopTopN(n)))
+}
+
+// NewLabel returns a new label for the kind of construct, see LabelAllocator.
+func (w *Walker) NewLabel(kind string) string {
+	return w.Environment.Labels.New(kind)
+}
+
+// Warnf reports a warning, logged with the others once the parse completes.
+func (w *Walker) Warnf(format string, args ...any) {
+	w.warnings = append(w.warnings, fmt.Sprintf(format, args...))
 }
 
 func (w *Walker) Emit(dist string, op string, args ...any) {
 	if op == "" {
-		w.ThreeAddress = append(w.ThreeAddress, fmt.Sprintf("%s = %s", dist, args[0]))
+		w.EmitQuad(ir.Quad{Op: ir.Copy, Arg1: fmt.Sprint(args[0]), Result: dist})
+	} else if len(args) == 1 {
+		w.EmitQuad(ir.Quad{Op: op, Arg1: fmt.Sprint(args[0]), Result: dist})
 	} else {
-		w.ThreeAddress = append(w.ThreeAddress, fmt.Sprintf("%s = %s %s %s", dist, args[0], op, args[1]))
+		w.EmitQuad(ir.Quad{Op: op, Arg1: fmt.Sprint(args[0]), Arg2: fmt.Sprint(args[1]), Result: dist})
+	}
+}
+
+// EmitQuad appends the instruction to the code, as the text of ThreeAddress.
+func (w *Walker) EmitQuad(q ir.Quad) {
+	w.ThreeAddress = append(w.ThreeAddress, q.TAC())
+}
+
+// Quads returns the code emitted so far as quadruples.
+func (w *Walker) Quads() []ir.Quad {
+	return ir.ParseAll(w.ThreeAddress)
+}
+
+// Emitter returns the stream of the code of the session, which allocates the
+// temporaries in the symbol table and the labels as NewLabel does.
+func (w *Walker) Emitter() *ir.Emitter {
+	return &ir.Emitter{
+		Temps:  func() string { return fmt.Sprintf("$(0x%x)", w.SymbolTable.TempAddr(4)) },
+		Labels: w.NewLabel,
+		Stream: threeAddressStream{w},
+	}
+}
+
+// threeAddressStream is the code of the session as an ir.Stream.
+type threeAddressStream struct {
+	w *Walker
+}
+
+func (s threeAddressStream) Append(q ir.Quad) { s.w.EmitQuad(q) }
+
+func (s threeAddressStream) Len() int { return len(s.w.ThreeAddress) }
+
+func (s threeAddressStream) Patch(i int, label string) {
+	q := ir.Parse(s.w.ThreeAddress[i])
+	q.Result = label
+	s.w.ThreeAddress[i] = q.TAC()
+}
+
+// NewTemp allocates a temporary for the value of an expression and returns
+// the node standing for it.
+func (w *Walker) NewTemp(head Symbol, dataType lexer.TokenSpecificType, raw string, children []*ASTNode) *ASTNode {
+	addr := w.SymbolTable.TempAddr(max(4, w.IntWidth.Bytes()))
+	return &ASTNode{
+		raw: raw,
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  fmt.Sprintf("$(0x%x)", addr),
+		},
+		Children: children,
+		Type:     head,
+		DataType: dataType,
 	}
 }
 
-func (w *Walker) EmitLabel(label int) {
-	w.ThreeAddress = append(w.ThreeAddress, fmt.Sprintf("L%d:", label))
+func (w *Walker) EmitLabel(label string) {
+	w.EmitQuad(ir.Quad{Op: ir.Label, Result: label})
+}
+
+// EmitJump emits an unconditional jump to the label.
+func (w *Walker) EmitJump(label string) {
+	w.EmitQuad(ir.Quad{Op: ir.Goto, Result: label})
+}
+
+// EmitConditionalJump emits a jump to the label taken if arg1 relop arg2 holds.
+func (w *Walker) EmitConditionalJump(arg1 any, relop string, arg2 any, label string) {
+	w.EmitQuad(ir.Quad{Op: ir.Jump(relop), Arg1: fmt.Sprint(arg1), Arg2: fmt.Sprint(arg2), Result: label})
 }
 
-func (w *Walker) GetBreakLabel() int {
+func (w *Walker) GetBreakLabel() string {
 	if w.Environment.BreakLabelStack.IsEmpty() {
-		return -1
+		return ""
 	}
 	t, _ := w.Environment.BreakLabelStack.Peek()
 	return t
 }
 
 func (w *Walker) EnterLoop() {
-	label := w.NewLabel()
+	label := w.NewLabel("loop") + "_end"
 	w.Environment.BreakLabelStack.Push(label)
 }
 
@@ -2,10 +2,16 @@ package parser_test
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"slices"
+	"strings"
+	"sync"
 	"testing"
 
+	"app/lexer"
 	. "app/parser"
+	"app/parser/ir"
 	. "app/utils/collections"
 	"app/utils/log"
 )
@@ -206,3 +212,99 @@ func TestWalker_Next3(t *testing.T) {
 		}
 	}
 }
+
+func TestParser_Limits(t *testing.T) {
+	p := sharedParser()
+	defer func() { p.Limits = DefaultLimits }()
+	src := "{ int a; a = " + strings.Repeat("(", 50) + "a" + strings.Repeat(")", 50) + "; }"
+	tests := []struct {
+		name   string
+		limits Limits
+		failed bool
+	}{
+		{name: "Default", limits: DefaultLimits, failed: false},
+		{name: "Depth", limits: Limits{MaxDepth: 40}, failed: true},
+		{name: "Steps", limits: Limits{MaxSteps: 100}, failed: true},
+		{name: "Unlimited", limits: Limits{}, failed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.Limits = tt.limits
+			last := ""
+			p.Parse(lexer.NewLexer(strings.NewReader(src)), func(s string) { last = s })
+			if failed := strings.Contains(last, ErrResourceLimit.Error()); failed != tt.failed {
+				t.Errorf("Expected failed = %v, got %q", tt.failed, last)
+			}
+		})
+	}
+}
+
+func TestParserTables_Concurrent(t *testing.T) {
+	tables := sharedParser().Tables()
+	src := `{ int a[3] = {1, 2, 3}; string s; s = "x" + "y"; a[1] = abs(a[0]) % 2; }`
+	expected := tables.Parse(lexer.NewLexer(strings.NewReader(src)), func(string) {}).ThreeAddress
+	results := make([][]string, 8)
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = tables.Parse(lexer.NewLexer(strings.NewReader(src)), func(string) {}).ThreeAddress
+		}()
+	}
+	wg.Wait()
+	for i, result := range results {
+		if !slices.Equal(result, expected) {
+			t.Errorf("Session %d: expected %v, got %v", i, expected, result)
+		}
+	}
+}
+
+func TestLabelAllocator(t *testing.T) {
+	a := LabelAllocator{}
+	labels := []string{a.New(""), a.New("while"), a.New("abs")}
+	a.Function = "f"
+	labels = append(labels, a.New(""))
+	expected := []string{"L_main_0", "L_while_1", "L_abs_2", "L_f_3"}
+	if !slices.Equal(labels, expected) {
+		t.Errorf("Expected %v, got %v", expected, labels)
+	}
+}
+
+func TestWalker_Quads(t *testing.T) {
+	files, _ := filepath.Glob("../tests/parser/*.in")
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := parseSource(t, string(src))
+		code, _, _, err := CompileTAC(w, "linear")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the code is written back as it was emitted, before and after the registers
+		for _, code := range [][]string{w.ThreeAddress, code} {
+			for i, q := range ir.ParseAll(code) {
+				if q.TAC() != code[i] {
+					t.Errorf("%s: expected %v to be written as %q, got %q", file, q, code[i], q.TAC())
+				}
+			}
+		}
+		if quads := w.Quads(); len(quads) != len(w.ThreeAddress) {
+			t.Errorf("%s: expected %d quadruples, got %d", file, len(w.ThreeAddress), len(quads))
+		}
+	}
+}
+
+func TestWalker_Emitter(t *testing.T) {
+	w := parseSource(t, "{ int a; if (a < 1) a = -a; }")
+	w.ThreeAddress = nil
+	ir.Translate(w.Emitter(), w.Program)
+	// the jumps are patched in the text, the labels and temporaries allocated
+	// by the session, after L_if_0 of the if parsed
+	expected := []string{"if a < 1 goto L_then_1", "goto L_end_2", "L_then_1:", "$(0x10000003) = minus a", "a = $(0x10000003)", "L_end_2:"}
+	if !slices.Equal(w.ThreeAddress, expected) {
+		t.Errorf("Expected %q, got %q", expected, w.ThreeAddress)
+	}
+}
@@ -0,0 +1,364 @@
+// Package preprocess expands the #include and #define directives of a source
+// into the text the lexer reads, with a LineMap taking the positions in that
+// text back to the files, lines and positions they come from, so that the
+// diagnostics and the spans of the compiler name what the user wrote.
+package preprocess
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"app/utils/charset"
+)
+
+// Position is a position in a file, counted as the lexer counts them: lines
+// from 0, and positions from 1, from 2 on the first line.
+type Position struct {
+	File string
+	Line int64
+	Pos  int64
+}
+
+// LineMap takes the positions of the expanded text back to the source. Each
+// line of the text is made of segments, each copied from a line of a file, a
+// position of the text mapping to the one as far into it, or expanded from a
+// macro, all its positions mapping to the name of the macro.
+type LineMap struct {
+	Files []string // the files read, the main one first
+
+	lines [][]segment
+	texts map[string][]string
+}
+
+type segment struct {
+	col      int // the column of the line of the text the segment starts at, in runes
+	file     int
+	line     int64
+	origin   int  // the column of the line of the file it starts at
+	width    int  // the runes of the file it stands for
+	expanded bool // text of a macro, the columns of which all map to origin
+}
+
+// pos returns the pos of the lexer of a column of the line, in runes from 0.
+func pos(line int64, col int) int64 {
+	if line == 0 {
+		return int64(col) + 2
+	}
+	return int64(col) + 1
+}
+
+// col returns the column of the pos of the lexer on the line.
+func col(line, pos int64) int {
+	if line == 0 {
+		return int(pos) - 2
+	}
+	return int(pos) - 1
+}
+
+// Lookup returns where the position of the expanded text comes from. The
+// lines past the end of the text go on from the last one.
+func (m *LineMap) Lookup(line, p int64) Position {
+	if len(m.lines) == 0 {
+		return Position{File: m.main(), Line: line, Pos: p}
+	}
+	extra := int64(0)
+	if last := int64(len(m.lines)) - 1; line > last {
+		line, extra = last, line-last
+	}
+	if line < 0 {
+		return Position{File: m.main(), Line: line, Pos: p}
+	}
+	segments := m.lines[line]
+	c := col(line, p)
+	i := max(0, segmentAt(segments, c))
+	s := segments[i]
+	origin := s.origin
+	if !s.expanded {
+		origin += c - s.col
+	}
+	if extra > 0 {
+		return Position{File: m.Files[s.file], Line: s.line + extra, Pos: p}
+	}
+	return Position{File: m.Files[s.file], Line: s.line, Pos: pos(s.line, origin)}
+}
+
+// End returns where the position past the end of a token of the expanded
+// text comes from, past the name of a macro for the tokens of its text.
+func (m *LineMap) End(line, p int64) Position {
+	if line < 0 || line >= int64(len(m.lines)) || col(line, p) < 1 {
+		return m.Lookup(line, p)
+	}
+	segments := m.lines[line]
+	c := col(line, p) - 1
+	s := segments[max(0, segmentAt(segments, c))]
+	end := s.origin + s.width
+	if !s.expanded {
+		end = s.origin + c - s.col + 1
+	}
+	return Position{File: m.Files[s.file], Line: s.line, Pos: pos(s.line, end)}
+}
+
+// segmentAt returns the index of the last segment starting at the column or
+// before, -1 if none.
+func segmentAt(segments []segment, c int) int {
+	i, _ := slices.BinarySearchFunc(segments, c+1, func(s segment, c int) int { return s.col - c })
+	return i - 1
+}
+
+func (m *LineMap) main() string {
+	if len(m.Files) == 0 {
+		return ""
+	}
+	return m.Files[0]
+}
+
+// Main returns the file the text was expanded from.
+func (m *LineMap) Main() string {
+	return m.main()
+}
+
+// Snippet returns the line of the file, empty if unknown.
+func (m *LineMap) Snippet(file string, line int64) string {
+	lines := m.texts[file]
+	if line < 0 || line >= int64(len(lines)) {
+		return ""
+	}
+	return lines[line]
+}
+
+// expander expands a file and those it includes into text, building the
+// line map of the text as it goes.
+type expander struct {
+	read   func(path string) (string, error)
+	macros map[string]string
+	m      *LineMap
+	out    strings.Builder
+	stack  []string // the files being expanded, to tell an include cycle
+
+	// the segments of the line being written and its length in runes
+	segments []segment
+	length   int
+}
+
+// File expands the file read from the disk, the files it includes being
+// relative to it.
+func File(path string) (string, *LineMap, error) {
+	return Expand(path, nil)
+}
+
+// Expand expands the file, read by read, os.ReadFile if nil, converted to
+// UTF-8 with \n line endings as charset does. A line whose
+// first rune but blanks is # is a directive:
+//
+//	#include "file"   the lines of the file, relative to the one including it
+//	#define NAME text  NAME is replaced by the text in the lines after it
+//	#undef NAME        NAME is no longer replaced
+//
+// The others are kept for the lexer, which skips them. The names are not
+// replaced in the strings, the chars and the comments, nor in the text of
+// their own macro. The lines of #define and #undef are left empty, so that
+// a file without includes keeps its lines.
+func Expand(path string, read func(path string) (string, error)) (string, *LineMap, error) {
+	if read == nil {
+		read = func(path string) (string, error) {
+			b, err := os.ReadFile(path)
+			text, _ := charset.Decode(b)
+			return text, err
+		}
+	}
+	e := &expander{read: read, macros: map[string]string{}, m: &LineMap{texts: map[string][]string{}}}
+	text, err := read(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := e.file(path, text); err != nil {
+		return "", nil, err
+	}
+	return e.out.String(), e.m, nil
+}
+
+func (e *expander) file(path, text string) error {
+	file := slices.Index(e.m.Files, path)
+	if file < 0 {
+		file = len(e.m.Files)
+		e.m.Files = append(e.m.Files, path)
+	}
+	e.stack = append(e.stack, path)
+	defer func() { e.stack = e.stack[:len(e.stack)-1] }()
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	e.m.texts[path] = lines
+	comment := false // in a /* comment going on over the lines
+	for i, line := range lines {
+		n := int64(i)
+		trimmed := strings.TrimLeft(line, " \t")
+		if !comment && strings.HasPrefix(trimmed, "#") {
+			at := pos(n, len([]rune(line))-len([]rune(trimmed)))
+			directive, arg, _ := strings.Cut(strings.TrimSpace(trimmed[1:]), " ")
+			arg = strings.TrimSpace(arg)
+			switch directive {
+			case "include":
+				if err := e.include(path, arg, n, at); err != nil {
+					return err
+				}
+				continue
+			case "define", "undef":
+				name, body, _ := strings.Cut(arg, " ")
+				if !isName(name) {
+					return fmt.Errorf("%s: #%s of no name, at line %d, pos %d", path, directive, n, at)
+				}
+				if directive == "define" {
+					e.macros[name] = strings.TrimSpace(body)
+				} else {
+					delete(e.macros, name)
+				}
+				e.copy(file, n, 0, "")
+				e.newline()
+				continue
+			}
+			e.copy(file, n, 0, line)
+			e.newline()
+			continue
+		}
+		comment = e.line(file, n, line, comment)
+		e.newline()
+	}
+	return nil
+}
+
+func (e *expander) include(from, arg string, line, at int64) error {
+	name, ok := strings.CutPrefix(arg, `"`)
+	if name, ok = strings.CutSuffix(name, `"`); !ok || name == "" {
+		return fmt.Errorf("%s: #include expects a \"file\", at line %d, pos %d", from, line, at)
+	}
+	path := filepath.Join(filepath.Dir(from), name)
+	if slices.Contains(e.stack, path) {
+		return fmt.Errorf("%s: #include %s includes itself, at line %d, pos %d", from, name, line, at)
+	}
+	text, err := e.read(path)
+	if err != nil {
+		return fmt.Errorf("%s: cannot include %s: %v, at line %d, pos %d", from, name, err, line, at)
+	}
+	return e.file(path, text)
+}
+
+// line writes the line of the file with its macros replaced and tells if a
+// comment goes on past it.
+func (e *expander) line(file int, n int64, line string, comment bool) bool {
+	runes := []rune(line)
+	start := 0 // the first rune not written yet
+	flush := func(end int) {
+		if end > start {
+			e.copy(file, n, start, string(runes[start:end]))
+		}
+		start = end
+	}
+	var quote rune
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case comment:
+			if r == '*' && i+1 < len(runes) && runes[i+1] == '/' {
+				comment = false
+				i++
+			}
+		case quote != 0:
+			if r == '\\' {
+				i++
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			i = len(runes)
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			comment = true
+			i++
+		case isStart(r):
+			j := i + 1
+			for j < len(runes) && isPart(runes[j]) {
+				j++
+			}
+			name := string(runes[i:j])
+			if _, ok := e.macros[name]; ok {
+				flush(i)
+				e.expanded(file, n, i, name, e.expand(name, map[string]bool{}))
+				start = j
+			}
+			i = j - 1
+		}
+	}
+	flush(len(runes))
+	return comment
+}
+
+// expand returns the text of the macro with the macros in it replaced, but
+// those being expanded.
+func (e *expander) expand(name string, active map[string]bool) string {
+	active[name] = true
+	defer delete(active, name)
+	runes := []rune(e.macros[name])
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		if !isStart(runes[i]) || i > 0 && isPart(runes[i-1]) {
+			b.WriteRune(runes[i])
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && isPart(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+		if _, ok := e.macros[word]; ok && !active[word] {
+			word = e.expand(word, active)
+		}
+		b.WriteString(word)
+		i = j - 1
+	}
+	return b.String()
+}
+
+// copy writes the text copied from the column of the line of the file.
+func (e *expander) copy(file int, line int64, origin int, text string) {
+	e.segments = append(e.segments, segment{col: e.length, file: file, line: line, origin: origin, width: len([]rune(text))})
+	e.write(text)
+}
+
+// expanded writes the text of the macro named at the column of the line.
+func (e *expander) expanded(file int, line int64, origin int, name, text string) {
+	e.segments = append(e.segments, segment{col: e.length, file: file, line: line, origin: origin, width: len([]rune(name)), expanded: true})
+	e.write(text)
+}
+
+func (e *expander) write(text string) {
+	e.out.WriteString(text)
+	e.length += len([]rune(text))
+}
+
+func (e *expander) newline() {
+	e.out.WriteByte('\n')
+	e.m.lines = append(e.m.lines, e.segments)
+	e.segments, e.length = nil, 0
+}
+
+func isStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func isPart(r rune) bool {
+	return isStart(r) || unicode.IsDigit(r)
+}
+
+func isName(s string) bool {
+	for i, r := range s {
+		if !isPart(r) || i == 0 && !isStart(r) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,115 @@
+package preprocess_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "app/preprocess"
+)
+
+// files reads the files of the map, failing on the others.
+func files(m map[string]string) func(string) (string, error) {
+	return func(path string) (string, error) {
+		if text, ok := m[path]; ok {
+			return text, nil
+		}
+		return "", os.ErrNotExist
+	}
+}
+
+func TestExpand(t *testing.T) {
+	read := files(map[string]string{
+		"src/main.txt":  "#define N 10\n{\nint a;\n#include \"lib/g.txt\"\na = N + 1; // N\nprintf(\"N\");\n#undef N\nN = 2;\n}\n",
+		"src/lib/g.txt": "#define X X + N\nint g;\n",
+	})
+	text, m, err := Expand("src/main.txt", read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = "\n{\nint a;\n\nint g;\na = 10 + 1; // N\nprintf(\"N\");\n\nN = 2;\n}\n"
+	if text != expected {
+		t.Fatalf("Expected\n%q\ngot\n%q", expected, text)
+	}
+	if len(m.Files) != 2 || m.Main() != "src/main.txt" || m.Files[1] != "src/lib/g.txt" {
+		t.Errorf("Expected the files read, the main one first, got %v", m.Files)
+	}
+
+	for _, tc := range []struct {
+		line, pos int64
+		expected  Position
+	}{
+		{2, 5, Position{"src/main.txt", 2, 5}},  // int a;
+		{4, 5, Position{"src/lib/g.txt", 1, 5}}, // the g of the file included
+		{5, 6, Position{"src/main.txt", 4, 5}},  // the 0 of 10, at the N
+		{5, 8, Position{"src/main.txt", 4, 7}},  // the + after it
+		{10, 2, Position{"src/main.txt", 9, 2}}, // the }
+		{12, 3, Position{"src/main.txt", 11, 3}},
+	} {
+		if p := m.Lookup(tc.line, tc.pos); p != tc.expected {
+			t.Errorf("line %d, pos %d: expected %v, got %v", tc.line, tc.pos, tc.expected, p)
+		}
+	}
+	if p := m.End(5, 7); p != (Position{"src/main.txt", 4, 6}) {
+		t.Errorf("Expected the end of 10 at the end of N, got %v", p)
+	}
+	if s := m.Snippet("src/lib/g.txt", 1); s != "int g;" {
+		t.Errorf("Expected the line of the file included, got %q", s)
+	}
+}
+
+func TestExpand_Macros(t *testing.T) {
+	text, _, err := Expand("main.txt", files(map[string]string{
+		"main.txt": "#define X X + Y\n#define Y (X)\n/* X\nX */ X; 'X'; NX; X1; `X`\n",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a macro is not expanded in its own text, nor in the comments, the
+	// strings and the longer names
+	if expected := "\n\n/* X\nX */ X + (X); 'X'; NX; X1; `X`\n"; text != expected {
+		t.Errorf("Expected\n%q\ngot\n%q", expected, text)
+	}
+}
+
+func TestExpand_Errors(t *testing.T) {
+	read := files(map[string]string{
+		"a.txt":       "#include \"b.txt\"\n",
+		"b.txt":       "int b;\n  #include \"a.txt\"\n",
+		"missing.txt": "{\n#include \"none.txt\"\n",
+		"quoted.txt":  "#include none.txt\n",
+		"define.txt":  "#define 1 2\n",
+	})
+	for path, expected := range map[string]string{
+		"a.txt":       "b.txt: #include a.txt includes itself, at line 1, pos 3",
+		"missing.txt": "missing.txt: cannot include none.txt: file does not exist, at line 1, pos 1",
+		"quoted.txt":  `#include expects a "file", at line 0, pos 2`,
+		"define.txt":  "#define of no name, at line 0, pos 2",
+	} {
+		if _, _, err := Expand(path, read); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected %q, got %v", path, expected, err)
+		}
+	}
+	if _, _, err := Expand("none.txt", read); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the file not to exist, got %v", err)
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lib.txt"), []byte("int g;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.txt"), []byte("{\n#include \"lib.txt\"\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	text, m, err := File(filepath.Join(dir, "main.txt"))
+	if err != nil || text != "{\nint g;\n}\n" {
+		t.Fatalf("Expected the file included, got %q, %v", text, err)
+	}
+	if p := m.Lookup(1, 1); p.File != filepath.Join(dir, "lib.txt") || p.Line != 0 || p.Pos != 2 {
+		t.Errorf("Expected the first line of lib.txt, got %v", p)
+	}
+}
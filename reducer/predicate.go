@@ -0,0 +1,96 @@
+package reducer
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"app/parser"
+	"app/parser/codegen"
+	"app/parser/vm"
+)
+
+// MaxSteps bounds the steps of the programs Output runs, as removing the
+// statement a loop ends with leaves one that never does.
+const MaxSteps = 1000000
+
+// compile compiles the program with the options, the source replaced.
+func compile(source string, opts parser.Options) (*parser.Result, error) {
+	opts.Source = strings.NewReader(source)
+	return parser.Compile(opts)
+}
+
+// Panics holds on the programs compiling or generating the code of which
+// panics, whatever the value.
+func Panics(opts parser.Options) Predicate {
+	return func(source string) (panicked bool) {
+		defer func() {
+			if recover() != nil {
+				panicked = true
+			}
+		}()
+		result, err := compile(source, opts)
+		if err == nil && !result.Failed() {
+			_ = codegen.MIPS(io.Discard, result.Walker, result.Walker.Quads())
+		}
+		return false
+	}
+}
+
+// Diagnostic holds on the programs with a diagnostic the message of which
+// contains the text.
+func Diagnostic(text string, opts parser.Options) Predicate {
+	return func(source string) bool {
+		result, err := compile(source, opts)
+		if err != nil {
+			return false
+		}
+		for _, d := range result.Diagnostics {
+			if strings.Contains(d.Message, text) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Output holds on the programs that compile and print the text, somewhere in
+// what they print run on the vm with the input, within MaxSteps.
+func Output(text, stdin string, opts parser.Options) Predicate {
+	return func(source string) bool {
+		result, err := compile(source, opts)
+		if err != nil || result.Failed() {
+			return false
+		}
+		var out strings.Builder
+		m, err := vm.New(result.Walker.SymbolTable, strings.NewReader(stdin), &out)
+		if err != nil {
+			return false
+		}
+		m.MaxSteps = MaxSteps
+		if err = m.Run(result.Walker.Quads()); err != nil {
+			return false
+		}
+		return strings.Contains(out.String(), text)
+	}
+}
+
+// Command holds on the programs the command exits with 0 on, the program
+// written to a file passed as its last argument, as the tests of interest of
+// the reducers of C do.
+func Command(name string, args ...string) Predicate {
+	return func(source string) bool {
+		f, err := os.CreateTemp("", "reduce-*.in")
+		if err != nil {
+			return false
+		}
+		defer func() { _ = os.Remove(f.Name()) }()
+		_, err = f.WriteString(source)
+		if closeErr := f.Close(); err != nil || closeErr != nil {
+			return false
+		}
+		return exec.Command(name, slices.Concat(args, []string{f.Name()})...).Run() == nil
+	}
+}
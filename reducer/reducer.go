@@ -0,0 +1,287 @@
+// Package reducer shrinks a program the compiler fails on, by a panic, a
+// wrong output or a diagnostic, to a small program failing alike, by delta
+// debugging over its statements, then over its tokens, for the bugs fuzzing
+// finds to be reported with a program a reader can follow.
+package reducer
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"app/lexer"
+)
+
+// Predicate checks if the program fails as the one being reduced does, the
+// test of interest of delta debugging. It must not depend on the layout of
+// the program, as Reduce rewrites it token by token.
+type Predicate func(source string) bool
+
+// Options are the bounds of Reduce.
+type Options struct {
+	MaxTests int              // programs to test at most, no bound if 0
+	Log      func(tokens int) // receives the tokens left after each pass, if not nil
+}
+
+// Result is the reduced program and how Reduce got it.
+type Result struct {
+	Source    string
+	Original  int  // tokens of the program given
+	Tokens    int  // tokens of the reduced program
+	Tests     int  // programs the predicate was called on, those tested twice counted once
+	Exhausted bool // whether MaxTests stopped the reduction before the program was minimal
+}
+
+// ErrNotFailing is returned by Reduce for a program the predicate does not
+// hold on to start with.
+var ErrNotFailing = errors.New("the program does not fail as expected")
+
+// Reduce returns a program the predicate holds on made of tokens of the
+// program, in order. It removes the statements of each block, the blocks
+// first, then the nested ones, in chunks halved until a single one, then the
+// braces of the blocks and the heads of the statements, such as while (a),
+// and then the tokens, until removing any single one of them makes the
+// predicate fail. Comments are dropped and a token is on a new line if it
+// was.
+func Reduce(source string, fails Predicate, opts Options) (*Result, error) {
+	if !strings.HasSuffix(source, "\n") {
+		source += "\n"
+	}
+	d := lexer.NewDocument(source)
+	r := &reducer{fails: fails, opts: opts, seen: map[string]bool{}}
+	for _, span := range d.Spans {
+		r.texts = append(r.texts, d.Text[span.Start:span.End])
+		r.lines = append(r.lines, span.Line)
+	}
+	r.removed = make([]bool, len(r.texts))
+	if !r.test(r.removed) {
+		if r.exhausted {
+			return nil, errors.New("no test allowed by the bounds")
+		}
+		return nil, ErrNotFailing
+	}
+
+	for size := len(r.texts); ; {
+		r.reduceBlock(0, len(r.texts))
+		r.log()
+		r.ddmin(r.brackets())
+		r.ddmin(r.units(0, len(r.texts), false))
+		r.log()
+		left := r.left()
+		if left == size || r.exhausted {
+			break
+		}
+		size = left
+	}
+	return &Result{Source: r.render(r.removed), Original: len(r.texts), Tokens: r.left(), Tests: r.tests, Exhausted: r.exhausted}, nil
+}
+
+// reducer holds the tokens of the program and which of them are removed, by
+// their index, for the ranges of the blocks to hold as they shrink.
+type reducer struct {
+	texts     []string // of the tokens, as written
+	lines     []int64
+	removed   []bool
+	fails     Predicate
+	opts      Options
+	seen      map[string]bool // the programs tested and if they failed
+	tests     int
+	exhausted bool
+}
+
+// render returns the program of the tokens not removed.
+func (r *reducer) render(removed []bool) string {
+	var b strings.Builder
+	line := int64(-1)
+	for i, text := range r.texts {
+		if removed[i] {
+			continue
+		}
+		if line >= 0 {
+			if r.lines[i] > line {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(text)
+		line = r.lines[i]
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// test checks if the program of the tokens not removed fails, false once
+// MaxTests programs are tested.
+func (r *reducer) test(removed []bool) bool {
+	source := r.render(removed)
+	if failed, ok := r.seen[source]; ok {
+		return failed
+	}
+	if r.opts.MaxTests > 0 && r.tests >= r.opts.MaxTests {
+		r.exhausted = true
+		return false
+	}
+	r.tests++
+	failed := r.fails(source)
+	r.seen[source] = failed
+	return failed
+}
+
+// remove removes the tokens of the units if the program without them still
+// fails.
+func (r *reducer) remove(units [][]int) bool {
+	removed := slices.Clone(r.removed)
+	for _, unit := range units {
+		for _, i := range unit {
+			removed[i] = true
+		}
+	}
+	if !r.test(removed) {
+		return false
+	}
+	r.removed = removed
+	return true
+}
+
+// ddmin removes the units in chunks, halving them while no chunk may be
+// removed, and returns those left, each needed for the program to fail.
+func (r *reducer) ddmin(units [][]int) [][]int {
+	for n := 2; len(units) > 0 && !r.exhausted; {
+		n = min(n, len(units))
+		removed := false
+		for i := 0; i < n; i++ {
+			lo, hi := i*len(units)/n, (i+1)*len(units)/n
+			if r.remove(units[lo:hi]) {
+				units = slices.Delete(slices.Clone(units), lo, hi)
+				n = max(n-1, 2)
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			if n == len(units) {
+				break
+			}
+			n = min(2*n, len(units))
+		}
+	}
+	return units
+}
+
+// reduceBlock reduces the statements of the range of tokens, then the inside
+// of the blocks of those left.
+func (r *reducer) reduceBlock(from, to int) {
+	for _, unit := range r.ddmin(r.units(from, to, true)) {
+		depth, open := 0, 0
+		for _, i := range unit {
+			if r.removed[i] {
+				continue
+			}
+			switch r.texts[i] {
+			case "{":
+				if depth == 0 {
+					open = i
+				}
+				depth++
+			case "}":
+				if depth--; depth == 0 {
+					r.reduceBlock(open+1, i)
+				}
+			}
+		}
+	}
+}
+
+// units splits the tokens left of the range into statements, a statement
+// ending with a ; or with the } of a block it opens, out of any bracket, or
+// into single tokens if not statements.
+func (r *reducer) units(from, to int, statements bool) [][]int {
+	var units [][]int
+	var unit []int
+	depth := 0
+	for i := from; i < to; i++ {
+		if r.removed[i] {
+			continue
+		}
+		unit = append(unit, i)
+		switch r.texts[i] {
+		case "{", "(", "[":
+			depth++
+		case "}", ")", "]":
+			depth--
+		}
+		if !statements || depth <= 0 && (r.texts[i] == ";" || r.texts[i] == "}") {
+			units = append(units, unit)
+			unit, depth = nil, 0
+		}
+	}
+	if unit != nil {
+		units = append(units, unit)
+	}
+	return units
+}
+
+// brackets returns the braces of each block, to remove the block and leave
+// its statements, and each parenthesis with the token before it and all it
+// holds, to remove the heads of the statements, such as while (a), and the
+// calls.
+func (r *reducer) brackets() [][]int {
+	var units [][]int
+	var open []int // the brackets not closed yet
+	for i, text := range r.texts {
+		if r.removed[i] {
+			continue
+		}
+		switch text {
+		case "{", "(":
+			open = append(open, i)
+		case "}", ")":
+			if len(open) == 0 {
+				break
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			if text == "}" {
+				units = append(units, []int{start, i})
+				break
+			}
+			var unit []int
+			for j := start; j <= i; j++ {
+				if !r.removed[j] {
+					unit = append(unit, j)
+				}
+			}
+			if head := r.before(start); head >= 0 {
+				unit = append([]int{head}, unit...)
+			}
+			units = append(units, unit)
+		}
+	}
+	return units
+}
+
+// before returns the index of the token left before the one, -1 if none.
+func (r *reducer) before(i int) int {
+	for i--; i >= 0 && r.removed[i]; i-- {
+	}
+	return i
+}
+
+// left returns the number of tokens not removed.
+func (r *reducer) left() int {
+	n := 0
+	for _, removed := range r.removed {
+		if !removed {
+			n++
+		}
+	}
+	return n
+}
+
+// log reports the tokens left to Log.
+func (r *reducer) log() {
+	if r.opts.Log != nil {
+		r.opts.Log(r.left())
+	}
+}
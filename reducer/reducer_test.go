@@ -0,0 +1,95 @@
+package reducer_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"app/parser"
+	. "app/reducer"
+)
+
+func TestReduce(t *testing.T) {
+	source := `{
+    int a; int b;
+    a = 1;
+    while (a < 10) { a = a + 1; if (a == 5) { b = 2; } }
+    b = a * 3;
+}`
+	var sizes []int
+	result, err := Reduce(source, func(s string) bool { return strings.Contains(s, "b = 2") }, Options{Log: func(n int) { sizes = append(sizes, n) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != "b = 2\n" || result.Tokens != 3 || result.Original != 44 {
+		t.Errorf("Expected b = 2 out of 44 tokens, got %q of %d out of %d", result.Source, result.Tokens, result.Original)
+	}
+	if result.Exhausted || result.Tests == 0 || len(sizes) < 2 || sizes[len(sizes)-1] != 3 {
+		t.Errorf("Expected the tokens left logged after each pass, got %v in %d tests", sizes, result.Tests)
+	}
+
+	// the braces of a block go together, as the heads of the statements
+	result, err = Reduce(source, func(s string) bool {
+		return strings.Contains(s, "if") && strings.Count(s, "{") == strings.Count(s, "}")
+	}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != "if\n" {
+		t.Errorf("Expected the if alone, got %q", result.Source)
+	}
+
+	if _, err = Reduce(source, func(string) bool { return false }, Options{}); !errors.Is(err, ErrNotFailing) {
+		t.Errorf("Expected a program not failing to fail, got %v", err)
+	}
+	result, err = Reduce(source, func(s string) bool { return strings.Contains(s, "b = 2") }, Options{MaxTests: 5})
+	if err != nil || !result.Exhausted || result.Tests != 5 || !strings.Contains(result.Source, "b = 2") {
+		t.Errorf("Expected the reduction stopped after 5 tests, got %+v %v", result, err)
+	}
+}
+
+func TestReduce_Predicates(t *testing.T) {
+	source := `{
+    int a; int b; float f;
+    a = 1;
+    b = a * 2;
+    f = 1.5;
+    while (a < 3) { a = a + b; }
+    printf("%d\n", a);
+}
+`
+	// the compiler panics on the sums
+	opts := parser.Options{Hooks: []parser.SemanticAction{func(c *parser.ReduceContext) error {
+		if c.Production.Head == "expr" && len(c.Nodes) == 3 && c.Nodes[1].Token.Val == "+" {
+			panic("sum")
+		}
+		return nil
+	}}}
+	result, err := Reduce(source, Panics(opts), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != "{\na = a + b ;\n" || !Panics(opts)(result.Source) {
+		t.Errorf("Expected a sum alone, got %q", result.Source)
+	}
+
+	result, err = Reduce(source, Diagnostic("item b already exists", parser.Options{}), Options{})
+	if err == nil {
+		t.Errorf("Expected a program not reporting the message to fail, got %q", result.Source)
+	}
+	result, err = Reduce(strings.Replace(source, "float f", "float b", 1), Diagnostic("item b already exists", parser.Options{}), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != "{\nint b ; float b ;\n" {
+		t.Errorf("Expected the two declarations of b in a block, got %q", result.Source)
+	}
+
+	result, err = Reduce(source, Output("3", "", parser.Options{}), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Source, "printf") || strings.Contains(result.Source, "f =") || !Output("3", "", parser.Options{})(result.Source) {
+		t.Errorf("Expected a program printing 3, got %q", result.Source)
+	}
+}
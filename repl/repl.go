@@ -0,0 +1,181 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"app/lexer"
+)
+
+// Help lists the commands of the REPL.
+const Help = `Enter declarations and statements, a brace left open going on over the next lines.
+:save [file]  save the session to the file, the workspace if none
+:load [file]  restore the session of the file, the workspace if none
+:replay       run the program again, printing all it prints
+:tac          print the code of the program
+:globals      print the variables of the outer block
+:reset        forget the statements entered
+:help         print this help
+:quit         leave, saving the session to the workspace if any
+`
+
+// Run reads the statements and the commands of in, one per line, and
+// writes the prompts, what the program prints and the errors to out. The
+// session is restored from the workspace file first, if it exists, and
+// saved to it when in ends or on :quit, none if the file is empty.
+func (s *Session) Run(in io.Reader, out io.Writer, workspace string) error {
+	if workspace != "" {
+		if err := s.load(workspace); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		} else if err == nil {
+			fmt.Fprintf(out, "restored %d statements from %s\n", len(s.Statements), workspace)
+		}
+	}
+	scanner := bufio.NewScanner(in)
+	pending := ""
+	for {
+		if pending == "" {
+			fmt.Fprint(out, "> ")
+		} else {
+			fmt.Fprint(out, "... ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		line := scanner.Text()
+		if pending == "" && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			quit, err := s.command(strings.Fields(line), out, workspace)
+			if err != nil {
+				fmt.Fprintf(out, "error: %v\n", err)
+			}
+			if quit {
+				return s.quit(workspace)
+			}
+			continue
+		}
+		if pending += line + "\n"; open(pending) {
+			continue
+		}
+		statement := strings.TrimSpace(pending)
+		pending = ""
+		if statement == "" {
+			continue
+		}
+		printed, err := s.Enter(statement)
+		fmt.Fprint(out, printed)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out)
+	return s.quit(workspace)
+}
+
+// open checks if the text leaves a brace open, a statement going on.
+func open(text string) bool {
+	l := lexer.NewLexer(strings.NewReader(text))
+	depth := 0
+	for {
+		token, err := l.Next()
+		if token.Type == lexer.EOF || errors.Is(err, io.EOF) {
+			return depth > 0
+		}
+		if err == nil && token.Type == lexer.DELIMITER {
+			switch token.Val {
+			case "{":
+				depth++
+			case "}":
+				depth--
+			}
+		}
+	}
+}
+
+// command runs the command of the fields and tells if it is :quit.
+func (s *Session) command(fields []string, out io.Writer, workspace string) (bool, error) {
+	file := workspace
+	if len(fields) > 1 {
+		file = fields[1]
+	}
+	switch fields[0] {
+	case ":save":
+		if file == "" {
+			return false, fmt.Errorf("no file to save to")
+		}
+		if err := s.save(file); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(out, "saved %d statements to %s\n", len(s.Statements), file)
+	case ":load":
+		if file == "" {
+			return false, fmt.Errorf("no file to load")
+		}
+		if err := s.load(file); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(out, "restored %d statements from %s\n", len(s.Statements), file)
+	case ":replay":
+		printed, err := s.Replay()
+		fmt.Fprint(out, printed)
+		return false, err
+	case ":tac":
+		for _, line := range s.Workspace().TAC {
+			fmt.Fprintln(out, line)
+		}
+	case ":globals":
+		for _, item := range s.Workspace().Globals {
+			fmt.Fprintf(out, "%s %s at 0x%x\n", item.Name, item.Type, item.Address)
+		}
+	case ":reset":
+		s.Reset()
+	case ":help":
+		fmt.Fprint(out, Help)
+	case ":quit":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %s, see :help", fields[0])
+	}
+	return false, nil
+}
+
+// quit saves the session to the workspace, if any.
+func (s *Session) quit(workspace string) error {
+	if workspace == "" {
+		return nil
+	}
+	return s.save(workspace)
+}
+
+func (s *Session) save(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := s.Save(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *Session) load(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	loaded, err := Load(f, s.Tables)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	loaded.Budget = s.Budget
+	*s = *loaded
+	return nil
+}
@@ -0,0 +1,151 @@
+package repl_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"app/parser"
+	. "app/repl"
+)
+
+func TestSession_Enter(t *testing.T) {
+	s := &Session{}
+	for _, step := range []struct {
+		statement string
+		printed   string
+	}{
+		{"int a;", ""},
+		{"a = 3;", ""},
+		{`printf("%d\n", a);`, "3\n"},
+		{"if (a > 1) {\n  a = a + 1;\n}", ""},
+		{`printf("%d\n", a);`, "4\n"},
+	} {
+		printed, err := s.Enter(step.statement)
+		if err != nil {
+			t.Fatalf("%s: %v", step.statement, err)
+		}
+		if printed != step.printed {
+			t.Errorf("%s: expected to print %q, got %q", step.statement, step.printed, printed)
+		}
+	}
+	if s.Output != "3\n4\n" {
+		t.Errorf("Expected the output of the whole program, got %q", s.Output)
+	}
+
+	if _, err := s.Enter("a = ;"); err == nil {
+		t.Error("Expected a syntax error")
+	}
+	if _, err := s.Enter("float a;"); err == nil || !strings.Contains(err.Error(), "a") {
+		t.Errorf("Expected a redeclaration of a, got %v", err)
+	}
+	if len(s.Statements) != 5 {
+		t.Errorf("Expected the statements failing to be left out, got %q", s.Statements)
+	}
+	if out, err := s.Replay(); err != nil || out != "3\n4\n" {
+		t.Errorf("Expected the replay to print it all again, got %q, %v", out, err)
+	}
+}
+
+func TestSession_SaveLoad(t *testing.T) {
+	s := &Session{}
+	for _, statement := range []string{"int a;", "float f = 2.5;", "a = 7;", `printf("%d\n", a);`} {
+		if _, err := s.Enter(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := s.Workspace()
+	var names []string
+	for _, item := range w.Globals {
+		names = append(names, item.Name)
+	}
+	if !slices.Contains(names, "a") || !slices.Contains(names, "f") {
+		t.Errorf("Expected a and f among the globals, got %v", names)
+	}
+	if _, ok := w.Constants["float 2.5"]; !ok {
+		t.Errorf("Expected 2.5 in the constant pool, got %v", w.Constants)
+	}
+	if len(w.TAC) == 0 {
+		t.Error("Expected the code of the program")
+	}
+
+	var b bytes.Buffer
+	if err := s.Save(&b); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(&b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(loaded.Statements, s.Statements) || loaded.Output != "7\n" {
+		t.Errorf("Expected the session restored, got %q printing %q", loaded.Statements, loaded.Output)
+	}
+	if !slices.Equal(loaded.Workspace().TAC, w.TAC) {
+		t.Error("Expected the same code once restored")
+	}
+	if printed, err := loaded.Enter(`printf("%d\n", a + 1);`); err != nil || printed != "8\n" {
+		t.Errorf("Expected the restored session to go on, got %q, %v", printed, err)
+	}
+}
+
+func TestSession_Budget(t *testing.T) {
+	s := &Session{Budget: &parser.Budget{MaxTokens: 35, MaxSteps: 3}}
+	if _, err := s.Enter("int i;"); err != nil {
+		t.Fatalf("Enter: %v", err)
+	}
+	if _, err := s.Enter("{ i = i + 1; i = i * 2; i = i - 3; }"); err == nil || !strings.Contains(err.Error(), "budget exceeded: more than 3 steps") {
+		t.Errorf("Expected the statement to run out of steps, got %v", err)
+	}
+	if _, err := s.Enter("i = 1 + 2 + 3 + 4 + 5 + 6 + 7 + 8 + 9 + 10 + 11 + 12 + 13 + 14 + 15;"); err == nil || !strings.Contains(err.Error(), "more than 35 tokens") {
+		t.Errorf("Expected the statement to run out of tokens, got %v", err)
+	}
+	if len(s.Statements) != 1 {
+		t.Errorf("Expected the statements beyond the budget left out, got %q", s.Statements)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for name, workspace := range map[string]string{
+		"not json":      "statements",
+		"version":       `{"version": 2, "statements": []}`,
+		"not compiling": `{"version": 1, "statements": ["a = 1 +;"]}`,
+	} {
+		if _, err := Load(strings.NewReader(workspace), nil); err == nil {
+			t.Errorf("%s: expected the workspace to fail to load", name)
+		}
+	}
+	s, err := Load(strings.NewReader(`{"version": 1, "statements": []}`), nil)
+	if err != nil || len(s.Statements) != 0 {
+		t.Errorf("Expected an empty session, got %v, %v", s, err)
+	}
+}
+
+func TestSession_Run(t *testing.T) {
+	workspace := filepath.Join(t.TempDir(), "session.json")
+	var out bytes.Buffer
+	in := "int a;\na = 2;\nif (a > 1) {\n  a = a * 5;\n}\n:bogus\nprintf(\"%d\\n\", a);\n:quit\nint b;\n"
+	if err := (&Session{}).Run(strings.NewReader(in), &out, workspace); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "... ") || !strings.Contains(out.String(), "10\n") {
+		t.Errorf("Expected the if to go on over the lines and print 10, got\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "unknown command :bogus") {
+		t.Errorf("Expected :bogus to be unknown, got\n%s", out.String())
+	}
+	if _, err := os.Stat(workspace); err != nil {
+		t.Fatalf("Expected :quit to save the workspace, got %v", err)
+	}
+
+	out.Reset()
+	s := &Session{}
+	if err := s.Run(strings.NewReader(":replay\n"), &out, workspace); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Statements) != 4 || !strings.Contains(out.String(), "restored 4 statements") || !strings.Contains(out.String(), "10\n") {
+		t.Errorf("Expected the session restored and replayed, got %q\n%s", s.Statements, out.String())
+	}
+}
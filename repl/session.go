@@ -0,0 +1,183 @@
+// Package repl reads the declarations and statements of a program one at a
+// time, compiling the program they make so far and running it on the vm
+// after each, and saves the session to a workspace file so that a demo can
+// be resumed and replayed later.
+package repl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"app/diagnostics"
+	"app/parser"
+	"app/parser/vm"
+)
+
+// Session is the program entered so far, the statements of its outer block
+// in order. The program is compiled and run again whole after each one, so
+// it must not read its input, which is the one of the REPL.
+type Session struct {
+	Tables     *parser.ParserTables // the tables to parse with, the default ones if nil
+	Budget     *parser.Budget       // what compiling and running the program may use, none if nil
+	Statements []string
+	Output     string // what the program printed on its last run
+
+	result *parser.Result
+}
+
+// source returns the program of the statements.
+func source(statements []string) string {
+	return "{\n" + strings.Join(statements, "\n") + "\n}\n"
+}
+
+// compile compiles the statements, failing with the errors reported.
+func (s *Session) compile(statements []string) (*parser.Result, error) {
+	result, err := parser.Compile(parser.Options{Source: strings.NewReader(source(statements)), Tables: s.Tables, Budget: s.Budget})
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for _, d := range result.Diagnostics {
+		if d.Severity == diagnostics.Error {
+			messages = append(messages, d.Message)
+		}
+	}
+	if len(messages) > 0 {
+		return nil, errors.New(strings.Join(messages, "\n"))
+	}
+	return result, nil
+}
+
+// run runs the code of the result and returns what it prints.
+func (s *Session) run(result *parser.Result) (string, error) {
+	var out strings.Builder
+	m, err := vm.New(result.Walker.SymbolTable, nil, &out)
+	if err != nil {
+		return "", err
+	}
+	if s.Budget != nil {
+		m.Budget = *s.Budget
+	}
+	if err := m.Run(result.Walker.Quads()); err != nil {
+		return out.String(), fmt.Errorf("run: %w", err)
+	}
+	return out.String(), nil
+}
+
+// Enter adds the statement to the program if the program still compiles and
+// runs with it, and returns what the run printed besides what the last one
+// did. A statement failing leaves the session as it was.
+func (s *Session) Enter(statement string) (string, error) {
+	statements := append(slices.Clip(s.Statements), statement)
+	result, err := s.compile(statements)
+	if err != nil {
+		return "", err
+	}
+	out, err := s.run(result)
+	if err != nil {
+		return "", err
+	}
+	printed := out
+	if strings.HasPrefix(out, s.Output) {
+		printed = out[len(s.Output):]
+	}
+	s.Statements, s.Output, s.result = statements, out, result
+	return printed, nil
+}
+
+// Replay runs the program again and returns all it prints.
+func (s *Session) Replay() (string, error) {
+	if s.result == nil {
+		return "", nil
+	}
+	return s.run(s.result)
+}
+
+// Reset forgets the statements entered.
+func (s *Session) Reset() {
+	s.Statements, s.Output, s.result = nil, "", nil
+}
+
+// WorkspaceVersion is the version of the format of the workspace files,
+// which Load checks.
+const WorkspaceVersion = 1
+
+// Workspace is what a session saves: the statements, which restore it, and
+// what they compile to, to read without compiling them again.
+type Workspace struct {
+	Version    int                 `json:"version"`
+	Statements []string            `json:"statements"`
+	Globals    []parser.ItemExport `json:"globals"`   // the variables of the outer block, the func ones included
+	Constants  map[string]int      `json:"constants"` // the constant pool, the address of each literal by its type and its text, as float 2.5
+	TAC        []string            `json:"tac"`
+	Output     string              `json:"output"`
+}
+
+// Workspace returns the workspace of the session.
+func (s *Session) Workspace() Workspace {
+	w := Workspace{
+		Version:    WorkspaceVersion,
+		Statements: slices.Clone(s.Statements),
+		Globals:    []parser.ItemExport{},
+		Constants:  map[string]int{},
+		TAC:        []string{},
+		Output:     s.Output,
+	}
+	if w.Statements == nil {
+		w.Statements = []string{}
+	}
+	if s.result == nil {
+		return w
+	}
+	st := s.result.Walker.SymbolTable
+	for _, scope := range st.Scopes() {
+		if scope.Level == 1 {
+			w.Globals = append(w.Globals, scope.Items...)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(st.Constants)) {
+		w.Constants[key] = st.Constants[key].Address
+	}
+	w.TAC = slices.Clone(s.result.TAC)
+	return w
+}
+
+// Save writes the workspace of the session in JSON.
+func (s *Session) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s.Workspace())
+}
+
+// Load restores a session saved with the tables, the default ones if nil,
+// compiling and running its statements again, so that a workspace the
+// compiler no longer accepts fails to load. The code saved is not read
+// back, what the statements compile to now replaces it.
+func Load(r io.Reader, tables *parser.ParserTables) (*Session, error) {
+	var w Workspace
+	if err := json.NewDecoder(r).Decode(&w); err != nil {
+		return nil, fmt.Errorf("invalid workspace: %v", err)
+	}
+	if w.Version != WorkspaceVersion {
+		return nil, fmt.Errorf("workspace of version %d, %d expected", w.Version, WorkspaceVersion)
+	}
+	s := &Session{Tables: tables}
+	if len(w.Statements) == 0 {
+		return s, nil
+	}
+	result, err := s.compile(w.Statements)
+	if err != nil {
+		return nil, fmt.Errorf("the workspace does not compile: %w", err)
+	}
+	out, err := s.run(result)
+	if err != nil {
+		return nil, fmt.Errorf("the workspace does not run: %w", err)
+	}
+	s.Statements, s.Output, s.result = w.Statements, out, result
+	return s, nil
+}
@@ -0,0 +1,155 @@
+// Package selftest compiles the example programs embedded in the binary and
+// runs them on the vm, checking what they print, the values they leave or the
+// errors they fail with, a sanity check of the whole toolchain from the lexer
+// to the vm.
+package selftest
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"app/parser"
+	"app/parser/vm"
+)
+
+// The programs are name.in, with what they print in name.out, the values of
+// their variables once run in name.vars, as x = 1 per line, or the errors
+// they fail with in name.err, a part of a message per line, and the input
+// they read in name.stdin, if any.
+//
+//go:embed programs
+var programs embed.FS
+
+// Case is an example program and what running it must give.
+type Case struct {
+	Name   string
+	Source string
+	Stdin  string
+	Output string            // what the program prints
+	Vars   map[string]string // the values of variables after the run, as printed
+	Errors []string          // parts of the errors of the program, if it must fail
+}
+
+// Cases returns the embedded programs in the order of their names.
+func Cases() ([]Case, error) {
+	sources, err := fs.Glob(programs, "programs/*.in")
+	if err != nil {
+		return nil, err
+	}
+	cases := make([]Case, 0, len(sources))
+	for _, source := range sources {
+		name := strings.TrimSuffix(path.Base(source), ".in")
+		c := Case{Name: name}
+		var vars, errs string
+		for ext, field := range map[string]*string{".in": &c.Source, ".stdin": &c.Stdin, ".out": &c.Output, ".vars": &vars, ".err": &errs} {
+			text, err := programs.ReadFile("programs/" + name + ext)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			*field = string(text)
+		}
+		for _, line := range lines(vars) {
+			variable, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("%s.vars: expected x = value, got %s", name, line)
+			}
+			if c.Vars == nil {
+				c.Vars = map[string]string{}
+			}
+			c.Vars[strings.TrimSpace(variable)] = strings.TrimSpace(value)
+		}
+		c.Errors = lines(errs)
+		if c.Output == "" && c.Vars == nil && c.Errors == nil {
+			return nil, fmt.Errorf("%s has nothing to check", name)
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// lines returns the lines of the text that are not blank, trimmed.
+func lines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Check compiles the program with the tables, the default ones if nil, and
+// runs it, returning how it differs from what it must give.
+func (c Case) Check(tables *parser.ParserTables) error {
+	result, err := parser.Compile(parser.Options{Source: strings.NewReader(c.Source), Tables: tables})
+	if err != nil {
+		return err
+	}
+	var messages []string
+	for _, d := range result.Diagnostics {
+		if d.Severity == "Error" {
+			messages = append(messages, d.Message)
+		}
+	}
+	if c.Errors != nil {
+		all := strings.Join(messages, "\n")
+		for _, expected := range c.Errors {
+			if !strings.Contains(all, expected) {
+				return fmt.Errorf("expected an error with %q, got %q", expected, messages)
+			}
+		}
+		return nil
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("expected the program to compile, got %q", messages)
+	}
+
+	var out strings.Builder
+	m, err := vm.New(result.Walker.SymbolTable, strings.NewReader(c.Stdin), &out)
+	if err != nil {
+		return err
+	}
+	if err := m.Run(result.Walker.Quads()); err != nil {
+		return fmt.Errorf("run: %w", err)
+	}
+	if out.String() != c.Output {
+		return fmt.Errorf("expected the output %q, got %q", c.Output, out.String())
+	}
+	for variable, expected := range c.Vars {
+		v, err := m.Value(variable)
+		if err != nil {
+			return err
+		}
+		if fmt.Sprint(v) != expected {
+			return fmt.Errorf("expected %s = %s, got %v", variable, expected, v)
+		}
+	}
+	return nil
+}
+
+// Result is the outcome of a case, Err nil if it passed.
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// Run checks every case with the tables, the default ones if nil.
+func Run(tables *parser.ParserTables) ([]Result, error) {
+	cases, err := Cases()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(cases))
+	for i, c := range cases {
+		start := time.Now()
+		err := c.Check(tables)
+		results[i] = Result{Name: c.Name, Err: err, Duration: time.Since(start)}
+	}
+	return results, nil
+}
@@ -0,0 +1,22 @@
+package selftest_test
+
+import (
+	"testing"
+
+	. "app/selftest"
+)
+
+func TestRun(t *testing.T) {
+	results, err := Run(nil)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("Expected programs embedded")
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("%s: %v", result.Name, result.Err)
+		}
+	}
+}
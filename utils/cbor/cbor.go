@@ -0,0 +1,310 @@
+// Package cbor encodes values in CBOR (RFC 8949), a binary format read by
+// libraries of most languages, and decodes them back into generic values.
+package cbor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// the major types of the initial byte
+const (
+	majorUnsigned = 0
+	majorNegative = 1
+	majorBytes    = 2
+	majorText     = 3
+	majorArray    = 4
+	majorMap      = 5
+	majorSimple   = 7
+)
+
+// Marshal encodes the value: booleans, integers, floats, strings, byte
+// slices, slices and arrays, maps and structs, following pointers, with nil
+// as null. The fields of structs are encoded as maps keyed by the name of
+// their `cbor` tag or else their own name, those tagged "-" are skipped and
+// ",omitempty" skips the zero values. The keys of maps are sorted the way
+// the deterministic encoding of the RFC sorts them, so a value always gives
+// the same bytes.
+func Marshal(v any) ([]byte, error) {
+	var b bytes.Buffer
+	if err := encode(&b, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Encode writes the value encoded by Marshal to the writer.
+func Encode(w io.Writer, v any) error {
+	data, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// head writes the initial byte of the major type and its argument.
+func head(b *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		b.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		b.WriteByte(major<<5 | 24)
+		b.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		b.WriteByte(major<<5 | 25)
+		b.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		b.WriteByte(major<<5 | 26)
+		b.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		b.WriteByte(major<<5 | 27)
+		b.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func encode(b *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		b.WriteByte(majorSimple<<5 | 22)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			b.WriteByte(majorSimple<<5 | 22)
+			return nil
+		}
+		return encode(b, v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			b.WriteByte(majorSimple<<5 | 21)
+		} else {
+			b.WriteByte(majorSimple<<5 | 20)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := v.Int(); n >= 0 {
+			head(b, majorUnsigned, uint64(n))
+		} else {
+			head(b, majorNegative, uint64(-1-n))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		head(b, majorUnsigned, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		b.WriteByte(majorSimple<<5 | 27)
+		b.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v.Float())))
+	case reflect.String:
+		head(b, majorText, uint64(v.Len()))
+		b.WriteString(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			b.WriteByte(majorSimple<<5 | 22)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			head(b, majorBytes, uint64(v.Len()))
+			b.Write(v.Bytes())
+			return nil
+		}
+		head(b, majorArray, uint64(v.Len()))
+		for i := range v.Len() {
+			if err := encode(b, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			b.WriteByte(majorSimple<<5 | 22)
+			return nil
+		}
+		entries := make([][2][]byte, 0, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			var key, value bytes.Buffer
+			if err := encode(&key, iter.Key()); err != nil {
+				return err
+			}
+			if err := encode(&value, iter.Value()); err != nil {
+				return err
+			}
+			entries = append(entries, [2][]byte{key.Bytes(), value.Bytes()})
+		}
+		writeMap(b, entries)
+	case reflect.Struct:
+		var entries [][2][]byte
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, options, _ := strings.Cut(field.Tag.Get("cbor"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if options == "omitempty" && v.Field(i).IsZero() {
+				continue
+			}
+			var key, value bytes.Buffer
+			head(&key, majorText, uint64(len(name)))
+			key.WriteString(name)
+			if err := encode(&value, v.Field(i)); err != nil {
+				return err
+			}
+			entries = append(entries, [2][]byte{key.Bytes(), value.Bytes()})
+		}
+		writeMap(b, entries)
+	default:
+		return fmt.Errorf("cbor: cannot encode %s", v.Type())
+	}
+	return nil
+}
+
+// writeMap writes the encoded entries of a map with the keys in the
+// bytewise order of their encoding.
+func writeMap(b *bytes.Buffer, entries [][2][]byte) {
+	slices.SortFunc(entries, func(x, y [2][]byte) int { return bytes.Compare(x[0], y[0]) })
+	head(b, majorMap, uint64(len(entries)))
+	for _, entry := range entries {
+		b.Write(entry[0])
+		b.Write(entry[1])
+	}
+}
+
+// ErrInvalid is returned for data that is not well-formed CBOR, or uses what
+// Decode does not read.
+var ErrInvalid = errors.New("cbor: invalid data")
+
+// Decode reads a value encoded by Encode into generic values: uint64 and
+// int64 for the integers, float64, string, []byte, bool, nil, []any and
+// map[any]any, with the keys of text as strings. Tags, indefinite lengths and
+// simple values other than the booleans and null are not read.
+func Decode(r io.Reader) (any, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		b := bufio.NewReader(r)
+		r, br = b, b
+	}
+	return decode(r, br)
+}
+
+func decode(r io.Reader, br io.ByteReader) (any, error) {
+	initial, err := br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	major, info := initial>>5, initial&0x1f
+	if major == majorSimple {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		case 27:
+			var buf [8]byte
+			if _, err := io.ReadFull(r, buf[:]); err != nil {
+				return nil, unexpected(err)
+			}
+			return math.Float64frombits(binary.BigEndian.Uint64(buf[:])), nil
+		}
+		return nil, fmt.Errorf("%w: simple value %d", ErrInvalid, info)
+	}
+
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info <= 27:
+		buf := make([]byte, 1<<(info-24))
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, unexpected(err)
+		}
+		for _, c := range buf {
+			n = n<<8 | uint64(c)
+		}
+	default:
+		return nil, fmt.Errorf("%w: additional information %d", ErrInvalid, info)
+	}
+
+	switch major {
+	case majorUnsigned:
+		return n, nil
+	case majorNegative:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer out of range", ErrInvalid)
+		}
+		return -1 - int64(n), nil
+	case majorBytes, majorText:
+		buf := make([]byte, 0, min(n, 1<<16))
+		buf, err = readN(r, buf, n)
+		if err != nil {
+			return nil, err
+		}
+		if major == majorText {
+			return string(buf), nil
+		}
+		return buf, nil
+	case majorArray:
+		a := make([]any, 0, min(n, 1<<16))
+		for range n {
+			item, err := decode(r, br)
+			if err != nil {
+				return nil, unexpected(err)
+			}
+			a = append(a, item)
+		}
+		return a, nil
+	case majorMap:
+		m := make(map[any]any, min(n, 1<<16))
+		for range n {
+			key, err := decode(r, br)
+			if err != nil {
+				return nil, unexpected(err)
+			}
+			value, err := decode(r, br)
+			if err != nil {
+				return nil, unexpected(err)
+			}
+			switch key.(type) {
+			case []any, map[any]any, []byte:
+				return nil, fmt.Errorf("%w: key of type %T", ErrInvalid, key)
+			}
+			m[key] = value
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("%w: major type %d", ErrInvalid, major)
+}
+
+// readN appends n bytes read from r to buf, in chunks so that a length
+// larger than the data does not allocate it all at once.
+func readN(r io.Reader, buf []byte, n uint64) ([]byte, error) {
+	for n > 0 {
+		chunk := min(n, 1<<16)
+		start := len(buf)
+		buf = append(buf, make([]byte, chunk)...)
+		if _, err := io.ReadFull(r, buf[start:]); err != nil {
+			return nil, unexpected(err)
+		}
+		n -= chunk
+	}
+	return buf, nil
+}
+
+// unexpected turns the end of the data in the middle of a value into an error.
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
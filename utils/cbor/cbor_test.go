@@ -0,0 +1,96 @@
+package cbor_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	. "app/utils/cbor"
+)
+
+func TestMarshal(t *testing.T) {
+	type item struct {
+		Name  string `cbor:"name"`
+		Count int    `cbor:"count,omitempty"`
+		Skip  bool   `cbor:"-"`
+		Tags  []string
+	}
+	// the examples of the appendix A of RFC 8949
+	for _, tt := range []struct {
+		value    any
+		expected string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{1000, "1903e8"},
+		{uint64(1000000000000), "1b000000e8d4a51000"},
+		{-1, "20"},
+		{-1000, "3903e7"},
+		{1.1, "fb3ff199999999999a"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{"", "60"},
+		{"IETF", "6449455446"},
+		{"ü", "62c3bc"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{[]any{1, []int{2, 3}, [2]int{4, 5}}, "8301820203820405"},
+		{map[string]any{"a": 1, "b": []int{2, 3}}, "a26161016162820203"},
+		{map[int]string{10: "x", 1: "y", -1: "z"}, "a3016179" + "0a6178" + "20617a"},
+		{&item{Name: "a", Skip: true}, "a26454616773f6" + "646e616d656161"},
+		{item{Name: "a", Count: 2, Tags: []string{"t"}}, "a36454616773816174" + "646e616d656161" + "65636f756e7402"},
+	} {
+		data, err := Marshal(tt.value)
+		if err != nil || hex.EncodeToString(data) != tt.expected {
+			t.Errorf("%#v: expected %s, got %x (%v)", tt.value, tt.expected, data, err)
+		}
+	}
+	if _, err := Marshal(func() {}); err == nil {
+		t.Errorf("Expected functions not to be encoded")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	value := map[string]any{
+		"tokens": []any{map[string]any{"val": "a", "line": 0, "pos": -3}},
+		"ok":     true,
+		"none":   nil,
+		"bytes":  []byte("xy"),
+		"float":  0.5,
+	}
+	var b bytes.Buffer
+	if err := Encode(&b, value); err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+	decoded, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[any]any{
+		"tokens": []any{map[any]any{"val": "a", "line": uint64(0), "pos": int64(-3)}},
+		"ok":     true,
+		"none":   nil,
+		"bytes":  []byte("xy"),
+		"float":  0.5,
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected %v, got %v", expected, decoded)
+	}
+
+	for i := 1; i < len(data); i++ {
+		if _, err := Decode(bytes.NewReader(data[:i])); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Expected the data cut at %d to be unexpected EOF, got %v", i, err)
+		}
+	}
+	for _, invalid := range []string{"1c", "9f", "c0", "a1800102", "f7"} {
+		data, _ := hex.DecodeString(invalid)
+		if _, err := Decode(bytes.NewReader(data)); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", invalid, err)
+		}
+	}
+}
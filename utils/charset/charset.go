@@ -0,0 +1,209 @@
+// Package charset reads the source files written on any platform as the
+// UTF-8 text with \n line endings the lexer expects: the byte order mark
+// some editors put at the start of UTF-8 files is dropped, files in GBK,
+// the default of the Chinese editions of Windows, are converted to UTF-8,
+// and \r\n and \r end the lines as \n does. The positions of the tokens and
+// the strings of a program are then the same whatever machine wrote it.
+package charset
+
+import (
+	"bytes"
+	"cmp"
+	_ "embed"
+	"encoding/binary"
+	"io"
+	"unicode/utf8"
+)
+
+// Encoding is the encoding a source was detected in.
+type Encoding int
+
+const (
+	ASCII   Encoding = iota // no byte above 0x7f, read the same in all the others
+	UTF8                    // UTF-8 without a byte order mark
+	UTF8BOM                 // UTF-8 after a byte order mark
+	GBK
+)
+
+func (e Encoding) String() string {
+	return [...]string{"ASCII", "UTF-8", "UTF-8 with BOM", "GBK"}[e]
+}
+
+// Converted checks if the source read differs from the text it is read as,
+// but for its line endings.
+func (e Encoding) Converted() bool {
+	return e == UTF8BOM || e == GBK
+}
+
+// gbk is the code point of each pair of bytes of GBK, from the lead byte
+// 0x81 to 0xfe and the trail byte 0x40 to 0xfe but 0x7f, as big-endian
+// 16 bits, 0 for the pairs that have none. It is the gbk codec of Python:
+//
+//	for lead in range(0x81, 0xff):
+//	    for trail in range(0x40, 0xff):
+//	        if trail != 0x7f:
+//	            try: c = ord(bytes([lead, trail]).decode('gbk'))
+//	            except UnicodeDecodeError: c = 0
+//	            out += struct.pack('>H', c)
+//
+//go:embed gbk.bin
+var gbk []byte
+
+// gbkRune returns the character of the pair of bytes of GBK, false if it
+// has none.
+func gbkRune(lead, trail byte) (rune, bool) {
+	if lead < 0x81 || lead > 0xfe || trail < 0x40 || trail > 0xfe || trail == 0x7f {
+		return 0, false
+	}
+	column := int(trail - 0x40)
+	if trail > 0x7f {
+		column--
+	}
+	i := 2 * (int(lead-0x81)*190 + column)
+	r := rune(binary.BigEndian.Uint16(gbk[i:]))
+	return r, r != 0
+}
+
+// detectWindow is the number of bytes from the first one above 0x7f that
+// must be valid UTF-8 for a source to be read as UTF-8 rather than GBK, many
+// pairs of GBK being valid UTF-8 alone, such as the D1 A7 of 学.
+const detectWindow = 4096
+
+// Reader reads a source as UTF-8 with \n line endings. The encoding is
+// detected at the first byte above 0x7f, before which all of them read the
+// same, so the source is converted as it is read rather than read whole.
+type Reader struct {
+	r        io.Reader
+	buf      []byte
+	in       []byte // the bytes read and not converted yet
+	out      []byte // the bytes converted and not returned yet
+	eof      bool
+	err      error
+	started  bool // whether the byte order mark was looked for
+	encoding Encoding
+	crlf     bool
+}
+
+// NewReader returns a reader converting the source.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Encoding returns the encoding of the source read so far, ASCII until a
+// byte above 0x7f is.
+func (r *Reader) Encoding() Encoding {
+	return r.encoding
+}
+
+// CRLF checks if a line of the source read so far ended with \r.
+func (r *Reader) CRLF() bool {
+	return r.crlf
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.eof {
+			return 0, cmp.Or(r.err, io.EOF)
+		}
+		if r.buf == nil {
+			r.buf = make([]byte, 4096)
+		}
+		n, err := r.r.Read(r.buf)
+		r.in = append(r.in, r.buf[:n]...)
+		if err != nil {
+			r.eof = true
+			if err != io.EOF {
+				r.err = err
+			}
+		}
+		r.convert()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// convert converts the bytes read as far as it can, leaving those that need
+// the bytes after them, until the end of the source.
+func (r *Reader) convert() {
+	if !r.started {
+		if len(r.in) < 3 && !r.eof {
+			return
+		}
+		r.started = true
+		if len(r.in) >= 3 && r.in[0] == 0xef && r.in[1] == 0xbb && r.in[2] == 0xbf {
+			r.in, r.encoding = r.in[3:], UTF8BOM
+		}
+	}
+	i := 0
+	for i < len(r.in) {
+		b := r.in[i]
+		if b == '\r' {
+			if i+1 == len(r.in) && !r.eof {
+				break
+			}
+			r.crlf = true
+			r.out = append(r.out, '\n')
+			if i++; i < len(r.in) && r.in[i] == '\n' {
+				i++
+			}
+			continue
+		}
+		if b < utf8.RuneSelf {
+			r.out = append(r.out, b)
+			i++
+			continue
+		}
+		if r.encoding == ASCII {
+			if len(r.in)-i < detectWindow && !r.eof {
+				break
+			}
+			r.encoding = detect(r.in[i:min(i+detectWindow, len(r.in))], len(r.in)-i > detectWindow || !r.eof)
+		}
+		if r.encoding != GBK {
+			if !utf8.FullRune(r.in[i:]) && !r.eof {
+				break
+			}
+			_, size := utf8.DecodeRune(r.in[i:])
+			r.out = append(r.out, r.in[i:i+size]...)
+			i += size
+			continue
+		}
+		if i+1 == len(r.in) && !r.eof && b >= 0x81 && b <= 0xfe {
+			break
+		}
+		if i+1 < len(r.in) {
+			if c, ok := gbkRune(b, r.in[i+1]); ok {
+				r.out = utf8.AppendRune(r.out, c)
+				i += 2
+				continue
+			}
+		}
+		r.out = utf8.AppendRune(r.out, utf8.RuneError)
+		i++
+	}
+	r.in = r.in[i:]
+}
+
+// detect tells UTF-8 from GBK by the bytes from the first one above 0x7f,
+// the last rune of which may be cut if more follow.
+func detect(window []byte, more bool) Encoding {
+	for len(window) > 0 {
+		c, size := utf8.DecodeRune(window)
+		if c == utf8.RuneError && size <= 1 {
+			if more && !utf8.FullRune(window) {
+				break
+			}
+			return GBK
+		}
+		window = window[size:]
+	}
+	return UTF8
+}
+
+// Decode converts a whole source, returning the text and its encoding.
+func Decode(data []byte) (string, Encoding) {
+	r := NewReader(bytes.NewReader(data))
+	text, _ := io.ReadAll(r)
+	return string(text), r.Encoding()
+}
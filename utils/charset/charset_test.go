@@ -0,0 +1,54 @@
+package charset_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	. "app/utils/charset"
+)
+
+func TestReader(t *testing.T) {
+	long := strings.Repeat("// ascii\n", 1000)
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+		encoding Encoding
+		crlf     bool
+	}{
+		{"ASCII", "int a;\n", "int a;\n", ASCII, false},
+		{"UTF8", "s = \"中文\";\n", "s = \"中文\";\n", UTF8, false},
+		{"BOM", "\xef\xbb\xbfs = \"中文\";\n", "s = \"中文\";\n", UTF8BOM, false},
+		{"GBK", "s = \"\xd6\xd0\xce\xc4\";\n", "s = \"中文\";\n", GBK, false},
+		// D1 A7 alone is valid UTF-8, the pair after it is not
+		{"GBKValidPair", "// \xd1\xa7\xc9\xfa\n", "// 学生\n", GBK, false},
+		{"GBKInvalid", "a\xff\xd6", "a��", GBK, false},
+		{"CRLF", "a;\r\nb;\rc;\r", "a;\nb;\nc;\n", ASCII, true},
+		{"GBKCRLF", "\xd6\xd0\r\n\xce\xc4\r\n", "中\n文\n", GBK, true},
+		{"LateGBK", long + "\xd6\xd0\xce\xc4", long + "中文", GBK, false},
+		{"LateUTF8", long + "中文\r\n", long + "中文\n", UTF8, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, r := range []io.Reader{strings.NewReader(test.src), iotest.OneByteReader(strings.NewReader(test.src))} {
+				cr := NewReader(r)
+				text, err := io.ReadAll(cr)
+				if err != nil {
+					t.Fatalf("ReadAll: %v", err)
+				}
+				if string(text) != test.expected || cr.Encoding() != test.encoding || cr.CRLF() != test.crlf {
+					t.Errorf("Expected %q in %v, CRLF %v, got %q in %v, CRLF %v", test.expected, test.encoding, test.crlf, text, cr.Encoding(), cr.CRLF())
+				}
+			}
+			if text, encoding := Decode([]byte(test.src)); text != test.expected || encoding != test.encoding {
+				t.Errorf("Expected Decode to give %q in %v, got %q in %v", test.expected, test.encoding, text, encoding)
+			}
+		})
+	}
+
+	if _, err := io.ReadAll(NewReader(iotest.ErrReader(io.ErrUnexpectedEOF))); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected the error of the source, got %v", err)
+	}
+}
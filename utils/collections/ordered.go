@@ -0,0 +1,152 @@
+package collections
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// OrderedSet is a set iterating its elements in a fixed order, the order
+// they were added in or that of a comparison function, rather than the order
+// of a map, which changes from one run to the next: what is numbered or
+// written from it is then the same on every run. The zero value is an empty
+// set in the order of insertion.
+type OrderedSet[T comparable] struct {
+	members  Set[T]
+	elements []T
+	compare  func(a, b T) int // nil for the order of insertion
+}
+
+// NewOrderedSet creates a set in the order its elements are added in.
+func NewOrderedSet[T comparable]() *OrderedSet[T] {
+	return &OrderedSet[T]{members: NewSet[T]()}
+}
+
+// NewSortedSet creates a set in the order of the comparison function, which
+// returns a negative number, zero or a positive number as cmp.Compare does.
+func NewSortedSet[T comparable](compare func(a, b T) int) *OrderedSet[T] {
+	return &OrderedSet[T]{members: NewSet[T](), compare: compare}
+}
+
+// Add adds an element to the set, at its end or at its place in the order.
+func (s *OrderedSet[T]) Add(value T) *OrderedSet[T] {
+	if s.members == nil {
+		s.members = NewSet[T]()
+	}
+	if s.members.Contains(value) {
+		return s
+	}
+	s.members.Add(value)
+	if s.compare == nil {
+		s.elements = append(s.elements, value)
+		return s
+	}
+	i, _ := slices.BinarySearchFunc(s.elements, value, s.compare)
+	s.elements = slices.Insert(s.elements, i, value)
+	return s
+}
+
+// AddAll adds the elements to the set in turn.
+func (s *OrderedSet[T]) AddAll(values ...T) *OrderedSet[T] {
+	for _, value := range values {
+		s.Add(value)
+	}
+	return s
+}
+
+// Remove removes an element from the set, keeping the order of the others.
+func (s *OrderedSet[T]) Remove(value T) *OrderedSet[T] {
+	if !s.Contains(value) {
+		return s
+	}
+	delete(s.members, value)
+	i := slices.Index(s.elements, value)
+	s.elements = slices.Delete(s.elements, i, i+1)
+	return s
+}
+
+// Contains checks if the set contains an element.
+func (s *OrderedSet[T]) Contains(value T) bool {
+	return s != nil && s.members.Contains(value)
+}
+
+// Size returns the number of elements in the set.
+func (s *OrderedSet[T]) Size() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.elements)
+}
+
+// Clear removes all elements from the set, keeping its order.
+func (s *OrderedSet[T]) Clear() *OrderedSet[T] {
+	clear(s.members)
+	s.elements = s.elements[:0]
+	return s
+}
+
+// Elements returns a slice of the elements in order.
+func (s *OrderedSet[T]) Elements() []T {
+	if s == nil {
+		return nil
+	}
+	return slices.Clone(s.elements)
+}
+
+// All returns an iterator over the elements in order, which must not change
+// the set.
+func (s *OrderedSet[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if s == nil {
+			return
+		}
+		for _, value := range s.elements {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// Copy creates a shallow copy of the set, in the same order.
+func (s *OrderedSet[T]) Copy() *OrderedSet[T] {
+	return &OrderedSet[T]{members: s.members.Copy(), elements: slices.Clone(s.elements), compare: s.compare}
+}
+
+// Union returns a new set of the elements of both, in the order of the set,
+// the elements of the other after its own if in the order of insertion.
+func (s *OrderedSet[T]) Union(other *OrderedSet[T]) *OrderedSet[T] {
+	union := s.Copy()
+	for value := range other.All() {
+		union.Add(value)
+	}
+	return union
+}
+
+// Equal checks if two sets have the same elements, in whatever order.
+func (s *OrderedSet[T]) Equal(other *OrderedSet[T]) bool {
+	if s.Size() != other.Size() {
+		return false
+	}
+	for value := range s.All() {
+		if !other.Contains(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// ToSet returns the elements as a Set.
+func (s *OrderedSet[T]) ToSet() Set[T] {
+	return NewSet[T]().AddAll(s.elements...)
+}
+
+// String returns a string representation of the set, its elements in order.
+func (s *OrderedSet[T]) String() string {
+	elements := make([]string, 0, s.Size())
+	for value := range s.All() {
+		elements = append(elements, fmt.Sprint(value))
+	}
+	return "{" + strings.Join(elements, " ") + "}"
+}
@@ -0,0 +1,63 @@
+package collections_test
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+
+	. "app/utils/collections"
+)
+
+// TestOrderedSet_Model runs random operations on ordered sets and on a
+// slice kept in the order of insertion alike, adding the positive values and
+// removing the opposite of the others.
+func TestOrderedSet_Model(t *testing.T) {
+	check(t, "an ordered set keeps the order its elements were first added in", func(ops []int8) bool {
+		s, sorted, model := NewOrderedSet[int8](), NewSortedSet(cmp.Compare[int8]), []int8{}
+		for _, op := range ops {
+			if op > 0 {
+				s.Add(op)
+				sorted.Add(op)
+				if !slices.Contains(model, op) {
+					model = append(model, op)
+				}
+				continue
+			}
+			s.Remove(-op)
+			sorted.Remove(-op)
+			if i := slices.Index(model, -op); i >= 0 {
+				model = slices.Delete(model, i, i+1)
+			}
+		}
+		if !slices.Equal(s.Elements(), model) || s.Size() != len(model) || !s.Equal(sorted) {
+			return false
+		}
+		slices.Sort(model)
+		return slices.Equal(sorted.Elements(), model) && slices.Equal(slices.Collect(sorted.All()), model)
+	})
+	check(t, "a union adds the other elements after those of the set", func(a, b []byte) bool {
+		union := NewOrderedSet[byte]().AddAll(a...).Union(NewOrderedSet[byte]().AddAll(b...))
+		return union.ToSet().Equal(set(a).Union(set(b))) && union.ToSet().Size() == union.Size() &&
+			slices.Equal(union.Elements()[:set(a).Size()], NewOrderedSet[byte]().AddAll(a...).Elements())
+	})
+}
+
+func TestOrderedSet(t *testing.T) {
+	var s OrderedSet[string]
+	s.AddAll("b", "a", "c", "a")
+	if got := s.String(); got != "{b a c}" {
+		t.Errorf("Expected the elements in the order added, got %s", got)
+	}
+	c := s.Copy().Remove("a").Add("a")
+	if got := c.String(); got != "{b c a}" || s.String() != "{b a c}" {
+		t.Errorf("Expected an element added again at the end of the copy alone, got %s and %s", c, &s)
+	}
+	sorted := NewSortedSet(func(a, b string) int { return cmp.Compare(b, a) }).AddAll("b", "a", "c")
+	if got := sorted.String(); got != "{c b a}" || !sorted.Equal(&s) {
+		t.Errorf("Expected the elements in the order of the function, got %s", got)
+	}
+	var empty *OrderedSet[string]
+	if empty.Size() != 0 || empty.Contains("a") || len(empty.Elements()) != 0 {
+		t.Errorf("Expected a nil set to be empty")
+	}
+}
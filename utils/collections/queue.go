@@ -23,6 +23,9 @@ func (q *Queue[T]) Dequeue() (T, bool) {
 		return zero, false
 	}
 	item := q.items[0]
+	// the slot is cleared so that the item taken is not kept alive by the queue
+	var zero T
+	q.items[0] = zero
 	q.items = q.items[1:]
 	return item, true
 }
@@ -46,6 +49,11 @@ func (q *Queue[T]) Size() int {
 	return len(q.items)
 }
 
+// Len returns the number of items in the queue, as Size does
+func (q *Queue[T]) Len() int {
+	return len(q.items)
+}
+
 // Clear removes all items from the queue
 func (q *Queue[T]) Clear() {
 	q.items = []T{}
@@ -0,0 +1,33 @@
+package collections_test
+
+import (
+	"slices"
+	"testing"
+
+	. "app/utils/collections"
+)
+
+// TestQueue_Model runs random operations on a queue and on a slice alike,
+// enqueuing the positive values and dequeuing for the others.
+func TestQueue_Model(t *testing.T) {
+	check(t, "a queue behaves as a slice appended to and taken from its front", func(ops []int8) bool {
+		q, model := NewQueue[int8](), []int8{}
+		for _, op := range ops {
+			if op > 0 {
+				q.Enqueue(op)
+				model = append(model, op)
+				continue
+			}
+			v, ok := q.Dequeue()
+			if ok != (len(model) > 0) || ok && v != model[0] {
+				return false
+			}
+			if ok {
+				model = model[1:]
+			}
+		}
+		front, ok := q.Peek()
+		return q.Size() == len(model) && q.Len() == len(model) && q.IsEmpty() == (len(model) == 0) &&
+			(!ok || front == model[0]) && slices.Equal(q.ToSlice(), model)
+	})
+}
@@ -0,0 +1,87 @@
+package collections_test
+
+import (
+	"sync"
+	"testing"
+	"testing/quick"
+
+	. "app/utils/collections"
+)
+
+// set makes a set of the values, bytes so that random sets overlap.
+func set(values []byte) Set[byte] {
+	return NewSet[byte]().AddAll(values...)
+}
+
+func check(t *testing.T, name string, law any) {
+	t.Helper()
+	if err := quick.Check(law, &quick.Config{MaxCount: 500}); err != nil {
+		t.Errorf("%s: %v", name, err)
+	}
+}
+
+func TestSet_Laws(t *testing.T) {
+	check(t, "union is commutative", func(a, b []byte) bool {
+		return set(a).Union(set(b)).Equal(set(b).Union(set(a)))
+	})
+	check(t, "union is associative", func(a, b, c []byte) bool {
+		return set(a).Union(set(b)).Union(set(c)).Equal(set(a).Union(set(b).Union(set(c))))
+	})
+	check(t, "the empty set is the identity of union", func(a []byte) bool {
+		return set(a).Union(NewSet[byte]()).Equal(set(a))
+	})
+	check(t, "intersection is commutative", func(a, b []byte) bool {
+		return set(a).Intersection(set(b)).Equal(set(b).Intersection(set(a)))
+	})
+	check(t, "intersection distributes over union", func(a, b, c []byte) bool {
+		return set(a).Intersection(set(b).Union(set(c))).Equal(set(a).Intersection(set(b)).Union(set(a).Intersection(set(c))))
+	})
+	check(t, "a set is its intersection with another and its difference from it", func(a, b []byte) bool {
+		both, only := set(a).Intersection(set(b)), set(a).Difference(set(b))
+		return both.Union(only).Equal(set(a)) && both.Intersection(only).Size() == 0
+	})
+	check(t, "the difference shares nothing with the set subtracted", func(a, b []byte) bool {
+		return set(a).Difference(set(b)).Intersection(set(b)).Size() == 0
+	})
+	check(t, "a difference from a union is the intersection of the differences", func(a, b, c []byte) bool {
+		return set(a).Difference(set(b).Union(set(c))).Equal(set(a).Difference(set(b)).Intersection(set(a).Difference(set(c))))
+	})
+	check(t, "a set is a subset of its union with another", func(a, b []byte) bool {
+		union := set(a).Union(set(b))
+		return set(a).IsSubset(union) && union.IsSuperset(set(b)) && set(a).Intersection(set(b)).IsSubset(set(a))
+	})
+	check(t, "the operations leave their operands alone", func(a, b []byte) bool {
+		x, y := set(a), set(b)
+		x.Union(y)
+		x.Intersection(y)
+		x.Difference(y)
+		return x.Equal(set(a)) && y.Equal(set(b))
+	})
+	check(t, "a copy equals the set and is independent of it", func(a []byte, v byte) bool {
+		c := set(a).Copy()
+		c.Add(v)
+		return c.Contains(v) && set(a).IsSubset(c) && c.Size() <= set(a).Size()+1
+	})
+}
+
+// TestSet_ConcurrentReads reads a set from several goroutines, as the files
+// compiled in parallel read the sets of the shared parse tables. Run with
+// -race, it fails if any of the operations writes the sets it reads.
+func TestSet_ConcurrentReads(t *testing.T) {
+	a, b := set([]byte{1, 2, 3, 4}), set([]byte{3, 4, 5})
+	wg := sync.WaitGroup{}
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if !a.Union(b).Equal(set([]byte{1, 2, 3, 4, 5})) || a.Intersection(b).Size() != 2 ||
+					!a.Difference(b).Copy().Equal(set([]byte{1, 2})) || !a.IsSuperset(a.Filter(func(v byte) bool { return v > 2 })) {
+					t.Error("Unexpected result of an operation on the shared sets")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
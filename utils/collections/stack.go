@@ -4,6 +4,7 @@ package collections
 
 import (
 	"fmt"
+	"slices"
 )
 
 type Stack[T any] struct {
@@ -20,6 +21,11 @@ func (s *Stack[T]) Push(value T) {
 	s.data = append(s.data, value)
 }
 
+// PushAll pushes the elements in order, the last one ending on top.
+func (s *Stack[T]) PushAll(values ...T) {
+	s.data = append(s.data, values...)
+}
+
 // Pop removes and returns the top element of the stack.
 func (s *Stack[T]) Pop() (T, bool) {
 	if len(s.data) == 0 {
@@ -57,6 +63,12 @@ func (s *Stack[T]) Peek() (T, bool) {
 	return s.data[len(s.data)-1], true
 }
 
+// Copy creates a shallow copy of the stack, pushed to and popped from
+// independently of it.
+func (s *Stack[T]) Copy() Stack[T] {
+	return Stack[T]{data: slices.Clone(s.data)}
+}
+
 // IsEmpty checks if the stack is empty.
 func (s *Stack[T]) IsEmpty() bool {
 	return len(s.data) == 0
@@ -67,6 +79,11 @@ func (s *Stack[T]) Size() int {
 	return len(s.data)
 }
 
+// Len returns the number of elements in the stack, as Size does.
+func (s *Stack[T]) Len() int {
+	return len(s.data)
+}
+
 // Clear removes all elements from the stack.
 func (s *Stack[T]) Clear() {
 	s.data = []T{}
@@ -0,0 +1,56 @@
+package collections_test
+
+import (
+	"slices"
+	"testing"
+
+	. "app/utils/collections"
+)
+
+// TestStack_Model runs random operations on a stack and on a slice alike,
+// pushing the positive values and popping for the others.
+func TestStack_Model(t *testing.T) {
+	check(t, "a stack behaves as a slice pushed and popped at its end", func(ops []int8) bool {
+		s, model := NewStack[int8](), []int8{}
+		for _, op := range ops {
+			if op > 0 {
+				s.Push(op)
+				model = append(model, op)
+				continue
+			}
+			v, ok := s.Pop()
+			if ok != (len(model) > 0) || ok && v != model[len(model)-1] {
+				return false
+			}
+			if ok {
+				model = model[:len(model)-1]
+			}
+		}
+		for k := range model {
+			if v, ok := s.PeekAtK(k); !ok || v != model[len(model)-1-k] {
+				return false
+			}
+		}
+		return s.Size() == len(model) && s.Len() == len(model)
+	})
+	check(t, "PushAll pushes as many Push calls do", func(values []int8) bool {
+		s, pushed := NewStack[int8](), NewStack[int8]()
+		s.PushAll(values...)
+		for _, v := range values {
+			pushed.Push(v)
+		}
+		top, ok := s.Peek()
+		return s.String() == pushed.String() && s.Len() == len(values) && (!ok || top == values[len(values)-1])
+	})
+	check(t, "PopTopN pops the top n in the order they were pushed", func(values []int8, n uint8) bool {
+		s := NewStack[int8]()
+		for _, v := range values {
+			s.Push(v)
+		}
+		top := s.PopTopN(int(n))
+		if int(n) == 0 || int(n) > len(values) {
+			return top == nil && s.Size() == len(values)
+		}
+		return slices.Equal(top, values[len(values)-int(n):]) && s.Size() == len(values)-int(n)
+	})
+}
@@ -1,8 +1,14 @@
 package utils
 
 import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
 type FileInfo struct {
@@ -30,3 +36,81 @@ func GetDirFiles(dir string) ([]FileInfo, error) {
 
 	return files, nil
 }
+
+// FileDiff is a file differing between two directories, by its path
+// relative to them.
+type FileDiff struct {
+	Path string
+	Line int    // the first line that differs, from 1, 0 if one of the directories lacks the file
+	A, B string // the line in each directory, or whether it has the file
+}
+
+func (d FileDiff) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s / %s", d.Path, d.A, d.B)
+	}
+	return fmt.Sprintf("%s:%d: %q / %q", d.Path, d.Line, d.A, d.B)
+}
+
+// maxDiffLine is the length the lines of a FileDiff are cut to.
+const maxDiffLine = 80
+
+// DiffDirs compares the files of the directories and their subdirectories,
+// returning those that differ sorted by path.
+func DiffDirs(a, b string) ([]FileDiff, error) {
+	paths := map[string][2]bool{}
+	for i, dir := range []string{a, b} {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			found := paths[rel]
+			found[i] = true
+			paths[rel] = found
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var diffs []FileDiff
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		if found := paths[path]; !found[0] || !found[1] {
+			present := map[bool]string{true: "present", false: "missing"}
+			diffs = append(diffs, FileDiff{Path: path, A: present[found[0]], B: present[found[1]]})
+			continue
+		}
+		x, err := os.ReadFile(filepath.Join(a, path))
+		if err != nil {
+			return nil, err
+		}
+		y, err := os.ReadFile(filepath.Join(b, path))
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(x, y) {
+			continue
+		}
+		xs, ys := strings.Split(string(x), "\n"), strings.Split(string(y), "\n")
+		i := 0
+		for i < len(xs) && i < len(ys) && xs[i] == ys[i] {
+			i++
+		}
+		line := func(lines []string) string {
+			if i >= len(lines) {
+				return ""
+			}
+			s := lines[i]
+			if len(s) > maxDiffLine {
+				s = s[:maxDiffLine] + "..."
+			}
+			return s
+		}
+		diffs = append(diffs, FileDiff{Path: path, Line: i + 1, A: line(xs), B: line(ys)})
+	}
+	return diffs, nil
+}
@@ -0,0 +1,45 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "app/utils"
+)
+
+func TestDiffDirs(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	write := func(dir, path, text string) {
+		t.Helper()
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(a, "same.txt", "x\ny\n")
+	write(b, "same.txt", "x\ny\n")
+	write(a, "sub/changed.txt", "x\ny\nz\n")
+	write(b, "sub/changed.txt", "x\nY\nz\n")
+	write(a, "only.txt", "")
+
+	diffs, err := DiffDirs(a, b)
+	if err != nil {
+		t.Fatalf("DiffDirs: %v", err)
+	}
+	expected := []string{
+		"only.txt: present / missing",
+		filepath.Join("sub", "changed.txt") + `:2: "y" / "Y"`,
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences, got %v", len(expected), diffs)
+	}
+	for i, diff := range diffs {
+		if diff.String() != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], diff)
+		}
+	}
+}
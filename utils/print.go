@@ -1,6 +1,11 @@
 package utils
 
-import "app/utils/log"
+import (
+	"strings"
+	"unicode"
+
+	"app/utils/log"
+)
 
 func Divider() log.Argument {
 	return log.Argument{
@@ -8,3 +13,23 @@ func Divider() log.Argument {
 		Format:    "====================\n",
 	}
 }
+
+// Width returns the columns the text takes in a terminal, two for each of
+// the Chinese characters and the full-width forms, one for the others.
+func Width(s string) int {
+	width := 0
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) || r >= 0x3000 && r <= 0x303f || r >= 0xff00 && r <= 0xff60 {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// PadRight pads the text with spaces to take the columns, leaving it as it
+// is if it takes more.
+func PadRight(s string, columns int) string {
+	return s + strings.Repeat(" ", max(columns-Width(s), 0))
+}
@@ -0,0 +1,25 @@
+package utils_test
+
+import (
+	"testing"
+
+	. "app/utils"
+)
+
+func TestPadRight(t *testing.T) {
+	tests := []struct {
+		text     string
+		columns  int
+		expected string
+	}{
+		{"id", 4, "id  "},
+		{"保留字", 8, "保留字  "},
+		{"（）", 6, "（）  "},
+		{"identifier", 4, "identifier"},
+	}
+	for _, test := range tests {
+		if padded := PadRight(test.text, test.columns); padded != test.expected {
+			t.Errorf("Expected %q padded to %d columns as %q, got %q", test.text, test.columns, test.expected, padded)
+		}
+	}
+}
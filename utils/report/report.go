@@ -0,0 +1,116 @@
+// Package report lays out the text artifacts of the exporters alike: the
+// columns of the tables aligned on the widths a terminal shows them with,
+// the numbers of the states and of the instructions padded to the widest
+// one, and the symbols of the grammars in a single order. Two artifacts of
+// the same input are then equal byte for byte, and those of two inputs
+// differ only on the lines that changed.
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+
+	"app/utils"
+)
+
+// End is the end of input as the grammars write it.
+const End = "$"
+
+// CompareSymbols orders the symbols of a grammar as the artifacts list them,
+// by their text with the end of input last, as the textbooks lay out the
+// columns of the tables.
+func CompareSymbols[S ~string](a, b S) int {
+	switch {
+	case a == b:
+		return 0
+	case a == End:
+		return 1
+	case b == End:
+		return -1
+	}
+	return cmp.Compare(a, b)
+}
+
+// SortSymbols sorts the symbols in the order of CompareSymbols.
+func SortSymbols[S ~string](symbols []S) {
+	slices.SortFunc(symbols, CompareSymbols)
+}
+
+// SortedSymbols returns the symbols in the order of CompareSymbols.
+func SortedSymbols[S ~string](symbols iter.Seq[S]) []S {
+	return slices.SortedFunc(symbols, CompareSymbols)
+}
+
+// NumberWidth returns the digits of the highest number of a list numbered up
+// to last, the width every number of the list is padded to.
+func NumberWidth(last int) int {
+	return len(fmt.Sprint(max(last, 0)))
+}
+
+// Table is a table of text, each column as wide as its widest cell. The
+// lines are written without the spaces at their end, so a table with its
+// last column empty ends where the column before does.
+type Table struct {
+	Indent string // before every line
+	Sep    string // between two columns, two spaces if empty
+	Right  []int  // the columns aligned right, such as those of numbers
+	rows   [][]string
+}
+
+// Row adds a row of cells, as many as the columns or fewer.
+func (t *Table) Row(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// Widths returns the width of each column, that of its widest cell.
+func (t *Table) Widths() []int {
+	var widths []int
+	for _, row := range t.rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utils.Width(cell))
+		}
+	}
+	return widths
+}
+
+// Lines returns the rows laid out, a line each.
+func (t *Table) Lines() []string {
+	widths := t.Widths()
+	sep := cmp.Or(t.Sep, "  ")
+	lines := make([]string, len(t.rows))
+	for i, row := range t.rows {
+		var b strings.Builder
+		b.WriteString(t.Indent)
+		for j, cell := range row {
+			if j > 0 {
+				b.WriteString(sep)
+			}
+			padding := strings.Repeat(" ", widths[j]-utils.Width(cell))
+			if slices.Contains(t.Right, j) {
+				cell = padding + cell
+			} else {
+				cell += padding
+			}
+			b.WriteString(cell)
+		}
+		lines[i] = strings.TrimRight(b.String(), " ")
+	}
+	return lines
+}
+
+// Write writes the lines of the table.
+func (t *Table) Write(w io.Writer) error {
+	for _, line := range t.Lines() {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
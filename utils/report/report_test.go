@@ -0,0 +1,55 @@
+package report_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/utils/report"
+)
+
+func TestTable(t *testing.T) {
+	table := Table{Right: []int{1}}
+	table.Row("状态", "n", "ACTION")
+	table.Row("id", "12", "")
+	table.Row("E'", "3")
+	lines := table.Lines()
+	expected := []string{
+		"状态   n  ACTION",
+		"id    12",
+		"E'     3",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("Expected the columns aligned on the widths of the terminal\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+	if widths := table.Widths(); !slices.Equal(widths, []int{4, 2, 6}) {
+		t.Errorf("Expected the widths of the widest cells, got %v", widths)
+	}
+
+	table = Table{Indent: "    ", Sep: " | "}
+	table.Row("", "id", "")
+	table.Row("E", "0", "")
+	var b strings.Builder
+	if err := table.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "      | id |\n    E | 0  |\n" {
+		t.Errorf("Expected the rows indented and split by |, got %q", b.String())
+	}
+}
+
+func TestSortSymbols(t *testing.T) {
+	symbols := []string{"$", "id", ")", "ε", "+", "E'"}
+	SortSymbols(symbols)
+	if !slices.Equal(symbols, []string{")", "+", "E'", "id", "ε", "$"}) {
+		t.Errorf("Expected the symbols by their text with $ last, got %v", symbols)
+	}
+	if sorted := SortedSymbols(slices.Values([]string{"$", "a"})); !slices.Equal(sorted, []string{"a", "$"}) {
+		t.Errorf("Expected $ last, got %v", sorted)
+	}
+	for last, width := range map[int]int{-1: 1, 0: 1, 9: 1, 10: 2, 100: 3} {
+		if w := NumberWidth(last); w != width {
+			t.Errorf("Expected the numbers up to %d %d wide, got %d", last, width, w)
+		}
+	}
+}
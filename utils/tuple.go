@@ -0,0 +1,60 @@
+package utils
+
+// Pair is an ordered pair, such as a state and a symbol keying a transition.
+// It is comparable when both its types are, so it can key a map.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// MakePair returns the pair of the two values.
+func MakePair[A, B any](first A, second B) Pair[A, B] {
+	return Pair[A, B]{First: first, Second: second}
+}
+
+// Unpack returns the two values of the pair.
+func (p Pair[A, B]) Unpack() (A, B) {
+	return p.First, p.Second
+}
+
+// Result is a value that may not have been found, the value of a query
+// carried around as one instead of a value and a flag.
+type Result[T any] struct {
+	Value T
+	Found bool
+}
+
+// Found returns the result of the value found.
+func Found[T any](value T) Result[T] {
+	return Result[T]{Value: value, Found: true}
+}
+
+// NotFound returns the result of nothing found.
+func NotFound[T any]() Result[T] {
+	return Result[T]{}
+}
+
+// Get returns the value and whether it was found, for an if statement.
+func (r Result[T]) Get() (T, bool) {
+	return r.Value, r.Found
+}
+
+// Or returns the value if it was found, the fallback otherwise.
+func (r Result[T]) Or(fallback T) T {
+	if r.Found {
+		return r.Value
+	}
+	return fallback
+}
+
+// Lookup returns the value of the key in the map, which may be nil.
+func Lookup[K comparable, V any](m map[K]V, key K) Result[V] {
+	value, ok := m[key]
+	return Result[V]{Value: value, Found: ok}
+}
+
+// Lookup2 returns the value of the two keys in the map of maps, such as the
+// action of a state and a terminal in the action table.
+func Lookup2[K1, K2 comparable, V any](m map[K1]map[K2]V, first K1, second K2) Result[V] {
+	return Lookup(m[first], second)
+}
@@ -0,0 +1,29 @@
+package utils_test
+
+import (
+	"testing"
+
+	. "app/utils"
+)
+
+func TestPair(t *testing.T) {
+	seen := map[Pair[int, string]]bool{MakePair(1, "id"): true}
+	if !seen[MakePair(1, "id")] || seen[MakePair(1, "+")] {
+		t.Errorf("Expected pairs of equal values to key the same entry, got %v", seen)
+	}
+	if state, symbol := MakePair(3, "E").Unpack(); state != 3 || symbol != "E" {
+		t.Errorf("Expected (3, E), got (%d, %s)", state, symbol)
+	}
+}
+
+func TestResult(t *testing.T) {
+	table := map[int]map[string]int{0: {"id": 5}}
+	if r := Lookup2(table, 0, "id"); r != Found(5) {
+		t.Errorf("Expected 5 to be found, got %+v", r)
+	}
+	for _, r := range []Result[int]{Lookup2(table, 0, "+"), Lookup2(table, 1, "id"), Lookup[string, int](nil, "id")} {
+		if value, ok := r.Get(); ok || r != NotFound[int]() || r.Or(-1) != -1 {
+			t.Errorf("Expected nothing to be found, got %d, %t", value, ok)
+		}
+	}
+}